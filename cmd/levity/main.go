@@ -17,6 +17,7 @@ import (
 	"github.com/keeth/levity/db"
 	"github.com/keeth/levity/monitoring"
 	"github.com/keeth/levity/server"
+	"github.com/keeth/levity/version"
 )
 
 func main() {
@@ -44,7 +45,9 @@ func main() {
 		return
 	}
 
-	logger.Info("Starting OCPP Central System...")
+	logger.Info("Starting OCPP Central System...",
+		slog.String("version", version.Version),
+		slog.String("commit", version.Commit))
 
 	// Initialize core components
 	coreSystem, err := core.NewSystem(cfg, logger)
@@ -55,9 +58,16 @@ func main() {
 
 	// Initialize monitoring
 	metrics := monitoring.NewMetrics()
+	metrics.SetBuildInfo(version.Version, version.Commit)
+	coreSystem.SetMetrics(metrics)
 
 	// Initialize server
 	srv := server.NewServer(cfg, coreSystem, metrics, logger)
+	coreSystem.SetConnectionRegistry(srv.Registry())
+	coreSystem.SetCommandSender(srv.Registry())
+
+	// Start background monitors
+	coreSystem.Start()
 
 	// Start server in a goroutine
 	go func() {
@@ -84,6 +94,10 @@ func main() {
 		logger.Error("Server forced to shutdown", slog.Any("error", err))
 	}
 
+	if err := coreSystem.Shutdown(ctx); err != nil {
+		logger.Error("Core system shutdown failed", slog.Any("error", err))
+	}
+
 	logger.Info("Server exited")
 }
 
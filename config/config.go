@@ -1,23 +1,33 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // OCPP time zones must resolve in minimal images
 
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server     ServerConfig     `mapstructure:"server"`
-	Database   DatabaseConfig   `mapstructure:"database"`
-	OCPP       OCPPConfig       `mapstructure:"ocpp"`
-	Log        LogConfig        `mapstructure:"log"`
-	Monitoring MonitoringConfig `mapstructure:"monitoring"`
+	Server         ServerConfig         `mapstructure:"server"`
+	Database       DatabaseConfig       `mapstructure:"database"`
+	OCPP           OCPPConfig           `mapstructure:"ocpp"`
+	Log            LogConfig            `mapstructure:"log"`
+	Monitoring     MonitoringConfig     `mapstructure:"monitoring"`
+	Retention      RetentionConfig      `mapstructure:"retention"`
+	Webhooks       WebhooksConfig       `mapstructure:"webhooks"`
+	Events         EventsConfig         `mapstructure:"events"`
+	Reconciliation ReconciliationConfig `mapstructure:"reconciliation"`
+	Auth           AuthConfig           `mapstructure:"auth"`
+	Audit          AuditConfig          `mapstructure:"audit"`
+	Provisioning   ProvisioningConfig   `mapstructure:"provisioning"`
 }
 
 // ServerConfig holds server-related configuration
@@ -26,6 +36,13 @@ type ServerConfig struct {
 	ReadTimeout    time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout   time.Duration `mapstructure:"write_timeout"`
 	MaxHeaderBytes int           `mapstructure:"max_header_bytes"`
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+	CORS           CORSConfig    `mapstructure:"cors"`
+}
+
+// CORSConfig holds cross-origin configuration for browser clients
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
 }
 
 // DatabaseConfig holds database-related configuration
@@ -34,15 +51,93 @@ type DatabaseConfig struct {
 	MaxOpenConns    int           `mapstructure:"max_open_conns"`
 	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	IntegerEnergy   bool          `mapstructure:"integer_energy"`
+	// CheckpointInterval runs passive WAL checkpoints on a timer in place of
+	// SQLite's automatic checkpoints; 0 keeps the automatic checkpoints
+	CheckpointInterval time.Duration `mapstructure:"checkpoint_interval"`
+	// PingOnBorrow validates a pooled connection before each use
+	PingOnBorrow bool `mapstructure:"ping_on_borrow"`
+	// AutoMigrate runs pending migrations at startup; when off, startup only
+	// checks that the schema is current, for databases migrated by a separate job
+	AutoMigrate bool `mapstructure:"auto_migrate"`
 }
 
 // OCPPConfig holds OCPP-specific configuration
 type OCPPConfig struct {
-	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
-	MaxMessageSize    int           `mapstructure:"max_message_size"`
-	ConnectionTimeout time.Duration `mapstructure:"connection_timeout"`
+	HeartbeatInterval    time.Duration `mapstructure:"heartbeat_interval"`
+	MaxMessageSize       int           `mapstructure:"max_message_size"`
+	ConnectionTimeout    time.Duration `mapstructure:"connection_timeout"`
+	HandshakeTimeout     time.Duration `mapstructure:"handshake_timeout"`
+	StaleTimeout         time.Duration `mapstructure:"stale_timeout"`
+	TimeZone             string        `mapstructure:"time_zone"`
+	PersistedMeasurands  []string      `mapstructure:"persisted_measurands"`
+	StartDedupWindow     time.Duration `mapstructure:"start_dedup_window"`
+	MeterFlushInterval   time.Duration `mapstructure:"meter_flush_interval"`
+	MeterFlushSize       int           `mapstructure:"meter_flush_size"`
+	ConcurrentCallPolicy string        `mapstructure:"concurrent_call_policy"`
+	// MeterSampleInterval and TriggerMeterMeasurands are pushed to chargers
+	// after boot; zero or empty leaves the charger's own setting
+	MeterSampleInterval    time.Duration `mapstructure:"meter_sample_interval"`
+	TriggerMeterMeasurands []string      `mapstructure:"trigger_meter_measurands"`
+	ChargerIDPattern       string        `mapstructure:"charger_id_pattern"`
+	MeterStartFallback     bool          `mapstructure:"meter_start_fallback"`
+	// ConnectionAuthURL, when set, is asked whether each charger may connect
+	ConnectionAuthURL     string        `mapstructure:"connection_auth_url"`
+	ConnectionAuthTimeout time.Duration `mapstructure:"connection_auth_timeout"`
+	// MaxMeterValuesPerMinute caps the sampled values a charger may submit in
+	// MeterValues each minute; 0 is unlimited
+	MaxMeterValuesPerMinute int    `mapstructure:"max_meter_values_per_minute"`
+	MeterRateLimitAction    string `mapstructure:"meter_rate_limit_action"`
+	// MaxClockSkew is how far a charger-supplied timestamp may be from server
+	// time before ClockSkewAction applies; 0 disables the check
+	MaxClockSkew    time.Duration `mapstructure:"max_clock_skew"`
+	ClockSkewAction string        `mapstructure:"clock_skew_action"`
+	// MaxConnectorsPerCharger is the highest connector id a StatusNotification
+	// may create a connector for; 0 is unlimited
+	MaxConnectorsPerCharger int `mapstructure:"max_connectors_per_charger"`
+	// StubActions maps actions the server does not implement to the JSON
+	// payload answered in place of a NotImplemented CALLERROR
+	StubActions map[string]string `mapstructure:"stub_actions"`
+	// HeartbeatSLAFactor raises a HeartbeatMissed alert for a connected
+	// charger not heard from in this many heartbeat intervals; 0 disables it
+	HeartbeatSLAFactor     float64       `mapstructure:"heartbeat_sla_factor"`
+	HeartbeatAlertCooldown time.Duration `mapstructure:"heartbeat_alert_cooldown"`
+	// PostStopAvailableTimeout marks a connector Available this long after
+	// StopTransaction unless the charger reports an idle status first; 0 disables
+	PostStopAvailableTimeout time.Duration `mapstructure:"post_stop_available_timeout"`
+	// RejectUnknownIDTags refuses tags missing from id_tags as Invalid;
+	// AuthorizationCacheTTL is how long a tag's decision is reused, 0 disables
+	RejectUnknownIDTags   bool          `mapstructure:"reject_unknown_id_tags"`
+	AuthorizationCacheTTL time.Duration `mapstructure:"authorization_cache_ttl"`
+	// MaxConcurrentCommandsPerCharger caps operator-initiated commands in
+	// flight to one charger, queuing the rest; 0 is unlimited
+	MaxConcurrentCommandsPerCharger int `mapstructure:"max_concurrent_commands_per_charger"`
+	// AutocreateChargerOnTransaction creates a minimal charger row for a
+	// StartTransaction from a charger that never booted instead of refusing it
+	AutocreateChargerOnTransaction bool `mapstructure:"autocreate_charger_on_transaction"`
 }
 
+// DefaultChargerIDPattern is the charger id pattern used when none is configured
+const DefaultChargerIDPattern = `^[A-Za-z0-9_.\-:]{1,64}$`
+
+// Policies for a CALL that arrives while a previous one is unanswered
+const (
+	ConcurrentCallQueue  = "queue"
+	ConcurrentCallReject = "reject"
+)
+
+// Actions for MeterValues beyond max_meter_values_per_minute
+const (
+	MeterRateLimitDrop     = "drop"
+	MeterRateLimitThrottle = "throttle"
+)
+
+// Actions for charger timestamps skewed beyond max_clock_skew
+const (
+	ClockSkewSubstitute = "substitute"
+	ClockSkewFlag       = "flag"
+)
+
 // LogConfig holds logging configuration
 type LogConfig struct {
 	Level      string `mapstructure:"level"`
@@ -54,8 +149,83 @@ type LogConfig struct {
 
 // MonitoringConfig holds monitoring configuration
 type MonitoringConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Address string `mapstructure:"address"`
+	Enabled     bool   `mapstructure:"enabled"`
+	Address     string `mapstructure:"address"`
+	RequireAuth bool   `mapstructure:"require_auth"`
+}
+
+// RetentionConfig holds data retention configuration
+type RetentionConfig struct {
+	Enabled        bool                     `mapstructure:"enabled"`
+	Interval       time.Duration            `mapstructure:"interval"`
+	MeterValuesAge time.Duration            `mapstructure:"meter_values_age"`
+	ErrorsAge      time.Duration            `mapstructure:"errors_age"`
+	ConnectionsAge time.Duration            `mapstructure:"connections_age"`
+	MeasurandAges  map[string]time.Duration `mapstructure:"measurand_ages"`
+}
+
+// WebhooksConfig holds outbound webhook configuration
+type WebhooksConfig struct {
+	ConnectionURL string        `mapstructure:"connection_url"`
+	Secret        string        `mapstructure:"secret"`
+	MaxAttempts   int           `mapstructure:"max_attempts"`
+	RetryBackoff  time.Duration `mapstructure:"retry_backoff"`
+	PollInterval  time.Duration `mapstructure:"poll_interval"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+}
+
+// EventsConfig holds domain event outbox configuration
+type EventsConfig struct {
+	RelayInterval time.Duration `mapstructure:"relay_interval"`
+}
+
+// ReconciliationConfig holds meter value reconciliation configuration
+type ReconciliationConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Interval    time.Duration `mapstructure:"interval"`
+	GracePeriod time.Duration `mapstructure:"grace_period"`
+}
+
+// AuthConfig holds API key authentication configuration
+type AuthConfig struct {
+	AdminKeys         []string      `mapstructure:"admin_keys"`
+	OperatorKeys      []string      `mapstructure:"operator_keys"`
+	ConfirmOperations []string      `mapstructure:"confirm_operations"`
+	ConfirmTokenTTL   time.Duration `mapstructure:"confirm_token_ttl"`
+}
+
+// Enabled reports whether any API keys are configured
+func (a AuthConfig) Enabled() bool {
+	return len(a.AdminKeys) > 0 || len(a.OperatorKeys) > 0
+}
+
+// AuditConfig holds audit log configuration
+type AuditConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// ProvisioningConfig holds the configuration profiles pushed to chargers
+// after boot
+type ProvisioningConfig struct {
+	Profiles []ProvisioningProfile `mapstructure:"profiles"`
+}
+
+// ProvisioningProfile is a set of configuration keys pushed to chargers of a
+// vendor and model. Empty Vendor or Model match any charger; the first
+// matching profile applies. Bumping Version pushes the profile again.
+type ProvisioningProfile struct {
+	Name          string                `mapstructure:"name"`
+	Version       int                   `mapstructure:"version"`
+	Vendor        string                `mapstructure:"vendor"`
+	Model         string                `mapstructure:"model"`
+	Configuration []ProvisioningSetting `mapstructure:"configuration"`
+}
+
+// ProvisioningSetting is one configuration key of a provisioning profile. Keys
+// are listed rather than mapped because viper lowercases map keys.
+type ProvisioningSetting struct {
+	Key   string `mapstructure:"key"`
+	Value string `mapstructure:"value"`
 }
 
 // Load loads configuration from environment variables and config files
@@ -100,17 +270,50 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", "30s")
 	viper.SetDefault("server.write_timeout", "30s")
 	viper.SetDefault("server.max_header_bytes", 1<<20)
+	viper.SetDefault("server.request_timeout", "15s")
+	viper.SetDefault("server.cors.allowed_origins", []string{})
 
 	// Database defaults
 	viper.SetDefault("database.path", "./levity.db")
 	viper.SetDefault("database.max_open_conns", 25)
 	viper.SetDefault("database.max_idle_conns", 25)
 	viper.SetDefault("database.conn_max_lifetime", "5m")
+	viper.SetDefault("database.integer_energy", false)
+	viper.SetDefault("database.checkpoint_interval", "0s")
+	viper.SetDefault("database.ping_on_borrow", false)
+	viper.SetDefault("database.auto_migrate", true)
 
 	// OCPP defaults
 	viper.SetDefault("ocpp.heartbeat_interval", "60s")
 	viper.SetDefault("ocpp.max_message_size", 1024*1024) // 1MB
 	viper.SetDefault("ocpp.connection_timeout", "30s")
+	viper.SetDefault("ocpp.handshake_timeout", "10s")
+	viper.SetDefault("ocpp.stale_timeout", "180s")
+	viper.SetDefault("ocpp.time_zone", "UTC")
+	viper.SetDefault("ocpp.persisted_measurands", []string{})
+	viper.SetDefault("ocpp.start_dedup_window", "60s")
+	viper.SetDefault("ocpp.meter_flush_interval", "1s")
+	viper.SetDefault("ocpp.meter_flush_size", 100)
+	viper.SetDefault("ocpp.concurrent_call_policy", ConcurrentCallQueue)
+	viper.SetDefault("ocpp.meter_sample_interval", "0s")
+	viper.SetDefault("ocpp.trigger_meter_measurands", []string{})
+	viper.SetDefault("ocpp.charger_id_pattern", DefaultChargerIDPattern)
+	viper.SetDefault("ocpp.meter_start_fallback", false)
+	viper.SetDefault("ocpp.connection_auth_url", "")
+	viper.SetDefault("ocpp.connection_auth_timeout", "5s")
+	viper.SetDefault("ocpp.max_meter_values_per_minute", 0)
+	viper.SetDefault("ocpp.meter_rate_limit_action", MeterRateLimitDrop)
+	viper.SetDefault("ocpp.max_clock_skew", "0s")
+	viper.SetDefault("ocpp.clock_skew_action", ClockSkewFlag)
+	viper.SetDefault("ocpp.max_connectors_per_charger", 64)
+	viper.SetDefault("ocpp.stub_actions", map[string]string{})
+	viper.SetDefault("ocpp.heartbeat_sla_factor", 1.5)
+	viper.SetDefault("ocpp.heartbeat_alert_cooldown", "1h")
+	viper.SetDefault("ocpp.post_stop_available_timeout", "2m")
+	viper.SetDefault("ocpp.reject_unknown_id_tags", false)
+	viper.SetDefault("ocpp.autocreate_charger_on_transaction", false)
+	viper.SetDefault("ocpp.authorization_cache_ttl", "1m")
+	viper.SetDefault("ocpp.max_concurrent_commands_per_charger", 1)
 
 	// Log defaults
 	viper.SetDefault("log.level", "info")
@@ -122,6 +325,43 @@ func setDefaults() {
 	// Monitoring defaults
 	viper.SetDefault("monitoring.enabled", true)
 	viper.SetDefault("monitoring.address", ":9090")
+	viper.SetDefault("monitoring.require_auth", false)
+
+	// Retention defaults
+	viper.SetDefault("retention.enabled", true)
+	viper.SetDefault("retention.interval", "1h")
+	viper.SetDefault("retention.meter_values_age", "2160h") // 90 days
+	viper.SetDefault("retention.errors_age", "720h")        // 30 days
+	viper.SetDefault("retention.connections_age", "720h")   // 30 days
+	viper.SetDefault("retention.measurand_ages", map[string]string{})
+
+	// Webhook defaults
+	viper.SetDefault("webhooks.connection_url", "")
+	viper.SetDefault("webhooks.secret", "")
+	viper.SetDefault("webhooks.max_attempts", 10)
+	viper.SetDefault("webhooks.retry_backoff", "5s")
+	viper.SetDefault("webhooks.poll_interval", "5s")
+	viper.SetDefault("webhooks.timeout", "10s")
+
+	// Events defaults
+	viper.SetDefault("events.relay_interval", "1s")
+
+	// Reconciliation defaults
+	viper.SetDefault("reconciliation.enabled", true)
+	viper.SetDefault("reconciliation.interval", "5m")
+	viper.SetDefault("reconciliation.grace_period", "15m")
+
+	// Auth defaults
+	viper.SetDefault("auth.admin_keys", []string{})
+	viper.SetDefault("auth.operator_keys", []string{})
+	viper.SetDefault("auth.confirm_operations", []string{"reconcile_fix", "reset_connections"})
+	viper.SetDefault("auth.confirm_token_ttl", "2m")
+
+	// Audit defaults
+	viper.SetDefault("audit.enabled", false)
+
+	// Provisioning defaults
+	viper.SetDefault("provisioning.profiles", []ProvisioningProfile{})
 }
 
 func bindEnvVars() {
@@ -130,17 +370,49 @@ func bindEnvVars() {
 	viper.BindEnv("server.read_timeout", "SERVER_READ_TIMEOUT")
 	viper.BindEnv("server.write_timeout", "SERVER_WRITE_TIMEOUT")
 	viper.BindEnv("server.max_header_bytes", "SERVER_MAX_HEADER_BYTES")
+	viper.BindEnv("server.request_timeout", "SERVER_REQUEST_TIMEOUT")
+	viper.BindEnv("server.cors.allowed_origins", "SERVER_CORS_ALLOWED_ORIGINS")
 
 	// Database
 	viper.BindEnv("database.path", "DB_PATH")
 	viper.BindEnv("database.max_open_conns", "DB_MAX_OPEN_CONNS")
 	viper.BindEnv("database.max_idle_conns", "DB_MAX_IDLE_CONNS")
 	viper.BindEnv("database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME")
+	viper.BindEnv("database.integer_energy", "DB_INTEGER_ENERGY")
+	viper.BindEnv("database.checkpoint_interval", "DB_CHECKPOINT_INTERVAL")
+	viper.BindEnv("database.ping_on_borrow", "DB_PING_ON_BORROW")
+	viper.BindEnv("database.auto_migrate", "DB_AUTO_MIGRATE")
 
 	// OCPP
 	viper.BindEnv("ocpp.heartbeat_interval", "OCPP_HEARTBEAT_INTERVAL")
 	viper.BindEnv("ocpp.max_message_size", "OCPP_MAX_MESSAGE_SIZE")
 	viper.BindEnv("ocpp.connection_timeout", "OCPP_CONNECTION_TIMEOUT")
+	viper.BindEnv("ocpp.handshake_timeout", "OCPP_HANDSHAKE_TIMEOUT")
+	viper.BindEnv("ocpp.stale_timeout", "OCPP_STALE_TIMEOUT")
+	viper.BindEnv("ocpp.time_zone", "OCPP_TIME_ZONE")
+	viper.BindEnv("ocpp.persisted_measurands", "OCPP_PERSISTED_MEASURANDS")
+	viper.BindEnv("ocpp.start_dedup_window", "OCPP_START_DEDUP_WINDOW")
+	viper.BindEnv("ocpp.meter_flush_interval", "OCPP_METER_FLUSH_INTERVAL")
+	viper.BindEnv("ocpp.meter_flush_size", "OCPP_METER_FLUSH_SIZE")
+	viper.BindEnv("ocpp.concurrent_call_policy", "OCPP_CONCURRENT_CALL_POLICY")
+	viper.BindEnv("ocpp.meter_sample_interval", "OCPP_METER_SAMPLE_INTERVAL")
+	viper.BindEnv("ocpp.trigger_meter_measurands", "OCPP_TRIGGER_METER_MEASURANDS")
+	viper.BindEnv("ocpp.charger_id_pattern", "OCPP_CHARGER_ID_PATTERN")
+	viper.BindEnv("ocpp.meter_start_fallback", "OCPP_METER_START_FALLBACK")
+	viper.BindEnv("ocpp.connection_auth_url", "OCPP_CONNECTION_AUTH_URL")
+	viper.BindEnv("ocpp.connection_auth_timeout", "OCPP_CONNECTION_AUTH_TIMEOUT")
+	viper.BindEnv("ocpp.max_meter_values_per_minute", "OCPP_MAX_METER_VALUES_PER_MINUTE")
+	viper.BindEnv("ocpp.meter_rate_limit_action", "OCPP_METER_RATE_LIMIT_ACTION")
+	viper.BindEnv("ocpp.max_clock_skew", "OCPP_MAX_CLOCK_SKEW")
+	viper.BindEnv("ocpp.clock_skew_action", "OCPP_CLOCK_SKEW_ACTION")
+	viper.BindEnv("ocpp.max_connectors_per_charger", "OCPP_MAX_CONNECTORS_PER_CHARGER")
+	viper.BindEnv("ocpp.heartbeat_sla_factor", "OCPP_HEARTBEAT_SLA_FACTOR")
+	viper.BindEnv("ocpp.heartbeat_alert_cooldown", "OCPP_HEARTBEAT_ALERT_COOLDOWN")
+	viper.BindEnv("ocpp.post_stop_available_timeout", "OCPP_POST_STOP_AVAILABLE_TIMEOUT")
+	viper.BindEnv("ocpp.reject_unknown_id_tags", "OCPP_REJECT_UNKNOWN_ID_TAGS")
+	viper.BindEnv("ocpp.autocreate_charger_on_transaction", "OCPP_AUTOCREATE_CHARGER_ON_TRANSACTION")
+	viper.BindEnv("ocpp.authorization_cache_ttl", "OCPP_AUTHORIZATION_CACHE_TTL")
+	viper.BindEnv("ocpp.max_concurrent_commands_per_charger", "OCPP_MAX_CONCURRENT_COMMANDS_PER_CHARGER")
 
 	// Log
 	viper.BindEnv("log.level", "LOG_LEVEL")
@@ -152,6 +424,39 @@ func bindEnvVars() {
 	// Monitoring
 	viper.BindEnv("monitoring.enabled", "MONITORING_ENABLED")
 	viper.BindEnv("monitoring.address", "MONITORING_ADDRESS")
+	viper.BindEnv("monitoring.require_auth", "MONITORING_REQUIRE_AUTH")
+
+	// Retention
+	viper.BindEnv("retention.enabled", "RETENTION_ENABLED")
+	viper.BindEnv("retention.interval", "RETENTION_INTERVAL")
+	viper.BindEnv("retention.meter_values_age", "RETENTION_METER_VALUES_AGE")
+	viper.BindEnv("retention.errors_age", "RETENTION_ERRORS_AGE")
+	viper.BindEnv("retention.connections_age", "RETENTION_CONNECTIONS_AGE")
+
+	// Webhooks
+	viper.BindEnv("webhooks.connection_url", "WEBHOOKS_CONNECTION_URL")
+	viper.BindEnv("webhooks.secret", "WEBHOOKS_SECRET")
+	viper.BindEnv("webhooks.max_attempts", "WEBHOOKS_MAX_ATTEMPTS")
+	viper.BindEnv("webhooks.retry_backoff", "WEBHOOKS_RETRY_BACKOFF")
+	viper.BindEnv("webhooks.poll_interval", "WEBHOOKS_POLL_INTERVAL")
+	viper.BindEnv("webhooks.timeout", "WEBHOOKS_TIMEOUT")
+
+	// Events
+	viper.BindEnv("events.relay_interval", "EVENTS_RELAY_INTERVAL")
+
+	// Reconciliation
+	viper.BindEnv("reconciliation.enabled", "RECONCILIATION_ENABLED")
+	viper.BindEnv("reconciliation.interval", "RECONCILIATION_INTERVAL")
+	viper.BindEnv("reconciliation.grace_period", "RECONCILIATION_GRACE_PERIOD")
+
+	// Auth
+	viper.BindEnv("auth.admin_keys", "AUTH_ADMIN_KEYS")
+	viper.BindEnv("auth.operator_keys", "AUTH_OPERATOR_KEYS")
+	viper.BindEnv("auth.confirm_operations", "AUTH_CONFIRM_OPERATIONS")
+	viper.BindEnv("auth.confirm_token_ttl", "AUTH_CONFIRM_TOKEN_TTL")
+
+	// Audit
+	viper.BindEnv("audit.enabled", "AUDIT_ENABLED")
 }
 
 func validateConfig(config *Config) error {
@@ -179,12 +484,105 @@ func validateConfig(config *Config) error {
 	if config.Database.Path == "" {
 		return fmt.Errorf("database path cannot be empty")
 	}
+	if config.Database.CheckpointInterval < 0 {
+		return fmt.Errorf("database checkpoint_interval cannot be negative")
+	}
 
 	// Validate server address
 	if config.Server.Address == "" {
 		return fmt.Errorf("server address cannot be empty")
 	}
 
+	// Validate OCPP time zone
+	if _, err := time.LoadLocation(config.OCPP.TimeZone); err != nil {
+		return fmt.Errorf("invalid OCPP time zone: %s", config.OCPP.TimeZone)
+	}
+
+	// Validate OCPP concurrent call policy
+	switch config.OCPP.ConcurrentCallPolicy {
+	case ConcurrentCallQueue, ConcurrentCallReject:
+	default:
+		return fmt.Errorf("invalid OCPP concurrent call policy: %s", config.OCPP.ConcurrentCallPolicy)
+	}
+
+	// Validate OCPP meter value rate limit
+	if config.OCPP.MaxMeterValuesPerMinute < 0 {
+		return fmt.Errorf("OCPP max meter values per minute must not be negative")
+	}
+	switch config.OCPP.MeterRateLimitAction {
+	case MeterRateLimitDrop, MeterRateLimitThrottle:
+	default:
+		return fmt.Errorf("invalid OCPP meter rate limit action: %s", config.OCPP.MeterRateLimitAction)
+	}
+
+	// Validate OCPP clock skew handling
+	if config.OCPP.MaxClockSkew < 0 {
+		return fmt.Errorf("OCPP max clock skew must not be negative")
+	}
+	switch config.OCPP.ClockSkewAction {
+	case ClockSkewSubstitute, ClockSkewFlag:
+	default:
+		return fmt.Errorf("invalid OCPP clock skew action: %s", config.OCPP.ClockSkewAction)
+	}
+
+	// Validate OCPP connector limit
+	if config.OCPP.MaxConnectorsPerCharger < 0 {
+		return fmt.Errorf("OCPP max connectors per charger must not be negative")
+	}
+
+	// Validate OCPP heartbeat SLA
+	if config.OCPP.HeartbeatSLAFactor < 0 {
+		return fmt.Errorf("OCPP heartbeat SLA factor must not be negative")
+	}
+	if config.OCPP.HeartbeatAlertCooldown < 0 {
+		return fmt.Errorf("OCPP heartbeat alert cooldown must not be negative")
+	}
+
+	if config.OCPP.PostStopAvailableTimeout < 0 {
+		return fmt.Errorf("OCPP post-stop available timeout must not be negative")
+	}
+
+	if config.OCPP.AuthorizationCacheTTL < 0 {
+		return fmt.Errorf("OCPP authorization cache TTL must not be negative")
+	}
+
+	if config.OCPP.MaxConcurrentCommandsPerCharger < 0 {
+		return fmt.Errorf("OCPP max concurrent commands per charger must not be negative")
+	}
+
+	// Validate OCPP stub payloads
+	for action, payload := range config.OCPP.StubActions {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(payload), &object); err != nil {
+			return fmt.Errorf("invalid OCPP stub payload for %s: must be a JSON object", action)
+		}
+	}
+
+	// Validate OCPP charger id pattern
+	if _, err := regexp.Compile(config.OCPP.ChargerIDPattern); err != nil {
+		return fmt.Errorf("invalid OCPP charger id pattern: %w", err)
+	}
+
+	// Validate provisioning profiles
+	names := make(map[string]bool)
+	for _, profile := range config.Provisioning.Profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("provisioning profile name cannot be empty")
+		}
+		if names[profile.Name] {
+			return fmt.Errorf("duplicate provisioning profile: %s", profile.Name)
+		}
+		names[profile.Name] = true
+		if profile.Version < 1 {
+			return fmt.Errorf("provisioning profile %s version must be at least 1", profile.Name)
+		}
+		for _, setting := range profile.Configuration {
+			if setting.Key == "" {
+				return fmt.Errorf("provisioning profile %s has a setting without a key", profile.Name)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -175,3 +175,28 @@ func TestGetEnvDuration(t *testing.T) {
 	assert.Equal(t, 3*time.Minute, value)
 	os.Unsetenv("INVALID_DURATION")
 }
+
+func TestOCPPTimeZoneValidation(t *testing.T) {
+	os.Setenv("OCPP_TIME_ZONE", "Europe/Berlin")
+	config, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", config.OCPP.TimeZone)
+
+	os.Setenv("OCPP_TIME_ZONE", "Mars/Olympus_Mons")
+	defer os.Unsetenv("OCPP_TIME_ZONE")
+
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestOCPPChargerIDPatternValidation(t *testing.T) {
+	config, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultChargerIDPattern, config.OCPP.ChargerIDPattern)
+
+	os.Setenv("OCPP_CHARGER_ID_PATTERN", "^[A-Z")
+	defer os.Unsetenv("OCPP_CHARGER_ID_PATTERN")
+
+	_, err = Load()
+	assert.Error(t, err)
+}
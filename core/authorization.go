@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// IDTagAuthorizer decides whether an ID tag may start a transaction from the
+// id_tags table. Decisions are cached per tag for the configured TTL so
+// repeated starts with the same tag do not each read the table.
+type IDTagAuthorizer struct {
+	repos         db.RepositoryManager
+	acceptUnknown bool
+	ttl           time.Duration
+	clock         clock.Clock
+
+	mu    sync.Mutex
+	cache map[string]cachedAuthorization
+	swept time.Time
+}
+
+// cachedAuthorization is a cached decision for one ID tag
+type cachedAuthorization struct {
+	info    ocpp.IDTagInfo
+	expires time.Time
+}
+
+// NewIDTagAuthorizer creates an authorizer. Tags missing from id_tags are
+// accepted when acceptUnknown is set and Invalid otherwise; a ttl of 0
+// disables the cache.
+func NewIDTagAuthorizer(repos db.RepositoryManager, acceptUnknown bool, ttl time.Duration, clk clock.Clock) *IDTagAuthorizer {
+	return &IDTagAuthorizer{
+		repos:         repos,
+		acceptUnknown: acceptUnknown,
+		ttl:           ttl,
+		clock:         clk,
+		cache:         make(map[string]cachedAuthorization),
+	}
+}
+
+// Authorize returns the IDTagInfo for a presented ID tag. A known tag is
+// Expired past its expiry date and otherwise carries its stored status.
+func (a *IDTagAuthorizer) Authorize(ctx context.Context, idTag string) (ocpp.IDTagInfo, error) {
+	now := a.clock.Now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[idTag]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.info, nil
+	}
+
+	info, err := a.lookup(ctx, idTag, now)
+	if err != nil {
+		return ocpp.IDTagInfo{}, err
+	}
+
+	if a.ttl > 0 {
+		a.mu.Lock()
+		a.sweep(now)
+		a.cache[idTag] = cachedAuthorization{info: info, expires: now.Add(a.ttl)}
+		a.mu.Unlock()
+	}
+	return info, nil
+}
+
+// lookup reads a tag's decision from the id_tags table
+func (a *IDTagAuthorizer) lookup(ctx context.Context, idTag string, now time.Time) (ocpp.IDTagInfo, error) {
+	tag, err := a.repos.IDTags().GetByIDTag(ctx, idTag)
+	if errors.Is(err, db.ErrIDTagNotFound) {
+		if a.acceptUnknown {
+			return ocpp.IDTagInfo{Status: ocpp.AuthorizationAccepted}, nil
+		}
+		return ocpp.IDTagInfo{Status: ocpp.AuthorizationInvalid}, nil
+	}
+	if err != nil {
+		return ocpp.IDTagInfo{}, fmt.Errorf("failed to authorize ID tag: %w", err)
+	}
+
+	info := ocpp.IDTagInfo{Status: tag.Status, ExpiryDate: tag.ExpiryDate, ParentIDTag: tag.ParentIDTag}
+	if tag.ExpiryDate != nil && !now.Before(*tag.ExpiryDate) {
+		info.Status = ocpp.AuthorizationExpired
+	}
+	return info, nil
+}
+
+// sweep drops expired decisions, at most once per TTL, so tags that are not
+// presented again do not keep an entry. The caller must hold a.mu.
+func (a *IDTagAuthorizer) sweep(now time.Time) {
+	if now.Before(a.swept.Add(a.ttl)) {
+		return
+	}
+	for idTag, cached := range a.cache {
+		if !now.Before(cached.expires) {
+			delete(a.cache, idTag)
+		}
+	}
+	a.swept = now
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// ErrInvalidAvailability is returned when an availability change fails validation
+var ErrInvalidAvailability = errors.New("invalid availability change")
+
+// AvailabilityManager changes the availability of chargers and connectors
+type AvailabilityManager struct {
+	repos   db.RepositoryManager
+	sender  ocpp.Sender
+	metrics *monitoring.Metrics
+	logger  *slog.Logger
+}
+
+// NewAvailabilityManager creates a new availability manager
+func NewAvailabilityManager(repos db.RepositoryManager, sender ocpp.Sender, logger *slog.Logger) *AvailabilityManager {
+	return &AvailabilityManager{
+		repos:  repos,
+		sender: sender,
+		logger: logger,
+	}
+}
+
+// SetMetrics sets the metrics updated by availability changes
+func (m *AvailabilityManager) SetMetrics(metrics *monitoring.Metrics) {
+	m.metrics = metrics
+}
+
+// ChangeAvailability asks a charger to change the availability of a connector
+// and returns the charger's response status. Once accepted, connector 0
+// updates Charger.Status and cascades to every connector of the charger;
+// any other connector updates only itself. A Scheduled change is applied
+// when the charger reports it through StatusNotification.
+func (m *AvailabilityManager) ChangeAvailability(ctx context.Context, chargerID string, connectorID int, availabilityType string) (string, error) {
+	if err := ValidateAvailability(connectorID, availabilityType); err != nil {
+		return "", err
+	}
+	if m.sender == nil {
+		return "", ocpp.ErrNotConnected
+	}
+
+	var resp ocpp.ChangeAvailabilityResponse
+	req := ocpp.ChangeAvailabilityRequest{ConnectorID: connectorID, Type: availabilityType}
+	if err := m.sender.SendCall(ctx, chargerID, ocpp.ActionChangeAvailability, req, &resp); err != nil {
+		return "", fmt.Errorf("failed to change availability: %w", err)
+	}
+
+	if resp.Status != ocpp.AvailabilityStatusAccepted {
+		m.logger.Info("Availability change not applied",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", connectorID),
+			slog.String("status", resp.Status))
+		return resp.Status, nil
+	}
+
+	status := ocpp.ChargePointStatusAvailable
+	if availabilityType == ocpp.AvailabilityTypeInoperative {
+		status = ocpp.ChargePointStatusUnavailable
+	}
+
+	if connectorID != db.ChargePointConnectorID {
+		if err := m.repos.Connectors().UpdateStatus(ctx, chargerID, connectorID, status); err != nil {
+			return resp.Status, fmt.Errorf("failed to update connector status: %w", err)
+		}
+	} else if err := m.cascade(ctx, chargerID, status); err != nil {
+		return resp.Status, err
+	}
+
+	if err := syncConnectorsByStatus(ctx, m.repos, m.metrics); err != nil {
+		m.logger.Warn("Failed to sync connector metrics", slog.Any("error", err))
+	}
+	return resp.Status, nil
+}
+
+// ValidateAvailability checks the connector and type of an availability change
+func ValidateAvailability(connectorID int, availabilityType string) error {
+	if connectorID < 0 {
+		return fmt.Errorf("%w: invalid connector %d", ErrInvalidAvailability, connectorID)
+	}
+	if availabilityType != ocpp.AvailabilityTypeOperative && availabilityType != ocpp.AvailabilityTypeInoperative {
+		return fmt.Errorf("%w: unknown availability type %q", ErrInvalidAvailability, availabilityType)
+	}
+	return nil
+}
+
+// cascade applies a charge point status to the charger and all its connectors
+func (m *AvailabilityManager) cascade(ctx context.Context, chargerID, status string) error {
+	var updated int
+	err := WithTx(ctx, m.repos, m.metrics, "change_availability", func(tx db.TxManager) error {
+		if err := tx.Chargers().UpdateStatus(ctx, chargerID, status); err != nil {
+			return fmt.Errorf("failed to update charger status: %w", err)
+		}
+
+		var err error
+		updated, err = tx.Connectors().UpdateStatusAll(ctx, chargerID, status)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Changed charge point availability",
+		slog.String("charger_id", chargerID),
+		slog.String("status", status),
+		slog.Int("connectors", updated))
+	return nil
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAvailabilityFixture(t *testing.T, status string) (db.RepositoryManager, *fakeSender) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1", NumConnectors: 2})
+	require.NoError(t, err)
+	for connectorID := 1; connectorID <= 2; connectorID++ {
+		_, err = repos.Connectors().Create(ctx, "CP-1", connectorID)
+		require.NoError(t, err)
+	}
+
+	sender := &fakeSender{responses: map[string]interface{}{
+		ocpp.ActionChangeAvailability: ocpp.ChangeAvailabilityResponse{Status: status},
+	}}
+	return repos, sender
+}
+
+func connectorStatuses(t *testing.T, repos db.RepositoryManager) map[int]string {
+	connectors, err := repos.Connectors().GetByChargerID(context.Background(), "CP-1")
+	require.NoError(t, err)
+
+	statuses := map[int]string{}
+	for _, conn := range connectors {
+		statuses[conn.ConnectorID] = conn.Status
+	}
+	return statuses
+}
+
+func TestChangeAvailabilityConnectorZeroCascades(t *testing.T) {
+	ctx := context.Background()
+	repos, sender := newAvailabilityFixture(t, ocpp.AvailabilityStatusAccepted)
+	manager := NewAvailabilityManager(repos, sender, dbtest.Logger())
+
+	status, err := manager.ChangeAvailability(ctx, "CP-1", 0, ocpp.AvailabilityTypeInoperative)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AvailabilityStatusAccepted, status)
+
+	require.Len(t, sender.calls, 1)
+	assert.JSONEq(t, `{"connectorId": 0, "type": "Inoperative"}`, string(sender.calls[0].Payload))
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusUnavailable, charger.Status)
+	assert.Equal(t, map[int]string{
+		1: ocpp.ChargePointStatusUnavailable,
+		2: ocpp.ChargePointStatusUnavailable,
+	}, connectorStatuses(t, repos))
+
+	_, err = manager.ChangeAvailability(ctx, "CP-1", 0, ocpp.AvailabilityTypeOperative)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{
+		1: ocpp.ChargePointStatusAvailable,
+		2: ocpp.ChargePointStatusAvailable,
+	}, connectorStatuses(t, repos))
+}
+
+func TestChangeAvailabilitySingleConnector(t *testing.T) {
+	ctx := context.Background()
+	repos, sender := newAvailabilityFixture(t, ocpp.AvailabilityStatusAccepted)
+	manager := NewAvailabilityManager(repos, sender, dbtest.Logger())
+
+	_, err := manager.ChangeAvailability(ctx, "CP-1", 2, ocpp.AvailabilityTypeInoperative)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[int]string{
+		1: ocpp.ChargePointStatusAvailable,
+		2: ocpp.ChargePointStatusUnavailable,
+	}, connectorStatuses(t, repos))
+}
+
+func TestChangeAvailabilityScheduledLeavesStatus(t *testing.T) {
+	ctx := context.Background()
+	repos, sender := newAvailabilityFixture(t, ocpp.AvailabilityStatusScheduled)
+	manager := NewAvailabilityManager(repos, sender, dbtest.Logger())
+
+	status, err := manager.ChangeAvailability(ctx, "CP-1", 0, ocpp.AvailabilityTypeInoperative)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AvailabilityStatusScheduled, status)
+
+	assert.Equal(t, map[int]string{
+		1: ocpp.ChargePointStatusAvailable,
+		2: ocpp.ChargePointStatusAvailable,
+	}, connectorStatuses(t, repos))
+}
+
+func TestCommandHistoryRecordsResult(t *testing.T) {
+	ctx := db.WithActor(context.Background(), "operator:1a2b3c4d")
+	repos, sender := newAvailabilityFixture(t, ocpp.AvailabilityStatusRejected)
+	system := &System{repos: repos, sender: sender, clock: clock.Real(), logger: dbtest.Logger()}
+
+	status, err := system.Availability().ChangeAvailability(ctx, "CP-1", 1, ocpp.AvailabilityTypeInoperative)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AvailabilityStatusRejected, status)
+
+	sender.err = ocpp.ErrNotConnected
+	_, err = system.LocalLists().GetLocalListVersion(ctx, "CP-1")
+	require.Error(t, err)
+
+	entries, err := repos.Commands().ListByCharger(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, ocpp.ActionGetLocalListVersion, entries[0].Action)
+	assert.Equal(t, db.CommandStatusError, entries[0].Status)
+	assert.Equal(t, ocpp.ErrNotConnected.Error(), entries[0].Error)
+
+	assert.Equal(t, ocpp.ActionChangeAvailability, entries[1].Action)
+	assert.Equal(t, ocpp.AvailabilityStatusRejected, entries[1].Status)
+	assert.Equal(t, "operator:1a2b3c4d", entries[1].Operator)
+	assert.JSONEq(t, `{"connectorId": 1, "type": "Inoperative"}`, string(entries[1].Params))
+}
+
+func TestCommandHistoryCountsCallErrors(t *testing.T) {
+	ctx := context.Background()
+	repos, sender := newAvailabilityFixture(t, ocpp.AvailabilityStatusAccepted)
+	metrics := monitoring.NewMetrics()
+	system := &System{repos: repos, sender: sender, metrics: metrics, clock: clock.Real(), logger: dbtest.Logger()}
+
+	sender.err = &ocpp.CallError{Code: ocpp.ErrorCodeSecurityError, Description: "Not allowed"}
+	_, err := system.Availability().ChangeAvailability(ctx, "CP-1", 1, ocpp.AvailabilityTypeInoperative)
+	require.Error(t, err)
+
+	// Codes outside the OCPP set are bucketed rather than labelled as sent
+	sender.err = &ocpp.CallError{Code: "VendorSpecificFailure"}
+	_, err = system.Availability().ChangeAvailability(ctx, "CP-1", 1, ocpp.AvailabilityTypeInoperative)
+	require.Error(t, err)
+
+	// Failures that are not CALLERRORs are not counted
+	sender.err = ocpp.ErrNotConnected
+	_, err = system.Availability().ChangeAvailability(ctx, "CP-1", 1, ocpp.AvailabilityTypeInoperative)
+	require.Error(t, err)
+
+	expected := `
+# HELP ocpp_call_errors_total Total number of CALLERRORs sent to or received from charge points, by action and error code
+# TYPE ocpp_call_errors_total counter
+ocpp_call_errors_total{action="ChangeAvailability",error_code="Other"} 1
+ocpp_call_errors_total{action="ChangeAvailability",error_code="SecurityError"} 1
+`
+	err = testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_call_errors_total")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// CheckpointJob runs passive WAL checkpoints on a timer in place of SQLite's
+// automatic checkpoints, so a write that crosses the autocheckpoint threshold
+// does not pay for the whole checkpoint itself
+type CheckpointJob struct {
+	interval time.Duration
+	database *db.Database
+	metrics  *monitoring.Metrics
+	logger   *slog.Logger
+}
+
+// NewCheckpointJob creates a new checkpoint job. metrics may be nil.
+func NewCheckpointJob(interval time.Duration, database *db.Database, metrics *monitoring.Metrics, logger *slog.Logger) *CheckpointJob {
+	return &CheckpointJob{
+		interval: interval,
+		database: database,
+		metrics:  metrics,
+		logger:   logger,
+	}
+}
+
+// RunOnce runs a passive checkpoint and records its duration and the WAL size
+func (j *CheckpointJob) RunOnce(ctx context.Context) (db.WALCheckpointResult, error) {
+	start := time.Now()
+	result, err := j.database.PassiveWALCheckpoint(ctx)
+	if err != nil {
+		return result, err
+	}
+	duration := time.Since(start)
+
+	walSize, err := j.database.WALSize()
+	if err != nil {
+		return result, err
+	}
+
+	if j.metrics != nil {
+		j.metrics.RecordDatabaseCheckpoint(duration.Seconds(), walSize)
+	}
+
+	j.logger.Debug("WAL checkpoint completed",
+		slog.Bool("busy", result.Busy),
+		slog.Int("log_frames", result.LogFrames),
+		slog.Int("checkpointed_frames", result.CheckpointedFrames),
+		slog.Int64("wal_size", walSize),
+		slog.Duration("duration", duration))
+
+	return result, nil
+}
+
+// Run executes the checkpoint job on the configured interval until the context is cancelled
+func (j *CheckpointJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := j.RunOnce(ctx); err != nil {
+				j.logger.Error("WAL checkpoint failed", slog.Any("error", err))
+			}
+		}
+	}
+}
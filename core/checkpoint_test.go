@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointJobCheckpointsWAL(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DatabaseConfig{
+		Path:               filepath.Join(t.TempDir(), "levity.db"),
+		MaxOpenConns:       4,
+		MaxIdleConns:       4,
+		CheckpointInterval: 10 * time.Millisecond,
+	}
+	database, err := db.NewDatabase(cfg, dbtest.Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	require.NoError(t, database.RunMigrations())
+
+	repos := db.NewRepositoryManager(database, dbtest.Logger(), clock.Real())
+	for i := 0; i < 50; i++ {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: fmt.Sprintf("CP-%d", i)})
+		require.NoError(t, err)
+	}
+
+	metrics := monitoring.NewMetrics()
+	job := NewCheckpointJob(cfg.CheckpointInterval, database, metrics, dbtest.Logger())
+
+	// With autocheckpoint off the writes stay in the WAL until the job runs
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		job.Run(runCtx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		return testutil.CollectAndCount(metrics.Registry(), "database_checkpoint_duration_seconds") == 1
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	result, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.False(t, result.Busy)
+	assert.Greater(t, result.LogFrames, 0)
+	assert.Equal(t, result.LogFrames, result.CheckpointedFrames)
+
+	// A fully checkpointed WAL is started over by the next writer
+	_, err = repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-LAST"})
+	require.NoError(t, err)
+	next, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Less(t, next.LogFrames, result.LogFrames)
+	assert.Equal(t, next.LogFrames, next.CheckpointedFrames)
+}
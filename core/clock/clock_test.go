@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+	assert.Equal(t, start, fake.Now())
+
+	fake.Advance(90 * time.Second)
+	assert.Equal(t, start.Add(90*time.Second), fake.Now())
+
+	later := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	fake.Set(later)
+	assert.Equal(t, later, fake.Now())
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	fake := NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	ch := fake.After(time.Minute)
+
+	fake.Advance(30 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("After fired before its deadline")
+	default:
+	}
+
+	fake.Advance(30 * time.Second)
+	select {
+	case fired := <-ch:
+		assert.Equal(t, fake.Now(), fired)
+	default:
+		t.Fatal("After did not fire at its deadline")
+	}
+
+	// A non-positive duration fires immediately
+	select {
+	case <-fake.After(0):
+	default:
+		t.Fatal("After(0) did not fire")
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	before := time.Now()
+	now := Real().Now()
+	assert.False(t, now.Before(before))
+}
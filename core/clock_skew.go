@@ -0,0 +1,46 @@
+package core
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/keeth/levity/config"
+)
+
+// checkClockSkew compares a charger-supplied timestamp with server time and
+// records the skew. When the skew exceeds ocpp.max_clock_skew, the substitute
+// action returns server time in its place and the flag action keeps the
+// timestamp and returns the skew for the caller to flag its record; otherwise
+// the timestamp is returned with a nil skew. A zero timestamp, meaning the
+// charger sent none, is returned unchanged.
+func (h *OCPPHandler) checkClockSkew(chargerID, action string, timestamp time.Time) (time.Time, *time.Duration) {
+	if timestamp.IsZero() {
+		return timestamp, nil
+	}
+
+	now := h.clock.Now()
+	skew := timestamp.Sub(now)
+	if h.metrics != nil {
+		h.metrics.SetClockSkew(chargerID, skew.Seconds())
+	}
+
+	if h.config.MaxClockSkew <= 0 || absDuration(skew) <= h.config.MaxClockSkew {
+		return timestamp, nil
+	}
+
+	if h.config.ClockSkewAction == config.ClockSkewSubstitute {
+		h.logger.Warn("Replacing skewed charger timestamp with server time",
+			slog.String("charger_id", chargerID),
+			slog.String("action", action),
+			slog.Time("timestamp", timestamp),
+			slog.Duration("skew", skew))
+		return now, nil
+	}
+
+	h.logger.Warn("Charger timestamp exceeds the maximum clock skew",
+		slog.String("charger_id", chargerID),
+		slog.String("action", action),
+		slog.Time("timestamp", timestamp),
+		slog.Duration("skew", skew))
+	return timestamp, &skew
+}
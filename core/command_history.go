@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// historySender records every command sent through it in the command history,
+// and counts the CALLERRORs chargers answer with. Failing to record is logged
+// rather than returned, since the command itself has already been sent.
+type historySender struct {
+	next    ocpp.Sender
+	repo    db.CommandHistoryRepository
+	metrics *monitoring.Metrics
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+// newHistorySender wraps a sender so that its commands are recorded. metrics may be nil.
+func newHistorySender(next ocpp.Sender, repo db.CommandHistoryRepository, metrics *monitoring.Metrics, clk clock.Clock, logger *slog.Logger) *historySender {
+	return &historySender{
+		next:    next,
+		repo:    repo,
+		metrics: metrics,
+		clock:   clk,
+		logger:  logger,
+	}
+}
+
+// SendCall implements ocpp.Sender.SendCall
+func (s *historySender) SendCall(ctx context.Context, chargerID string, action string, request interface{}, response interface{}) error {
+	start := s.clock.Now()
+	err := s.next.SendCall(ctx, chargerID, action, request, response)
+
+	entry := db.CreateCommandHistoryRequest{
+		ChargerID: chargerID,
+		Action:    action,
+		Status:    commandResultStatus(response),
+		Latency:   s.clock.Now().Sub(start),
+	}
+	if err != nil {
+		entry.Status = db.CommandStatusError
+		entry.Error = err.Error()
+
+		var callErr *ocpp.CallError
+		if errors.As(err, &callErr) && s.metrics != nil {
+			s.metrics.RecordCallError(ocpp.ActionLabel(action), ocpp.ErrorCodeLabel(callErr.Code))
+		}
+	}
+	if params, merr := json.Marshal(request); merr == nil {
+		entry.Params = params
+	}
+
+	// The command may have timed out; the history entry is written regardless
+	if _, rerr := s.repo.Record(context.WithoutCancel(ctx), entry); rerr != nil {
+		s.logger.Error("Failed to record command history",
+			slog.String("charger_id", chargerID),
+			slog.String("action", action),
+			slog.Any("error", rerr))
+	}
+
+	return err
+}
+
+// commandResultStatus returns the status reported in a command response, or
+// Completed for responses that carry none
+func commandResultStatus(response interface{}) string {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return db.CommandStatusCompleted
+	}
+
+	var result struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil || result.Status == "" {
+		return db.CommandStatusCompleted
+	}
+	return result.Status
+}
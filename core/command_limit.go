@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"sync"
+
+	"github.com/keeth/levity/core/ocpp"
+)
+
+// CommandLimiter caps how many operator-initiated commands may be in flight
+// to each charger at once, so a bulk operation cannot flood one charger.
+// Commands beyond the limit wait for a slot.
+type CommandLimiter struct {
+	limit int
+
+	mu       sync.Mutex
+	chargers map[string]*commandSlots
+}
+
+// commandSlots is the semaphore of one charger. users counts the commands
+// holding or waiting for a slot, so idle chargers do not keep an entry.
+type commandSlots struct {
+	sem   chan struct{}
+	users int
+}
+
+// NewCommandLimiter creates a limiter allowing limit commands per charger
+func NewCommandLimiter(limit int) *CommandLimiter {
+	return &CommandLimiter{
+		limit:    limit,
+		chargers: make(map[string]*commandSlots),
+	}
+}
+
+// Acquire blocks until the charger has a free slot and returns the function
+// that frees it again. It returns early with the context's error.
+func (l *CommandLimiter) Acquire(ctx context.Context, chargerID string) (func(), error) {
+	l.mu.Lock()
+	slots, ok := l.chargers[chargerID]
+	if !ok {
+		slots = &commandSlots{sem: make(chan struct{}, l.limit)}
+		l.chargers[chargerID] = slots
+	}
+	slots.users++
+	l.mu.Unlock()
+
+	select {
+	case slots.sem <- struct{}{}:
+	case <-ctx.Done():
+		l.leave(chargerID, slots)
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-slots.sem
+		l.leave(chargerID, slots)
+	}, nil
+}
+
+// leave drops a command from the charger's users, and the charger's entry
+// once nothing holds or waits for a slot
+func (l *CommandLimiter) leave(chargerID string, slots *commandSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots.users--
+	if slots.users == 0 {
+		delete(l.chargers, chargerID)
+	}
+}
+
+// limitedSender sends each command once the charger has a free slot
+type limitedSender struct {
+	next    ocpp.Sender
+	limiter *CommandLimiter
+}
+
+// newLimitedSender wraps a sender so that its commands are limited per charger
+func newLimitedSender(next ocpp.Sender, limiter *CommandLimiter) *limitedSender {
+	return &limitedSender{
+		next:    next,
+		limiter: limiter,
+	}
+}
+
+// SendCall implements ocpp.Sender.SendCall
+func (s *limitedSender) SendCall(ctx context.Context, chargerID string, action string, request interface{}, response interface{}) error {
+	release, err := s.limiter.Acquire(ctx, chargerID)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.next.SendCall(ctx, chargerID, action, request, response)
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedSender holds every command until released and tracks how many are in
+// flight per charger
+type gatedSender struct {
+	release chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[string]int
+	peak     map[string]int
+	sent     atomic.Int32
+}
+
+func newGatedSender() *gatedSender {
+	return &gatedSender{
+		release:  make(chan struct{}),
+		inFlight: make(map[string]int),
+		peak:     make(map[string]int),
+	}
+}
+
+func (s *gatedSender) SendCall(ctx context.Context, chargerID, action string, request, response interface{}) error {
+	s.mu.Lock()
+	s.inFlight[chargerID]++
+	s.peak[chargerID] = max(s.peak[chargerID], s.inFlight[chargerID])
+	s.mu.Unlock()
+	s.sent.Add(1)
+
+	<-s.release
+
+	s.mu.Lock()
+	s.inFlight[chargerID]--
+	s.mu.Unlock()
+	return nil
+}
+
+func TestCommandLimiterSerializesCommandsPerCharger(t *testing.T) {
+	ctx := context.Background()
+	next := newGatedSender()
+	limiter := NewCommandLimiter(2)
+	sender := newLimitedSender(next, limiter)
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, sender.SendCall(ctx, "CP-1", "Reset", struct{}{}, &struct{}{}))
+		}()
+	}
+
+	// A busy charger does not hold up commands to another one
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, sender.SendCall(ctx, "CP-2", "Reset", struct{}{}, &struct{}{}))
+	}()
+
+	require.Eventually(t, func() bool { return next.sent.Load() == 3 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(t, 3, next.sent.Load(), "commands beyond the limit must wait")
+
+	// Queued commands go out as earlier ones finish
+	for range 7 {
+		next.release <- struct{}{}
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 7, next.sent.Load())
+	assert.Equal(t, 2, next.peak["CP-1"])
+	assert.Equal(t, 1, next.peak["CP-2"])
+	assert.Empty(t, limiter.chargers)
+}
+
+func TestCommandLimiterWaitHonorsContext(t *testing.T) {
+	limiter := NewCommandLimiter(1)
+	release, err := limiter.Acquire(context.Background(), "CP-1")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx, "CP-1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	assert.Empty(t, limiter.chargers)
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// supportedCommands are the Central System initiated actions that can be sent to chargers
+var supportedCommands = map[string]bool{
+	ocpp.ActionChangeAvailability:     true,
+	ocpp.ActionChangeConfiguration:    true,
+	ocpp.ActionGetConfiguration:       true,
+	ocpp.ActionGetLocalListVersion:    true,
+	ocpp.ActionGetLog:                 true,
+	ocpp.ActionRemoteStartTransaction: true,
+	ocpp.ActionRemoteStopTransaction:  true,
+	ocpp.ActionReset:                  true,
+	ocpp.ActionSendLocalList:          true,
+	ocpp.ActionSignedUpdateFirmware:   true,
+	ocpp.ActionTriggerMessage:         true,
+}
+
+// CommandTarget reports whether a command could be delivered to a charger
+type CommandTarget struct {
+	ChargerID string `json:"charger_id"`
+	Found     bool   `json:"found"`
+	Online    bool   `json:"online"`
+	Supported bool   `json:"supported"`
+}
+
+// CommandValidator resolves the targets of a remote command without sending it
+type CommandValidator struct {
+	repos    db.RepositoryManager
+	registry ConnectionRegistry
+}
+
+// NewCommandValidator creates a new command validator. registry may be nil,
+// in which case the stored connection state is used.
+func NewCommandValidator(repos db.RepositoryManager, registry ConnectionRegistry) *CommandValidator {
+	return &CommandValidator{
+		repos:    repos,
+		registry: registry,
+	}
+}
+
+// Validate reports, for each charger, whether it exists, is online and
+// supports the action. Nothing is sent to the chargers.
+func (v *CommandValidator) Validate(ctx context.Context, action string, chargerIDs []string) []CommandTarget {
+	targets := make([]CommandTarget, 0, len(chargerIDs))
+	for _, chargerID := range chargerIDs {
+		target := CommandTarget{ChargerID: chargerID}
+
+		charger, err := v.repos.Chargers().GetByID(ctx, chargerID)
+		if err == nil {
+			target.Found = true
+			target.Supported = supportedCommands[action]
+			if v.registry != nil {
+				target.Online = v.registry.IsConnected(chargerID)
+			} else {
+				target.Online = charger.IsConnected
+			}
+		}
+
+		targets = append(targets, target)
+	}
+	return targets
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/keeth/levity/core/ocpp"
+)
+
+// ConfigurationResult is a charger's configuration assembled from one or more
+// GetConfiguration calls
+type ConfigurationResult struct {
+	Keys        []ocpp.KeyValue `json:"keys"`
+	UnknownKeys []string        `json:"unknown_keys"`
+	// MissingKeys were expected but the charger neither returned nor
+	// reported them as unknown, even when asked for directly
+	MissingKeys []string `json:"missing_keys"`
+	// Calls is the number of GetConfiguration calls made
+	Calls int `json:"calls"`
+}
+
+// ConfigurationReader reads charger configuration, following up on truncated
+// GetConfiguration responses
+type ConfigurationReader struct {
+	sender  ocpp.Sender
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewConfigurationReader creates a configuration reader. Each call has the
+// timeout to be answered; 0 waits for the caller's context.
+func NewConfigurationReader(sender ocpp.Sender, timeout time.Duration, logger *slog.Logger) *ConfigurationReader {
+	return &ConfigurationReader{
+		sender:  sender,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// Get returns the given keys of a charger, or all of them when keys is empty.
+//
+// Some chargers silently truncate large responses. The response is checked
+// against the keys it should contain, the requested keys or, for all keys,
+// the ones the Core profile requires, and the missing ones are asked for in
+// follow-up calls of at most GetConfigurationMaxKeys keys.
+func (r *ConfigurationReader) Get(ctx context.Context, chargerID string, keys []string) (*ConfigurationResult, error) {
+	result := &ConfigurationResult{Keys: []ocpp.KeyValue{}, UnknownKeys: []string{}, MissingKeys: []string{}}
+	seen := make(map[string]bool)
+
+	first, err := r.call(ctx, chargerID, keys, result, seen)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := keys
+	if len(expected) == 0 {
+		expected = ocpp.CoreConfigurationKeys
+	}
+	pending := unanswered(expected, seen)
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	returned := len(first.ConfigurationKey) + len(first.UnknownKey)
+	chunk := r.chunkSize(result.Keys, returned, len(pending))
+	r.logger.Warn("GetConfiguration response truncated, requesting missing keys",
+		slog.String("charger_id", chargerID),
+		slog.Int("expected", len(expected)),
+		slog.Int("returned", returned),
+		slog.Int("missing", len(pending)),
+		slog.Int("chunk", chunk))
+
+	// Every follow-up either answers a key or gives up on its whole batch,
+	// so a charger that never answers cannot keep this looping
+	for len(pending) > 0 {
+		batch := pending[:min(chunk, len(pending))]
+		if _, err := r.call(ctx, chargerID, batch, result, seen); err != nil {
+			return nil, err
+		}
+
+		remaining := unanswered(batch, seen)
+		rest := pending[len(batch):]
+		if len(remaining) == len(batch) {
+			result.MissingKeys = append(result.MissingKeys, batch...)
+			pending = rest
+			continue
+		}
+		if len(remaining) > 0 {
+			// The charger answers fewer keys at once than it claims
+			chunk = len(batch) - len(remaining)
+		}
+		pending = append(remaining, rest...)
+	}
+
+	if len(result.MissingKeys) > 0 {
+		r.logger.Warn("Charger did not return configuration keys",
+			slog.String("charger_id", chargerID),
+			slog.Any("keys", result.MissingKeys))
+	}
+	return result, nil
+}
+
+// call makes one GetConfiguration call and merges its answer into result
+func (r *ConfigurationReader) call(ctx context.Context, chargerID string, keys []string, result *ConfigurationResult, seen map[string]bool) (*ocpp.GetConfigurationResponse, error) {
+	var resp ocpp.GetConfigurationResponse
+	req := ocpp.GetConfigurationRequest{Key: keys}
+	if err := sendWithTimeout(ctx, r.sender, r.timeout, chargerID, ocpp.ActionGetConfiguration, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get configuration: %w", err)
+	}
+	result.Calls++
+
+	for _, kv := range resp.ConfigurationKey {
+		if !seen[kv.Key] {
+			seen[kv.Key] = true
+			result.Keys = append(result.Keys, kv)
+		}
+	}
+	for _, key := range resp.UnknownKey {
+		if !seen[key] {
+			seen[key] = true
+			result.UnknownKeys = append(result.UnknownKeys, key)
+		}
+	}
+	return &resp, nil
+}
+
+// chunkSize is how many keys a follow-up asks for: the charger's
+// GetConfigurationMaxKeys when it reported one, else as many as its truncated
+// response held
+func (r *ConfigurationReader) chunkSize(keys []ocpp.KeyValue, returned, pending int) int {
+	for _, kv := range keys {
+		if kv.Key != ocpp.ConfigGetConfigurationMaxKeys || kv.Value == nil {
+			continue
+		}
+		if maxKeys, err := strconv.Atoi(*kv.Value); err == nil && maxKeys > 0 {
+			return maxKeys
+		}
+	}
+	if returned > 0 {
+		return returned
+	}
+	return pending
+}
+
+// unanswered returns the keys not in seen, in order
+func unanswered(keys []string, seen map[string]bool) []string {
+	var missing []string
+	for _, key := range keys {
+		if !seen[key] {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
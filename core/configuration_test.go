@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// truncatingCharger answers GetConfiguration with at most limit keys, like
+// chargers that silently cut large responses short. Keys in broken are never
+// returned, not even as unknown.
+type truncatingCharger struct {
+	keys     []ocpp.KeyValue
+	limit    int
+	broken   map[string]bool
+	requests [][]string
+}
+
+func (c *truncatingCharger) SendCall(ctx context.Context, chargerID, action string, request, response interface{}) error {
+	req := request.(ocpp.GetConfigurationRequest)
+	c.requests = append(c.requests, req.Key)
+
+	resp := response.(*ocpp.GetConfigurationResponse)
+	answer := func(kv ocpp.KeyValue) bool {
+		if len(resp.ConfigurationKey)+len(resp.UnknownKey) == c.limit {
+			return false
+		}
+		resp.ConfigurationKey = append(resp.ConfigurationKey, kv)
+		return true
+	}
+
+	if len(req.Key) == 0 {
+		for _, kv := range c.keys {
+			if !c.broken[kv.Key] && !answer(kv) {
+				break
+			}
+		}
+		return nil
+	}
+
+	for _, key := range req.Key {
+		if c.broken[key] {
+			continue
+		}
+		found := false
+		for _, kv := range c.keys {
+			if kv.Key == key {
+				found = true
+				answer(kv)
+			}
+		}
+		if !found && len(resp.ConfigurationKey)+len(resp.UnknownKey) < c.limit {
+			resp.UnknownKey = append(resp.UnknownKey, key)
+		}
+	}
+	return nil
+}
+
+func newTruncatingCharger(limit int) *truncatingCharger {
+	charger := &truncatingCharger{limit: limit, broken: map[string]bool{}}
+	// The vendor key comes first, pushing Core keys out of a truncated response
+	vendor := "on"
+	charger.keys = append(charger.keys, ocpp.KeyValue{Key: "VendorFeature", Value: &vendor})
+	for i, key := range ocpp.CoreConfigurationKeys {
+		value := strconv.Itoa(i)
+		if key == ocpp.ConfigGetConfigurationMaxKeys {
+			value = "4"
+		}
+		charger.keys = append(charger.keys, ocpp.KeyValue{Key: key, Readonly: true, Value: &value})
+	}
+	return charger
+}
+
+func configurationKeys(result *ConfigurationResult) []string {
+	keys := make([]string, 0, len(result.Keys))
+	for _, kv := range result.Keys {
+		keys = append(keys, kv.Key)
+	}
+	return keys
+}
+
+func TestConfigurationFollowsUpTruncatedResponse(t *testing.T) {
+	charger := newTruncatingCharger(6)
+	reader := NewConfigurationReader(charger, 0, dbtest.Logger())
+
+	result, err := reader.Get(context.Background(), "CP-1", nil)
+	require.NoError(t, err)
+
+	// The first response held the vendor key and five Core keys, including
+	// GetConfigurationMaxKeys, so the other 16 Core keys follow in batches of 4
+	assert.Len(t, result.Keys, len(ocpp.CoreConfigurationKeys)+1)
+	assert.ElementsMatch(t, append([]string{"VendorFeature"}, ocpp.CoreConfigurationKeys...), configurationKeys(result))
+	assert.Empty(t, result.UnknownKeys)
+	assert.Empty(t, result.MissingKeys)
+	assert.Equal(t, 5, result.Calls)
+
+	require.Len(t, charger.requests, 5)
+	assert.Empty(t, charger.requests[0])
+	for _, follow := range charger.requests[1:] {
+		assert.Len(t, follow, 4)
+	}
+}
+
+func TestConfigurationFollowsUpRequestedKeys(t *testing.T) {
+	charger := newTruncatingCharger(2)
+	charger.broken["HeartbeatInterval"] = true
+	reader := NewConfigurationReader(charger, 0, dbtest.Logger())
+
+	keys := []string{"NumberOfConnectors", "HeartbeatInterval", "NoSuchKey", "ResetRetries", "VendorFeature"}
+	result, err := reader.Get(context.Background(), "CP-1", keys)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"NumberOfConnectors", "ResetRetries", "VendorFeature"}, configurationKeys(result))
+	assert.Equal(t, []string{"NoSuchKey"}, result.UnknownKeys)
+	// A key the charger never answers is reported instead of asked for forever
+	assert.Equal(t, []string{"HeartbeatInterval"}, result.MissingKeys)
+	assert.Equal(t, []string{"HeartbeatInterval", "ResetRetries"}, charger.requests[1])
+}
+
+func TestConfigurationCompleteResponseMakesOneCall(t *testing.T) {
+	charger := newTruncatingCharger(100)
+	reader := NewConfigurationReader(charger, 0, dbtest.Logger())
+
+	result, err := reader.Get(context.Background(), "CP-1", nil)
+	require.NoError(t, err)
+	assert.Len(t, result.Keys, len(ocpp.CoreConfigurationKeys)+1)
+	assert.Equal(t, 1, result.Calls)
+
+	_, err = NewConfigurationReader(nil, 0, dbtest.Logger()).Get(context.Background(), "CP-1", nil)
+	assert.ErrorIs(t, err, ocpp.ErrNotConnected)
+}
@@ -0,0 +1,25 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// syncConnectorsByStatus sets connectors_by_status from the stored connector
+// statuses. StatusNotification moves the gauge one transition at a time; this
+// seeds it at startup and corrects it after changes that bypass the handler.
+func syncConnectorsByStatus(ctx context.Context, repos db.RepositoryManager, metrics *monitoring.Metrics) error {
+	if metrics == nil {
+		return nil
+	}
+
+	counts, err := repos.Connectors().CountByStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to sync connectors by status: %w", err)
+	}
+	metrics.SetConnectorsByStatus(counts)
+	return nil
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// stopReasonReconciled is recorded on transactions closed by a consistency fix
+const stopReasonReconciled = "Other"
+
+// ConnectionRegistry reports whether a charger has a live OCPP socket
+type ConnectionRegistry interface {
+	IsConnected(chargerID string) bool
+}
+
+// ConnectorRef identifies a single connector
+type ConnectorRef struct {
+	ChargerID   string `json:"charger_id"`
+	ConnectorID int    `json:"connector_id"`
+}
+
+// TransactionRef identifies a transaction and its connector
+type TransactionRef struct {
+	ID            int    `json:"id"`
+	TransactionID *int   `json:"transaction_id"`
+	ChargerID     string `json:"charger_id"`
+	ConnectorID   int    `json:"connector_id"`
+}
+
+// ConsistencyReport lists the inconsistencies found by a consistency check
+type ConsistencyReport struct {
+	ChargingWithoutTransaction []ConnectorRef   `json:"charging_without_transaction"`
+	TransactionsNotCharging    []TransactionRef `json:"transactions_not_charging"`
+	ConnectedWithoutSocket     []string         `json:"connected_without_socket"`
+	SocketCheckSkipped         bool             `json:"socket_check_skipped"`
+	Fixed                      bool             `json:"fixed"`
+}
+
+// ConsistencyChecker detects and optionally repairs connector, transaction
+// and connection state that disagree with each other
+type ConsistencyChecker struct {
+	repos    db.RepositoryManager
+	registry ConnectionRegistry
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewConsistencyChecker creates a new consistency checker. registry may be nil,
+// in which case the live socket check is skipped.
+func NewConsistencyChecker(repos db.RepositoryManager, registry ConnectionRegistry, clk clock.Clock, logger *slog.Logger) *ConsistencyChecker {
+	return &ConsistencyChecker{
+		repos:    repos,
+		registry: registry,
+		clock:    clk,
+		logger:   logger,
+	}
+}
+
+// Check scans for inconsistencies and, when fix is set, repairs them:
+// charging connectors without a transaction are set Available, active
+// transactions on idle connectors are stopped, and chargers without a
+// live socket are marked disconnected
+func (c *ConsistencyChecker) Check(ctx context.Context, fix bool) (*ConsistencyReport, error) {
+	report := &ConsistencyReport{
+		ChargingWithoutTransaction: []ConnectorRef{},
+		TransactionsNotCharging:    []TransactionRef{},
+		ConnectedWithoutSocket:     []string{},
+	}
+
+	connectors, err := c.repos.Connectors().GetChargingWithoutTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check connectors: %w", err)
+	}
+	for _, conn := range connectors {
+		report.ChargingWithoutTransaction = append(report.ChargingWithoutTransaction, ConnectorRef{
+			ChargerID:   conn.ChargerID,
+			ConnectorID: conn.ConnectorID,
+		})
+	}
+
+	transactions, err := c.repos.Transactions().GetActiveWithoutChargingConnector(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check transactions: %w", err)
+	}
+	for _, tx := range transactions {
+		report.TransactionsNotCharging = append(report.TransactionsNotCharging, TransactionRef{
+			ID:            tx.ID,
+			TransactionID: tx.TransactionID,
+			ChargerID:     tx.ChargerID,
+			ConnectorID:   tx.ConnectorID,
+		})
+	}
+
+	if c.registry == nil {
+		report.SocketCheckSkipped = true
+	} else {
+		chargers, err := c.repos.Chargers().GetConnected(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check connected chargers: %w", err)
+		}
+		for _, charger := range chargers {
+			if !c.registry.IsConnected(charger.ID) {
+				report.ConnectedWithoutSocket = append(report.ConnectedWithoutSocket, charger.ID)
+			}
+		}
+	}
+
+	if fix {
+		if err := c.fix(ctx, report, transactions); err != nil {
+			return nil, err
+		}
+		report.Fixed = true
+	}
+
+	return report, nil
+}
+
+// fix repairs every inconsistency in the report
+func (c *ConsistencyChecker) fix(ctx context.Context, report *ConsistencyReport, transactions []*db.Transaction) error {
+	for _, conn := range report.ChargingWithoutTransaction {
+		if err := c.repos.Connectors().UpdateStatus(ctx, conn.ChargerID, conn.ConnectorID, ocpp.ChargePointStatusAvailable); err != nil {
+			return fmt.Errorf("failed to reset connector %s/%d: %w", conn.ChargerID, conn.ConnectorID, err)
+		}
+		c.logger.Warn("Reset charging connector without transaction",
+			slog.String("charger_id", conn.ChargerID),
+			slog.Int("connector_id", conn.ConnectorID))
+	}
+
+	for _, tx := range transactions {
+		if err := c.repos.Transactions().Stop(ctx, tx.ID, c.lastMeterReading(ctx, tx), c.clock.Now(), stopReasonReconciled, db.StopSourceServer); err != nil {
+			return fmt.Errorf("failed to stop transaction %d: %w", tx.ID, err)
+		}
+		c.logger.Warn("Stopped transaction on idle connector",
+			slog.Int("transaction_id", tx.ID),
+			slog.String("charger_id", tx.ChargerID),
+			slog.Int("connector_id", tx.ConnectorID))
+	}
+
+	for _, chargerID := range report.ConnectedWithoutSocket {
+		if err := c.repos.Chargers().UpdateConnectionStatus(ctx, chargerID, false); err != nil {
+			return fmt.Errorf("failed to mark charger %s disconnected: %w", chargerID, err)
+		}
+		c.logger.Warn("Marked charger without socket as disconnected", slog.String("charger_id", chargerID))
+	}
+
+	return nil
+}
+
+// lastMeterReading returns the latest energy register reading recorded for the
+// transaction, falling back to its meter start
+func (c *ConsistencyChecker) lastMeterReading(ctx context.Context, tx *db.Transaction) int {
+	mv, err := c.repos.MeterValues().GetLatestByConnector(ctx, tx.ChargerID, tx.ConnectorID)
+	if err != nil || mv == nil || mv.TransactionID == nil || *mv.TransactionID != tx.ID ||
+		mv.Measurand != "Energy.Active.Import.Register" {
+		return tx.MeterStart
+	}
+	return int(mv.ValueNormalized)
+}
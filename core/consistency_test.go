@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry reports the listed chargers as having live sockets
+type fakeRegistry map[string]bool
+
+func (r fakeRegistry) IsConnected(chargerID string) bool {
+	return r[chargerID]
+}
+
+func seedInconsistencies(t *testing.T, ctx context.Context, repos db.RepositoryManager) *db.Transaction {
+	for _, id := range []string{"CP-1", "CP-2"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+		require.NoError(t, repos.Chargers().UpdateConnectionStatus(ctx, id, true))
+	}
+
+	// Connector 1 is charging without a transaction
+	_, err := repos.Connectors().Create(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	require.NoError(t, repos.Connectors().UpdateStatus(ctx, "CP-1", 1, "Charging"))
+
+	// Connector 2 has an active transaction but is available
+	_, err = repos.Connectors().Create(ctx, "CP-1", 2)
+	require.NoError(t, err)
+	stuck, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 2, IDTag: "TAG-1"})
+	require.NoError(t, err)
+
+	// Connector 3 is consistent: suspended during an active transaction
+	_, err = repos.Connectors().Create(ctx, "CP-1", 3)
+	require.NoError(t, err)
+	require.NoError(t, repos.Connectors().UpdateStatus(ctx, "CP-1", 3, "SuspendedEV"))
+	_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 3, IDTag: "TAG-2"})
+	require.NoError(t, err)
+
+	return stuck
+}
+
+func TestConsistencyCheckDetects(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+	stuck := seedInconsistencies(t, ctx, repos)
+
+	checker := NewConsistencyChecker(repos, fakeRegistry{"CP-1": true}, fake, dbtest.Logger())
+	report, err := checker.Check(ctx, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ConnectorRef{{ChargerID: "CP-1", ConnectorID: 1}}, report.ChargingWithoutTransaction)
+	require.Len(t, report.TransactionsNotCharging, 1)
+	assert.Equal(t, stuck.ID, report.TransactionsNotCharging[0].ID)
+	assert.Equal(t, []string{"CP-2"}, report.ConnectedWithoutSocket)
+	assert.False(t, report.SocketCheckSkipped)
+	assert.False(t, report.Fixed)
+
+	// Detection alone changes nothing
+	conn, err := repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Charging", conn.Status)
+}
+
+func TestConsistencyCheckFixes(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+	stuck := seedInconsistencies(t, ctx, repos)
+
+	checker := NewConsistencyChecker(repos, fakeRegistry{"CP-1": true}, fake, dbtest.Logger())
+	report, err := checker.Check(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, report.Fixed)
+
+	conn, err := repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Available", conn.Status)
+
+	tx, err := repos.Transactions().GetByID(ctx, stuck.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.TransactionStatusCompleted, tx.Status)
+	assert.Equal(t, db.StopSourceServer, tx.StopSource)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-2")
+	require.NoError(t, err)
+	assert.False(t, charger.IsConnected)
+
+	// Everything is consistent afterwards
+	report, err = checker.Check(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.ChargingWithoutTransaction)
+	assert.Empty(t, report.TransactionsNotCharging)
+	assert.Empty(t, report.ConnectedWithoutSocket)
+}
+
+func TestConsistencyCheckWithoutRegistry(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+	seedInconsistencies(t, ctx, repos)
+
+	report, err := NewConsistencyChecker(repos, nil, fake, dbtest.Logger()).Check(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, report.SocketCheckSkipped)
+	assert.Empty(t, report.ConnectedWithoutSocket)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-2")
+	require.NoError(t, err)
+	assert.True(t, charger.IsConnected)
+}
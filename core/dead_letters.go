@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// ErrDeadLetterResolved is returned when replaying a dead letter that an
+// earlier replay already resolved
+var ErrDeadLetterResolved = errors.New("dead letter is already resolved")
+
+// deadLetterBatchSize is how many dead letters a bulk replay loads at a time
+const deadLetterBatchSize = 100
+
+// DeadLetterReplay is the outcome of replaying one dead letter
+type DeadLetterReplay struct {
+	ID       int    `json:"id"`
+	Resolved bool   `json:"resolved"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DeadLetterReplayResult summarizes a bulk replay
+type DeadLetterReplayResult struct {
+	Replayed int                `json:"replayed"`
+	Resolved int                `json:"resolved"`
+	Failed   []DeadLetterReplay `json:"failed"`
+}
+
+// DeadLetterQueue keeps charger CALLs that failed with an internal error, and
+// replays them through the current handlers once the cause is fixed
+type DeadLetterQueue struct {
+	repos   db.RepositoryManager
+	handler *OCPPHandler
+	logger  *slog.Logger
+}
+
+// NewDeadLetterQueue creates a new dead letter queue replaying through handler
+func NewDeadLetterQueue(repos db.RepositoryManager, handler *OCPPHandler, logger *slog.Logger) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		repos:   repos,
+		handler: handler,
+		logger:  logger,
+	}
+}
+
+// Record stores a CALL whose handler failed. Calls answered with a
+// HandlerError were rejected on purpose and are not kept, since replaying
+// them would fail the same way.
+func (q *DeadLetterQueue) Record(ctx context.Context, chargerID string, call ocpp.Call, handlerErr error) {
+	var rejected *ocpp.HandlerError
+	if errors.As(handlerErr, &rejected) {
+		return
+	}
+
+	msg := ocpp.Message{MessageType: ocpp.MessageTypeCall, UniqueID: call.UniqueID, Action: call.Action, Payload: call.Payload}
+	frame, err := msg.Marshal()
+	if err != nil {
+		q.logger.Error("Failed to encode dead letter", slog.String("charger_id", chargerID), slog.Any("error", err))
+		return
+	}
+
+	// The call's context may be why it failed
+	_, err = q.repos.DeadLetters().Create(context.WithoutCancel(ctx), db.CreateDeadLetterRequest{
+		ChargerID: chargerID,
+		Action:    call.Action,
+		UniqueID:  call.UniqueID,
+		Frame:     string(frame),
+		Error:     handlerErr.Error(),
+	})
+	if err != nil {
+		q.logger.Error("Failed to dead-letter OCPP call",
+			slog.String("charger_id", chargerID),
+			slog.String("action", call.Action),
+			slog.Any("error", err))
+	}
+}
+
+// Replay runs a dead letter's frame through the current handlers, marking it
+// resolved when the handler succeeds and recording the error otherwise. A
+// failed replay is reported in the result rather than as an error.
+func (q *DeadLetterQueue) Replay(ctx context.Context, letter *db.DeadLetter) (*DeadLetterReplay, error) {
+	if letter.ResolvedAt != nil {
+		return nil, fmt.Errorf("%w: %d", ErrDeadLetterResolved, letter.ID)
+	}
+
+	result := &DeadLetterReplay{ID: letter.ID}
+	if err := q.replayFrame(ctx, letter); err != nil {
+		result.Error = err.Error()
+		if err := q.repos.DeadLetters().RecordReplayFailure(ctx, letter.ID, result.Error); err != nil {
+			return nil, err
+		}
+		q.logger.Warn("Dead letter replay failed",
+			slog.Int("id", letter.ID),
+			slog.String("charger_id", letter.ChargerID),
+			slog.String("action", letter.Action),
+			slog.String("error", result.Error))
+		return result, nil
+	}
+
+	if err := q.repos.DeadLetters().MarkResolved(ctx, letter.ID); err != nil {
+		return nil, err
+	}
+	result.Resolved = true
+
+	q.logger.Info("Replayed dead letter",
+		slog.Int("id", letter.ID),
+		slog.String("charger_id", letter.ChargerID),
+		slog.String("action", letter.Action))
+	return result, nil
+}
+
+// replayFrame decodes a stored frame and hands it to the router. The
+// response is dropped; the charger was answered when the call failed.
+func (q *DeadLetterQueue) replayFrame(ctx context.Context, letter *db.DeadLetter) error {
+	msg, err := ocpp.ParseMessage([]byte(letter.Frame))
+	if err != nil {
+		return err
+	}
+	if msg.MessageType != ocpp.MessageTypeCall {
+		return errors.New("frame is not a CALL")
+	}
+
+	_, err = q.handler.HandleCall(ctx, letter.ChargerID, ocpp.Call{
+		UniqueID: msg.UniqueID,
+		Action:   msg.Action,
+		Payload:  msg.Payload,
+	})
+	return err
+}
+
+// ReplayAll replays every unresolved dead letter matching the filter, oldest
+// first. Letters that fail stay unresolved and are reported in Failed.
+func (q *DeadLetterQueue) ReplayAll(ctx context.Context, filter db.DeadLetterFilter) (DeadLetterReplayResult, error) {
+	result := DeadLetterReplayResult{Failed: []DeadLetterReplay{}}
+
+	unresolved := false
+	filter.Resolved = &unresolved
+
+	// Resolved letters drop out of the filter, so only the failed ones are
+	// skipped on the next page
+	opts := db.ListOptions{Limit: deadLetterBatchSize, OrderBy: "id", SortDir: "ASC"}
+	for {
+		letters, err := q.repos.DeadLetters().List(ctx, filter, opts)
+		if err != nil {
+			return result, err
+		}
+
+		for _, letter := range letters {
+			replay, err := q.Replay(ctx, letter)
+			if err != nil {
+				return result, err
+			}
+			result.Replayed++
+			if replay.Resolved {
+				result.Resolved++
+			} else {
+				result.Failed = append(result.Failed, *replay)
+				opts.Offset++
+			}
+		}
+
+		if len(letters) < opts.Limit {
+			return result, nil
+		}
+	}
+}
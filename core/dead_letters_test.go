@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeadLetterTestSystem returns a system and its database, which tests
+// break and repair to make handlers fail
+func newDeadLetterTestSystem(t *testing.T) (*System, *db.Database) {
+	t.Helper()
+	fake := clock.NewFake(time.Date(2024, 11, 2, 8, 0, 0, 0, time.UTC))
+	database := dbtest.NewDatabase(t)
+	repos := db.NewRepositoryManager(database, &slogAdapter{logger: dbtest.Logger()}, fake)
+
+	cfg := &config.Config{OCPP: config.OCPPConfig{HeartbeatInterval: time.Minute}}
+	return &System{
+		config: cfg,
+		logger: dbtest.Logger(),
+		clock:  fake,
+		repos:  repos,
+		ocpp:   NewOCPPHandler(cfg.OCPP, repos, fake, dbtest.Logger()),
+	}, database
+}
+
+func bootCall(uniqueID string) ocpp.Call {
+	payload, _ := json.Marshal(ocpp.BootNotificationRequest{ChargePointVendor: "Acme", ChargePointModel: "X1"})
+	return ocpp.Call{UniqueID: uniqueID, Action: ocpp.ActionBootNotification, Payload: payload}
+}
+
+func TestReplayDeadLetteredBootNotification(t *testing.T) {
+	ctx := context.Background()
+	system, database := newDeadLetterTestSystem(t)
+
+	// A broken schema stands in for a handler bug
+	_, err := database.GetDB().Exec(`ALTER TABLE chargers RENAME TO chargers_broken`)
+	require.NoError(t, err)
+
+	_, err = system.HandleCall(ctx, "CP-1", bootCall("boot-1"))
+	require.Error(t, err)
+
+	letters, err := system.GetRepositories().DeadLetters().List(ctx, db.DeadLetterFilter{}, db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	letter := letters[0]
+	assert.Equal(t, "CP-1", letter.ChargerID)
+	assert.Equal(t, ocpp.ActionBootNotification, letter.Action)
+	assert.Equal(t, "boot-1", letter.UniqueID)
+	assert.Contains(t, letter.Frame, `"BootNotification"`)
+	assert.NotEmpty(t, letter.LastError)
+
+	// Replaying before the fix keeps the letter unresolved
+	replay, err := system.DeadLetters().Replay(ctx, letter)
+	require.NoError(t, err)
+	assert.False(t, replay.Resolved)
+	assert.NotEmpty(t, replay.Error)
+
+	_, err = database.GetDB().Exec(`ALTER TABLE chargers_broken RENAME TO chargers`)
+	require.NoError(t, err)
+
+	letter, err = system.GetRepositories().DeadLetters().GetByID(ctx, letter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, letter.Replays)
+
+	replay, err = system.DeadLetters().Replay(ctx, letter)
+	require.NoError(t, err)
+	assert.True(t, replay.Resolved, replay.Error)
+
+	charger, err := system.GetRepositories().Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", charger.Vendor)
+
+	letter, err = system.GetRepositories().DeadLetters().GetByID(ctx, letter.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, letter.ResolvedAt)
+
+	_, err = system.DeadLetters().Replay(ctx, letter)
+	assert.ErrorIs(t, err, ErrDeadLetterResolved)
+}
+
+func TestRejectedCallsAreNotDeadLettered(t *testing.T) {
+	ctx := context.Background()
+	system, _ := newDeadLetterTestSystem(t)
+
+	_, err := system.HandleCall(ctx, "CP-1", ocpp.Call{UniqueID: "1", Action: "Unknown", Payload: json.RawMessage(`{}`)})
+	require.Error(t, err)
+	_, err = system.HandleCall(ctx, "CP-1", ocpp.Call{UniqueID: "2", Action: ocpp.ActionBootNotification, Payload: json.RawMessage(`{"chargePointVendor": 1}`)})
+	require.Error(t, err)
+
+	letters, err := system.GetRepositories().DeadLetters().List(ctx, db.DeadLetterFilter{}, db.DefaultListOptions())
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+func TestReplayAllDeadLettersByFilter(t *testing.T) {
+	ctx := context.Background()
+	system, _ := newDeadLetterTestSystem(t)
+	deadLetters := system.GetRepositories().DeadLetters()
+
+	store := func(chargerID string, call ocpp.Call) {
+		frame, err := (&ocpp.Message{MessageType: ocpp.MessageTypeCall, UniqueID: call.UniqueID, Action: call.Action, Payload: call.Payload}).Marshal()
+		require.NoError(t, err)
+		_, err = deadLetters.Create(ctx, db.CreateDeadLetterRequest{
+			ChargerID: chargerID, Action: call.Action, UniqueID: call.UniqueID, Frame: string(frame), Error: "boom",
+		})
+		require.NoError(t, err)
+	}
+	store("CP-1", bootCall("a"))
+	store("CP-1", ocpp.Call{UniqueID: "b", Action: ocpp.ActionBootNotification, Payload: json.RawMessage(`{"chargePointVendor": 1}`)})
+	store("CP-1", bootCall("c"))
+	store("CP-2", bootCall("d"))
+
+	result, err := system.DeadLetters().ReplayAll(ctx, db.DeadLetterFilter{ChargerID: "CP-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Replayed)
+	assert.Equal(t, 2, result.Resolved)
+	require.Len(t, result.Failed, 1)
+	assert.Contains(t, result.Failed[0].Error, ocpp.ErrorCodeFormationViolation)
+
+	unresolved := false
+	letters, err := deadLetters.List(ctx, db.DeadLetterFilter{Resolved: &unresolved}, db.ListOptions{Limit: 10, OrderBy: "id", SortDir: "ASC"})
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	assert.Equal(t, "b", letters[0].UniqueID)
+	assert.Equal(t, "d", letters[1].UniqueID)
+}
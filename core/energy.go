@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// Where a recomputed energy_delivered came from
+const (
+	EnergySourceMeterValues    = "meter_values"
+	EnergySourceMeterStartStop = "meter_start_stop"
+	EnergySourceNone           = "none"
+)
+
+// recomputeBatchSize is how many transactions a bulk recompute loads at a time
+const recomputeBatchSize = 200
+
+// EnergyRecomputation is the outcome of recomputing one transaction's energy
+type EnergyRecomputation struct {
+	ID              int    `json:"id"`
+	Previous        int    `json:"previous_energy_delivered"`
+	EnergyDelivered int    `json:"energy_delivered"`
+	Source          string `json:"source"`
+	Changed         bool   `json:"changed"`
+}
+
+// EnergyRecomputeResult summarizes a bulk recompute
+type EnergyRecomputeResult struct {
+	Checked int `json:"checked"`
+	Updated int `json:"updated"`
+}
+
+// EnergyRecomputer recomputes the energy_delivered of transactions after their
+// meter data was backfilled or corrected
+type EnergyRecomputer struct {
+	repos  db.RepositoryManager
+	logger *slog.Logger
+}
+
+// NewEnergyRecomputer creates a new energy recomputer
+func NewEnergyRecomputer(repos db.RepositoryManager, logger *slog.Logger) *EnergyRecomputer {
+	return &EnergyRecomputer{
+		repos:  repos,
+		logger: logger,
+	}
+}
+
+// Recompute sets a transaction's energy_delivered from the spread of its
+// energy register meter values when it has at least two, and from its meter
+// start and stop otherwise. A transaction with neither is left unchanged.
+func (r *EnergyRecomputer) Recompute(ctx context.Context, tx *db.Transaction) (*EnergyRecomputation, error) {
+	result := &EnergyRecomputation{
+		ID:              tx.ID,
+		Previous:        tx.EnergyDelivered,
+		EnergyDelivered: tx.EnergyDelivered,
+		Source:          EnergySourceNone,
+	}
+
+	register, err := r.repos.MeterValues().GetRangeByTransaction(ctx, tx.ID, ocpp.DefaultMeasurand)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case register.Count >= 2:
+		result.EnergyDelivered = int(math.Round(register.Max - register.Min))
+		result.Source = EnergySourceMeterValues
+	case tx.MeterStop != nil:
+		result.EnergyDelivered = max(*tx.MeterStop-tx.MeterStart, 0)
+		result.Source = EnergySourceMeterStartStop
+	default:
+		return result, nil
+	}
+
+	if result.EnergyDelivered == result.Previous {
+		return result, nil
+	}
+
+	if _, err := r.repos.Transactions().Update(ctx, tx.ID, db.UpdateTransactionRequest{EnergyDelivered: &result.EnergyDelivered}); err != nil {
+		return nil, fmt.Errorf("failed to update energy of transaction %d: %w", tx.ID, err)
+	}
+	result.Changed = true
+
+	r.logger.Info("Recomputed transaction energy",
+		slog.Int("transaction_id", tx.ID),
+		slog.Int("previous", result.Previous),
+		slog.Int("energy_delivered", result.EnergyDelivered),
+		slog.String("source", result.Source))
+	return result, nil
+}
+
+// RecomputeAll recomputes every transaction matching the filter
+func (r *EnergyRecomputer) RecomputeAll(ctx context.Context, filter db.TransactionFilter) (EnergyRecomputeResult, error) {
+	var result EnergyRecomputeResult
+
+	opts := db.ListOptions{Limit: recomputeBatchSize, OrderBy: "id", SortDir: "ASC"}
+	for {
+		transactions, err := r.repos.Transactions().Search(ctx, filter, opts)
+		if err != nil {
+			return result, fmt.Errorf("failed to search transactions: %w", err)
+		}
+
+		for _, tx := range transactions {
+			recomputed, err := r.Recompute(ctx, tx)
+			if err != nil {
+				return result, err
+			}
+			result.Checked++
+			if recomputed.Changed {
+				result.Updated++
+			}
+		}
+
+		if len(transactions) < opts.Limit {
+			return result, nil
+		}
+		opts.Offset += opts.Limit
+	}
+}
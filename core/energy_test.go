@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecomputeEnergyFromMeterValues(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	repos := dbtest.NewRepositories(t, clock.NewFake(start))
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		ChargerID:   "CP-1",
+		ConnectorID: 1,
+		IDTag:       "TAG-1",
+		MeterStart:  0, // charger omitted meterStart
+		StartTime:   &start,
+	})
+	require.NoError(t, err)
+	require.NoError(t, repos.Transactions().Stop(ctx, tx.ID, 12500, start.Add(time.Hour), "Local", db.StopSourceCharger))
+
+	// Backfilled register readings, in kWh, plus a per-phase value that is ignored
+	readings := []struct {
+		value float64
+		unit  string
+		phase string
+	}{
+		{10.0, db.UnitKWh, ""},
+		{11.2, db.UnitKWh, ""},
+		{12.5, db.UnitKWh, ""},
+		{99.0, db.UnitKWh, "L1"},
+	}
+	for i, r := range readings {
+		_, err := repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			TransactionID: &tx.ID,
+			ChargerID:     "CP-1",
+			ConnectorID:   1,
+			Timestamp:     start.Add(time.Duration(i) * 20 * time.Minute),
+			Measurand:     "Energy.Active.Import.Register",
+			Value:         dbtest.Float64(r.value),
+			Unit:          r.unit,
+			Phase:         r.phase,
+		})
+		require.NoError(t, err)
+	}
+
+	stopped, err := repos.Transactions().GetByID(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 12500, stopped.EnergyDelivered, "meter stop minus a missing meter start")
+
+	recomputer := NewEnergyRecomputer(repos, dbtest.Logger())
+	result, err := recomputer.Recompute(ctx, stopped)
+	require.NoError(t, err)
+	assert.Equal(t, EnergyRecomputation{
+		ID:              tx.ID,
+		Previous:        12500,
+		EnergyDelivered: 2500,
+		Source:          EnergySourceMeterValues,
+		Changed:         true,
+	}, *result)
+
+	updated, err := repos.Transactions().GetByID(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2500, updated.EnergyDelivered)
+
+	// Recomputing again finds nothing to change
+	result, err = recomputer.Recompute(ctx, updated)
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+}
+
+func TestRecomputeEnergyWithoutMeterValues(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	repos := dbtest.NewRepositories(t, clock.NewFake(start))
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	stopped, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-1", MeterStart: 1000, StartTime: &start,
+	})
+	require.NoError(t, err)
+	require.NoError(t, repos.Transactions().Stop(ctx, stopped.ID, 4000, start.Add(time.Hour), "Local", db.StopSourceCharger))
+	corrupted := 0
+	_, err = repos.Transactions().Update(ctx, stopped.ID, db.UpdateTransactionRequest{EnergyDelivered: &corrupted})
+	require.NoError(t, err)
+
+	active, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		ChargerID: "CP-1", ConnectorID: 2, IDTag: "TAG-2", MeterStart: 500, StartTime: &start,
+	})
+	require.NoError(t, err)
+
+	recomputer := NewEnergyRecomputer(repos, dbtest.Logger())
+	summary, err := recomputer.RecomputeAll(ctx, db.TransactionFilter{ChargerID: "CP-1"})
+	require.NoError(t, err)
+	assert.Equal(t, EnergyRecomputeResult{Checked: 2, Updated: 1}, summary)
+
+	updated, err := repos.Transactions().GetByID(ctx, stopped.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3000, updated.EnergyDelivered)
+
+	// An active transaction without register readings has nothing to recompute from
+	result, err := recomputer.Recompute(ctx, active)
+	require.NoError(t, err)
+	assert.Equal(t, EnergySourceNone, result.Source)
+	assert.False(t, result.Changed)
+}
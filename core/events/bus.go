@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AllTopics subscribes a handler to every topic
+const AllTopics = "*"
+
+// Event is a domain event delivered to bus subscribers
+type Event struct {
+	ID         int             `json:"id"`
+	Topic      string          `json:"topic"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// Decode unmarshals the event payload into v
+func (e Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode %s event: %w", e.Topic, err)
+	}
+	return nil
+}
+
+// Handler processes a published event
+type Handler func(ctx context.Context, event Event) error
+
+// Bus fans published events out to subscribed handlers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates a new event bus
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers a handler for a topic, or for every topic with AllTopics
+func (b *Bus) Subscribe(topic string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+}
+
+// Publish calls every handler subscribed to the event topic and returns
+// their combined errors. Handlers run synchronously in subscription order.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers[event.Topic])+len(b.handlers[AllTopics]))
+	handlers = append(handlers, b.handlers[event.Topic]...)
+	handlers = append(handlers, b.handlers[AllTopics]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+)
+
+// relayBatchSize is the number of pending events relayed per poll
+const relayBatchSize = 100
+
+// Write marshals payload and appends it to the outbox. Pass the outbox of a
+// TxManager so the event commits or rolls back with the state change.
+func Write(ctx context.Context, outbox db.OutboxRepository, topic string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", topic, err)
+	}
+
+	if _, err := outbox.Append(ctx, db.CreateOutboxEventRequest{
+		Topic:   topic,
+		Payload: string(body),
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Relay publishes committed outbox events to the bus and marks them published.
+// Delivery is at-least-once: an event whose handlers fail stays pending and is
+// published again, to every handler, on the next run.
+type Relay struct {
+	outbox   db.OutboxRepository
+	bus      *Bus
+	interval time.Duration
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewRelay creates a new outbox relay
+func NewRelay(outbox db.OutboxRepository, bus *Bus, interval time.Duration, clk clock.Clock, logger *slog.Logger) *Relay {
+	return &Relay{
+		outbox:   outbox,
+		bus:      bus,
+		interval: interval,
+		clock:    clk,
+		logger:   logger,
+	}
+}
+
+// RelayOnce publishes pending events in order and returns how many were published.
+// It stops at the first failure so later events are not published ahead of it.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	pending, err := r.outbox.GetPending(ctx, relayBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending outbox events: %w", err)
+	}
+
+	published := 0
+	for _, entry := range pending {
+		event := Event{
+			ID:         entry.ID,
+			Topic:      entry.Topic,
+			Payload:    json.RawMessage(entry.Payload),
+			OccurredAt: entry.CreatedAt,
+		}
+
+		if err := r.bus.Publish(ctx, event); err != nil {
+			r.logger.Warn("Failed to publish outbox event",
+				slog.Int("id", entry.ID),
+				slog.String("topic", entry.Topic),
+				slog.Int("attempts", entry.Attempts+1),
+				slog.Any("error", err))
+			if markErr := r.outbox.MarkFailed(ctx, entry.ID, err.Error()); markErr != nil {
+				r.logger.Error("Failed to record outbox publish failure", slog.Int("id", entry.ID), slog.Any("error", markErr))
+			}
+			break
+		}
+
+		if err := r.outbox.MarkPublished(ctx, entry.ID, r.clock.Now()); err != nil {
+			return published, fmt.Errorf("failed to mark outbox event published: %w", err)
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// Run relays pending events on the configured interval until the context is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	interval := r.interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.RelayOnce(ctx); err != nil {
+				r.logger.Error("Outbox relay run failed", slog.Any("error", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPayload struct {
+	ChargerID string `json:"charger_id"`
+}
+
+func newTestRelay(t *testing.T) (*Relay, *Bus, db.RepositoryManager) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	bus := NewBus()
+	return NewRelay(repos.Outbox(), bus, time.Second, fake, dbtest.Logger()), bus, repos
+}
+
+func TestRelayPublishesCommittedEvents(t *testing.T) {
+	ctx := context.Background()
+	relay, bus, repos := newTestRelay(t)
+
+	var received []Event
+	bus.Subscribe("charger.updated", func(ctx context.Context, event Event) error {
+		received = append(received, event)
+		return nil
+	})
+
+	tx, err := repos.BeginTx(ctx)
+	require.NoError(t, err)
+	_, err = tx.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	require.NoError(t, Write(ctx, tx.Outbox(), "charger.updated", testPayload{ChargerID: "CP-1"}))
+	require.NoError(t, tx.Commit())
+
+	published, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	require.Len(t, received, 1)
+
+	var payload testPayload
+	require.NoError(t, received[0].Decode(&payload))
+	assert.Equal(t, "CP-1", payload.ChargerID)
+
+	// Published events are not relayed again
+	published, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+	assert.Len(t, received, 1)
+}
+
+func TestRelaySkipsRolledBackEvents(t *testing.T) {
+	ctx := context.Background()
+	relay, bus, repos := newTestRelay(t)
+
+	var received []Event
+	bus.Subscribe(AllTopics, func(ctx context.Context, event Event) error {
+		received = append(received, event)
+		return nil
+	})
+
+	tx, err := repos.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, Write(ctx, tx.Outbox(), "charger.updated", testPayload{ChargerID: "CP-1"}))
+	require.NoError(t, tx.Rollback())
+
+	published, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+	assert.Empty(t, received)
+}
+
+func TestRelayRetriesFailedEvents(t *testing.T) {
+	ctx := context.Background()
+	relay, bus, repos := newTestRelay(t)
+
+	fail := true
+	var received []Event
+	bus.Subscribe("charger.updated", func(ctx context.Context, event Event) error {
+		if fail {
+			return errors.New("sink unavailable")
+		}
+		received = append(received, event)
+		return nil
+	})
+
+	require.NoError(t, Write(ctx, repos.Outbox(), "charger.updated", testPayload{ChargerID: "CP-1"}))
+	require.NoError(t, Write(ctx, repos.Outbox(), "charger.updated", testPayload{ChargerID: "CP-2"}))
+
+	published, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	entry, err := repos.Outbox().GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.OutboxStatusPending, entry.Status)
+	assert.Equal(t, 1, entry.Attempts)
+	assert.Equal(t, "sink unavailable", entry.LastError)
+
+	fail = false
+	published, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	require.Len(t, received, 2)
+	assert.Equal(t, 1, received[0].ID)
+	assert.Equal(t, 2, received[1].ID)
+
+	count, err := repos.Outbox().CountPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
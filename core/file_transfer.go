@@ -0,0 +1,193 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// ErrInvalidFileTransfer is returned when a firmware update or log upload
+// request fails validation
+var ErrInvalidFileTransfer = errors.New("invalid file transfer")
+
+// FileTransferManager requests signed firmware updates and log uploads from
+// chargers, tracking each one in the file transfers so that the status
+// notifications the charger sends later can be attributed to it
+type FileTransferManager struct {
+	repos  db.RepositoryManager
+	sender ocpp.Sender
+	logger *slog.Logger
+}
+
+// NewFileTransferManager creates a new file transfer manager
+func NewFileTransferManager(repos db.RepositoryManager, sender ocpp.Sender, logger *slog.Logger) *FileTransferManager {
+	return &FileTransferManager{
+		repos:  repos,
+		sender: sender,
+		logger: logger,
+	}
+}
+
+// ValidateFirmware checks the fields a signed firmware update requires
+func ValidateFirmware(firmware ocpp.Firmware) error {
+	switch {
+	case firmware.Location == "":
+		return fmt.Errorf("%w: firmware location is required", ErrInvalidFileTransfer)
+	case firmware.SigningCertificate == "":
+		return fmt.Errorf("%w: signing certificate is required", ErrInvalidFileTransfer)
+	case firmware.Signature == "":
+		return fmt.Errorf("%w: firmware signature is required", ErrInvalidFileTransfer)
+	case firmware.RetrieveDateTime.IsZero():
+		return fmt.Errorf("%w: retrieve date time is required", ErrInvalidFileTransfer)
+	}
+	return nil
+}
+
+// ValidateLogRequest checks the log type and upload location of a log request
+func ValidateLogRequest(logType string, log ocpp.LogParameters) error {
+	if logType != ocpp.LogTypeDiagnostics && logType != ocpp.LogTypeSecurity {
+		return fmt.Errorf("%w: unknown log type %q", ErrInvalidFileTransfer, logType)
+	}
+	if log.RemoteLocation == "" {
+		return fmt.Errorf("%w: remote location is required", ErrInvalidFileTransfer)
+	}
+	return nil
+}
+
+// SignedUpdateFirmware asks a charger to install signed firmware. The update
+// is recorded first so its id can be sent as the requestId, then updated with
+// the charger's response status.
+func (m *FileTransferManager) SignedUpdateFirmware(ctx context.Context, chargerID string, firmware ocpp.Firmware, retries, retryInterval *int) (*db.FileTransfer, error) {
+	if err := ValidateFirmware(firmware); err != nil {
+		return nil, err
+	}
+	if m.sender == nil {
+		return nil, ocpp.ErrNotConnected
+	}
+
+	transfer, err := m.repos.FileTransfers().Create(ctx, db.CreateFileTransferRequest{
+		ChargerID: chargerID,
+		Kind:      db.FileTransferFirmware,
+		Location:  firmware.Location,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ocpp.SignedUpdateFirmwareResponse
+	req := ocpp.SignedUpdateFirmwareRequest{
+		Retries:       retries,
+		RetryInterval: retryInterval,
+		RequestID:     transfer.ID,
+		Firmware:      firmware,
+	}
+	if err := m.sender.SendCall(ctx, chargerID, ocpp.ActionSignedUpdateFirmware, req, &resp); err != nil {
+		m.fail(ctx, transfer)
+		return nil, fmt.Errorf("failed to send signed firmware update: %w", err)
+	}
+
+	m.logger.Info("Requested signed firmware update",
+		slog.String("charger_id", chargerID),
+		slog.Int("request_id", transfer.ID),
+		slog.String("status", resp.Status))
+	return m.repos.FileTransfers().Update(ctx, transfer.ID, resp.Status, "")
+}
+
+// GetLog asks a charger to upload a diagnostics or security log, recording
+// the request like SignedUpdateFirmware
+func (m *FileTransferManager) GetLog(ctx context.Context, chargerID, logType string, log ocpp.LogParameters, retries, retryInterval *int) (*db.FileTransfer, error) {
+	if err := ValidateLogRequest(logType, log); err != nil {
+		return nil, err
+	}
+	if m.sender == nil {
+		return nil, ocpp.ErrNotConnected
+	}
+
+	transfer, err := m.repos.FileTransfers().Create(ctx, db.CreateFileTransferRequest{
+		ChargerID: chargerID,
+		Kind:      db.FileTransferLog,
+		LogType:   logType,
+		Location:  log.RemoteLocation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ocpp.GetLogResponse
+	req := ocpp.GetLogRequest{
+		Log:           log,
+		LogType:       logType,
+		RequestID:     transfer.ID,
+		Retries:       retries,
+		RetryInterval: retryInterval,
+	}
+	if err := m.sender.SendCall(ctx, chargerID, ocpp.ActionGetLog, req, &resp); err != nil {
+		m.fail(ctx, transfer)
+		return nil, fmt.Errorf("failed to request log: %w", err)
+	}
+
+	m.logger.Info("Requested log upload",
+		slog.String("charger_id", chargerID),
+		slog.String("log_type", logType),
+		slog.Int("request_id", transfer.ID),
+		slog.String("status", resp.Status))
+	return m.repos.FileTransfers().Update(ctx, transfer.ID, resp.Status, resp.Filename)
+}
+
+// fail marks a transfer whose command could not be delivered
+func (m *FileTransferManager) fail(ctx context.Context, transfer *db.FileTransfer) {
+	if _, err := m.repos.FileTransfers().Update(ctx, transfer.ID, db.CommandStatusError, ""); err != nil {
+		m.logger.Warn("Failed to mark file transfer as failed",
+			slog.Int("id", transfer.ID),
+			slog.Any("error", err))
+	}
+}
+
+// SignedFirmwareStatusNotification records the progress of a signed firmware update
+func (h *OCPPHandler) SignedFirmwareStatusNotification(ctx context.Context, chargerID string, req ocpp.SignedFirmwareStatusNotificationRequest) (*ocpp.SignedFirmwareStatusNotificationResponse, error) {
+	if err := h.updateTransferStatus(ctx, chargerID, db.FileTransferFirmware, req.RequestID, req.Status); err != nil {
+		return nil, err
+	}
+	return &ocpp.SignedFirmwareStatusNotificationResponse{}, nil
+}
+
+// LogStatusNotification records the progress of a log upload
+func (h *OCPPHandler) LogStatusNotification(ctx context.Context, chargerID string, req ocpp.LogStatusNotificationRequest) (*ocpp.LogStatusNotificationResponse, error) {
+	if err := h.updateTransferStatus(ctx, chargerID, db.FileTransferLog, req.RequestID, req.Status); err != nil {
+		return nil, err
+	}
+	return &ocpp.LogStatusNotificationResponse{}, nil
+}
+
+// updateTransferStatus applies a reported status to the transfer it refers to,
+// or the charger's latest transfer of the kind when no requestId is given. An
+// Idle status without a requestId only answers a TriggerMessage and is not
+// recorded.
+func (h *OCPPHandler) updateTransferStatus(ctx context.Context, chargerID, kind string, requestID *int, status string) error {
+	if requestID == nil && status == ocpp.TransferStatusIdle {
+		return nil
+	}
+
+	transfer, err := h.repos.FileTransfers().UpdateStatus(ctx, chargerID, kind, requestID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update %s status: %w", kind, err)
+	}
+	if transfer == nil {
+		h.logger.Warn("Status notification for unknown file transfer",
+			slog.String("charger_id", chargerID),
+			slog.String("kind", kind),
+			slog.String("status", status))
+		return nil
+	}
+
+	h.logger.Info("File transfer status updated",
+		slog.String("charger_id", chargerID),
+		slog.String("kind", kind),
+		slog.Int("request_id", transfer.ID),
+		slog.String("status", status))
+	return nil
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFirmware(retrieve time.Time) ocpp.Firmware {
+	return ocpp.Firmware{
+		Location:           "https://firmware.example.com/x1-2.0.bin",
+		RetrieveDateTime:   retrieve,
+		SigningCertificate: "-----BEGIN CERTIFICATE-----",
+		Signature:          "c2lnbmF0dXJl",
+	}
+}
+
+func TestSignedFirmwareStatusPersistence(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	sender := &fakeSender{responses: map[string]interface{}{
+		ocpp.ActionSignedUpdateFirmware: ocpp.SignedUpdateFirmwareResponse{Status: ocpp.UpdateFirmwareStatusAccepted},
+	}}
+	manager := NewFileTransferManager(repos, sender, dbtest.Logger())
+
+	transfer, err := manager.SignedUpdateFirmware(ctx, "CP-1", testFirmware(fake.Now()), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, db.FileTransferFirmware, transfer.Kind)
+	assert.Equal(t, ocpp.UpdateFirmwareStatusAccepted, transfer.Status)
+
+	// The transfer id is sent as the requestId
+	require.Len(t, sender.calls, 1)
+	assert.JSONEq(t, `{
+		"requestId": `+strconv.Itoa(transfer.ID)+`,
+		"firmware": {
+			"location": "https://firmware.example.com/x1-2.0.bin",
+			"retrieveDateTime": "2024-09-01T10:00:00Z",
+			"signingCertificate": "-----BEGIN CERTIFICATE-----",
+			"signature": "c2lnbmF0dXJl"
+		}
+	}`, string(sender.calls[0].Payload))
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	for _, status := range []string{"Downloading", "Downloaded", "SignatureVerified", "Installing", "Installed"} {
+		fake.Advance(time.Minute)
+		_, err := handler.SignedFirmwareStatusNotification(ctx, "CP-1", ocpp.SignedFirmwareStatusNotificationRequest{
+			Status:    status,
+			RequestID: &transfer.ID,
+		})
+		require.NoError(t, err)
+	}
+
+	// An Idle reply to a trigger does not overwrite the last status
+	_, err = handler.SignedFirmwareStatusNotification(ctx, "CP-1", ocpp.SignedFirmwareStatusNotificationRequest{Status: ocpp.TransferStatusIdle})
+	require.NoError(t, err)
+
+	transfers, err := repos.FileTransfers().ListByCharger(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, "Installed", transfers[0].Status)
+	assert.True(t, fake.Now().Equal(transfers[0].UpdatedAt))
+}
+
+func TestSignedFirmwareStatusWithoutRequestID(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	sender := &fakeSender{responses: map[string]interface{}{
+		ocpp.ActionSignedUpdateFirmware: ocpp.SignedUpdateFirmwareResponse{Status: ocpp.UpdateFirmwareStatusAccepted},
+		ocpp.ActionGetLog:               ocpp.GetLogResponse{Status: ocpp.LogStatusAccepted, Filename: "security.log"},
+	}}
+	manager := NewFileTransferManager(repos, sender, dbtest.Logger())
+
+	first, err := manager.SignedUpdateFirmware(ctx, "CP-1", testFirmware(fake.Now()), nil, nil)
+	require.NoError(t, err)
+	fake.Advance(time.Minute)
+	latest, err := manager.SignedUpdateFirmware(ctx, "CP-1", testFirmware(fake.Now()), nil, nil)
+	require.NoError(t, err)
+	logTransfer, err := manager.GetLog(ctx, "CP-1", ocpp.LogTypeSecurity, ocpp.LogParameters{RemoteLocation: "ftp://logs.example.com/"}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "security.log", logTransfer.Filename)
+
+	// Without a requestId the most recent firmware update is updated
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	_, err = handler.SignedFirmwareStatusNotification(ctx, "CP-1", ocpp.SignedFirmwareStatusNotificationRequest{Status: "InvalidSignature"})
+	require.NoError(t, err)
+
+	transfers, err := repos.FileTransfers().ListByCharger(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	statuses := make(map[int]string)
+	for _, transfer := range transfers {
+		statuses[transfer.ID] = transfer.Status
+	}
+	assert.Equal(t, map[int]string{
+		first.ID:       ocpp.UpdateFirmwareStatusAccepted,
+		latest.ID:      "InvalidSignature",
+		logTransfer.ID: ocpp.LogStatusAccepted,
+	}, statuses)
+}
+
+func TestSignedUpdateFirmwareSendFailure(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	manager := NewFileTransferManager(repos, &fakeSender{err: errors.New("timeout")}, dbtest.Logger())
+	_, err = manager.SignedUpdateFirmware(ctx, "CP-1", testFirmware(time.Now()), nil, nil)
+	require.Error(t, err)
+
+	transfers, err := repos.FileTransfers().ListByCharger(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, db.CommandStatusError, transfers[0].Status)
+
+	_, err = manager.SignedUpdateFirmware(ctx, "CP-1", ocpp.Firmware{Location: "https://firmware.example.com/x1.bin"}, nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidFileTransfer)
+}
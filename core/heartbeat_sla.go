@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/events"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// TopicHeartbeatMissed is published when a connected charger is overdue on
+// its heartbeat
+const TopicHeartbeatMissed = "charger.heartbeat_missed"
+
+// HeartbeatMissed is the payload of a charger.heartbeat_missed event
+type HeartbeatMissed struct {
+	ChargerID        string     `json:"charger_id"`
+	LastSeenAt       *time.Time `json:"last_seen_at"`
+	ExpectedInterval int        `json:"expected_interval_seconds"`
+	SilentSeconds    int        `json:"silent_seconds"`
+	Timestamp        time.Time  `json:"timestamp"`
+}
+
+// HeartbeatSLAMonitor alerts when a connected charger has not been heard from
+// within a multiple of the heartbeat interval. Unlike the stale monitor it
+// does not change the charger's state; it raises at most one alert per
+// charger per cooldown so a flapping charger cannot flood notifications.
+type HeartbeatSLAMonitor struct {
+	interval time.Duration
+	factor   float64
+	cooldown time.Duration
+	repos    db.RepositoryManager
+	metrics  *monitoring.Metrics
+	clock    clock.Clock
+	logger   *slog.Logger
+	// alerted is when each connected charger was last alerted on within
+	// the cooldown; other entries are pruned every check
+	alerted map[string]time.Time
+}
+
+// NewHeartbeatSLAMonitor creates a new heartbeat SLA monitor. metrics may be nil.
+func NewHeartbeatSLAMonitor(interval time.Duration, factor float64, cooldown time.Duration, repos db.RepositoryManager, metrics *monitoring.Metrics, clk clock.Clock, logger *slog.Logger) *HeartbeatSLAMonitor {
+	return &HeartbeatSLAMonitor{
+		interval: interval,
+		factor:   factor,
+		cooldown: cooldown,
+		repos:    repos,
+		metrics:  metrics,
+		clock:    clk,
+		logger:   logger,
+		alerted:  make(map[string]time.Time),
+	}
+}
+
+// CheckOnce raises a HeartbeatMissed alert for each overdue charger outside
+// its cooldown and returns their IDs
+func (m *HeartbeatSLAMonitor) CheckOnce(ctx context.Context) ([]string, error) {
+	chargers, err := m.repos.Chargers().GetConnected(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connected chargers: %w", err)
+	}
+
+	now := m.clock.Now()
+	m.prune(chargers, now)
+
+	allowed := time.Duration(float64(m.interval) * m.factor)
+	var missed []string
+	for _, charger := range chargers {
+		lastSeen := lastSeenAt(charger)
+		if lastSeen == nil || now.Sub(*lastSeen) <= allowed {
+			continue
+		}
+		if last, ok := m.alerted[charger.ID]; ok && now.Sub(last) < m.cooldown {
+			continue
+		}
+
+		alert := HeartbeatMissed{
+			ChargerID:        charger.ID,
+			LastSeenAt:       lastSeen,
+			ExpectedInterval: int(m.interval / time.Second),
+			SilentSeconds:    int(now.Sub(*lastSeen) / time.Second),
+			Timestamp:        now.UTC(),
+		}
+		if err := events.Write(ctx, m.repos.Outbox(), TopicHeartbeatMissed, alert); err != nil {
+			m.logger.Error("Failed to raise HeartbeatMissed alert",
+				slog.String("charger_id", charger.ID),
+				slog.Any("error", err))
+			continue
+		}
+
+		m.alerted[charger.ID] = now
+		if m.metrics != nil {
+			m.metrics.RecordHeartbeatMissed(charger.ID)
+		}
+		m.logger.Warn("Charger missed its heartbeat",
+			slog.String("charger_id", charger.ID),
+			slog.Int("silent_seconds", alert.SilentSeconds),
+			slog.Int("expected_interval_seconds", alert.ExpectedInterval))
+		missed = append(missed, charger.ID)
+	}
+
+	return missed, nil
+}
+
+// prune forgets chargers that have disconnected or whose cooldown has passed,
+// so alerted only holds chargers that could still be suppressed. A charger
+// that recovers and goes silent again within its cooldown stays suppressed.
+func (m *HeartbeatSLAMonitor) prune(connected []*db.Charger, now time.Time) {
+	ids := make(map[string]bool, len(connected))
+	for _, charger := range connected {
+		ids[charger.ID] = true
+	}
+	for id, last := range m.alerted {
+		if !ids[id] || now.Sub(last) >= m.cooldown {
+			delete(m.alerted, id)
+		}
+	}
+}
+
+// Run executes the check every heartbeat interval until the context is cancelled
+func (m *HeartbeatSLAMonitor) Run(ctx context.Context) {
+	interval := m.interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.CheckOnce(ctx); err != nil {
+				m.logger.Error("Heartbeat SLA check failed", slog.Any("error", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// maxLocalListIDTagLength is the OCPP 1.6 limit on idTag length
+const maxLocalListIDTagLength = 20
+
+// ErrInvalidLocalList is returned when a local list update fails validation
+var ErrInvalidLocalList = errors.New("invalid local list")
+
+// LocalListManager synchronizes local authorization lists with chargers
+type LocalListManager struct {
+	repos  db.RepositoryManager
+	sender ocpp.Sender
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewLocalListManager creates a new local list manager
+func NewLocalListManager(repos db.RepositoryManager, sender ocpp.Sender, clk clock.Clock, logger *slog.Logger) *LocalListManager {
+	return &LocalListManager{
+		repos:  repos,
+		sender: sender,
+		clock:  clk,
+		logger: logger,
+	}
+}
+
+// BuildLocalList converts ID tags into local authorization list entries
+func BuildLocalList(tags []*db.IDTag) []ocpp.AuthorizationData {
+	list := make([]ocpp.AuthorizationData, 0, len(tags))
+	for _, tag := range tags {
+		info := &ocpp.IDTagInfo{
+			Status:      tag.Status,
+			ParentIDTag: tag.ParentIDTag,
+		}
+		if tag.ExpiryDate != nil {
+			expiry := tag.ExpiryDate.UTC()
+			info.ExpiryDate = &expiry
+		}
+		list = append(list, ocpp.AuthorizationData{IDTag: tag.IDTag, IDTagInfo: info})
+	}
+	return list
+}
+
+// BuildFullLocalList builds a full local list from every stored ID tag
+func (m *LocalListManager) BuildFullLocalList(ctx context.Context) ([]ocpp.AuthorizationData, error) {
+	tags, err := m.repos.IDTags().GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ID tags: %w", err)
+	}
+	return BuildLocalList(tags), nil
+}
+
+// GetLocalListVersion asks a charger for the version of its local list
+func (m *LocalListManager) GetLocalListVersion(ctx context.Context, chargerID string) (int, error) {
+	if m.sender == nil {
+		return 0, ocpp.ErrNotConnected
+	}
+
+	var resp ocpp.GetLocalListVersionResponse
+	if err := m.sender.SendCall(ctx, chargerID, ocpp.ActionGetLocalListVersion, ocpp.GetLocalListVersionRequest{}, &resp); err != nil {
+		return 0, fmt.Errorf("failed to get local list version: %w", err)
+	}
+
+	return resp.ListVersion, nil
+}
+
+// SendLocalList sends a local list update to a charger. A listVersion of 0
+// uses the next version after the one last synced, and a nil list on a Full
+// update sends every stored ID tag. The version is persisted once the
+// charger accepts the update.
+func (m *LocalListManager) SendLocalList(ctx context.Context, chargerID string, listVersion int, updateType string, list []ocpp.AuthorizationData) (*ocpp.SendLocalListResponse, int, error) {
+	if m.sender == nil {
+		return nil, 0, ocpp.ErrNotConnected
+	}
+
+	if updateType != ocpp.UpdateTypeFull && updateType != ocpp.UpdateTypeDifferential {
+		return nil, 0, fmt.Errorf("%w: unknown update type %q", ErrInvalidLocalList, updateType)
+	}
+
+	if listVersion <= 0 {
+		current, err := m.repos.Chargers().GetLocalListVersion(ctx, chargerID)
+		if err != nil {
+			return nil, 0, err
+		}
+		listVersion = current + 1
+	}
+
+	if list == nil && updateType == ocpp.UpdateTypeFull {
+		full, err := m.BuildFullLocalList(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = full
+	}
+
+	for _, entry := range list {
+		if entry.IDTag == "" || len(entry.IDTag) > maxLocalListIDTagLength {
+			return nil, 0, fmt.Errorf("%w: invalid idTag %q", ErrInvalidLocalList, entry.IDTag)
+		}
+	}
+
+	req := ocpp.SendLocalListRequest{
+		ListVersion:            listVersion,
+		LocalAuthorizationList: list,
+		UpdateType:             updateType,
+	}
+
+	var resp ocpp.SendLocalListResponse
+	if err := m.sender.SendCall(ctx, chargerID, ocpp.ActionSendLocalList, req, &resp); err != nil {
+		return nil, listVersion, fmt.Errorf("failed to send local list: %w", err)
+	}
+
+	if resp.Status == ocpp.UpdateStatusAccepted {
+		if err := m.repos.Chargers().SetLocalListVersion(ctx, chargerID, listVersion, m.clock.Now()); err != nil {
+			return &resp, listVersion, err
+		}
+	}
+
+	m.logger.Info("Sent local list",
+		slog.String("charger_id", chargerID),
+		slog.Int("list_version", listVersion),
+		slog.String("update_type", updateType),
+		slog.Int("entries", len(list)),
+		slog.String("status", resp.Status))
+
+	return &resp, listVersion, nil
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedCall is a CALL captured by fakeSender
+type recordedCall struct {
+	ChargerID string
+	Action    string
+	Payload   json.RawMessage
+}
+
+// fakeSender records outgoing calls and replies with canned responses per action
+type fakeSender struct {
+	calls     []recordedCall
+	responses map[string]interface{}
+	err       error
+}
+
+func (s *fakeSender) SendCall(ctx context.Context, chargerID, action string, request, response interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	s.calls = append(s.calls, recordedCall{ChargerID: chargerID, Action: action, Payload: payload})
+	if s.err != nil {
+		return s.err
+	}
+
+	body, err := json.Marshal(s.responses[action])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, response)
+}
+
+func TestBuildLocalList(t *testing.T) {
+	expiry := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	list := BuildLocalList([]*db.IDTag{
+		{IDTag: "TAG-1", Status: "Accepted", ExpiryDate: &expiry, ParentIDTag: "FLEET"},
+		{IDTag: "TAG-2", Status: "Blocked"},
+	})
+
+	payload, err := json.Marshal(ocpp.SendLocalListRequest{
+		ListVersion:            3,
+		LocalAuthorizationList: list,
+		UpdateType:             ocpp.UpdateTypeFull,
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"listVersion": 3,
+		"updateType": "Full",
+		"localAuthorizationList": [
+			{"idTag": "TAG-1", "idTagInfo": {"status": "Accepted", "expiryDate": "2025-06-01T00:00:00Z", "parentIdTag": "FLEET"}},
+			{"idTag": "TAG-2", "idTagInfo": {"status": "Blocked"}}
+		]
+	}`, string(payload))
+}
+
+func TestSendLocalListTracksVersion(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	_, err = repos.IDTags().Create(ctx, db.CreateIDTagRequest{IDTag: "TAG-1"})
+	require.NoError(t, err)
+
+	sender := &fakeSender{responses: map[string]interface{}{
+		ocpp.ActionSendLocalList: ocpp.SendLocalListResponse{Status: ocpp.UpdateStatusAccepted},
+	}}
+	manager := NewLocalListManager(repos, sender, fake, dbtest.Logger())
+
+	// The first full update gets version 1 and carries every stored tag
+	resp, version, err := manager.SendLocalList(ctx, "CP-1", 0, ocpp.UpdateTypeFull, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.UpdateStatusAccepted, resp.Status)
+	assert.Equal(t, 1, version)
+
+	require.Len(t, sender.calls, 1)
+	var sent ocpp.SendLocalListRequest
+	require.NoError(t, json.Unmarshal(sender.calls[0].Payload, &sent))
+	require.Len(t, sent.LocalAuthorizationList, 1)
+	assert.Equal(t, "TAG-1", sent.LocalAuthorizationList[0].IDTag)
+
+	stored, err := repos.Chargers().GetLocalListVersion(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored)
+
+	// The next update increments the stored version
+	_, version, err = manager.SendLocalList(ctx, "CP-1", 0, ocpp.UpdateTypeDifferential, []ocpp.AuthorizationData{{IDTag: "TAG-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	// A rejected update leaves the stored version unchanged
+	sender.responses[ocpp.ActionSendLocalList] = ocpp.SendLocalListResponse{Status: ocpp.UpdateStatusVersionMismatch}
+	resp, _, err = manager.SendLocalList(ctx, "CP-1", 10, ocpp.UpdateTypeFull, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.UpdateStatusVersionMismatch, resp.Status)
+
+	stored, err = repos.Chargers().GetLocalListVersion(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored)
+}
+
+func TestSendLocalListValidation(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+	sender := &fakeSender{}
+	manager := NewLocalListManager(repos, sender, fake, dbtest.Logger())
+
+	_, _, err := manager.SendLocalList(ctx, "CP-1", 1, "Partial", nil)
+	assert.ErrorIs(t, err, ErrInvalidLocalList)
+
+	_, _, err = manager.SendLocalList(ctx, "CP-1", 1, ocpp.UpdateTypeDifferential,
+		[]ocpp.AuthorizationData{{IDTag: "THIS-TAG-IS-TOO-LONG-FOR-OCPP"}})
+	assert.ErrorIs(t, err, ErrInvalidLocalList)
+	assert.Empty(t, sender.calls)
+
+	_, err = NewLocalListManager(repos, nil, fake, dbtest.Logger()).GetLocalListVersion(ctx, "CP-1")
+	assert.ErrorIs(t, err, ocpp.ErrNotConnected)
+}
+
+func TestGetLocalListVersion(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+	sender := &fakeSender{responses: map[string]interface{}{
+		ocpp.ActionGetLocalListVersion: ocpp.GetLocalListVersionResponse{ListVersion: 7},
+	}}
+
+	version, err := NewLocalListManager(repos, sender, fake, dbtest.Logger()).GetLocalListVersion(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, 7, version)
+	assert.Equal(t, ocpp.ActionGetLocalListVersion, sender.calls[0].Action)
+	assert.JSONEq(t, `{}`, string(sender.calls[0].Payload))
+}
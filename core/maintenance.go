@@ -0,0 +1,33 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/keeth/levity/db"
+)
+
+// settingMaintenanceMode is the settings key holding the maintenance flag
+const settingMaintenanceMode = "maintenance_mode"
+
+// MaintenanceMode reports whether new transactions are currently being refused
+func (s *System) MaintenanceMode(ctx context.Context) (bool, error) {
+	return maintenanceMode(ctx, s.repos.Settings())
+}
+
+// SetMaintenanceMode turns maintenance mode on or off. While it is on, new
+// transactions are rejected and sessions already in progress continue.
+func (s *System) SetMaintenanceMode(ctx context.Context, enabled bool) error {
+	if err := s.repos.Settings().Set(ctx, settingMaintenanceMode, strconv.FormatBool(enabled)); err != nil {
+		return fmt.Errorf("failed to set maintenance mode: %w", err)
+	}
+	s.logger.Warn("Maintenance mode changed", slog.Bool("enabled", enabled))
+	return nil
+}
+
+// maintenanceMode reads the persisted maintenance flag, which defaults to off
+func maintenanceMode(ctx context.Context, settings db.SettingsRepository) (bool, error) {
+	return settings.GetBool(ctx, settingMaintenanceMode, false)
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceModeRejectsStarts(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	system := &System{repos: repos, clock: fake, logger: dbtest.Logger()}
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	enabled, err := system.MaintenanceMode(ctx)
+	require.NoError(t, err)
+	assert.False(t, enabled, "maintenance mode defaults to off")
+
+	// A session started before maintenance keeps running
+	running, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+		ConnectorID: 1, IDTag: "TAG-1", MeterStart: 0, Timestamp: fake.Now(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AuthorizationAccepted, running.IDTagInfo.Status)
+
+	require.NoError(t, system.SetMaintenanceMode(ctx, true))
+	enabled, err = system.MaintenanceMode(ctx)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	rejected, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+		ConnectorID: 2, IDTag: "TAG-2", MeterStart: 0, Timestamp: fake.Now(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AuthorizationBlocked, rejected.IDTagInfo.Status)
+
+	active, err := repos.Transactions().GetActiveByConnector(ctx, "CP-1", 2)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	stopped, err := handler.StopTransaction(ctx, "CP-1", ocpp.StopTransactionRequest{
+		TransactionID: running.TransactionID, MeterStop: 2000, Timestamp: fake.Now(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AuthorizationAccepted, stopped.IDTagInfo.Status)
+
+	require.NoError(t, system.SetMaintenanceMode(ctx, false))
+	fake.Advance(time.Second)
+	allowed, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+		ConnectorID: 2, IDTag: "TAG-2", MeterStart: 0, Timestamp: fake.Now(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AuthorizationAccepted, allowed.IDTagInfo.Status)
+	assert.NotZero(t, allowed.TransactionID)
+}
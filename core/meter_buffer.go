@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/keeth/levity/db"
+)
+
+// meterBufferOverflowFactor bounds how many flush batches may queue up while
+// the database is failing before the oldest values are dropped
+const meterBufferOverflowFactor = 10
+
+// MeterValueBuffer accumulates meter values in memory and writes them with
+// batch inserts once the flush size is reached or the flush interval elapses
+type MeterValueBuffer struct {
+	size     int
+	interval time.Duration
+	repo     db.MeterValueRepository
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending []db.CreateMeterValueRequest
+}
+
+// NewMeterValueBuffer creates a new meter value buffer
+func NewMeterValueBuffer(size int, interval time.Duration, repo db.MeterValueRepository, logger *slog.Logger) *MeterValueBuffer {
+	return &MeterValueBuffer{
+		size:     size,
+		interval: interval,
+		repo:     repo,
+		logger:   logger,
+	}
+}
+
+// Add queues meter values and flushes immediately once the buffer is full.
+// Flush failures are logged and the values retried on the next flush.
+func (b *MeterValueBuffer) Add(ctx context.Context, values ...db.CreateMeterValueRequest) {
+	b.mu.Lock()
+	b.pending = append(b.pending, values...)
+	full := len(b.pending) >= b.size
+	b.mu.Unlock()
+
+	if full {
+		b.flushLogged(ctx)
+	}
+}
+
+// Len returns the number of meter values waiting to be flushed
+func (b *MeterValueBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// Flush writes every buffered meter value and returns how many were stored.
+// Values that could not be written are put back at the front of the buffer.
+func (b *MeterValueBuffer) Flush(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	created, err := b.repo.CreateBatch(ctx, batch)
+	if err != nil {
+		b.requeue(batch[len(created):])
+		return len(created), err
+	}
+	return len(created), nil
+}
+
+// Run flushes on the configured interval until the context is cancelled, then
+// flushes whatever is left so no values are lost on shutdown
+func (b *MeterValueBuffer) Run(ctx context.Context) {
+	interval := b.interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.flushLogged(context.Background())
+			return
+		case <-ticker.C:
+			b.flushLogged(ctx)
+		}
+	}
+}
+
+// flushLogged flushes the buffer and logs rather than returns failures
+func (b *MeterValueBuffer) flushLogged(ctx context.Context) {
+	stored, err := b.Flush(ctx)
+	if err != nil {
+		b.logger.Error("Failed to flush meter values",
+			slog.Int("stored", stored),
+			slog.Int("pending", b.Len()),
+			slog.Any("error", err))
+		return
+	}
+	if stored > 0 {
+		b.logger.Debug("Flushed meter values", slog.Int("stored", stored))
+	}
+}
+
+// requeue puts unwritten values back ahead of newer ones, dropping the oldest
+// when the buffer has grown past its overflow bound
+func (b *MeterValueBuffer) requeue(values []db.CreateMeterValueRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(values, b.pending...)
+	if limit := b.size * meterBufferOverflowFactor; len(b.pending) > limit {
+		dropped := len(b.pending) - limit
+		b.pending = b.pending[dropped:]
+		b.logger.Error("Meter value buffer overflowed, dropping oldest values", slog.Int("dropped", dropped))
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBufferTestRepos returns repositories with a charger to attach meter values to
+func newBufferTestRepos(t *testing.T) db.RepositoryManager {
+	t.Helper()
+	repos := dbtest.NewRepositories(t, clock.Real())
+	_, err := repos.Chargers().Create(context.Background(), db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	return repos
+}
+
+// storedMeterValues counts the meter values persisted for CP-1
+func storedMeterValues(t *testing.T, repos db.RepositoryManager) int {
+	t.Helper()
+	values, err := repos.MeterValues().GetByChargerID(context.Background(), "CP-1", db.ListOptions{Limit: 1000})
+	require.NoError(t, err)
+	return len(values)
+}
+
+func bufferSample(i int) db.CreateMeterValueRequest {
+	return db.CreateMeterValueRequest{
+		ChargerID:   "CP-1",
+		ConnectorID: 1,
+		Timestamp:   time.Date(2024, 5, 1, 12, 0, i, 0, time.UTC),
+		Measurand:   "Energy.Active.Import.Register",
+		Value:       dbtest.Float64(float64(i)),
+		Unit:        db.UnitWh,
+	}
+}
+
+func TestMeterValueBufferFlushesWhenFull(t *testing.T) {
+	ctx := context.Background()
+	repos := newBufferTestRepos(t)
+	buffer := NewMeterValueBuffer(3, time.Hour, repos.MeterValues(), dbtest.Logger())
+
+	buffer.Add(ctx, bufferSample(1), bufferSample(2))
+	assert.Equal(t, 2, buffer.Len())
+	assert.Zero(t, storedMeterValues(t, repos), "nothing is written before the buffer fills")
+
+	buffer.Add(ctx, bufferSample(3))
+	assert.Zero(t, buffer.Len())
+	assert.Equal(t, 3, storedMeterValues(t, repos))
+}
+
+func TestMeterValueBufferFlushesOnInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repos := newBufferTestRepos(t)
+	buffer := NewMeterValueBuffer(100, 10*time.Millisecond, repos.MeterValues(), dbtest.Logger())
+	go buffer.Run(ctx)
+
+	buffer.Add(ctx, bufferSample(1))
+	assert.Eventually(t, func() bool {
+		return storedMeterValues(t, repos) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestMeterValueBufferFlushesOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	repos := newBufferTestRepos(t)
+	buffer := NewMeterValueBuffer(100, time.Hour, repos.MeterValues(), dbtest.Logger())
+
+	done := make(chan struct{})
+	go func() {
+		buffer.Run(ctx)
+		close(done)
+	}()
+
+	buffer.Add(ctx, bufferSample(1), bufferSample(2))
+	cancel()
+	<-done
+
+	assert.Zero(t, buffer.Len())
+	assert.Equal(t, 2, storedMeterValues(t, repos))
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/ocpp"
+)
+
+// MeterConfigurator configures which meter values chargers sample and how often
+type MeterConfigurator struct {
+	interval   time.Duration
+	measurands []string
+	sender     ocpp.Sender
+	logger     *slog.Logger
+}
+
+// NewMeterConfigurator creates a meter configurator from the OCPP configuration
+func NewMeterConfigurator(cfg config.OCPPConfig, sender ocpp.Sender, logger *slog.Logger) *MeterConfigurator {
+	return &MeterConfigurator{
+		interval:   cfg.MeterSampleInterval,
+		measurands: cfg.TriggerMeterMeasurands,
+		sender:     sender,
+		logger:     logger,
+	}
+}
+
+// Enabled reports whether any meter value configuration is pushed to chargers
+func (m *MeterConfigurator) Enabled() bool {
+	return m.interval > 0 || len(m.measurands) > 0
+}
+
+// Configure pushes the configured MeterValueSampleInterval and
+// MeterValuesSampledData to a charger. A charger that rejects a key keeps its
+// own setting; only failures to reach the charger are returned.
+func (m *MeterConfigurator) Configure(ctx context.Context, chargerID string) error {
+	if m.interval > 0 {
+		seconds := strconv.Itoa(int(m.interval / time.Second))
+		if err := m.changeConfiguration(ctx, chargerID, ocpp.ConfigMeterValueSampleInterval, seconds); err != nil {
+			return err
+		}
+	}
+	return m.configureMeasurands(ctx, chargerID)
+}
+
+// TriggerMeterValues asks a charger to send MeterValues now, first setting the
+// measurands it should sample. A nil connectorID covers every connector.
+func (m *MeterConfigurator) TriggerMeterValues(ctx context.Context, chargerID string, connectorID *int) (string, error) {
+	if m.sender == nil {
+		return "", ocpp.ErrNotConnected
+	}
+
+	if err := m.configureMeasurands(ctx, chargerID); err != nil {
+		return "", err
+	}
+
+	var resp ocpp.TriggerMessageResponse
+	req := ocpp.TriggerMessageRequest{RequestedMessage: ocpp.MessageTriggerMeterValues, ConnectorID: connectorID}
+	if err := m.sender.SendCall(ctx, chargerID, ocpp.ActionTriggerMessage, req, &resp); err != nil {
+		return "", fmt.Errorf("failed to trigger meter values: %w", err)
+	}
+
+	return resp.Status, nil
+}
+
+// configureMeasurands pushes the configured measurands, if any
+func (m *MeterConfigurator) configureMeasurands(ctx context.Context, chargerID string) error {
+	if len(m.measurands) == 0 {
+		return nil
+	}
+	return m.changeConfiguration(ctx, chargerID, ocpp.ConfigMeterValuesSampledData, strings.Join(m.measurands, ","))
+}
+
+// changeConfiguration sets one configuration key on a charger
+func (m *MeterConfigurator) changeConfiguration(ctx context.Context, chargerID, key, value string) error {
+	if m.sender == nil {
+		return ocpp.ErrNotConnected
+	}
+
+	var resp ocpp.ChangeConfigurationResponse
+	req := ocpp.ChangeConfigurationRequest{Key: key, Value: value}
+	if err := m.sender.SendCall(ctx, chargerID, ocpp.ActionChangeConfiguration, req, &resp); err != nil {
+		return fmt.Errorf("failed to change %s: %w", key, err)
+	}
+
+	if resp.Status != ocpp.ConfigurationStatusAccepted {
+		m.logger.Warn("Charger did not accept configuration",
+			slog.String("charger_id", chargerID),
+			slog.String("key", key),
+			slog.String("value", value),
+			slog.String("status", resp.Status))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/events"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/core/webhook"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func meterConfigTestConfig() config.OCPPConfig {
+	return config.OCPPConfig{
+		HeartbeatInterval:      time.Minute,
+		StaleTimeout:           time.Hour,
+		MeterSampleInterval:    30 * time.Second,
+		TriggerMeterMeasurands: []string{"Energy.Active.Import.Register", "Power.Active.Import", "SoC"},
+	}
+}
+
+func TestBootPushesMeterConfiguration(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	cfg := &config.Config{OCPP: meterConfigTestConfig()}
+	cfg.Events.RelayInterval = time.Hour
+	system := &System{
+		config:   cfg,
+		logger:   dbtest.Logger(),
+		clock:    fake,
+		repos:    repos,
+		bus:      events.NewBus(),
+		webhooks: webhook.NewDispatcher(cfg.Webhooks, repos.Webhooks(), fake, dbtest.Logger()),
+		ocpp:     NewOCPPHandler(cfg.OCPP, repos, fake, dbtest.Logger()),
+	}
+	system.ocpp.OnBoot(system.afterBoot)
+
+	sender := &fakeSender{responses: map[string]interface{}{
+		ocpp.ActionChangeConfiguration: ocpp.ChangeConfigurationResponse{Status: ocpp.ConfigurationStatusAccepted},
+	}}
+	system.SetCommandSender(sender)
+	system.Start()
+
+	_, err := system.OCPP().BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{
+		ChargePointVendor: "Acme",
+		ChargePointModel:  "X1",
+	})
+	require.NoError(t, err)
+
+	// Shutdown waits for the background configuration push
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.NoError(t, system.Shutdown(shutdownCtx))
+
+	require.Len(t, sender.calls, 2)
+	assert.Equal(t, ocpp.ActionChangeConfiguration, sender.calls[0].Action)
+	assert.JSONEq(t, `{"key": "MeterValueSampleInterval", "value": "30"}`, string(sender.calls[0].Payload))
+	assert.Equal(t, ocpp.ActionChangeConfiguration, sender.calls[1].Action)
+	assert.JSONEq(t, `{"key": "MeterValuesSampledData", "value": "Energy.Active.Import.Register,Power.Active.Import,SoC"}`,
+		string(sender.calls[1].Payload))
+}
+
+func TestBootWithoutMeterConfiguration(t *testing.T) {
+	sender := &fakeSender{}
+	configurator := NewMeterConfigurator(config.OCPPConfig{}, sender, dbtest.Logger())
+	assert.False(t, configurator.Enabled())
+
+	require.NoError(t, configurator.Configure(context.Background(), "CP-1"))
+	assert.Empty(t, sender.calls)
+}
+
+func TestTriggerMeterValuesSetsMeasurands(t *testing.T) {
+	sender := &fakeSender{responses: map[string]interface{}{
+		ocpp.ActionChangeConfiguration: ocpp.ChangeConfigurationResponse{Status: ocpp.ConfigurationStatusRejected},
+		ocpp.ActionTriggerMessage:      ocpp.TriggerMessageResponse{Status: ocpp.TriggerMessageStatusAccepted},
+	}}
+	configurator := NewMeterConfigurator(meterConfigTestConfig(), sender, dbtest.Logger())
+
+	connectorID := 1
+	status, err := configurator.TriggerMeterValues(context.Background(), "CP-1", &connectorID)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.TriggerMessageStatusAccepted, status)
+
+	// A rejected measurand change still triggers with the charger's own measurands
+	require.Len(t, sender.calls, 2)
+	assert.JSONEq(t, `{"key": "MeterValuesSampledData", "value": "Energy.Active.Import.Register,Power.Active.Import,SoC"}`,
+		string(sender.calls[0].Payload))
+	assert.Equal(t, ocpp.ActionTriggerMessage, sender.calls[1].Action)
+	assert.JSONEq(t, `{"requestedMessage": "MeterValues", "connectorId": 1}`, string(sender.calls[1].Payload))
+}
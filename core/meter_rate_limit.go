@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+)
+
+// meterRateWindow is the period max_meter_values_per_minute is counted over
+const meterRateWindow = time.Minute
+
+// MeterRateLimiter caps how many sampled values each charger may submit per
+// minute, counted in fixed one-minute windows per charger
+type MeterRateLimiter struct {
+	limit int
+	clock clock.Clock
+
+	mu      sync.Mutex
+	windows map[string]*meterRateCount
+	swept   time.Time
+}
+
+// meterRateCount is the samples a charger submitted in its current window
+type meterRateCount struct {
+	start time.Time
+	count int
+}
+
+// NewMeterRateLimiter creates a limiter allowing limit samples per charger per minute
+func NewMeterRateLimiter(limit int, clk clock.Clock) *MeterRateLimiter {
+	return &MeterRateLimiter{
+		limit:   limit,
+		clock:   clk,
+		windows: make(map[string]*meterRateCount),
+	}
+}
+
+// Allow takes up to n samples from the charger's allowance for the current
+// window and returns how many were granted
+func (l *MeterRateLimiter) Allow(chargerID string, n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window := l.window(chargerID)
+	granted := min(n, l.limit-window.count)
+	if granted < 0 {
+		granted = 0
+	}
+	window.count += granted
+	return granted
+}
+
+// Wait blocks until the charger's window has room for n samples, or for a
+// full window when n exceeds the limit, and then takes them. It returns early
+// with the context's error.
+func (l *MeterRateLimiter) Wait(ctx context.Context, chargerID string, n int) error {
+	for {
+		l.mu.Lock()
+		window := l.window(chargerID)
+		if window.count == 0 || window.count+n <= l.limit {
+			window.count += n
+			l.mu.Unlock()
+			return nil
+		}
+		delay := window.start.Add(meterRateWindow).Sub(l.clock.Now())
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.clock.After(delay):
+		}
+	}
+}
+
+// window returns the charger's current window, starting a new one once the
+// previous has ended. The caller must hold l.mu.
+func (l *MeterRateLimiter) window(chargerID string) *meterRateCount {
+	now := l.clock.Now()
+	l.sweep(now)
+
+	window, ok := l.windows[chargerID]
+	if !ok || !now.Before(window.start.Add(meterRateWindow)) {
+		window = &meterRateCount{start: now}
+		l.windows[chargerID] = window
+	}
+	return window
+}
+
+// sweep drops ended windows, at most once per window period, so chargers that
+// stop sending do not keep an entry. The caller must hold l.mu.
+func (l *MeterRateLimiter) sweep(now time.Time) {
+	if now.Before(l.swept.Add(meterRateWindow)) {
+		return
+	}
+	for chargerID, window := range l.windows {
+		if !now.Before(window.start.Add(meterRateWindow)) {
+			delete(l.windows, chargerID)
+		}
+	}
+	l.swept = now
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleChargerMonitor(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	require.NoError(t, repos.Chargers().UpdateConnectionStatus(ctx, "CP-1", true))
+	require.NoError(t, repos.Chargers().UpdateLastHeartbeat(ctx, "CP-1", fake.Now()))
+
+	monitor := NewStaleChargerMonitor(3*time.Minute, time.Minute, repos, fake, dbtest.Logger())
+
+	// Within the timeout the charger stays connected
+	fake.Advance(2 * time.Minute)
+	stale, err := monitor.CheckOnce(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, stale)
+
+	// Past the timeout the charger is marked disconnected
+	fake.Advance(2 * time.Minute)
+	stale, err = monitor.CheckOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CP-1"}, stale)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.False(t, charger.IsConnected)
+}
+
+func TestHeartbeatSLAMonitorRaisesOneAlertPerCooldown(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	require.NoError(t, repos.Chargers().UpdateConnectionStatus(ctx, "CP-1", true))
+	require.NoError(t, repos.Chargers().UpdateLastHeartbeat(ctx, "CP-1", fake.Now()))
+
+	monitor := NewHeartbeatSLAMonitor(time.Minute, 1.5, 10*time.Minute, repos, nil, fake, dbtest.Logger())
+
+	pendingAlerts := func() int {
+		pending, err := repos.Outbox().GetPending(ctx, 10)
+		require.NoError(t, err)
+		return len(pending)
+	}
+
+	// Within the SLA no alert is raised
+	fake.Advance(80 * time.Second)
+	missed, err := monitor.CheckOnce(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, missed)
+	assert.Equal(t, 0, pendingAlerts())
+
+	// Past the SLA the charger is alerted on once
+	fake.Advance(20 * time.Second)
+	missed, err = monitor.CheckOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CP-1"}, missed)
+
+	pending, err := repos.Outbox().GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, TopicHeartbeatMissed, pending[0].Topic)
+	assert.Contains(t, pending[0].Payload, `"charger_id":"CP-1"`)
+
+	// Still silent within the cooldown: no further alerts
+	for i := 0; i < 5; i++ {
+		fake.Advance(time.Minute)
+		missed, err = monitor.CheckOnce(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, missed)
+	}
+	assert.Equal(t, 1, pendingAlerts())
+
+	// The charger is still connected; the monitor does not change its state
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.True(t, charger.IsConnected)
+
+	// Once the cooldown has passed a still-silent charger is alerted on again
+	fake.Advance(5 * time.Minute)
+	missed, err = monitor.CheckOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CP-1"}, missed)
+	assert.Equal(t, 2, pendingAlerts())
+}
+
+func TestHeartbeatSLAMonitorForgetsDisconnectedChargers(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+
+	for _, id := range []string{"CP-1", "CP-2"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+		require.NoError(t, repos.Chargers().UpdateConnectionStatus(ctx, id, true))
+		require.NoError(t, repos.Chargers().UpdateLastHeartbeat(ctx, id, fake.Now()))
+	}
+
+	monitor := NewHeartbeatSLAMonitor(time.Minute, 1.5, 10*time.Minute, repos, nil, fake, dbtest.Logger())
+
+	fake.Advance(2 * time.Minute)
+	missed, err := monitor.CheckOnce(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"CP-1", "CP-2"}, missed)
+	assert.Len(t, monitor.alerted, 2)
+
+	// A charger that disconnects is forgotten at the next check
+	require.NoError(t, repos.Chargers().UpdateConnectionStatus(ctx, "CP-1", false))
+	fake.Advance(time.Minute)
+	missed, err = monitor.CheckOnce(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, missed)
+	assert.NotContains(t, monitor.alerted, "CP-1")
+	assert.Contains(t, monitor.alerted, "CP-2")
+
+	// A charger that recovers is forgotten once its cooldown has passed
+	require.NoError(t, repos.Chargers().UpdateLastHeartbeat(ctx, "CP-2", fake.Now()))
+	fake.Advance(10 * time.Minute)
+	require.NoError(t, repos.Chargers().UpdateLastHeartbeat(ctx, "CP-2", fake.Now()))
+	missed, err = monitor.CheckOnce(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, missed)
+	assert.Empty(t, monitor.alerted)
+}
+
+func TestRetentionJob(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	_, err = repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+		ChargerID:   "CP-1",
+		ConnectorID: 1,
+		Timestamp:   fake.Now(),
+		Measurand:   "Energy.Active.Import.Register",
+		Value:       dbtest.Float64(1200),
+	})
+	require.NoError(t, err)
+
+	chargerErr, err := repos.Errors().Create(ctx, db.CreateChargerErrorRequest{ChargerID: "CP-1", ErrorCode: "GroundFailure"})
+	require.NoError(t, err)
+	require.NoError(t, repos.Errors().Resolve(ctx, chargerErr.ID, fake.Now()))
+
+	job := NewRetentionJob(config.RetentionConfig{
+		MeterValuesAge: 30 * 24 * time.Hour,
+		ErrorsAge:      7 * 24 * time.Hour,
+	}, repos, fake, dbtest.Logger())
+
+	// Nothing is old enough yet
+	fake.Advance(24 * time.Hour)
+	result, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, RetentionResult{}, result)
+
+	// Resolved errors expire first
+	fake.Advance(7 * 24 * time.Hour)
+	result, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, RetentionResult{ErrorsDeleted: 1}, result)
+
+	// Meter values expire after their own age
+	fake.Advance(30 * 24 * time.Hour)
+	result, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, RetentionResult{MeterValuesDeleted: 1}, result)
+
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestRetentionJobMeasurandAges(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	for _, measurand := range []string{"Energy.Active.Import.Register", "SoC", "Temperature", "Voltage"} {
+		_, err := repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			ChargerID:   "CP-1",
+			ConnectorID: 1,
+			Timestamp:   fake.Now(),
+			Measurand:   measurand,
+			Value:       dbtest.Float64(1),
+		})
+		require.NoError(t, err)
+	}
+
+	job := NewRetentionJob(config.RetentionConfig{
+		MeterValuesAge: 30 * 24 * time.Hour,
+		MeasurandAges: map[string]time.Duration{
+			"Temperature": 24 * time.Hour,
+			"SoC":         7 * 24 * time.Hour,
+			"Voltage":     0, // kept forever
+		},
+	}, repos, fake, dbtest.Logger())
+
+	remaining := func() []string {
+		values, err := repos.MeterValues().GetByChargerID(ctx, "CP-1", db.ListOptions{Limit: 10, OrderBy: "measurand", SortDir: "ASC"})
+		require.NoError(t, err)
+		var measurands []string
+		for _, v := range values {
+			measurands = append(measurands, v.Measurand)
+		}
+		return measurands
+	}
+
+	fake.Advance(2 * 24 * time.Hour)
+	result, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.MeterValuesDeleted)
+	assert.ElementsMatch(t, []string{"Energy.Active.Import.Register", "SoC", "Voltage"}, remaining())
+
+	fake.Advance(7 * 24 * time.Hour)
+	result, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.MeterValuesDeleted)
+	assert.ElementsMatch(t, []string{"Energy.Active.Import.Register", "Voltage"}, remaining())
+
+	// Unlisted measurands follow the global age; a zero age is never pruned
+	fake.Advance(30 * 24 * time.Hour)
+	result, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.MeterValuesDeleted)
+	assert.ElementsMatch(t, []string{"Voltage"}, remaining())
+}
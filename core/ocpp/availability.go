@@ -0,0 +1,25 @@
+package ocpp
+
+// Availability types requested in ChangeAvailability.req
+const (
+	AvailabilityTypeInoperative = "Inoperative"
+	AvailabilityTypeOperative   = "Operative"
+)
+
+// Availability statuses returned in ChangeAvailability.conf
+const (
+	AvailabilityStatusAccepted  = "Accepted"
+	AvailabilityStatusRejected  = "Rejected"
+	AvailabilityStatusScheduled = "Scheduled"
+)
+
+// ChangeAvailabilityRequest is the ChangeAvailability.req payload
+type ChangeAvailabilityRequest struct {
+	ConnectorID int    `json:"connectorId"`
+	Type        string `json:"type"`
+}
+
+// ChangeAvailabilityResponse is the ChangeAvailability.conf payload
+type ChangeAvailabilityResponse struct {
+	Status string `json:"status"`
+}
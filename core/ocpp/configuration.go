@@ -0,0 +1,75 @@
+package ocpp
+
+// Standard configuration keys for meter value sampling
+const (
+	ConfigMeterValueSampleInterval = "MeterValueSampleInterval"
+	ConfigMeterValuesSampledData   = "MeterValuesSampledData"
+)
+
+// ConfigGetConfigurationMaxKeys is the most keys a charger accepts in one GetConfiguration.req
+const ConfigGetConfigurationMaxKeys = "GetConfigurationMaxKeys"
+
+// CoreConfigurationKeys are the keys the Core profile requires every charger
+// to report
+var CoreConfigurationKeys = []string{
+	"AuthorizeRemoteTxRequests",
+	"ClockAlignedDataInterval",
+	"ConnectionTimeOut",
+	"ConnectorPhaseRotation",
+	ConfigGetConfigurationMaxKeys,
+	"HeartbeatInterval",
+	"LocalAuthorizeOffline",
+	"LocalPreAuthorize",
+	"MeterValuesAlignedData",
+	ConfigMeterValuesSampledData,
+	ConfigMeterValueSampleInterval,
+	"NumberOfConnectors",
+	"ResetRetries",
+	"StopTransactionOnEVSideDisconnect",
+	"StopTransactionOnInvalidId",
+	"StopTxnAlignedData",
+	"StopTxnSampledData",
+	"SupportedFeatureProfiles",
+	"TransactionMessageAttempts",
+	"TransactionMessageRetryInterval",
+	"UnlockConnectorOnEVSideDisconnect",
+}
+
+// Configuration statuses returned in ChangeConfiguration.conf
+const (
+	ConfigurationStatusAccepted       = "Accepted"
+	ConfigurationStatusRejected       = "Rejected"
+	ConfigurationStatusRebootRequired = "RebootRequired"
+	ConfigurationStatusNotSupported   = "NotSupported"
+)
+
+// ChangeConfigurationRequest is the ChangeConfiguration.req payload
+type ChangeConfigurationRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ChangeConfigurationResponse is the ChangeConfiguration.conf payload
+type ChangeConfigurationResponse struct {
+	Status string `json:"status"`
+}
+
+// GetConfigurationRequest is the GetConfiguration.req payload. No keys asks
+// for every key.
+type GetConfigurationRequest struct {
+	Key []string `json:"key,omitempty"`
+}
+
+// KeyValue is one configuration key in GetConfiguration.conf. Value is nil
+// when the key is set but its value is not reported.
+type KeyValue struct {
+	Key      string  `json:"key"`
+	Readonly bool    `json:"readonly"`
+	Value    *string `json:"value,omitempty"`
+}
+
+// GetConfigurationResponse is the GetConfiguration.conf payload
+type GetConfigurationResponse struct {
+	ConfigurationKey []KeyValue `json:"configurationKey,omitempty"`
+	UnknownKey       []string   `json:"unknownKey,omitempty"`
+}
@@ -0,0 +1,196 @@
+package ocpp
+
+import (
+	"strings"
+	"time"
+)
+
+// Charge Point initiated actions
+const (
+	ActionBootNotification   = "BootNotification"
+	ActionHeartbeat          = "Heartbeat"
+	ActionMeterValues        = "MeterValues"
+	ActionStartTransaction   = "StartTransaction"
+	ActionStopTransaction    = "StopTransaction"
+	ActionStatusNotification = "StatusNotification"
+)
+
+// Registration statuses returned in BootNotification.conf
+const (
+	RegistrationAccepted = "Accepted"
+	RegistrationPending  = "Pending"
+	RegistrationRejected = "Rejected"
+)
+
+// Authorization statuses returned in IDTagInfo
+const (
+	AuthorizationAccepted     = "Accepted"
+	AuthorizationBlocked      = "Blocked"
+	AuthorizationExpired      = "Expired"
+	AuthorizationInvalid      = "Invalid"
+	AuthorizationConcurrentTx = "ConcurrentTx"
+)
+
+// StopTransaction reasons with server-side meaning
+const (
+	ReasonLocal  = "Local"
+	ReasonRemote = "Remote"
+)
+
+// Connector statuses reported in StatusNotification
+const (
+	ChargePointStatusAvailable     = "Available"
+	ChargePointStatusPreparing     = "Preparing"
+	ChargePointStatusCharging      = "Charging"
+	ChargePointStatusSuspendedEVSE = "SuspendedEVSE"
+	ChargePointStatusSuspendedEV   = "SuspendedEV"
+	ChargePointStatusFinishing     = "Finishing"
+	ChargePointStatusReserved      = "Reserved"
+	ChargePointStatusUnavailable   = "Unavailable"
+	ChargePointStatusFaulted       = "Faulted"
+)
+
+// ConnectorStatus is a status a StatusNotification reports for a connector,
+// one of the ChargePointStatus constants
+type ConnectorStatus string
+
+// connectorStatuses maps the lower-cased OCPP connector statuses to their spelling
+var connectorStatuses = map[string]ConnectorStatus{
+	"available":     ChargePointStatusAvailable,
+	"preparing":     ChargePointStatusPreparing,
+	"charging":      ChargePointStatusCharging,
+	"suspendedevse": ChargePointStatusSuspendedEVSE,
+	"suspendedev":   ChargePointStatusSuspendedEV,
+	"finishing":     ChargePointStatusFinishing,
+	"reserved":      ChargePointStatusReserved,
+	"unavailable":   ChargePointStatusUnavailable,
+	"faulted":       ChargePointStatusFaulted,
+}
+
+// ParseConnectorStatus normalizes a reported status to its OCPP spelling,
+// ignoring case and surrounding whitespace. It reports false for a status
+// outside the OCPP set.
+func ParseConnectorStatus(status string) (ConnectorStatus, bool) {
+	normalized, ok := connectorStatuses[strings.ToLower(strings.TrimSpace(status))]
+	return normalized, ok
+}
+
+// IsValid reports whether s is spelled exactly as one of the OCPP connector statuses
+func (s ConnectorStatus) IsValid() bool {
+	normalized, ok := ParseConnectorStatus(string(s))
+	return ok && normalized == s
+}
+
+// ChargePointErrorNoError is the StatusNotification error code when no error is present
+const ChargePointErrorNoError = "NoError"
+
+// DateTimeFormat is the layout used for dateTime values sent to chargers
+const DateTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// BootNotificationRequest is the BootNotification.req payload
+type BootNotificationRequest struct {
+	ChargePointVendor       string `json:"chargePointVendor"`
+	ChargePointModel        string `json:"chargePointModel"`
+	ChargePointSerialNumber string `json:"chargePointSerialNumber,omitempty"`
+	ChargeBoxSerialNumber   string `json:"chargeBoxSerialNumber,omitempty"`
+	FirmwareVersion         string `json:"firmwareVersion,omitempty"`
+	Iccid                   string `json:"iccid,omitempty"`
+	Imsi                    string `json:"imsi,omitempty"`
+	MeterType               string `json:"meterType,omitempty"`
+	MeterSerialNumber       string `json:"meterSerialNumber,omitempty"`
+}
+
+// BootNotificationResponse is the BootNotification.conf payload
+type BootNotificationResponse struct {
+	Status      string `json:"status"`
+	CurrentTime string `json:"currentTime"`
+	Interval    int    `json:"interval"`
+}
+
+// HeartbeatRequest is the Heartbeat.req payload
+type HeartbeatRequest struct{}
+
+// HeartbeatResponse is the Heartbeat.conf payload
+type HeartbeatResponse struct {
+	CurrentTime string `json:"currentTime"`
+}
+
+// Sampled value defaults applied when a charger omits the field
+const (
+	DefaultMeasurand = "Energy.Active.Import.Register"
+	DefaultUnit      = "Wh"
+	DefaultContext   = "Sample.Periodic"
+	DefaultLocation  = "Outlet"
+	DefaultFormat    = "Raw"
+)
+
+// SampledValue is a single measured value within a MeterValue
+type SampledValue struct {
+	Value     string `json:"value"`
+	Context   string `json:"context,omitempty"`
+	Format    string `json:"format,omitempty"`
+	Measurand string `json:"measurand,omitempty"`
+	Phase     string `json:"phase,omitempty"`
+	Location  string `json:"location,omitempty"`
+	Unit      string `json:"unit,omitempty"`
+}
+
+// MeterValue is a set of sampled values taken at the same time
+type MeterValue struct {
+	Timestamp    time.Time      `json:"timestamp"`
+	SampledValue []SampledValue `json:"sampledValue"`
+}
+
+// MeterValuesRequest is the MeterValues.req payload
+type MeterValuesRequest struct {
+	ConnectorID   int          `json:"connectorId"`
+	TransactionID *int         `json:"transactionId,omitempty"`
+	MeterValue    []MeterValue `json:"meterValue"`
+}
+
+// MeterValuesResponse is the MeterValues.conf payload
+type MeterValuesResponse struct{}
+
+// StartTransactionRequest is the StartTransaction.req payload
+type StartTransactionRequest struct {
+	ConnectorID   int       `json:"connectorId"`
+	IDTag         string    `json:"idTag"`
+	MeterStart    int       `json:"meterStart"`
+	ReservationID *int      `json:"reservationId,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// StartTransactionResponse is the StartTransaction.conf payload
+type StartTransactionResponse struct {
+	IDTagInfo     IDTagInfo `json:"idTagInfo"`
+	TransactionID int       `json:"transactionId"`
+}
+
+// StopTransactionRequest is the StopTransaction.req payload
+type StopTransactionRequest struct {
+	IDTag           string       `json:"idTag,omitempty"`
+	MeterStop       int          `json:"meterStop"`
+	Timestamp       time.Time    `json:"timestamp"`
+	TransactionID   int          `json:"transactionId"`
+	Reason          string       `json:"reason,omitempty"`
+	TransactionData []MeterValue `json:"transactionData,omitempty"`
+}
+
+// StopTransactionResponse is the StopTransaction.conf payload
+type StopTransactionResponse struct {
+	IDTagInfo *IDTagInfo `json:"idTagInfo,omitempty"`
+}
+
+// StatusNotificationRequest is the StatusNotification.req payload
+type StatusNotificationRequest struct {
+	ConnectorID     int        `json:"connectorId"`
+	ErrorCode       string     `json:"errorCode"`
+	Info            string     `json:"info,omitempty"`
+	Status          string     `json:"status"`
+	Timestamp       *time.Time `json:"timestamp,omitempty"`
+	VendorID        string     `json:"vendorId,omitempty"`
+	VendorErrorCode string     `json:"vendorErrorCode,omitempty"`
+}
+
+// StatusNotificationResponse is the StatusNotification.conf payload
+type StatusNotificationResponse struct{}
@@ -0,0 +1,97 @@
+package ocpp
+
+import "errors"
+
+// internalErrorDescription answers a CALL whose handler failed with an error
+// that is not a HandlerError; the error itself is logged, not sent
+const internalErrorDescription = "Failed to process request"
+
+// HandlerError is an error a CALL handler returns to answer the charger with
+// a specific CALLERROR. Any other error is answered with InternalError.
+type HandlerError struct {
+	Code        string
+	Description string
+	Details     map[string]interface{}
+}
+
+// NewHandlerError creates a handler error with a CALLERROR code, description and details
+func NewHandlerError(code, description string, details map[string]interface{}) *HandlerError {
+	return &HandlerError{Code: code, Description: description, Details: details}
+}
+
+// NewNotImplementedError answers a CALL for an action the server does not know
+func NewNotImplementedError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeNotImplemented, description, nil)
+}
+
+// NewNotSupportedError answers a CALL for a known action the server does not support
+func NewNotSupportedError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeNotSupported, description, nil)
+}
+
+// NewInternalError answers a CALL the server failed to process
+func NewInternalError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeInternalError, description, nil)
+}
+
+// NewProtocolError answers a CALL with an incomplete payload
+func NewProtocolError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeProtocolError, description, nil)
+}
+
+// NewSecurityError answers a CALL the charger is not allowed to make
+func NewSecurityError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeSecurityError, description, nil)
+}
+
+// NewFormationViolationError answers a CALL whose payload does not decode
+func NewFormationViolationError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeFormationViolation, description, nil)
+}
+
+// NewPropertyConstraintError answers a CALL with a field holding an invalid value
+func NewPropertyConstraintError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodePropertyConstraint, description, nil)
+}
+
+// NewOccurenceConstraintError answers a CALL with a missing or repeated field
+func NewOccurenceConstraintError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeOccurenceConstraint, description, nil)
+}
+
+// NewTypeConstraintError answers a CALL with a field of the wrong type
+func NewTypeConstraintError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeTypeConstraint, description, nil)
+}
+
+// NewGenericError answers a CALL that failed for a reason no other code covers
+func NewGenericError(description string) *HandlerError {
+	return NewHandlerError(ErrorCodeGenericError, description, nil)
+}
+
+// Error implements error
+func (e *HandlerError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// Frame builds the CALLERROR frame answering the CALL with uniqueID
+func (e *HandlerError) Frame(uniqueID string) []interface{} {
+	var details interface{} = struct{}{}
+	if len(e.Details) > 0 {
+		details = e.Details
+	}
+	return []interface{}{MessageTypeCallError, uniqueID, e.Code, e.Description, details}
+}
+
+// AsHandlerError returns the HandlerError in err's chain, or an InternalError
+// that does not reveal err for any other error
+func AsHandlerError(err error) *HandlerError {
+	var handlerErr *HandlerError
+	if errors.As(err, &handlerErr) {
+		return handlerErr
+	}
+	return NewInternalError(internalErrorDescription)
+}
@@ -0,0 +1,51 @@
+package ocpp
+
+import "time"
+
+// Local list update types
+const (
+	UpdateTypeDifferential = "Differential"
+	UpdateTypeFull         = "Full"
+)
+
+// SendLocalList response statuses
+const (
+	UpdateStatusAccepted        = "Accepted"
+	UpdateStatusFailed          = "Failed"
+	UpdateStatusNotSupported    = "NotSupported"
+	UpdateStatusVersionMismatch = "VersionMismatch"
+)
+
+// IDTagInfo holds the authorization status of an idTag
+type IDTagInfo struct {
+	Status      string     `json:"status"`
+	ExpiryDate  *time.Time `json:"expiryDate,omitempty"`
+	ParentIDTag string     `json:"parentIdTag,omitempty"`
+}
+
+// AuthorizationData is a single entry of a local authorization list. A nil
+// IDTagInfo in a Differential update removes the entry.
+type AuthorizationData struct {
+	IDTag     string     `json:"idTag"`
+	IDTagInfo *IDTagInfo `json:"idTagInfo,omitempty"`
+}
+
+// GetLocalListVersionRequest is the GetLocalListVersion.req payload
+type GetLocalListVersionRequest struct{}
+
+// GetLocalListVersionResponse is the GetLocalListVersion.conf payload
+type GetLocalListVersionResponse struct {
+	ListVersion int `json:"listVersion"`
+}
+
+// SendLocalListRequest is the SendLocalList.req payload
+type SendLocalListRequest struct {
+	ListVersion            int                 `json:"listVersion"`
+	LocalAuthorizationList []AuthorizationData `json:"localAuthorizationList,omitempty"`
+	UpdateType             string              `json:"updateType"`
+}
+
+// SendLocalListResponse is the SendLocalList.conf payload
+type SendLocalListResponse struct {
+	Status string `json:"status"`
+}
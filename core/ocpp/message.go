@@ -0,0 +1,154 @@
+package ocpp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Message is a decoded OCPP-J frame of any message type. Action and Payload
+// are set for a CALL, Payload for a CALLRESULT and the Error fields for a
+// CALLERROR.
+type Message struct {
+	MessageType      int
+	UniqueID         string
+	Action           string
+	Payload          json.RawMessage
+	ErrorCode        string
+	ErrorDescription string
+	ErrorDetails     json.RawMessage
+}
+
+// FrameError is returned by ParseMessage for a frame that is not valid
+// OCPP-J. MessageType and UniqueID are set as far as the frame could be read,
+// so a malformed CALL can still be answered with a CALLERROR carrying Code.
+type FrameError struct {
+	MessageType int
+	UniqueID    string
+	Code        string
+	Description string
+}
+
+// Error implements error
+func (e *FrameError) Error() string {
+	return "malformed OCPP frame: " + e.Description
+}
+
+// HandlerError returns the CALLERROR answering the malformed frame
+func (e *FrameError) HandlerError() *HandlerError {
+	return NewHandlerError(e.Code, e.Description, nil)
+}
+
+// ParseMessage decodes an OCPP-J frame:
+//
+//	[2, uniqueId, action, payload]                              CALL
+//	[3, uniqueId, payload]                                      CALLRESULT
+//	[4, uniqueId, errorCode, errorDescription, errorDetails]    CALLERROR
+//
+// Any other shape is reported as a *FrameError.
+func ParseMessage(data []byte) (*Message, error) {
+	var frame []json.RawMessage
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return nil, &FrameError{Code: ErrorCodeFormationViolation, Description: "frame is not a JSON array: " + err.Error()}
+	}
+	if len(frame) < 3 {
+		return nil, &FrameError{Code: ErrorCodeFormationViolation, Description: fmt.Sprintf("frame has %d elements, want at least 3", len(frame))}
+	}
+
+	msg := &Message{}
+	if err := json.Unmarshal(frame[0], &msg.MessageType); err != nil {
+		return nil, &FrameError{Code: ErrorCodeFormationViolation, Description: "message type is not an integer"}
+	}
+	if err := json.Unmarshal(frame[1], &msg.UniqueID); err != nil || msg.UniqueID == "" {
+		return nil, &FrameError{MessageType: msg.MessageType, Code: ErrorCodeFormationViolation, Description: "unique id is not a non-empty string"}
+	}
+
+	invalid := func(description string) error {
+		return &FrameError{MessageType: msg.MessageType, UniqueID: msg.UniqueID, Code: ErrorCodeFormationViolation, Description: description}
+	}
+
+	switch msg.MessageType {
+	case MessageTypeCall:
+		if len(frame) != 4 {
+			return nil, invalid("CALL must be [2, uniqueId, action, payload]")
+		}
+		if err := json.Unmarshal(frame[2], &msg.Action); err != nil || msg.Action == "" {
+			return nil, invalid("CALL action is not a non-empty string")
+		}
+		if !isObject(frame[3]) {
+			return nil, invalid("CALL payload is not a JSON object")
+		}
+		msg.Payload = frame[3]
+
+	case MessageTypeCallResult:
+		if len(frame) != 3 {
+			return nil, invalid("CALLRESULT must be [3, uniqueId, payload]")
+		}
+		if !isObject(frame[2]) {
+			return nil, invalid("CALLRESULT payload is not a JSON object")
+		}
+		msg.Payload = frame[2]
+
+	case MessageTypeCallError:
+		if len(frame) != 5 {
+			return nil, invalid("CALLERROR must be [4, uniqueId, errorCode, errorDescription, errorDetails]")
+		}
+		if err := json.Unmarshal(frame[2], &msg.ErrorCode); err != nil || msg.ErrorCode == "" {
+			return nil, invalid("CALLERROR error code is not a non-empty string")
+		}
+		if err := json.Unmarshal(frame[3], &msg.ErrorDescription); err != nil {
+			return nil, invalid("CALLERROR error description is not a string")
+		}
+		if !isObject(frame[4]) {
+			return nil, invalid("CALLERROR error details is not a JSON object")
+		}
+		msg.ErrorDetails = frame[4]
+
+	default:
+		return nil, &FrameError{
+			MessageType: msg.MessageType,
+			UniqueID:    msg.UniqueID,
+			Code:        ErrorCodeProtocolError,
+			Description: fmt.Sprintf("unknown message type %d", msg.MessageType),
+		}
+	}
+
+	return msg, nil
+}
+
+// Marshal encodes the message as an OCPP-J frame. A nil payload or error
+// details is encoded as an empty object.
+func (m *Message) Marshal() ([]byte, error) {
+	var frame []interface{}
+	switch m.MessageType {
+	case MessageTypeCall:
+		if m.Action == "" {
+			return nil, fmt.Errorf("CALL %s has no action", m.UniqueID)
+		}
+		frame = []interface{}{m.MessageType, m.UniqueID, m.Action, objectOrEmpty(m.Payload)}
+	case MessageTypeCallResult:
+		frame = []interface{}{m.MessageType, m.UniqueID, objectOrEmpty(m.Payload)}
+	case MessageTypeCallError:
+		if m.ErrorCode == "" {
+			return nil, fmt.Errorf("CALLERROR %s has no error code", m.UniqueID)
+		}
+		frame = []interface{}{m.MessageType, m.UniqueID, m.ErrorCode, m.ErrorDescription, objectOrEmpty(m.ErrorDetails)}
+	default:
+		return nil, fmt.Errorf("unknown message type %d", m.MessageType)
+	}
+	return json.Marshal(frame)
+}
+
+// isObject reports whether raw is a JSON object
+func isObject(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// objectOrEmpty returns raw, or an empty object when raw is unset
+func objectOrEmpty(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage(`{}`)
+	}
+	return raw
+}
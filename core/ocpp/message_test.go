@@ -0,0 +1,112 @@
+package ocpp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessage(t *testing.T) {
+	t.Run("call", func(t *testing.T) {
+		msg, err := ParseMessage([]byte(`[2, "19223201", "BootNotification", {"chargePointVendor": "VendorX"}]`))
+		require.NoError(t, err)
+		assert.Equal(t, MessageTypeCall, msg.MessageType)
+		assert.Equal(t, "19223201", msg.UniqueID)
+		assert.Equal(t, "BootNotification", msg.Action)
+		assert.JSONEq(t, `{"chargePointVendor": "VendorX"}`, string(msg.Payload))
+	})
+
+	t.Run("call result", func(t *testing.T) {
+		msg, err := ParseMessage([]byte(`[3, "19223201", {"status": "Accepted"}]`))
+		require.NoError(t, err)
+		assert.Equal(t, MessageTypeCallResult, msg.MessageType)
+		assert.Equal(t, "19223201", msg.UniqueID)
+		assert.JSONEq(t, `{"status": "Accepted"}`, string(msg.Payload))
+	})
+
+	t.Run("call error", func(t *testing.T) {
+		msg, err := ParseMessage([]byte(`[4, "19223201", "NotImplemented", "Unknown action", {"action": "Foo"}]`))
+		require.NoError(t, err)
+		assert.Equal(t, MessageTypeCallError, msg.MessageType)
+		assert.Equal(t, "19223201", msg.UniqueID)
+		assert.Equal(t, ErrorCodeNotImplemented, msg.ErrorCode)
+		assert.Equal(t, "Unknown action", msg.ErrorDescription)
+		assert.JSONEq(t, `{"action": "Foo"}`, string(msg.ErrorDetails))
+	})
+}
+
+func TestParseMessageRejectsMalformedFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    string
+		uniqueID string
+		code     string
+	}{
+		{"invalid JSON", `[2, "1", "Heartbeat", {}`, "", ErrorCodeFormationViolation},
+		{"not an array", `{"action": "Heartbeat"}`, "", ErrorCodeFormationViolation},
+		{"too short", `[2, "1"]`, "", ErrorCodeFormationViolation},
+		{"message type not a number", `["2", "1", "Heartbeat", {}]`, "", ErrorCodeFormationViolation},
+		{"unique id not a string", `[2, 1, "Heartbeat", {}]`, "", ErrorCodeFormationViolation},
+		{"unknown message type", `[5, "1", "Heartbeat", {}]`, "1", ErrorCodeProtocolError},
+		{"call without payload", `[2, "1", "Heartbeat"]`, "1", ErrorCodeFormationViolation},
+		{"call with extra element", `[2, "1", "Heartbeat", {}, {}]`, "1", ErrorCodeFormationViolation},
+		{"call action not a string", `[2, "1", 7, {}]`, "1", ErrorCodeFormationViolation},
+		{"call payload not an object", `[2, "1", "Heartbeat", []]`, "1", ErrorCodeFormationViolation},
+		{"call result with extra element", `[3, "1", {}, {}]`, "1", ErrorCodeFormationViolation},
+		{"call result payload not an object", `[3, "1", "Accepted"]`, "1", ErrorCodeFormationViolation},
+		{"call error without details", `[4, "1", "GenericError", "Failed"]`, "1", ErrorCodeFormationViolation},
+		{"call error code not a string", `[4, "1", 500, "Failed", {}]`, "1", ErrorCodeFormationViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseMessage([]byte(tt.frame))
+			require.Error(t, err)
+			assert.Nil(t, msg)
+
+			var frameErr *FrameError
+			require.True(t, errors.As(err, &frameErr))
+			assert.Equal(t, tt.uniqueID, frameErr.UniqueID)
+			assert.Equal(t, tt.code, frameErr.Code)
+			assert.NotEmpty(t, frameErr.Description)
+			assert.Equal(t, tt.code, frameErr.HandlerError().Code)
+		})
+	}
+}
+
+func TestMessageMarshalRoundTrips(t *testing.T) {
+	messages := []*Message{
+		{MessageType: MessageTypeCall, UniqueID: "1", Action: "Heartbeat"},
+		{MessageType: MessageTypeCall, UniqueID: "2", Action: "Authorize", Payload: []byte(`{"idTag":"TAG"}`)},
+		{MessageType: MessageTypeCallResult, UniqueID: "3", Payload: []byte(`{"status":"Accepted"}`)},
+		{MessageType: MessageTypeCallError, UniqueID: "4", ErrorCode: ErrorCodeGenericError, ErrorDescription: "Failed"},
+	}
+
+	for _, msg := range messages {
+		data, err := msg.Marshal()
+		require.NoError(t, err)
+
+		parsed, err := ParseMessage(data)
+		require.NoError(t, err, string(data))
+		assert.Equal(t, msg.MessageType, parsed.MessageType)
+		assert.Equal(t, msg.UniqueID, parsed.UniqueID)
+		assert.Equal(t, msg.Action, parsed.Action)
+		assert.Equal(t, msg.ErrorCode, parsed.ErrorCode)
+		assert.Equal(t, msg.ErrorDescription, parsed.ErrorDescription)
+	}
+
+	data, err := (&Message{MessageType: MessageTypeCall, UniqueID: "1", Action: "Heartbeat"}).Marshal()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[2, "1", "Heartbeat", {}]`, string(data))
+
+	data, err = (&Message{MessageType: MessageTypeCallError, UniqueID: "4", ErrorCode: ErrorCodeGenericError}).Marshal()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[4, "4", "GenericError", "", {}]`, string(data))
+
+	_, err = (&Message{MessageType: 9, UniqueID: "1"}).Marshal()
+	assert.Error(t, err)
+	_, err = (&Message{MessageType: MessageTypeCall, UniqueID: "1"}).Marshal()
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+// Package ocpp defines the OCPP 1.6J actions and payloads exchanged with chargers.
+package ocpp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Central System initiated actions
+const (
+	ActionChangeAvailability     = "ChangeAvailability"
+	ActionChangeConfiguration    = "ChangeConfiguration"
+	ActionGetConfiguration       = "GetConfiguration"
+	ActionGetLocalListVersion    = "GetLocalListVersion"
+	ActionSendLocalList          = "SendLocalList"
+	ActionTriggerMessage         = "TriggerMessage"
+	ActionRemoteStartTransaction = "RemoteStartTransaction"
+	ActionRemoteStopTransaction  = "RemoteStopTransaction"
+	ActionReset                  = "Reset"
+)
+
+// OCPP-J message type identifiers, the first element of every frame
+const (
+	MessageTypeCall       = 2
+	MessageTypeCallResult = 3
+	MessageTypeCallError  = 4
+)
+
+// CALLERROR error codes
+const (
+	ErrorCodeNotImplemented      = "NotImplemented"
+	ErrorCodeNotSupported        = "NotSupported"
+	ErrorCodeInternalError       = "InternalError"
+	ErrorCodeProtocolError       = "ProtocolError"
+	ErrorCodeFormationViolation  = "FormationViolation"
+	ErrorCodeGenericError        = "GenericError"
+	ErrorCodeOccurenceConstraint = "OccurenceConstraintViolation"
+	ErrorCodePropertyConstraint  = "PropertyConstraintViolation"
+	ErrorCodeTypeConstraint      = "TypeConstraintViolation"
+	ErrorCodeSecurityError       = "SecurityError"
+)
+
+// otherLabel stands in for values outside a known set in metric labels
+const otherLabel = "Other"
+
+// knownActions are the OCPP 1.6 actions, including the security extensions,
+// in either direction
+var knownActions = map[string]bool{
+	"Authorize": true, "BootNotification": true, "ChangeAvailability": true, "ChangeConfiguration": true,
+	"ClearCache": true, "DataTransfer": true, "GetConfiguration": true, "Heartbeat": true,
+	"MeterValues": true, "RemoteStartTransaction": true, "RemoteStopTransaction": true, "Reset": true,
+	"StartTransaction": true, "StatusNotification": true, "StopTransaction": true, "UnlockConnector": true,
+	"GetDiagnostics": true, "DiagnosticsStatusNotification": true, "FirmwareStatusNotification": true,
+	"UpdateFirmware": true, "GetLocalListVersion": true, "SendLocalList": true,
+	"CancelReservation": true, "ReserveNow": true, "ClearChargingProfile": true,
+	"GetCompositeSchedule": true, "SetChargingProfile": true, "TriggerMessage": true,
+	"CertificateSigned": true, "DeleteCertificate": true, "ExtendedTriggerMessage": true,
+	"GetInstalledCertificateIds": true, "GetLog": true, "InstallCertificate": true,
+	"LogStatusNotification": true, "SecurityEventNotification": true, "SignCertificate": true,
+	"SignedFirmwareStatusNotification": true, "SignedUpdateFirmware": true,
+}
+
+// knownErrorCodes are the CALLERROR codes OCPP-J defines
+var knownErrorCodes = map[string]bool{
+	ErrorCodeNotImplemented: true, ErrorCodeNotSupported: true, ErrorCodeInternalError: true,
+	ErrorCodeProtocolError: true, ErrorCodeSecurityError: true, ErrorCodeFormationViolation: true,
+	ErrorCodePropertyConstraint: true, ErrorCodeOccurenceConstraint: true, ErrorCodeTypeConstraint: true,
+	ErrorCodeGenericError: true,
+}
+
+// ActionLabel returns action if it is an OCPP action and "Other" otherwise,
+// so metrics labelled by action stay bounded whatever chargers send
+func ActionLabel(action string) string {
+	if knownActions[action] {
+		return action
+	}
+	return otherLabel
+}
+
+// ErrorCodeLabel returns code if it is an OCPP-J CALLERROR code and "Other" otherwise
+func ErrorCodeLabel(code string) string {
+	if knownErrorCodes[code] {
+		return code
+	}
+	return otherLabel
+}
+
+// Call is an inbound CALL frame: [2, uniqueId, action, payload]
+type Call struct {
+	UniqueID string
+	Action   string
+	Payload  json.RawMessage
+}
+
+// CallResultFrame builds a CALLRESULT frame: [3, uniqueId, payload]
+func CallResultFrame(uniqueID string, payload interface{}) []interface{} {
+	return []interface{}{MessageTypeCallResult, uniqueID, payload}
+}
+
+// CallErrorFrame builds a CALLERROR frame: [4, uniqueId, errorCode, errorDescription, errorDetails]
+func CallErrorFrame(uniqueID, errorCode, description string) []interface{} {
+	return []interface{}{MessageTypeCallError, uniqueID, errorCode, description, struct{}{}}
+}
+
+// ErrNotConnected is returned when a command targets a charger without a live connection
+var ErrNotConnected = errors.New("charger is not connected")
+
+// CallError is a CALLERROR a charger answered an outbound CALL with
+type CallError struct {
+	Code        string
+	Description string
+}
+
+// Error implements error
+func (e *CallError) Error() string {
+	if e.Description == "" {
+		return "charger returned " + e.Code
+	}
+	return "charger returned " + e.Code + ": " + e.Description
+}
+
+// Sender sends a CALL to a connected charger and decodes the CALLRESULT payload into response
+type Sender interface {
+	SendCall(ctx context.Context, chargerID string, action string, request interface{}, response interface{}) error
+}
@@ -0,0 +1,45 @@
+package ocpp
+
+// Statuses returned in RemoteStartTransaction.conf and RemoteStopTransaction.conf
+const (
+	RemoteStartStopAccepted = "Accepted"
+	RemoteStartStopRejected = "Rejected"
+)
+
+// RemoteStartTransactionRequest is the RemoteStartTransaction.req payload. A
+// nil ConnectorID leaves the choice of connector to the charger.
+type RemoteStartTransactionRequest struct {
+	ConnectorID *int   `json:"connectorId,omitempty"`
+	IDTag       string `json:"idTag"`
+}
+
+// RemoteStartTransactionResponse is the RemoteStartTransaction.conf payload
+type RemoteStartTransactionResponse struct {
+	Status string `json:"status"`
+}
+
+// RemoteStopTransactionRequest is the RemoteStopTransaction.req payload
+type RemoteStopTransactionRequest struct {
+	TransactionID int `json:"transactionId"`
+}
+
+// RemoteStopTransactionResponse is the RemoteStopTransaction.conf payload
+type RemoteStopTransactionResponse struct {
+	Status string `json:"status"`
+}
+
+// Reset types in Reset.req
+const (
+	ResetTypeSoft = "Soft"
+	ResetTypeHard = "Hard"
+)
+
+// ResetRequest is the Reset.req payload
+type ResetRequest struct {
+	Type string `json:"type"`
+}
+
+// ResetResponse is the Reset.conf payload
+type ResetResponse struct {
+	Status string `json:"status"`
+}
@@ -0,0 +1,135 @@
+package ocpp
+
+import "time"
+
+// Security extension actions from the OCPP 1.6 Security Whitepaper
+const (
+	ActionSignedUpdateFirmware             = "SignedUpdateFirmware"
+	ActionGetLog                           = "GetLog"
+	ActionSignedFirmwareStatusNotification = "SignedFirmwareStatusNotification"
+	ActionLogStatusNotification            = "LogStatusNotification"
+	ActionSecurityEventNotification        = "SecurityEventNotification"
+)
+
+// Security event types from the OCPP 1.6 Security Whitepaper. Chargers may
+// also report their own types.
+const (
+	SecurityEventFirmwareUpdated                     = "FirmwareUpdated"
+	SecurityEventFailedToAuthenticateAtCentralSystem = "FailedToAuthenticateAtCentralSystem"
+	SecurityEventCentralSystemFailedToAuthenticate   = "CentralSystemFailedToAuthenticate"
+	SecurityEventSettingSystemTime                   = "SettingSystemTime"
+	SecurityEventStartupOfTheDevice                  = "StartupOfTheDevice"
+	SecurityEventResetOrReboot                       = "ResetOrReboot"
+	SecurityEventSecurityLogWasCleared               = "SecurityLogWasCleared"
+	SecurityEventReconfigurationOfSecurityParameters = "ReconfigurationOfSecurityParameters"
+	SecurityEventMemoryExhaustion                    = "MemoryExhaustion"
+	SecurityEventInvalidMessages                     = "InvalidMessages"
+	SecurityEventAttemptedReplayAttacks              = "AttemptedReplayAttacks"
+	SecurityEventTamperDetectionActivated            = "TamperDetectionActivated"
+	SecurityEventInvalidFirmwareSignature            = "InvalidFirmwareSignature"
+	SecurityEventInvalidFirmwareSigningCertificate   = "InvalidFirmwareSigningCertificate"
+	SecurityEventInvalidCentralSystemCertificate     = "InvalidCentralSystemCertificate"
+	SecurityEventInvalidChargePointCertificate       = "InvalidChargePointCertificate"
+	SecurityEventInvalidTLSVersion                   = "InvalidTLSVersion"
+	SecurityEventInvalidTLSCipherSuite               = "InvalidTLSCipherSuite"
+)
+
+// Log types requested in GetLog.req
+const (
+	LogTypeDiagnostics = "DiagnosticsLog"
+	LogTypeSecurity    = "SecurityLog"
+)
+
+// Statuses returned in SignedUpdateFirmware.conf
+const (
+	UpdateFirmwareStatusAccepted           = "Accepted"
+	UpdateFirmwareStatusRejected           = "Rejected"
+	UpdateFirmwareStatusAcceptedCanceled   = "AcceptedCanceled"
+	UpdateFirmwareStatusInvalidCertificate = "InvalidCertificate"
+	UpdateFirmwareStatusRevokedCertificate = "RevokedCertificate"
+)
+
+// Statuses returned in GetLog.conf
+const (
+	LogStatusAccepted         = "Accepted"
+	LogStatusRejected         = "Rejected"
+	LogStatusAcceptedCanceled = "AcceptedCanceled"
+)
+
+// TransferStatusIdle is reported in SignedFirmwareStatusNotification and
+// LogStatusNotification when no firmware update or log upload is in progress
+const TransferStatusIdle = "Idle"
+
+// Firmware describes a signed firmware image to install
+type Firmware struct {
+	Location           string     `json:"location"`
+	RetrieveDateTime   time.Time  `json:"retrieveDateTime"`
+	InstallDateTime    *time.Time `json:"installDateTime,omitempty"`
+	SigningCertificate string     `json:"signingCertificate"`
+	Signature          string     `json:"signature"`
+}
+
+// SignedUpdateFirmwareRequest is the SignedUpdateFirmware.req payload
+type SignedUpdateFirmwareRequest struct {
+	Retries       *int     `json:"retries,omitempty"`
+	RetryInterval *int     `json:"retryInterval,omitempty"`
+	RequestID     int      `json:"requestId"`
+	Firmware      Firmware `json:"firmware"`
+}
+
+// SignedUpdateFirmwareResponse is the SignedUpdateFirmware.conf payload
+type SignedUpdateFirmwareResponse struct {
+	Status string `json:"status"`
+}
+
+// LogParameters describes where to upload a log and the period it covers
+type LogParameters struct {
+	RemoteLocation  string     `json:"remoteLocation"`
+	OldestTimestamp *time.Time `json:"oldestTimestamp,omitempty"`
+	LatestTimestamp *time.Time `json:"latestTimestamp,omitempty"`
+}
+
+// GetLogRequest is the GetLog.req payload
+type GetLogRequest struct {
+	Log           LogParameters `json:"log"`
+	LogType       string        `json:"logType"`
+	RequestID     int           `json:"requestId"`
+	Retries       *int          `json:"retries,omitempty"`
+	RetryInterval *int          `json:"retryInterval,omitempty"`
+}
+
+// GetLogResponse is the GetLog.conf payload
+type GetLogResponse struct {
+	Status   string `json:"status"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// SignedFirmwareStatusNotificationRequest is the
+// SignedFirmwareStatusNotification.req payload. RequestID is omitted when the
+// status does not relate to a SignedUpdateFirmware request.
+type SignedFirmwareStatusNotificationRequest struct {
+	Status    string `json:"status"`
+	RequestID *int   `json:"requestId,omitempty"`
+}
+
+// SignedFirmwareStatusNotificationResponse is the SignedFirmwareStatusNotification.conf payload
+type SignedFirmwareStatusNotificationResponse struct{}
+
+// LogStatusNotificationRequest is the LogStatusNotification.req payload
+type LogStatusNotificationRequest struct {
+	Status    string `json:"status"`
+	RequestID *int   `json:"requestId,omitempty"`
+}
+
+// LogStatusNotificationResponse is the LogStatusNotification.conf payload
+type LogStatusNotificationResponse struct{}
+
+// SecurityEventNotificationRequest is the SecurityEventNotification.req payload
+type SecurityEventNotificationRequest struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	TechInfo  string    `json:"techInfo,omitempty"`
+}
+
+// SecurityEventNotificationResponse is the SecurityEventNotification.conf payload
+type SecurityEventNotificationResponse struct{}
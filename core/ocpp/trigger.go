@@ -0,0 +1,28 @@
+package ocpp
+
+// Messages a charger can be asked to send with TriggerMessage
+const (
+	MessageTriggerBootNotification   = "BootNotification"
+	MessageTriggerHeartbeat          = "Heartbeat"
+	MessageTriggerMeterValues        = "MeterValues"
+	MessageTriggerStatusNotification = "StatusNotification"
+)
+
+// TriggerMessage statuses returned in TriggerMessage.conf
+const (
+	TriggerMessageStatusAccepted       = "Accepted"
+	TriggerMessageStatusRejected       = "Rejected"
+	TriggerMessageStatusNotImplemented = "NotImplemented"
+)
+
+// TriggerMessageRequest is the TriggerMessage.req payload. A nil ConnectorID
+// applies to the whole charge point.
+type TriggerMessageRequest struct {
+	RequestedMessage string `json:"requestedMessage"`
+	ConnectorID      *int   `json:"connectorId,omitempty"`
+}
+
+// TriggerMessageResponse is the TriggerMessage.conf payload
+type TriggerMessageResponse struct {
+	Status string `json:"status"`
+}
@@ -0,0 +1,962 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// OCPPHandler handles Charge Point initiated OCPP requests
+type OCPPHandler struct {
+	config   config.OCPPConfig
+	repos    db.RepositoryManager
+	clock    clock.Clock
+	location *time.Location
+	// persisted limits stored measurands; nil persists all
+	persisted map[string]bool
+	// meterBuffer batches meter value writes; nil writes each message directly
+	meterBuffer *MeterValueBuffer
+	// meterLimiter caps sampled values per charger; nil is unlimited
+	meterLimiter *MeterRateLimiter
+	metrics      *monitoring.Metrics
+	authorizer   *IDTagAuthorizer
+	// postStop marks connectors Available when the charger does not after a
+	// stop; nil leaves the status to the charger
+	postStop *PostStopFallback
+	// stubs are canned payloads for unimplemented actions, keyed by lower-case
+	// action name as configuration keys are case-insensitive
+	stubs map[string]json.RawMessage
+	// onBoot is called after a BootNotification is accepted; nil does nothing
+	onBoot func(chargerID string)
+	logger *slog.Logger
+}
+
+// NewOCPPHandler creates a new OCPP request handler. An invalid time zone
+// falls back to UTC; config.Load rejects one up front.
+func NewOCPPHandler(cfg config.OCPPConfig, repos db.RepositoryManager, clk clock.Clock, logger *slog.Logger) *OCPPHandler {
+	location, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil || cfg.TimeZone == "" {
+		location = time.UTC
+	}
+
+	var persisted map[string]bool
+	if len(cfg.PersistedMeasurands) > 0 {
+		persisted = make(map[string]bool, len(cfg.PersistedMeasurands))
+		for _, measurand := range cfg.PersistedMeasurands {
+			persisted[measurand] = true
+		}
+	}
+
+	var meterBuffer *MeterValueBuffer
+	if cfg.MeterFlushSize > 1 {
+		meterBuffer = NewMeterValueBuffer(cfg.MeterFlushSize, cfg.MeterFlushInterval, repos.MeterValues(), logger)
+	}
+
+	var meterLimiter *MeterRateLimiter
+	if cfg.MaxMeterValuesPerMinute > 0 {
+		meterLimiter = NewMeterRateLimiter(cfg.MaxMeterValuesPerMinute, clk)
+	}
+
+	var postStop *PostStopFallback
+	if cfg.PostStopAvailableTimeout > 0 {
+		postStop = NewPostStopFallback(cfg.PostStopAvailableTimeout, repos, clk, logger)
+	}
+
+	stubs := make(map[string]json.RawMessage, len(cfg.StubActions))
+	for action, payload := range cfg.StubActions {
+		stubs[strings.ToLower(action)] = json.RawMessage(payload)
+	}
+
+	return &OCPPHandler{
+		config:       cfg,
+		repos:        repos,
+		clock:        clk,
+		location:     location,
+		persisted:    persisted,
+		meterBuffer:  meterBuffer,
+		meterLimiter: meterLimiter,
+		authorizer:   NewIDTagAuthorizer(repos, !cfg.RejectUnknownIDTags, cfg.AuthorizationCacheTTL, clk),
+		postStop:     postStop,
+		stubs:        stubs,
+		logger:       logger,
+	}
+}
+
+// SetMetrics sets the metrics updated while handling requests
+func (h *OCPPHandler) SetMetrics(metrics *monitoring.Metrics) {
+	h.metrics = metrics
+	if h.postStop != nil {
+		h.postStop.SetMetrics(metrics)
+	}
+}
+
+// OnBoot sets a function called with the charger id after each accepted
+// BootNotification. It must not block, as the charger is still waiting for
+// the BootNotification response.
+func (h *OCPPHandler) OnBoot(fn func(chargerID string)) {
+	h.onBoot = fn
+}
+
+// CurrentTime returns the current time formatted for chargers in the configured time zone
+func (h *OCPPHandler) CurrentTime() string {
+	return h.clock.Now().In(h.location).Format(ocpp.DateTimeFormat)
+}
+
+// BootNotification registers a charger, recording its identity and boot time
+func (h *OCPPHandler) BootNotification(ctx context.Context, chargerID string, req ocpp.BootNotificationRequest) (*ocpp.BootNotificationResponse, error) {
+	serial := req.ChargePointSerialNumber
+	if serial == "" {
+		serial = req.ChargeBoxSerialNumber
+	}
+
+	charger, err := h.repos.Chargers().GetByID(ctx, chargerID)
+	switch {
+	case errors.Is(err, db.ErrChargerNotFound):
+		charger, err = h.repos.Chargers().Create(ctx, db.CreateChargerRequest{
+			ID:              chargerID,
+			Vendor:          req.ChargePointVendor,
+			Model:           req.ChargePointModel,
+			SerialNumber:    serial,
+			FirmwareVersion: req.FirmwareVersion,
+			ICCID:           req.Iccid,
+			IMSI:            req.Imsi,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to register charger: %w", err)
+		}
+	case err != nil:
+		// Registering over a charger that could not be read would lose its row
+		return nil, fmt.Errorf("failed to get charger: %w", err)
+	default:
+		// Optional fields a reboot omits keep their stored value
+		charger, err = h.repos.Chargers().Update(ctx, chargerID, db.UpdateChargerRequest{
+			Vendor:          &req.ChargePointVendor,
+			Model:           &req.ChargePointModel,
+			SerialNumber:    nonEmpty(serial),
+			FirmwareVersion: nonEmpty(req.FirmwareVersion),
+			ICCID:           nonEmpty(req.Iccid),
+			IMSI:            nonEmpty(req.Imsi),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update charger: %w", err)
+		}
+	}
+
+	if err := h.repos.Chargers().UpdateLastBoot(ctx, chargerID, h.clock.Now()); err != nil {
+		return nil, fmt.Errorf("failed to record boot: %w", err)
+	}
+
+	if err := h.provisionConnectors(ctx, charger); err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Charger booted",
+		slog.String("charger_id", chargerID),
+		slog.String("vendor", req.ChargePointVendor),
+		slog.String("model", req.ChargePointModel),
+		slog.String("firmware_version", req.FirmwareVersion))
+
+	if h.onBoot != nil {
+		h.onBoot(chargerID)
+	}
+
+	return &ocpp.BootNotificationResponse{
+		Status:      ocpp.RegistrationAccepted,
+		CurrentTime: h.CurrentTime(),
+		Interval:    int(h.config.HeartbeatInterval / time.Second),
+	}, nil
+}
+
+// nonEmpty returns a pointer to s, or nil when s is empty so an update leaves
+// the stored value alone
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// provisionConnectors creates the charger's configured connectors that do not
+// exist yet, as Unavailable until the charger reports their status
+func (h *OCPPHandler) provisionConnectors(ctx context.Context, charger *db.Charger) error {
+	if charger.NumConnectors <= 0 {
+		return nil
+	}
+
+	connectors, err := h.repos.Connectors().GetByChargerID(ctx, charger.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get connectors: %w", err)
+	}
+	existing := make(map[int]bool, len(connectors))
+	for _, conn := range connectors {
+		existing[conn.ConnectorID] = true
+	}
+
+	for connectorID := 1; connectorID <= charger.NumConnectors; connectorID++ {
+		if existing[connectorID] {
+			continue
+		}
+		connector, err := h.repos.Connectors().Create(ctx, charger.ID, connectorID)
+		if err != nil {
+			return fmt.Errorf("failed to provision connector %d: %w", connectorID, err)
+		}
+		h.moveConnectorStatus("", connector.Status)
+		if err := h.repos.Connectors().UpdateStatus(ctx, charger.ID, connectorID, ocpp.ChargePointStatusUnavailable); err != nil {
+			return fmt.Errorf("failed to provision connector %d: %w", connectorID, err)
+		}
+		h.moveConnectorStatus(connector.Status, ocpp.ChargePointStatusUnavailable)
+		h.logger.Info("Provisioned connector",
+			slog.String("charger_id", charger.ID),
+			slog.Int("connector_id", connectorID))
+	}
+
+	return nil
+}
+
+// Heartbeat records that a charger is alive and returns the server time, so
+// chargers with drifting clocks can resynchronize. A charger without a row is
+// answered with a SecurityError.
+func (h *OCPPHandler) Heartbeat(ctx context.Context, chargerID string, req ocpp.HeartbeatRequest) (*ocpp.HeartbeatResponse, error) {
+	if err := h.repos.Chargers().UpdateLastHeartbeat(ctx, chargerID, h.clock.Now()); err != nil {
+		if errors.Is(err, db.ErrChargerNotFound) {
+			// Refuse rather than create a row for a charger that never booted
+			h.logger.Warn("Heartbeat from unregistered charger", slog.String("charger_id", chargerID))
+			return nil, ocpp.NewSecurityError("Charger is not registered; send BootNotification first")
+		}
+		return nil, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	return &ocpp.HeartbeatResponse{CurrentTime: h.CurrentTime()}, nil
+}
+
+// MeterValues stores the sampled values reported by a charger, one row per
+// sampled value, skipping measurands that are not in the persisted allowlist
+// and samples beyond the charger's rate limit. Values sent without a
+// transactionId are linked to the connector's active transaction, if any;
+// values naming another charger's transaction are stored unlinked.
+func (h *OCPPHandler) MeterValues(ctx context.Context, chargerID string, req ocpp.MeterValuesRequest) (*ocpp.MeterValuesResponse, error) {
+	meterValues, err := h.limitMeterValues(ctx, chargerID, req.MeterValue)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactionID *int
+	if req.TransactionID != nil {
+		tx, err := h.repos.Transactions().GetByTransactionID(ctx, *req.TransactionID)
+		switch {
+		case err == nil && tx.ChargerID == chargerID:
+			transactionID = &tx.ID
+		case err == nil:
+			// Another charger's session is treated as unknown
+			h.logger.Warn("Meter values reference another charger's transaction",
+				slog.String("charger_id", chargerID),
+				slog.String("owner_id", tx.ChargerID),
+				slog.Int("transaction_id", *req.TransactionID))
+		default:
+			// Left for the reconciliation job to link once the transaction is known
+			h.logger.Warn("Meter values reference an unknown transaction",
+				slog.String("charger_id", chargerID),
+				slog.Int("transaction_id", *req.TransactionID))
+		}
+	} else if req.ConnectorID > 0 {
+		tx, err := h.repos.Transactions().GetActiveByConnector(ctx, chargerID, req.ConnectorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get active transaction: %w", err)
+		}
+		if tx != nil {
+			transactionID = &tx.ID
+		}
+	}
+
+	if err := h.storeMeterValues(ctx, chargerID, req.ConnectorID, transactionID, meterValues); err != nil {
+		return nil, err
+	}
+
+	return &ocpp.MeterValuesResponse{}, nil
+}
+
+// limitMeterValues applies the charger's meter value rate limit. Dropping
+// keeps the samples that fit in the current minute, in order; throttling
+// delays the response, and so the charger's next message, until they fit.
+func (h *OCPPHandler) limitMeterValues(ctx context.Context, chargerID string, meterValues []ocpp.MeterValue) ([]ocpp.MeterValue, error) {
+	if h.meterLimiter == nil {
+		return meterValues, nil
+	}
+
+	samples := 0
+	for _, meterValue := range meterValues {
+		samples += len(meterValue.SampledValue)
+	}
+
+	if h.config.MeterRateLimitAction == config.MeterRateLimitThrottle {
+		if err := h.meterLimiter.Wait(ctx, chargerID, samples); err != nil {
+			return nil, fmt.Errorf("meter values throttled: %w", err)
+		}
+		return meterValues, nil
+	}
+
+	allowed := h.meterLimiter.Allow(chargerID, samples)
+	if allowed == samples {
+		return meterValues, nil
+	}
+
+	kept := make([]ocpp.MeterValue, 0, len(meterValues))
+	remaining := allowed
+	for _, meterValue := range meterValues {
+		if remaining == 0 {
+			break
+		}
+		if len(meterValue.SampledValue) > remaining {
+			meterValue.SampledValue = meterValue.SampledValue[:remaining]
+		}
+		remaining -= len(meterValue.SampledValue)
+		kept = append(kept, meterValue)
+	}
+
+	h.logger.Warn("Dropped meter values over the rate limit",
+		slog.String("charger_id", chargerID),
+		slog.Int("dropped", samples-allowed),
+		slog.Int("limit_per_minute", h.config.MaxMeterValuesPerMinute))
+	if h.metrics != nil {
+		h.metrics.RecordMeterValuesRateLimited(chargerID, samples-allowed)
+	}
+	return kept, nil
+}
+
+// StartTransaction starts a transaction on a connector. A retried
+// StartTransaction, recognised by an active transaction on the connector for
+// the same ID tag started within the dedup window, returns the existing
+// transaction instead of creating another; any other start on a connector
+// with an active transaction is refused with ConcurrentTx. The database
+// allows one active transaction per connector, so concurrent starts are
+// resolved by whichever inserts first. New transactions are refused with
+// Blocked while maintenance mode is on, and the ID tag must be authorized.
+func (h *OCPPHandler) StartTransaction(ctx context.Context, chargerID string, req ocpp.StartTransactionRequest) (*ocpp.StartTransactionResponse, error) {
+	if err := h.ensureTransactionCharger(ctx, chargerID); err != nil {
+		return nil, err
+	}
+
+	startTime := req.Timestamp
+	if startTime.IsZero() {
+		startTime = h.clock.Now()
+	}
+
+	if resp, err := h.startOnActiveConnector(ctx, chargerID, req, startTime); resp != nil || err != nil {
+		return resp, err
+	}
+
+	maintenance, err := maintenanceMode(ctx, h.repos.Settings())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check maintenance mode: %w", err)
+	}
+	if maintenance {
+		h.logger.Warn("Rejected StartTransaction during maintenance",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", req.ConnectorID))
+		return &ocpp.StartTransactionResponse{
+			IDTagInfo: ocpp.IDTagInfo{Status: ocpp.AuthorizationBlocked},
+		}, nil
+	}
+
+	idTagInfo, err := h.authorizer.Authorize(ctx, req.IDTag)
+	if err != nil {
+		return nil, err
+	}
+	if idTagInfo.Status != ocpp.AuthorizationAccepted {
+		h.logger.Warn("Rejected StartTransaction for unauthorized ID tag",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", req.ConnectorID),
+			slog.String("id_tag", req.IDTag),
+			slog.String("status", idTagInfo.Status))
+		return &ocpp.StartTransactionResponse{IDTagInfo: idTagInfo}, nil
+	}
+
+	reservation, err := h.repos.Reservations().GetActiveByConnector(ctx, chargerID, req.ConnectorID, startTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check reservation: %w", err)
+	}
+	if reservation != nil && !h.holdsReservation(ctx, reservation, req.IDTag) {
+		h.logger.Warn("Rejected StartTransaction on reserved connector",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", req.ConnectorID),
+			slog.Int("reservation_id", reservation.ID))
+		return &ocpp.StartTransactionResponse{
+			IDTagInfo: ocpp.IDTagInfo{Status: ocpp.AuthorizationInvalid},
+		}, nil
+	}
+
+	meterStart := req.MeterStart
+	if meterStart == 0 && h.config.MeterStartFallback {
+		meterStart, err = h.fallbackMeterStart(ctx, chargerID, req.ConnectorID, startTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := h.repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		ChargerID:   chargerID,
+		ConnectorID: req.ConnectorID,
+		IDTag:       req.IDTag,
+		MeterStart:  meterStart,
+		StartTime:   &startTime,
+	})
+	if errors.Is(err, db.ErrConnectorTransactionActive) {
+		// A concurrent start inserted first; answer as if it had been seen above
+		resp, err := h.startOnActiveConnector(ctx, chargerID, req, startTime)
+		if resp == nil && err == nil {
+			err = fmt.Errorf("failed to start transaction: %w", db.ErrConnectorTransactionActive)
+		}
+		return resp, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	if reservation != nil {
+		if err := h.repos.Reservations().UpdateStatus(ctx, reservation.ID, db.ReservationStatusUsed); err != nil {
+			return nil, fmt.Errorf("failed to consume reservation: %w", err)
+		}
+	}
+
+	if err := h.repos.Chargers().UpdateLastTxStart(ctx, chargerID, startTime); err != nil {
+		h.logger.Warn("Failed to record last transaction start",
+			slog.String("charger_id", chargerID),
+			slog.String("error", err.Error()))
+	}
+
+	h.logger.Info("Transaction started",
+		slog.String("charger_id", chargerID),
+		slog.Int("connector_id", req.ConnectorID),
+		slog.Int("transaction_id", *tx.TransactionID))
+
+	return &ocpp.StartTransactionResponse{
+		IDTagInfo:     idTagInfo,
+		TransactionID: *tx.TransactionID,
+	}, nil
+}
+
+// startOnActiveConnector answers a StartTransaction on a connector that
+// already has an active transaction: the existing transaction for a retried
+// start, ConcurrentTx otherwise. It returns nil when the connector is free.
+func (h *OCPPHandler) startOnActiveConnector(ctx context.Context, chargerID string, req ocpp.StartTransactionRequest, startTime time.Time) (*ocpp.StartTransactionResponse, error) {
+	active, err := h.repos.Transactions().GetActiveByConnector(ctx, chargerID, req.ConnectorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check active transaction: %w", err)
+	}
+	if active == nil {
+		return nil, nil
+	}
+
+	if active.TransactionID != nil && active.IDTag == req.IDTag &&
+		absDuration(startTime.Sub(active.StartTime)) <= h.config.StartDedupWindow {
+		h.logger.Info("Returning existing transaction for repeated StartTransaction",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", req.ConnectorID),
+			slog.Int("transaction_id", *active.TransactionID))
+		return &ocpp.StartTransactionResponse{
+			IDTagInfo:     ocpp.IDTagInfo{Status: ocpp.AuthorizationAccepted},
+			TransactionID: *active.TransactionID,
+		}, nil
+	}
+
+	h.logger.Warn("Rejected StartTransaction on connector with an active transaction",
+		slog.String("charger_id", chargerID),
+		slog.Int("connector_id", req.ConnectorID),
+		slog.Int("active_id", active.ID))
+	return &ocpp.StartTransactionResponse{
+		IDTagInfo: ocpp.IDTagInfo{Status: ocpp.AuthorizationConcurrentTx},
+	}, nil
+}
+
+// fallbackMeterStart returns the connector's latest energy register reading
+// at or before the start time, in Wh, for chargers that omit meterStart. It
+// returns 0 when the connector has no such reading.
+func (h *OCPPHandler) fallbackMeterStart(ctx context.Context, chargerID string, connectorID int, startTime time.Time) (int, error) {
+	latest, err := h.repos.MeterValues().GetLatestMeasurandByConnector(ctx, chargerID, connectorID, ocpp.DefaultMeasurand, startTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get meter start fallback: %w", err)
+	}
+	if latest == nil {
+		return 0, nil
+	}
+
+	meterStart := int(math.Round(latest.ValueNormalized))
+	h.logger.Info("StartTransaction without meterStart, using latest energy reading",
+		slog.String("charger_id", chargerID),
+		slog.Int("connector_id", connectorID),
+		slog.Int("meter_start", meterStart),
+		slog.Time("reading_time", latest.Timestamp))
+	return meterStart, nil
+}
+
+// holdsReservation reports whether an ID tag may use a reservation, either
+// directly or through the reservation's parent ID tag
+func (h *OCPPHandler) holdsReservation(ctx context.Context, reservation *db.Reservation, idTag string) bool {
+	if idTag == reservation.IDTag {
+		return true
+	}
+	if reservation.ParentIDTag == "" {
+		return false
+	}
+
+	tag, err := h.repos.IDTags().GetByIDTag(ctx, idTag)
+	return err == nil && tag.ParentIDTag == reservation.ParentIDTag
+}
+
+// StopTransaction completes a transaction and stores its transaction data.
+// A retried StopTransaction for a transaction that is no longer active, or
+// one for an unknown transaction or another charger's, is acknowledged without
+// being processed. Otherwise the stop, its transaction data and the charger's
+// last stop time are written in one database transaction. A stop time beyond
+// ocpp.max_clock_skew is replaced with server time or flagged on the
+// transaction, per ocpp.clock_skew_action.
+func (h *OCPPHandler) StopTransaction(ctx context.Context, chargerID string, req ocpp.StopTransactionRequest) (*ocpp.StopTransactionResponse, error) {
+	accepted := &ocpp.StopTransactionResponse{
+		IDTagInfo: &ocpp.IDTagInfo{Status: ocpp.AuthorizationAccepted},
+	}
+
+	transaction, err := h.repos.Transactions().GetByTransactionID(ctx, req.TransactionID)
+	if errors.Is(err, db.ErrTransactionNotFound) {
+		// Accept so the charger drops the message instead of retrying it forever
+		h.logger.Warn("StopTransaction for unknown transaction",
+			slog.String("charger_id", chargerID),
+			slog.Int("transaction_id", req.TransactionID))
+		return accepted, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if transaction.ChargerID != chargerID {
+		// A charger may only stop its own sessions; accepted like an unknown one
+		h.logger.Warn("StopTransaction for another charger's transaction",
+			slog.String("charger_id", chargerID),
+			slog.String("owner_id", transaction.ChargerID),
+			slog.Int("transaction_id", req.TransactionID))
+		return accepted, nil
+	}
+
+	if transaction.Status.IsFinal() {
+		h.logger.Info("Ignoring repeated StopTransaction",
+			slog.String("charger_id", chargerID),
+			slog.Int("transaction_id", req.TransactionID),
+			slog.String("status", string(transaction.Status)))
+		return accepted, nil
+	}
+
+	stopTime, skew := h.checkClockSkew(chargerID, ocpp.ActionStopTransaction, req.Timestamp)
+	if stopTime.IsZero() {
+		stopTime = h.clock.Now()
+	}
+	reason := valueOrDefault(req.Reason, ocpp.ReasonLocal)
+	source := db.StopSourceCharger
+	if reason == ocpp.ReasonRemote {
+		source = db.StopSourceRemote
+	}
+
+	// Transaction data bypasses the meter buffer so it commits with the stop
+	values := h.meterValueRequests(chargerID, transaction.ConnectorID, &transaction.ID, req.TransactionData)
+
+	err = WithTx(ctx, h.repos, h.metrics, "stop_transaction", func(tx db.TxManager) error {
+		if err := tx.Transactions().Stop(ctx, transaction.ID, req.MeterStop, stopTime, reason, source); err != nil {
+			return fmt.Errorf("failed to stop transaction: %w", err)
+		}
+		if skew != nil {
+			if err := tx.Transactions().FlagClockSkew(ctx, transaction.ID, *skew); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.MeterValues().CreateBatch(ctx, values); err != nil {
+			return fmt.Errorf("failed to store transaction data: %w", err)
+		}
+		return tx.Chargers().UpdateLastTxStop(ctx, transaction.ChargerID, stopTime)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if req.MeterStop < transaction.MeterStart {
+		h.logger.Warn("StopTransaction meterStop is below meterStart, energy delivered clamped to zero",
+			slog.String("charger_id", chargerID),
+			slog.Int("transaction_id", req.TransactionID),
+			slog.Int("meter_start", transaction.MeterStart),
+			slog.Int("meter_stop", req.MeterStop))
+	}
+
+	// The connector's status is left to the charger's StatusNotification
+	if h.postStop != nil {
+		h.postStop.Stopped(chargerID, transaction.ConnectorID)
+	}
+
+	return accepted, nil
+}
+
+// StatusNotification records a status change. Connector 0 reports the charge
+// point as a whole and maps to Charger.Status without a connector row; other
+// connectors are created on first report, up to ocpp.max_connectors_per_charger,
+// and their reported errors are logged as charger errors.
+func (h *OCPPHandler) StatusNotification(ctx context.Context, chargerID string, req ocpp.StatusNotificationRequest) (*ocpp.StatusNotificationResponse, error) {
+	if req.Timestamp != nil {
+		// The status timestamp is not stored, so there is nothing to substitute or flag
+		h.checkClockSkew(chargerID, ocpp.ActionStatusNotification, *req.Timestamp)
+	}
+
+	// An unknown status is acknowledged so the charger moves on, but the last
+	// known status is kept rather than persisting firmware typos
+	status, known := ocpp.ParseConnectorStatus(req.Status)
+	if !known {
+		h.logger.Warn("Ignoring unknown status in StatusNotification",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", req.ConnectorID),
+			slog.String("status", req.Status))
+		if h.metrics != nil {
+			h.metrics.RecordUnknownConnectorStatus(chargerID)
+		}
+	}
+
+	if req.ConnectorID == db.ChargePointConnectorID {
+		if known {
+			if err := h.repos.Chargers().UpdateStatus(ctx, chargerID, string(status)); err != nil {
+				return nil, fmt.Errorf("failed to update charger status: %w", err)
+			}
+		}
+		previous, err := h.chargePointErrorCode(ctx, chargerID)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.recordConnectorError(ctx, chargerID, previous, req); err != nil {
+			return nil, err
+		}
+		return &ocpp.StatusNotificationResponse{}, nil
+	}
+
+	if limit := h.config.MaxConnectorsPerCharger; limit > 0 && req.ConnectorID > limit {
+		// Acknowledge so the charger moves on, without creating a connector row
+		h.logger.Warn("Ignoring StatusNotification for connector beyond the limit",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", req.ConnectorID),
+			slog.Int("max_connectors", limit))
+		if h.metrics != nil {
+			h.metrics.RecordConnectorOutOfRange(chargerID)
+		}
+		return &ocpp.StatusNotificationResponse{}, nil
+	}
+
+	connectors := h.repos.Connectors()
+	connector, err := connectors.GetByChargerAndConnector(ctx, chargerID, req.ConnectorID)
+	if err != nil {
+		if !errors.Is(err, db.ErrConnectorNotFound) {
+			return nil, fmt.Errorf("failed to get connector %d: %w", req.ConnectorID, err)
+		}
+		connector, err = connectors.Create(ctx, chargerID, req.ConnectorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create connector %d: %w", req.ConnectorID, err)
+		}
+		h.moveConnectorStatus("", connector.Status)
+	}
+
+	if known {
+		if h.postStop != nil {
+			h.postStop.Reported(chargerID, req.ConnectorID, string(status))
+		}
+		keep, err := h.keepsActiveSession(ctx, chargerID, req.ConnectorID, string(status))
+		if err != nil {
+			return nil, err
+		}
+		if !keep {
+			if err := connectors.UpdateStatus(ctx, chargerID, req.ConnectorID, string(status)); err != nil {
+				return nil, fmt.Errorf("failed to update connector status: %w", err)
+			}
+			h.moveConnectorStatus(connector.Status, string(status))
+		}
+	}
+
+	if err := h.recordConnectorError(ctx, chargerID, connector.ErrorCode, req); err != nil {
+		return nil, err
+	}
+
+	return &ocpp.StatusNotificationResponse{}, nil
+}
+
+// ensureTransactionCharger makes sure a charger starting a transaction has a
+// row for the transaction to reference. A charger that skipped or lost its
+// BootNotification gets a minimal row with ocpp.autocreate_charger_on_transaction,
+// and is otherwise answered with a SecurityError like an unregistered Heartbeat.
+func (h *OCPPHandler) ensureTransactionCharger(ctx context.Context, chargerID string) error {
+	_, err := h.repos.Chargers().GetByID(ctx, chargerID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, db.ErrChargerNotFound) {
+		return fmt.Errorf("failed to get charger: %w", err)
+	}
+
+	if !h.config.AutocreateChargerOnTransaction {
+		h.logger.Warn("StartTransaction from unregistered charger", slog.String("charger_id", chargerID))
+		return ocpp.NewSecurityError("Charger is not registered; send BootNotification first")
+	}
+
+	if _, err := h.repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: chargerID}); err != nil {
+		return fmt.Errorf("failed to create charger for transaction: %w", err)
+	}
+	h.logger.Warn("Created charger for StartTransaction before BootNotification", slog.String("charger_id", chargerID))
+	return nil
+}
+
+// moveConnectorStatus moves a connector between connectors_by_status labels
+// after its status row was written
+func (h *OCPPHandler) moveConnectorStatus(from, to string) {
+	if h.metrics != nil {
+		h.metrics.MoveConnectorStatus(from, to)
+	}
+}
+
+// chargePointErrorCode returns the code of the charge point's open error, or
+// "" if it has none. Connector 0 has no connector row to hold its error, so
+// the error log is the record of it.
+func (h *OCPPHandler) chargePointErrorCode(ctx context.Context, chargerID string) (string, error) {
+	active, err := h.repos.Errors().GetActiveByChargerID(ctx, chargerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get active charger errors: %w", err)
+	}
+	for _, e := range active {
+		if e.ConnectorID == nil || *e.ConnectorID == db.ChargePointConnectorID {
+			return e.ErrorCode, nil
+		}
+	}
+	return "", nil
+}
+
+// recordConnectorError keeps a connector's error and the charger error log in
+// step with a StatusNotification. A newly reported error code is logged once,
+// however often the charger repeats it; reporting NoError, or a different
+// code, resolves the connector's previous error. Connector 0 reports faults
+// of the whole charge point, which are logged but have no connector row.
+func (h *OCPPHandler) recordConnectorError(ctx context.Context, chargerID, previous string, req ocpp.StatusNotificationRequest) error {
+	connectors := h.repos.Connectors()
+	chargePoint := req.ConnectorID == db.ChargePointConnectorID
+	hadError := previous != "" && previous != ocpp.ChargePointErrorNoError
+	hasError := req.ErrorCode != "" && req.ErrorCode != ocpp.ChargePointErrorNoError
+	now := h.clock.Now()
+
+	if hadError && previous != req.ErrorCode {
+		if _, err := h.repos.Errors().ResolveByErrorCode(ctx, chargerID, req.ConnectorID, previous, now); err != nil {
+			return fmt.Errorf("failed to resolve connector error: %w", err)
+		}
+	}
+
+	if !hasError {
+		if chargePoint {
+			return nil
+		}
+		if err := connectors.ClearError(ctx, chargerID, req.ConnectorID); err != nil {
+			return fmt.Errorf("failed to clear connector error: %w", err)
+		}
+		return nil
+	}
+
+	if !chargePoint {
+		if err := connectors.UpdateError(ctx, chargerID, req.ConnectorID, req.ErrorCode, req.VendorErrorCode); err != nil {
+			return fmt.Errorf("failed to update connector error: %w", err)
+		}
+	}
+	if previous == req.ErrorCode {
+		return nil
+	}
+
+	timestamp := now
+	if req.Timestamp != nil {
+		timestamp = *req.Timestamp
+	}
+	connectorID := req.ConnectorID
+	if _, err := h.repos.Errors().Create(ctx, db.CreateChargerErrorRequest{
+		ChargerID:        chargerID,
+		ConnectorID:      &connectorID,
+		ErrorCode:        req.ErrorCode,
+		VendorErrorCode:  req.VendorErrorCode,
+		ErrorDescription: req.Info,
+		Timestamp:        timestamp,
+	}); err != nil {
+		return fmt.Errorf("failed to record charger error: %w", err)
+	}
+
+	h.logger.Warn("Connector reported an error",
+		slog.String("charger_id", chargerID),
+		slog.Int("connector_id", req.ConnectorID),
+		slog.String("error_code", req.ErrorCode),
+		slog.String("vendor_error_code", req.VendorErrorCode))
+	return nil
+}
+
+// keepsActiveSession reports whether an Available status should be ignored
+// because the connector still has an active transaction. Chargers that
+// reconnect mid-session can report Available before they replay a queued
+// StopTransaction; the transaction, not the status, ends the session.
+func (h *OCPPHandler) keepsActiveSession(ctx context.Context, chargerID string, connectorID int, status string) (bool, error) {
+	if status != ocpp.ChargePointStatusAvailable {
+		return false, nil
+	}
+
+	active, err := h.repos.Transactions().GetActiveByConnector(ctx, chargerID, connectorID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get active transaction: %w", err)
+	}
+	if active == nil {
+		return false, nil
+	}
+
+	h.logger.Info("Ignoring Available status for connector with an active transaction",
+		slog.String("charger_id", chargerID),
+		slog.Int("connector_id", connectorID),
+		slog.Int("transaction_id", active.ID))
+	return true, nil
+}
+
+// RestoreActiveTransactions puts the connectors of a reconnecting charger
+// that have an active transaction back in Charging, unless they already
+// report a charging or suspended status, so the session is not treated as
+// over. It returns how many connectors were restored.
+func (h *OCPPHandler) RestoreActiveTransactions(ctx context.Context, chargerID string) (int, error) {
+	active, err := h.repos.Transactions().GetActive(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get active transactions: %w", err)
+	}
+
+	restored := 0
+	for _, tx := range active {
+		if tx.ChargerID != chargerID {
+			continue
+		}
+
+		connector, err := h.repos.Connectors().GetByChargerAndConnector(ctx, chargerID, tx.ConnectorID)
+		if err != nil {
+			if connector, err = h.repos.Connectors().Create(ctx, chargerID, tx.ConnectorID); err != nil {
+				return restored, fmt.Errorf("failed to create connector %d: %w", tx.ConnectorID, err)
+			}
+			h.moveConnectorStatus("", connector.Status)
+		} else if inSession(connector.Status) {
+			continue
+		}
+
+		if err := h.repos.Connectors().UpdateStatus(ctx, chargerID, tx.ConnectorID, ocpp.ChargePointStatusCharging); err != nil {
+			return restored, fmt.Errorf("failed to restore connector %d: %w", tx.ConnectorID, err)
+		}
+		h.moveConnectorStatus(connector.Status, ocpp.ChargePointStatusCharging)
+		h.logger.Info("Restored active transaction on reconnect",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", tx.ConnectorID),
+			slog.Int("transaction_id", tx.ID))
+		restored++
+	}
+
+	return restored, nil
+}
+
+// inSession reports whether a connector status belongs to a running session
+func inSession(status string) bool {
+	switch status {
+	case ocpp.ChargePointStatusCharging, ocpp.ChargePointStatusSuspendedEV, ocpp.ChargePointStatusSuspendedEVSE:
+		return true
+	}
+	return false
+}
+
+// storeMeterValues persists sampled values, dropping non-allowlisted measurands
+// and invalid samples. With buffering enabled the values are
+// queued and written by the next flush.
+func (h *OCPPHandler) storeMeterValues(ctx context.Context, chargerID string, connectorID int, transactionID *int, meterValues []ocpp.MeterValue) error {
+	values := h.meterValueRequests(chargerID, connectorID, transactionID, meterValues)
+
+	if h.meterBuffer != nil {
+		h.meterBuffer.Add(ctx, values...)
+	} else if _, err := h.repos.MeterValues().CreateBatch(ctx, values); err != nil {
+		return fmt.Errorf("failed to store meter values: %w", err)
+	}
+
+	if len(meterValues) > 0 {
+		h.logger.Debug("Meter values stored",
+			slog.String("charger_id", chargerID),
+			slog.Int("connector_id", connectorID),
+			slog.Int("stored", len(values)),
+			slog.Bool("buffered", h.meterBuffer != nil))
+	}
+
+	return nil
+}
+
+// meterValueRequests flattens sampled values into rows to store, dropping
+// non-allowlisted measurands, values that are not numeric and samples without
+// a timestamp, so one bad sample cannot fail a buffered batch
+func (h *OCPPHandler) meterValueRequests(chargerID string, connectorID int, transactionID *int, meterValues []ocpp.MeterValue) []db.CreateMeterValueRequest {
+	var values []db.CreateMeterValueRequest
+	for _, meterValue := range meterValues {
+		for _, sample := range meterValue.SampledValue {
+			measurand := valueOrDefault(sample.Measurand, ocpp.DefaultMeasurand)
+			if h.persisted != nil && !h.persisted[measurand] {
+				if h.metrics != nil {
+					h.metrics.RecordMeterValueDropped(measurand)
+				}
+				continue
+			}
+
+			value, err := strconv.ParseFloat(sample.Value, 64)
+			if err != nil {
+				h.logger.Warn("Skipping non-numeric sampled value",
+					slog.String("charger_id", chargerID),
+					slog.String("measurand", measurand),
+					slog.String("value", sample.Value))
+				continue
+			}
+
+			req := db.CreateMeterValueRequest{
+				TransactionID: transactionID,
+				ChargerID:     chargerID,
+				ConnectorID:   connectorID,
+				Timestamp:     meterValue.Timestamp,
+				Measurand:     measurand,
+				Value:         &value,
+				Unit:          valueOrDefault(sample.Unit, ocpp.DefaultUnit),
+				Context:       valueOrDefault(sample.Context, ocpp.DefaultContext),
+				Location:      valueOrDefault(sample.Location, ocpp.DefaultLocation),
+				Phase:         sample.Phase,
+				Format:        valueOrDefault(sample.Format, ocpp.DefaultFormat),
+			}
+			if err := req.Validate(); err != nil {
+				h.logger.Warn("Skipping invalid sampled value",
+					slog.String("charger_id", chargerID),
+					slog.String("measurand", measurand),
+					slog.Any("error", err))
+				continue
+			}
+			values = append(values, req)
+		}
+	}
+	return values
+}
+
+// valueOrDefault returns value, or fallback when it is empty
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// absDuration returns the absolute value of d
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,1586 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrentTimeUsesConfiguredZone(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	tests := []struct {
+		zone string
+		want string
+	}{
+		{"", "2024-07-01T12:00:00.000Z"},
+		{"UTC", "2024-07-01T12:00:00.000Z"},
+		{"America/New_York", "2024-07-01T08:00:00.000-04:00"},
+		{"Asia/Kolkata", "2024-07-01T17:30:00.000+05:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			handler := NewOCPPHandler(config.OCPPConfig{TimeZone: tt.zone, HeartbeatInterval: time.Minute}, repos, fake, dbtest.Logger())
+
+			boot, err := handler.BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{
+				ChargePointVendor: "Acme",
+				ChargePointModel:  "X1",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, boot.CurrentTime)
+			assert.Equal(t, 60, boot.Interval)
+
+			heartbeat, err := handler.Heartbeat(ctx, "CP-1", ocpp.HeartbeatRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, heartbeat.CurrentTime)
+
+			parsed, err := time.Parse(ocpp.DateTimeFormat, heartbeat.CurrentTime)
+			require.NoError(t, err)
+			assert.True(t, parsed.Equal(fake.Now()))
+		})
+	}
+}
+
+func TestHeartbeatFromUnknownChargerIsRefused(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+
+	_, err := handler.HandleCall(ctx, "CP-GHOST", ocpp.Call{UniqueID: "1", Action: ocpp.ActionHeartbeat, Payload: []byte(`{}`)})
+	var handlerErr *ocpp.HandlerError
+	require.ErrorAs(t, err, &handlerErr)
+	assert.Equal(t, ocpp.ErrorCodeSecurityError, handlerErr.Code)
+
+	// No phantom row is created
+	count, err := repos.Chargers().Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// A registered charger's heartbeat is recorded at the server's time
+	_, err = repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	fake.Advance(90 * time.Second)
+
+	resp, err := handler.Heartbeat(ctx, "CP-1", ocpp.HeartbeatRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-07-01T12:01:30.000Z", resp.CurrentTime)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	require.NotNil(t, charger.LastHeartbeatAt)
+	assert.True(t, fake.Now().Equal(*charger.LastHeartbeatAt))
+}
+
+func TestBootNotificationRegistersCharger(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+
+	resp, err := handler.BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{
+		ChargePointVendor: "Acme",
+		ChargePointModel:  "X1",
+		FirmwareVersion:   "1.0",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.RegistrationAccepted, resp.Status)
+
+	// A second boot updates the existing charger
+	_, err = handler.BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{
+		ChargePointVendor: "Acme",
+		ChargePointModel:  "X1",
+		FirmwareVersion:   "1.1",
+	})
+	require.NoError(t, err)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", charger.Vendor)
+	assert.Equal(t, "1.1", charger.FirmwareVersion)
+	assert.NotNil(t, charger.LastBootAt)
+}
+
+func TestBootNotificationLookupFailure(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	database := dbtest.NewDatabase(t)
+	repos := db.NewRepositoryManager(database, dbtest.NopLogger(), fake)
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	require.NoError(t, database.Close())
+
+	// A failed lookup is answered with an error, not by registering the charger anew
+	_, err := handler.BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{
+		ChargePointVendor: "Acme",
+		ChargePointModel:  "X1",
+	})
+	assert.ErrorContains(t, err, "failed to get charger")
+}
+
+func TestBootNotificationRebootKeepsOneCharger(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	handler := NewOCPPHandler(config.OCPPConfig{HeartbeatInterval: 5 * time.Minute}, repos, fake, dbtest.Logger())
+
+	boot := ocpp.BootNotificationRequest{
+		ChargePointVendor:       "Acme",
+		ChargePointModel:        "X1",
+		ChargePointSerialNumber: "SN-0001",
+		FirmwareVersion:         "1.0",
+		Iccid:                   "8944500000000000001",
+		Imsi:                    "234150000000001",
+	}
+	resp, err := handler.BootNotification(ctx, "CP-1", boot)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.RegistrationAccepted, resp.Status)
+	assert.Equal(t, 300, resp.Interval)
+	assert.Equal(t, "2024-07-01T12:00:00.000Z", resp.CurrentTime)
+
+	// The same serial reboots with new firmware and without its modem details
+	fake.Advance(time.Hour)
+	resp, err = handler.BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{
+		ChargePointVendor:       "Acme",
+		ChargePointModel:        "X1",
+		ChargePointSerialNumber: "SN-0001",
+		FirmwareVersion:         "1.1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.RegistrationAccepted, resp.Status)
+
+	count, err := repos.Chargers().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, "SN-0001", charger.SerialNumber)
+	assert.Equal(t, "1.1", charger.FirmwareVersion)
+	assert.Equal(t, boot.Iccid, charger.ICCID)
+	assert.Equal(t, boot.Imsi, charger.IMSI)
+	require.NotNil(t, charger.LastBootAt)
+	assert.True(t, fake.Now().Equal(*charger.LastBootAt))
+}
+
+func TestMeterValuesPersistedMeasurands(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	metrics := monitoring.NewMetrics()
+	handler := NewOCPPHandler(config.OCPPConfig{
+		PersistedMeasurands: []string{"Energy.Active.Import.Register", "Power.Active.Import"},
+	}, repos, fake, dbtest.Logger())
+	handler.SetMetrics(metrics)
+
+	_, err = handler.MeterValues(ctx, "CP-1", ocpp.MeterValuesRequest{
+		ConnectorID: 1,
+		MeterValue: []ocpp.MeterValue{{
+			Timestamp: fake.Now(),
+			SampledValue: []ocpp.SampledValue{
+				{Value: "1500"}, // defaults to Energy.Active.Import.Register
+				{Value: "7.2", Measurand: "Power.Active.Import", Unit: "kW"},
+				{Value: "230", Measurand: "Voltage", Unit: "V", Phase: "L1"},
+				{Value: "16", Measurand: "Current.Import", Unit: "A"},
+				{Value: "230", Measurand: "Voltage", Unit: "V", Phase: "L2"},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	voltage, err := repos.MeterValues().GetByMeasurand(ctx, "CP-1", "Voltage", db.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, voltage)
+
+	energy, err := repos.MeterValues().GetByMeasurand(ctx, "CP-1", ocpp.DefaultMeasurand, db.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, energy, 1)
+	assert.Equal(t, 1500.0, energy[0].Value)
+	assert.Equal(t, ocpp.DefaultUnit, energy[0].Unit)
+
+	expected := `
+# HELP meter_values_dropped_total Total number of sampled values dropped because their measurand is not persisted
+# TYPE meter_values_dropped_total counter
+meter_values_dropped_total{measurand="Current.Import"} 1
+meter_values_dropped_total{measurand="Voltage"} 2
+`
+	err = testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "meter_values_dropped_total")
+	require.NoError(t, err)
+}
+
+func TestMeterValuesKeepsZeroReadings(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	_, err = handler.MeterValues(ctx, "CP-1", ocpp.MeterValuesRequest{
+		ConnectorID: 1,
+		MeterValue: []ocpp.MeterValue{
+			// No current flows while the session is paused
+			{Timestamp: fake.Now(), SampledValue: []ocpp.SampledValue{{Value: "0", Measurand: "Current.Import", Unit: "A"}}},
+			// A sample without a timestamp is dropped rather than failing the rest
+			{SampledValue: []ocpp.SampledValue{{Value: "16", Measurand: "Current.Import", Unit: "A"}}},
+		},
+	})
+	require.NoError(t, err)
+
+	current, err := repos.MeterValues().GetByMeasurand(ctx, "CP-1", "Current.Import", db.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, current, 1)
+	assert.Equal(t, 0.0, current[0].Value)
+}
+
+func TestMeterValuesFlattensThreePhaseSamples(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	// An active session on connector 1; the charger omits transactionId
+	ocppTxID := 42
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		TransactionID: &ocppTxID, ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-1",
+	})
+	require.NoError(t, err)
+
+	threePhase := func(energy string) []ocpp.SampledValue {
+		samples := []ocpp.SampledValue{{Value: energy, Unit: "kWh", Context: "Sample.Clock", Format: "Raw"}}
+		for _, phase := range []string{"L1", "L2", "L3"} {
+			samples = append(samples,
+				ocpp.SampledValue{Value: "231.4", Measurand: "Voltage", Unit: "V", Phase: phase + "-N", Location: "Inlet"},
+				ocpp.SampledValue{Value: "15.9", Measurand: "Current.Import", Unit: "A", Phase: phase},
+				ocpp.SampledValue{Value: "3.68", Measurand: "Power.Active.Import", Unit: "kW", Phase: phase},
+			)
+		}
+		return samples
+	}
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	_, err = handler.MeterValues(ctx, "CP-1", ocpp.MeterValuesRequest{
+		ConnectorID: 1,
+		MeterValue: []ocpp.MeterValue{
+			{Timestamp: fake.Now(), SampledValue: threePhase("12.5")},
+			{Timestamp: fake.Now().Add(time.Minute), SampledValue: threePhase("12.7")},
+		},
+	})
+	require.NoError(t, err)
+
+	// One energy register and three measurands on each of three phases, twice
+	values, err := repos.MeterValues().GetByTransactionID(ctx, tx.ID, db.ListOptions{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, values, 20)
+
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 20, count)
+
+	energy, err := repos.MeterValues().GetByMeasurand(ctx, "CP-1", ocpp.DefaultMeasurand, db.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, energy, 2)
+	assert.Equal(t, 12.7, energy[0].Value)
+	assert.Equal(t, "kWh", energy[0].Unit)
+	assert.Equal(t, "Sample.Clock", energy[0].Context)
+	assert.Equal(t, ocpp.DefaultLocation, energy[0].Location)
+	assert.Empty(t, energy[0].Phase)
+
+	voltage, err := repos.MeterValues().GetByMeasurand(ctx, "CP-1", "Voltage", db.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, voltage, 6)
+	phases := map[string]int{}
+	for _, v := range voltage {
+		phases[v.Phase]++
+		assert.Equal(t, "Inlet", v.Location)
+		assert.Equal(t, ocpp.DefaultContext, v.Context)
+		assert.Equal(t, ocpp.DefaultFormat, v.Format)
+		require.NotNil(t, v.TransactionID)
+		assert.Equal(t, tx.ID, *v.TransactionID)
+	}
+	assert.Equal(t, map[string]int{"L1-N": 2, "L2-N": 2, "L3-N": 2}, phases)
+
+	// Without an active session the values stay transaction-less
+	_, err = handler.MeterValues(ctx, "CP-1", ocpp.MeterValuesRequest{
+		ConnectorID: 2,
+		MeterValue:  []ocpp.MeterValue{{Timestamp: fake.Now(), SampledValue: threePhase("3.1")}},
+	})
+	require.NoError(t, err)
+	count, err = repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 30, count)
+	values, err = repos.MeterValues().GetByTransactionID(ctx, tx.ID, db.ListOptions{Limit: 100})
+	require.NoError(t, err)
+	assert.Len(t, values, 20)
+}
+
+func TestMeterValuesRateLimitDropsExcess(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	for _, id := range []string{"CP-1", "CP-2"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+	}
+
+	metrics := monitoring.NewMetrics()
+	handler := NewOCPPHandler(config.OCPPConfig{
+		MaxMeterValuesPerMinute: 3,
+		MeterRateLimitAction:    config.MeterRateLimitDrop,
+	}, repos, fake, dbtest.Logger())
+	handler.SetMetrics(metrics)
+
+	send := func(chargerID string) {
+		_, err := handler.MeterValues(ctx, chargerID, ocpp.MeterValuesRequest{
+			ConnectorID: 1,
+			MeterValue: []ocpp.MeterValue{
+				{Timestamp: fake.Now(), SampledValue: []ocpp.SampledValue{{Value: "100"}}},
+				{Timestamp: fake.Now(), SampledValue: []ocpp.SampledValue{{Value: "7.2", Measurand: "Power.Active.Import", Unit: "kW"}}},
+			},
+		})
+		require.NoError(t, err)
+	}
+	stored := func() int {
+		count, err := repos.MeterValues().Count(ctx)
+		require.NoError(t, err)
+		return count
+	}
+
+	send("CP-1")
+	send("CP-1") // only the first sample fits in the minute
+	assert.Equal(t, 3, stored())
+
+	send("CP-1")
+	assert.Equal(t, 3, stored())
+
+	// Each charger has its own allowance
+	send("CP-2")
+	assert.Equal(t, 5, stored())
+
+	// The allowance renews with the next minute
+	fake.Advance(time.Minute)
+	send("CP-1")
+	assert.Equal(t, 7, stored())
+
+	expected := `
+# HELP meter_values_rate_limited_total Total number of sampled values dropped because a charger exceeded its meter value rate limit
+# TYPE meter_values_rate_limited_total counter
+meter_values_rate_limited_total{charge_point_id="CP-1"} 3
+`
+	err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "meter_values_rate_limited_total")
+	require.NoError(t, err)
+}
+
+func TestMeterValuesRateLimitThrottles(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	limiter := NewMeterRateLimiter(3, fake)
+
+	require.NoError(t, limiter.Wait(context.Background(), "CP-1", 2))
+
+	// Over the limit the call waits for the next minute, bounded by the context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, "CP-1", 2), context.DeadlineExceeded)
+
+	fake.Advance(time.Minute)
+	require.NoError(t, limiter.Wait(context.Background(), "CP-1", 2))
+
+	// A waiting call proceeds once the fake clock reaches the next window
+	done := make(chan error, 1)
+	go func() { done <- limiter.Wait(context.Background(), "CP-1", 2) }()
+	assert.Eventually(t, func() bool {
+		fake.Advance(time.Second)
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, time.Millisecond)
+
+	// A message larger than the limit goes through on its own in a fresh window
+	fake.Advance(time.Minute)
+	require.NoError(t, limiter.Wait(context.Background(), "CP-1", 10))
+	assert.Zero(t, limiter.Allow("CP-1", 1))
+}
+
+func TestMeterRateLimiterDropsEndedWindows(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	limiter := NewMeterRateLimiter(3, fake)
+
+	limiter.Allow("CP-1", 1)
+	limiter.Allow("CP-2", 1)
+	assert.Len(t, limiter.windows, 2)
+
+	fake.Advance(2 * time.Minute)
+	limiter.Allow("CP-3", 1)
+	assert.Len(t, limiter.windows, 1)
+	assert.Contains(t, limiter.windows, "CP-3")
+}
+
+func TestMeterValuesEmptyAllowlistPersistsAll(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	_, err = handler.MeterValues(ctx, "CP-1", ocpp.MeterValuesRequest{
+		ConnectorID: 1,
+		MeterValue: []ocpp.MeterValue{{
+			Timestamp: fake.Now(),
+			SampledValue: []ocpp.SampledValue{
+				{Value: "1500"},
+				{Value: "230", Measurand: "Voltage", Unit: "V"},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestStopTransactionIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	ocppTxID := 42
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		TransactionID: &ocppTxID,
+		ChargerID:     "CP-1",
+		ConnectorID:   1,
+		IDTag:         "TAG-1",
+		MeterStart:    1000,
+	})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	req := ocpp.StopTransactionRequest{
+		IDTag:         "TAG-1",
+		MeterStop:     5000,
+		Timestamp:     fake.Now(),
+		TransactionID: ocppTxID,
+		Reason:        "EVDisconnected",
+		TransactionData: []ocpp.MeterValue{{
+			Timestamp:    fake.Now(),
+			SampledValue: []ocpp.SampledValue{{Value: "5000", Context: "Transaction.End"}},
+		}},
+	}
+
+	first, err := handler.StopTransaction(ctx, "CP-1", req)
+	require.NoError(t, err)
+	require.NotNil(t, first.IDTagInfo)
+	assert.Equal(t, ocpp.AuthorizationAccepted, first.IDTagInfo.Status)
+
+	// The retry carries a different reading to prove it is not applied
+	req.MeterStop = 9000
+	second, err := handler.StopTransaction(ctx, "CP-1", req)
+	require.NoError(t, err)
+	require.NotNil(t, second.IDTagInfo)
+	assert.Equal(t, ocpp.AuthorizationAccepted, second.IDTagInfo.Status)
+
+	stopped, err := repos.Transactions().GetByID(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.TransactionStatusCompleted, stopped.Status)
+	require.NotNil(t, stopped.MeterStop)
+	assert.Equal(t, 5000, *stopped.MeterStop)
+	assert.Equal(t, 4000, stopped.EnergyDelivered)
+	assert.Equal(t, "EVDisconnected", stopped.StopReason)
+	assert.Equal(t, db.StopSourceCharger, stopped.StopSource)
+
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	require.NotNil(t, charger.LastTxStopAt)
+	assert.True(t, fake.Now().Equal(*charger.LastTxStopAt))
+}
+
+func TestStopTransactionClampsNegativeEnergy(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	// A meter swapped or reset mid-session reports a stop below the start
+	ocppTxID := 42
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		TransactionID: &ocppTxID, ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-1", MeterStart: 5000,
+	})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	resp, err := handler.StopTransaction(ctx, "CP-1", ocpp.StopTransactionRequest{
+		MeterStop: 1200, Timestamp: fake.Now(), TransactionID: ocppTxID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AuthorizationAccepted, resp.IDTagInfo.Status)
+
+	stopped, err := repos.Transactions().GetByID(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.TransactionStatusCompleted, stopped.Status)
+	require.NotNil(t, stopped.MeterStop)
+	assert.Equal(t, 1200, *stopped.MeterStop)
+	assert.Zero(t, stopped.EnergyDelivered)
+}
+
+func TestStopTransactionForUnknownTransactionIsAccepted(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	resp, err := handler.StopTransaction(ctx, "CP-1", ocpp.StopTransactionRequest{
+		MeterStop: 1000, Timestamp: fake.Now(), TransactionID: 404,
+		TransactionData: []ocpp.MeterValue{{
+			Timestamp:    fake.Now(),
+			SampledValue: []ocpp.SampledValue{{Value: "1000"}},
+		}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.IDTagInfo)
+	assert.Equal(t, ocpp.AuthorizationAccepted, resp.IDTagInfo.Status)
+
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Nil(t, charger.LastTxStopAt)
+}
+
+func TestTransactionMessagesFromAnotherChargerAreIgnored(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	for _, id := range []string{"CP-1", "CP-2"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+	}
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	start, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+		ConnectorID: 1, IDTag: "TAG-1", MeterStart: 0, Timestamp: fake.Now(),
+	})
+	require.NoError(t, err)
+
+	_, err = handler.MeterValues(ctx, "CP-2", ocpp.MeterValuesRequest{
+		ConnectorID: 1, TransactionID: &start.TransactionID,
+		MeterValue: []ocpp.MeterValue{{Timestamp: fake.Now(), SampledValue: []ocpp.SampledValue{{Value: "500"}}}},
+	})
+	require.NoError(t, err)
+
+	resp, err := handler.StopTransaction(ctx, "CP-2", ocpp.StopTransactionRequest{
+		MeterStop: 1000, Timestamp: fake.Now(), TransactionID: start.TransactionID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AuthorizationAccepted, resp.IDTagInfo.Status)
+
+	tx, err := repos.Transactions().GetByTransactionID(ctx, start.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, db.TransactionStatusActive, tx.Status)
+	assert.Nil(t, tx.StopTime)
+
+	values, err := repos.MeterValues().GetByTransactionID(ctx, tx.ID, db.DefaultListOptions())
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestStopTransactionRecordsStopSource(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+
+	for i, tc := range []struct {
+		reason string
+		source string
+	}{
+		{"", db.StopSourceCharger},
+		{ocpp.ReasonLocal, db.StopSourceCharger},
+		{"EmergencyStop", db.StopSourceCharger},
+		{ocpp.ReasonRemote, db.StopSourceRemote},
+	} {
+		ocppTxID := 100 + i
+		tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+			TransactionID: &ocppTxID, ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-1",
+		})
+		require.NoError(t, err)
+
+		_, err = handler.StopTransaction(ctx, "CP-1", ocpp.StopTransactionRequest{
+			MeterStop: 1000, Timestamp: fake.Now(), TransactionID: ocppTxID, Reason: tc.reason,
+		})
+		require.NoError(t, err)
+
+		stopped, err := repos.Transactions().GetByID(ctx, tx.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.source, stopped.StopSource, "reason %q", tc.reason)
+	}
+}
+
+func TestStopTransactionRollsBackWhenTransactionDataFails(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	ocppTxID := 42
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		TransactionID: &ocppTxID,
+		ChargerID:     "CP-1",
+		ConnectorID:   1,
+		IDTag:         "TAG-1",
+		MeterStart:    1000,
+	})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	req := ocpp.StopTransactionRequest{
+		MeterStop:     5000,
+		Timestamp:     fake.Now(),
+		TransactionID: ocppTxID,
+		TransactionData: []ocpp.MeterValue{{
+			Timestamp:    fake.Now(),
+			SampledValue: []ocpp.SampledValue{{Value: "NaN"}},
+		}},
+	}
+
+	// NaN is stored as NULL and fails the NOT NULL value column
+	_, err = handler.StopTransaction(ctx, "CP-1", req)
+	require.Error(t, err)
+
+	active, err := repos.Transactions().GetByID(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.TransactionStatusActive, active.Status)
+
+	// The charger's retry is processed in full
+	req.TransactionData[0].SampledValue[0].Value = "5000"
+	_, err = handler.StopTransaction(ctx, "CP-1", req)
+	require.NoError(t, err)
+
+	stopped, err := repos.Transactions().GetByID(ctx, tx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.TransactionStatusCompleted, stopped.Status)
+
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestStartTransactionDedup(t *testing.T) {
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		idTag     string
+		offset    time.Duration
+		wantDedup bool
+	}{
+		{"retry with same timestamp", "TAG-1", 0, true},
+		{"retry within window", "TAG-1", 20 * time.Second, true},
+		{"outside window", "TAG-1", 2 * time.Minute, false},
+		{"different id tag", "TAG-2", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := clock.NewFake(start)
+			repos := dbtest.NewRepositories(t, fake)
+			_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+			require.NoError(t, err)
+
+			handler := NewOCPPHandler(config.OCPPConfig{StartDedupWindow: time.Minute}, repos, fake, dbtest.Logger())
+
+			first, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+				ConnectorID: 1,
+				IDTag:       "TAG-1",
+				MeterStart:  1000,
+				Timestamp:   start,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, ocpp.AuthorizationAccepted, first.IDTagInfo.Status)
+
+			fake.Advance(time.Second)
+			second, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+				ConnectorID: 1,
+				IDTag:       tt.idTag,
+				MeterStart:  1000,
+				Timestamp:   start.Add(tt.offset),
+			})
+			require.NoError(t, err)
+
+			// Anything but a retry is a second transaction on a busy connector
+			count, err := repos.Transactions().Count(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+			if tt.wantDedup {
+				assert.Equal(t, ocpp.AuthorizationAccepted, second.IDTagInfo.Status)
+				assert.Equal(t, first.TransactionID, second.TransactionID)
+			} else {
+				assert.Equal(t, ocpp.AuthorizationConcurrentTx, second.IDTagInfo.Status)
+				assert.Zero(t, second.TransactionID)
+			}
+		})
+	}
+}
+
+func TestConcurrentStartTransactionsAcrossHandlers(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	// Separate handlers stand in for separate processes sharing the database
+	var wg sync.WaitGroup
+	statuses := make([]string, 8)
+	for i := range statuses {
+		handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+				ConnectorID: 1, IDTag: fmt.Sprintf("TAG-%d", i), Timestamp: fake.Now(),
+			})
+			if assert.NoError(t, err) {
+				statuses[i] = resp.IDTagInfo.Status
+			}
+		}()
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, status := range statuses {
+		if status == ocpp.AuthorizationAccepted {
+			accepted++
+		} else {
+			assert.Equal(t, ocpp.AuthorizationConcurrentTx, status)
+		}
+	}
+	assert.Equal(t, 1, accepted)
+
+	count, err := repos.Transactions().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestStartTransactionBackToBackOnOneConnector(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{StartDedupWindow: time.Minute}, repos, fake, dbtest.Logger())
+
+	// Several tags race for the same connector; none is a retry of another
+	const starts = 8
+	var wg sync.WaitGroup
+	ready := make(chan struct{})
+	responses := make([]*ocpp.StartTransactionResponse, starts)
+	for i := range starts {
+		idTag := fmt.Sprintf("TAG-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-ready
+			resp, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+				ConnectorID: 1,
+				IDTag:       idTag,
+				MeterStart:  1000,
+				Timestamp:   start,
+			})
+			assert.NoError(t, err)
+			responses[i] = resp
+		}()
+	}
+	close(ready)
+	wg.Wait()
+
+	accepted := 0
+	for _, resp := range responses {
+		require.NotNil(t, resp)
+		if resp.IDTagInfo.Status == ocpp.AuthorizationAccepted {
+			accepted++
+		} else {
+			assert.Equal(t, ocpp.AuthorizationConcurrentTx, resp.IDTagInfo.Status)
+		}
+	}
+	assert.Equal(t, 1, accepted)
+
+	count, err := repos.Transactions().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	require.NotNil(t, charger.LastTxStartAt)
+	assert.True(t, start.Equal(*charger.LastTxStartAt))
+}
+
+func TestStartTransactionAuthorizesIDTag(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	expired := start.Add(-time.Hour)
+	expires := start.Add(time.Hour)
+	for _, tag := range []db.CreateIDTagRequest{
+		{IDTag: "BLOCKED", Status: ocpp.AuthorizationBlocked},
+		{IDTag: "EXPIRED", ExpiryDate: &expired},
+		{IDTag: "CHILD", ParentIDTag: "FLEET", ExpiryDate: &expires},
+	} {
+		_, err := repos.IDTags().Create(ctx, tag)
+		require.NoError(t, err)
+	}
+
+	cfg := config.OCPPConfig{RejectUnknownIDTags: true, AuthorizationCacheTTL: time.Minute}
+	handler := NewOCPPHandler(cfg, repos, fake, dbtest.Logger())
+	startWith := func(connectorID int, idTag string) *ocpp.StartTransactionResponse {
+		resp, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+			ConnectorID: connectorID, IDTag: idTag, Timestamp: fake.Now(),
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	assert.Equal(t, ocpp.AuthorizationBlocked, startWith(1, "BLOCKED").IDTagInfo.Status)
+	assert.Equal(t, ocpp.AuthorizationExpired, startWith(1, "EXPIRED").IDTagInfo.Status)
+	assert.Equal(t, ocpp.AuthorizationInvalid, startWith(1, "UNKNOWN").IDTagInfo.Status)
+
+	count, err := repos.Transactions().Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	accepted := startWith(1, "CHILD")
+	assert.Equal(t, ocpp.AuthorizationAccepted, accepted.IDTagInfo.Status)
+	assert.Equal(t, "FLEET", accepted.IDTagInfo.ParentIDTag)
+	require.NotNil(t, accepted.IDTagInfo.ExpiryDate)
+	assert.True(t, expires.Equal(*accepted.IDTagInfo.ExpiryDate))
+	assert.NotZero(t, accepted.TransactionID)
+
+	// A cached decision is reused until the TTL passes
+	require.NoError(t, repos.IDTags().UpdateStatus(ctx, "BLOCKED", ocpp.AuthorizationAccepted))
+	assert.Equal(t, ocpp.AuthorizationBlocked, startWith(2, "BLOCKED").IDTagInfo.Status)
+	fake.Advance(time.Minute)
+	assert.Equal(t, ocpp.AuthorizationAccepted, startWith(2, "BLOCKED").IDTagInfo.Status)
+}
+
+func TestStartTransactionFromUnknownCharger(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	startTransaction := func(t *testing.T, cfg config.OCPPConfig) (db.RepositoryManager, *ocpp.StartTransactionResponse, error) {
+		fake := clock.NewFake(start)
+		repos := dbtest.NewRepositories(t, fake)
+		handler := NewOCPPHandler(cfg, repos, fake, dbtest.Logger())
+		resp, err := handler.StartTransaction(ctx, "CP-GHOST", ocpp.StartTransactionRequest{
+			ConnectorID: 1, IDTag: "TAG", MeterStart: 100, Timestamp: start,
+		})
+		return repos, resp, err
+	}
+
+	t.Run("rejected by default", func(t *testing.T) {
+		repos, _, err := startTransaction(t, config.OCPPConfig{})
+		var handlerErr *ocpp.HandlerError
+		require.ErrorAs(t, err, &handlerErr)
+		assert.Equal(t, ocpp.ErrorCodeSecurityError, handlerErr.Code)
+
+		count, err := repos.Chargers().Count(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("auto-created", func(t *testing.T) {
+		repos, resp, err := startTransaction(t, config.OCPPConfig{AutocreateChargerOnTransaction: true})
+		require.NoError(t, err)
+		assert.Equal(t, ocpp.AuthorizationAccepted, resp.IDTagInfo.Status)
+
+		charger, err := repos.Chargers().GetByID(ctx, "CP-GHOST")
+		require.NoError(t, err)
+		assert.Equal(t, "Unknown", charger.Status)
+
+		tx, err := repos.Transactions().GetActiveByConnector(ctx, "CP-GHOST", 1)
+		require.NoError(t, err)
+		require.NotNil(t, tx)
+		assert.Equal(t, resp.TransactionID, *tx.TransactionID)
+		assert.Equal(t, 100, tx.MeterStart)
+	})
+}
+
+func TestStartTransactionHonorsReservation(t *testing.T) {
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		idTag       string
+		reservedOn  int
+		wantStatus  string
+		wantReserve string
+	}{
+		{"matching id tag", "TAG-1", 1, ocpp.AuthorizationAccepted, db.ReservationStatusUsed},
+		{"matching parent id tag", "TAG-CHILD", 1, ocpp.AuthorizationAccepted, db.ReservationStatusUsed},
+		{"different id tag", "TAG-2", 1, ocpp.AuthorizationInvalid, db.ReservationStatusActive},
+		{"any connector reservation", "TAG-2", 0, ocpp.AuthorizationInvalid, db.ReservationStatusActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := clock.NewFake(start)
+			repos := dbtest.NewRepositories(t, fake)
+			_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+			require.NoError(t, err)
+			_, err = repos.IDTags().Create(ctx, db.CreateIDTagRequest{IDTag: "TAG-CHILD", ParentIDTag: "FLEET"})
+			require.NoError(t, err)
+			_, err = repos.Reservations().Create(ctx, db.CreateReservationRequest{
+				ID:          7,
+				ChargerID:   "CP-1",
+				ConnectorID: tt.reservedOn,
+				IDTag:       "TAG-1",
+				ParentIDTag: "FLEET",
+				ExpiryDate:  start.Add(time.Hour),
+			})
+			require.NoError(t, err)
+
+			handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+			resp, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+				ConnectorID: 1,
+				IDTag:       tt.idTag,
+				MeterStart:  1000,
+				Timestamp:   start,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.IDTagInfo.Status)
+
+			reservation, err := repos.Reservations().GetByID(ctx, 7)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReserve, reservation.Status)
+
+			active, err := repos.Transactions().GetActiveByConnector(ctx, "CP-1", 1)
+			require.NoError(t, err)
+			if tt.wantStatus == ocpp.AuthorizationAccepted {
+				require.NotNil(t, active)
+				assert.Equal(t, resp.TransactionID, *active.TransactionID)
+			} else {
+				assert.Nil(t, active)
+			}
+		})
+	}
+}
+
+func TestStartTransactionIgnoresExpiredReservation(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	_, err = repos.Reservations().Create(ctx, db.CreateReservationRequest{
+		ID:          7,
+		ChargerID:   "CP-1",
+		ConnectorID: 1,
+		IDTag:       "TAG-1",
+		ExpiryDate:  start.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	resp, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+		ConnectorID: 1,
+		IDTag:       "TAG-2",
+		Timestamp:   start,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.AuthorizationAccepted, resp.IDTagInfo.Status)
+}
+
+func TestBootNotificationProvisionsConnectors(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1", NumConnectors: 3})
+	require.NoError(t, err)
+
+	// Connector 2 has already reported and must keep its status
+	_, err = repos.Connectors().Create(ctx, "CP-1", 2)
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	for i := 0; i < 2; i++ {
+		_, err = handler.BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{ChargePointVendor: "Acme", ChargePointModel: "X1"})
+		require.NoError(t, err)
+	}
+
+	connectors, err := repos.Connectors().GetByChargerID(ctx, "CP-1")
+	require.NoError(t, err)
+	require.Len(t, connectors, 3)
+
+	statuses := map[int]string{}
+	for _, conn := range connectors {
+		statuses[conn.ConnectorID] = conn.Status
+	}
+	assert.Equal(t, map[int]string{
+		1: ocpp.ChargePointStatusUnavailable,
+		2: ocpp.ChargePointStatusAvailable,
+		3: ocpp.ChargePointStatusUnavailable,
+	}, statuses)
+}
+
+func TestBootNotificationWithoutConnectorCount(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	_, err := handler.BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{ChargePointVendor: "Acme", ChargePointModel: "X1"})
+	require.NoError(t, err)
+
+	connectors, err := repos.Connectors().GetByChargerID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Empty(t, connectors)
+}
+
+func TestStatusNotificationConnectorZero(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	_, err = handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+		ConnectorID: 0,
+		ErrorCode:   ocpp.ChargePointErrorNoError,
+		Status:      ocpp.ChargePointStatusUnavailable,
+	})
+	require.NoError(t, err)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusUnavailable, charger.Status)
+
+	connectors, err := repos.Connectors().GetByChargerID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Empty(t, connectors)
+}
+
+func TestStatusNotificationIgnoresConnectorBeyondLimit(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{MaxConnectorsPerCharger: 2}, repos, fake, dbtest.Logger())
+	for _, connectorID := range []int{2, 3, 1000} {
+		resp, err := handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+			ConnectorID: connectorID,
+			ErrorCode:   ocpp.ChargePointErrorNoError,
+			Status:      ocpp.ChargePointStatusAvailable,
+		})
+		require.NoError(t, err)
+		assert.NotNil(t, resp)
+	}
+
+	connectors, err := repos.Connectors().GetByChargerID(ctx, "CP-1")
+	require.NoError(t, err)
+	require.Len(t, connectors, 1)
+	assert.Equal(t, 2, connectors[0].ConnectorID)
+}
+
+func TestStatusNotificationValidatesStatus(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	metrics := monitoring.NewMetrics()
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	handler.SetMetrics(metrics)
+
+	notify := func(connectorID int, status, errorCode string) {
+		t.Helper()
+		resp, err := handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+			ConnectorID: connectorID, ErrorCode: errorCode, Status: status,
+		})
+		require.NoError(t, err)
+		assert.NotNil(t, resp)
+	}
+	connectorStatus := func() string {
+		t.Helper()
+		connector, err := repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 1)
+		require.NoError(t, err)
+		return connector.Status
+	}
+	// A valid status persists, normalized to its OCPP spelling
+	notify(1, ocpp.ChargePointStatusPreparing, ocpp.ChargePointErrorNoError)
+	assert.Equal(t, ocpp.ChargePointStatusPreparing, connectorStatus())
+	notify(1, " charging", ocpp.ChargePointErrorNoError)
+	assert.Equal(t, ocpp.ChargePointStatusCharging, connectorStatus())
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.Registry(), "ocpp_unknown_connector_status_total"))
+
+	// An unknown status is flagged and the last known status kept, while the
+	// error code is still recorded
+	notify(1, "Chargin", "GroundFailure")
+	assert.Equal(t, ocpp.ChargePointStatusCharging, connectorStatus())
+	connector, err := repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "GroundFailure", connector.ErrorCode)
+
+	notify(0, "Booting", ocpp.ChargePointErrorNoError)
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.NotEqual(t, "Booting", charger.Status)
+
+	expected := `
+# HELP ocpp_unknown_connector_status_total Total number of StatusNotifications reporting a status outside the OCPP set
+# TYPE ocpp_unknown_connector_status_total counter
+ocpp_unknown_connector_status_total{charge_point_id="CP-1"} 2
+`
+	err = testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_unknown_connector_status_total")
+	assert.NoError(t, err)
+}
+
+func TestStatusNotificationMovesConnectorsByStatus(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	_, err = repos.Connectors().Create(ctx, "CP-1", 1)
+	require.NoError(t, err)
+
+	metrics := monitoring.NewMetrics()
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	handler.SetMetrics(metrics)
+	require.NoError(t, syncConnectorsByStatus(ctx, repos, metrics))
+
+	notify := func(connectorID int, status string) {
+		t.Helper()
+		_, err := handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+			ConnectorID: connectorID, ErrorCode: ocpp.ChargePointErrorNoError, Status: status,
+		})
+		require.NoError(t, err)
+	}
+	notify(1, ocpp.ChargePointStatusPreparing)
+	notify(1, ocpp.ChargePointStatusCharging)
+	// Repeats and unknown statuses are not transitions
+	notify(1, ocpp.ChargePointStatusCharging)
+	notify(1, "Chargin")
+	// A connector first seen through its report is counted once, in its reported status
+	notify(2, ocpp.ChargePointStatusFaulted)
+
+	expected := `
+# HELP connectors_by_status Current number of connectors in each OCPP status
+# TYPE connectors_by_status gauge
+connectors_by_status{status="Available"} 0
+connectors_by_status{status="Charging"} 1
+connectors_by_status{status="Faulted"} 1
+connectors_by_status{status="Preparing"} 0
+`
+	err = testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "connectors_by_status")
+	assert.NoError(t, err)
+
+	// A resync agrees with the transitions, dropping the empty statuses
+	require.NoError(t, syncConnectorsByStatus(ctx, repos, metrics))
+	expected = `
+# HELP connectors_by_status Current number of connectors in each OCPP status
+# TYPE connectors_by_status gauge
+connectors_by_status{status="Charging"} 1
+connectors_by_status{status="Faulted"} 1
+`
+	err = testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "connectors_by_status")
+	assert.NoError(t, err)
+}
+
+func TestStatusNotificationCreatesConnector(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	_, err = handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+		ConnectorID:     2,
+		ErrorCode:       "GroundFailure",
+		Status:          ocpp.ChargePointStatusFaulted,
+		VendorErrorCode: "E42",
+	})
+	require.NoError(t, err)
+
+	conn, err := repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusFaulted, conn.Status)
+	assert.Equal(t, "GroundFailure", conn.ErrorCode)
+	assert.Equal(t, "E42", conn.VendorErrorCode)
+
+	_, err = handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+		ConnectorID: 2,
+		ErrorCode:   ocpp.ChargePointErrorNoError,
+		Status:      ocpp.ChargePointStatusAvailable,
+	})
+	require.NoError(t, err)
+
+	conn, err = repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusAvailable, conn.Status)
+	assert.Empty(t, conn.ErrorCode)
+}
+
+func TestStatusNotificationLogsConnectorErrors(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	notify := func(connectorID int, status, errorCode string) {
+		t.Helper()
+		_, err := handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+			ConnectorID: connectorID, ErrorCode: errorCode, Status: status, Info: "reported by test",
+		})
+		require.NoError(t, err)
+		fake.Advance(time.Minute)
+	}
+	activeCodes := func() []string {
+		t.Helper()
+		active, err := repos.Errors().GetActiveByChargerID(ctx, "CP-1")
+		require.NoError(t, err)
+		codes := []string{}
+		for _, cerr := range active {
+			codes = append(codes, cerr.ErrorCode)
+		}
+		return codes
+	}
+
+	// A fault is logged once however often the charger repeats it
+	notify(1, ocpp.ChargePointStatusFaulted, "GroundFailure")
+	notify(1, ocpp.ChargePointStatusFaulted, "GroundFailure")
+	assert.Equal(t, []string{"GroundFailure"}, activeCodes())
+
+	logged, err := repos.Errors().GetByChargerID(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, logged, 1)
+	require.NotNil(t, logged[0].ConnectorID)
+	assert.Equal(t, 1, *logged[0].ConnectorID)
+	assert.Equal(t, "reported by test", logged[0].ErrorDescription)
+
+	// A different code replaces the previous error
+	notify(1, ocpp.ChargePointStatusFaulted, "OverVoltage")
+	assert.Equal(t, []string{"OverVoltage"}, activeCodes())
+
+	// Recovering clears the connector and resolves its error
+	notify(1, ocpp.ChargePointStatusAvailable, ocpp.ChargePointErrorNoError)
+	assert.Empty(t, activeCodes())
+	conn, err := repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusAvailable, conn.Status)
+	assert.Empty(t, conn.ErrorCode)
+
+	// Connector 0 updates the charger, not a connector
+	notify(0, ocpp.ChargePointStatusUnavailable, ocpp.ChargePointErrorNoError)
+	charger, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusUnavailable, charger.Status)
+	connectors, err := repos.Connectors().GetByChargerID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Len(t, connectors, 1)
+
+	total, err := repos.Errors().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+}
+
+func TestStatusNotificationScopesErrorsByConnector(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	notify := func(connectorID int, status, errorCode string) {
+		t.Helper()
+		_, err := handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+			ConnectorID: connectorID, ErrorCode: errorCode, Status: status,
+		})
+		require.NoError(t, err)
+		fake.Advance(time.Minute)
+	}
+	activeConnectors := func() []int {
+		t.Helper()
+		active, err := repos.Errors().GetActiveByChargerID(ctx, "CP-1")
+		require.NoError(t, err)
+		ids := []int{}
+		for _, cerr := range active {
+			require.NotNil(t, cerr.ConnectorID)
+			ids = append(ids, *cerr.ConnectorID)
+		}
+		sort.Ints(ids)
+		return ids
+	}
+
+	// Charge point faults are logged once, like connector faults
+	notify(0, ocpp.ChargePointStatusFaulted, "PowerMeterFailure")
+	notify(0, ocpp.ChargePointStatusFaulted, "PowerMeterFailure")
+	notify(1, ocpp.ChargePointStatusFaulted, "PowerMeterFailure")
+	notify(2, ocpp.ChargePointStatusFaulted, "PowerMeterFailure")
+	assert.Equal(t, []int{0, 1, 2}, activeConnectors())
+
+	// Recovering one connector leaves the same fault open elsewhere
+	notify(1, ocpp.ChargePointStatusAvailable, ocpp.ChargePointErrorNoError)
+	assert.Equal(t, []int{0, 2}, activeConnectors())
+
+	notify(0, ocpp.ChargePointStatusAvailable, ocpp.ChargePointErrorNoError)
+	assert.Equal(t, []int{2}, activeConnectors())
+
+	total, err := repos.Errors().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+}
+
+func TestStartTransactionMeterStartFallback(t *testing.T) {
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		meterStart int
+		want       int
+	}{
+		{"missing meterStart uses latest register", true, 0, 12346},
+		{"reported meterStart is kept", true, 500, 500},
+		{"fallback disabled", false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fake := clock.NewFake(start)
+			repos := dbtest.NewRepositories(t, fake)
+			_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+			require.NoError(t, err)
+
+			handler := NewOCPPHandler(config.OCPPConfig{MeterStartFallback: tt.enabled}, repos, fake, dbtest.Logger())
+			_, err = handler.MeterValues(ctx, "CP-1", ocpp.MeterValuesRequest{
+				ConnectorID: 1,
+				MeterValue: []ocpp.MeterValue{
+					{Timestamp: start.Add(-time.Hour), SampledValue: []ocpp.SampledValue{{Value: "11000"}}},
+					{Timestamp: start.Add(-time.Minute), SampledValue: []ocpp.SampledValue{
+						{Value: "12.3456", Unit: "kWh"},
+						{Value: "99999", Phase: "L1"},
+						{Value: "7.2", Measurand: "Power.Active.Import", Unit: "kW"},
+					}},
+					// Read after the transaction started, so not its starting register
+					{Timestamp: start.Add(time.Minute), SampledValue: []ocpp.SampledValue{{Value: "13000"}}},
+				},
+			})
+			require.NoError(t, err)
+
+			resp, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+				ConnectorID: 1,
+				IDTag:       "TAG-1",
+				MeterStart:  tt.meterStart,
+				Timestamp:   start,
+			})
+			require.NoError(t, err)
+			require.Equal(t, ocpp.AuthorizationAccepted, resp.IDTagInfo.Status)
+
+			active, err := repos.Transactions().GetActiveByConnector(ctx, "CP-1", 1)
+			require.NoError(t, err)
+			require.NotNil(t, active)
+			assert.Equal(t, tt.want, active.MeterStart)
+		})
+	}
+}
+
+func TestStartTransactionMeterStartFallbackWithoutReadings(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{MeterStartFallback: true}, repos, fake, dbtest.Logger())
+	_, err = handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+		ConnectorID: 2,
+		IDTag:       "TAG-1",
+		Timestamp:   start,
+	})
+	require.NoError(t, err)
+
+	active, err := repos.Transactions().GetActiveByConnector(ctx, "CP-1", 2)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, 0, active.MeterStart)
+}
+
+// stopSkewedTransaction starts a transaction and stops it with a stop time an
+// hour ahead of server time, returning the stopped transaction
+func stopSkewedTransaction(t *testing.T, action string, metrics *monitoring.Metrics) (*db.Transaction, *clock.Fake) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	ocppTxID := 42
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		TransactionID: &ocppTxID,
+		ChargerID:     "CP-1",
+		ConnectorID:   1,
+		IDTag:         "TAG-1",
+		MeterStart:    1000,
+	})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{
+		MaxClockSkew:    5 * time.Minute,
+		ClockSkewAction: action,
+	}, repos, fake, dbtest.Logger())
+	handler.SetMetrics(metrics)
+
+	_, err = handler.StopTransaction(ctx, "CP-1", ocpp.StopTransactionRequest{
+		MeterStop:     5000,
+		Timestamp:     fake.Now().Add(time.Hour),
+		TransactionID: ocppTxID,
+	})
+	require.NoError(t, err)
+
+	stopped, err := repos.Transactions().GetByID(ctx, tx.ID)
+	require.NoError(t, err)
+	return stopped, fake
+}
+
+func TestStopTransactionSubstitutesSkewedTimestamp(t *testing.T) {
+	metrics := monitoring.NewMetrics()
+	stopped, fake := stopSkewedTransaction(t, config.ClockSkewSubstitute, metrics)
+
+	require.NotNil(t, stopped.StopTime)
+	assert.True(t, fake.Now().Equal(*stopped.StopTime))
+	assert.Nil(t, stopped.ClockSkewSeconds)
+
+	expected := `
+# HELP ocpp_clock_skew_seconds Difference between the last charger-supplied timestamp and server time, positive when the charger clock is ahead
+# TYPE ocpp_clock_skew_seconds gauge
+ocpp_clock_skew_seconds{charge_point_id="CP-1"} 3600
+`
+	err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_clock_skew_seconds")
+	require.NoError(t, err)
+}
+
+func TestStopTransactionFlagsSkewedTimestamp(t *testing.T) {
+	stopped, fake := stopSkewedTransaction(t, config.ClockSkewFlag, monitoring.NewMetrics())
+
+	require.NotNil(t, stopped.StopTime)
+	assert.True(t, fake.Now().Add(time.Hour).Equal(*stopped.StopTime))
+	require.NotNil(t, stopped.ClockSkewSeconds)
+	assert.Equal(t, int64(3600), *stopped.ClockSkewSeconds)
+}
+
+func TestStatusNotificationRecordsClockSkew(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	metrics := monitoring.NewMetrics()
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	handler.SetMetrics(metrics)
+
+	behind := fake.Now().Add(-90 * time.Second)
+	_, err = handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+		ConnectorID: 1,
+		Status:      ocpp.ChargePointStatusAvailable,
+		Timestamp:   &behind,
+	})
+	require.NoError(t, err)
+
+	expected := `
+# HELP ocpp_clock_skew_seconds Difference between the last charger-supplied timestamp and server time, positive when the charger clock is ahead
+# TYPE ocpp_clock_skew_seconds gauge
+ocpp_clock_skew_seconds{charge_point_id="CP-1"} -90
+`
+	err = testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_clock_skew_seconds")
+	require.NoError(t, err)
+}
+
+func TestHandleCallStubActions(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	handler := NewOCPPHandler(config.OCPPConfig{
+		StubActions: map[string]string{"getconfiguration": `{"configurationKey": []}`},
+	}, repos, clock.Real(), dbtest.Logger())
+
+	resp, err := handler.HandleCall(ctx, "CP-1", ocpp.Call{UniqueID: "1", Action: "GetConfiguration", Payload: []byte(`{}`)})
+	require.NoError(t, err)
+	payload, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"configurationKey": []}`, string(payload))
+
+	_, err = handler.HandleCall(ctx, "CP-1", ocpp.Call{UniqueID: "2", Action: "DataTransfer", Payload: []byte(`{}`)})
+	var handlerErr *ocpp.HandlerError
+	require.ErrorAs(t, err, &handlerErr)
+	assert.Equal(t, ocpp.ErrorCodeNotImplemented, handlerErr.Code)
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/keeth/levity/core/ocpp"
+)
+
+// HandleCall routes an inbound CALL to the handler for its action and returns
+// the CALLRESULT payload. Unknown actions answer their ocpp.stub_actions
+// payload or fail with a NotImplemented, and payloads that do not decode fail
+// with a FormationViolation ocpp.HandlerError.
+func (h *OCPPHandler) HandleCall(ctx context.Context, chargerID string, call ocpp.Call) (interface{}, error) {
+	switch call.Action {
+	case ocpp.ActionBootNotification:
+		return handleCall(ctx, chargerID, call, h.BootNotification)
+	case ocpp.ActionHeartbeat:
+		return handleCall(ctx, chargerID, call, h.Heartbeat)
+	case ocpp.ActionStatusNotification:
+		return handleCall(ctx, chargerID, call, h.StatusNotification)
+	case ocpp.ActionStartTransaction:
+		return handleCall(ctx, chargerID, call, h.StartTransaction)
+	case ocpp.ActionStopTransaction:
+		return handleCall(ctx, chargerID, call, h.StopTransaction)
+	case ocpp.ActionMeterValues:
+		return handleCall(ctx, chargerID, call, h.MeterValues)
+	case ocpp.ActionSignedFirmwareStatusNotification:
+		return handleCall(ctx, chargerID, call, h.SignedFirmwareStatusNotification)
+	case ocpp.ActionLogStatusNotification:
+		return handleCall(ctx, chargerID, call, h.LogStatusNotification)
+	case ocpp.ActionSecurityEventNotification:
+		return handleCall(ctx, chargerID, call, h.SecurityEventNotification)
+	default:
+		if stub, ok := h.stubs[strings.ToLower(call.Action)]; ok {
+			return stub, nil
+		}
+		return nil, ocpp.NewNotImplementedError("Unknown action " + call.Action)
+	}
+}
+
+// handleCall decodes a CALL payload into the handler's request type and runs it
+func handleCall[Req, Resp any](ctx context.Context, chargerID string, call ocpp.Call, handler func(context.Context, string, Req) (*Resp, error)) (interface{}, error) {
+	var req Req
+	if len(call.Payload) > 0 {
+		if err := json.Unmarshal(call.Payload, &req); err != nil {
+			return nil, ocpp.NewFormationViolationError(fmt.Sprintf("Invalid %s payload: %v", call.Action, err))
+		}
+	}
+	return handler(ctx, chargerID, req)
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// PostStopFallback marks a connector Available when its charger has not
+// reported an idle status within a timeout of StopTransaction. The charger's
+// own StatusNotification is preferred: many report Finishing and only later
+// Available, and forcing Available at the stop makes the UI flicker.
+type PostStopFallback struct {
+	timeout time.Duration
+	repos   db.RepositoryManager
+	metrics *monitoring.Metrics
+	clock   clock.Clock
+	logger  *slog.Logger
+
+	mu sync.Mutex
+	// stopped is when each connector's transaction stopped, until its
+	// charger reports an idle status or the fallback fires
+	stopped map[ConnectorRef]time.Time
+}
+
+// NewPostStopFallback creates a fallback forcing Available timeout after a stop
+func NewPostStopFallback(timeout time.Duration, repos db.RepositoryManager, clk clock.Clock, logger *slog.Logger) *PostStopFallback {
+	return &PostStopFallback{
+		timeout: timeout,
+		repos:   repos,
+		clock:   clk,
+		logger:  logger,
+		stopped: make(map[ConnectorRef]time.Time),
+	}
+}
+
+// SetMetrics sets the metrics updated when connectors are forced Available
+func (f *PostStopFallback) SetMetrics(metrics *monitoring.Metrics) {
+	f.metrics = metrics
+}
+
+// Stopped records that the transaction on a connector stopped now
+func (f *PostStopFallback) Stopped(chargerID string, connectorID int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped[ConnectorRef{ChargerID: chargerID, ConnectorID: connectorID}] = f.clock.Now()
+}
+
+// Reported notes a status the charger reported for a connector. A status
+// outside a charging session means the charger has settled the connector
+// itself, so no fallback is needed.
+func (f *PostStopFallback) Reported(chargerID string, connectorID int, status string) {
+	switch status {
+	case ocpp.ChargePointStatusCharging, ocpp.ChargePointStatusSuspendedEV,
+		ocpp.ChargePointStatusSuspendedEVSE, ocpp.ChargePointStatusFinishing:
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.stopped, ConnectorRef{ChargerID: chargerID, ConnectorID: connectorID})
+}
+
+// CheckOnce marks Available every connector whose charger has stayed silent
+// for the timeout since its stop and returns them. A connector that started
+// a new transaction in the meantime is left alone.
+func (f *PostStopFallback) CheckOnce(ctx context.Context) ([]ConnectorRef, error) {
+	now := f.clock.Now()
+
+	f.mu.Lock()
+	due := make(map[ConnectorRef]time.Time)
+	for ref, stoppedAt := range f.stopped {
+		if now.Sub(stoppedAt) >= f.timeout {
+			due[ref] = stoppedAt
+		}
+	}
+	f.mu.Unlock()
+
+	var forced []ConnectorRef
+	for ref, stoppedAt := range due {
+		active, err := f.repos.Transactions().GetActiveByConnector(ctx, ref.ChargerID, ref.ConnectorID)
+		if err != nil {
+			return forced, fmt.Errorf("failed to get active transaction: %w", err)
+		}
+		if active == nil {
+			if err := f.repos.Connectors().UpdateStatus(ctx, ref.ChargerID, ref.ConnectorID, ocpp.ChargePointStatusAvailable); err != nil {
+				return forced, fmt.Errorf("failed to mark connector %s/%d available: %w", ref.ChargerID, ref.ConnectorID, err)
+			}
+			f.logger.Info("Marked connector Available after stop without a status report",
+				slog.String("charger_id", ref.ChargerID),
+				slog.Int("connector_id", ref.ConnectorID),
+				slog.Duration("timeout", f.timeout))
+			forced = append(forced, ref)
+		}
+
+		// Keep the entry if the connector stopped again meanwhile
+		f.mu.Lock()
+		if f.stopped[ref].Equal(stoppedAt) {
+			delete(f.stopped, ref)
+		}
+		f.mu.Unlock()
+	}
+
+	if len(forced) > 0 {
+		if err := syncConnectorsByStatus(ctx, f.repos, f.metrics); err != nil {
+			f.logger.Warn("Failed to sync connector metrics", slog.Any("error", err))
+		}
+	}
+	return forced, nil
+}
+
+// Run checks for due connectors until the context is cancelled
+func (f *PostStopFallback) Run(ctx context.Context) {
+	interval := max(f.timeout/4, time.Second)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := f.CheckOnce(ctx); err != nil {
+				f.logger.Error("Post-stop availability check failed", slog.Any("error", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startCharging puts a connector of CP-1 in Charging with an active transaction
+func startCharging(t *testing.T, repos db.RepositoryManager, connectorID, txID int) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := repos.Connectors().Create(ctx, "CP-1", connectorID)
+	require.NoError(t, err)
+	require.NoError(t, repos.Connectors().UpdateStatus(ctx, "CP-1", connectorID, ocpp.ChargePointStatusCharging))
+	_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		TransactionID: &txID, ChargerID: "CP-1", ConnectorID: connectorID, IDTag: "TAG",
+	})
+	require.NoError(t, err)
+}
+
+func TestPostStopAvailability(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	startCharging(t, repos, 1, 101)
+	startCharging(t, repos, 2, 102)
+
+	handler := NewOCPPHandler(config.OCPPConfig{PostStopAvailableTimeout: 2 * time.Minute}, repos, fake, dbtest.Logger())
+	fallback := handler.postStop
+	require.NotNil(t, fallback)
+
+	status := func(connectorID int) string {
+		t.Helper()
+		connector, err := repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", connectorID)
+		require.NoError(t, err)
+		return connector.Status
+	}
+	notify := func(connectorID int, status string) {
+		t.Helper()
+		_, err := handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{
+			ConnectorID: connectorID, ErrorCode: ocpp.ChargePointErrorNoError, Status: status,
+		})
+		require.NoError(t, err)
+	}
+
+	for _, txID := range []int{101, 102} {
+		_, err := handler.StopTransaction(ctx, "CP-1", ocpp.StopTransactionRequest{
+			TransactionID: txID, MeterStop: 1000, Timestamp: fake.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	// The stop does not force Available; the charger's reports are kept
+	assert.Equal(t, ocpp.ChargePointStatusCharging, status(1))
+	notify(1, ocpp.ChargePointStatusFinishing)
+	notify(2, ocpp.ChargePointStatusFinishing)
+
+	t.Run("charger reports", func(t *testing.T) {
+		fake.Advance(time.Minute)
+		notify(1, ocpp.ChargePointStatusAvailable)
+
+		forced, err := fallback.CheckOnce(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, forced)
+		assert.Equal(t, ocpp.ChargePointStatusAvailable, status(1))
+		assert.Equal(t, ocpp.ChargePointStatusFinishing, status(2))
+	})
+
+	t.Run("timeout fallback", func(t *testing.T) {
+		// Connector 2 never reports an idle status
+		fake.Advance(time.Minute)
+		forced, err := fallback.CheckOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []ConnectorRef{{ChargerID: "CP-1", ConnectorID: 2}}, forced)
+		assert.Equal(t, ocpp.ChargePointStatusAvailable, status(2))
+
+		// The fallback fires once per stop
+		fake.Advance(time.Hour)
+		forced, err = fallback.CheckOnce(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, forced)
+	})
+
+	t.Run("new session before the timeout", func(t *testing.T) {
+		startCharging(t, repos, 3, 103)
+		_, err := handler.StopTransaction(ctx, "CP-1", ocpp.StopTransactionRequest{
+			TransactionID: 103, MeterStop: 1000, Timestamp: fake.Now(),
+		})
+		require.NoError(t, err)
+
+		txID := 104
+		_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+			TransactionID: &txID, ChargerID: "CP-1", ConnectorID: 3, IDTag: "TAG",
+		})
+		require.NoError(t, err)
+
+		fake.Advance(3 * time.Minute)
+		forced, err := fallback.CheckOnce(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, forced)
+		assert.Equal(t, ocpp.ChargePointStatusCharging, status(3))
+	})
+}
+
+func TestPostStopFallbackDisabled(t *testing.T) {
+	repos := dbtest.NewRepositories(t, clock.Real())
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, clock.Real(), dbtest.Logger())
+	assert.Nil(t, handler.postStop)
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// Provisioner pushes the configured provisioning profile to chargers after
+// boot, once per profile version
+type Provisioner struct {
+	profiles []config.ProvisioningProfile
+	repos    db.RepositoryManager
+	sender   ocpp.Sender
+	logger   *slog.Logger
+}
+
+// NewProvisioner creates a provisioner for the configured profiles
+func NewProvisioner(cfg config.ProvisioningConfig, repos db.RepositoryManager, sender ocpp.Sender, logger *slog.Logger) *Provisioner {
+	return &Provisioner{
+		profiles: cfg.Profiles,
+		repos:    repos,
+		sender:   sender,
+		logger:   logger,
+	}
+}
+
+// Enabled reports whether any provisioning profiles are configured
+func (p *Provisioner) Enabled() bool {
+	return len(p.profiles) > 0
+}
+
+// Match returns the first profile for a charger vendor and model, or nil
+func (p *Provisioner) Match(vendor, model string) *config.ProvisioningProfile {
+	for i := range p.profiles {
+		profile := &p.profiles[i]
+		if (profile.Vendor == "" || profile.Vendor == vendor) && (profile.Model == "" || profile.Model == model) {
+			return profile
+		}
+	}
+	return nil
+}
+
+// Provision pushes the charger's matching profile unless that version was
+// already pushed. A charger that rejects a key keeps its own setting; the
+// profile is recorded once every key was delivered, so only failures to reach
+// the charger push it again on the next boot.
+func (p *Provisioner) Provision(ctx context.Context, chargerID string) error {
+	charger, err := p.repos.Chargers().GetByID(ctx, chargerID)
+	if err != nil {
+		return fmt.Errorf("failed to get charger: %w", err)
+	}
+
+	profile := p.Match(charger.Vendor, charger.Model)
+	if profile == nil {
+		return nil
+	}
+
+	applied, err := p.repos.Provisioning().Get(ctx, chargerID)
+	if err != nil {
+		return err
+	}
+	if applied != nil && applied.Profile == profile.Name && applied.Version == profile.Version {
+		p.logger.Debug("Provisioning profile already applied",
+			slog.String("charger_id", chargerID),
+			slog.String("profile", profile.Name),
+			slog.Int("version", profile.Version))
+		return nil
+	}
+
+	if p.sender == nil {
+		return ocpp.ErrNotConnected
+	}
+
+	for _, setting := range profile.Configuration {
+		var resp ocpp.ChangeConfigurationResponse
+		req := ocpp.ChangeConfigurationRequest{Key: setting.Key, Value: setting.Value}
+		if err := p.sender.SendCall(ctx, chargerID, ocpp.ActionChangeConfiguration, req, &resp); err != nil {
+			return fmt.Errorf("failed to change %s: %w", setting.Key, err)
+		}
+
+		if resp.Status != ocpp.ConfigurationStatusAccepted {
+			p.logger.Warn("Charger did not accept provisioning setting",
+				slog.String("charger_id", chargerID),
+				slog.String("profile", profile.Name),
+				slog.String("key", setting.Key),
+				slog.String("status", resp.Status))
+		}
+	}
+
+	if err := p.repos.Provisioning().Record(ctx, chargerID, profile.Name, profile.Version); err != nil {
+		return err
+	}
+
+	p.logger.Info("Provisioning profile applied",
+		slog.String("charger_id", chargerID),
+		slog.String("profile", profile.Name),
+		slog.Int("version", profile.Version))
+	return nil
+}
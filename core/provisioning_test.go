@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func provisioningTestProfiles() []config.ProvisioningProfile {
+	return []config.ProvisioningProfile{
+		{
+			Name:    "acme-x1",
+			Version: 1,
+			Vendor:  "Acme",
+			Model:   "X1",
+			Configuration: []config.ProvisioningSetting{
+				{Key: "HeartbeatInterval", Value: "300"},
+				{Key: "LocalPreAuthorize", Value: "true"},
+			},
+		},
+		{
+			Name:          "default",
+			Version:       1,
+			Configuration: []config.ProvisioningSetting{{Key: "ConnectionTimeOut", Value: "60"}},
+		},
+	}
+}
+
+func TestProvisioningMatchesVendorAndModel(t *testing.T) {
+	provisioner := NewProvisioner(config.ProvisioningConfig{Profiles: provisioningTestProfiles()}, nil, nil, dbtest.Logger())
+	assert.True(t, provisioner.Enabled())
+	assert.Equal(t, "acme-x1", provisioner.Match("Acme", "X1").Name)
+	assert.Equal(t, "default", provisioner.Match("Acme", "X2").Name)
+	assert.Equal(t, "default", provisioner.Match("Other", "X1").Name)
+
+	assert.Nil(t, NewProvisioner(config.ProvisioningConfig{}, nil, nil, dbtest.Logger()).Match("Acme", "X1"))
+}
+
+// provisioningTestSystem returns a system that runs the work after boot but
+// none of the background jobs Start would add, so tests can wait on the
+// WaitGroup for just the boot work
+func provisioningTestSystem(cfg *config.Config, repos db.RepositoryManager, fake *clock.Fake, sender ocpp.Sender) *System {
+	system := &System{
+		config: cfg,
+		logger: dbtest.Logger(),
+		clock:  fake,
+		repos:  repos,
+		ocpp:   NewOCPPHandler(cfg.OCPP, repos, fake, dbtest.Logger()),
+	}
+	system.ctx, system.cancel = context.WithCancel(context.Background())
+	system.ocpp.OnBoot(system.afterBoot)
+	system.SetCommandSender(sender)
+	return system
+}
+
+func TestBootPushesProvisioningProfileOnce(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 10, 5, 9, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	cfg := &config.Config{OCPP: config.OCPPConfig{HeartbeatInterval: time.Minute}}
+	cfg.Provisioning.Profiles = provisioningTestProfiles()
+
+	sender := &fakeSender{responses: map[string]interface{}{
+		ocpp.ActionChangeConfiguration: ocpp.ChangeConfigurationResponse{Status: ocpp.ConfigurationStatusAccepted},
+	}}
+	system := provisioningTestSystem(cfg, repos, fake, sender)
+	defer system.cancel()
+
+	boot := func() {
+		_, err := system.OCPP().BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{
+			ChargePointVendor: "Acme",
+			ChargePointModel:  "X1",
+		})
+		require.NoError(t, err)
+		system.wg.Wait()
+	}
+
+	// The first boot pushes the matching profile
+	boot()
+	require.Len(t, sender.calls, 2)
+	assert.JSONEq(t, `{"key": "HeartbeatInterval", "value": "300"}`, string(sender.calls[0].Payload))
+	assert.JSONEq(t, `{"key": "LocalPreAuthorize", "value": "true"}`, string(sender.calls[1].Payload))
+
+	applied, err := repos.Provisioning().Get(ctx, "CP-1")
+	require.NoError(t, err)
+	require.NotNil(t, applied)
+	assert.Equal(t, "acme-x1", applied.Profile)
+	assert.Equal(t, 1, applied.Version)
+	assert.True(t, fake.Now().Equal(applied.AppliedAt))
+
+	// A later boot skips the profile already applied
+	boot()
+	assert.Len(t, sender.calls, 2)
+
+	// A new profile version is pushed again
+	cfg.Provisioning.Profiles[0].Version = 2
+	boot()
+	assert.Len(t, sender.calls, 4)
+
+	applied, err = repos.Provisioning().Get(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied.Version)
+}
+
+func TestProvisioningNotRecordedWhenUndelivered(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 10, 5, 9, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	handler := NewOCPPHandler(config.OCPPConfig{HeartbeatInterval: time.Minute}, repos, fake, dbtest.Logger())
+	_, err := handler.BootNotification(ctx, "CP-1", ocpp.BootNotificationRequest{ChargePointVendor: "Acme", ChargePointModel: "X1"})
+	require.NoError(t, err)
+
+	cfg := config.ProvisioningConfig{Profiles: provisioningTestProfiles()}
+	provisioner := NewProvisioner(cfg, repos, &fakeSender{err: ocpp.ErrNotConnected}, dbtest.Logger())
+	require.ErrorIs(t, provisioner.Provision(ctx, "CP-1"), ocpp.ErrNotConnected)
+
+	applied, err := repos.Provisioning().Get(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Nil(t, applied)
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// ReconciliationResult summarizes a reconciliation run
+type ReconciliationResult struct {
+	Backfilled   int
+	Flagged      int
+	Unassociated int
+}
+
+// ReconciliationJob links meter values that arrived without a transaction id
+// to the active transaction on their connector, and flags the ones that
+// cannot be linked once the grace period has passed
+type ReconciliationJob struct {
+	config  config.ReconciliationConfig
+	repos   db.RepositoryManager
+	metrics *monitoring.Metrics
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+// NewReconciliationJob creates a new reconciliation job. metrics may be nil.
+func NewReconciliationJob(cfg config.ReconciliationConfig, repos db.RepositoryManager, metrics *monitoring.Metrics, clk clock.Clock, logger *slog.Logger) *ReconciliationJob {
+	return &ReconciliationJob{
+		config:  cfg,
+		repos:   repos,
+		metrics: metrics,
+		clock:   clk,
+		logger:  logger,
+	}
+}
+
+// RunOnce backfills transaction ids, flags unassociated meter values and
+// updates the unassociated meter values metric
+func (j *ReconciliationJob) RunOnce(ctx context.Context) (ReconciliationResult, error) {
+	var result ReconciliationResult
+
+	active, err := j.repos.Transactions().GetActive(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to get active transactions: %w", err)
+	}
+
+	for _, tx := range active {
+		backfilled, err := j.repos.MeterValues().AssignTransaction(ctx, tx.ChargerID, tx.ConnectorID, tx.ID, tx.StartTime)
+		if err != nil {
+			return result, fmt.Errorf("failed to backfill meter values for transaction %d: %w", tx.ID, err)
+		}
+		if backfilled > 0 {
+			j.logger.Info("Backfilled meter values for transaction",
+				slog.Int("transaction_id", tx.ID),
+				slog.String("charger_id", tx.ChargerID),
+				slog.Int("connector_id", tx.ConnectorID),
+				slog.Int("count", backfilled))
+		}
+		result.Backfilled += backfilled
+	}
+
+	flagged, err := j.repos.MeterValues().FlagUnassociated(ctx, j.clock.Now().Add(-j.config.GracePeriod))
+	if err != nil {
+		return result, fmt.Errorf("failed to flag unassociated meter values: %w", err)
+	}
+	result.Flagged = flagged
+
+	unassociated, err := j.repos.MeterValues().CountUnassociated(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to count unassociated meter values: %w", err)
+	}
+	result.Unassociated = unassociated
+
+	if j.metrics != nil {
+		j.metrics.SetMeterValuesUnassociated(float64(unassociated))
+	}
+
+	j.logger.Info("Reconciliation run completed",
+		slog.Int("backfilled", result.Backfilled),
+		slog.Int("flagged", result.Flagged),
+		slog.Int("unassociated", result.Unassociated))
+
+	return result, nil
+}
+
+// Run executes the reconciliation job on the configured interval until the context is cancelled
+func (j *ReconciliationJob) Run(ctx context.Context) {
+	interval := j.config.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := j.RunOnce(ctx); err != nil {
+				j.logger.Error("Reconciliation run failed", slog.Any("error", err))
+			}
+		}
+	}
+}
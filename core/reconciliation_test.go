@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciliationJob(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Now().UTC())
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		ChargerID:   "CP-1",
+		ConnectorID: 1,
+		IDTag:       "TAG-1",
+	})
+	require.NoError(t, err)
+
+	createMeterValue := func(connectorID int, at time.Time) *db.MeterValue {
+		mv, err := repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			ChargerID:   "CP-1",
+			ConnectorID: connectorID,
+			Timestamp:   at.UTC(),
+			Measurand:   "Energy.Active.Import.Register",
+			Value:       dbtest.Float64(1200),
+		})
+		require.NoError(t, err)
+		return mv
+	}
+
+	// Taken during the active transaction on connector 1
+	during := createMeterValue(1, tx.StartTime.Add(time.Minute))
+	// Taken on connector 1 before the transaction started
+	before := createMeterValue(1, tx.StartTime.Add(-time.Hour))
+	// Taken on connector 2, which has no transaction
+	idle := createMeterValue(2, fake.Now().Add(-time.Hour))
+	// Recent reading on connector 2, still within the grace period
+	recent := createMeterValue(2, fake.Now())
+
+	job := NewReconciliationJob(config.ReconciliationConfig{GracePeriod: 15 * time.Minute}, repos, nil, fake, dbtest.Logger())
+
+	result, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ReconciliationResult{Backfilled: 1, Flagged: 2, Unassociated: 2}, result)
+
+	mv, err := repos.MeterValues().GetByID(ctx, during.ID)
+	require.NoError(t, err)
+	require.NotNil(t, mv.TransactionID)
+	assert.Equal(t, tx.ID, *mv.TransactionID)
+
+	for _, id := range []int{before.ID, idle.ID, recent.ID} {
+		mv, err := repos.MeterValues().GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Nil(t, mv.TransactionID)
+	}
+
+	// A second run is a no-op until the recent reading ages past the grace period
+	result, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ReconciliationResult{Unassociated: 2}, result)
+
+	fake.Advance(time.Hour)
+	result, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ReconciliationResult{Flagged: 1, Unassociated: 3}, result)
+}
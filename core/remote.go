@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// ErrInvalidRemoteStart is returned when a remote start fails validation
+var ErrInvalidRemoteStart = errors.New("invalid remote start")
+
+// ErrInvalidReset is returned when a reset has an unknown type
+var ErrInvalidReset = errors.New("invalid reset")
+
+// ErrTransactionNotActive is returned when stopping a transaction that has
+// already ended
+var ErrTransactionNotActive = errors.New("transaction not active")
+
+// maxIDTagLength is the longest idTag OCPP 1.6 allows (IdToken is CiString20)
+const maxIDTagLength = 20
+
+// RemoteControl starts and stops transactions on chargers and resets them on
+// an operator's behalf
+type RemoteControl struct {
+	sender ocpp.Sender
+	// timeout bounds how long the charger has to answer; 0 waits for the caller's context
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+// NewRemoteControl creates a new remote control
+func NewRemoteControl(sender ocpp.Sender, timeout time.Duration, logger *slog.Logger) *RemoteControl {
+	return &RemoteControl{
+		sender:  sender,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// ValidateRemoteStart checks a remote start before it is sent
+func ValidateRemoteStart(idTag string, connectorID *int) error {
+	if idTag == "" || len(idTag) > maxIDTagLength {
+		return fmt.Errorf("%w: id tag must be 1 to %d characters", ErrInvalidRemoteStart, maxIDTagLength)
+	}
+	if connectorID != nil && *connectorID <= 0 {
+		return fmt.Errorf("%w: connector id must be positive", ErrInvalidRemoteStart)
+	}
+	return nil
+}
+
+// RemoteStart asks a charger to start a transaction for an ID tag and returns
+// the charger's response status. Accepted only means the charger will try;
+// the transaction itself arrives as a StartTransaction. A charger that does
+// not answer within the timeout fails with context.DeadlineExceeded.
+func (r *RemoteControl) RemoteStart(ctx context.Context, chargerID, idTag string, connectorID *int) (string, error) {
+	if err := ValidateRemoteStart(idTag, connectorID); err != nil {
+		return "", err
+	}
+
+	var resp ocpp.RemoteStartTransactionResponse
+	req := ocpp.RemoteStartTransactionRequest{ConnectorID: connectorID, IDTag: idTag}
+	if err := r.send(ctx, chargerID, ocpp.ActionRemoteStartTransaction, req, &resp); err != nil {
+		return "", fmt.Errorf("failed to remote start: %w", err)
+	}
+
+	r.logger.Info("Remote start answered",
+		slog.String("charger_id", chargerID),
+		slog.String("id_tag", idTag),
+		slog.String("status", resp.Status))
+	return resp.Status, nil
+}
+
+// RemoteStop asks the charger of an active transaction to stop it and returns
+// the charger's response status. As with RemoteStart, the transaction only
+// ends when the charger sends StopTransaction.
+func (r *RemoteControl) RemoteStop(ctx context.Context, tx *db.Transaction) (string, error) {
+	if tx.Status != db.TransactionStatusActive || tx.TransactionID == nil {
+		return "", fmt.Errorf("%w: transaction %d is %s", ErrTransactionNotActive, tx.ID, tx.Status)
+	}
+
+	var resp ocpp.RemoteStopTransactionResponse
+	req := ocpp.RemoteStopTransactionRequest{TransactionID: *tx.TransactionID}
+	if err := r.send(ctx, tx.ChargerID, ocpp.ActionRemoteStopTransaction, req, &resp); err != nil {
+		return "", fmt.Errorf("failed to remote stop: %w", err)
+	}
+
+	r.logger.Info("Remote stop answered",
+		slog.String("charger_id", tx.ChargerID),
+		slog.Int("transaction_id", *tx.TransactionID),
+		slog.String("status", resp.Status))
+	return resp.Status, nil
+}
+
+// ValidateReset checks the type of a reset
+func ValidateReset(resetType string) error {
+	if resetType != ocpp.ResetTypeSoft && resetType != ocpp.ResetTypeHard {
+		return fmt.Errorf("%w: unknown reset type %q", ErrInvalidReset, resetType)
+	}
+	return nil
+}
+
+// Reset asks a charger to reset and returns its response status. It returns
+// as soon as the charger answers: a charger accepting a Hard reset drops its
+// connection afterwards, which the connection's read loop handles like any
+// other disconnect.
+func (r *RemoteControl) Reset(ctx context.Context, chargerID, resetType string) (string, error) {
+	if err := ValidateReset(resetType); err != nil {
+		return "", err
+	}
+
+	var resp ocpp.ResetResponse
+	if err := r.send(ctx, chargerID, ocpp.ActionReset, ocpp.ResetRequest{Type: resetType}, &resp); err != nil {
+		return "", fmt.Errorf("failed to reset: %w", err)
+	}
+
+	r.logger.Info("Reset answered",
+		slog.String("charger_id", chargerID),
+		slog.String("type", resetType),
+		slog.String("status", resp.Status))
+	return resp.Status, nil
+}
+
+// send makes a call to a charger and waits for its answer
+func (r *RemoteControl) send(ctx context.Context, chargerID, action string, request, response interface{}) error {
+	return sendWithTimeout(ctx, r.sender, r.timeout, chargerID, action, request, response)
+}
+
+// sendWithTimeout makes a call to a charger and waits at most timeout for its
+// answer when timeout is positive. A nil sender means no charger can be reached.
+func sendWithTimeout(ctx context.Context, sender ocpp.Sender, timeout time.Duration, chargerID, action string, request, response interface{}) error {
+	if sender == nil {
+		return ocpp.ErrNotConnected
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return sender.SendCall(ctx, chargerID, action, request, response)
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+)
+
+// RetentionResult summarizes the rows removed by a retention run
+type RetentionResult struct {
+	MeterValuesDeleted int
+	// OrphansDeleted counts meter values whose transaction no longer exists
+	OrphansDeleted     int
+	ErrorsDeleted      int
+	ConnectionsDeleted int
+}
+
+// RetentionJob periodically prunes old meter values, resolved errors and
+// closed connection records, and meter values of transactions that no longer
+// exist whatever their age.
+// Measurands listed in MeasurandAges follow their own age instead of
+// MeterValuesAge, and a zero age keeps them indefinitely.
+type RetentionJob struct {
+	config config.RetentionConfig
+	repos  db.RepositoryManager
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewRetentionJob creates a new retention job
+func NewRetentionJob(cfg config.RetentionConfig, repos db.RepositoryManager, clk clock.Clock, logger *slog.Logger) *RetentionJob {
+	return &RetentionJob{
+		config: cfg,
+		repos:  repos,
+		clock:  clk,
+		logger: logger,
+	}
+}
+
+// RunOnce deletes all data older than the configured retention ages
+func (j *RetentionJob) RunOnce(ctx context.Context) (RetentionResult, error) {
+	var result RetentionResult
+	now := j.clock.Now()
+
+	// Measurands with their own age are pruned separately and left out of the global sweep
+	measurands := make([]string, 0, len(j.config.MeasurandAges))
+	for measurand := range j.config.MeasurandAges {
+		measurands = append(measurands, measurand)
+	}
+	sort.Strings(measurands)
+
+	for _, measurand := range measurands {
+		age := j.config.MeasurandAges[measurand]
+		if age <= 0 {
+			continue
+		}
+		deleted, err := j.repos.MeterValues().DeleteMeasurandOlderThan(ctx, measurand, now.Add(-age))
+		if err != nil {
+			return result, fmt.Errorf("failed to prune %s meter values: %w", measurand, err)
+		}
+		result.MeterValuesDeleted += deleted
+	}
+
+	if j.config.MeterValuesAge > 0 {
+		deleted, err := j.repos.MeterValues().DeleteOlderThanExcept(ctx, now.Add(-j.config.MeterValuesAge), measurands)
+		if err != nil {
+			return result, fmt.Errorf("failed to prune meter values: %w", err)
+		}
+		result.MeterValuesDeleted += deleted
+	}
+
+	orphans, err := j.repos.MeterValues().DeleteOrphaned(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to prune orphaned meter values: %w", err)
+	}
+	result.OrphansDeleted = orphans
+
+	if j.config.ErrorsAge > 0 {
+		deleted, err := j.repos.Errors().DeleteOldResolved(ctx, now.Add(-j.config.ErrorsAge))
+		if err != nil {
+			return result, fmt.Errorf("failed to prune resolved errors: %w", err)
+		}
+		result.ErrorsDeleted = deleted
+	}
+
+	if j.config.ConnectionsAge > 0 {
+		deleted, err := j.repos.Connections().DeleteOlderThan(ctx, now.Add(-j.config.ConnectionsAge))
+		if err != nil {
+			return result, fmt.Errorf("failed to prune connections: %w", err)
+		}
+		result.ConnectionsDeleted = deleted
+	}
+
+	j.logger.Info("Retention run completed",
+		slog.Int("meter_values_deleted", result.MeterValuesDeleted),
+		slog.Int("orphans_deleted", result.OrphansDeleted),
+		slog.Int("errors_deleted", result.ErrorsDeleted),
+		slog.Int("connections_deleted", result.ConnectionsDeleted))
+
+	return result, nil
+}
+
+// Run executes the retention job on the configured interval until the context is cancelled
+func (j *RetentionJob) Run(ctx context.Context) {
+	interval := j.config.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := j.RunOnce(ctx); err != nil {
+				j.logger.Error("Retention run failed", slog.Any("error", err))
+			}
+		}
+	}
+}
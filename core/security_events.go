@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/keeth/levity/core/events"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// TopicSecurityAlert is published for critical security events
+const TopicSecurityAlert = "security.alert"
+
+// SeverityHigh is the severity of a security alert
+const SeverityHigh = "high"
+
+// criticalSecurityEvents are the event types the OCPP 1.6 Security Whitepaper
+// marks as critical
+var criticalSecurityEvents = map[string]bool{
+	ocpp.SecurityEventFirmwareUpdated:          true,
+	ocpp.SecurityEventSettingSystemTime:        true,
+	ocpp.SecurityEventStartupOfTheDevice:       true,
+	ocpp.SecurityEventResetOrReboot:            true,
+	ocpp.SecurityEventSecurityLogWasCleared:    true,
+	ocpp.SecurityEventMemoryExhaustion:         true,
+	ocpp.SecurityEventTamperDetectionActivated: true,
+}
+
+// IsCriticalSecurityEvent reports whether a security event type is critical
+func IsCriticalSecurityEvent(eventType string) bool {
+	return criticalSecurityEvents[eventType]
+}
+
+// SecurityAlert is the payload of a security.alert event
+type SecurityAlert struct {
+	EventID   int       `json:"event_id"`
+	ChargerID string    `json:"charger_id"`
+	Type      string    `json:"type"`
+	Severity  string    `json:"severity"`
+	TechInfo  string    `json:"tech_info,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SecurityEventNotification records a security event. A critical event also
+// raises a security alert through the outbox, committed with the event.
+func (h *OCPPHandler) SecurityEventNotification(ctx context.Context, chargerID string, req ocpp.SecurityEventNotificationRequest) (*ocpp.SecurityEventNotificationResponse, error) {
+	timestamp := req.Timestamp
+	if timestamp.IsZero() {
+		timestamp = h.clock.Now()
+	}
+	critical := IsCriticalSecurityEvent(req.Type)
+
+	err := WithTx(ctx, h.repos, h.metrics, "security_event", func(tx db.TxManager) error {
+		event, err := tx.SecurityEvents().Create(ctx, db.CreateSecurityEventRequest{
+			ChargerID: chargerID,
+			Type:      req.Type,
+			TechInfo:  req.TechInfo,
+			Critical:  critical,
+			Timestamp: timestamp,
+		})
+		if err != nil {
+			return err
+		}
+		if !critical {
+			return nil
+		}
+
+		return events.Write(ctx, tx.Outbox(), TopicSecurityAlert, SecurityAlert{
+			EventID:   event.ID,
+			ChargerID: chargerID,
+			Type:      req.Type,
+			Severity:  SeverityHigh,
+			TechInfo:  req.TechInfo,
+			Timestamp: event.Timestamp,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record security event: %w", err)
+	}
+
+	level := slog.LevelInfo
+	if critical {
+		level = slog.LevelWarn
+	}
+	h.logger.Log(ctx, level, "Security event reported",
+		slog.String("charger_id", chargerID),
+		slog.String("type", req.Type),
+		slog.Bool("critical", critical),
+		slog.String("tech_info", req.TechInfo))
+
+	return &ocpp.SecurityEventNotificationResponse{}, nil
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/events"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityEventNotificationPersists(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	reported := fake.Now().Add(-time.Minute)
+	_, err = handler.SecurityEventNotification(ctx, "CP-1", ocpp.SecurityEventNotificationRequest{
+		Type:      ocpp.SecurityEventReconfigurationOfSecurityParameters,
+		Timestamp: reported,
+		TechInfo:  "SecurityProfile=3",
+	})
+	require.NoError(t, err)
+
+	securityEvents, err := repos.SecurityEvents().List(ctx, db.SecurityEventFilter{ChargerID: "CP-1"})
+	require.NoError(t, err)
+	require.Len(t, securityEvents, 1)
+	assert.Equal(t, ocpp.SecurityEventReconfigurationOfSecurityParameters, securityEvents[0].Type)
+	assert.Equal(t, "SecurityProfile=3", securityEvents[0].TechInfo)
+	assert.False(t, securityEvents[0].Critical)
+	assert.True(t, reported.Equal(securityEvents[0].Timestamp))
+
+	// Non-critical events raise no alert
+	pending, err := repos.Outbox().GetPending(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestCriticalSecurityEventRaisesAlert(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	bus := events.NewBus()
+	var alerts []SecurityAlert
+	bus.Subscribe(TopicSecurityAlert, func(ctx context.Context, event events.Event) error {
+		var alert SecurityAlert
+		if err := event.Decode(&alert); err != nil {
+			return err
+		}
+		alerts = append(alerts, alert)
+		return nil
+	})
+
+	handler := NewOCPPHandler(config.OCPPConfig{}, repos, fake, dbtest.Logger())
+	for _, eventType := range []string{ocpp.SecurityEventTamperDetectionActivated, ocpp.SecurityEventInvalidMessages, ocpp.SecurityEventFirmwareUpdated} {
+		_, err := handler.SecurityEventNotification(ctx, "CP-1", ocpp.SecurityEventNotificationRequest{
+			Type:      eventType,
+			Timestamp: fake.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	relay := events.NewRelay(repos.Outbox(), bus, time.Second, fake, dbtest.Logger())
+	published, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	require.Len(t, alerts, 2)
+	assert.Equal(t, ocpp.SecurityEventTamperDetectionActivated, alerts[0].Type)
+	assert.Equal(t, ocpp.SecurityEventFirmwareUpdated, alerts[1].Type)
+	for _, alert := range alerts {
+		assert.Equal(t, "CP-1", alert.ChargerID)
+		assert.Equal(t, SeverityHigh, alert.Severity)
+	}
+
+	critical := true
+	securityEvents, err := repos.SecurityEvents().List(ctx, db.SecurityEventFilter{Critical: &critical})
+	require.NoError(t, err)
+	assert.Len(t, securityEvents, 2)
+}
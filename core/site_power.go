@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+)
+
+// Measurands summed into a site's draw
+const (
+	MeasurandPowerActiveImport = "Power.Active.Import"
+	MeasurandCurrentImport     = "Current.Import"
+)
+
+// ChargerPower is what one charger of a site is drawing
+type ChargerPower struct {
+	ChargerID string  `json:"charger_id"`
+	Connected bool    `json:"connected"`
+	Power     float64 `json:"power"`
+	Current   float64 `json:"current"`
+}
+
+// SitePower is the aggregate draw of a site's chargers against its limit.
+// Power is in W and currents in A; Headroom is nil for an unlimited site and
+// negative when the site is over its limit.
+type SitePower struct {
+	SiteID     string         `json:"site_id"`
+	MaxCurrent float64        `json:"max_current"`
+	Power      float64        `json:"power"`
+	Current    float64        `json:"current"`
+	Headroom   *float64       `json:"headroom"`
+	Chargers   []ChargerPower `json:"chargers"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+// SitePowerReader sums the latest power and current readings of the chargers
+// in a site
+type SitePowerReader struct {
+	repos    db.RepositoryManager
+	registry ConnectionRegistry
+	clock    clock.Clock
+}
+
+// NewSitePowerReader creates a new site power reader. Without a registry,
+// the stored is_connected flag decides whether a charger is connected.
+func NewSitePowerReader(repos db.RepositoryManager, registry ConnectionRegistry, clk clock.Clock) *SitePowerReader {
+	return &SitePowerReader{
+		repos:    repos,
+		registry: registry,
+		clock:    clk,
+	}
+}
+
+// Read returns the draw of a site. Only connectors of connected chargers with
+// an active transaction draw power; each contributes its latest phase-less
+// Power.Active.Import and Current.Import reading.
+func (r *SitePowerReader) Read(ctx context.Context, site *db.Site) (*SitePower, error) {
+	chargerIDs, err := r.repos.Sites().ChargerIDs(ctx, site.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := r.clock.Now().UTC()
+	result := &SitePower{
+		SiteID:     site.ID,
+		MaxCurrent: site.MaxCurrent,
+		Chargers:   make([]ChargerPower, 0, len(chargerIDs)),
+		Timestamp:  now,
+	}
+
+	for _, chargerID := range chargerIDs {
+		charger, err := r.repos.Chargers().GetByID(ctx, chargerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get charger: %w", err)
+		}
+
+		draw := ChargerPower{ChargerID: chargerID, Connected: charger.IsConnected}
+		if r.registry != nil {
+			draw.Connected = r.registry.IsConnected(chargerID)
+		}
+		if draw.Connected {
+			if err := r.readCharger(ctx, &draw, now); err != nil {
+				return nil, err
+			}
+		}
+
+		result.Power += draw.Power
+		result.Current += draw.Current
+		result.Chargers = append(result.Chargers, draw)
+	}
+
+	if site.MaxCurrent > 0 {
+		headroom := site.MaxCurrent - result.Current
+		result.Headroom = &headroom
+	}
+	return result, nil
+}
+
+// readCharger sums the latest readings of a charger's charging connectors
+func (r *SitePowerReader) readCharger(ctx context.Context, draw *ChargerPower, at time.Time) error {
+	connectors, err := r.repos.Connectors().GetByChargerID(ctx, draw.ChargerID)
+	if err != nil {
+		return fmt.Errorf("failed to get connectors: %w", err)
+	}
+
+	meterValues := r.repos.MeterValues()
+	for _, connector := range connectors {
+		tx, err := r.repos.Transactions().GetActiveByConnector(ctx, draw.ChargerID, connector.ConnectorID)
+		if err != nil {
+			return fmt.Errorf("failed to get active transaction: %w", err)
+		}
+		if tx == nil {
+			continue
+		}
+
+		power, err := meterValues.GetLatestMeasurandByConnector(ctx, draw.ChargerID, connector.ConnectorID, MeasurandPowerActiveImport, at)
+		if err != nil {
+			return err
+		}
+		if power != nil {
+			draw.Power += powerInWatts(power.Value, power.Unit)
+		}
+
+		current, err := meterValues.GetLatestMeasurandByConnector(ctx, draw.ChargerID, connector.ConnectorID, MeasurandCurrentImport, at)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			draw.Current += current.Value
+		}
+	}
+	return nil
+}
+
+// powerInWatts converts a power reading to W; OCPP defaults power to W
+func powerInWatts(value float64, unit string) float64 {
+	if unit == "kW" {
+		return value * 1000
+	}
+	return value
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+)
+
+// StaleChargerMonitor marks connected chargers as disconnected once they
+// have not been heard from within the stale timeout
+type StaleChargerMonitor struct {
+	timeout  time.Duration
+	interval time.Duration
+	repos    db.RepositoryManager
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewStaleChargerMonitor creates a new stale charger monitor
+func NewStaleChargerMonitor(timeout, interval time.Duration, repos db.RepositoryManager, clk clock.Clock, logger *slog.Logger) *StaleChargerMonitor {
+	return &StaleChargerMonitor{
+		timeout:  timeout,
+		interval: interval,
+		repos:    repos,
+		clock:    clk,
+		logger:   logger,
+	}
+}
+
+// CheckOnce marks stale chargers as disconnected and returns their IDs
+func (m *StaleChargerMonitor) CheckOnce(ctx context.Context) ([]string, error) {
+	chargers, err := m.repos.Chargers().GetConnected(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connected chargers: %w", err)
+	}
+
+	now := m.clock.Now()
+	var stale []string
+	for _, charger := range chargers {
+		lastSeen := lastSeenAt(charger)
+		if lastSeen != nil && now.Sub(*lastSeen) <= m.timeout {
+			continue
+		}
+
+		if err := m.repos.Chargers().UpdateConnectionStatus(ctx, charger.ID, false); err != nil {
+			m.logger.Error("Failed to mark stale charger disconnected",
+				slog.String("charger_id", charger.ID),
+				slog.Any("error", err))
+			continue
+		}
+
+		m.logger.Warn("Marked stale charger as disconnected", slog.String("charger_id", charger.ID))
+		stale = append(stale, charger.ID)
+	}
+
+	return stale, nil
+}
+
+// Run executes the stale check on the configured interval until the context is cancelled
+func (m *StaleChargerMonitor) Run(ctx context.Context) {
+	interval := m.interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.CheckOnce(ctx); err != nil {
+				m.logger.Error("Stale charger check failed", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// lastSeenAt returns the most recent heartbeat or connect time of a charger
+func lastSeenAt(charger *db.Charger) *time.Time {
+	lastSeen := charger.LastHeartbeatAt
+	if charger.LastConnectAt != nil && (lastSeen == nil || charger.LastConnectAt.After(*lastSeen)) {
+		lastSeen = charger.LastConnectAt
+	}
+	return lastSeen
+}
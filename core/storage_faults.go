@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/keeth/levity/core/events"
+	"github.com/keeth/levity/db"
+)
+
+// TopicStorageFault is published when the database storage starts failing
+const TopicStorageFault = "system.storage_fault"
+
+// StorageFaultAlert is the payload of a system.storage_fault event
+type StorageFaultAlert struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Error    string `json:"error"`
+}
+
+// StorageFault implements db.StorageFaultObserver. The database is marked
+// unhealthy, failing readiness, and the first fault while it was healthy is
+// published as a high-severity event. The event goes straight to the bus
+// since the outbox lives in the failing database.
+func (s *System) StorageFault(fault *db.StorageFaultError) {
+	s.mu.Lock()
+	first := s.storageFault == nil
+	s.storageFault = fault
+	s.healthyDB = false
+	s.mu.Unlock()
+
+	if s.metrics != nil {
+		s.metrics.RecordDatabaseStorageFault(fault.Kind)
+	}
+	if !first {
+		return
+	}
+
+	s.logger.Error("Database storage fault, marking database unhealthy",
+		slog.String("kind", fault.Kind),
+		slog.Any("error", fault.Err))
+	if fault.Kind == db.StorageFaultCorrupt {
+		s.logger.Error("Database may be corrupt: stop the server and run PRAGMA integrity_check against it, restoring from a backup if it reports errors",
+			slog.String("path", s.config.Database.Path))
+	}
+
+	s.publishStorageFault(fault)
+}
+
+// StorageRecovered implements db.StorageFaultObserver, marking the database
+// healthy again once a write succeeds after a full disk or I/O error.
+// Corruption is kept until the server restarts.
+func (s *System) StorageRecovered() {
+	s.mu.Lock()
+	if s.storageFault == nil || s.storageFault.Kind == db.StorageFaultCorrupt {
+		s.mu.Unlock()
+		return
+	}
+	s.storageFault = nil
+	s.healthyDB = true
+	s.mu.Unlock()
+
+	s.logger.Info("Database storage recovered, marking database healthy")
+}
+
+// LastStorageFault returns the storage fault that made the database unhealthy,
+// or nil when there is none
+func (s *System) LastStorageFault() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.storageFault == nil {
+		return nil
+	}
+	return s.storageFault
+}
+
+// publishStorageFault publishes a storage fault alert to the event bus
+func (s *System) publishStorageFault(fault *db.StorageFaultError) {
+	if s.bus == nil {
+		return
+	}
+
+	payload, err := json.Marshal(StorageFaultAlert{
+		Kind:     fault.Kind,
+		Severity: SeverityHigh,
+		Error:    fault.Err.Error(),
+	})
+	if err != nil {
+		s.logger.Error("Failed to encode storage fault alert", slog.Any("error", err))
+		return
+	}
+
+	event := events.Event{Topic: TopicStorageFault, Payload: payload, OccurredAt: s.clock.Now().UTC()}
+	if err := s.bus.Publish(context.Background(), event); err != nil {
+		s.logger.Warn("Storage fault alert handlers failed", slog.Any("error", err))
+	}
+}
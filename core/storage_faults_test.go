@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/events"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	sqlitedriver "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStorageFaultTestSystem returns a healthy system observing the storage
+// faults of its database, and the events it publishes
+func newStorageFaultTestSystem(t *testing.T) (*System, *[]events.Event) {
+	t.Helper()
+	fake := clock.NewFake(time.Date(2024, 11, 3, 8, 0, 0, 0, time.UTC))
+	database := dbtest.NewDatabase(t)
+	repos := db.NewRepositoryManager(database, &slogAdapter{logger: dbtest.Logger()}, fake)
+
+	cfg := &config.Config{OCPP: config.OCPPConfig{HeartbeatInterval: time.Minute}}
+	system := &System{
+		config:    cfg,
+		logger:    dbtest.Logger(),
+		clock:     fake,
+		db:        database,
+		repos:     repos,
+		bus:       events.NewBus(),
+		healthyDB: true,
+		ocpp:      NewOCPPHandler(cfg.OCPP, repos, fake, dbtest.Logger()),
+	}
+	system.SetMetrics(monitoring.NewMetrics())
+	database.SetStorageFaultObserver(system)
+
+	var published []events.Event
+	system.bus.Subscribe(events.AllTopics, func(ctx context.Context, event events.Event) error {
+		published = append(published, event)
+		return nil
+	})
+	return system, &published
+}
+
+// storageFaultCount returns how many storage faults of a kind were counted
+func storageFaultCount(t *testing.T, metrics *monitoring.Metrics, kind string) float64 {
+	t.Helper()
+	families, err := metrics.Registry().Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "database_storage_faults_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "kind" && label.GetValue() == kind {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestStorageFullMarksDatabaseUnhealthy(t *testing.T) {
+	ctx := context.Background()
+	system, published := newStorageFaultTestSystem(t)
+
+	// Queries keep failing while the disk stays full
+	for i := range 3 {
+		err := system.db.ReportFault(fmt.Errorf("failed to create charger %d: %w", i, sqlitedriver.Error{Code: sqlitedriver.ErrFull}))
+		assert.ErrorIs(t, err, db.ErrStorageFault)
+	}
+
+	assert.False(t, system.IsHealthy())
+	fault := db.AsStorageFault(system.LastStorageFault())
+	require.NotNil(t, fault)
+	assert.Equal(t, db.StorageFaultFull, fault.Kind)
+	assert.Equal(t, 3.0, storageFaultCount(t, system.metrics, db.StorageFaultFull))
+
+	// One alert per outage, however many queries fail
+	require.Len(t, *published, 1)
+	event := (*published)[0]
+	assert.Equal(t, TopicStorageFault, event.Topic)
+	var alert StorageFaultAlert
+	require.NoError(t, event.Decode(&alert))
+	assert.Equal(t, db.StorageFaultFull, alert.Kind)
+	assert.Equal(t, SeverityHigh, alert.Severity)
+
+	// The next write to get through clears the fault
+	_, err := system.HandleCall(ctx, "CP-1", bootCall("boot"))
+	require.NoError(t, err)
+	assert.True(t, system.IsHealthy())
+	assert.NoError(t, system.LastStorageFault())
+}
+
+func TestStorageCorruptionLastsUntilRestart(t *testing.T) {
+	system, published := newStorageFaultTestSystem(t)
+
+	err := system.db.ReportFault(fmt.Errorf("failed to get charger: %w", sqlitedriver.Error{Code: sqlitedriver.ErrCorrupt}))
+	assert.ErrorIs(t, err, db.ErrStorageFault)
+	assert.False(t, system.IsHealthy())
+	require.Len(t, *published, 1)
+
+	// Neither a successful write nor a good ping proves the database is intact
+	system.StorageRecovered()
+	assert.False(t, system.IsHealthy())
+
+	require.NoError(t, system.db.GetDB().Ping())
+	assert.ErrorIs(t, system.PerformHealthCheck(), db.ErrStorageFault)
+	assert.False(t, system.IsHealthy())
+	assert.ErrorIs(t, system.LastStorageFault(), db.ErrStorageFault)
+}
+
+func TestHealthCheckClearsTransientStorageFaults(t *testing.T) {
+	system, _ := newStorageFaultTestSystem(t)
+
+	system.db.ReportFault(sqlitedriver.Error{Code: sqlitedriver.ErrIoErr})
+	assert.False(t, system.IsHealthy())
+
+	require.NoError(t, system.PerformHealthCheck())
+	assert.True(t, system.IsHealthy())
+	assert.NoError(t, system.LastStorageFault())
+}
+
+func TestOtherErrorsAreNotStorageFaults(t *testing.T) {
+	system, published := newStorageFaultTestSystem(t)
+
+	for _, err := range []error{
+		errors.New("boom"),
+		sqlitedriver.Error{Code: sqlitedriver.ErrConstraint},
+		fmt.Errorf("wrapped: %w", sqlitedriver.Error{Code: sqlitedriver.ErrBusy}),
+	} {
+		assert.NotErrorIs(t, system.db.ReportFault(err), db.ErrStorageFault)
+	}
+	assert.True(t, system.IsHealthy())
+	assert.Empty(t, *published)
+}
@@ -7,19 +7,39 @@ import (
 	"sync"
 
 	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/events"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/core/webhook"
 	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
 	"github.com/keeth/levity/plugins"
 )
 
 // System represents the core OCPP Central System
 type System struct {
-	config    *config.Config
-	logger    *slog.Logger
-	db        *db.Database
-	repos     db.RepositoryManager
-	plugins   *plugins.Manager
+	config   *config.Config
+	logger   *slog.Logger
+	clock    clock.Clock
+	db       *db.Database
+	repos    db.RepositoryManager
+	plugins  *plugins.Manager
+	webhooks *webhook.Dispatcher
+	bus      *events.Bus
+	metrics  *monitoring.Metrics
+	registry ConnectionRegistry
+	sender   ocpp.Sender
+	// commands limits in-flight commands per charger; nil is unlimited
+	commands  *CommandLimiter
+	ocpp      *OCPPHandler
 	mu        sync.RWMutex
 	healthyDB bool
+	// storageFault is the storage fault that made the database unhealthy
+	storageFault *db.StorageFaultError
+	ctx          context.Context
+	cancel       context.CancelFunc
+	stopping     bool
+	wg           sync.WaitGroup
 }
 
 // NewSystem creates and initializes a new core system
@@ -27,6 +47,7 @@ func NewSystem(cfg *config.Config, logger *slog.Logger) (*System, error) {
 	system := &System{
 		config: cfg,
 		logger: logger,
+		clock:  clock.Real(),
 	}
 
 	// Initialize database
@@ -35,10 +56,26 @@ func NewSystem(cfg *config.Config, logger *slog.Logger) (*System, error) {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 	system.db = database
+	database.SetStorageFaultObserver(system)
 
 	// Initialize repository manager with logger adapter
 	loggerAdapter := &slogAdapter{logger: logger}
-	system.repos = db.NewRepositoryManager(database, loggerAdapter)
+	system.repos = db.NewRepositoryManager(database, loggerAdapter, system.clock,
+		db.WithAudit(cfg.Audit.Enabled), db.WithIntegerEnergy(cfg.Database.IntegerEnergy))
+
+	// Initialize webhook dispatcher
+	system.webhooks = webhook.NewDispatcher(cfg.Webhooks, system.repos.Webhooks(), system.clock, logger)
+
+	// Initialize OCPP request handler
+	system.ocpp = NewOCPPHandler(cfg.OCPP, system.repos, system.clock, logger)
+	system.ocpp.OnBoot(system.afterBoot)
+
+	if cfg.OCPP.MaxConcurrentCommandsPerCharger > 0 {
+		system.commands = NewCommandLimiter(cfg.OCPP.MaxConcurrentCommandsPerCharger)
+	}
+
+	// Initialize event bus
+	system.bus = events.NewBus()
 
 	// Initialize plugin manager
 	pluginManager, err := plugins.NewManager(cfg, logger)
@@ -52,6 +89,14 @@ func NewSystem(cfg *config.Config, logger *slog.Logger) (*System, error) {
 		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
 	}
 
+	// Ids from before the int32 guard would be truncated by most chargers
+	if count, err := system.repos.Transactions().CountOutOfRangeIDs(context.Background()); err != nil {
+		logger.Warn("Failed to check transaction id range", slog.Any("error", err))
+	} else if count > 0 {
+		logger.Warn("Transactions have OCPP transaction ids outside the int32 range",
+			slog.Int("count", count))
+	}
+
 	// Perform initial health check
 	if err := system.healthCheck(); err != nil {
 		logger.Warn("Initial health check failed", slog.Any("error", err))
@@ -85,6 +130,167 @@ func (s *System) GetConfig() *config.Config {
 	return s.config
 }
 
+// GetClock returns the system clock
+func (s *System) GetClock() clock.Clock {
+	return s.clock
+}
+
+// GetEventBus returns the domain event bus
+func (s *System) GetEventBus() *events.Bus {
+	return s.bus
+}
+
+// SetMetrics sets the metrics updated by the background jobs and OCPP handler. Call before Start.
+func (s *System) SetMetrics(metrics *monitoring.Metrics) {
+	s.metrics = metrics
+	s.ocpp.SetMetrics(metrics)
+}
+
+// SetConnectionRegistry sets the registry used to check for live charger sockets
+func (s *System) SetConnectionRegistry(registry ConnectionRegistry) {
+	s.registry = registry
+}
+
+// OCPP returns the handler for Charge Point initiated OCPP requests
+func (s *System) OCPP() *OCPPHandler {
+	return s.ocpp
+}
+
+// SetCommandSender sets the sender used for Central System initiated OCPP calls
+func (s *System) SetCommandSender(sender ocpp.Sender) {
+	s.sender = sender
+}
+
+// commandSender returns the command sender, recording each command in the
+// command history and limiting the commands in flight to each charger, or nil
+// when no sender is set
+func (s *System) commandSender() ocpp.Sender {
+	if s.sender == nil {
+		return nil
+	}
+
+	var sender ocpp.Sender = newHistorySender(s.sender, s.repos.Commands(), s.metrics, s.clock, s.logger)
+	if s.commands != nil {
+		// Queued commands wait outside the history so their latency is the charger's
+		sender = newLimitedSender(sender, s.commands)
+	}
+	return sender
+}
+
+// LocalLists returns a manager for charger local authorization lists
+func (s *System) LocalLists() *LocalListManager {
+	return NewLocalListManager(s.repos, s.commandSender(), s.clock, s.logger)
+}
+
+// Availability returns a manager for charger and connector availability
+func (s *System) Availability() *AvailabilityManager {
+	manager := NewAvailabilityManager(s.repos, s.commandSender(), s.logger)
+	manager.SetMetrics(s.metrics)
+	return manager
+}
+
+// FileTransfers returns a manager for signed firmware updates and log uploads
+func (s *System) FileTransfers() *FileTransferManager {
+	return NewFileTransferManager(s.repos, s.commandSender(), s.logger)
+}
+
+// MeterValues returns a configurator for charger meter value sampling
+func (s *System) MeterValues() *MeterConfigurator {
+	return NewMeterConfigurator(s.config.OCPP, s.commandSender(), s.logger)
+}
+
+// RemoteControl returns a manager for remote transaction control. Chargers
+// have ocpp.connection_timeout to answer each command.
+func (s *System) RemoteControl() *RemoteControl {
+	return NewRemoteControl(s.commandSender(), s.config.OCPP.ConnectionTimeout, s.logger)
+}
+
+// Configuration returns a reader for charger configuration. Chargers have
+// ocpp.connection_timeout to answer each GetConfiguration.
+func (s *System) Configuration() *ConfigurationReader {
+	return NewConfigurationReader(s.commandSender(), s.config.OCPP.ConnectionTimeout, s.logger)
+}
+
+// Provisioning returns a provisioner for the configured provisioning profiles
+func (s *System) Provisioning() *Provisioner {
+	return NewProvisioner(s.config.Provisioning, s.repos, s.commandSender(), s.logger)
+}
+
+// afterBoot pushes the configured meter value sampling and provisioning
+// profile to a charger that has just booted. It runs in the background so the
+// charger can receive its BootNotification response before the
+// ChangeConfiguration calls.
+func (s *System) afterBoot(chargerID string) {
+	configurator := s.MeterValues()
+	provisioner := s.Provisioning()
+	if (!configurator.Enabled() && !provisioner.Enabled()) || s.sender == nil {
+		return
+	}
+
+	s.Go(func(ctx context.Context) {
+		if configurator.Enabled() {
+			if err := configurator.Configure(ctx, chargerID); err != nil {
+				s.logger.Warn("Failed to configure meter values after boot",
+					slog.String("charger_id", chargerID),
+					slog.Any("error", err))
+			}
+		}
+		if provisioner.Enabled() {
+			if err := provisioner.Provision(ctx, chargerID); err != nil {
+				s.logger.Warn("Failed to provision charger after boot",
+					slog.String("charger_id", chargerID),
+					slog.Any("error", err))
+			}
+		}
+	})
+}
+
+// ValidateCommand resolves the targets of a remote command without sending it
+func (s *System) ValidateCommand(ctx context.Context, action string, chargerIDs []string) []CommandTarget {
+	return NewCommandValidator(s.repos, s.registry).Validate(ctx, action, chargerIDs)
+}
+
+// CheckConsistency reports, and optionally fixes, inconsistent connector,
+// transaction and connection state
+func (s *System) CheckConsistency(ctx context.Context, fix bool) (*ConsistencyReport, error) {
+	report, err := NewConsistencyChecker(s.repos, s.registry, s.clock, s.logger).Check(ctx, fix)
+	if err != nil {
+		return nil, err
+	}
+	if report.Fixed {
+		if err := syncConnectorsByStatus(ctx, s.repos, s.metrics); err != nil {
+			s.logger.Warn("Failed to sync connector metrics", slog.Any("error", err))
+		}
+	}
+	return report, nil
+}
+
+// SitePower returns a reader for the aggregate draw of sites
+func (s *System) SitePower() *SitePowerReader {
+	return NewSitePowerReader(s.repos, s.registry, s.clock)
+}
+
+// DeadLetters returns the queue of charger calls whose handler failed
+func (s *System) DeadLetters() *DeadLetterQueue {
+	return NewDeadLetterQueue(s.repos, s.ocpp, s.logger)
+}
+
+// HandleCall routes a charger's CALL to its handler, reporting storage faults
+// and dead-lettering the call when the handler fails with an internal error
+func (s *System) HandleCall(ctx context.Context, chargerID string, call ocpp.Call) (interface{}, error) {
+	payload, err := s.ocpp.HandleCall(ctx, chargerID, call)
+	if err != nil {
+		err = s.db.ReportFault(err)
+		s.DeadLetters().Record(ctx, chargerID, call, err)
+	}
+	return payload, err
+}
+
+// Energy returns the recomputer for transaction energy totals
+func (s *System) Energy() *EnergyRecomputer {
+	return NewEnergyRecomputer(s.repos, s.logger)
+}
+
 // GetLogger returns the logger
 func (s *System) GetLogger() *slog.Logger {
 	return s.logger
@@ -97,8 +303,15 @@ func (s *System) IsHealthy() bool {
 	return s.healthyDB
 }
 
-// initializeSchema ensures the database schema is up to date
+// initializeSchema ensures the database schema is up to date, migrating it
+// unless database.auto_migrate is off
 func (s *System) initializeSchema() error {
+	// The schema is owned by a separate migration job; fail fast if it is behind
+	if !s.config.Database.AutoMigrate {
+		s.logger.Info("Skipping database migrations, verifying schema version")
+		return s.db.VerifySchema()
+	}
+
 	s.logger.Info("Initializing database schema...")
 
 	// Run migrations
@@ -119,8 +332,14 @@ func (s *System) healthCheck() error {
 
 	// Update health status
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A ping does not prove a corrupt database intact; it stays unhealthy until restart
+	if s.storageFault != nil && s.storageFault.Kind == db.StorageFaultCorrupt {
+		return fmt.Errorf("database health check failed: %w", s.storageFault)
+	}
 	s.healthyDB = true
-	s.mu.Unlock()
+	s.storageFault = nil
 
 	return nil
 }
@@ -130,10 +349,166 @@ func (s *System) PerformHealthCheck() error {
 	return s.healthCheck()
 }
 
-// Shutdown gracefully shuts down the core system
-func (s *System) Shutdown() error {
+// Start launches the background monitors
+func (s *System) Start() {
+	s.mu.Lock()
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.stopping = false
+	s.mu.Unlock()
+
+	if err := syncConnectorsByStatus(s.ctx, s.repos, s.metrics); err != nil {
+		s.logger.Warn("Failed to sync connector metrics", slog.Any("error", err))
+	}
+
+	staleMonitor := NewStaleChargerMonitor(s.config.OCPP.StaleTimeout, s.config.OCPP.HeartbeatInterval, s.repos, s.clock, s.logger)
+	s.Go(staleMonitor.Run)
+
+	if s.config.OCPP.HeartbeatSLAFactor > 0 {
+		slaMonitor := NewHeartbeatSLAMonitor(s.config.OCPP.HeartbeatInterval, s.config.OCPP.HeartbeatSLAFactor,
+			s.config.OCPP.HeartbeatAlertCooldown, s.repos, s.metrics, s.clock, s.logger)
+		s.Go(slaMonitor.Run)
+	}
+
+	if s.config.Retention.Enabled {
+		retentionJob := NewRetentionJob(s.config.Retention, s.repos, s.clock, s.logger)
+		s.Go(retentionJob.Run)
+	}
+
+	if s.config.Reconciliation.Enabled {
+		reconciliationJob := NewReconciliationJob(s.config.Reconciliation, s.repos, s.metrics, s.clock, s.logger)
+		s.Go(reconciliationJob.Run)
+	}
+
+	if s.ocpp != nil && s.ocpp.meterBuffer != nil {
+		s.Go(s.ocpp.meterBuffer.Run)
+	}
+
+	if s.ocpp != nil && s.ocpp.postStop != nil {
+		s.Go(s.ocpp.postStop.Run)
+	}
+
+	if s.db != nil && s.config.Database.CheckpointInterval > 0 {
+		checkpointJob := NewCheckpointJob(s.config.Database.CheckpointInterval, s.db, s.metrics, s.logger)
+		s.Go(checkpointJob.Run)
+	}
+
+	relay := events.NewRelay(s.repos.Outbox(), s.bus, s.config.Events.RelayInterval, s.clock, s.logger)
+	s.Go(relay.Run)
+
+	if s.config.Webhooks.ConnectionURL != "" {
+		s.Go(s.webhooks.Run)
+	}
+}
+
+// Go runs fn in a goroutine that Shutdown cancels and waits for. Long-running
+// work such as connection pumps should be started here rather than with a bare
+// go statement so it cannot outlive the system. It reports whether fn was
+// started; it is not before Start or once Shutdown has begun.
+func (s *System) Go(fn func(ctx context.Context)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil || s.stopping {
+		return false
+	}
+
+	ctx := s.ctx
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn(ctx)
+	}()
+	return true
+}
+
+// ConnectionInfo describes a newly opened charger connection
+type ConnectionInfo struct {
+	RemoteIP    string
+	Subprotocol string
+}
+
+// ChargerConnected records that a charger opened its OCPP connection, restores
+// the connector state of its active transactions, and returns the id of its
+// connection record, or 0 if it could not be recorded
+func (s *System) ChargerConnected(ctx context.Context, chargerID string, info ConnectionInfo) int {
+	var connectionID int
+	conn, err := s.repos.Connections().Open(ctx, db.CreateChargerConnectionRequest{
+		ChargerID:   chargerID,
+		RemoteIP:    info.RemoteIP,
+		Subprotocol: info.Subprotocol,
+		ConnectedAt: s.clock.Now(),
+	})
+	if err != nil {
+		s.logger.Error("Failed to record connection",
+			slog.String("charger_id", chargerID),
+			slog.Any("error", err))
+	} else {
+		connectionID = conn.ID
+	}
+
+	if s.ocpp != nil {
+		if _, err := s.ocpp.RestoreActiveTransactions(ctx, chargerID); err != nil {
+			s.logger.Error("Failed to restore active transactions",
+				slog.String("charger_id", chargerID),
+				slog.Any("error", err))
+		}
+	}
+
+	if err := s.webhooks.EnqueueConnectionEvent(ctx, chargerID, true); err != nil {
+		s.logger.Error("Failed to enqueue connection webhook",
+			slog.String("charger_id", chargerID),
+			slog.Any("error", err))
+	}
+
+	return connectionID
+}
+
+// ChargerDisconnected records that a charger's OCPP connection closed. The
+// connection id is the one returned by ChargerConnected.
+func (s *System) ChargerDisconnected(ctx context.Context, chargerID string, connectionID int, reason string) {
+	if connectionID != 0 {
+		if err := s.repos.Connections().Close(ctx, connectionID, s.clock.Now(), reason); err != nil {
+			s.logger.Error("Failed to record disconnection",
+				slog.String("charger_id", chargerID),
+				slog.Int("connection_id", connectionID),
+				slog.Any("error", err))
+		}
+	}
+
+	if err := s.webhooks.EnqueueConnectionEvent(ctx, chargerID, false); err != nil {
+		s.logger.Error("Failed to enqueue disconnection webhook",
+			slog.String("charger_id", chargerID),
+			slog.Any("error", err))
+	}
+}
+
+// Shutdown gracefully shuts down the core system, waiting for background
+// goroutines to exit until ctx is done
+func (s *System) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down core system...")
 
+	// Stop background goroutines and wait for them to exit. Go starts no new
+	// work once stopping is set, so Add cannot race with Wait.
+	s.mu.Lock()
+	s.stopping = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(stopped)
+	}()
+
+	var waitErr error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		waitErr = fmt.Errorf("timed out waiting for background goroutines: %w", ctx.Err())
+		s.logger.Error("Background goroutines did not stop in time", slog.Any("error", ctx.Err()))
+	}
+
 	// Shutdown plugins
 	if s.plugins != nil {
 		if err := s.plugins.Shutdown(); err != nil {
@@ -148,6 +523,10 @@ func (s *System) Shutdown() error {
 		}
 	}
 
+	if waitErr != nil {
+		return waitErr
+	}
+
 	s.logger.Info("Core system shutdown complete")
 	return nil
 }
@@ -0,0 +1,247 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/core/events"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/core/webhook"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLifecycleSystem builds a system with every background job enabled
+func newLifecycleSystem(t *testing.T) *System {
+	t.Helper()
+	clk := clock.Real()
+	repos := dbtest.NewRepositories(t, clk)
+
+	cfg := &config.Config{}
+	cfg.OCPP.StaleTimeout = time.Minute
+	cfg.OCPP.HeartbeatInterval = 10 * time.Millisecond
+	cfg.Retention = config.RetentionConfig{Enabled: true, Interval: 10 * time.Millisecond}
+	cfg.Reconciliation = config.ReconciliationConfig{Enabled: true, Interval: 10 * time.Millisecond}
+	cfg.Events.RelayInterval = 10 * time.Millisecond
+	cfg.Webhooks = config.WebhooksConfig{ConnectionURL: "http://127.0.0.1:0/hook", PollInterval: 10 * time.Millisecond}
+
+	return &System{
+		config:   cfg,
+		logger:   dbtest.Logger(),
+		clock:    clk,
+		repos:    repos,
+		bus:      events.NewBus(),
+		webhooks: webhook.NewDispatcher(cfg.Webhooks, repos.Webhooks(), clk, dbtest.Logger()),
+	}
+}
+
+func TestShutdownWaitsForBackgroundGoroutines(t *testing.T) {
+	system := newLifecycleSystem(t)
+	baseline := runtime.NumGoroutine()
+
+	system.Start()
+	stopped := make(chan struct{})
+	system.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	// Let the jobs tick at least once
+	time.Sleep(50 * time.Millisecond)
+	assert.Greater(t, runtime.NumGoroutine(), baseline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, system.Shutdown(ctx))
+
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Shutdown returned before a tracked goroutine exited")
+	}
+
+	// The waiter goroutine inside Shutdown may still be unwinding
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= baseline
+	}, time.Second, 10*time.Millisecond, "background goroutines leaked")
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	system := newLifecycleSystem(t)
+	system.Start()
+
+	release := make(chan struct{})
+	defer close(release)
+	system.Go(func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := system.Shutdown(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Goroutines cannot be started once shutdown has begun
+	started := false
+	assert.False(t, system.Go(func(ctx context.Context) { started = true }))
+	assert.False(t, started)
+}
+
+func TestGoBeforeStart(t *testing.T) {
+	system := newLifecycleSystem(t)
+	assert.False(t, system.Go(func(ctx context.Context) {}))
+
+	system.Start()
+	assert.True(t, system.Go(func(ctx context.Context) {}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, system.Shutdown(ctx))
+}
+
+func TestConnectDisconnectRecordsConnection(t *testing.T) {
+	ctx := context.Background()
+	system := newLifecycleSystem(t)
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	system.clock = fake
+
+	connectionID := system.ChargerConnected(ctx, "CP-1", ConnectionInfo{RemoteIP: "192.0.2.10", Subprotocol: "ocpp1.6"})
+	require.NotZero(t, connectionID)
+
+	fake.Advance(5 * time.Minute)
+	system.ChargerDisconnected(ctx, "CP-1", connectionID, "timeout")
+
+	conns, err := system.repos.Connections().ListByCharger(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, conns, 1)
+
+	conn := conns[0]
+	assert.Equal(t, connectionID, conn.ID)
+	assert.Equal(t, "192.0.2.10", conn.RemoteIP)
+	assert.Equal(t, "ocpp1.6", conn.Subprotocol)
+	assert.True(t, conn.ConnectedAt.Equal(fake.Now().Add(-5*time.Minute)))
+	require.NotNil(t, conn.DisconnectedAt)
+	assert.True(t, conn.DisconnectedAt.Equal(fake.Now()))
+	assert.Equal(t, "timeout", conn.DisconnectReason)
+	require.NotNil(t, conn.DurationSeconds)
+	assert.Equal(t, int64(300), *conn.DurationSeconds)
+}
+
+func TestReconnectRestoresActiveTransaction(t *testing.T) {
+	ctx := context.Background()
+	system := newLifecycleSystem(t)
+	system.ocpp = NewOCPPHandler(config.OCPPConfig{}, system.repos, system.clock, dbtest.Logger())
+	handler := system.OCPP()
+
+	_, err := system.repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1", NumConnectors: 2})
+	require.NoError(t, err)
+	for _, status := range []ocpp.StatusNotificationRequest{
+		{ConnectorID: 1, Status: ocpp.ChargePointStatusCharging},
+		{ConnectorID: 2, Status: ocpp.ChargePointStatusAvailable},
+	} {
+		_, err := handler.StatusNotification(ctx, "CP-1", status)
+		require.NoError(t, err)
+	}
+	started, err := handler.StartTransaction(ctx, "CP-1", ocpp.StartTransactionRequest{
+		ConnectorID: 1,
+		IDTag:       "TAG-1",
+		MeterStart:  1000,
+		Timestamp:   time.Now(),
+	})
+	require.NoError(t, err)
+
+	// The charger drops off cellular and comes back; its connector was
+	// marked unavailable in the meantime
+	require.NoError(t, system.repos.Connectors().UpdateStatus(ctx, "CP-1", 1, ocpp.ChargePointStatusUnavailable))
+	system.ChargerConnected(ctx, "CP-1", ConnectionInfo{})
+
+	connector, err := system.repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusCharging, connector.Status)
+	free, err := system.repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusAvailable, free.Status)
+
+	// A stale Available status sent on reconnect does not free the connector
+	_, err = handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{ConnectorID: 1, Status: ocpp.ChargePointStatusAvailable})
+	require.NoError(t, err)
+	connector, err = system.repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusCharging, connector.Status)
+
+	report, err := system.CheckConsistency(ctx, true)
+	require.NoError(t, err)
+	assert.Empty(t, report.TransactionsNotCharging)
+	active, err := system.repos.Transactions().GetActiveByConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, started.TransactionID, *active.TransactionID)
+
+	// Once the queued StopTransaction arrives the connector can become Available
+	_, err = handler.StopTransaction(ctx, "CP-1", ocpp.StopTransactionRequest{
+		TransactionID: started.TransactionID,
+		MeterStop:     2000,
+		Timestamp:     time.Now(),
+	})
+	require.NoError(t, err)
+	_, err = handler.StatusNotification(ctx, "CP-1", ocpp.StatusNotificationRequest{ConnectorID: 1, Status: ocpp.ChargePointStatusAvailable})
+	require.NoError(t, err)
+	connector, err = system.repos.Connectors().GetByChargerAndConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, ocpp.ChargePointStatusAvailable, connector.Status)
+}
+
+func TestNewSystemWithoutAutoMigrateVerifiesSchema(t *testing.T) {
+	// migratedTo prepares a database at the latest migration less behind steps
+	migratedTo := func(t *testing.T, behind int) config.DatabaseConfig {
+		cfg := config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "levity.db"), MaxOpenConns: 5, MaxIdleConns: 5}
+		database, err := db.NewDatabase(cfg, dbtest.Logger())
+		require.NoError(t, err)
+		require.NoError(t, database.RunMigrations())
+		if behind > 0 {
+			require.NoError(t, database.MigrateDown(behind))
+		}
+		require.NoError(t, database.Close())
+		return cfg
+	}
+
+	t.Run("current schema", func(t *testing.T) {
+		cfg := &config.Config{Database: migratedTo(t, 0)}
+		system, err := NewSystem(cfg, dbtest.Logger())
+		require.NoError(t, err)
+		defer system.GetDatabase().Close()
+
+		_, err = system.GetRepositories().Chargers().Create(context.Background(), db.CreateChargerRequest{ID: "CP-1"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("behind schema", func(t *testing.T) {
+		cfg := &config.Config{Database: migratedTo(t, 1)}
+		_, err := NewSystem(cfg, dbtest.Logger())
+		require.ErrorIs(t, err, db.ErrSchemaOutdated)
+
+		// Startup must not have migrated the database itself
+		database, err := db.NewDatabase(cfg.Database, dbtest.Logger())
+		require.NoError(t, err)
+		defer database.Close()
+		version, _, err := database.GetMigrationVersion()
+		require.NoError(t, err)
+		latest, err := database.LatestMigrationVersion()
+		require.NoError(t, err)
+		assert.Less(t, version, latest)
+	})
+
+	t.Run("empty database", func(t *testing.T) {
+		cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "levity.db"), MaxOpenConns: 5}}
+		_, err := NewSystem(cfg, dbtest.Logger())
+		assert.ErrorIs(t, err, db.ErrSchemaOutdated)
+	})
+}
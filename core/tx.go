@@ -0,0 +1,41 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/monitoring"
+)
+
+// WithTx runs fn in a database transaction, committing when fn returns nil and
+// rolling back otherwise. The transaction's duration, and whether it rolled
+// back, is recorded under operation in metrics, which may be nil.
+func WithTx(ctx context.Context, repos db.RepositoryManager, metrics *monitoring.Metrics, operation string, fn func(tx db.TxManager) error) error {
+	tx, err := repos.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	start := time.Now()
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+		if metrics != nil {
+			metrics.RecordDatabaseTransaction(operation, time.Since(start).Seconds(), committed)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", operation, err)
+	}
+	committed = true
+	return nil
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transactionCount returns how many transactions were observed for operation
+func transactionCount(t *testing.T, metrics *monitoring.Metrics, operation string) uint64 {
+	t.Helper()
+	families, err := metrics.Registry().Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "database_transaction_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operation" && label.GetValue() == operation {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestWithTxRecordsMetrics(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	metrics := monitoring.NewMetrics()
+
+	err := WithTx(ctx, repos, metrics, "create_charger", func(tx db.TxManager) error {
+		_, err := tx.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+		return err
+	})
+	require.NoError(t, err)
+
+	failure := errors.New("boom")
+	err = WithTx(ctx, repos, metrics, "create_and_fail", func(tx db.TxManager) error {
+		if _, err := tx.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-2"}); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	// Only the committed charger was stored
+	_, err = repos.Chargers().GetByID(ctx, "CP-1")
+	assert.NoError(t, err)
+	_, err = repos.Chargers().GetByID(ctx, "CP-2")
+	assert.Error(t, err)
+
+	assert.Equal(t, uint64(1), transactionCount(t, metrics, "create_charger"))
+	assert.Equal(t, uint64(1), transactionCount(t, metrics, "create_and_fail"))
+
+	expected := `
+# HELP database_transaction_rollbacks_total Total number of database transactions rolled back
+# TYPE database_transaction_rollbacks_total counter
+database_transaction_rollbacks_total{operation="create_and_fail"} 1
+`
+	err = testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "database_transaction_rollbacks_total")
+	require.NoError(t, err)
+}
@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+)
+
+// Connection event types
+const (
+	EventChargerConnected    = "charger.connected"
+	EventChargerDisconnected = "charger.disconnected"
+)
+
+// Request headers sent with every delivery
+const (
+	HeaderEvent     = "X-Levity-Event"
+	HeaderDelivery  = "X-Levity-Delivery"
+	HeaderSignature = "X-Levity-Signature"
+)
+
+// maxBackoff caps the delay between retries
+const maxBackoff = time.Hour
+
+// batchSize is the number of due deliveries processed per poll
+const batchSize = 50
+
+// ConnectionEvent is the JSON body of a connection webhook
+type ConnectionEvent struct {
+	Event     string    `json:"event"`
+	ChargerID string    `json:"charger_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Dispatcher queues webhooks in the outbox and delivers them with retries
+type Dispatcher struct {
+	config config.WebhooksConfig
+	repo   db.WebhookOutboxRepository
+	client *http.Client
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(cfg config.WebhooksConfig, repo db.WebhookOutboxRepository, clk clock.Clock, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		config: cfg,
+		repo:   repo,
+		client: &http.Client{Timeout: cfg.Timeout},
+		clock:  clk,
+		logger: logger,
+	}
+}
+
+// EnqueueConnectionEvent queues a connect or disconnect webhook for a charger
+func (d *Dispatcher) EnqueueConnectionEvent(ctx context.Context, chargerID string, connected bool) error {
+	if d.config.ConnectionURL == "" {
+		return nil
+	}
+
+	event := EventChargerDisconnected
+	if connected {
+		event = EventChargerConnected
+	}
+
+	now := d.clock.Now().UTC()
+	payload, err := json.Marshal(ConnectionEvent{
+		Event:     event,
+		ChargerID: chargerID,
+		Timestamp: now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal connection event: %w", err)
+	}
+
+	_, err = d.repo.Enqueue(ctx, db.CreateWebhookDeliveryRequest{
+		EventType:     event,
+		URL:           d.config.ConnectionURL,
+		Payload:       string(payload),
+		NextAttemptAt: now,
+	})
+	return err
+}
+
+// DeliverDue attempts every pending delivery that is due and returns how many succeeded
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	due, err := d.repo.GetDue(ctx, d.clock.Now(), batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due webhooks: %w", err)
+	}
+
+	delivered := 0
+	for _, delivery := range due {
+		if err := d.deliver(ctx, delivery); err != nil {
+			d.recordFailure(ctx, delivery, err)
+			continue
+		}
+
+		if err := d.repo.MarkDelivered(ctx, delivery.ID, d.clock.Now()); err != nil {
+			d.logger.Error("Failed to mark webhook delivered", slog.Int("id", delivery.ID), slog.Any("error", err))
+			continue
+		}
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+// Run delivers due webhooks on the configured poll interval until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	interval := d.config.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.DeliverDue(ctx); err != nil {
+				d.logger.Error("Webhook delivery run failed", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// deliver POSTs a single webhook and treats any non-2xx response as a failure
+func (d *Dispatcher) deliver(ctx context.Context, delivery *db.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	if d.config.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.config.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// recordFailure schedules a retry with exponential backoff or dead-letters the delivery
+func (d *Dispatcher) recordFailure(ctx context.Context, delivery *db.WebhookDelivery, deliveryErr error) {
+	attempts := delivery.Attempts + 1
+
+	if d.config.MaxAttempts > 0 && attempts >= d.config.MaxAttempts {
+		d.logger.Error("Webhook dead-lettered after max attempts",
+			slog.Int("id", delivery.ID),
+			slog.String("event_type", delivery.EventType),
+			slog.Int("attempts", attempts),
+			slog.Any("error", deliveryErr))
+		if err := d.repo.MarkDeadLetter(ctx, delivery.ID, deliveryErr.Error()); err != nil {
+			d.logger.Error("Failed to dead-letter webhook", slog.Int("id", delivery.ID), slog.Any("error", err))
+		}
+		return
+	}
+
+	nextAttemptAt := d.clock.Now().Add(Backoff(d.config.RetryBackoff, attempts))
+	d.logger.Warn("Webhook delivery failed, scheduling retry",
+		slog.Int("id", delivery.ID),
+		slog.Int("attempts", attempts),
+		slog.Time("next_attempt_at", nextAttemptAt),
+		slog.Any("error", deliveryErr))
+	if err := d.repo.MarkFailed(ctx, delivery.ID, nextAttemptAt, deliveryErr.Error()); err != nil {
+		d.logger.Error("Failed to record webhook failure", slog.Int("id", delivery.ID), slog.Any("error", err))
+	}
+}
+
+// Backoff returns the exponential delay before the next attempt, doubling per attempt
+func Backoff(base time.Duration, attempts int) time.Duration {
+	if base <= 0 {
+		base = time.Second
+	}
+
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// Sign returns the HMAC-SHA256 signature header value for a payload
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
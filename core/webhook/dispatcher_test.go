@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDispatcher(t *testing.T, url string, maxAttempts int) (*Dispatcher, db.RepositoryManager, *clock.Fake) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	cfg := config.WebhooksConfig{
+		ConnectionURL: url,
+		Secret:        "s3cret",
+		MaxAttempts:   maxAttempts,
+		RetryBackoff:  time.Second,
+		Timeout:       time.Second,
+	}
+	return NewDispatcher(cfg, repos.Webhooks(), fake, dbtest.Logger()), repos, fake
+}
+
+func TestDeliverConnectionEvent(t *testing.T) {
+	ctx := context.Background()
+
+	var received ConnectionEvent
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(HeaderSignature)
+		assert.Equal(t, Sign("s3cret", body), signature)
+		assert.Equal(t, EventChargerConnected, r.Header.Get(HeaderEvent))
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	dispatcher, repos, _ := newTestDispatcher(t, srv.URL, 3)
+	require.NoError(t, dispatcher.EnqueueConnectionEvent(ctx, "CP-1", true))
+
+	delivered, err := dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, "CP-1", received.ChargerID)
+	assert.Equal(t, EventChargerConnected, received.Event)
+	assert.NotEmpty(t, signature)
+
+	delivery, err := repos.Webhooks().GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.WebhookStatusDelivered, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.NotNil(t, delivery.DeliveredAt)
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	ctx := context.Background()
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	dispatcher, repos, fake := newTestDispatcher(t, srv.URL, 5)
+	require.NoError(t, dispatcher.EnqueueConnectionEvent(ctx, "CP-1", false))
+
+	delivered, err := dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	delivery, err := repos.Webhooks().GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.WebhookStatusPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Contains(t, delivery.LastError, "503")
+
+	// The retry is not due until the backoff has elapsed
+	delivered, err = dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	fake.Advance(time.Second)
+	delivered, err = dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	delivery, err = repos.Webhooks().GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.WebhookStatusDelivered, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+}
+
+func TestDeadLetterAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	dispatcher, repos, fake := newTestDispatcher(t, srv.URL, 3)
+	require.NoError(t, dispatcher.EnqueueConnectionEvent(ctx, "CP-1", true))
+
+	for i := 0; i < 3; i++ {
+		_, err := dispatcher.DeliverDue(ctx)
+		require.NoError(t, err)
+		fake.Advance(time.Hour)
+	}
+
+	delivery, err := repos.Webhooks().GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, db.WebhookStatusDeadLetter, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+
+	// Dead-lettered deliveries are never retried
+	due, err := repos.Webhooks().GetDue(ctx, fake.Now(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, Backoff(time.Second, 1))
+	assert.Equal(t, 2*time.Second, Backoff(time.Second, 2))
+	assert.Equal(t, 8*time.Second, Backoff(time.Second, 4))
+	assert.Equal(t, time.Hour, Backoff(time.Second, 30))
+}
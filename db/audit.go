@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// ActorSystem is recorded for changes made outside an authenticated API request
+const ActorSystem = "system"
+
+// actorContextKey is the context key holding the caller recorded in the audit log
+type actorContextKey struct{}
+
+// WithActor returns a context that attributes repository changes to actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or ActorSystem
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorContextKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return ActorSystem
+}
+
+// auditIgnoredFields change on every write and are left out of diffs
+var auditIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Diff returns the JSON fields that differ between before and after. Either
+// may be nil, for a create or a delete.
+func Diff(before, after interface{}) map[string]FieldChange {
+	beforeFields := toFields(before)
+	afterFields := toFields(after)
+
+	changes := map[string]FieldChange{}
+	for field, value := range afterFields {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		if old, ok := beforeFields[field]; !ok || !reflect.DeepEqual(old, value) {
+			changes[field] = FieldChange{Before: beforeFields[field], After: value}
+		}
+	}
+	for field, value := range beforeFields {
+		if _, ok := afterFields[field]; !ok && !auditIgnoredFields[field] {
+			changes[field] = FieldChange{Before: value}
+		}
+	}
+	return changes
+}
+
+// toFields flattens a model into its JSON fields
+func toFields(v interface{}) map[string]interface{} {
+	if v == nil || reflect.ValueOf(v).IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// auditor records changes made through an audited repository. Failing to
+// record is logged rather than returned, since the change itself succeeded.
+type auditor struct {
+	log    AuditLogRepository
+	logger Logger
+}
+
+// record writes an audit entry unless nothing changed
+func (a *auditor) record(ctx context.Context, entityType, entityID, operation string, before, after interface{}) {
+	changes := Diff(before, after)
+	if operation == AuditOperationUpdate && len(changes) == 0 {
+		return
+	}
+
+	_, err := a.log.Record(ctx, CreateAuditEntryRequest{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Operation:  operation,
+		Changes:    changes,
+	})
+	if err != nil {
+		a.logger.Error("Failed to record audit entry",
+			"entity_type", entityType, "entity_id", entityID, "operation", operation, "error", err)
+	}
+}
+
+// auditedChargerRepository records charger creates, updates and deletes
+type auditedChargerRepository struct {
+	ChargerRepository
+	auditor *auditor
+}
+
+// Create implements ChargerRepository.Create
+func (r *auditedChargerRepository) Create(ctx context.Context, req CreateChargerRequest) (*Charger, error) {
+	charger, err := r.ChargerRepository.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r.auditor.record(ctx, AuditEntityCharger, charger.ID, AuditOperationCreate, nil, charger)
+	return charger, nil
+}
+
+// Update implements ChargerRepository.Update
+func (r *auditedChargerRepository) Update(ctx context.Context, id string, req UpdateChargerRequest) (*Charger, error) {
+	before, _ := r.ChargerRepository.GetByID(ctx, id)
+	charger, err := r.ChargerRepository.Update(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	r.auditor.record(ctx, AuditEntityCharger, id, AuditOperationUpdate, before, charger)
+	return charger, nil
+}
+
+// Delete implements ChargerRepository.Delete
+func (r *auditedChargerRepository) Delete(ctx context.Context, id string) error {
+	before, _ := r.ChargerRepository.GetByID(ctx, id)
+	if err := r.ChargerRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.auditor.record(ctx, AuditEntityCharger, id, AuditOperationDelete, before, nil)
+	return nil
+}
+
+// auditedIDTagRepository records ID tag creates, status changes and deletes
+type auditedIDTagRepository struct {
+	IDTagRepository
+	auditor *auditor
+}
+
+// Create implements IDTagRepository.Create
+func (r *auditedIDTagRepository) Create(ctx context.Context, req CreateIDTagRequest) (*IDTag, error) {
+	tag, err := r.IDTagRepository.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r.auditor.record(ctx, AuditEntityIDTag, tag.IDTag, AuditOperationCreate, nil, tag)
+	return tag, nil
+}
+
+// UpdateStatus implements IDTagRepository.UpdateStatus
+func (r *auditedIDTagRepository) UpdateStatus(ctx context.Context, idTag string, status string) error {
+	before, _ := r.IDTagRepository.GetByIDTag(ctx, idTag)
+	if err := r.IDTagRepository.UpdateStatus(ctx, idTag, status); err != nil {
+		return err
+	}
+	after, _ := r.IDTagRepository.GetByIDTag(ctx, idTag)
+	r.auditor.record(ctx, AuditEntityIDTag, idTag, AuditOperationUpdate, before, after)
+	return nil
+}
+
+// Delete implements IDTagRepository.Delete
+func (r *auditedIDTagRepository) Delete(ctx context.Context, idTag string) error {
+	before, _ := r.IDTagRepository.GetByIDTag(ctx, idTag)
+	if err := r.IDTagRepository.Delete(ctx, idTag); err != nil {
+		return err
+	}
+	r.auditor.record(ctx, AuditEntityIDTag, idTag, AuditOperationDelete, before, nil)
+	return nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/keeth/levity/core/clock"
+)
+
+// auditLogRepository implements AuditLogRepository
+type auditLogRepository struct {
+	db     Executor
+	logger Logger
+	clock  clock.Clock
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db Executor, logger Logger, clk clock.Clock) AuditLogRepository {
+	return &auditLogRepository{
+		db:     db,
+		logger: logger,
+		clock:  clk,
+	}
+}
+
+// Record implements AuditLogRepository.Record
+func (r *auditLogRepository) Record(ctx context.Context, req CreateAuditEntryRequest) (*AuditEntry, error) {
+	actor := req.Actor
+	if actor == "" {
+		actor = ActorFromContext(ctx)
+	}
+
+	changes := req.Changes
+	if changes == nil {
+		changes = map[string]FieldChange{}
+	}
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_log (actor, entity_type, entity_id, operation, changes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, actor, entity_type, entity_id, operation, changes, created_at`
+
+	entry, err := scanAuditEntry(r.db.QueryRowContext(ctx, query,
+		actor, req.EntityType, req.EntityID, req.Operation, string(payload), r.clock.Now().UTC(),
+	))
+	if err != nil {
+		r.logger.Error("Failed to record audit entry",
+			"entity_type", req.EntityType, "entity_id", req.EntityID, "error", err)
+		return nil, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// List implements AuditLogRepository.List
+func (r *auditLogRepository) List(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.EntityType != "" {
+		conditions = append(conditions, "entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Operation != "" {
+		conditions = append(conditions, "operation = ?")
+		args = append(args, filter.Operation)
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListOptions().Limit
+	}
+	args = append(args, limit, filter.Offset)
+
+	query := fmt.Sprintf(`
+		SELECT id, actor, entity_type, entity_id, operation, changes, created_at
+		FROM audit_log %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`, where)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to list audit entries", "error", err)
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return entries, nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAuditEntry scans an audit_log row, keeping the changes as raw JSON
+func scanAuditEntry(row rowScanner) (*AuditEntry, error) {
+	var entry AuditEntry
+	var changes string
+	err := row.Scan(
+		&entry.ID, &entry.Actor, &entry.EntityType, &entry.EntityID, &entry.Operation, &changes, &entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	entry.Changes = json.RawMessage(changes)
+	return &entry, nil
+}
@@ -0,0 +1,103 @@
+package db_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditChargerUpdate(t *testing.T) {
+	ctx := db.WithActor(context.Background(), "operator:1a2b3c4d")
+	fake := clock.NewFake(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake, db.WithAudit(true))
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1", Name: "Lobby"})
+	require.NoError(t, err)
+
+	fake.Advance(time.Minute)
+	name := "Garage"
+	notes := "Level -1"
+	_, err = repos.Chargers().Update(ctx, "CP-1", db.UpdateChargerRequest{Name: &name, Notes: &notes})
+	require.NoError(t, err)
+
+	// An update that changes nothing is not recorded
+	_, err = repos.Chargers().Update(ctx, "CP-1", db.UpdateChargerRequest{Name: &name})
+	require.NoError(t, err)
+
+	entries, err := repos.Audit().List(context.Background(), db.AuditFilter{EntityType: db.AuditEntityCharger, EntityID: "CP-1"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	update := entries[0]
+	assert.Equal(t, db.AuditOperationUpdate, update.Operation)
+	assert.Equal(t, "operator:1a2b3c4d", update.Actor)
+	assert.True(t, fake.Now().Equal(update.CreatedAt))
+
+	var changes map[string]db.FieldChange
+	require.NoError(t, json.Unmarshal(update.Changes, &changes))
+	assert.Equal(t, map[string]db.FieldChange{
+		"name":  {Before: "Lobby", After: "Garage"},
+		"notes": {Before: "", After: "Level -1"},
+	}, changes)
+
+	assert.Equal(t, db.AuditOperationCreate, entries[1].Operation)
+}
+
+func TestAuditFilters(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake, db.WithAudit(true))
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	fake.Advance(time.Hour)
+	_, err = repos.IDTags().Create(db.WithActor(ctx, "admin:ffff0000"), db.CreateIDTagRequest{IDTag: "TAG-1"})
+	require.NoError(t, err)
+	fake.Advance(time.Hour)
+	require.NoError(t, repos.IDTags().UpdateStatus(ctx, "TAG-1", "Blocked"))
+
+	tests := []struct {
+		name   string
+		filter db.AuditFilter
+		want   int
+	}{
+		{"all", db.AuditFilter{}, 3},
+		{"entity type", db.AuditFilter{EntityType: db.AuditEntityIDTag}, 2},
+		{"actor", db.AuditFilter{Actor: db.ActorSystem}, 2},
+		{"operation", db.AuditFilter{Operation: db.AuditOperationCreate}, 2},
+		{"since", db.AuditFilter{Since: timePtr(fake.Now().Add(-90 * time.Minute))}, 2},
+		{"until", db.AuditFilter{Until: timePtr(fake.Now().Add(-90 * time.Minute))}, 1},
+		{"limit", db.AuditFilter{Limit: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := repos.Audit().List(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Len(t, entries, tt.want)
+		})
+	}
+}
+
+func TestAuditDisabled(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	entries, err := repos.Audit().List(ctx, db.AuditFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,147 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChargerErrorListFilters(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repos := dbtest.NewRepositories(t, clock.NewFake(base))
+
+	for _, id := range []string{"CP-1", "CP-2"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+	}
+
+	seed := []struct {
+		chargerID string
+		code      string
+		hour      int
+		resolved  bool
+	}{
+		{"CP-1", "OverVoltage", 1, true},
+		{"CP-1", "OverVoltage", 2, false},
+		{"CP-1", "GroundFailure", 3, false},
+		{"CP-2", "OverVoltage", 4, true},
+		{"CP-2", "ConnectorLockFailure", 5, false},
+	}
+	for _, e := range seed {
+		created, err := repos.Errors().Create(ctx, db.CreateChargerErrorRequest{
+			ChargerID: e.chargerID,
+			ErrorCode: e.code,
+			Timestamp: base.Add(time.Duration(e.hour) * time.Hour),
+		})
+		require.NoError(t, err)
+		if e.resolved {
+			require.NoError(t, repos.Errors().Resolve(ctx, created.ID, base.Add(time.Duration(e.hour+1)*time.Hour)))
+		}
+	}
+
+	resolved, unresolved := true, false
+	since := base.Add(2 * time.Hour)
+	until := base.Add(4 * time.Hour)
+
+	tests := []struct {
+		name   string
+		filter db.ErrorFilter
+		want   []int // hours of the expected errors, newest first
+	}{
+		{"no filter", db.ErrorFilter{}, []int{5, 4, 3, 2, 1}},
+		{"error code", db.ErrorFilter{ErrorCode: "OverVoltage"}, []int{4, 2, 1}},
+		{"resolved", db.ErrorFilter{Resolved: &resolved}, []int{4, 1}},
+		{"unresolved", db.ErrorFilter{Resolved: &unresolved}, []int{5, 3, 2}},
+		{"since", db.ErrorFilter{Since: &since}, []int{5, 4, 3, 2}},
+		{"until", db.ErrorFilter{Until: &until}, []int{4, 3, 2, 1}},
+		{"time range", db.ErrorFilter{Since: &since, Until: &until}, []int{4, 3, 2}},
+		{"charger", db.ErrorFilter{ChargerID: "CP-2"}, []int{5, 4}},
+		{"code and unresolved", db.ErrorFilter{ErrorCode: "OverVoltage", Resolved: &unresolved}, []int{2}},
+		{"code, resolved and range", db.ErrorFilter{ErrorCode: "OverVoltage", Resolved: &resolved, Since: &since, Until: &until}, []int{4}},
+		{"charger and code", db.ErrorFilter{ChargerID: "CP-1", ErrorCode: "GroundFailure"}, []int{3}},
+		{"no match", db.ErrorFilter{ErrorCode: "OverVoltage", ChargerID: "CP-2", Resolved: &unresolved}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := repos.Errors().List(ctx, tt.filter, db.DefaultListOptions())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, errorHours(base, errs))
+		})
+	}
+
+	t.Run("pagination and ordering", func(t *testing.T) {
+		opts := db.ListOptions{Limit: 2, Offset: 1, OrderBy: "timestamp", SortDir: "ASC"}
+		errs, err := repos.Errors().List(ctx, db.ErrorFilter{}, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []int{2, 3}, errorHours(base, errs))
+	})
+
+	t.Run("unknown order by falls back to timestamp", func(t *testing.T) {
+		opts := db.ListOptions{Limit: 10, OrderBy: "1; DROP TABLE charger_errors", SortDir: "ASC"}
+		errs, err := repos.Errors().List(ctx, db.ErrorFilter{}, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, errorHours(base, errs))
+	})
+}
+
+func errorHours(base time.Time, errs []*db.ChargerError) []int {
+	var hours []int
+	for _, e := range errs {
+		hours = append(hours, int(e.Timestamp.Sub(base)/time.Hour))
+	}
+	return hours
+}
+
+func TestTopErrorCodes(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repos := dbtest.NewRepositories(t, clock.NewFake(base))
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	distribution := map[string]int{
+		"OverVoltage":          5,
+		"GroundFailure":        3,
+		"ConnectorLockFailure": 3,
+		"HighTemperature":      1,
+	}
+	for code, n := range distribution {
+		for i := 0; i < n; i++ {
+			_, err := repos.Errors().Create(ctx, db.CreateChargerErrorRequest{
+				ChargerID: "CP-1",
+				ErrorCode: code,
+				Timestamp: base.Add(time.Duration(i+1) * time.Minute),
+			})
+			require.NoError(t, err)
+		}
+	}
+	// Outside the window
+	for i := 0; i < 10; i++ {
+		_, err := repos.Errors().Create(ctx, db.CreateChargerErrorRequest{
+			ChargerID: "CP-1",
+			ErrorCode: "PowerMeterFailure",
+			Timestamp: base.Add(48 * time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	top, err := repos.Errors().TopErrorCodes(ctx, base, base.Add(time.Hour), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []db.ErrorCodeCount{
+		{ErrorCode: "OverVoltage", Count: 5},
+		{ErrorCode: "ConnectorLockFailure", Count: 3},
+		{ErrorCode: "GroundFailure", Count: 3},
+	}, top)
+
+	none, err := repos.Errors().TopErrorCodes(ctx, base.Add(-time.Hour), base, 3)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
@@ -3,11 +3,15 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 )
 
+// ErrChargerNotFound is returned when a lookup or update targets a charger without a row
+var ErrChargerNotFound = errors.New("charger not found")
+
 // chargerRepository implements ChargerRepository
 type chargerRepository struct {
 	db     Executor
@@ -35,23 +39,23 @@ func (r *chargerRepository) Create(ctx context.Context, req CreateChargerRequest
 	query := `
 		INSERT INTO chargers (
 			id, name, vendor, model, serial_number, firmware_version, 
-			iccid, imsi, status, is_connected, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'Unknown', 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			iccid, imsi, status, is_connected, num_connectors, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'Unknown', 0, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, name, vendor, model, serial_number, firmware_version, 
 				  iccid, imsi, status, is_connected, 
 				  last_heartbeat_at, last_boot_at, last_connect_at, 
-				  last_tx_start_at, last_tx_stop_at, created_at, updated_at`
+				  last_tx_start_at, last_tx_stop_at, notes, num_connectors, created_at, updated_at`
 
 	var charger Charger
 	err := r.db.QueryRowContext(ctx, query,
 		req.ID, req.Name, req.Vendor, req.Model, req.SerialNumber,
-		req.FirmwareVersion, req.ICCID, req.IMSI,
+		req.FirmwareVersion, req.ICCID, req.IMSI, req.NumConnectors,
 	).Scan(
 		&charger.ID, &charger.Name, &charger.Vendor, &charger.Model,
 		&charger.SerialNumber, &charger.FirmwareVersion, &charger.ICCID,
 		&charger.IMSI, &charger.Status, &charger.IsConnected,
 		&charger.LastHeartbeatAt, &charger.LastBootAt, &charger.LastConnectAt,
-		&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.CreatedAt, &charger.UpdatedAt,
+		&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.Notes, &charger.NumConnectors, &charger.CreatedAt, &charger.UpdatedAt,
 	)
 
 	if err != nil {
@@ -69,7 +73,7 @@ func (r *chargerRepository) GetByID(ctx context.Context, id string) (*Charger, e
 		SELECT id, name, vendor, model, serial_number, firmware_version, 
 			   iccid, imsi, status, is_connected, 
 			   last_heartbeat_at, last_boot_at, last_connect_at, 
-			   last_tx_start_at, last_tx_stop_at, created_at, updated_at
+			   last_tx_start_at, last_tx_stop_at, notes, num_connectors, created_at, updated_at
 		FROM chargers WHERE id = ?`
 
 	var charger Charger
@@ -78,12 +82,12 @@ func (r *chargerRepository) GetByID(ctx context.Context, id string) (*Charger, e
 		&charger.SerialNumber, &charger.FirmwareVersion, &charger.ICCID,
 		&charger.IMSI, &charger.Status, &charger.IsConnected,
 		&charger.LastHeartbeatAt, &charger.LastBootAt, &charger.LastConnectAt,
-		&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.CreatedAt, &charger.UpdatedAt,
+		&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.Notes, &charger.NumConnectors, &charger.CreatedAt, &charger.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("charger not found: %s", id)
+			return nil, fmt.Errorf("%w: %s", ErrChargerNotFound, id)
 		}
 		r.logger.Error("Failed to get charger", "charger_id", id, "error", err)
 		return nil, fmt.Errorf("failed to get charger: %w", err)
@@ -138,6 +142,14 @@ func (r *chargerRepository) Update(ctx context.Context, id string, req UpdateCha
 		setParts = append(setParts, "is_connected = ?")
 		args = append(args, connected)
 	}
+	if req.Notes != nil {
+		setParts = append(setParts, "notes = ?")
+		args = append(args, *req.Notes)
+	}
+	if req.NumConnectors != nil {
+		setParts = append(setParts, "num_connectors = ?")
+		args = append(args, *req.NumConnectors)
+	}
 
 	if len(setParts) == 0 {
 		return r.GetByID(ctx, id) // No updates, return current state
@@ -152,7 +164,7 @@ func (r *chargerRepository) Update(ctx context.Context, id string, req UpdateCha
 		RETURNING id, name, vendor, model, serial_number, firmware_version, 
 				  iccid, imsi, status, is_connected, 
 				  last_heartbeat_at, last_boot_at, last_connect_at, 
-				  last_tx_start_at, last_tx_stop_at, created_at, updated_at`,
+				  last_tx_start_at, last_tx_stop_at, notes, num_connectors, created_at, updated_at`,
 		strings.Join(setParts, ", "))
 
 	var charger Charger
@@ -161,7 +173,7 @@ func (r *chargerRepository) Update(ctx context.Context, id string, req UpdateCha
 		&charger.SerialNumber, &charger.FirmwareVersion, &charger.ICCID,
 		&charger.IMSI, &charger.Status, &charger.IsConnected,
 		&charger.LastHeartbeatAt, &charger.LastBootAt, &charger.LastConnectAt,
-		&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.CreatedAt, &charger.UpdatedAt,
+		&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.Notes, &charger.NumConnectors, &charger.CreatedAt, &charger.UpdatedAt,
 	)
 
 	if err != nil {
@@ -201,58 +213,46 @@ func (r *chargerRepository) Delete(ctx context.Context, id string) error {
 
 // List implements ChargerRepository.List
 func (r *chargerRepository) List(ctx context.Context, opts ListOptions) ([]*Charger, error) {
-	opts.ValidateSortDirection()
-
-	// Validate order by field for security
-	validOrderFields := map[string]bool{
-		"id": true, "name": true, "vendor": true, "model": true, "status": true,
-		"is_connected": true, "created_at": true, "updated_at": true,
-		"last_heartbeat_at": true, "last_boot_at": true, "last_connect_at": true,
-	}
-
-	if !validOrderFields[opts.OrderBy] {
-		opts.OrderBy = "created_at"
-	}
-
-	query := fmt.Sprintf(`
+	query := `
 		SELECT id, name, vendor, model, serial_number, firmware_version, 
 			   iccid, imsi, status, is_connected, 
 			   last_heartbeat_at, last_boot_at, last_connect_at, 
-			   last_tx_start_at, last_tx_stop_at, created_at, updated_at
-		FROM chargers 
-		ORDER BY %s %s 
-		LIMIT ? OFFSET ?`, opts.OrderBy, opts.SortDir)
+			   last_tx_start_at, last_tx_stop_at, notes, num_connectors, created_at, updated_at
+		FROM chargers`
 
-	rows, err := r.db.QueryContext(ctx, query, opts.Limit, opts.Offset)
+	chargers, err := listQuery(ctx, r.db, query, nil, chargerOrder, opts, scanCharger)
 	if err != nil {
 		r.logger.Error("Failed to list chargers", "error", err)
 		return nil, fmt.Errorf("failed to list chargers: %w", err)
 	}
-	defer rows.Close()
 
-	var chargers []*Charger
-	for rows.Next() {
-		var charger Charger
-		err := rows.Scan(
-			&charger.ID, &charger.Name, &charger.Vendor, &charger.Model,
-			&charger.SerialNumber, &charger.FirmwareVersion, &charger.ICCID,
-			&charger.IMSI, &charger.Status, &charger.IsConnected,
-			&charger.LastHeartbeatAt, &charger.LastBootAt, &charger.LastConnectAt,
-			&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.CreatedAt, &charger.UpdatedAt,
-		)
-		if err != nil {
-			r.logger.Error("Failed to scan charger row", "error", err)
-			return nil, fmt.Errorf("failed to scan charger: %w", err)
-		}
-		chargers = append(chargers, &charger)
-	}
+	return chargers, nil
+}
 
-	if err = rows.Err(); err != nil {
-		r.logger.Error("Row iteration error", "error", err)
-		return nil, fmt.Errorf("row iteration error: %w", err)
-	}
+// chargerOrder is the ordering accepted by chargerRepository.List
+var chargerOrder = listOrder{
+	fields: map[string]bool{
+		"id": true, "name": true, "vendor": true, "model": true, "status": true,
+		"is_connected": true, "created_at": true, "updated_at": true,
+		"last_heartbeat_at": true, "last_boot_at": true, "last_connect_at": true,
+	},
+	fallback: "created_at",
+}
 
-	return chargers, nil
+// scanCharger scans a chargers row selected with the List columns
+func scanCharger(row rowScanner) (*Charger, error) {
+	var charger Charger
+	err := row.Scan(
+		&charger.ID, &charger.Name, &charger.Vendor, &charger.Model,
+		&charger.SerialNumber, &charger.FirmwareVersion, &charger.ICCID,
+		&charger.IMSI, &charger.Status, &charger.IsConnected,
+		&charger.LastHeartbeatAt, &charger.LastBootAt, &charger.LastConnectAt,
+		&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.Notes, &charger.NumConnectors, &charger.CreatedAt, &charger.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &charger, nil
 }
 
 // Count implements ChargerRepository.Count
@@ -339,7 +339,7 @@ func (r *chargerRepository) UpdateLastHeartbeat(ctx context.Context, id string,
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("charger not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrChargerNotFound, id)
 	}
 
 	return nil
@@ -439,7 +439,7 @@ func (r *chargerRepository) GetConnected(ctx context.Context) ([]*Charger, error
 		SELECT id, name, vendor, model, serial_number, firmware_version, 
 			   iccid, imsi, status, is_connected, 
 			   last_heartbeat_at, last_boot_at, last_connect_at, 
-			   last_tx_start_at, last_tx_stop_at, created_at, updated_at
+			   last_tx_start_at, last_tx_stop_at, notes, num_connectors, created_at, updated_at
 		FROM chargers WHERE is_connected = 1 
 		ORDER BY last_connect_at DESC`
 
@@ -458,7 +458,7 @@ func (r *chargerRepository) GetConnected(ctx context.Context) ([]*Charger, error
 			&charger.SerialNumber, &charger.FirmwareVersion, &charger.ICCID,
 			&charger.IMSI, &charger.Status, &charger.IsConnected,
 			&charger.LastHeartbeatAt, &charger.LastBootAt, &charger.LastConnectAt,
-			&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.CreatedAt, &charger.UpdatedAt,
+			&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.Notes, &charger.NumConnectors, &charger.CreatedAt, &charger.UpdatedAt,
 		)
 		if err != nil {
 			r.logger.Error("Failed to scan charger row", "error", err)
@@ -481,7 +481,7 @@ func (r *chargerRepository) GetByStatus(ctx context.Context, status string) ([]*
 		SELECT id, name, vendor, model, serial_number, firmware_version, 
 			   iccid, imsi, status, is_connected, 
 			   last_heartbeat_at, last_boot_at, last_connect_at, 
-			   last_tx_start_at, last_tx_stop_at, created_at, updated_at
+			   last_tx_start_at, last_tx_stop_at, notes, num_connectors, created_at, updated_at
 		FROM chargers WHERE status = ? 
 		ORDER BY updated_at DESC`
 
@@ -500,7 +500,7 @@ func (r *chargerRepository) GetByStatus(ctx context.Context, status string) ([]*
 			&charger.SerialNumber, &charger.FirmwareVersion, &charger.ICCID,
 			&charger.IMSI, &charger.Status, &charger.IsConnected,
 			&charger.LastHeartbeatAt, &charger.LastBootAt, &charger.LastConnectAt,
-			&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.CreatedAt, &charger.UpdatedAt,
+			&charger.LastTxStartAt, &charger.LastTxStopAt, &charger.Notes, &charger.NumConnectors, &charger.CreatedAt, &charger.UpdatedAt,
 		)
 		if err != nil {
 			r.logger.Error("Failed to scan charger row", "error", err)
@@ -516,3 +516,36 @@ func (r *chargerRepository) GetByStatus(ctx context.Context, status string) ([]*
 
 	return chargers, nil
 }
+
+// GetLocalListVersion implements ChargerRepository.GetLocalListVersion
+func (r *chargerRepository) GetLocalListVersion(ctx context.Context, id string) (int, error) {
+	query := `SELECT list_version FROM charger_local_lists WHERE charger_id = ?`
+
+	var version int
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil // Never synced
+		}
+		r.logger.Error("Failed to get local list version", "charger_id", id, "error", err)
+		return 0, fmt.Errorf("failed to get local list version: %w", err)
+	}
+
+	return version, nil
+}
+
+// SetLocalListVersion implements ChargerRepository.SetLocalListVersion
+func (r *chargerRepository) SetLocalListVersion(ctx context.Context, id string, version int, syncedAt time.Time) error {
+	query := `
+		INSERT INTO charger_local_lists (charger_id, list_version, synced_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (charger_id) DO UPDATE SET list_version = excluded.list_version, synced_at = excluded.synced_at`
+
+	if _, err := r.db.ExecContext(ctx, query, id, version, syncedAt.UTC()); err != nil {
+		r.logger.Error("Failed to set local list version", "charger_id", id, "version", version, "error", err)
+		return fmt.Errorf("failed to set local list version: %w", err)
+	}
+
+	r.logger.Debug("Set local list version", "charger_id", id, "version", version)
+	return nil
+}
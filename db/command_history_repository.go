@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/keeth/levity/core/clock"
+)
+
+// commandHistoryRepository implements CommandHistoryRepository
+type commandHistoryRepository struct {
+	db     Executor
+	logger Logger
+	clock  clock.Clock
+}
+
+// NewCommandHistoryRepository creates a new command history repository
+func NewCommandHistoryRepository(db Executor, logger Logger, clk clock.Clock) CommandHistoryRepository {
+	return &commandHistoryRepository{
+		db:     db,
+		logger: logger,
+		clock:  clk,
+	}
+}
+
+// Record implements CommandHistoryRepository.Record
+func (r *commandHistoryRepository) Record(ctx context.Context, req CreateCommandHistoryRequest) (*CommandHistoryEntry, error) {
+	operator := req.Operator
+	if operator == "" {
+		operator = ActorFromContext(ctx)
+	}
+
+	params := string(req.Params)
+	if params == "" {
+		params = "{}"
+	}
+
+	query := `
+		INSERT INTO command_history (charger_id, action, params, status, error, latency_ms, operator, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, charger_id, action, params, status, error, latency_ms, operator, created_at`
+
+	entry, err := scanCommandHistoryEntry(r.db.QueryRowContext(ctx, query,
+		req.ChargerID, req.Action, params, req.Status, req.Error, req.Latency.Milliseconds(), operator, r.clock.Now().UTC(),
+	))
+	if err != nil {
+		r.logger.Error("Failed to record command", "charger_id", req.ChargerID, "action", req.Action, "error", err)
+		return nil, fmt.Errorf("failed to record command: %w", err)
+	}
+
+	return entry, nil
+}
+
+// ListByCharger implements CommandHistoryRepository.ListByCharger
+func (r *commandHistoryRepository) ListByCharger(ctx context.Context, chargerID string, opts ListOptions) ([]*CommandHistoryEntry, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListOptions().Limit
+	}
+
+	query := `
+		SELECT id, charger_id, action, params, status, error, latency_ms, operator, created_at
+		FROM command_history WHERE charger_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryContext(ctx, query, chargerID, limit, opts.Offset)
+	if err != nil {
+		r.logger.Error("Failed to list commands", "charger_id", chargerID, "error", err)
+		return nil, fmt.Errorf("failed to list commands: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*CommandHistoryEntry
+	for rows.Next() {
+		entry, err := scanCommandHistoryEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan command: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return entries, nil
+}
+
+// scanCommandHistoryEntry scans a command_history row, keeping the params as raw JSON
+func scanCommandHistoryEntry(row rowScanner) (*CommandHistoryEntry, error) {
+	var entry CommandHistoryEntry
+	var params string
+	err := row.Scan(
+		&entry.ID, &entry.ChargerID, &entry.Action, &params, &entry.Status, &entry.Error,
+		&entry.LatencyMS, &entry.Operator, &entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	entry.Params = json.RawMessage(params)
+	return &entry, nil
+}
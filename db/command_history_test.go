@@ -0,0 +1,70 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandHistoryRecordAndList(t *testing.T) {
+	ctx := db.WithActor(context.Background(), "operator:1a2b3c4d")
+	fake := clock.NewFake(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	for _, id := range []string{"CP-1", "CP-2"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+	}
+
+	_, err := repos.Commands().Record(ctx, db.CreateCommandHistoryRequest{
+		ChargerID: "CP-1",
+		Action:    "ChangeAvailability",
+		Params:    []byte(`{"connectorId":0,"type":"Inoperative"}`),
+		Status:    "Accepted",
+		Latency:   250 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	fake.Advance(time.Minute)
+	_, err = repos.Commands().Record(ctx, db.CreateCommandHistoryRequest{
+		ChargerID: "CP-1",
+		Action:    "SendLocalList",
+		Status:    db.CommandStatusError,
+		Error:     "charger is not connected",
+		Operator:  db.ActorSystem,
+	})
+	require.NoError(t, err)
+
+	_, err = repos.Commands().Record(ctx, db.CreateCommandHistoryRequest{ChargerID: "CP-2", Action: "SendLocalList", Status: "Accepted"})
+	require.NoError(t, err)
+
+	entries, err := repos.Commands().ListByCharger(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	latest := entries[0]
+	assert.Equal(t, "SendLocalList", latest.Action)
+	assert.Equal(t, db.CommandStatusError, latest.Status)
+	assert.Equal(t, "charger is not connected", latest.Error)
+	assert.Equal(t, db.ActorSystem, latest.Operator)
+	assert.JSONEq(t, `{}`, string(latest.Params))
+	assert.True(t, fake.Now().Equal(latest.CreatedAt))
+
+	first := entries[1]
+	assert.Equal(t, "ChangeAvailability", first.Action)
+	assert.Equal(t, "Accepted", first.Status)
+	assert.Equal(t, int64(250), first.LatencyMS)
+	assert.Equal(t, "operator:1a2b3c4d", first.Operator)
+	assert.JSONEq(t, `{"connectorId":0,"type":"Inoperative"}`, string(first.Params))
+
+	paged, err := repos.Commands().ListByCharger(ctx, "CP-1", db.ListOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, paged, 1)
+	assert.Equal(t, first.ID, paged[0].ID)
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// chargerConnectionRepository implements ChargerConnectionRepository
+type chargerConnectionRepository struct {
+	db     Executor
+	logger Logger
+}
+
+// NewChargerConnectionRepository creates a new charger connection repository
+func NewChargerConnectionRepository(db Executor, logger Logger) ChargerConnectionRepository {
+	return &chargerConnectionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Open implements ChargerConnectionRepository.Open
+func (r *chargerConnectionRepository) Open(ctx context.Context, req CreateChargerConnectionRequest) (*ChargerConnection, error) {
+	query := `
+		INSERT INTO charger_connections (charger_id, remote_ip, subprotocol, connected_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, charger_id, remote_ip, subprotocol, connected_at, disconnected_at, disconnect_reason, duration_seconds`
+
+	conn, err := scanChargerConnection(r.db.QueryRowContext(ctx, query,
+		req.ChargerID, req.RemoteIP, req.Subprotocol, req.ConnectedAt.UTC(),
+	))
+	if err != nil {
+		r.logger.Error("Failed to record connection", "charger_id", req.ChargerID, "error", err)
+		return nil, fmt.Errorf("failed to record connection: %w", err)
+	}
+
+	return conn, nil
+}
+
+// Close implements ChargerConnectionRepository.Close. Closing a connection
+// that is already closed is a no-op.
+func (r *chargerConnectionRepository) Close(ctx context.Context, id int, disconnectedAt time.Time, reason string) error {
+	var connectedAt time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT connected_at FROM charger_connections WHERE id = ?`, id).Scan(&connectedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("connection not found: %d", id)
+		}
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	duration := disconnectedAt.Sub(connectedAt)
+	if duration < 0 {
+		duration = 0
+	}
+
+	query := `
+		UPDATE charger_connections
+		SET disconnected_at = ?, disconnect_reason = ?, duration_seconds = ?
+		WHERE id = ? AND disconnected_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, disconnectedAt.UTC(), reason, int64(duration/time.Second), id); err != nil {
+		r.logger.Error("Failed to close connection", "id", id, "error", err)
+		return fmt.Errorf("failed to close connection: %w", err)
+	}
+
+	return nil
+}
+
+// ListByCharger implements ChargerConnectionRepository.ListByCharger
+func (r *chargerConnectionRepository) ListByCharger(ctx context.Context, chargerID string, opts ListOptions) ([]*ChargerConnection, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListOptions().Limit
+	}
+
+	query := `
+		SELECT id, charger_id, remote_ip, subprotocol, connected_at, disconnected_at, disconnect_reason, duration_seconds
+		FROM charger_connections WHERE charger_id = ?
+		ORDER BY connected_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryContext(ctx, query, chargerID, limit, opts.Offset)
+	if err != nil {
+		r.logger.Error("Failed to list connections", "charger_id", chargerID, "error", err)
+		return nil, fmt.Errorf("failed to list connections: %w", err)
+	}
+	defer rows.Close()
+
+	var conns []*ChargerConnection
+	for rows.Next() {
+		conn, err := scanChargerConnection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan connection: %w", err)
+		}
+		conns = append(conns, conn)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return conns, nil
+}
+
+// CountDisconnects implements ChargerConnectionRepository.CountDisconnects
+func (r *chargerConnectionRepository) CountDisconnects(ctx context.Context, chargerID string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM charger_connections WHERE charger_id = ? AND disconnected_at >= ?`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, chargerID, since.UTC()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count disconnects: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteOlderThan implements ChargerConnectionRepository.DeleteOlderThan
+func (r *chargerConnectionRepository) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	query := `DELETE FROM charger_connections WHERE disconnected_at IS NOT NULL AND disconnected_at < ?`
+	result, err := r.db.ExecContext(ctx, query, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old connections: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return int(rowsAffected), nil
+}
+
+// scanChargerConnection scans a charger_connections row
+func scanChargerConnection(row rowScanner) (*ChargerConnection, error) {
+	var conn ChargerConnection
+	err := row.Scan(
+		&conn.ID, &conn.ChargerID, &conn.RemoteIP, &conn.Subprotocol, &conn.ConnectedAt,
+		&conn.DisconnectedAt, &conn.DisconnectReason, &conn.DurationSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
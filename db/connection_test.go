@@ -0,0 +1,70 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChargerConnectionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+	repos := dbtest.NewRepositories(t, clock.NewFake(start))
+	connections := repos.Connections()
+
+	// Unregistered chargers are recorded too
+	first, err := connections.Open(ctx, db.CreateChargerConnectionRequest{
+		ChargerID:   "CP-1",
+		RemoteIP:    "10.0.0.7",
+		Subprotocol: "ocpp1.6",
+		ConnectedAt: start,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, first.DisconnectedAt)
+	assert.Nil(t, first.DurationSeconds)
+
+	require.NoError(t, connections.Close(ctx, first.ID, start.Add(90*time.Second), "abnormal"))
+	// A second close keeps the first disconnect
+	require.NoError(t, connections.Close(ctx, first.ID, start.Add(time.Hour), "clean"))
+
+	second, err := connections.Open(ctx, db.CreateChargerConnectionRequest{ChargerID: "CP-1", ConnectedAt: start.Add(2 * time.Minute)})
+	require.NoError(t, err)
+
+	conns, err := connections.ListByCharger(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, conns, 2)
+	assert.Equal(t, second.ID, conns[0].ID)
+	assert.Nil(t, conns[0].DisconnectedAt)
+
+	closed := conns[1]
+	assert.Equal(t, "10.0.0.7", closed.RemoteIP)
+	assert.Equal(t, "ocpp1.6", closed.Subprotocol)
+	assert.Equal(t, "abnormal", closed.DisconnectReason)
+	require.NotNil(t, closed.DisconnectedAt)
+	assert.True(t, start.Add(90*time.Second).Equal(*closed.DisconnectedAt))
+	require.NotNil(t, closed.DurationSeconds)
+	assert.Equal(t, int64(90), *closed.DurationSeconds)
+
+	count, err := connections.CountDisconnects(ctx, "CP-1", start)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = connections.CountDisconnects(ctx, "CP-1", start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// Retention removes closed connections only
+	deleted, err := connections.DeleteOlderThan(ctx, start.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	conns, err = connections.ListByCharger(ctx, "CP-1", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, conns, 1)
+	assert.Equal(t, second.ID, conns[0].ID)
+}
@@ -1,23 +1,46 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/keeth/levity/config"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/keeth/levity/sql/migrations"
+	sqlitedriver "github.com/mattn/go-sqlite3"
 )
 
+// ErrNoMigrations is returned when the migration source contains no up migrations
+var ErrNoMigrations = errors.New("no migrations found; did you forget to embed sql/migrations?")
+
+// ErrSchemaOutdated is returned by VerifySchema when the database has not been
+// migrated to the latest embedded migration
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
 // Database represents the database connection and operations
 type Database struct {
-	db     *sql.DB
-	config config.DatabaseConfig
-	logger *slog.Logger
+	db         *sql.DB
+	dsn        string
+	config     config.DatabaseConfig
+	migrations fs.FS
+	logger     *slog.Logger
+	// faultObserver is told about storage faults; nil ignores them
+	faultObserver StorageFaultObserver
+	// recoverable is set after a full disk or I/O error until a write succeeds
+	recoverable atomic.Bool
 }
 
 // NewDatabase creates a new database connection with SQLite optimizations
@@ -27,10 +50,12 @@ func NewDatabase(cfg config.DatabaseConfig, logger *slog.Logger) (*Database, err
 	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=10000&_foreign_keys=ON&_temp_store=MEMORY&_busy_timeout=30000", cfg.Path)
 
 	// Open SQLite database
-	db, err := sql.Open("sqlite3", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
+	db := sql.OpenDB(&connector{
+		driver:       &sqlitedriver.SQLiteDriver{},
+		dsn:          dsn,
+		pragmas:      connectionPragmas(cfg),
+		pingOnBorrow: cfg.PingOnBorrow,
+	})
 
 	// Configure connection pool for SQLite optimization
 	// SQLite with WAL mode can handle more concurrent readers
@@ -53,16 +78,18 @@ func NewDatabase(cfg config.DatabaseConfig, logger *slog.Logger) (*Database, err
 	db.SetMaxIdleConns(maxIdleConns)
 	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	// Test connection
-	if err := db.Ping(); err != nil {
+	// Open the idle connections up front so the first queries do not pay for them
+	if err := warmup(db, maxIdleConns); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	database := &Database{
-		db:     db,
-		config: cfg,
-		logger: logger,
+		db:         db,
+		dsn:        dsn,
+		config:     cfg,
+		migrations: migrations.FS,
+		logger:     logger,
 	}
 
 	// Apply additional SQLite performance pragmas
@@ -76,23 +103,107 @@ func NewDatabase(cfg config.DatabaseConfig, logger *slog.Logger) (*Database, err
 		slog.String("path", cfg.Path),
 		slog.Int("max_open_conns", maxOpenConns),
 		slog.Int("max_idle_conns", maxIdleConns),
-		slog.Duration("conn_max_lifetime", cfg.ConnMaxLifetime))
+		slog.Duration("conn_max_lifetime", cfg.ConnMaxLifetime),
+		slog.Bool("ping_on_borrow", cfg.PingOnBorrow))
 
 	return database, nil
 }
 
-// applyPerformanceSettings applies SQLite-specific performance optimizations
-func (d *Database) applyPerformanceSettings() error {
-	pragmas := []string{
-		// WAL mode checkpoint settings for better performance
-		"PRAGMA wal_autocheckpoint = 1000", // Checkpoint every 1000 pages
+// warmup opens and pings n connections, then returns them to the pool as idle
+func warmup(db *sql.DB, n int) error {
+	ctx := context.Background()
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
 
-		// Memory and performance optimizations
+		if err := conn.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connectionPragmas returns the pragmas that only affect the connection they
+// run on, so each pooled connection runs them when it is opened
+func connectionPragmas(cfg config.DatabaseConfig) []string {
+	// Checkpoint every 1000 pages, unless a timer runs passive checkpoints instead
+	autocheckpoint := "PRAGMA wal_autocheckpoint = 1000"
+	if cfg.CheckpointInterval > 0 {
+		autocheckpoint = "PRAGMA wal_autocheckpoint = 0"
+	}
+
+	return []string{
+		autocheckpoint,
 		"PRAGMA mmap_size = 268435456", // 256MB memory mapped I/O
-		"PRAGMA optimize",              // Analyze and optimize query planner
+		"PRAGMA trusted_schema = OFF",  // Security: don't trust schema
+	}
+}
+
+// connector opens SQLite connections for the pool
+type connector struct {
+	driver       *sqlitedriver.SQLiteDriver
+	dsn          string
+	pragmas      []string
+	pingOnBorrow bool
+}
+
+// Connect implements driver.Connector.Connect, running the connection
+// pragmas on each new connection
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	sqliteConn := conn.(*sqlitedriver.SQLiteConn)
+
+	for _, pragma := range c.pragmas {
+		if _, err := sqliteConn.Exec(pragma, nil); err != nil {
+			sqliteConn.Close()
+			return nil, fmt.Errorf("failed to apply %q: %w", pragma, err)
+		}
+	}
+
+	if !c.pingOnBorrow {
+		return sqliteConn, nil
+	}
+	return &pingingConn{SQLiteConn: sqliteConn}, nil
+}
+
+// Driver implements driver.Connector.Driver
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// pingingConn validates a pooled connection each time it is borrowed, so a
+// connection that went bad while idle is replaced instead of failing a query
+type pingingConn struct {
+	*sqlitedriver.SQLiteConn
+}
+
+// ResetSession implements driver.SessionResetter; database/sql calls it
+// before reusing a pooled connection
+func (c *pingingConn) ResetSession(ctx context.Context) error {
+	if err := c.Ping(ctx); err != nil {
+		return driver.ErrBadConn
+	}
+	return nil
+}
 
-		// Additional safety and performance settings
-		"PRAGMA trusted_schema = OFF", // Security: don't trust schema
+// applyPerformanceSettings applies SQLite-specific performance optimizations
+// to the database as a whole; per-connection pragmas run in connector.Connect
+func (d *Database) applyPerformanceSettings() error {
+	pragmas := []string{
+		"PRAGMA optimize", // Analyze and optimize query planner
 	}
 
 	for _, pragma := range pragmas {
@@ -221,6 +332,43 @@ func (d *Database) WALCheckpoint() error {
 	return nil
 }
 
+// WALCheckpointResult is the outcome of a WAL checkpoint
+type WALCheckpointResult struct {
+	// Busy is set when a reader or writer kept the checkpoint from completing
+	Busy bool
+	// LogFrames is the number of frames in the WAL
+	LogFrames int
+	// CheckpointedFrames is the number of those frames copied into the database
+	CheckpointedFrames int
+}
+
+// PassiveWALCheckpoint copies as much of the WAL into the database as it can
+// without waiting for readers or writers. Once every frame is checkpointed the
+// next writer starts the WAL over from the beginning.
+func (d *Database) PassiveWALCheckpoint(ctx context.Context) (WALCheckpointResult, error) {
+	var result WALCheckpointResult
+	var busy int
+	err := d.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)").Scan(
+		&busy, &result.LogFrames, &result.CheckpointedFrames)
+	if err != nil {
+		return result, fmt.Errorf("WAL checkpoint failed: %w", err)
+	}
+	result.Busy = busy != 0
+	return result, nil
+}
+
+// WALSize returns the size of the WAL file in bytes, or 0 if there is none
+func (d *Database) WALSize() (int64, error) {
+	info, err := os.Stat(d.config.Path + "-wal")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat WAL: %w", err)
+	}
+	return info.Size(), nil
+}
+
 // Close closes the database connection with graceful shutdown
 func (d *Database) Close() error {
 	if d.db != nil {
@@ -247,6 +395,69 @@ type MigrationResult struct {
 	Error       error
 }
 
+// MigrationError describes a migration that failed part way. The database is
+// left dirty at Version until the version is forced after fixing the cause.
+type MigrationError struct {
+	Version   uint
+	Statement string
+	Err       error
+}
+
+func (e *MigrationError) Error() string {
+	if e.Statement != "" {
+		return fmt.Sprintf("migration %d failed in statement %q: %v", e.Version, e.Statement, e.Err)
+	}
+	return fmt.Sprintf("migration %d failed: %v", e.Version, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// SetMigrationSource replaces the embedded migrations with the given file system
+func (d *Database) SetMigrationSource(fsys fs.FS) {
+	d.migrations = fsys
+}
+
+// newMigrate creates a migrate instance reading the migration files
+func (d *Database) newMigrate() (*migrate.Migrate, error) {
+	ups, err := fs.Glob(d.migrations, "*.up.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	if len(ups) == 0 {
+		return nil, ErrNoMigrations
+	}
+
+	source, err := iofs.New(d.migrations, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migration source: %w", err)
+	}
+
+	// Use a dedicated handle: closing the migrate instance also closes the
+	// driver's database, which must not take the shared pool down with it
+	migrationDB, err := sql.Open("sqlite3", d.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migration database: %w", err)
+	}
+
+	// Create sqlite3 driver instance
+	driver, err := sqlite3.WithInstance(migrationDB, &sqlite3.Config{})
+	if err != nil {
+		migrationDB.Close()
+		return nil, fmt.Errorf("failed to create sqlite3 driver: %w", err)
+	}
+
+	// Create migrate instance
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite3", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	return m, nil
+}
+
 // RunMigrations runs database migrations up to the latest version
 func (d *Database) RunMigrations() error {
 	return d.RunMigrationsWithCallback(nil)
@@ -256,20 +467,9 @@ func (d *Database) RunMigrations() error {
 func (d *Database) RunMigrationsWithCallback(callback func(MigrationResult)) error {
 	d.logger.Info("Running database migrations...")
 
-	// Create sqlite3 driver instance
-	driver, err := sqlite3.WithInstance(d.db, &sqlite3.Config{})
+	m, err := d.newMigrate()
 	if err != nil {
-		return fmt.Errorf("failed to create sqlite3 driver: %w", err)
-	}
-
-	// Create migrate instance
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://sql/migrations",
-		"sqlite3",
-		driver,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		return err
 	}
 	defer m.Close()
 
@@ -301,7 +501,17 @@ func (d *Database) RunMigrationsWithCallback(callback func(MigrationResult)) err
 	// Run migrations
 	err = m.Up()
 	if err != nil && err != migrate.ErrNoChange {
+		err = d.migrationError(m, err)
+
 		// Log specific migration error details
+		var migrationErr *MigrationError
+		if errors.As(err, &migrationErr) {
+			d.logger.Error("Migration failed, database left dirty",
+				slog.Uint64("version", uint64(migrationErr.Version)),
+				slog.String("statement", migrationErr.Statement),
+				slog.Any("error", migrationErr.Err),
+				slog.String("hint", d.forceHint(migrationErr.Version)))
+		}
 		if callback != nil {
 			callback(MigrationResult{
 				FromVersion: currentVersion,
@@ -345,24 +555,62 @@ func (d *Database) RunMigrationsWithCallback(callback func(MigrationResult)) err
 	return nil
 }
 
-// MigrateDown rolls back database migrations by N steps
-func (d *Database) MigrateDown(steps int) error {
-	d.logger.Info("Rolling back database migrations", slog.Int("steps", steps))
+// migrationError adds the failing version and statement to an error from
+// m.Up. The version is read back from the dirty state the failure left behind.
+func (d *Database) migrationError(m *migrate.Migrate, err error) error {
+	version, dirty, versionErr := m.Version()
+	if versionErr != nil || !dirty {
+		return err
+	}
 
-	// Create sqlite3 driver instance
-	driver, err := sqlite3.WithInstance(d.db, &sqlite3.Config{})
+	migrationErr := &MigrationError{Version: version, Err: err}
+	var dbErr *database.Error
+	if errors.As(err, &dbErr) {
+		migrationErr.Statement = strings.TrimSpace(string(dbErr.Query))
+		migrationErr.Err = dbErr.OrigErr
+	}
+	return migrationErr
+}
+
+// forceHint tells the operator how to recover from a failed migration. SQLite
+// migrations run in a transaction, so the schema is still at the version
+// before the failing one.
+func (d *Database) forceHint(version uint) string {
+	previous, err := d.previousMigrationVersion(version)
+	if err != nil || previous < 0 {
+		return "fix the migration, then recreate the database"
+	}
+	return fmt.Sprintf("run --migrate-force %d after fixing the migration, then --migrate-up", previous)
+}
+
+// previousMigrationVersion returns the version of the up migration before
+// version, or -1 when version is the first
+func (d *Database) previousMigrationVersion(version uint) (int, error) {
+	ups, err := fs.Glob(d.migrations, "*.up.sql")
 	if err != nil {
-		return fmt.Errorf("failed to create sqlite3 driver: %w", err)
+		return -1, err
 	}
 
-	// Create migrate instance
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://sql/migrations",
-		"sqlite3",
-		driver,
-	)
+	previous := -1
+	for _, name := range ups {
+		migration, err := source.Parse(name)
+		if err != nil {
+			continue
+		}
+		if migration.Version < version && int(migration.Version) > previous {
+			previous = int(migration.Version)
+		}
+	}
+	return previous, nil
+}
+
+// MigrateDown rolls back database migrations by N steps
+func (d *Database) MigrateDown(steps int) error {
+	d.logger.Info("Rolling back database migrations", slog.Int("steps", steps))
+
+	m, err := d.newMigrate()
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		return err
 	}
 	defer m.Close()
 
@@ -407,20 +655,9 @@ func (d *Database) MigrateDown(steps int) error {
 
 // GetMigrationVersion returns the current migration version
 func (d *Database) GetMigrationVersion() (uint, bool, error) {
-	// Create sqlite3 driver instance
-	driver, err := sqlite3.WithInstance(d.db, &sqlite3.Config{})
-	if err != nil {
-		return 0, false, fmt.Errorf("failed to create sqlite3 driver: %w", err)
-	}
-
-	// Create migrate instance
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://sql/migrations",
-		"sqlite3",
-		driver,
-	)
+	m, err := d.newMigrate()
 	if err != nil {
-		return 0, false, fmt.Errorf("failed to create migrate instance: %w", err)
+		return 0, false, err
 	}
 	defer m.Close()
 
@@ -435,24 +672,63 @@ func (d *Database) GetMigrationVersion() (uint, bool, error) {
 	return version, dirty, nil
 }
 
-// ForceMigrationVersion forces the migration version (use with caution)
-func (d *Database) ForceMigrationVersion(version int) error {
-	d.logger.Warn("Forcing migration version", slog.Int("version", version))
+// LatestMigrationVersion returns the version of the newest up migration
+func (d *Database) LatestMigrationVersion() (uint, error) {
+	ups, err := fs.Glob(d.migrations, "*.up.sql")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list migrations: %w", err)
+	}
 
-	// Create sqlite3 driver instance
-	driver, err := sqlite3.WithInstance(d.db, &sqlite3.Config{})
+	var latest uint
+	for _, name := range ups {
+		migration, err := source.Parse(name)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, migration.Version)
+	}
+	if latest == 0 {
+		return 0, ErrNoMigrations
+	}
+	return latest, nil
+}
+
+// VerifySchema checks, without migrating, that the database is at the latest
+// embedded migration. A behind or dirty schema returns ErrSchemaOutdated; a
+// schema ahead of this build, as during a rolling upgrade, is only logged.
+func (d *Database) VerifySchema() error {
+	latest, err := d.LatestMigrationVersion()
 	if err != nil {
-		return fmt.Errorf("failed to create sqlite3 driver: %w", err)
+		return err
 	}
 
-	// Create migrate instance
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://sql/migrations",
-		"sqlite3",
-		driver,
-	)
+	version, dirty, err := d.GetMigrationVersion()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w: migration %d did not complete", ErrSchemaOutdated, version)
+	}
+	if version < latest {
+		return fmt.Errorf("%w: at version %d, expected %d; run --migrate-up", ErrSchemaOutdated, version, latest)
+	}
+	if version > latest {
+		d.logger.Warn("Database schema is newer than this build",
+			slog.Uint64("version", uint64(version)),
+			slog.Uint64("latest", uint64(latest)))
+	}
+
+	d.logger.Info("Database schema is current", slog.Uint64("version", uint64(version)))
+	return nil
+}
+
+// ForceMigrationVersion forces the migration version (use with caution)
+func (d *Database) ForceMigrationVersion(version int) error {
+	d.logger.Warn("Forcing migration version", slog.Int("version", version))
+
+	m, err := d.newMigrate()
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		return err
 	}
 	defer m.Close()
 
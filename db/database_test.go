@@ -0,0 +1,122 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMigrationsWithEmptySource(t *testing.T) {
+	database, err := db.NewDatabase(config.DatabaseConfig{
+		Path: filepath.Join(t.TempDir(), "levity.db"),
+	}, dbtest.Logger())
+	require.NoError(t, err)
+	defer database.Close()
+
+	sources := map[string]fstest.MapFS{
+		"empty":         {},
+		"no migrations": {"README.md": &fstest.MapFile{Data: []byte("migrations live here")}},
+		"down only":     {"001_initial.down.sql": &fstest.MapFile{Data: []byte("DROP TABLE chargers;")}},
+	}
+
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			database.SetMigrationSource(source)
+
+			err := database.RunMigrations()
+			assert.ErrorIs(t, err, db.ErrNoMigrations)
+			assert.Contains(t, err.Error(), "did you forget to embed sql/migrations?")
+		})
+	}
+}
+
+func TestRunMigrationsReportsFailingVersion(t *testing.T) {
+	database, err := db.NewDatabase(config.DatabaseConfig{
+		Path: filepath.Join(t.TempDir(), "levity.db"),
+	}, dbtest.Logger())
+	require.NoError(t, err)
+	defer database.Close()
+
+	database.SetMigrationSource(fstest.MapFS{
+		"001_initial.up.sql":   &fstest.MapFile{Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY);")},
+		"001_initial.down.sql": &fstest.MapFile{Data: []byte("DROP TABLE widgets;")},
+		"002_broken.up.sql":    &fstest.MapFile{Data: []byte("ALTER TABLE gadgets ADD COLUMN name TEXT;")},
+		"002_broken.down.sql":  &fstest.MapFile{Data: []byte("SELECT 1;")},
+	})
+
+	var result db.MigrationResult
+	err = database.RunMigrationsWithCallback(func(r db.MigrationResult) {
+		result = r
+	})
+	require.Error(t, err)
+
+	var migrationErr *db.MigrationError
+	require.ErrorAs(t, result.Error, &migrationErr)
+	assert.Equal(t, uint(2), migrationErr.Version)
+	assert.Equal(t, "ALTER TABLE gadgets ADD COLUMN name TEXT;", migrationErr.Statement)
+	assert.Contains(t, migrationErr.Err.Error(), "no such table: gadgets")
+	assert.ErrorAs(t, err, &migrationErr)
+
+	version, dirty, err := database.GetMigrationVersion()
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), version)
+	assert.True(t, dirty)
+}
+
+func TestNewDatabaseWarmsUpIdleConnections(t *testing.T) {
+	for _, pingOnBorrow := range []bool{false, true} {
+		database, err := db.NewDatabase(config.DatabaseConfig{
+			Path:         filepath.Join(t.TempDir(), "levity.db"),
+			MaxOpenConns: 10,
+			MaxIdleConns: 4,
+			PingOnBorrow: pingOnBorrow,
+		}, dbtest.Logger())
+		require.NoError(t, err)
+
+		stats := database.Stats()
+		assert.Equal(t, 4, stats.OpenConnections, "ping_on_borrow=%v", pingOnBorrow)
+		assert.Equal(t, 4, stats.Idle, "ping_on_borrow=%v", pingOnBorrow)
+
+		// Borrowed connections are usable and go back to the pool
+		require.NoError(t, database.RunMigrations())
+		assert.Equal(t, 0, database.Stats().InUse)
+		require.NoError(t, database.Close())
+	}
+}
+
+func TestConnectionPragmasApplyToEveryConnection(t *testing.T) {
+	ctx := context.Background()
+	for interval, want := range map[time.Duration]int{0: 1000, time.Minute: 0} {
+		database, err := db.NewDatabase(config.DatabaseConfig{
+			Path:               filepath.Join(t.TempDir(), "levity.db"),
+			MaxOpenConns:       4,
+			MaxIdleConns:       2,
+			CheckpointInterval: interval,
+		}, dbtest.Logger())
+		require.NoError(t, err)
+
+		// Hold every connection the pool allows, warmed up or opened later
+		var conns []*sql.Conn
+		for i := 0; i < 4; i++ {
+			conn, err := database.GetDB().Conn(ctx)
+			require.NoError(t, err)
+			conns = append(conns, conn)
+		}
+		for i, conn := range conns {
+			var autocheckpoint int
+			require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA wal_autocheckpoint").Scan(&autocheckpoint))
+			assert.Equal(t, want, autocheckpoint, "connection %d, checkpoint_interval=%v", i, interval)
+			require.NoError(t, conn.Close())
+		}
+		require.NoError(t, database.Close())
+	}
+}
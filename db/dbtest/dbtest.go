@@ -0,0 +1,65 @@
+package dbtest
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+)
+
+// NewDatabase creates a migrated SQLite database in a temporary directory
+// that is closed when the test finishes
+func NewDatabase(t testing.TB) *db.Database {
+	t.Helper()
+
+	cfg := config.DatabaseConfig{
+		Path:         filepath.Join(t.TempDir(), "levity.db"),
+		MaxOpenConns: 5,
+		MaxIdleConns: 5,
+	}
+
+	database, err := db.NewDatabase(cfg, Logger())
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if err := database.RunMigrations(); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	return database
+}
+
+// NewRepositories creates a repository manager backed by a fresh test database
+func NewRepositories(t testing.TB, clk clock.Clock, opts ...db.RepositoryOption) db.RepositoryManager {
+	t.Helper()
+	return db.NewRepositoryManager(NewDatabase(t), nopLogger{}, clk, opts...)
+}
+
+// Float64 returns a pointer to v, for meter values in create requests
+func Float64(v float64) *float64 {
+	return &v
+}
+
+// Logger returns a structured logger that discards all output
+func Logger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// NopLogger returns a repository logger that discards all output
+func NopLogger() db.Logger {
+	return nopLogger{}
+}
+
+// nopLogger implements db.Logger and discards all output
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/keeth/levity/core/clock"
+)
+
+// ErrDeadLetterNotFound is returned when a lookup or update targets a dead letter without a row
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// deadLetterRepository implements DeadLetterRepository
+type deadLetterRepository struct {
+	db     Executor
+	logger Logger
+	clock  clock.Clock
+}
+
+// NewDeadLetterRepository creates a new dead letter repository
+func NewDeadLetterRepository(db Executor, logger Logger, clk clock.Clock) DeadLetterRepository {
+	return &deadLetterRepository{
+		db:     db,
+		logger: logger,
+		clock:  clk,
+	}
+}
+
+// deadLetterOrderFields are the columns dead letter lists may be ordered by
+var deadLetterOrderFields = map[string]bool{
+	"id": true, "charger_id": true, "action": true, "created_at": true,
+}
+
+const deadLetterColumns = `id, charger_id, action, unique_id, frame, last_error, replays, resolved_at, created_at`
+
+// scanDeadLetter scans a dead_letters row
+func scanDeadLetter(row rowScanner) (*DeadLetter, error) {
+	var letter DeadLetter
+	err := row.Scan(
+		&letter.ID, &letter.ChargerID, &letter.Action, &letter.UniqueID, &letter.Frame,
+		&letter.LastError, &letter.Replays, &letter.ResolvedAt, &letter.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// Create implements DeadLetterRepository.Create
+func (r *deadLetterRepository) Create(ctx context.Context, req CreateDeadLetterRequest) (*DeadLetter, error) {
+	query := `
+		INSERT INTO dead_letters (charger_id, action, unique_id, frame, last_error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING ` + deadLetterColumns
+
+	letter, err := scanDeadLetter(r.db.QueryRowContext(ctx, query,
+		req.ChargerID, req.Action, req.UniqueID, req.Frame, req.Error, r.clock.Now().UTC(),
+	))
+	if err != nil {
+		r.logger.Error("Failed to store dead letter", "charger_id", req.ChargerID, "action", req.Action, "error", err)
+		return nil, fmt.Errorf("failed to store dead letter: %w", err)
+	}
+
+	r.logger.Info("Stored dead letter", "id", letter.ID, "charger_id", letter.ChargerID, "action", letter.Action)
+	return letter, nil
+}
+
+// GetByID implements DeadLetterRepository.GetByID
+func (r *deadLetterRepository) GetByID(ctx context.Context, id int) (*DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters WHERE id = ?`
+
+	letter, err := scanDeadLetter(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %d", ErrDeadLetterNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get dead letter %d: %w", id, err)
+	}
+	return letter, nil
+}
+
+// List implements DeadLetterRepository.List
+func (r *deadLetterRepository) List(ctx context.Context, filter DeadLetterFilter, opts ListOptions) ([]*DeadLetter, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.ChargerID != "" {
+		conditions = append(conditions, "charger_id = ?")
+		args = append(args, filter.ChargerID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Resolved != nil {
+		if *filter.Resolved {
+			conditions = append(conditions, "resolved_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "resolved_at IS NULL")
+		}
+	}
+
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	order := listOrder{fields: deadLetterOrderFields, fallback: "id", tiebreak: true}
+	letters, err := listQuery(ctx, r.db, query, args, order, opts, scanDeadLetter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// MarkResolved implements DeadLetterRepository.MarkResolved
+func (r *deadLetterRepository) MarkResolved(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE dead_letters SET resolved_at = ? WHERE id = ?`, r.clock.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dead letter %d: %w", id, err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrDeadLetterNotFound, id)
+	}
+
+	r.logger.Info("Resolved dead letter", "id", id)
+	return nil
+}
+
+// RecordReplayFailure implements DeadLetterRepository.RecordReplayFailure
+func (r *deadLetterRepository) RecordReplayFailure(ctx context.Context, id int, lastError string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE dead_letters SET replays = replays + 1, last_error = ? WHERE id = ?`, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to record replay of dead letter %d: %w", id, err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrDeadLetterNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/keeth/levity/core/clock"
+)
+
+// fileTransferRepository implements FileTransferRepository
+type fileTransferRepository struct {
+	db     Executor
+	logger Logger
+	clock  clock.Clock
+}
+
+// NewFileTransferRepository creates a new file transfer repository
+func NewFileTransferRepository(db Executor, logger Logger, clk clock.Clock) FileTransferRepository {
+	return &fileTransferRepository{
+		db:     db,
+		logger: logger,
+		clock:  clk,
+	}
+}
+
+const fileTransferColumns = `id, charger_id, kind, log_type, location, status, filename, created_at, updated_at`
+
+// Create implements FileTransferRepository.Create
+func (r *fileTransferRepository) Create(ctx context.Context, req CreateFileTransferRequest) (*FileTransfer, error) {
+	now := r.clock.Now().UTC()
+	query := `
+		INSERT INTO file_transfers (charger_id, kind, log_type, location, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + fileTransferColumns
+
+	transfer, err := scanFileTransfer(r.db.QueryRowContext(ctx, query,
+		req.ChargerID, req.Kind, req.LogType, req.Location, FileTransferStatusRequested, now, now,
+	))
+	if err != nil {
+		r.logger.Error("Failed to record file transfer", "charger_id", req.ChargerID, "kind", req.Kind, "error", err)
+		return nil, fmt.Errorf("failed to record file transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+// Update implements FileTransferRepository.Update
+func (r *fileTransferRepository) Update(ctx context.Context, id int, status, filename string) (*FileTransfer, error) {
+	query := `
+		UPDATE file_transfers SET status = ?, filename = ?, updated_at = ?
+		WHERE id = ?
+		RETURNING ` + fileTransferColumns
+
+	transfer, err := scanFileTransfer(r.db.QueryRowContext(ctx, query, status, filename, r.clock.Now().UTC(), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("file transfer not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to update file transfer: %w", err)
+	}
+
+	return transfer, nil
+}
+
+// UpdateStatus implements FileTransferRepository.UpdateStatus
+func (r *fileTransferRepository) UpdateStatus(ctx context.Context, chargerID, kind string, requestID *int, status string) (*FileTransfer, error) {
+	match := `SELECT id FROM file_transfers WHERE charger_id = ? AND kind = ? ORDER BY created_at DESC, id DESC LIMIT 1`
+	args := []interface{}{chargerID, kind}
+	if requestID != nil {
+		match = `SELECT id FROM file_transfers WHERE charger_id = ? AND kind = ? AND id = ?`
+		args = append(args, *requestID)
+	}
+
+	query := `
+		UPDATE file_transfers SET status = ?, updated_at = ?
+		WHERE id = (` + match + `)
+		RETURNING ` + fileTransferColumns
+
+	transfer, err := scanFileTransfer(r.db.QueryRowContext(ctx, query,
+		append([]interface{}{status, r.clock.Now().UTC()}, args...)...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to update file transfer status", "charger_id", chargerID, "kind", kind, "error", err)
+		return nil, fmt.Errorf("failed to update file transfer status: %w", err)
+	}
+
+	return transfer, nil
+}
+
+// ListByCharger implements FileTransferRepository.ListByCharger
+func (r *fileTransferRepository) ListByCharger(ctx context.Context, chargerID string, opts ListOptions) ([]*FileTransfer, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListOptions().Limit
+	}
+
+	query := `
+		SELECT ` + fileTransferColumns + `
+		FROM file_transfers WHERE charger_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryContext(ctx, query, chargerID, limit, opts.Offset)
+	if err != nil {
+		r.logger.Error("Failed to list file transfers", "charger_id", chargerID, "error", err)
+		return nil, fmt.Errorf("failed to list file transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var transfers []*FileTransfer
+	for rows.Next() {
+		transfer, err := scanFileTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan file transfer: %w", err)
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// scanFileTransfer scans a file_transfers row
+func scanFileTransfer(row rowScanner) (*FileTransfer, error) {
+	var transfer FileTransfer
+	err := row.Scan(
+		&transfer.ID, &transfer.ChargerID, &transfer.Kind, &transfer.LogType, &transfer.Location,
+		&transfer.Status, &transfer.Filename, &transfer.CreatedAt, &transfer.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
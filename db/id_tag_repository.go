@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrIDTagNotFound is returned when an ID tag is not in the id_tags table
+var ErrIDTagNotFound = errors.New("ID tag not found")
+
+// idTagRepository implements IDTagRepository
+type idTagRepository struct {
+	db     Executor
+	logger Logger
+}
+
+// NewIDTagRepository creates a new ID tag repository
+func NewIDTagRepository(db Executor, logger Logger) IDTagRepository {
+	return &idTagRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create implements IDTagRepository.Create
+func (r *idTagRepository) Create(ctx context.Context, req CreateIDTagRequest) (*IDTag, error) {
+	status := req.Status
+	if status == "" {
+		status = "Accepted"
+	}
+
+	query := `
+		INSERT INTO id_tags (id_tag, parent_id_tag, status, expiry_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id_tag, parent_id_tag, status, expiry_date, created_at, updated_at`
+
+	var tag IDTag
+	err := r.db.QueryRowContext(ctx, query, req.IDTag, req.ParentIDTag, status, req.ExpiryDate).Scan(
+		&tag.IDTag, &tag.ParentIDTag, &tag.Status, &tag.ExpiryDate, &tag.CreatedAt, &tag.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error("Failed to create ID tag", "id_tag", req.IDTag, "error", err)
+		return nil, fmt.Errorf("failed to create ID tag: %w", err)
+	}
+
+	r.logger.Info("Created ID tag", "id_tag", tag.IDTag, "status", tag.Status)
+	return &tag, nil
+}
+
+// GetByIDTag implements IDTagRepository.GetByIDTag
+func (r *idTagRepository) GetByIDTag(ctx context.Context, idTag string) (*IDTag, error) {
+	query := `
+		SELECT id_tag, parent_id_tag, status, expiry_date, created_at, updated_at
+		FROM id_tags WHERE id_tag = ?`
+
+	var tag IDTag
+	err := r.db.QueryRowContext(ctx, query, idTag).Scan(
+		&tag.IDTag, &tag.ParentIDTag, &tag.Status, &tag.ExpiryDate, &tag.CreatedAt, &tag.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrIDTagNotFound, idTag)
+		}
+		return nil, fmt.Errorf("failed to get ID tag: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// List implements IDTagRepository.List
+func (r *idTagRepository) List(ctx context.Context, opts ListOptions) ([]*IDTag, error) {
+	opts.ValidateSortDirection()
+
+	// Validate order by field for security
+	validOrderFields := map[string]bool{
+		"id_tag": true, "status": true, "expiry_date": true, "created_at": true, "updated_at": true,
+	}
+
+	if !validOrderFields[opts.OrderBy] {
+		opts.OrderBy = "created_at"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id_tag, parent_id_tag, status, expiry_date, created_at, updated_at
+		FROM id_tags
+		ORDER BY %s %s
+		LIMIT ? OFFSET ?`, opts.OrderBy, opts.SortDir)
+
+	return r.query(ctx, query, opts.Limit, opts.Offset)
+}
+
+// GetAll implements IDTagRepository.GetAll
+func (r *idTagRepository) GetAll(ctx context.Context) ([]*IDTag, error) {
+	query := `
+		SELECT id_tag, parent_id_tag, status, expiry_date, created_at, updated_at
+		FROM id_tags ORDER BY id_tag`
+
+	return r.query(ctx, query)
+}
+
+// UpdateStatus implements IDTagRepository.UpdateStatus
+func (r *idTagRepository) UpdateStatus(ctx context.Context, idTag string, status string) error {
+	query := `UPDATE id_tags SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id_tag = ?`
+
+	result, err := r.db.ExecContext(ctx, query, status, idTag)
+	if err != nil {
+		r.logger.Error("Failed to update ID tag status", "id_tag", idTag, "error", err)
+		return fmt.Errorf("failed to update ID tag status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("ID tag not found: %s", idTag)
+	}
+
+	return nil
+}
+
+// Delete implements IDTagRepository.Delete
+func (r *idTagRepository) Delete(ctx context.Context, idTag string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM id_tags WHERE id_tag = ?`, idTag)
+	if err != nil {
+		r.logger.Error("Failed to delete ID tag", "id_tag", idTag, "error", err)
+		return fmt.Errorf("failed to delete ID tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("ID tag not found: %s", idTag)
+	}
+
+	r.logger.Info("Deleted ID tag", "id_tag", idTag)
+	return nil
+}
+
+// query runs an ID tag select and scans every row
+func (r *idTagRepository) query(ctx context.Context, query string, args ...interface{}) ([]*IDTag, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to list ID tags", "error", err)
+		return nil, fmt.Errorf("failed to list ID tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*IDTag
+	for rows.Next() {
+		var tag IDTag
+		err := rows.Scan(&tag.IDTag, &tag.ParentIDTag, &tag.Status, &tag.ExpiryDate, &tag.CreatedAt, &tag.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ID tag: %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// listOrder is the ORDER BY a paginated list accepts. ListOptions.OrderBy is
+// only interpolated into the query when it is one of fields, so callers may
+// pass it through from request parameters.
+type listOrder struct {
+	// fields are the columns a caller may order by
+	fields map[string]bool
+	// fallback is the column used when ListOptions.OrderBy is not in fields
+	fallback string
+	// sortDir fixes the direction regardless of ListOptions.SortDir when set
+	sortDir string
+	// tiebreak orders rows with equal keys by id in the same direction, so
+	// pages do not overlap
+	tiebreak bool
+}
+
+// clause returns the ORDER BY expression for opts
+func (o listOrder) clause(opts ListOptions) string {
+	opts.ValidateSortDirection()
+
+	column := opts.OrderBy
+	if !o.fields[column] {
+		column = o.fallback
+	}
+
+	dir := opts.SortDir
+	if o.sortDir != "" {
+		dir = o.sortDir
+	}
+
+	if o.tiebreak {
+		return fmt.Sprintf("%s %s, id %s", column, dir, dir)
+	}
+	return fmt.Sprintf("%s %s", column, dir)
+}
+
+// listQuery runs base, a SELECT without ORDER BY or LIMIT, ordered and paged
+// by opts, and scans each row with scan
+func listQuery[T any](ctx context.Context, db Executor, base string, args []interface{}, order listOrder, opts ListOptions, scan func(rowScanner) (*T, error)) ([]*T, error) {
+	query := fmt.Sprintf("%s\n\t\tORDER BY %s\n\t\tLIMIT ? OFFSET ?", base, order.clause(opts))
+
+	queryArgs := make([]interface{}, 0, len(args)+2)
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, opts.Limit, opts.Offset)
+
+	rows, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*T
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,127 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryListsThroughSharedHelper(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	repos := dbtest.NewRepositories(t, fake)
+
+	for i := 1; i <= 3; i++ {
+		chargerID := fmt.Sprintf("CP-%d", i)
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: chargerID})
+		require.NoError(t, err)
+
+		txID := i
+		startTime := start.Add(time.Duration(i) * time.Minute)
+		_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+			TransactionID: &txID, ChargerID: chargerID, ConnectorID: 1, IDTag: "TAG", StartTime: &startTime,
+		})
+		require.NoError(t, err)
+
+		_, err = repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			TransactionID: &txID,
+			ChargerID:     "CP-1",
+			ConnectorID:   1,
+			Timestamp:     startTime,
+			Measurand:     "Energy.Active.Import.Register",
+			Value:         dbtest.Float64(float64(i * 100)),
+			Unit:          db.UnitWh,
+		})
+		require.NoError(t, err)
+
+		_, err = repos.Errors().Create(ctx, db.CreateChargerErrorRequest{
+			ChargerID: "CP-1", ErrorCode: fmt.Sprintf("Error%d", i), Timestamp: startTime,
+		})
+		require.NoError(t, err)
+	}
+
+	asc := db.ListOptions{Limit: 10, OrderBy: "id", SortDir: "ASC"}
+
+	t.Run("chargers", func(t *testing.T) {
+		chargers, err := repos.Chargers().List(ctx, asc)
+		require.NoError(t, err)
+		require.Len(t, chargers, 3)
+		assert.Equal(t, "CP-1", chargers[0].ID)
+		assert.Equal(t, "CP-3", chargers[2].ID)
+
+		page, err := repos.Chargers().List(ctx, db.ListOptions{Limit: 1, Offset: 1, OrderBy: "id", SortDir: "DESC"})
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, "CP-2", page[0].ID)
+
+		// Columns outside the whitelist fall back to the default order
+		chargers, err = repos.Chargers().List(ctx, db.ListOptions{Limit: 10, OrderBy: "id; DROP TABLE chargers", SortDir: "ASC;"})
+		require.NoError(t, err)
+		assert.Len(t, chargers, 3)
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		txs, err := repos.Transactions().List(ctx, db.ListOptions{Limit: 10, OrderBy: "transaction_id", SortDir: "DESC"})
+		require.NoError(t, err)
+		require.Len(t, txs, 3)
+		assert.Equal(t, 3, *txs[0].TransactionID)
+
+		txs, err = repos.Transactions().Search(ctx, db.TransactionFilter{IDTag: "TAG"}, db.ListOptions{Limit: 2, SortDir: "ASC"})
+		require.NoError(t, err)
+		require.Len(t, txs, 2)
+		assert.Equal(t, 1, *txs[0].TransactionID)
+
+		txs, err = repos.Transactions().GetByChargerID(ctx, "CP-2", db.DefaultListOptions())
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+		assert.Equal(t, "CP-2", txs[0].ChargerID)
+	})
+
+	t.Run("meter values", func(t *testing.T) {
+		// Meter value lists keep their timestamp order whatever the options ask for
+		values, err := repos.MeterValues().List(ctx, asc)
+		require.NoError(t, err)
+		require.Len(t, values, 3)
+		assert.Equal(t, 300.0, values[0].Value)
+
+		values, err = repos.MeterValues().GetByChargerID(ctx, "CP-1", db.ListOptions{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, values, 2)
+		assert.Equal(t, 300.0, values[0].Value)
+
+		values, err = repos.MeterValues().GetByTimeRange(ctx, "CP-1", start, start.Add(time.Hour), db.DefaultListOptions())
+		require.NoError(t, err)
+		require.Len(t, values, 3)
+		assert.Equal(t, 100.0, values[0].Value)
+
+		values, err = repos.MeterValues().GetByTransactionID(ctx, 2, db.DefaultListOptions())
+		require.NoError(t, err)
+		require.Len(t, values, 1)
+		assert.Equal(t, 200.0, values[0].Value)
+
+		values, err = repos.MeterValues().GetByMeasurand(ctx, "CP-1", "Energy.Active.Import.Register", db.ListOptions{Limit: 1, Offset: 2})
+		require.NoError(t, err)
+		require.Len(t, values, 1)
+		assert.Equal(t, 100.0, values[0].Value)
+	})
+
+	t.Run("charger errors", func(t *testing.T) {
+		errs, err := repos.Errors().List(ctx, db.ErrorFilter{ChargerID: "CP-1"}, db.ListOptions{Limit: 10, OrderBy: "error_code", SortDir: "ASC"})
+		require.NoError(t, err)
+		require.Len(t, errs, 3)
+		assert.Equal(t, "Error1", errs[0].ErrorCode)
+
+		errs, err = repos.Errors().GetByChargerID(ctx, "CP-1", db.ListOptions{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, errs, 2)
+		assert.Equal(t, "Error3", errs[0].ErrorCode)
+	})
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"math"
+	"strings"
+)
+
+// Meter value units that are normalized at ingestion
+const (
+	UnitWh    = "Wh"
+	UnitKWh   = "kWh"
+	UnitVarh  = "varh"
+	UnitKVarh = "kvarh"
+)
+
+// NormalizeMeterValue converts energy readings to Wh (or varh), so samples from
+// chargers reporting in kWh can be aggregated with those reporting in Wh.
+// Values in any other unit are returned unchanged.
+func NormalizeMeterValue(value float64, unit string) float64 {
+	switch unit {
+	case UnitKWh, UnitKVarh:
+		return value * 1000
+	default:
+		return value
+	}
+}
+
+// energyMeasurandPrefix marks the measurands that are cumulative registers or
+// energy intervals, as opposed to instantaneous readings like power or current
+const energyMeasurandPrefix = "Energy."
+
+// IntegerEnergy returns an energy reading as whole Wh (or varh). It reports
+// false for measurands that are not energy, and for units other than Wh,
+// kWh, varh and kvarh; OCPP defaults an energy reading without a unit to Wh.
+func IntegerEnergy(measurand string, value float64, unit string) (int64, bool) {
+	if !strings.HasPrefix(measurand, energyMeasurandPrefix) {
+		return 0, false
+	}
+	switch unit {
+	case "", UnitWh, UnitKWh, UnitVarh, UnitKVarh:
+		return int64(math.Round(NormalizeMeterValue(value, unit))), true
+	default:
+		return 0, false
+	}
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -21,10 +23,22 @@ type Charger struct {
 	LastConnectAt   *time.Time `json:"last_connect_at" db:"last_connect_at"`
 	LastTxStartAt   *time.Time `json:"last_tx_start_at" db:"last_tx_start_at"`
 	LastTxStopAt    *time.Time `json:"last_tx_stop_at" db:"last_tx_stop_at"`
+	Notes           string     `json:"notes" db:"notes"`
+	NumConnectors   int        `json:"num_connectors" db:"num_connectors"`
 	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// Transaction stop sources
+const (
+	// StopSourceCharger is a stop the charger initiated locally
+	StopSourceCharger = "Charger"
+	// StopSourceRemote is a stop the charger made for a RemoteStopTransaction
+	StopSourceRemote = "Remote"
+	// StopSourceServer is a stop recorded by a server policy without the charger
+	StopSourceServer = "Server"
+)
+
 // ChargerConnector represents an individual connector on a charger
 type ChargerConnector struct {
 	ID              int       `json:"id" db:"id"`
@@ -39,37 +53,42 @@ type ChargerConnector struct {
 
 // Transaction represents a charging session
 type Transaction struct {
-	ID              int        `json:"id" db:"id"`
-	TransactionID   *int       `json:"transaction_id" db:"transaction_id"`
-	ChargerID       string     `json:"charger_id" db:"charger_id"`
-	ConnectorID     int        `json:"connector_id" db:"connector_id"`
-	IDTag           string     `json:"id_tag" db:"id_tag"`
-	StartTime       time.Time  `json:"start_time" db:"start_time"`
-	StopTime        *time.Time `json:"stop_time" db:"stop_time"`
-	MeterStart      int        `json:"meter_start" db:"meter_start"`
-	MeterStop       *int       `json:"meter_stop" db:"meter_stop"`
-	EnergyDelivered int        `json:"energy_delivered" db:"energy_delivered"`
-	StopReason      string     `json:"stop_reason" db:"stop_reason"`
-	Status          string     `json:"status" db:"status"`
-	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	ID              int               `json:"id" db:"id"`
+	TransactionID   *int              `json:"transaction_id" db:"transaction_id"`
+	ChargerID       string            `json:"charger_id" db:"charger_id"`
+	ConnectorID     int               `json:"connector_id" db:"connector_id"`
+	IDTag           string            `json:"id_tag" db:"id_tag"`
+	StartTime       time.Time         `json:"start_time" db:"start_time"`
+	StopTime        *time.Time        `json:"stop_time" db:"stop_time"`
+	MeterStart      int               `json:"meter_start" db:"meter_start"`
+	MeterStop       *int              `json:"meter_stop" db:"meter_stop"`
+	EnergyDelivered int               `json:"energy_delivered" db:"energy_delivered"`
+	StopReason      string            `json:"stop_reason" db:"stop_reason"`
+	StopSource      string            `json:"stop_source" db:"stop_source"`
+	Status          TransactionStatus `json:"status" db:"status"`
+	// ClockSkewSeconds is set when a charger timestamp was kept despite
+	// exceeding ocpp.max_clock_skew
+	ClockSkewSeconds *int64    `json:"clock_skew_seconds,omitempty" db:"clock_skew_seconds"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // MeterValue represents a meter reading sample
 type MeterValue struct {
-	ID            int       `json:"id" db:"id"`
-	TransactionID *int      `json:"transaction_id" db:"transaction_id"`
-	ChargerID     string    `json:"charger_id" db:"charger_id"`
-	ConnectorID   int       `json:"connector_id" db:"connector_id"`
-	Timestamp     time.Time `json:"timestamp" db:"timestamp"`
-	Measurand     string    `json:"measurand" db:"measurand"`
-	Value         float64   `json:"value" db:"value"`
-	Unit          string    `json:"unit" db:"unit"`
-	Context       string    `json:"context" db:"context"`
-	Location      string    `json:"location" db:"location"`
-	Phase         string    `json:"phase" db:"phase"`
-	Format        string    `json:"format" db:"format"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	ID              int       `json:"id" db:"id"`
+	TransactionID   *int      `json:"transaction_id" db:"transaction_id"`
+	ChargerID       string    `json:"charger_id" db:"charger_id"`
+	ConnectorID     int       `json:"connector_id" db:"connector_id"`
+	Timestamp       time.Time `json:"timestamp" db:"timestamp"`
+	Measurand       string    `json:"measurand" db:"measurand"`
+	Value           float64   `json:"value" db:"value"`
+	ValueNormalized float64   `json:"value_normalized" db:"value_normalized"`
+	Unit            string    `json:"unit" db:"unit"`
+	Context         string    `json:"context" db:"context"`
+	Location        string    `json:"location" db:"location"`
+	Phase           string    `json:"phase" db:"phase"`
+	Format          string    `json:"format" db:"format"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 }
 
 // ChargerError represents an error event from a charger
@@ -86,6 +105,222 @@ type ChargerError struct {
 	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 }
 
+// IDTag represents an authorization identifier
+type IDTag struct {
+	IDTag       string     `json:"id_tag" db:"id_tag"`
+	ParentIDTag string     `json:"parent_id_tag" db:"parent_id_tag"`
+	Status      string     `json:"status" db:"status"`
+	ExpiryDate  *time.Time `json:"expiry_date" db:"expiry_date"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Reservation statuses
+const (
+	ReservationStatusActive    = "Active"
+	ReservationStatusUsed      = "Used"
+	ReservationStatusCancelled = "Cancelled"
+	ReservationStatusExpired   = "Expired"
+)
+
+// Reservation holds a connector for an ID tag until it expires
+type Reservation struct {
+	ID          int       `json:"id" db:"id"`
+	ChargerID   string    `json:"charger_id" db:"charger_id"`
+	ConnectorID int       `json:"connector_id" db:"connector_id"`
+	IDTag       string    `json:"id_tag" db:"id_tag"`
+	ParentIDTag string    `json:"parent_id_tag" db:"parent_id_tag"`
+	ExpiryDate  time.Time `json:"expiry_date" db:"expiry_date"`
+	Status      string    `json:"status" db:"status"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Audited operations
+const (
+	AuditOperationCreate = "Create"
+	AuditOperationUpdate = "Update"
+	AuditOperationDelete = "Delete"
+)
+
+// Audited entity types
+const (
+	AuditEntityCharger = "charger"
+	AuditEntityIDTag   = "id_tag"
+)
+
+// AuditEntry records a single mutation of an entity
+type AuditEntry struct {
+	ID         int             `json:"id" db:"id"`
+	Actor      string          `json:"actor" db:"actor"`
+	EntityType string          `json:"entity_type" db:"entity_type"`
+	EntityID   string          `json:"entity_id" db:"entity_id"`
+	Operation  string          `json:"operation" db:"operation"`
+	Changes    json.RawMessage `json:"changes" db:"changes"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// FieldChange is the before and after value of a changed field
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Command statuses recorded when the charger reply has no status of its own
+const (
+	CommandStatusCompleted = "Completed"
+	CommandStatusError     = "Error"
+)
+
+// CommandHistoryEntry records an outbound command sent to a charger
+type CommandHistoryEntry struct {
+	ID        int             `json:"id" db:"id"`
+	ChargerID string          `json:"charger_id" db:"charger_id"`
+	Action    string          `json:"action" db:"action"`
+	Params    json.RawMessage `json:"params" db:"params"`
+	Status    string          `json:"status" db:"status"`
+	Error     string          `json:"error" db:"error"`
+	LatencyMS int64           `json:"latency_ms" db:"latency_ms"`
+	Operator  string          `json:"operator" db:"operator"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// ChargerConnection records one OCPP connection of a charger. DisconnectedAt
+// and DurationSeconds are nil while the connection is open.
+type ChargerConnection struct {
+	ID               int        `json:"id" db:"id"`
+	ChargerID        string     `json:"charger_id" db:"charger_id"`
+	RemoteIP         string     `json:"remote_ip" db:"remote_ip"`
+	Subprotocol      string     `json:"subprotocol" db:"subprotocol"`
+	ConnectedAt      time.Time  `json:"connected_at" db:"connected_at"`
+	DisconnectedAt   *time.Time `json:"disconnected_at" db:"disconnected_at"`
+	DisconnectReason string     `json:"disconnect_reason" db:"disconnect_reason"`
+	DurationSeconds  *int64     `json:"duration_seconds" db:"duration_seconds"`
+}
+
+// File transfer kinds
+const (
+	FileTransferFirmware = "Firmware"
+	FileTransferLog      = "Log"
+)
+
+// FileTransferStatusRequested is the status of a file transfer whose command
+// has not yet been answered by the charger
+const FileTransferStatusRequested = "Requested"
+
+// FileTransfer tracks a signed firmware update or log upload requested from a
+// charger. Its ID is the requestId sent to the charger, which the charger
+// echoes in its status notifications.
+type FileTransfer struct {
+	ID        int       `json:"id" db:"id"`
+	ChargerID string    `json:"charger_id" db:"charger_id"`
+	Kind      string    `json:"kind" db:"kind"`
+	LogType   string    `json:"log_type,omitempty" db:"log_type"`
+	Location  string    `json:"location" db:"location"`
+	Status    string    `json:"status" db:"status"`
+	Filename  string    `json:"filename,omitempty" db:"filename"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SecurityEvent is a security event reported by a charger through
+// SecurityEventNotification
+type SecurityEvent struct {
+	ID        int       `json:"id" db:"id"`
+	ChargerID string    `json:"charger_id" db:"charger_id"`
+	Type      string    `json:"type" db:"type"`
+	TechInfo  string    `json:"tech_info,omitempty" db:"tech_info"`
+	Critical  bool      `json:"critical" db:"critical"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ChargerProvisioning records the provisioning profile last pushed to a
+// charger
+type ChargerProvisioning struct {
+	ChargerID string    `json:"charger_id" db:"charger_id"`
+	Profile   string    `json:"profile" db:"profile"`
+	Version   int       `json:"version" db:"version"`
+	AppliedAt time.Time `json:"applied_at" db:"applied_at"`
+}
+
+// Site groups chargers that share an electrical supply. MaxCurrent is the
+// current in amps they may draw together; 0 is unlimited.
+type Site struct {
+	ID         string    `json:"id" db:"id"`
+	Name       string    `json:"name" db:"name"`
+	MaxCurrent float64   `json:"max_current" db:"max_current"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateSiteRequest represents the data needed to create a site
+type CreateSiteRequest struct {
+	ID         string  `json:"id" validate:"required"`
+	Name       string  `json:"name"`
+	MaxCurrent float64 `json:"max_current"`
+}
+
+// UpdateSiteRequest represents the data that can be updated for a site
+type UpdateSiteRequest struct {
+	Name       *string  `json:"name,omitempty"`
+	MaxCurrent *float64 `json:"max_current,omitempty"`
+}
+
+// DeadLetter is a charger CALL whose handler failed, stored with its raw
+// frame so it can be replayed. ResolvedAt is set once a replay succeeds.
+type DeadLetter struct {
+	ID         int        `json:"id" db:"id"`
+	ChargerID  string     `json:"charger_id" db:"charger_id"`
+	Action     string     `json:"action" db:"action"`
+	UniqueID   string     `json:"unique_id" db:"unique_id"`
+	Frame      string     `json:"frame" db:"frame"`
+	LastError  string     `json:"last_error" db:"last_error"`
+	Replays    int        `json:"replays" db:"replays"`
+	ResolvedAt *time.Time `json:"resolved_at" db:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookStatusPending    = "Pending"
+	WebhookStatusDelivered  = "Delivered"
+	WebhookStatusDeadLetter = "DeadLetter"
+)
+
+// WebhookDelivery represents a webhook queued in the outbox
+type WebhookDelivery struct {
+	ID            int        `json:"id" db:"id"`
+	EventType     string     `json:"event_type" db:"event_type"`
+	URL           string     `json:"url" db:"url"`
+	Payload       string     `json:"payload" db:"payload"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string     `json:"last_error" db:"last_error"`
+	DeliveredAt   *time.Time `json:"delivered_at" db:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Outbox event statuses
+const (
+	OutboxStatusPending   = "Pending"
+	OutboxStatusPublished = "Published"
+)
+
+// OutboxEvent represents a domain event waiting to be relayed to the event bus
+type OutboxEvent struct {
+	ID          int        `json:"id" db:"id"`
+	Topic       string     `json:"topic" db:"topic"`
+	Payload     string     `json:"payload" db:"payload"`
+	Status      string     `json:"status" db:"status"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	LastError   string     `json:"last_error" db:"last_error"`
+	PublishedAt *time.Time `json:"published_at" db:"published_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
 // CreateChargerRequest represents the data needed to create a new charger
 type CreateChargerRequest struct {
 	ID              string `json:"id" validate:"required"`
@@ -96,6 +331,7 @@ type CreateChargerRequest struct {
 	FirmwareVersion string `json:"firmware_version"`
 	ICCID           string `json:"iccid"`
 	IMSI            string `json:"imsi"`
+	NumConnectors   int    `json:"num_connectors"`
 }
 
 // UpdateChargerRequest represents the data that can be updated for a charger
@@ -109,34 +345,39 @@ type UpdateChargerRequest struct {
 	IMSI            *string `json:"imsi,omitempty"`
 	Status          *string `json:"status,omitempty"`
 	IsConnected     *bool   `json:"is_connected,omitempty"`
+	Notes           *string `json:"notes,omitempty"`
+	NumConnectors   *int    `json:"num_connectors,omitempty"`
 }
 
 // CreateTransactionRequest represents the data needed to create a new transaction
 type CreateTransactionRequest struct {
-	TransactionID *int   `json:"transaction_id,omitempty"`
-	ChargerID     string `json:"charger_id" validate:"required"`
-	ConnectorID   int    `json:"connector_id" validate:"required"`
-	IDTag         string `json:"id_tag" validate:"required"`
-	MeterStart    int    `json:"meter_start"`
+	TransactionID *int       `json:"transaction_id,omitempty"`
+	ChargerID     string     `json:"charger_id" validate:"required"`
+	ConnectorID   int        `json:"connector_id" validate:"required"`
+	IDTag         string     `json:"id_tag" validate:"required"`
+	MeterStart    int        `json:"meter_start"`
+	StartTime     *time.Time `json:"start_time,omitempty"`
 }
 
 // UpdateTransactionRequest represents the data that can be updated for a transaction
 type UpdateTransactionRequest struct {
-	MeterStop       *int       `json:"meter_stop,omitempty"`
-	StopTime        *time.Time `json:"stop_time,omitempty"`
-	EnergyDelivered *int       `json:"energy_delivered,omitempty"`
-	StopReason      *string    `json:"stop_reason,omitempty"`
-	Status          *string    `json:"status,omitempty"`
+	MeterStop       *int               `json:"meter_stop,omitempty"`
+	StopTime        *time.Time         `json:"stop_time,omitempty"`
+	EnergyDelivered *int               `json:"energy_delivered,omitempty"`
+	StopReason      *string            `json:"stop_reason,omitempty"`
+	Status          *TransactionStatus `json:"status,omitempty"`
 }
 
-// CreateMeterValueRequest represents the data needed to create a meter value record
+// CreateMeterValueRequest represents the data needed to create a meter value
+// record. Value is a pointer so a zero reading is stored while a missing one is
+// rejected.
 type CreateMeterValueRequest struct {
 	TransactionID *int      `json:"transaction_id,omitempty"`
 	ChargerID     string    `json:"charger_id" validate:"required"`
 	ConnectorID   int       `json:"connector_id" validate:"required"`
 	Timestamp     time.Time `json:"timestamp" validate:"required"`
 	Measurand     string    `json:"measurand" validate:"required"`
-	Value         float64   `json:"value" validate:"required"`
+	Value         *float64  `json:"value" validate:"required"`
 	Unit          string    `json:"unit"`
 	Context       string    `json:"context"`
 	Location      string    `json:"location"`
@@ -144,6 +385,20 @@ type CreateMeterValueRequest struct {
 	Format        string    `json:"format"`
 }
 
+// Validate reports a request missing its value, measurand or timestamp as
+// ErrInvalidMeterValue
+func (req CreateMeterValueRequest) Validate() error {
+	switch {
+	case req.Value == nil:
+		return fmt.Errorf("%w: value is required", ErrInvalidMeterValue)
+	case req.Measurand == "":
+		return fmt.Errorf("%w: measurand is required", ErrInvalidMeterValue)
+	case req.Timestamp.IsZero():
+		return fmt.Errorf("%w: timestamp is required", ErrInvalidMeterValue)
+	}
+	return nil
+}
+
 // CreateChargerErrorRequest represents the data needed to create an error record
 type CreateChargerErrorRequest struct {
 	ChargerID        string    `json:"charger_id" validate:"required"`
@@ -155,6 +410,180 @@ type CreateChargerErrorRequest struct {
 	Timestamp        time.Time `json:"timestamp"`
 }
 
+// CreateIDTagRequest represents the data needed to create an ID tag
+type CreateIDTagRequest struct {
+	IDTag       string     `json:"id_tag" validate:"required"`
+	ParentIDTag string     `json:"parent_id_tag"`
+	Status      string     `json:"status"`
+	ExpiryDate  *time.Time `json:"expiry_date"`
+}
+
+// CreateReservationRequest represents the data needed to create a reservation
+type CreateReservationRequest struct {
+	ID          int       `json:"id" validate:"required"`
+	ChargerID   string    `json:"charger_id" validate:"required"`
+	ConnectorID int       `json:"connector_id"`
+	IDTag       string    `json:"id_tag" validate:"required"`
+	ParentIDTag string    `json:"parent_id_tag"`
+	ExpiryDate  time.Time `json:"expiry_date" validate:"required"`
+}
+
+// CreateAuditEntryRequest represents the data needed to record an audit entry
+type CreateAuditEntryRequest struct {
+	Actor      string                 `json:"actor"`
+	EntityType string                 `json:"entity_type" validate:"required"`
+	EntityID   string                 `json:"entity_id" validate:"required"`
+	Operation  string                 `json:"operation" validate:"required"`
+	Changes    map[string]FieldChange `json:"changes"`
+}
+
+// AuditFilter narrows an audit log query
+type AuditFilter struct {
+	EntityType string     `json:"entity_type"`
+	EntityID   string     `json:"entity_id"`
+	Actor      string     `json:"actor"`
+	Operation  string     `json:"operation"`
+	Since      *time.Time `json:"since"`
+	Until      *time.Time `json:"until"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+}
+
+// CreateCommandHistoryRequest represents the data needed to record a sent command
+type CreateCommandHistoryRequest struct {
+	ChargerID string          `json:"charger_id" validate:"required"`
+	Action    string          `json:"action" validate:"required"`
+	Params    json.RawMessage `json:"params"`
+	Status    string          `json:"status" validate:"required"`
+	Error     string          `json:"error"`
+	Latency   time.Duration   `json:"latency"`
+	Operator  string          `json:"operator"`
+}
+
+// CreateChargerConnectionRequest represents the data needed to record a new connection
+type CreateChargerConnectionRequest struct {
+	ChargerID   string    `json:"charger_id" validate:"required"`
+	RemoteIP    string    `json:"remote_ip"`
+	Subprotocol string    `json:"subprotocol"`
+	ConnectedAt time.Time `json:"connected_at" validate:"required"`
+}
+
+// CreateFileTransferRequest represents the data needed to record a requested file transfer
+type CreateFileTransferRequest struct {
+	ChargerID string `json:"charger_id" validate:"required"`
+	Kind      string `json:"kind" validate:"required"`
+	LogType   string `json:"log_type"`
+	Location  string `json:"location" validate:"required"`
+}
+
+// CreateSecurityEventRequest represents the data needed to record a security event
+type CreateSecurityEventRequest struct {
+	ChargerID string    `json:"charger_id" validate:"required"`
+	Type      string    `json:"type" validate:"required"`
+	TechInfo  string    `json:"tech_info"`
+	Critical  bool      `json:"critical"`
+	Timestamp time.Time `json:"timestamp" validate:"required"`
+}
+
+// SecurityEventFilter narrows a security event query
+type SecurityEventFilter struct {
+	ChargerID string     `json:"charger_id"`
+	Type      string     `json:"type"`
+	Critical  *bool      `json:"critical"`
+	Since     *time.Time `json:"since"`
+	Until     *time.Time `json:"until"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+}
+
+// CreateDeadLetterRequest represents the data needed to store a failed CALL
+type CreateDeadLetterRequest struct {
+	ChargerID string `json:"charger_id" validate:"required"`
+	Action    string `json:"action" validate:"required"`
+	UniqueID  string `json:"unique_id" validate:"required"`
+	Frame     string `json:"frame" validate:"required"`
+	Error     string `json:"error"`
+}
+
+// DeadLetterFilter narrows a dead letter query; nil Resolved returns both
+// resolved and unresolved dead letters
+type DeadLetterFilter struct {
+	ChargerID string `json:"charger_id"`
+	Action    string `json:"action"`
+	Resolved  *bool  `json:"resolved"`
+}
+
+// CreateWebhookDeliveryRequest represents the data needed to queue a webhook
+type CreateWebhookDeliveryRequest struct {
+	EventType     string    `json:"event_type" validate:"required"`
+	URL           string    `json:"url" validate:"required"`
+	Payload       string    `json:"payload" validate:"required"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+// CreateOutboxEventRequest represents the data needed to write an event to the outbox
+type CreateOutboxEventRequest struct {
+	Topic   string `json:"topic" validate:"required"`
+	Payload string `json:"payload" validate:"required"`
+}
+
+// ErrorFilter narrows a charger error query. Zero values do not filter, and a
+// TransactionFilter narrows a transaction search; zero-value fields are ignored
+type TransactionFilter struct {
+	ChargerID   string            `json:"charger_id"`
+	ConnectorID *int              `json:"connector_id"`
+	IDTag       string            `json:"id_tag"`
+	Status      TransactionStatus `json:"status"`
+	Since       *time.Time        `json:"since"`
+	Until       *time.Time        `json:"until"`
+}
+
+// nil Resolved returns both resolved and unresolved errors.
+type ErrorFilter struct {
+	ChargerID string     `json:"charger_id"`
+	ErrorCode string     `json:"error_code"`
+	Resolved  *bool      `json:"resolved"`
+	Since     *time.Time `json:"since"`
+	Until     *time.Time `json:"until"`
+}
+
+// ResolveErrorsFilter selects the active errors resolved in bulk; every set
+// field must match
+type ResolveErrorsFilter struct {
+	ChargerID string `json:"charger_id"`
+	ErrorCode string `json:"error_code"`
+	IDs       []int  `json:"ids"`
+}
+
+// IsEmpty reports whether the filter would match every active error
+func (f ResolveErrorsFilter) IsEmpty() bool {
+	return f.ChargerID == "" && f.ErrorCode == "" && len(f.IDs) == 0
+}
+
+// ErrorCodeCount is the number of errors reported with an error code
+type ErrorCodeCount struct {
+	ErrorCode string `json:"error_code" db:"error_code"`
+	Count     int    `json:"count" db:"count"`
+}
+
+// MeasurandRange is the lowest and highest normalized value of a measurand
+// among the meter values of a transaction
+type MeasurandRange struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+// SeriesPoint summarizes the meter values of a measurand in one time bucket.
+// Avg, Min and Max are nil for a bucket with no samples.
+type SeriesPoint struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+	Avg   *float64  `json:"avg"`
+	Min   *float64  `json:"min"`
+	Max   *float64  `json:"max"`
+}
+
 // ListOptions represents common options for list operations
 type ListOptions struct {
 	Limit   int    `json:"limit"`
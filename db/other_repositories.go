@@ -3,12 +3,28 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/keeth/levity/core/clock"
 )
 
 // Connector Repository Implementation
 
+// ChargePointConnectorID is the OCPP connector ID that addresses the whole
+// charge point. Its status lives on Charger.Status and it never has a
+// charger_connectors row.
+const ChargePointConnectorID = 0
+
+// ErrChargePointConnector is returned when a connector row is requested for connector 0
+var ErrChargePointConnector = errors.New("connector 0 refers to the charge point and has no connector row")
+
+// ErrConnectorNotFound is returned when a lookup or update targets a connector without a row
+var ErrConnectorNotFound = errors.New("connector not found")
+
 type chargerConnectorRepository struct {
 	db     Executor
 	logger Logger
@@ -19,6 +35,10 @@ func NewChargerConnectorRepository(db Executor, logger Logger) ChargerConnectorR
 }
 
 func (r *chargerConnectorRepository) Create(ctx context.Context, chargerID string, connectorID int) (*ChargerConnector, error) {
+	if connectorID == ChargePointConnectorID {
+		return nil, ErrChargePointConnector
+	}
+
 	query := `
 		INSERT INTO charger_connectors (charger_id, connector_id, status, created_at, updated_at)
 		VALUES (?, ?, 'Available', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
@@ -47,7 +67,7 @@ func (r *chargerConnectorRepository) GetByChargerAndConnector(ctx context.Contex
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("connector not found: %s/%d", chargerID, connectorID)
+			return nil, fmt.Errorf("%w: %s/%d", ErrConnectorNotFound, chargerID, connectorID)
 		}
 		return nil, fmt.Errorf("failed to get connector: %w", err)
 	}
@@ -86,11 +106,25 @@ func (r *chargerConnectorRepository) UpdateStatus(ctx context.Context, chargerID
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return fmt.Errorf("connector not found: %s/%d", chargerID, connectorID)
+		return fmt.Errorf("%w: %s/%d", ErrConnectorNotFound, chargerID, connectorID)
 	}
 	return nil
 }
 
+// UpdateStatusAll implements ChargerConnectorRepository.UpdateStatusAll
+func (r *chargerConnectorRepository) UpdateStatusAll(ctx context.Context, chargerID string, status string) (int, error) {
+	query := `UPDATE charger_connectors SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE charger_id = ?`
+	result, err := r.db.ExecContext(ctx, query, status, chargerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update connector statuses: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
 func (r *chargerConnectorRepository) UpdateError(ctx context.Context, chargerID string, connectorID int, errorCode, vendorErrorCode string) error {
 	query := `UPDATE charger_connectors SET error_code = ?, vendor_error_code = ?, updated_at = CURRENT_TIMESTAMP WHERE charger_id = ? AND connector_id = ?`
 	result, err := r.db.ExecContext(ctx, query, errorCode, vendorErrorCode, chargerID, connectorID)
@@ -99,7 +133,7 @@ func (r *chargerConnectorRepository) UpdateError(ctx context.Context, chargerID
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return fmt.Errorf("connector not found: %s/%d", chargerID, connectorID)
+		return fmt.Errorf("%w: %s/%d", ErrConnectorNotFound, chargerID, connectorID)
 	}
 	return nil
 }
@@ -114,33 +148,111 @@ func (r *chargerConnectorRepository) DeleteByChargerID(ctx context.Context, char
 	return err
 }
 
+// CountByStatus implements ChargerConnectorRepository.CountByStatus
+func (r *chargerConnectorRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM charger_connectors GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count connectors by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan connector count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetChargingWithoutTransaction implements ChargerConnectorRepository.GetChargingWithoutTransaction
+func (r *chargerConnectorRepository) GetChargingWithoutTransaction(ctx context.Context) ([]*ChargerConnector, error) {
+	query := `
+		SELECT c.id, c.charger_id, c.connector_id, c.status, c.error_code, c.vendor_error_code, c.created_at, c.updated_at
+		FROM charger_connectors c
+		WHERE c.status = 'Charging'
+		  AND NOT EXISTS (
+			SELECT 1 FROM transactions t
+			WHERE t.charger_id = c.charger_id AND t.connector_id = c.connector_id AND t.status = 'Active'
+		  )
+		ORDER BY c.charger_id, c.connector_id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get charging connectors without transaction: %w", err)
+	}
+	defer rows.Close()
+
+	var connectors []*ChargerConnector
+	for rows.Next() {
+		var conn ChargerConnector
+		err := rows.Scan(&conn.ID, &conn.ChargerID, &conn.ConnectorID, &conn.Status,
+			&conn.ErrorCode, &conn.VendorErrorCode, &conn.CreatedAt, &conn.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan connector: %w", err)
+		}
+		connectors = append(connectors, &conn)
+	}
+	return connectors, rows.Err()
+}
+
 // Meter Value Repository Implementation
 
+// ErrInvalidMeterValue is returned when a meter value to create lacks its
+// value, measurand or timestamp
+var ErrInvalidMeterValue = errors.New("invalid meter value")
+
 type meterValueRepository struct {
-	db     Executor
-	logger Logger
+	db            Executor
+	logger        Logger
+	integerEnergy bool
 }
 
 func NewMeterValueRepository(db Executor, logger Logger) MeterValueRepository {
 	return &meterValueRepository{db: db, logger: logger}
 }
 
+// valueWh returns the value_wh column for a sample: whole Wh for energy
+// measurands when integer energy is enabled, and NULL otherwise
+func (r *meterValueRepository) valueWh(req CreateMeterValueRequest) *int64 {
+	if !r.integerEnergy {
+		return nil
+	}
+	wh, ok := IntegerEnergy(req.Measurand, *req.Value, req.Unit)
+	if !ok {
+		return nil
+	}
+	return &wh
+}
+
 func (r *meterValueRepository) Create(ctx context.Context, req CreateMeterValueRequest) (*MeterValue, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
 	query := `
 		INSERT INTO meter_values (
 			transaction_id, charger_id, connector_id, timestamp, measurand, value, 
-			unit, context, location, phase, format, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			value_normalized, value_wh, unit, context, location, phase, format, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 		RETURNING id, transaction_id, charger_id, connector_id, timestamp, measurand, 
-				  value, unit, context, location, phase, format, created_at`
+				  value, value_normalized, unit, context, location, phase, format, created_at`
 
 	var mv MeterValue
 	err := r.db.QueryRowContext(ctx, query,
 		req.TransactionID, req.ChargerID, req.ConnectorID, req.Timestamp,
-		req.Measurand, req.Value, req.Unit, req.Context, req.Location, req.Phase, req.Format,
+		req.Measurand, *req.Value, NormalizeMeterValue(*req.Value, req.Unit), r.valueWh(req), req.Unit, req.Context, req.Location, req.Phase, req.Format,
 	).Scan(
 		&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
-		&mv.Measurand, &mv.Value, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt,
+		&mv.Measurand, &mv.Value, &mv.ValueNormalized, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create meter value: %w", err)
@@ -148,16 +260,92 @@ func (r *meterValueRepository) Create(ctx context.Context, req CreateMeterValueR
 	return &mv, nil
 }
 
+// sqliteMaxVariables is SQLite's default limit on bind variables per
+// statement before 3.32; newer builds allow more but may be configured lower
+const sqliteMaxVariables = 999
+
+// meterValueBatchRows bounds the rows per INSERT so that their 13 bind
+// variables each stay within sqliteMaxVariables
+const meterValueBatchRows = sqliteMaxVariables / 13
+
+// CreateBatch implements MeterValueRepository.CreateBatch
+func (r *meterValueRepository) CreateBatch(ctx context.Context, reqs []CreateMeterValueRequest) ([]*MeterValue, error) {
+	for i, req := range reqs {
+		if err := req.Validate(); err != nil {
+			return nil, fmt.Errorf("meter value %d: %w", i, err)
+		}
+	}
+
+	created := make([]*MeterValue, 0, len(reqs))
+	for start := 0; start < len(reqs); start += meterValueBatchRows {
+		end := min(start+meterValueBatchRows, len(reqs))
+		chunk := reqs[start:end]
+
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*13)
+		for i, req := range chunk {
+			placeholders[i] = "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)"
+			args = append(args,
+				req.TransactionID, req.ChargerID, req.ConnectorID, req.Timestamp,
+				req.Measurand, *req.Value, NormalizeMeterValue(*req.Value, req.Unit), r.valueWh(req), req.Unit, req.Context, req.Location, req.Phase, req.Format,
+			)
+		}
+
+		query := `
+			INSERT INTO meter_values (
+				transaction_id, charger_id, connector_id, timestamp, measurand, value,
+				value_normalized, value_wh, unit, context, location, phase, format, created_at
+			) VALUES ` + strings.Join(placeholders, ", ") + `
+			RETURNING id, transaction_id, charger_id, connector_id, timestamp, measurand,
+					  value, value_normalized, unit, context, location, phase, format, created_at`
+
+		rows, err := r.insertMeterValues(ctx, query, args)
+		if err != nil {
+			r.logger.Error("Failed to create meter value batch", "rows", len(chunk), "error", err)
+			return created, fmt.Errorf("failed to create meter value batch: %w", err)
+		}
+		created = append(created, rows...)
+	}
+
+	return created, nil
+}
+
+// insertMeterValues runs a multi-row INSERT ... RETURNING and returns the rows
+// in insertion order. SQLite does not order RETURNING output, but ids are
+// assigned in insertion order.
+func (r *meterValueRepository) insertMeterValues(ctx context.Context, query string, args []interface{}) ([]*MeterValue, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []*MeterValue
+	for rows.Next() {
+		mv, err := scanMeterValue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan meter value: %w", err)
+		}
+		values = append(values, mv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].ID < values[j].ID })
+	return values, nil
+}
+
 func (r *meterValueRepository) GetByID(ctx context.Context, id int) (*MeterValue, error) {
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand, 
-			   value, unit, context, location, phase, format, created_at
+			   value, value_normalized, unit, context, location, phase, format, created_at
 		FROM meter_values WHERE id = ?`
 
 	var mv MeterValue
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
-		&mv.Measurand, &mv.Value, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt,
+		&mv.Measurand, &mv.Value, &mv.ValueNormalized, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -171,115 +359,137 @@ func (r *meterValueRepository) GetByID(ctx context.Context, id int) (*MeterValue
 func (r *meterValueRepository) List(ctx context.Context, opts ListOptions) ([]*MeterValue, error) {
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand, 
-			   value, unit, context, location, phase, format, created_at
-		FROM meter_values ORDER BY timestamp DESC LIMIT ? OFFSET ?`
+			   value, value_normalized, unit, context, location, phase, format, created_at
+		FROM meter_values`
 
-	rows, err := r.db.QueryContext(ctx, query, opts.Limit, opts.Offset)
+	values, err := listQuery(ctx, r.db, query, nil, meterValueOrderDesc, opts, scanMeterValue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list meter values: %w", err)
 	}
-	defer rows.Close()
+	return values, nil
+}
 
-	var values []*MeterValue
-	for rows.Next() {
-		var mv MeterValue
-		err := rows.Scan(&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
-			&mv.Measurand, &mv.Value, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan meter value: %w", err)
-		}
-		values = append(values, &mv)
+// Meter value lists are always in timestamp order, whatever the ListOptions ask for
+var (
+	meterValueOrderDesc = listOrder{fallback: "timestamp", sortDir: "DESC"}
+	meterValueOrderAsc  = listOrder{fallback: "timestamp", sortDir: "ASC"}
+)
+
+// scanMeterValue scans a meter_values row selected with the list columns
+func scanMeterValue(row rowScanner) (*MeterValue, error) {
+	var mv MeterValue
+	err := row.Scan(&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
+		&mv.Measurand, &mv.Value, &mv.ValueNormalized, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt)
+	if err != nil {
+		return nil, err
 	}
-	return values, nil
+	return &mv, nil
 }
 
 func (r *meterValueRepository) GetByTransactionID(ctx context.Context, transactionID int, opts ListOptions) ([]*MeterValue, error) {
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand, 
-			   value, unit, context, location, phase, format, created_at
-		FROM meter_values WHERE transaction_id = ? ORDER BY timestamp ASC LIMIT ? OFFSET ?`
+			   value, value_normalized, unit, context, location, phase, format, created_at
+		FROM meter_values WHERE transaction_id = ?`
 
-	rows, err := r.db.QueryContext(ctx, query, transactionID, opts.Limit, opts.Offset)
+	values, err := listQuery(ctx, r.db, query, []interface{}{transactionID}, meterValueOrderAsc, opts, scanMeterValue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get meter values by transaction: %w", err)
 	}
-	defer rows.Close()
+	return values, nil
+}
 
-	var values []*MeterValue
-	for rows.Next() {
-		var mv MeterValue
-		err := rows.Scan(&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
-			&mv.Measurand, &mv.Value, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt)
+// meterValueTransactionChunk bounds the transaction IDs per GetByTransactionIDs query
+const meterValueTransactionChunk = 500
+
+// GetByTransactionIDs implements MeterValueRepository.GetByTransactionIDs.
+// Each chunk of IDs is one query; ROW_NUMBER pages every transaction's
+// values separately, in the order GetByTransactionID uses.
+func (r *meterValueRepository) GetByTransactionIDs(ctx context.Context, transactionIDs []int, opts ListOptions) (map[int][]*MeterValue, error) {
+	grouped := make(map[int][]*MeterValue)
+	order := meterValueOrderAsc.clause(opts)
+
+	for start := 0; start < len(transactionIDs); start += meterValueTransactionChunk {
+		end := min(start+meterValueTransactionChunk, len(transactionIDs))
+		chunk := transactionIDs[start:end]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+		query := fmt.Sprintf(`
+			SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand,
+				   value, value_normalized, unit, context, location, phase, format, created_at
+			FROM (
+				SELECT *, ROW_NUMBER() OVER (PARTITION BY transaction_id ORDER BY %s) AS row_num
+				FROM meter_values WHERE transaction_id IN (%s)
+			)
+			WHERE row_num > ? AND row_num <= ?
+			ORDER BY transaction_id, row_num`, order, placeholders)
+
+		args := make([]interface{}, 0, len(chunk)+2)
+		for _, id := range chunk {
+			args = append(args, id)
+		}
+		args = append(args, opts.Offset, opts.Offset+opts.Limit)
+
+		rows, err := r.db.QueryContext(ctx, query, args...)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan meter value: %w", err)
+			return nil, fmt.Errorf("failed to get meter values by transactions: %w", err)
+		}
+
+		for rows.Next() {
+			mv, err := scanMeterValue(rows)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan meter value: %w", err)
+			}
+			grouped[*mv.TransactionID] = append(grouped[*mv.TransactionID], mv)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("row iteration error: %w", err)
 		}
-		values = append(values, &mv)
 	}
-	return values, nil
+
+	return grouped, nil
 }
 
 func (r *meterValueRepository) GetByChargerID(ctx context.Context, chargerID string, opts ListOptions) ([]*MeterValue, error) {
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand, 
-			   value, unit, context, location, phase, format, created_at
-		FROM meter_values WHERE charger_id = ? ORDER BY timestamp DESC LIMIT ? OFFSET ?`
+			   value, value_normalized, unit, context, location, phase, format, created_at
+		FROM meter_values WHERE charger_id = ?`
 
-	rows, err := r.db.QueryContext(ctx, query, chargerID, opts.Limit, opts.Offset)
+	values, err := listQuery(ctx, r.db, query, []interface{}{chargerID}, meterValueOrderDesc, opts, scanMeterValue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get meter values by charger: %w", err)
 	}
-	defer rows.Close()
-
-	var values []*MeterValue
-	for rows.Next() {
-		var mv MeterValue
-		err := rows.Scan(&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
-			&mv.Measurand, &mv.Value, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan meter value: %w", err)
-		}
-		values = append(values, &mv)
-	}
 	return values, nil
 }
 
 func (r *meterValueRepository) GetByTimeRange(ctx context.Context, chargerID string, start, end time.Time, opts ListOptions) ([]*MeterValue, error) {
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand, 
-			   value, unit, context, location, phase, format, created_at
-		FROM meter_values WHERE charger_id = ? AND timestamp BETWEEN ? AND ? 
-		ORDER BY timestamp ASC LIMIT ? OFFSET ?`
+			   value, value_normalized, unit, context, location, phase, format, created_at
+		FROM meter_values WHERE charger_id = ? AND timestamp BETWEEN ? AND ?`
 
-	rows, err := r.db.QueryContext(ctx, query, chargerID, start, end, opts.Limit, opts.Offset)
+	values, err := listQuery(ctx, r.db, query, []interface{}{chargerID, start, end}, meterValueOrderAsc, opts, scanMeterValue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get meter values by time range: %w", err)
 	}
-	defer rows.Close()
-
-	var values []*MeterValue
-	for rows.Next() {
-		var mv MeterValue
-		err := rows.Scan(&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
-			&mv.Measurand, &mv.Value, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan meter value: %w", err)
-		}
-		values = append(values, &mv)
-	}
 	return values, nil
 }
 
 func (r *meterValueRepository) GetLatestByConnector(ctx context.Context, chargerID string, connectorID int) (*MeterValue, error) {
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand, 
-			   value, unit, context, location, phase, format, created_at
+			   value, value_normalized, unit, context, location, phase, format, created_at
 		FROM meter_values WHERE charger_id = ? AND connector_id = ? 
 		ORDER BY timestamp DESC LIMIT 1`
 
 	var mv MeterValue
 	err := r.db.QueryRowContext(ctx, query, chargerID, connectorID).Scan(
 		&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
-		&mv.Measurand, &mv.Value, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt,
+		&mv.Measurand, &mv.Value, &mv.ValueNormalized, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -290,29 +500,38 @@ func (r *meterValueRepository) GetLatestByConnector(ctx context.Context, charger
 	return &mv, nil
 }
 
+func (r *meterValueRepository) GetLatestMeasurandByConnector(ctx context.Context, chargerID string, connectorID int, measurand string, at time.Time) (*MeterValue, error) {
+	query := `
+		SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand,
+			   value, value_normalized, unit, context, location, phase, format, created_at
+		FROM meter_values
+		WHERE charger_id = ? AND connector_id = ? AND measurand = ? AND phase = '' AND timestamp <= ?
+		ORDER BY timestamp DESC LIMIT 1`
+
+	var mv MeterValue
+	err := r.db.QueryRowContext(ctx, query, chargerID, connectorID, measurand, at.UTC()).Scan(
+		&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
+		&mv.Measurand, &mv.Value, &mv.ValueNormalized, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // No meter values is not an error
+		}
+		return nil, fmt.Errorf("failed to get latest %s meter value: %w", measurand, err)
+	}
+	return &mv, nil
+}
+
 func (r *meterValueRepository) GetByMeasurand(ctx context.Context, chargerID string, measurand string, opts ListOptions) ([]*MeterValue, error) {
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, timestamp, measurand, 
-			   value, unit, context, location, phase, format, created_at
-		FROM meter_values WHERE charger_id = ? AND measurand = ? 
-		ORDER BY timestamp DESC LIMIT ? OFFSET ?`
+			   value, value_normalized, unit, context, location, phase, format, created_at
+		FROM meter_values WHERE charger_id = ? AND measurand = ?`
 
-	rows, err := r.db.QueryContext(ctx, query, chargerID, measurand, opts.Limit, opts.Offset)
+	values, err := listQuery(ctx, r.db, query, []interface{}{chargerID, measurand}, meterValueOrderDesc, opts, scanMeterValue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get meter values by measurand: %w", err)
 	}
-	defer rows.Close()
-
-	var values []*MeterValue
-	for rows.Next() {
-		var mv MeterValue
-		err := rows.Scan(&mv.ID, &mv.TransactionID, &mv.ChargerID, &mv.ConnectorID, &mv.Timestamp,
-			&mv.Measurand, &mv.Value, &mv.Unit, &mv.Context, &mv.Location, &mv.Phase, &mv.Format, &mv.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan meter value: %w", err)
-		}
-		values = append(values, &mv)
-	}
 	return values, nil
 }
 
@@ -326,6 +545,197 @@ func (r *meterValueRepository) DeleteOlderThan(ctx context.Context, cutoff time.
 	return int(rowsAffected), nil
 }
 
+// DeleteMeasurandOlderThan implements MeterValueRepository.DeleteMeasurandOlderThan
+func (r *meterValueRepository) DeleteMeasurandOlderThan(ctx context.Context, measurand string, cutoff time.Time) (int, error) {
+	query := `DELETE FROM meter_values WHERE measurand = ? AND created_at < ?`
+	result, err := r.db.ExecContext(ctx, query, measurand, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old %s meter values: %w", measurand, err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return int(rowsAffected), nil
+}
+
+// DeleteOlderThanExcept implements MeterValueRepository.DeleteOlderThanExcept
+func (r *meterValueRepository) DeleteOlderThanExcept(ctx context.Context, cutoff time.Time, measurands []string) (int, error) {
+	if len(measurands) == 0 {
+		return r.DeleteOlderThan(ctx, cutoff)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(measurands)), ", ")
+	query := `DELETE FROM meter_values WHERE created_at < ? AND measurand NOT IN (` + placeholders + `)`
+
+	args := make([]interface{}, 0, len(measurands)+1)
+	args = append(args, cutoff)
+	for _, measurand := range measurands {
+		args = append(args, measurand)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old meter values: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return int(rowsAffected), nil
+}
+
+// DeleteOrphaned implements MeterValueRepository.DeleteOrphaned
+func (r *meterValueRepository) DeleteOrphaned(ctx context.Context) (int, error) {
+	query := `
+		DELETE FROM meter_values
+		WHERE transaction_id IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.id = meter_values.transaction_id)`
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete orphaned meter values: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected > 0 {
+		r.logger.Info("Deleted orphaned meter values", "count", rowsAffected)
+	}
+	return int(rowsAffected), nil
+}
+
+// AssignTransaction implements MeterValueRepository.AssignTransaction
+func (r *meterValueRepository) AssignTransaction(ctx context.Context, chargerID string, connectorID int, transactionID int, since time.Time) (int, error) {
+	query := `
+		UPDATE meter_values SET transaction_id = ?
+		WHERE charger_id = ? AND connector_id = ? AND transaction_id IS NULL
+		  AND unassociated = FALSE AND timestamp >= ?`
+	result, err := r.db.ExecContext(ctx, query, transactionID, chargerID, connectorID, since.UTC())
+	if err != nil {
+		r.logger.Error("Failed to assign meter values to transaction",
+			"charger_id", chargerID, "connector_id", connectorID, "transaction_id", transactionID, "error", err)
+		return 0, fmt.Errorf("failed to assign meter values to transaction: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return int(rowsAffected), nil
+}
+
+// FlagUnassociated implements MeterValueRepository.FlagUnassociated
+func (r *meterValueRepository) FlagUnassociated(ctx context.Context, cutoff time.Time) (int, error) {
+	query := `
+		UPDATE meter_values SET unassociated = TRUE
+		WHERE transaction_id IS NULL AND unassociated = FALSE AND timestamp < ?`
+	result, err := r.db.ExecContext(ctx, query, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to flag unassociated meter values: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return int(rowsAffected), nil
+}
+
+// CountUnassociated implements MeterValueRepository.CountUnassociated
+func (r *meterValueRepository) CountUnassociated(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM meter_values WHERE unassociated = TRUE`
+	var count int
+	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unassociated meter values: %w", err)
+	}
+	return count, nil
+}
+
+// GetRangeByTransaction implements MeterValueRepository.GetRangeByTransaction.
+// Samples stored as whole Wh are compared by their integer value.
+func (r *meterValueRepository) GetRangeByTransaction(ctx context.Context, transactionID int, measurand string) (*MeasurandRange, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(MIN(COALESCE(value_wh, value_normalized)), 0),
+			   COALESCE(MAX(COALESCE(value_wh, value_normalized)), 0)
+		FROM meter_values
+		WHERE transaction_id = ? AND measurand = ? AND phase = ''`
+
+	var rng MeasurandRange
+	err := r.db.QueryRowContext(ctx, query, transactionID, measurand).Scan(&rng.Count, &rng.Min, &rng.Max)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s range: %w", measurand, err)
+	}
+	return &rng, nil
+}
+
+// GetSeries buckets samples on whole multiples of the bucket size since the
+// Unix epoch, so series of the same bucket size line up across requests
+func (r *meterValueRepository) GetSeries(ctx context.Context, chargerID string, connectorID int, measurand string, start, end time.Time, bucket time.Duration) ([]SeriesPoint, error) {
+	size := int64(bucket / time.Second)
+	if size <= 0 {
+		return nil, fmt.Errorf("series bucket must be at least one second: %s", bucket)
+	}
+
+	query := `
+		SELECT CAST(strftime('%s', timestamp) AS INTEGER) / ? AS bucket,
+			   COUNT(*), AVG(value_normalized), MIN(value_normalized), MAX(value_normalized)
+		FROM meter_values
+		WHERE charger_id = ? AND connector_id = ? AND measurand = ? AND timestamp >= ? AND timestamp < ?
+		GROUP BY bucket
+		ORDER BY bucket`
+
+	rows, err := r.db.QueryContext(ctx, query, size, chargerID, connectorID, measurand, start.UTC(), end.UTC())
+	if err != nil {
+		r.logger.Error("Failed to get meter value series", "charger_id", chargerID, "measurand", measurand, "error", err)
+		return nil, fmt.Errorf("failed to get meter value series: %w", err)
+	}
+	defer rows.Close()
+
+	filled := make(map[int64]SeriesPoint)
+	for rows.Next() {
+		var index int64
+		var point SeriesPoint
+		var avg, low, high float64
+		if err := rows.Scan(&index, &point.Count, &avg, &low, &high); err != nil {
+			return nil, fmt.Errorf("failed to scan meter value series: %w", err)
+		}
+		point.Avg, point.Min, point.Max = &avg, &low, &high
+		filled[index] = point
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	var series []SeriesPoint
+	for index := start.Unix() / size; index*size < end.Unix(); index++ {
+		point := filled[index]
+		point.Start = time.Unix(index*size, 0).UTC()
+		series = append(series, point)
+	}
+	return series, nil
+}
+
+// SumByMeasurand implements MeterValueRepository.SumByMeasurand. Samples
+// stored as whole Wh are summed as integers, so their total is exact.
+// DistinctMeasurands implements MeterValueRepository.DistinctMeasurands
+func (r *meterValueRepository) DistinctMeasurands(ctx context.Context, chargerID string) ([]string, error) {
+	query := `SELECT DISTINCT measurand FROM meter_values WHERE charger_id = ? ORDER BY measurand`
+	rows, err := r.db.QueryContext(ctx, query, chargerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list measurands: %w", err)
+	}
+	defer rows.Close()
+
+	var measurands []string
+	for rows.Next() {
+		var measurand string
+		if err := rows.Scan(&measurand); err != nil {
+			return nil, fmt.Errorf("failed to scan measurand: %w", err)
+		}
+		measurands = append(measurands, measurand)
+	}
+	return measurands, rows.Err()
+}
+
+func (r *meterValueRepository) SumByMeasurand(ctx context.Context, chargerID string, measurand string, start, end time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(value_wh), 0), COALESCE(SUM(CASE WHEN value_wh IS NULL THEN value_normalized END), 0)
+		FROM meter_values
+		WHERE charger_id = ? AND measurand = ? AND timestamp BETWEEN ? AND ?`
+	var whSum int64
+	var floatSum float64
+	err := r.db.QueryRowContext(ctx, query, chargerID, measurand, start, end).Scan(&whSum, &floatSum)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum meter values: %w", err)
+	}
+	return float64(whSum) + floatSum, nil
+}
+
 func (r *meterValueRepository) Count(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM meter_values`
 	var count int
@@ -341,16 +751,17 @@ func (r *meterValueRepository) Count(ctx context.Context) (int, error) {
 type chargerErrorRepository struct {
 	db     Executor
 	logger Logger
+	clock  clock.Clock
 }
 
-func NewChargerErrorRepository(db Executor, logger Logger) ChargerErrorRepository {
-	return &chargerErrorRepository{db: db, logger: logger}
+func NewChargerErrorRepository(db Executor, logger Logger, clk clock.Clock) ChargerErrorRepository {
+	return &chargerErrorRepository{db: db, logger: logger, clock: clk}
 }
 
 func (r *chargerErrorRepository) Create(ctx context.Context, req CreateChargerErrorRequest) (*ChargerError, error) {
 	timestamp := req.Timestamp
 	if timestamp.IsZero() {
-		timestamp = time.Now()
+		timestamp = r.clock.Now()
 	}
 
 	query := `
@@ -395,53 +806,109 @@ func (r *chargerErrorRepository) GetByID(ctx context.Context, id int) (*ChargerE
 	return &err, nil
 }
 
-func (r *chargerErrorRepository) List(ctx context.Context, opts ListOptions) ([]*ChargerError, error) {
-	query := `
+func (r *chargerErrorRepository) List(ctx context.Context, filter ErrorFilter, opts ListOptions) ([]*ChargerError, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.ChargerID != "" {
+		conditions = append(conditions, "charger_id = ?")
+		args = append(args, filter.ChargerID)
+	}
+	if filter.ErrorCode != "" {
+		conditions = append(conditions, "error_code = ?")
+		args = append(args, filter.ErrorCode)
+	}
+	if filter.Resolved != nil {
+		if *filter.Resolved {
+			conditions = append(conditions, "resolved_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "resolved_at IS NULL")
+		}
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, *filter.Since)
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, *filter.Until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
 		SELECT id, charger_id, connector_id, error_code, vendor_error_code, 
 			   error_description, vendor_error_info, timestamp, resolved_at, created_at
-		FROM charger_errors ORDER BY timestamp DESC LIMIT ? OFFSET ?`
+		FROM charger_errors %s`, where)
 
-	rows, err := r.db.QueryContext(ctx, query, opts.Limit, opts.Offset)
+	order := listOrder{fields: chargerErrorOrderFields, fallback: "timestamp", tiebreak: true}
+	errors, err := listQuery(ctx, r.db, query, args, order, opts, scanChargerError)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list charger errors: %w", err)
 	}
+	return errors, nil
+}
+
+// chargerErrorOrderFields are the columns charger error lists may be ordered by
+var chargerErrorOrderFields = map[string]bool{
+	"timestamp": true, "charger_id": true, "error_code": true, "resolved_at": true, "created_at": true,
+}
+
+// scanChargerError scans a charger_errors row selected with the list columns
+func scanChargerError(row rowScanner) (*ChargerError, error) {
+	var cerr ChargerError
+	err := row.Scan(&cerr.ID, &cerr.ChargerID, &cerr.ConnectorID, &cerr.ErrorCode, &cerr.VendorErrorCode,
+		&cerr.ErrorDescription, &cerr.VendorErrorInfo, &cerr.Timestamp, &cerr.ResolvedAt, &cerr.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &cerr, nil
+}
+
+// TopErrorCodes implements ChargerErrorRepository.TopErrorCodes
+func (r *chargerErrorRepository) TopErrorCodes(ctx context.Context, start, end time.Time, limit int) ([]ErrorCodeCount, error) {
+	query := `
+		SELECT error_code, COUNT(*) AS count
+		FROM charger_errors WHERE timestamp BETWEEN ? AND ?
+		GROUP BY error_code
+		ORDER BY count DESC, error_code ASC
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top error codes: %w", err)
+	}
 	defer rows.Close()
 
-	var errors []*ChargerError
+	var counts []ErrorCodeCount
 	for rows.Next() {
-		var cerr ChargerError
-		err := rows.Scan(&cerr.ID, &cerr.ChargerID, &cerr.ConnectorID, &cerr.ErrorCode, &cerr.VendorErrorCode,
-			&cerr.ErrorDescription, &cerr.VendorErrorInfo, &cerr.Timestamp, &cerr.ResolvedAt, &cerr.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan charger error: %w", err)
+		var c ErrorCodeCount
+		if err := rows.Scan(&c.ErrorCode, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan error code count: %w", err)
 		}
-		errors = append(errors, &cerr)
+		counts = append(counts, c)
 	}
-	return errors, nil
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return counts, nil
 }
 
 func (r *chargerErrorRepository) GetByChargerID(ctx context.Context, chargerID string, opts ListOptions) ([]*ChargerError, error) {
 	query := `
 		SELECT id, charger_id, connector_id, error_code, vendor_error_code, 
 			   error_description, vendor_error_info, timestamp, resolved_at, created_at
-		FROM charger_errors WHERE charger_id = ? ORDER BY timestamp DESC LIMIT ? OFFSET ?`
+		FROM charger_errors WHERE charger_id = ?`
 
-	rows, err := r.db.QueryContext(ctx, query, chargerID, opts.Limit, opts.Offset)
+	errors, err := listQuery(ctx, r.db, query, []interface{}{chargerID}, listOrder{fallback: "timestamp", sortDir: "DESC"}, opts, scanChargerError)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get charger errors by charger ID: %w", err)
 	}
-	defer rows.Close()
-
-	var errors []*ChargerError
-	for rows.Next() {
-		var cerr ChargerError
-		err := rows.Scan(&cerr.ID, &cerr.ChargerID, &cerr.ConnectorID, &cerr.ErrorCode, &cerr.VendorErrorCode,
-			&cerr.ErrorDescription, &cerr.VendorErrorInfo, &cerr.Timestamp, &cerr.ResolvedAt, &cerr.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan charger error: %w", err)
-		}
-		errors = append(errors, &cerr)
-	}
 	return errors, nil
 }
 
@@ -508,9 +975,13 @@ func (r *chargerErrorRepository) Resolve(ctx context.Context, id int, resolvedAt
 	return nil
 }
 
-func (r *chargerErrorRepository) ResolveByErrorCode(ctx context.Context, chargerID string, errorCode string, resolvedAt time.Time) (int, error) {
-	query := `UPDATE charger_errors SET resolved_at = ? WHERE charger_id = ? AND error_code = ? AND resolved_at IS NULL`
-	result, err := r.db.ExecContext(ctx, query, resolvedAt, chargerID, errorCode)
+// ResolveByErrorCode implements ChargerErrorRepository.ResolveByErrorCode.
+// Errors without a connector belong to the charge point, connector 0.
+func (r *chargerErrorRepository) ResolveByErrorCode(ctx context.Context, chargerID string, connectorID int, errorCode string, resolvedAt time.Time) (int, error) {
+	query := `
+		UPDATE charger_errors SET resolved_at = ?
+		WHERE charger_id = ? AND COALESCE(connector_id, 0) = ? AND error_code = ? AND resolved_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, resolvedAt, chargerID, connectorID, errorCode)
 	if err != nil {
 		return 0, fmt.Errorf("failed to resolve charger errors by error code: %w", err)
 	}
@@ -518,6 +989,36 @@ func (r *chargerErrorRepository) ResolveByErrorCode(ctx context.Context, charger
 	return int(rowsAffected), nil
 }
 
+// ResolveMatching implements ChargerErrorRepository.ResolveMatching
+func (r *chargerErrorRepository) ResolveMatching(ctx context.Context, filter ResolveErrorsFilter, resolvedAt time.Time) (int, error) {
+	conditions := []string{"resolved_at IS NULL"}
+	args := []interface{}{resolvedAt}
+
+	if filter.ChargerID != "" {
+		conditions = append(conditions, "charger_id = ?")
+		args = append(args, filter.ChargerID)
+	}
+	if filter.ErrorCode != "" {
+		conditions = append(conditions, "error_code = ?")
+		args = append(args, filter.ErrorCode)
+	}
+	if len(filter.IDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.IDs)), ", ")
+		conditions = append(conditions, "id IN ("+placeholders+")")
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+
+	query := `UPDATE charger_errors SET resolved_at = ? WHERE ` + strings.Join(conditions, " AND ")
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve charger errors: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return int(rowsAffected), nil
+}
+
 func (r *chargerErrorRepository) DeleteOldResolved(ctx context.Context, cutoff time.Time) (int, error) {
 	query := `DELETE FROM charger_errors WHERE resolved_at IS NOT NULL AND resolved_at < ?`
 	result, err := r.db.ExecContext(ctx, query, cutoff)
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// outboxRepository implements OutboxRepository
+type outboxRepository struct {
+	db     Executor
+	logger Logger
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db Executor, logger Logger) OutboxRepository {
+	return &outboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Append implements OutboxRepository.Append
+func (r *outboxRepository) Append(ctx context.Context, req CreateOutboxEventRequest) (*OutboxEvent, error) {
+	query := `
+		INSERT INTO outbox (topic, payload, status, attempts, created_at)
+		VALUES (?, ?, 'Pending', 0, CURRENT_TIMESTAMP)
+		RETURNING id, topic, payload, status, attempts, last_error, published_at, created_at`
+
+	var e OutboxEvent
+	err := r.db.QueryRowContext(ctx, query, req.Topic, req.Payload).Scan(
+		&e.ID, &e.Topic, &e.Payload, &e.Status, &e.Attempts, &e.LastError, &e.PublishedAt, &e.CreatedAt,
+	)
+	if err != nil {
+		r.logger.Error("Failed to append outbox event", "topic", req.Topic, "error", err)
+		return nil, fmt.Errorf("failed to append outbox event: %w", err)
+	}
+
+	r.logger.Debug("Appended outbox event", "id", e.ID, "topic", e.Topic)
+	return &e, nil
+}
+
+// GetByID implements OutboxRepository.GetByID
+func (r *outboxRepository) GetByID(ctx context.Context, id int) (*OutboxEvent, error) {
+	query := `
+		SELECT id, topic, payload, status, attempts, last_error, published_at, created_at
+		FROM outbox WHERE id = ?`
+
+	var e OutboxEvent
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&e.ID, &e.Topic, &e.Payload, &e.Status, &e.Attempts, &e.LastError, &e.PublishedAt, &e.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("outbox event not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get outbox event: %w", err)
+	}
+
+	return &e, nil
+}
+
+// GetPending implements OutboxRepository.GetPending
+func (r *outboxRepository) GetPending(ctx context.Context, limit int) ([]*OutboxEvent, error) {
+	query := `
+		SELECT id, topic, payload, status, attempts, last_error, published_at, created_at
+		FROM outbox
+		WHERE status = 'Pending'
+		ORDER BY id ASC
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		r.logger.Error("Failed to get pending outbox events", "error", err)
+		return nil, fmt.Errorf("failed to get pending outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		err := rows.Scan(
+			&e.ID, &e.Topic, &e.Payload, &e.Status, &e.Attempts, &e.LastError, &e.PublishedAt, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, &e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return events, nil
+}
+
+// MarkPublished implements OutboxRepository.MarkPublished
+func (r *outboxRepository) MarkPublished(ctx context.Context, id int, publishedAt time.Time) error {
+	query := `UPDATE outbox SET status = 'Published', published_at = ?, last_error = '' WHERE id = ?`
+
+	return r.exec(ctx, "mark outbox event published", id, query, publishedAt.UTC(), id)
+}
+
+// MarkFailed implements OutboxRepository.MarkFailed
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int, lastError string) error {
+	query := `UPDATE outbox SET attempts = attempts + 1, last_error = ? WHERE id = ?`
+
+	return r.exec(ctx, "mark outbox event failed", id, query, lastError, id)
+}
+
+// CountPending implements OutboxRepository.CountPending
+func (r *outboxRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM outbox WHERE status = 'Pending'`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending outbox events: %w", err)
+	}
+	return count, nil
+}
+
+// exec runs a single-row update and reports a missing row as not found
+func (r *outboxRepository) exec(ctx context.Context, op string, id int, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to "+op, "id", id, "error", err)
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("outbox event not found: %d", id)
+	}
+
+	return nil
+}
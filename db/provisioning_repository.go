@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/keeth/levity/core/clock"
+)
+
+// provisioningRepository implements ProvisioningRepository
+type provisioningRepository struct {
+	db     Executor
+	logger Logger
+	clock  clock.Clock
+}
+
+// NewProvisioningRepository creates a new provisioning repository
+func NewProvisioningRepository(db Executor, logger Logger, clk clock.Clock) ProvisioningRepository {
+	return &provisioningRepository{
+		db:     db,
+		logger: logger,
+		clock:  clk,
+	}
+}
+
+// Get implements ProvisioningRepository.Get
+func (r *provisioningRepository) Get(ctx context.Context, chargerID string) (*ChargerProvisioning, error) {
+	query := `
+		SELECT charger_id, profile, version, applied_at
+		FROM charger_provisioning WHERE charger_id = ?`
+
+	var p ChargerProvisioning
+	err := r.db.QueryRowContext(ctx, query, chargerID).Scan(&p.ChargerID, &p.Profile, &p.Version, &p.AppliedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get provisioning for %s: %w", chargerID, err)
+	}
+
+	return &p, nil
+}
+
+// Record implements ProvisioningRepository.Record
+func (r *provisioningRepository) Record(ctx context.Context, chargerID, profile string, version int) error {
+	query := `
+		INSERT INTO charger_provisioning (charger_id, profile, version, applied_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(charger_id) DO UPDATE SET
+			profile = excluded.profile, version = excluded.version, applied_at = excluded.applied_at`
+
+	if _, err := r.db.ExecContext(ctx, query, chargerID, profile, version, r.clock.Now().UTC()); err != nil {
+		r.logger.Error("Failed to record provisioning", "charger_id", chargerID, "profile", profile, "error", err)
+		return fmt.Errorf("failed to record provisioning for %s: %w", chargerID, err)
+	}
+
+	r.logger.Debug("Recorded provisioning", "charger_id", chargerID, "profile", profile, "version", version)
+	return nil
+}
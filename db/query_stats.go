@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// QueryStats counts the queries made, and the time spent in them, on behalf
+// of one context, such as a single HTTP request. It is safe for concurrent use.
+type QueryStats struct {
+	count    atomic.Int64
+	duration atomic.Int64
+}
+
+// queryStatsKey is the context key holding a *QueryStats
+type queryStatsKey struct{}
+
+// WithQueryStats returns a context whose queries are counted in the returned stats
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{}
+	return context.WithValue(ctx, queryStatsKey{}, stats), stats
+}
+
+// Count returns the number of queries made
+func (s *QueryStats) Count() int {
+	return int(s.count.Load())
+}
+
+// Duration returns the total time spent in queries
+func (s *QueryStats) Duration() time.Duration {
+	return time.Duration(s.duration.Load())
+}
+
+// record adds one query of duration d
+func (s *QueryStats) record(d time.Duration) {
+	s.count.Add(1)
+	s.duration.Add(int64(d))
+}
+
+// statsExecutor decorates an Executor, recording every query in the
+// QueryStats of its context, if any. For QueryContext the time is until the
+// first rows are ready, not until they have all been read.
+type statsExecutor struct {
+	Executor
+}
+
+// ExecContext implements Executor.ExecContext
+func (e statsExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer recordQuery(ctx, time.Now())
+	return e.Executor.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements Executor.QueryContext
+func (e statsExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer recordQuery(ctx, time.Now())
+	return e.Executor.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext implements Executor.QueryRowContext
+func (e statsExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer recordQuery(ctx, time.Now())
+	return e.Executor.QueryRowContext(ctx, query, args...)
+}
+
+// recordQuery records a query started at start in the stats of ctx
+func recordQuery(ctx context.Context, start time.Time) {
+	if stats, ok := ctx.Value(queryStatsKey{}).(*QueryStats); ok {
+		stats.record(time.Since(start))
+	}
+}
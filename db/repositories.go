@@ -39,7 +39,8 @@ type ChargerRepository interface {
 	// Update status
 	UpdateStatus(ctx context.Context, id string, status string) error
 
-	// Update timestamps
+	// Update timestamps; UpdateLastHeartbeat fails with ErrChargerNotFound for
+	// an unknown charger
 	UpdateLastHeartbeat(ctx context.Context, id string, timestamp time.Time) error
 	UpdateLastBoot(ctx context.Context, id string, timestamp time.Time) error
 	UpdateLastConnect(ctx context.Context, id string, timestamp time.Time) error
@@ -51,6 +52,12 @@ type ChargerRepository interface {
 
 	// Get chargers by status
 	GetByStatus(ctx context.Context, status string) ([]*Charger, error)
+
+	// Get the local authorization list version last synced to a charger (0 if never synced)
+	GetLocalListVersion(ctx context.Context, id string) (int, error)
+
+	// Record the local authorization list version accepted by a charger
+	SetLocalListVersion(ctx context.Context, id string, version int, syncedAt time.Time) error
 }
 
 // ChargerConnectorRepository defines the interface for connector data operations
@@ -58,7 +65,7 @@ type ChargerConnectorRepository interface {
 	// Create connector
 	Create(ctx context.Context, chargerID string, connectorID int) (*ChargerConnector, error)
 
-	// Get connector by charger and connector ID
+	// Get connector by charger and connector ID (ErrConnectorNotFound if it does not exist)
 	GetByChargerAndConnector(ctx context.Context, chargerID string, connectorID int) (*ChargerConnector, error)
 
 	// Get all connectors for a charger
@@ -67,6 +74,9 @@ type ChargerConnectorRepository interface {
 	// Update connector status
 	UpdateStatus(ctx context.Context, chargerID string, connectorID int, status string) error
 
+	// Update the status of every connector of a charger, returning how many changed
+	UpdateStatusAll(ctx context.Context, chargerID string, status string) (int, error)
+
 	// Update connector error
 	UpdateError(ctx context.Context, chargerID string, connectorID int, errorCode, vendorErrorCode string) error
 
@@ -75,20 +85,29 @@ type ChargerConnectorRepository interface {
 
 	// Delete connectors for charger
 	DeleteByChargerID(ctx context.Context, chargerID string) error
+
+	// Get connectors in Charging status with no active transaction
+	GetChargingWithoutTransaction(ctx context.Context) ([]*ChargerConnector, error)
+
+	// Count connectors by status
+	CountByStatus(ctx context.Context) (map[string]int, error)
 }
 
 // TransactionRepository defines the interface for transaction data operations
 type TransactionRepository interface {
-	// Create a new transaction
+	// Create a new transaction; a supplied OCPP transaction ID outside the
+	// int32 range fails with ErrTransactionIDOutOfRange, and a connector with
+	// an active transaction fails with ErrConnectorTransactionActive
 	Create(ctx context.Context, req CreateTransactionRequest) (*Transaction, error)
 
-	// Get transaction by ID
+	// Get transaction by ID (ErrTransactionNotFound if none)
 	GetByID(ctx context.Context, id int) (*Transaction, error)
 
-	// Get transaction by OCPP transaction ID
+	// Get transaction by OCPP transaction ID (ErrTransactionNotFound if none)
 	GetByTransactionID(ctx context.Context, transactionID int) (*Transaction, error)
 
-	// Update transaction
+	// Update transaction; a status change the current status cannot make
+	// fails with ErrInvalidTransactionTransition
 	Update(ctx context.Context, id int, req UpdateTransactionRequest) (*Transaction, error)
 
 	// Delete transaction
@@ -97,6 +116,9 @@ type TransactionRepository interface {
 	// List transactions with optional filtering
 	List(ctx context.Context, opts ListOptions) ([]*Transaction, error)
 
+	// Search transactions matching every set field of the filter
+	Search(ctx context.Context, filter TransactionFilter, opts ListOptions) ([]*Transaction, error)
+
 	// Get transactions by charger
 	GetByChargerID(ctx context.Context, chargerID string, opts ListOptions) ([]*Transaction, error)
 
@@ -106,8 +128,16 @@ type TransactionRepository interface {
 	// Get active transaction for connector
 	GetActiveByConnector(ctx context.Context, chargerID string, connectorID int) (*Transaction, error)
 
-	// Stop transaction
-	Stop(ctx context.Context, id int, meterStop int, stopTime time.Time, stopReason string) error
+	// Get active transactions whose connector is not in a charging session status
+	GetActiveWithoutChargingConnector(ctx context.Context) ([]*Transaction, error)
+
+	// Stop transaction, recording which side ended it (one of the StopSource
+	// constants); only an Active or Faulted transaction can be stopped, any other
+	// fails with ErrInvalidTransactionTransition
+	Stop(ctx context.Context, id int, meterStop int, stopTime time.Time, stopReason, stopSource string) error
+
+	// Record that a transaction kept a charger timestamp skewed by skew
+	FlagClockSkew(ctx context.Context, id int, skew time.Duration) error
 
 	// Count transactions
 	Count(ctx context.Context) (int, error)
@@ -115,15 +145,24 @@ type TransactionRepository interface {
 	// Count transactions by charger
 	CountByChargerID(ctx context.Context, chargerID string) (int, error)
 
-	// Generate next OCPP transaction ID
+	// Generate next OCPP transaction ID, always within the int32 range
 	GenerateTransactionID(ctx context.Context) (int, error)
+
+	// Count transactions whose OCPP transaction ID is outside the int32 range
+	CountOutOfRangeIDs(ctx context.Context) (int, error)
 }
 
 // MeterValueRepository defines the interface for meter value data operations
 type MeterValueRepository interface {
-	// Create meter value record
+	// Create meter value record; a request without a value, measurand or
+	// timestamp fails with ErrInvalidMeterValue
 	Create(ctx context.Context, req CreateMeterValueRequest) (*MeterValue, error)
 
+	// Create many meter values with multi-row inserts, returning the created
+	// rows in request order; on error, the rows stored before it. An invalid
+	// request fails the batch with ErrInvalidMeterValue before any is stored.
+	CreateBatch(ctx context.Context, reqs []CreateMeterValueRequest) ([]*MeterValue, error)
+
 	// Get meter value by ID
 	GetByID(ctx context.Context, id int) (*MeterValue, error)
 
@@ -133,6 +172,10 @@ type MeterValueRepository interface {
 	// Get meter values by transaction
 	GetByTransactionID(ctx context.Context, transactionID int, opts ListOptions) ([]*MeterValue, error)
 
+	// Get meter values of many transactions keyed by transaction ID, with opts
+	// paging each transaction's values; transactions without values are absent
+	GetByTransactionIDs(ctx context.Context, transactionIDs []int, opts ListOptions) (map[int][]*MeterValue, error)
+
 	// Get meter values by charger
 	GetByChargerID(ctx context.Context, chargerID string, opts ListOptions) ([]*MeterValue, error)
 
@@ -142,12 +185,47 @@ type MeterValueRepository interface {
 	// Get latest meter value for connector
 	GetLatestByConnector(ctx context.Context, chargerID string, connectorID int) (*MeterValue, error)
 
+	// Get the latest whole-connector (no phase) value of a measurand taken at or before a time
+	GetLatestMeasurandByConnector(ctx context.Context, chargerID string, connectorID int, measurand string, at time.Time) (*MeterValue, error)
+
 	// Get meter values by measurand
 	GetByMeasurand(ctx context.Context, chargerID string, measurand string, opts ListOptions) ([]*MeterValue, error)
 
 	// Delete old meter values (for cleanup)
 	DeleteOlderThan(ctx context.Context, cutoff time.Time) (int, error)
 
+	// Delete old meter values of a single measurand
+	DeleteMeasurandOlderThan(ctx context.Context, measurand string, cutoff time.Time) (int, error)
+
+	// Delete old meter values of every measurand except the given ones
+	DeleteOlderThanExcept(ctx context.Context, cutoff time.Time, measurands []string) (int, error)
+
+	// Delete meter values whose transaction no longer exists; values taken
+	// outside a transaction (NULL transaction_id) are kept
+	DeleteOrphaned(ctx context.Context) (int, error)
+
+	// Get the min and max whole-connector (no phase) normalized value of a measurand in a transaction
+	GetRangeByTransaction(ctx context.Context, transactionID int, measurand string) (*MeasurandRange, error)
+
+	// Get avg/min/max normalized values of a measurand on a connector per time
+	// bucket in [start, end), including empty buckets
+	GetSeries(ctx context.Context, chargerID string, connectorID int, measurand string, start, end time.Time, bucket time.Duration) ([]SeriesPoint, error)
+
+	// List the distinct measurands recorded for a charger, sorted by name
+	DistinctMeasurands(ctx context.Context, chargerID string) ([]string, error)
+
+	// Sum normalized values of a measurand on a charger within a time range
+	SumByMeasurand(ctx context.Context, chargerID string, measurand string, start, end time.Time) (float64, error)
+
+	// Link unreconciled meter values on a connector taken at or after since to a transaction
+	AssignTransaction(ctx context.Context, chargerID string, connectorID int, transactionID int, since time.Time) (int, error)
+
+	// Flag unreconciled meter values taken before the cutoff as unassociated
+	FlagUnassociated(ctx context.Context, cutoff time.Time) (int, error)
+
+	// Count meter values flagged as unassociated
+	CountUnassociated(ctx context.Context) (int, error)
+
 	// Count meter values
 	Count(ctx context.Context) (int, error)
 }
@@ -160,8 +238,8 @@ type ChargerErrorRepository interface {
 	// Get error by ID
 	GetByID(ctx context.Context, id int) (*ChargerError, error)
 
-	// List errors with filtering
-	List(ctx context.Context, opts ListOptions) ([]*ChargerError, error)
+	// List errors matching the filter
+	List(ctx context.Context, filter ErrorFilter, opts ListOptions) ([]*ChargerError, error)
 
 	// Get errors by charger
 	GetByChargerID(ctx context.Context, chargerID string, opts ListOptions) ([]*ChargerError, error)
@@ -172,11 +250,17 @@ type ChargerErrorRepository interface {
 	// Get active errors by charger
 	GetActiveByChargerID(ctx context.Context, chargerID string) ([]*ChargerError, error)
 
+	// Get the most frequent error codes reported within a time range
+	TopErrorCodes(ctx context.Context, start, end time.Time, limit int) ([]ErrorCodeCount, error)
+
 	// Resolve error
 	Resolve(ctx context.Context, id int, resolvedAt time.Time) error
 
-	// Resolve errors by error code
-	ResolveByErrorCode(ctx context.Context, chargerID string, errorCode string, resolvedAt time.Time) (int, error)
+	// Resolve a connector's open errors with an error code; connector 0 is the charge point
+	ResolveByErrorCode(ctx context.Context, chargerID string, connectorID int, errorCode string, resolvedAt time.Time) (int, error)
+
+	// Resolve the active errors matching a filter, returning how many were resolved
+	ResolveMatching(ctx context.Context, filter ResolveErrorsFilter, resolvedAt time.Time) (int, error)
 
 	// Delete old resolved errors (for cleanup)
 	DeleteOldResolved(ctx context.Context, cutoff time.Time) (int, error)
@@ -188,6 +272,218 @@ type ChargerErrorRepository interface {
 	CountActive(ctx context.Context) (int, error)
 }
 
+// IDTagRepository defines the interface for ID tag data operations
+type IDTagRepository interface {
+	// Create ID tag
+	Create(ctx context.Context, req CreateIDTagRequest) (*IDTag, error)
+
+	// Get ID tag (ErrIDTagNotFound if it does not exist)
+	GetByIDTag(ctx context.Context, idTag string) (*IDTag, error)
+
+	// List ID tags
+	List(ctx context.Context, opts ListOptions) ([]*IDTag, error)
+
+	// Get every ID tag, ordered by tag, for building a full local list
+	GetAll(ctx context.Context) ([]*IDTag, error)
+
+	// Update ID tag status
+	UpdateStatus(ctx context.Context, idTag string, status string) error
+
+	// Delete ID tag
+	Delete(ctx context.Context, idTag string) error
+}
+
+// ReservationRepository defines the interface for connector reservation operations
+type ReservationRepository interface {
+	// Create reservation
+	Create(ctx context.Context, req CreateReservationRequest) (*Reservation, error)
+
+	// Get reservation by OCPP reservation ID
+	GetByID(ctx context.Context, id int) (*Reservation, error)
+
+	// Get the active, unexpired reservation covering a connector at the given time
+	GetActiveByConnector(ctx context.Context, chargerID string, connectorID int, at time.Time) (*Reservation, error)
+
+	// Update reservation status
+	UpdateStatus(ctx context.Context, id int, status string) error
+}
+
+// AuditLogRepository defines the interface for audit log operations
+type AuditLogRepository interface {
+	// Record an audit entry, attributed to the context actor when none is given
+	Record(ctx context.Context, req CreateAuditEntryRequest) (*AuditEntry, error)
+
+	// List audit entries, newest first
+	List(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error)
+}
+
+// CommandHistoryRepository defines the interface for outbound command history
+type CommandHistoryRepository interface {
+	// Record a sent command, attributed to the context actor when no operator is given
+	Record(ctx context.Context, req CreateCommandHistoryRequest) (*CommandHistoryEntry, error)
+
+	// List the commands sent to a charger, newest first
+	ListByCharger(ctx context.Context, chargerID string, opts ListOptions) ([]*CommandHistoryEntry, error)
+}
+
+// ChargerConnectionRepository defines the interface for charger connection history
+type ChargerConnectionRepository interface {
+	// Record a newly opened connection
+	Open(ctx context.Context, req CreateChargerConnectionRequest) (*ChargerConnection, error)
+
+	// Record that a connection closed, setting its duration
+	Close(ctx context.Context, id int, disconnectedAt time.Time, reason string) error
+
+	// List the connections of a charger, newest first
+	ListByCharger(ctx context.Context, chargerID string, opts ListOptions) ([]*ChargerConnection, error)
+
+	// Count the connections of a charger that closed at or after since
+	CountDisconnects(ctx context.Context, chargerID string, since time.Time) (int, error)
+
+	// Delete closed connections that ended before the cutoff
+	DeleteOlderThan(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// SettingsRepository defines the interface for persistent key-value settings
+type SettingsRepository interface {
+	// Get a setting, reporting whether it is set
+	Get(ctx context.Context, key string) (string, bool, error)
+
+	// Set a setting, replacing any previous value
+	Set(ctx context.Context, key string, value string) error
+
+	// Get a boolean setting, or def when it is not set
+	GetBool(ctx context.Context, key string, def bool) (bool, error)
+
+	// Get an integer setting, or def when it is not set
+	GetInt(ctx context.Context, key string, def int) (int, error)
+}
+
+// WebhookOutboxRepository defines the interface for webhook outbox operations
+type WebhookOutboxRepository interface {
+	// Queue a webhook for delivery
+	Enqueue(ctx context.Context, req CreateWebhookDeliveryRequest) (*WebhookDelivery, error)
+
+	// Get delivery by ID
+	GetByID(ctx context.Context, id int) (*WebhookDelivery, error)
+
+	// Get pending deliveries due at or before the given time
+	GetDue(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error)
+
+	// Mark delivery as delivered
+	MarkDelivered(ctx context.Context, id int, deliveredAt time.Time) error
+
+	// Record a failed attempt and schedule the next one
+	MarkFailed(ctx context.Context, id int, nextAttemptAt time.Time, lastError string) error
+
+	// Record a failed attempt and stop retrying
+	MarkDeadLetter(ctx context.Context, id int, lastError string) error
+}
+
+// OutboxRepository defines the interface for domain event outbox operations
+type OutboxRepository interface {
+	// Write an event to the outbox
+	Append(ctx context.Context, req CreateOutboxEventRequest) (*OutboxEvent, error)
+
+	// Get event by ID
+	GetByID(ctx context.Context, id int) (*OutboxEvent, error)
+
+	// Get pending events in the order they were written
+	GetPending(ctx context.Context, limit int) ([]*OutboxEvent, error)
+
+	// Mark an event as published
+	MarkPublished(ctx context.Context, id int, publishedAt time.Time) error
+
+	// Record a failed publish attempt, leaving the event pending
+	MarkFailed(ctx context.Context, id int, lastError string) error
+
+	// Count pending events
+	CountPending(ctx context.Context) (int, error)
+}
+
+// FileTransferRepository defines the interface for firmware update and log upload tracking
+type FileTransferRepository interface {
+	// Record a requested file transfer with the Requested status
+	Create(ctx context.Context, req CreateFileTransferRequest) (*FileTransfer, error)
+
+	// Update the status and log file name of a file transfer
+	Update(ctx context.Context, id int, status, filename string) (*FileTransfer, error)
+
+	// Update the status of the charger's transfer of a kind, identified by its
+	// requestId or, when nil, the most recent one. Returns nil when none matches.
+	UpdateStatus(ctx context.Context, chargerID, kind string, requestID *int, status string) (*FileTransfer, error)
+
+	// List the file transfers of a charger, newest first
+	ListByCharger(ctx context.Context, chargerID string, opts ListOptions) ([]*FileTransfer, error)
+}
+
+// SecurityEventRepository defines the interface for charger security events
+type SecurityEventRepository interface {
+	// Record a security event reported by a charger
+	Create(ctx context.Context, req CreateSecurityEventRequest) (*SecurityEvent, error)
+
+	// List security events, newest first
+	List(ctx context.Context, filter SecurityEventFilter) ([]*SecurityEvent, error)
+}
+
+// ProvisioningRepository defines the interface for the provisioning profiles
+// pushed to chargers
+type ProvisioningRepository interface {
+	// Get the profile last pushed to a charger, or nil when none was
+	Get(ctx context.Context, chargerID string) (*ChargerProvisioning, error)
+
+	// Record that a profile version was pushed to a charger
+	Record(ctx context.Context, chargerID, profile string, version int) error
+}
+
+// SiteRepository defines the interface for sites and the chargers in them
+type SiteRepository interface {
+	// Create a new site
+	Create(ctx context.Context, req CreateSiteRequest) (*Site, error)
+
+	// Get a site by ID, or nil when there is none
+	GetByID(ctx context.Context, id string) (*Site, error)
+
+	// Get the site a charger belongs to, or nil when it is in none
+	GetByChargerID(ctx context.Context, chargerID string) (*Site, error)
+
+	// Update a site
+	Update(ctx context.Context, id string, req UpdateSiteRequest) (*Site, error)
+
+	// Delete a site; its chargers are left without a site
+	Delete(ctx context.Context, id string) error
+
+	// List sites with pagination
+	List(ctx context.Context, opts ListOptions) ([]*Site, error)
+
+	// Add a charger to a site, moving it out of any other site
+	AddCharger(ctx context.Context, siteID, chargerID string) error
+
+	// Remove a charger from a site, reporting whether it was a member
+	RemoveCharger(ctx context.Context, siteID, chargerID string) (bool, error)
+
+	// Get the ids of the chargers in a site, sorted
+	ChargerIDs(ctx context.Context, siteID string) ([]string, error)
+}
+
+// DeadLetterRepository defines the interface for failed charger CALLs kept for replay
+type DeadLetterRepository interface {
+	// Store a failed CALL
+	Create(ctx context.Context, req CreateDeadLetterRequest) (*DeadLetter, error)
+
+	// Get a dead letter by ID; fails with ErrDeadLetterNotFound when there is none
+	GetByID(ctx context.Context, id int) (*DeadLetter, error)
+
+	// List dead letters matching the filter with pagination
+	List(ctx context.Context, filter DeadLetterFilter, opts ListOptions) ([]*DeadLetter, error)
+
+	// Mark a dead letter resolved after a successful replay
+	MarkResolved(ctx context.Context, id int) error
+
+	// Record a failed replay, keeping the dead letter unresolved
+	RecordReplayFailure(ctx context.Context, id int, lastError string) error
+}
+
 // RepositoryManager aggregates all repositories
 type RepositoryManager interface {
 	Chargers() ChargerRepository
@@ -195,6 +491,19 @@ type RepositoryManager interface {
 	Transactions() TransactionRepository
 	MeterValues() MeterValueRepository
 	Errors() ChargerErrorRepository
+	Webhooks() WebhookOutboxRepository
+	Outbox() OutboxRepository
+	IDTags() IDTagRepository
+	Reservations() ReservationRepository
+	Audit() AuditLogRepository
+	Settings() SettingsRepository
+	Commands() CommandHistoryRepository
+	Connections() ChargerConnectionRepository
+	FileTransfers() FileTransferRepository
+	SecurityEvents() SecurityEventRepository
+	Provisioning() ProvisioningRepository
+	Sites() SiteRepository
+	DeadLetters() DeadLetterRepository
 
 	// Transaction management
 	BeginTx(ctx context.Context) (TxManager, error)
@@ -210,6 +519,19 @@ type TxManager interface {
 	Transactions() TransactionRepository
 	MeterValues() MeterValueRepository
 	Errors() ChargerErrorRepository
+	Webhooks() WebhookOutboxRepository
+	Outbox() OutboxRepository
+	IDTags() IDTagRepository
+	Reservations() ReservationRepository
+	Audit() AuditLogRepository
+	Settings() SettingsRepository
+	Commands() CommandHistoryRepository
+	Connections() ChargerConnectionRepository
+	FileTransfers() FileTransferRepository
+	SecurityEvents() SecurityEventRepository
+	Provisioning() ProvisioningRepository
+	Sites() SiteRepository
+	DeadLetters() DeadLetterRepository
 
 	// Transaction control
 	Commit() error
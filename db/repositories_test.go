@@ -0,0 +1,555 @@
+package db_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTransactionIDFitsInt32(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	// Ids used to be derived from the clock in milliseconds, which overflows int32
+	id, err := repos.Transactions().GenerateTransactionID(ctx)
+	require.NoError(t, err)
+	assert.Positive(t, id)
+	assert.LessOrEqual(t, id, math.MaxInt32)
+}
+
+func TestChargerErrorDefaultTimestampUsesClock(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 3, 15, 8, 30, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	chargerErr, err := repos.Errors().Create(ctx, db.CreateChargerErrorRequest{ChargerID: "CP-1", ErrorCode: "OverVoltage"})
+	require.NoError(t, err)
+	assert.True(t, fake.Now().Equal(chargerErr.Timestamp))
+}
+
+func TestChargerNotes(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	charger, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	assert.Empty(t, charger.Notes)
+
+	notes := "Behind the gate, ask reception for access"
+	updated, err := repos.Chargers().Update(ctx, "CP-1", db.UpdateChargerRequest{Notes: &notes})
+	require.NoError(t, err)
+	assert.Equal(t, notes, updated.Notes)
+
+	fetched, err := repos.Chargers().GetByID(ctx, "CP-1")
+	require.NoError(t, err)
+	assert.Equal(t, notes, fetched.Notes)
+}
+
+func TestMeterValueNormalization(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	samples := []struct {
+		value float64
+		unit  string
+	}{
+		{500, db.UnitWh},
+		{1.5, db.UnitKWh},
+		{250, ""},
+		{0.75, db.UnitKWh},
+	}
+	for i, s := range samples {
+		_, err := repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			ChargerID:   "CP-1",
+			ConnectorID: 1,
+			Timestamp:   start.Add(time.Duration(i) * time.Minute),
+			Measurand:   "Energy.Active.Import.Interval",
+			Value:       dbtest.Float64(s.value),
+			Unit:        s.unit,
+		})
+		require.NoError(t, err)
+	}
+
+	latest, err := repos.MeterValues().GetLatestByConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0.75, latest.Value)
+	assert.Equal(t, db.UnitKWh, latest.Unit)
+	assert.Equal(t, 750.0, latest.ValueNormalized)
+
+	total, err := repos.MeterValues().SumByMeasurand(ctx, "CP-1", "Energy.Active.Import.Interval", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 3000.0, total)
+}
+
+func TestMeterValueCreateBatch(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	// Enough rows to span several INSERT statements
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reqs := make([]db.CreateMeterValueRequest, 1201)
+	for i := range reqs {
+		reqs[i] = db.CreateMeterValueRequest{
+			ChargerID:   "CP-1",
+			ConnectorID: 1,
+			Timestamp:   start.Add(time.Duration(i) * time.Second),
+			Measurand:   "Energy.Active.Import.Interval",
+			Value:       dbtest.Float64(0.001),
+			Unit:        db.UnitKWh,
+		}
+	}
+
+	created, err := repos.MeterValues().CreateBatch(ctx, reqs)
+	require.NoError(t, err)
+	require.Len(t, created, len(reqs))
+	for i, mv := range created {
+		assert.True(t, reqs[i].Timestamp.Equal(mv.Timestamp), "row %d out of order", i)
+		if i > 0 {
+			assert.Greater(t, mv.ID, created[i-1].ID)
+		}
+	}
+	assert.Equal(t, 1.0, created[0].ValueNormalized)
+
+	total, err := repos.MeterValues().SumByMeasurand(ctx, "CP-1", "Energy.Active.Import.Interval", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.InDelta(t, 1201.0, total, 1e-6)
+
+	created, err = repos.MeterValues().CreateBatch(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+}
+
+func TestMeterValueValidation(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	valid := db.CreateMeterValueRequest{
+		ChargerID: "CP-1", ConnectorID: 1, Timestamp: at,
+		Measurand: "Current.Import", Value: dbtest.Float64(0), Unit: "A",
+	}
+
+	// A zero reading, such as no current while paused, is a real sample
+	mv, err := repos.MeterValues().Create(ctx, valid)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, mv.Value)
+	latest, err := repos.MeterValues().GetLatestByConnector(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, mv.ID, latest.ID)
+
+	noValue := valid
+	noValue.Value = nil
+	noMeasurand := valid
+	noMeasurand.Measurand = ""
+	noTimestamp := valid
+	noTimestamp.Timestamp = time.Time{}
+
+	for name, req := range map[string]db.CreateMeterValueRequest{
+		"value":     noValue,
+		"measurand": noMeasurand,
+		"timestamp": noTimestamp,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := repos.MeterValues().Create(ctx, req)
+			assert.ErrorIs(t, err, db.ErrInvalidMeterValue)
+
+			// One invalid request rejects the whole batch
+			created, err := repos.MeterValues().CreateBatch(ctx, []db.CreateMeterValueRequest{valid, req})
+			assert.ErrorIs(t, err, db.ErrInvalidMeterValue)
+			assert.Empty(t, created)
+		})
+	}
+
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestMeterValueGetByTransactionIDs(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repos := dbtest.NewRepositories(t, clock.NewFake(start))
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	// Transaction 1 has three readings, 2 has two and 3 has none
+	readings := map[int]int{1: 3, 2: 2, 3: 0}
+	for txID := 1; txID <= 3; txID++ {
+		// Created in order, so each OCPP transaction id matches its row id
+		id, count := txID, readings[txID]
+		_, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+			TransactionID: &id, ChargerID: "CP-1", ConnectorID: txID, IDTag: "TAG", StartTime: &start,
+		})
+		require.NoError(t, err)
+
+		// Insert newest first so the results are ordered by timestamp, not id
+		for i := count - 1; i >= 0; i-- {
+			_, err := repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+				TransactionID: &id,
+				ChargerID:     "CP-1",
+				ConnectorID:   txID,
+				Timestamp:     start.Add(time.Duration(i) * time.Minute),
+				Measurand:     "Energy.Active.Import.Register",
+				Value:         dbtest.Float64(float64(txID*1000 + i)),
+				Unit:          db.UnitWh,
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	values := func(grouped map[int][]*db.MeterValue, txID int) []float64 {
+		var result []float64
+		for _, mv := range grouped[txID] {
+			require.Equal(t, txID, *mv.TransactionID)
+			result = append(result, mv.Value)
+		}
+		return result
+	}
+
+	// Unknown IDs pad the list past a single query
+	ids := []int{2, 1, 3}
+	for id := 100; id < 1300; id++ {
+		ids = append(ids, id)
+	}
+
+	grouped, err := repos.MeterValues().GetByTransactionIDs(ctx, ids, db.ListOptions{Limit: 100})
+	require.NoError(t, err)
+	assert.Len(t, grouped, 2)
+	assert.Equal(t, []float64{1000, 1001, 1002}, values(grouped, 1))
+	assert.Equal(t, []float64{2000, 2001}, values(grouped, 2))
+	assert.NotContains(t, grouped, 3)
+
+	// Paging applies to each transaction separately
+	grouped, err = repos.MeterValues().GetByTransactionIDs(ctx, []int{1, 2}, db.ListOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1001}, values(grouped, 1))
+	assert.Equal(t, []float64{2001}, values(grouped, 2))
+
+	grouped, err = repos.MeterValues().GetByTransactionIDs(ctx, nil, db.DefaultListOptions())
+	require.NoError(t, err)
+	assert.Empty(t, grouped)
+}
+
+func TestMeterValueDeleteOrphaned(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	database := dbtest.NewDatabase(t)
+	repos := db.NewRepositoryManager(database, dbtest.Logger(), clock.NewFake(start))
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	ocppTxID := 1
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		TransactionID: &ocppTxID, ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG", StartTime: &start,
+	})
+	require.NoError(t, err)
+
+	reading := func(transactionID *int) db.CreateMeterValueRequest {
+		return db.CreateMeterValueRequest{
+			TransactionID: transactionID,
+			ChargerID:     "CP-1",
+			ConnectorID:   1,
+			Timestamp:     start,
+			Measurand:     "Energy.Active.Import.Register",
+			Value:         dbtest.Float64(1000),
+		}
+	}
+	_, err = repos.MeterValues().Create(ctx, reading(&tx.ID))
+	require.NoError(t, err)
+	_, err = repos.MeterValues().Create(ctx, reading(nil))
+	require.NoError(t, err)
+
+	// Dangling references predate foreign key enforcement, so write them with it off
+	conn, err := database.GetDB().Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`)
+	require.NoError(t, err)
+	for range 2 {
+		_, err = conn.ExecContext(ctx, `
+			INSERT INTO meter_values (transaction_id, charger_id, connector_id, timestamp, measurand, value)
+			VALUES (999, 'CP-1', 1, ?, 'Energy.Active.Import.Register', 1000)`, start)
+		require.NoError(t, err)
+	}
+	_, err = conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	require.NoError(t, err)
+
+	deleted, err := repos.MeterValues().DeleteOrphaned(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	// The transaction's reading and the transaction-less one both survive
+	count, err := repos.MeterValues().Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	values, err := repos.MeterValues().GetByTransactionID(ctx, tx.ID, db.DefaultListOptions())
+	require.NoError(t, err)
+	assert.Len(t, values, 1)
+
+	deleted, err = repos.MeterValues().DeleteOrphaned(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestMeterValueIntegerEnergy(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// 1.001 kWh is 1000.9999999999999 Wh as a float, so float totals drift
+	reqs := make([]db.CreateMeterValueRequest, 1201)
+	for i := range reqs {
+		reqs[i] = db.CreateMeterValueRequest{
+			ChargerID:   "CP-1",
+			ConnectorID: 1,
+			Timestamp:   start.Add(time.Duration(i) * time.Second),
+			Measurand:   "Energy.Active.Import.Interval",
+			Value:       dbtest.Float64(1.001),
+			Unit:        db.UnitKWh,
+		}
+	}
+	sum := func(repos db.RepositoryManager) float64 {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+		require.NoError(t, err)
+		_, err = repos.MeterValues().CreateBatch(ctx, reqs)
+		require.NoError(t, err)
+		total, err := repos.MeterValues().SumByMeasurand(ctx, "CP-1", "Energy.Active.Import.Interval", start, start.Add(time.Hour))
+		require.NoError(t, err)
+		return total
+	}
+
+	assert.NotEqual(t, 1202201.0, sum(dbtest.NewRepositories(t, clock.Real())))
+	assert.Equal(t, 1202201.0, sum(dbtest.NewRepositories(t, clock.Real(), db.WithIntegerEnergy(true))))
+}
+
+func TestMeterValueIntegerEnergyKeepsInstantaneousFloats(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real(), db.WithIntegerEnergy(true))
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, value := range []float64{7.25, 7.5} {
+		_, err := repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			ChargerID:   "CP-1",
+			ConnectorID: 1,
+			Timestamp:   start.Add(time.Duration(i) * time.Minute),
+			Measurand:   "Current.Import",
+			Value:       dbtest.Float64(value),
+			Unit:        "A",
+		})
+		require.NoError(t, err)
+	}
+
+	total, err := repos.MeterValues().SumByMeasurand(ctx, "CP-1", "Current.Import", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 14.75, total)
+}
+
+func TestIntegerEnergy(t *testing.T) {
+	wh, ok := db.IntegerEnergy("Energy.Active.Import.Register", 12345.6785, db.UnitKWh)
+	assert.True(t, ok)
+	assert.Equal(t, int64(12345679), wh)
+
+	wh, ok = db.IntegerEnergy("Energy.Reactive.Import.Register", 250, "")
+	assert.True(t, ok)
+	assert.Equal(t, int64(250), wh)
+
+	_, ok = db.IntegerEnergy("Power.Active.Import", 7400, "W")
+	assert.False(t, ok)
+
+	_, ok = db.IntegerEnergy("Energy.Active.Import.Register", 10, "Percent")
+	assert.False(t, ok)
+}
+
+func TestMeterValueSeries(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	samples := []struct {
+		offset    time.Duration
+		value     float64
+		connector int
+	}{
+		{0, 1000, 1}, // first sample on the start boundary
+		{4*time.Minute + 59*time.Second, 3000, 1},
+		{5 * time.Minute, 7000, 1}, // starts the second bucket
+		{6 * time.Minute, 9000, 2}, // another connector
+		{15*time.Minute + 500*time.Millisecond, 2000, 1},
+		{20 * time.Minute, 4000, 1}, // on the end boundary, excluded
+	}
+	for _, s := range samples {
+		_, err := repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			ChargerID:   "CP-1",
+			ConnectorID: s.connector,
+			Timestamp:   start.Add(s.offset),
+			Measurand:   "Power.Active.Import",
+			Value:       dbtest.Float64(s.value),
+			Unit:        "W",
+		})
+		require.NoError(t, err)
+	}
+
+	series, err := repos.MeterValues().GetSeries(ctx, "CP-1", 1, "Power.Active.Import", start, start.Add(20*time.Minute), 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, series, 4)
+
+	want := []struct {
+		count         int
+		avg, min, max float64
+	}{
+		{2, 2000, 1000, 3000},
+		{1, 7000, 7000, 7000},
+		{0, 0, 0, 0}, // empty bucket
+		{1, 2000, 2000, 2000},
+	}
+	for i, w := range want {
+		point := series[i]
+		assert.Equal(t, start.Add(time.Duration(i)*5*time.Minute), point.Start)
+		assert.Equal(t, w.count, point.Count)
+		if w.count == 0 {
+			assert.Nil(t, point.Avg)
+			assert.Nil(t, point.Min)
+			assert.Nil(t, point.Max)
+			continue
+		}
+		require.NotNil(t, point.Avg)
+		assert.Equal(t, w.avg, *point.Avg)
+		assert.Equal(t, w.min, *point.Min)
+		assert.Equal(t, w.max, *point.Max)
+	}
+
+	// Buckets align to the epoch, not to the start of the range
+	series, err = repos.MeterValues().GetSeries(ctx, "CP-1", 1, "Power.Active.Import", start.Add(2*time.Minute), start.Add(6*time.Minute), 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	assert.Equal(t, start, series[0].Start)
+	assert.Equal(t, 1, series[0].Count)
+	assert.Equal(t, 1, series[1].Count)
+
+	_, err = repos.MeterValues().GetSeries(ctx, "CP-1", 1, "Power.Active.Import", start, start.Add(time.Hour), time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestConnectorZeroHasNoRow(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	_, err = repos.Connectors().Create(ctx, "CP-1", db.ChargePointConnectorID)
+	assert.ErrorIs(t, err, db.ErrChargePointConnector)
+
+	_, err = repos.Connectors().Create(ctx, "CP-1", 1)
+	require.NoError(t, err)
+	_, err = repos.Connectors().Create(ctx, "CP-1", 2)
+	require.NoError(t, err)
+
+	updated, err := repos.Connectors().UpdateStatusAll(ctx, "CP-1", "Unavailable")
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated)
+
+	connectors, err := repos.Connectors().GetByChargerID(ctx, "CP-1")
+	require.NoError(t, err)
+	require.Len(t, connectors, 2)
+	for _, conn := range connectors {
+		assert.NotEqual(t, db.ChargePointConnectorID, conn.ConnectorID)
+		assert.Equal(t, "Unavailable", conn.Status)
+	}
+}
+
+func TestChargerNumConnectors(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+
+	charger, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1", NumConnectors: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, charger.NumConnectors)
+
+	four := 4
+	updated, err := repos.Chargers().Update(ctx, "CP-1", db.UpdateChargerRequest{NumConnectors: &four})
+	require.NoError(t, err)
+	assert.Equal(t, 4, updated.NumConnectors)
+}
+
+// meterValueMessage is one MeterValues message worth of samples: an energy
+// register plus voltage, current and power on three phases, twice over
+func meterValueMessage(at time.Time) []db.CreateMeterValueRequest {
+	reqs := []db.CreateMeterValueRequest{}
+	for i := range 2 {
+		timestamp := at.Add(time.Duration(i) * time.Second)
+		reqs = append(reqs, db.CreateMeterValueRequest{
+			ChargerID: "CP-1", ConnectorID: 1, Timestamp: timestamp,
+			Measurand: "Energy.Active.Import.Register", Value: dbtest.Float64(12500), Unit: db.UnitWh,
+		})
+		for _, phase := range []string{"L1", "L2", "L3"} {
+			for _, measurand := range []string{"Voltage", "Current.Import", "Power.Active.Import"} {
+				reqs = append(reqs, db.CreateMeterValueRequest{
+					ChargerID: "CP-1", ConnectorID: 1, Timestamp: timestamp,
+					Measurand: measurand, Value: dbtest.Float64(230), Phase: phase,
+				})
+			}
+		}
+	}
+	return reqs
+}
+
+func BenchmarkMeterValueCreate(b *testing.B) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(b, clock.Real())
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(b, err)
+	reqs := meterValueMessage(time.Now())
+
+	b.ResetTimer()
+	for range b.N {
+		for _, req := range reqs {
+			if _, err := repos.MeterValues().Create(ctx, req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkMeterValueCreateBatch(b *testing.B) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(b, clock.Real())
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(b, err)
+	reqs := meterValueMessage(time.Now())
+
+	b.ResetTimer()
+	for range b.N {
+		if _, err := repos.MeterValues().CreateBatch(ctx, reqs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -4,40 +4,116 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/keeth/levity/core/clock"
 )
 
 // repositoryManager implements RepositoryManager
 type repositoryManager struct {
 	db              *Database
+	clock           clock.Clock
+	audit           bool
+	integerEnergy   bool
 	chargerRepo     ChargerRepository
 	connectorRepo   ChargerConnectorRepository
 	transactionRepo TransactionRepository
 	meterValueRepo  MeterValueRepository
 	errorRepo       ChargerErrorRepository
+	webhookRepo     WebhookOutboxRepository
+	outboxRepo      OutboxRepository
+	idTagRepo       IDTagRepository
+	reservationRepo ReservationRepository
+	auditRepo       AuditLogRepository
+	settingsRepo    SettingsRepository
+	commandRepo     CommandHistoryRepository
+	connectionRepo  ChargerConnectionRepository
+	transferRepo    FileTransferRepository
+	securityRepo    SecurityEventRepository
+	provisionRepo   ProvisioningRepository
+	siteRepo        SiteRepository
+	deadLetterRepo  DeadLetterRepository
 }
 
 // txRepositoryManager implements TxManager for transactional operations
 type txRepositoryManager struct {
 	tx              *sql.Tx
+	db              *Database
 	chargerRepo     ChargerRepository
 	connectorRepo   ChargerConnectorRepository
 	transactionRepo TransactionRepository
 	meterValueRepo  MeterValueRepository
 	errorRepo       ChargerErrorRepository
+	webhookRepo     WebhookOutboxRepository
+	outboxRepo      OutboxRepository
+	idTagRepo       IDTagRepository
+	reservationRepo ReservationRepository
+	auditRepo       AuditLogRepository
+	settingsRepo    SettingsRepository
+	commandRepo     CommandHistoryRepository
+	connectionRepo  ChargerConnectionRepository
+	transferRepo    FileTransferRepository
+	securityRepo    SecurityEventRepository
+	provisionRepo   ProvisioningRepository
+	siteRepo        SiteRepository
+	deadLetterRepo  DeadLetterRepository
+}
+
+// RepositoryOption configures a repository manager
+type RepositoryOption func(*repositoryManager)
+
+// WithAudit records charger and ID tag mutations in the audit log when enabled
+func WithAudit(enabled bool) RepositoryOption {
+	return func(rm *repositoryManager) {
+		rm.audit = enabled
+	}
+}
+
+// WithIntegerEnergy stores energy measurands as whole Wh alongside the float
+// value when enabled, so aggregated totals are exact
+func WithIntegerEnergy(enabled bool) RepositoryOption {
+	return func(rm *repositoryManager) {
+		rm.integerEnergy = enabled
+	}
 }
 
 // NewRepositoryManager creates a new repository manager
-func NewRepositoryManager(database *Database, logger Logger) RepositoryManager {
-	db := database.GetDB()
+func NewRepositoryManager(database *Database, logger Logger, clk clock.Clock, opts ...RepositoryOption) RepositoryManager {
+	// Queries are counted in the QueryStats of their context, if any
+	var db Executor = statsExecutor{faultExecutor{database.GetDB(), database}}
 
-	return &repositoryManager{
+	rm := &repositoryManager{
 		db:              database,
+		clock:           clk,
 		chargerRepo:     NewChargerRepository(db, logger),
 		connectorRepo:   NewChargerConnectorRepository(db, logger),
-		transactionRepo: NewTransactionRepository(db, logger),
-		meterValueRepo:  NewMeterValueRepository(db, logger),
-		errorRepo:       NewChargerErrorRepository(db, logger),
+		transactionRepo: NewTransactionRepository(db, logger, clk),
+		errorRepo:       NewChargerErrorRepository(db, logger, clk),
+		webhookRepo:     NewWebhookOutboxRepository(db, logger),
+		outboxRepo:      NewOutboxRepository(db, logger),
+		idTagRepo:       NewIDTagRepository(db, logger),
+		reservationRepo: NewReservationRepository(db, logger),
+		auditRepo:       NewAuditLogRepository(db, logger, clk),
+		settingsRepo:    NewSettingsRepository(db, logger),
+		commandRepo:     NewCommandHistoryRepository(db, logger, clk),
+		connectionRepo:  NewChargerConnectionRepository(db, logger),
+		transferRepo:    NewFileTransferRepository(db, logger, clk),
+		securityRepo:    NewSecurityEventRepository(db, logger),
+		provisionRepo:   NewProvisioningRepository(db, logger, clk),
+		siteRepo:        NewSiteRepository(db, logger, clk),
+		deadLetterRepo:  NewDeadLetterRepository(db, logger, clk),
 	}
+	for _, opt := range opts {
+		opt(rm)
+	}
+	rm.meterValueRepo = &meterValueRepository{db: db, logger: logger, integerEnergy: rm.integerEnergy}
+
+	if rm.audit {
+		a := &auditor{log: rm.auditRepo, logger: logger}
+		rm.chargerRepo = &auditedChargerRepository{ChargerRepository: rm.chargerRepo, auditor: a}
+		rm.idTagRepo = &auditedIDTagRepository{IDTagRepository: rm.idTagRepo, auditor: a}
+	}
+
+	return rm
 }
 
 // Chargers implements RepositoryManager.Chargers
@@ -65,24 +141,113 @@ func (rm *repositoryManager) Errors() ChargerErrorRepository {
 	return rm.errorRepo
 }
 
+// Webhooks implements RepositoryManager.Webhooks
+func (rm *repositoryManager) Webhooks() WebhookOutboxRepository {
+	return rm.webhookRepo
+}
+
+// Outbox implements RepositoryManager.Outbox
+func (rm *repositoryManager) Outbox() OutboxRepository {
+	return rm.outboxRepo
+}
+
+// IDTags implements RepositoryManager.IDTags
+func (rm *repositoryManager) IDTags() IDTagRepository {
+	return rm.idTagRepo
+}
+
+// Reservations implements RepositoryManager.Reservations
+func (rm *repositoryManager) Reservations() ReservationRepository {
+	return rm.reservationRepo
+}
+
+// Audit implements RepositoryManager.Audit
+func (rm *repositoryManager) Audit() AuditLogRepository {
+	return rm.auditRepo
+}
+
+// Settings implements RepositoryManager.Settings
+func (rm *repositoryManager) Settings() SettingsRepository {
+	return rm.settingsRepo
+}
+
+// Commands implements RepositoryManager.Commands
+func (rm *repositoryManager) Commands() CommandHistoryRepository {
+	return rm.commandRepo
+}
+
+// Connections implements RepositoryManager.Connections
+func (rm *repositoryManager) Connections() ChargerConnectionRepository {
+	return rm.connectionRepo
+}
+
+// FileTransfers implements RepositoryManager.FileTransfers
+func (rm *repositoryManager) FileTransfers() FileTransferRepository {
+	return rm.transferRepo
+}
+
+// SecurityEvents implements RepositoryManager.SecurityEvents
+func (rm *repositoryManager) SecurityEvents() SecurityEventRepository {
+	return rm.securityRepo
+}
+
+// Provisioning implements RepositoryManager.Provisioning
+func (rm *repositoryManager) Provisioning() ProvisioningRepository {
+	return rm.provisionRepo
+}
+
+// Sites implements RepositoryManager.Sites
+func (rm *repositoryManager) Sites() SiteRepository {
+	return rm.siteRepo
+}
+
+// DeadLetters implements RepositoryManager.DeadLetters
+func (rm *repositoryManager) DeadLetters() DeadLetterRepository {
+	return rm.deadLetterRepo
+}
+
 // BeginTx implements RepositoryManager.BeginTx
 func (rm *repositoryManager) BeginTx(ctx context.Context) (TxManager, error) {
 	tx, err := rm.db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", rm.db.checkFault(err, false))
 	}
 
 	// Create a simple logger adapter for transaction context
 	txLogger := &txLoggerAdapter{logger: rm.db.logger}
+	var exec Executor = statsExecutor{faultExecutor{tx, rm.db}}
 
-	return &txRepositoryManager{
+	tm := &txRepositoryManager{
 		tx:              tx,
-		chargerRepo:     NewChargerRepository(tx, txLogger),
-		connectorRepo:   NewChargerConnectorRepository(tx, txLogger),
-		transactionRepo: NewTransactionRepository(tx, txLogger),
-		meterValueRepo:  NewMeterValueRepository(tx, txLogger),
-		errorRepo:       NewChargerErrorRepository(tx, txLogger),
-	}, nil
+		db:              rm.db,
+		chargerRepo:     NewChargerRepository(exec, txLogger),
+		connectorRepo:   NewChargerConnectorRepository(exec, txLogger),
+		transactionRepo: NewTransactionRepository(exec, txLogger, rm.clock),
+		meterValueRepo:  &meterValueRepository{db: exec, logger: txLogger, integerEnergy: rm.integerEnergy},
+		errorRepo:       NewChargerErrorRepository(exec, txLogger, rm.clock),
+		webhookRepo:     NewWebhookOutboxRepository(exec, txLogger),
+		outboxRepo:      NewOutboxRepository(exec, txLogger),
+		idTagRepo:       NewIDTagRepository(exec, txLogger),
+		reservationRepo: NewReservationRepository(exec, txLogger),
+		auditRepo:       NewAuditLogRepository(exec, txLogger, rm.clock),
+		settingsRepo:    NewSettingsRepository(exec, txLogger),
+		commandRepo:     NewCommandHistoryRepository(exec, txLogger, rm.clock),
+		connectionRepo:  NewChargerConnectionRepository(exec, txLogger),
+		transferRepo:    NewFileTransferRepository(exec, txLogger, rm.clock),
+		securityRepo:    NewSecurityEventRepository(exec, txLogger),
+		provisionRepo:   NewProvisioningRepository(exec, txLogger, rm.clock),
+		siteRepo:        NewSiteRepository(exec, txLogger, rm.clock),
+		deadLetterRepo:  NewDeadLetterRepository(exec, txLogger, rm.clock),
+	}
+
+	// Audit entries are written in the same transaction as the change
+	if rm.audit {
+		a := &auditor{log: tm.auditRepo, logger: txLogger}
+		tm.chargerRepo = &auditedChargerRepository{ChargerRepository: tm.chargerRepo, auditor: a}
+		tm.idTagRepo = &auditedIDTagRepository{IDTagRepository: tm.idTagRepo, auditor: a}
+	}
+
+	return tm, nil
 }
 
 // HealthCheck implements RepositoryManager.HealthCheck
@@ -117,9 +282,74 @@ func (tm *txRepositoryManager) Errors() ChargerErrorRepository {
 	return tm.errorRepo
 }
 
+// Webhooks implements TxManager.Webhooks
+func (tm *txRepositoryManager) Webhooks() WebhookOutboxRepository {
+	return tm.webhookRepo
+}
+
+// Outbox implements TxManager.Outbox
+func (tm *txRepositoryManager) Outbox() OutboxRepository {
+	return tm.outboxRepo
+}
+
+// IDTags implements TxManager.IDTags
+func (tm *txRepositoryManager) IDTags() IDTagRepository {
+	return tm.idTagRepo
+}
+
+// Reservations implements TxManager.Reservations
+func (tm *txRepositoryManager) Reservations() ReservationRepository {
+	return tm.reservationRepo
+}
+
+// Audit implements TxManager.Audit
+func (tm *txRepositoryManager) Audit() AuditLogRepository {
+	return tm.auditRepo
+}
+
+// Settings implements TxManager.Settings
+func (tm *txRepositoryManager) Settings() SettingsRepository {
+	return tm.settingsRepo
+}
+
+// Commands implements TxManager.Commands
+func (tm *txRepositoryManager) Commands() CommandHistoryRepository {
+	return tm.commandRepo
+}
+
+// Connections implements TxManager.Connections
+func (tm *txRepositoryManager) Connections() ChargerConnectionRepository {
+	return tm.connectionRepo
+}
+
+// FileTransfers implements TxManager.FileTransfers
+func (tm *txRepositoryManager) FileTransfers() FileTransferRepository {
+	return tm.transferRepo
+}
+
+// SecurityEvents implements TxManager.SecurityEvents
+func (tm *txRepositoryManager) SecurityEvents() SecurityEventRepository {
+	return tm.securityRepo
+}
+
+// Provisioning implements TxManager.Provisioning
+func (tm *txRepositoryManager) Provisioning() ProvisioningRepository {
+	return tm.provisionRepo
+}
+
+// Sites implements TxManager.Sites
+func (tm *txRepositoryManager) Sites() SiteRepository {
+	return tm.siteRepo
+}
+
+// DeadLetters implements TxManager.DeadLetters
+func (tm *txRepositoryManager) DeadLetters() DeadLetterRepository {
+	return tm.deadLetterRepo
+}
+
 // Commit implements TxManager.Commit
 func (tm *txRepositoryManager) Commit() error {
-	return tm.tx.Commit()
+	return tm.db.checkFault(tm.tx.Commit(), true)
 }
 
 // Rollback implements TxManager.Rollback
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// reservationRepository implements ReservationRepository
+type reservationRepository struct {
+	db     Executor
+	logger Logger
+}
+
+// NewReservationRepository creates a new reservation repository
+func NewReservationRepository(db Executor, logger Logger) ReservationRepository {
+	return &reservationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create implements ReservationRepository.Create
+func (r *reservationRepository) Create(ctx context.Context, req CreateReservationRequest) (*Reservation, error) {
+	query := `
+		INSERT INTO reservations (
+			id, charger_id, connector_id, id_tag, parent_id_tag, expiry_date, status, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, 'Active', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id, charger_id, connector_id, id_tag, parent_id_tag, expiry_date, status, created_at, updated_at`
+
+	var res Reservation
+	err := r.db.QueryRowContext(ctx, query,
+		req.ID, req.ChargerID, req.ConnectorID, req.IDTag, req.ParentIDTag, req.ExpiryDate.UTC(),
+	).Scan(
+		&res.ID, &res.ChargerID, &res.ConnectorID, &res.IDTag, &res.ParentIDTag, &res.ExpiryDate,
+		&res.Status, &res.CreatedAt, &res.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error("Failed to create reservation", "id", req.ID, "charger_id", req.ChargerID, "error", err)
+		return nil, fmt.Errorf("failed to create reservation: %w", err)
+	}
+
+	r.logger.Info("Created reservation", "id", res.ID, "charger_id", res.ChargerID, "connector_id", res.ConnectorID)
+	return &res, nil
+}
+
+// GetByID implements ReservationRepository.GetByID
+func (r *reservationRepository) GetByID(ctx context.Context, id int) (*Reservation, error) {
+	query := `
+		SELECT id, charger_id, connector_id, id_tag, parent_id_tag, expiry_date, status, created_at, updated_at
+		FROM reservations WHERE id = ?`
+
+	var res Reservation
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&res.ID, &res.ChargerID, &res.ConnectorID, &res.IDTag, &res.ParentIDTag, &res.ExpiryDate,
+		&res.Status, &res.CreatedAt, &res.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("reservation not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	return &res, nil
+}
+
+// GetActiveByConnector implements ReservationRepository.GetActiveByConnector
+func (r *reservationRepository) GetActiveByConnector(ctx context.Context, chargerID string, connectorID int, at time.Time) (*Reservation, error) {
+	// A reservation on the connector itself takes precedence over one on connector 0
+	query := `
+		SELECT id, charger_id, connector_id, id_tag, parent_id_tag, expiry_date, status, created_at, updated_at
+		FROM reservations
+		WHERE charger_id = ? AND connector_id IN (?, 0) AND status = 'Active' AND expiry_date > ?
+		ORDER BY connector_id DESC, expiry_date ASC
+		LIMIT 1`
+
+	var res Reservation
+	err := r.db.QueryRowContext(ctx, query, chargerID, connectorID, at.UTC()).Scan(
+		&res.ID, &res.ChargerID, &res.ConnectorID, &res.IDTag, &res.ParentIDTag, &res.ExpiryDate,
+		&res.Status, &res.CreatedAt, &res.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // No reservation is not an error
+		}
+		r.logger.Error("Failed to get active reservation",
+			"charger_id", chargerID, "connector_id", connectorID, "error", err)
+		return nil, fmt.Errorf("failed to get active reservation: %w", err)
+	}
+
+	return &res, nil
+}
+
+// UpdateStatus implements ReservationRepository.UpdateStatus
+func (r *reservationRepository) UpdateStatus(ctx context.Context, id int, status string) error {
+	query := `UPDATE reservations SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, status, id)
+	if err != nil {
+		r.logger.Error("Failed to update reservation status", "id", id, "error", err)
+		return fmt.Errorf("failed to update reservation status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("reservation not found: %d", id)
+	}
+
+	return nil
+}
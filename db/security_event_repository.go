@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// securityEventRepository implements SecurityEventRepository
+type securityEventRepository struct {
+	db     Executor
+	logger Logger
+}
+
+// NewSecurityEventRepository creates a new security event repository
+func NewSecurityEventRepository(db Executor, logger Logger) SecurityEventRepository {
+	return &securityEventRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create implements SecurityEventRepository.Create
+func (r *securityEventRepository) Create(ctx context.Context, req CreateSecurityEventRequest) (*SecurityEvent, error) {
+	query := `
+		INSERT INTO security_events (charger_id, type, tech_info, critical, timestamp)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, charger_id, type, tech_info, critical, timestamp, created_at`
+
+	event, err := scanSecurityEvent(r.db.QueryRowContext(ctx, query,
+		req.ChargerID, req.Type, req.TechInfo, req.Critical, req.Timestamp.UTC(),
+	))
+	if err != nil {
+		r.logger.Error("Failed to record security event", "charger_id", req.ChargerID, "type", req.Type, "error", err)
+		return nil, fmt.Errorf("failed to record security event: %w", err)
+	}
+
+	return event, nil
+}
+
+// List implements SecurityEventRepository.List
+func (r *securityEventRepository) List(ctx context.Context, filter SecurityEventFilter) ([]*SecurityEvent, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.ChargerID != "" {
+		conditions = append(conditions, "charger_id = ?")
+		args = append(args, filter.ChargerID)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.Critical != nil {
+		conditions = append(conditions, "critical = ?")
+		args = append(args, *filter.Critical)
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, filter.Until.UTC())
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListOptions().Limit
+	}
+	args = append(args, limit, filter.Offset)
+
+	query := fmt.Sprintf(`
+		SELECT id, charger_id, type, tech_info, critical, timestamp, created_at
+		FROM security_events %s
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ? OFFSET ?`, where)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to list security events", "error", err)
+		return nil, fmt.Errorf("failed to list security events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*SecurityEvent
+	for rows.Next() {
+		event, err := scanSecurityEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan security event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return events, nil
+}
+
+// scanSecurityEvent scans a security_events row
+func scanSecurityEvent(row rowScanner) (*SecurityEvent, error) {
+	var event SecurityEvent
+	err := row.Scan(
+		&event.ID, &event.ChargerID, &event.Type, &event.TechInfo, &event.Critical,
+		&event.Timestamp, &event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// settingsRepository implements SettingsRepository
+type settingsRepository struct {
+	db     Executor
+	logger Logger
+}
+
+// NewSettingsRepository creates a new settings repository
+func NewSettingsRepository(db Executor, logger Logger) SettingsRepository {
+	return &settingsRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Get implements SettingsRepository.Get
+func (r *settingsRepository) Get(ctx context.Context, key string) (string, bool, error) {
+	query := `SELECT value FROM settings WHERE key = ?`
+
+	var value string
+	err := r.db.QueryRowContext(ctx, query, key).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+
+	return value, true, nil
+}
+
+// Set implements SettingsRepository.Set
+func (r *settingsRepository) Set(ctx context.Context, key string, value string) error {
+	query := `
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+
+	if _, err := r.db.ExecContext(ctx, query, key, value); err != nil {
+		r.logger.Error("Failed to set setting", "key", key, "error", err)
+		return fmt.Errorf("failed to set setting %s: %w", key, err)
+	}
+
+	r.logger.Debug("Set setting", "key", key)
+	return nil
+}
+
+// GetBool implements SettingsRepository.GetBool
+func (r *settingsRepository) GetBool(ctx context.Context, key string, def bool) (bool, error) {
+	value, ok, err := r.Get(ctx, key)
+	if err != nil || !ok {
+		return def, err
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return def, fmt.Errorf("setting %s is not a boolean: %q", key, value)
+	}
+	return parsed, nil
+}
+
+// GetInt implements SettingsRepository.GetInt
+func (r *settingsRepository) GetInt(ctx context.Context, key string, def int) (int, error) {
+	value, ok, err := r.Get(ctx, key)
+	if err != nil || !ok {
+		return def, err
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return def, fmt.Errorf("setting %s is not an integer: %q", key, value)
+	}
+	return parsed, nil
+}
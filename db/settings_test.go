@@ -0,0 +1,73 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsGetSet(t *testing.T) {
+	ctx := context.Background()
+	settings := dbtest.NewRepositories(t, clock.Real()).Settings()
+
+	_, ok, err := settings.Get(ctx, "greeting")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, settings.Set(ctx, "greeting", "hello"))
+	value, ok, err := settings.Get(ctx, "greeting")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "hello", value)
+
+	// Setting again replaces the value
+	require.NoError(t, settings.Set(ctx, "greeting", "bonjour"))
+	value, _, err = settings.Get(ctx, "greeting")
+	require.NoError(t, err)
+	assert.Equal(t, "bonjour", value)
+}
+
+func TestSettingsTypedAccessors(t *testing.T) {
+	ctx := context.Background()
+	settings := dbtest.NewRepositories(t, clock.Real()).Settings()
+
+	t.Run("missing keys return the default", func(t *testing.T) {
+		b, err := settings.GetBool(ctx, "missing_bool", true)
+		require.NoError(t, err)
+		assert.True(t, b)
+
+		n, err := settings.GetInt(ctx, "missing_int", 42)
+		require.NoError(t, err)
+		assert.Equal(t, 42, n)
+	})
+
+	t.Run("stored values are parsed", func(t *testing.T) {
+		require.NoError(t, settings.Set(ctx, "flag", "true"))
+		require.NoError(t, settings.Set(ctx, "counter", "-7"))
+
+		b, err := settings.GetBool(ctx, "flag", false)
+		require.NoError(t, err)
+		assert.True(t, b)
+
+		n, err := settings.GetInt(ctx, "counter", 0)
+		require.NoError(t, err)
+		assert.Equal(t, -7, n)
+	})
+
+	t.Run("malformed values return the default and an error", func(t *testing.T) {
+		require.NoError(t, settings.Set(ctx, "bad_flag", "sometimes"))
+		require.NoError(t, settings.Set(ctx, "bad_counter", "lots"))
+
+		b, err := settings.GetBool(ctx, "bad_flag", true)
+		assert.Error(t, err)
+		assert.True(t, b)
+
+		n, err := settings.GetInt(ctx, "bad_counter", 3)
+		assert.Error(t, err)
+		assert.Equal(t, 3, n)
+	})
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/keeth/levity/core/clock"
+)
+
+// siteRepository implements SiteRepository
+type siteRepository struct {
+	db     Executor
+	logger Logger
+	clock  clock.Clock
+}
+
+// NewSiteRepository creates a new site repository
+func NewSiteRepository(db Executor, logger Logger, clk clock.Clock) SiteRepository {
+	return &siteRepository{
+		db:     db,
+		logger: logger,
+		clock:  clk,
+	}
+}
+
+// siteOrderFields are the columns site lists may be ordered by
+var siteOrderFields = map[string]bool{
+	"id": true, "name": true, "max_current": true, "created_at": true, "updated_at": true,
+}
+
+// scanSite scans a sites row
+func scanSite(row rowScanner) (*Site, error) {
+	var site Site
+	if err := row.Scan(&site.ID, &site.Name, &site.MaxCurrent, &site.CreatedAt, &site.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+// Create implements SiteRepository.Create
+func (r *siteRepository) Create(ctx context.Context, req CreateSiteRequest) (*Site, error) {
+	query := `
+		INSERT INTO sites (id, name, max_current, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+		RETURNING id, name, max_current, created_at, updated_at`
+
+	now := r.clock.Now().UTC()
+	site, err := scanSite(r.db.QueryRowContext(ctx, query, req.ID, req.Name, req.MaxCurrent, now, now))
+	if err != nil {
+		r.logger.Error("Failed to create site", "site_id", req.ID, "error", err)
+		return nil, fmt.Errorf("failed to create site: %w", err)
+	}
+
+	r.logger.Info("Created site", "site_id", site.ID, "max_current", site.MaxCurrent)
+	return site, nil
+}
+
+// GetByID implements SiteRepository.GetByID
+func (r *siteRepository) GetByID(ctx context.Context, id string) (*Site, error) {
+	query := `SELECT id, name, max_current, created_at, updated_at FROM sites WHERE id = ?`
+
+	site, err := scanSite(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get site %s: %w", id, err)
+	}
+	return site, nil
+}
+
+// GetByChargerID implements SiteRepository.GetByChargerID
+func (r *siteRepository) GetByChargerID(ctx context.Context, chargerID string) (*Site, error) {
+	query := `
+		SELECT s.id, s.name, s.max_current, s.created_at, s.updated_at
+		FROM sites s JOIN site_chargers sc ON sc.site_id = s.id
+		WHERE sc.charger_id = ?`
+
+	site, err := scanSite(r.db.QueryRowContext(ctx, query, chargerID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get site of charger %s: %w", chargerID, err)
+	}
+	return site, nil
+}
+
+// Update implements SiteRepository.Update
+func (r *siteRepository) Update(ctx context.Context, id string, req UpdateSiteRequest) (*Site, error) {
+	query := `
+		UPDATE sites SET
+			name = COALESCE(?, name),
+			max_current = COALESCE(?, max_current),
+			updated_at = ?
+		WHERE id = ?
+		RETURNING id, name, max_current, created_at, updated_at`
+
+	site, err := scanSite(r.db.QueryRowContext(ctx, query, req.Name, req.MaxCurrent, r.clock.Now().UTC(), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("site not found: %s", id)
+		}
+		r.logger.Error("Failed to update site", "site_id", id, "error", err)
+		return nil, fmt.Errorf("failed to update site: %w", err)
+	}
+
+	r.logger.Info("Updated site", "site_id", id)
+	return site, nil
+}
+
+// Delete implements SiteRepository.Delete
+func (r *siteRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sites WHERE id = ?`, id)
+	if err != nil {
+		r.logger.Error("Failed to delete site", "site_id", id, "error", err)
+		return fmt.Errorf("failed to delete site: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("site not found: %s", id)
+	}
+
+	r.logger.Info("Deleted site", "site_id", id)
+	return nil
+}
+
+// List implements SiteRepository.List
+func (r *siteRepository) List(ctx context.Context, opts ListOptions) ([]*Site, error) {
+	query := `SELECT id, name, max_current, created_at, updated_at FROM sites`
+
+	order := listOrder{fields: siteOrderFields, fallback: "id"}
+	sites, err := listQuery(ctx, r.db, query, nil, order, opts, scanSite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sites: %w", err)
+	}
+	return sites, nil
+}
+
+// AddCharger implements SiteRepository.AddCharger
+func (r *siteRepository) AddCharger(ctx context.Context, siteID, chargerID string) error {
+	query := `
+		INSERT INTO site_chargers (charger_id, site_id, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(charger_id) DO UPDATE SET site_id = excluded.site_id, created_at = excluded.created_at`
+
+	if _, err := r.db.ExecContext(ctx, query, chargerID, siteID, r.clock.Now().UTC()); err != nil {
+		r.logger.Error("Failed to add charger to site", "site_id", siteID, "charger_id", chargerID, "error", err)
+		return fmt.Errorf("failed to add charger %s to site %s: %w", chargerID, siteID, err)
+	}
+
+	r.logger.Info("Added charger to site", "site_id", siteID, "charger_id", chargerID)
+	return nil
+}
+
+// RemoveCharger implements SiteRepository.RemoveCharger
+func (r *siteRepository) RemoveCharger(ctx context.Context, siteID, chargerID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM site_chargers WHERE site_id = ? AND charger_id = ?`, siteID, chargerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove charger %s from site %s: %w", chargerID, siteID, err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected > 0 {
+		r.logger.Info("Removed charger from site", "site_id", siteID, "charger_id", chargerID)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ChargerIDs implements SiteRepository.ChargerIDs
+func (r *siteRepository) ChargerIDs(ctx context.Context, siteID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT charger_id FROM site_chargers WHERE site_id = ? ORDER BY charger_id`, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chargers of site %s: %w", siteID, err)
+	}
+	defer rows.Close()
+
+	chargerIDs := []string{}
+	for rows.Next() {
+		var chargerID string
+		if err := rows.Scan(&chargerID); err != nil {
+			return nil, fmt.Errorf("failed to scan site charger: %w", err)
+		}
+		chargerIDs = append(chargerIDs, chargerID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return chargerIDs, nil
+}
@@ -0,0 +1,109 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiteCRUD(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 8, 1, 9, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+	sites := repos.Sites()
+
+	site, err := sites.Create(ctx, db.CreateSiteRequest{ID: "depot", Name: "Depot", MaxCurrent: 63})
+	require.NoError(t, err)
+	assert.Equal(t, "Depot", site.Name)
+	assert.Equal(t, 63.0, site.MaxCurrent)
+	assert.True(t, site.CreatedAt.Equal(fake.Now()))
+
+	_, err = sites.Create(ctx, db.CreateSiteRequest{ID: "depot"})
+	assert.Error(t, err)
+
+	fake.Advance(time.Minute)
+	maxCurrent := 80.0
+	site, err = sites.Update(ctx, "depot", db.UpdateSiteRequest{MaxCurrent: &maxCurrent})
+	require.NoError(t, err)
+	assert.Equal(t, "Depot", site.Name)
+	assert.Equal(t, 80.0, site.MaxCurrent)
+	assert.True(t, site.UpdatedAt.Equal(fake.Now()))
+
+	_, err = sites.Create(ctx, db.CreateSiteRequest{ID: "annex"})
+	require.NoError(t, err)
+	list, err := sites.List(ctx, db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "annex", list[0].ID)
+
+	require.NoError(t, sites.Delete(ctx, "annex"))
+	assert.Error(t, sites.Delete(ctx, "annex"))
+	missing, err := sites.GetByID(ctx, "annex")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	_, err = sites.Update(ctx, "annex", db.UpdateSiteRequest{MaxCurrent: &maxCurrent})
+	assert.Error(t, err)
+}
+
+func TestSiteMembership(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.Real())
+	sites := repos.Sites()
+
+	for _, id := range []string{"CP-1", "CP-2", "CP-3"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+	}
+	for _, id := range []string{"north", "south"} {
+		_, err := sites.Create(ctx, db.CreateSiteRequest{ID: id, MaxCurrent: 32})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, sites.AddCharger(ctx, "north", "CP-2"))
+	require.NoError(t, sites.AddCharger(ctx, "north", "CP-1"))
+	require.NoError(t, sites.AddCharger(ctx, "south", "CP-3"))
+
+	chargerIDs, err := sites.ChargerIDs(ctx, "north")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CP-1", "CP-2"}, chargerIDs)
+
+	// A charger belongs to one site; adding it elsewhere moves it
+	require.NoError(t, sites.AddCharger(ctx, "south", "CP-2"))
+	chargerIDs, err = sites.ChargerIDs(ctx, "north")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CP-1"}, chargerIDs)
+
+	site, err := sites.GetByChargerID(ctx, "CP-2")
+	require.NoError(t, err)
+	require.NotNil(t, site)
+	assert.Equal(t, "south", site.ID)
+
+	removed, err := sites.RemoveCharger(ctx, "north", "CP-2")
+	require.NoError(t, err)
+	assert.False(t, removed)
+	removed, err = sites.RemoveCharger(ctx, "south", "CP-2")
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	site, err = sites.GetByChargerID(ctx, "CP-2")
+	require.NoError(t, err)
+	assert.Nil(t, site)
+
+	// Deleting a site or a charger drops the membership
+	require.NoError(t, sites.Delete(ctx, "south"))
+	site, err = sites.GetByChargerID(ctx, "CP-3")
+	require.NoError(t, err)
+	assert.Nil(t, site)
+
+	require.NoError(t, repos.Chargers().Delete(ctx, "CP-1"))
+	chargerIDs, err = sites.ChargerIDs(ctx, "north")
+	require.NoError(t, err)
+	assert.Empty(t, chargerIDs)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sqlitedriver "github.com/mattn/go-sqlite3"
+)
+
+// ErrStorageFault matches errors caused by the storage under the database
+// rather than by the query: a full disk, an I/O error or corruption. Writes
+// are unlikely to succeed until an operator intervenes.
+var ErrStorageFault = errors.New("database storage fault")
+
+// Kinds of storage fault
+const (
+	StorageFaultFull    = "full"
+	StorageFaultIO      = "io"
+	StorageFaultCorrupt = "corrupt"
+)
+
+// StorageFaultError is a query error caused by the storage under the
+// database. It matches ErrStorageFault and unwraps to the failed query's error.
+type StorageFaultError struct {
+	Kind string
+	Err  error
+}
+
+// Error implements error
+func (e *StorageFaultError) Error() string {
+	return "database storage fault (" + e.Kind + "): " + e.Err.Error()
+}
+
+// Is reports whether target is ErrStorageFault
+func (e *StorageFaultError) Is(target error) bool {
+	return target == ErrStorageFault
+}
+
+// Unwrap returns the failed query's error
+func (e *StorageFaultError) Unwrap() error {
+	return e.Err
+}
+
+// AsStorageFault classifies err, returning the StorageFaultError it is or
+// wraps, or one built from a SQLITE_FULL, SQLITE_IOERR, SQLITE_CORRUPT or
+// SQLITE_NOTADB driver error. It returns nil for any other error.
+func AsStorageFault(err error) *StorageFaultError {
+	if err == nil {
+		return nil
+	}
+
+	var fault *StorageFaultError
+	if errors.As(err, &fault) {
+		return fault
+	}
+
+	var sqliteErr sqlitedriver.Error
+	if !errors.As(err, &sqliteErr) {
+		return nil
+	}
+	switch sqliteErr.Code {
+	case sqlitedriver.ErrFull:
+		return &StorageFaultError{Kind: StorageFaultFull, Err: err}
+	case sqlitedriver.ErrIoErr:
+		return &StorageFaultError{Kind: StorageFaultIO, Err: err}
+	case sqlitedriver.ErrCorrupt, sqlitedriver.ErrNotADB:
+		return &StorageFaultError{Kind: StorageFaultCorrupt, Err: err}
+	}
+	return nil
+}
+
+// StorageFaultObserver is told when queries fail with a storage fault, and
+// when a write succeeds again after a full disk or I/O error
+type StorageFaultObserver interface {
+	StorageFault(fault *StorageFaultError)
+	StorageRecovered()
+}
+
+// SetStorageFaultObserver registers the observer of storage faults on every
+// repository manager of the database. It must be called before queries run.
+func (d *Database) SetStorageFaultObserver(observer StorageFaultObserver) {
+	d.faultObserver = observer
+}
+
+// ReportFault returns err as a StorageFaultError when it is a storage fault,
+// reporting it to the observer unless it was already; any other error is
+// returned unchanged. Repositories report the faults of Exec, Query and
+// commit themselves, but a QueryRow error only surfaces from Scan, so callers
+// pass the errors leaving their unit of work through here.
+func (d *Database) ReportFault(err error) error {
+	return d.checkFault(err, false)
+}
+
+// checkFault returns a storage fault in err as a StorageFaultError and
+// reports it to the observer. A successful write after a full disk or I/O
+// error reports the recovery; corruption is not cleared by one.
+func (d *Database) checkFault(err error, write bool) error {
+	if d == nil {
+		return err
+	}
+
+	if err == nil {
+		if write && d.recoverable.CompareAndSwap(true, false) && d.faultObserver != nil {
+			d.faultObserver.StorageRecovered()
+		}
+		return nil
+	}
+
+	var reported *StorageFaultError
+	if errors.As(err, &reported) {
+		return err
+	}
+
+	fault := AsStorageFault(err)
+	if fault == nil {
+		return err
+	}
+	if fault.Kind != StorageFaultCorrupt {
+		d.recoverable.Store(true)
+	}
+	if d.faultObserver != nil {
+		d.faultObserver.StorageFault(fault)
+	}
+	return fault
+}
+
+// faultExecutor decorates an Executor, reporting storage faults to the
+// observer of its database and returning them as StorageFaultErrors.
+// QueryRowContext is passed through since its error is only seen by Scan.
+type faultExecutor struct {
+	Executor
+	database *Database
+}
+
+// ExecContext implements Executor.ExecContext
+func (e faultExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := e.Executor.ExecContext(ctx, query, args...)
+	return result, e.database.checkFault(err, true)
+}
+
+// QueryContext implements Executor.QueryContext
+func (e faultExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := e.Executor.QueryContext(ctx, query, args...)
+	return rows, e.database.checkFault(err, false)
+}
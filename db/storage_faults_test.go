@@ -0,0 +1,101 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	sqlitedriver "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver records the storage faults and recoveries it is told about
+type recordingObserver struct {
+	faults    []*db.StorageFaultError
+	recovered int
+}
+
+func (o *recordingObserver) StorageFault(fault *db.StorageFaultError) {
+	o.faults = append(o.faults, fault)
+}
+
+func (o *recordingObserver) StorageRecovered() {
+	o.recovered++
+}
+
+func TestAsStorageFault(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind string
+	}{
+		{sqlitedriver.Error{Code: sqlitedriver.ErrFull}, db.StorageFaultFull},
+		{fmt.Errorf("failed to create charger: %w", sqlitedriver.Error{Code: sqlitedriver.ErrIoErr}), db.StorageFaultIO},
+		{sqlitedriver.Error{Code: sqlitedriver.ErrCorrupt}, db.StorageFaultCorrupt},
+		{sqlitedriver.Error{Code: sqlitedriver.ErrNotADB}, db.StorageFaultCorrupt},
+		{sqlitedriver.Error{Code: sqlitedriver.ErrConstraint}, ""},
+		{sqlitedriver.Error{Code: sqlitedriver.ErrBusy}, ""},
+		{fmt.Errorf("boom"), ""},
+	}
+
+	for _, tt := range tests {
+		fault := db.AsStorageFault(tt.err)
+		if tt.kind == "" {
+			assert.Nil(t, fault, tt.err.Error())
+			continue
+		}
+		require.NotNil(t, fault, tt.err.Error())
+		assert.Equal(t, tt.kind, fault.Kind)
+		assert.ErrorIs(t, fault, db.ErrStorageFault)
+		assert.ErrorIs(t, fault, tt.err)
+	}
+}
+
+func TestFullDatabaseReportsStorageFault(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewDatabase(t)
+	// Pragmas below apply per connection
+	database.SetMaxOpenConns(1)
+	observer := &recordingObserver{}
+	database.SetStorageFaultObserver(observer)
+	repos := db.NewRepositoryManager(database, dbtest.NopLogger(), clock.NewFake(time.Now()))
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1", Vendor: "Acme", Model: "X1"})
+	require.NoError(t, err)
+
+	// Cap the database near its current size and fill the free pages left
+	raw := database.GetDB()
+	var pages int
+	require.NoError(t, raw.QueryRow(`PRAGMA page_count`).Scan(&pages))
+	_, err = raw.Exec(fmt.Sprintf(`PRAGMA max_page_count = %d`, pages+8))
+	require.NoError(t, err)
+	_, err = raw.Exec(`CREATE TABLE filler (data BLOB)`)
+	require.NoError(t, err)
+	for err == nil {
+		_, err = raw.Exec(`INSERT INTO filler (data) VALUES (randomblob(1024))`)
+	}
+
+	// A row spilling onto overflow pages needs space the database lacks
+	err = repos.Chargers().UpdateStatus(ctx, "CP-1", strings.Repeat("x", 64*1024))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, db.ErrStorageFault)
+	require.Len(t, observer.faults, 1)
+	assert.Equal(t, db.StorageFaultFull, observer.faults[0].Kind)
+
+	// Already reported faults are not reported again
+	assert.ErrorIs(t, database.ReportFault(err), db.ErrStorageFault)
+	assert.Len(t, observer.faults, 1)
+
+	_, err = raw.Exec(`DROP TABLE filler`)
+	require.NoError(t, err)
+	_, err = raw.Exec(`PRAGMA max_page_count = 1073741823`)
+	require.NoError(t, err)
+
+	require.NoError(t, repos.Chargers().UpdateStatus(ctx, "CP-1", "Available"))
+	assert.Equal(t, 1, observer.recovered)
+}
@@ -3,23 +3,64 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"strings"
 	"time"
+
+	"github.com/keeth/levity/core/clock"
+	sqlitedriver "github.com/mattn/go-sqlite3"
 )
 
+// ErrTransactionIDOutOfRange is returned for an OCPP transaction id that does
+// not fit in the signed 32-bit integer many chargers store it in
+var ErrTransactionIDOutOfRange = errors.New("transaction id is outside the int32 range")
+
+// ErrTransactionNotFound is returned when no transaction has the requested id
+// or OCPP transaction id
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// ErrConnectorTransactionActive is returned when creating a transaction on a
+// connector that already has an active one
+var ErrConnectorTransactionActive = errors.New("connector already has an active transaction")
+
+// activeConnectorConstraint is how SQLite names idx_transactions_active_connector
+// in a UNIQUE constraint error
+const activeConnectorConstraint = "transactions.charger_id, transactions.connector_id"
+
+// isActiveConnectorConflict reports whether err violates the one active
+// transaction per connector index
+func isActiveConnectorConflict(err error) bool {
+	var sqliteErr sqlitedriver.Error
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.ExtendedCode == sqlitedriver.ErrConstraintUnique &&
+		strings.Contains(sqliteErr.Error(), activeConnectorConstraint)
+}
+
+// checkTransactionID returns ErrTransactionIDOutOfRange for an id a charger
+// would truncate
+func checkTransactionID(id int) error {
+	if id < math.MinInt32 || id > math.MaxInt32 {
+		return fmt.Errorf("%w: %d", ErrTransactionIDOutOfRange, id)
+	}
+	return nil
+}
+
 // transactionRepository implements TransactionRepository
 type transactionRepository struct {
 	db     Executor
 	logger Logger
+	clock  clock.Clock
 }
 
 // NewTransactionRepository creates a new transaction repository
-func NewTransactionRepository(db Executor, logger Logger) TransactionRepository {
+func NewTransactionRepository(db Executor, logger Logger, clk clock.Clock) TransactionRepository {
 	return &transactionRepository{
 		db:     db,
 		logger: logger,
+		clock:  clk,
 	}
 }
 
@@ -30,6 +71,9 @@ func (r *transactionRepository) Create(ctx context.Context, req CreateTransactio
 	var err error
 	if req.TransactionID != nil {
 		ocppTxID = *req.TransactionID
+		if err := checkTransactionID(ocppTxID); err != nil {
+			return nil, err
+		}
 	} else {
 		ocppTxID, err = r.GenerateTransactionID(ctx)
 		if err != nil {
@@ -42,20 +86,29 @@ func (r *transactionRepository) Create(ctx context.Context, req CreateTransactio
 			transaction_id, charger_id, connector_id, id_tag, 
 			start_time, meter_start, energy_delivered, status, 
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?, 0, 'Active', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		) VALUES (?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), ?, 0, 'Active', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id, transaction_id, charger_id, connector_id, id_tag, 
 				  start_time, stop_time, meter_start, meter_stop, 
-				  energy_delivered, stop_reason, status, created_at, updated_at`
+				  energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at`
+
+	// A nil start time falls back to the insert time
+	var startTime interface{}
+	if req.StartTime != nil {
+		startTime = req.StartTime.UTC()
+	}
 
 	var tx Transaction
 	err = r.db.QueryRowContext(ctx, query,
-		ocppTxID, req.ChargerID, req.ConnectorID, req.IDTag, req.MeterStart,
+		ocppTxID, req.ChargerID, req.ConnectorID, req.IDTag, startTime, req.MeterStart,
 	).Scan(
 		&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
 		&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
-		&tx.EnergyDelivered, &tx.StopReason, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
+		&tx.EnergyDelivered, &tx.StopReason, &tx.StopSource, &tx.Status, &tx.ClockSkewSeconds, &tx.CreatedAt, &tx.UpdatedAt,
 	)
 
+	if isActiveConnectorConflict(err) {
+		return nil, fmt.Errorf("%w: %s connector %d", ErrConnectorTransactionActive, req.ChargerID, req.ConnectorID)
+	}
 	if err != nil {
 		r.logger.Error("Failed to create transaction",
 			"charger_id", req.ChargerID,
@@ -78,19 +131,19 @@ func (r *transactionRepository) GetByID(ctx context.Context, id int) (*Transacti
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, id_tag, 
 			   start_time, stop_time, meter_start, meter_stop, 
-			   energy_delivered, stop_reason, status, created_at, updated_at
+			   energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at
 		FROM transactions WHERE id = ?`
 
 	var tx Transaction
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
 		&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
-		&tx.EnergyDelivered, &tx.StopReason, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
+		&tx.EnergyDelivered, &tx.StopReason, &tx.StopSource, &tx.Status, &tx.ClockSkewSeconds, &tx.CreatedAt, &tx.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("transaction not found: %d", id)
+			return nil, fmt.Errorf("%w: %d", ErrTransactionNotFound, id)
 		}
 		r.logger.Error("Failed to get transaction", "id", id, "error", err)
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
@@ -104,19 +157,19 @@ func (r *transactionRepository) GetByTransactionID(ctx context.Context, transact
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, id_tag, 
 			   start_time, stop_time, meter_start, meter_stop, 
-			   energy_delivered, stop_reason, status, created_at, updated_at
+			   energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at
 		FROM transactions WHERE transaction_id = ?`
 
 	var tx Transaction
 	err := r.db.QueryRowContext(ctx, query, transactionID).Scan(
 		&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
 		&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
-		&tx.EnergyDelivered, &tx.StopReason, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
+		&tx.EnergyDelivered, &tx.StopReason, &tx.StopSource, &tx.Status, &tx.ClockSkewSeconds, &tx.CreatedAt, &tx.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("transaction not found with OCPP ID: %d", transactionID)
+			return nil, fmt.Errorf("%w with OCPP ID: %d", ErrTransactionNotFound, transactionID)
 		}
 		r.logger.Error("Failed to get transaction by OCPP ID", "ocpp_tx_id", transactionID, "error", err)
 		return nil, fmt.Errorf("failed to get transaction by OCPP ID: %w", err)
@@ -148,6 +201,14 @@ func (r *transactionRepository) Update(ctx context.Context, id int, req UpdateTr
 		args = append(args, *req.StopReason)
 	}
 	if req.Status != nil {
+		current, err := r.currentStatus(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := current.ValidateTransition(*req.Status); err != nil {
+			r.logger.Warn("Rejected transaction status change", "id", id, "from", current, "to", *req.Status)
+			return nil, err
+		}
 		setParts = append(setParts, "status = ?")
 		args = append(args, *req.Status)
 	}
@@ -164,14 +225,14 @@ func (r *transactionRepository) Update(ctx context.Context, id int, req UpdateTr
 		UPDATE transactions SET %s WHERE id = ?
 		RETURNING id, transaction_id, charger_id, connector_id, id_tag, 
 				  start_time, stop_time, meter_start, meter_stop, 
-				  energy_delivered, stop_reason, status, created_at, updated_at`,
+				  energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at`,
 		strings.Join(setParts, ", "))
 
 	var tx Transaction
 	err := r.db.QueryRowContext(ctx, query, args...).Scan(
 		&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
 		&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
-		&tx.EnergyDelivered, &tx.StopReason, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
+		&tx.EnergyDelivered, &tx.StopReason, &tx.StopSource, &tx.Status, &tx.ClockSkewSeconds, &tx.CreatedAt, &tx.UpdatedAt,
 	)
 
 	if err != nil {
@@ -186,6 +247,19 @@ func (r *transactionRepository) Update(ctx context.Context, id int, req UpdateTr
 	return &tx, nil
 }
 
+// currentStatus returns the status of the transaction with the given ID
+func (r *transactionRepository) currentStatus(ctx context.Context, id int) (TransactionStatus, error) {
+	var status TransactionStatus
+	err := r.db.QueryRowContext(ctx, `SELECT status FROM transactions WHERE id = ?`, id).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("transaction not found: %d", id)
+		}
+		return "", fmt.Errorf("failed to get transaction status: %w", err)
+	}
+	return status, nil
+}
+
 // Delete implements TransactionRepository.Delete
 func (r *transactionRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM transactions WHERE id = ?`
@@ -211,52 +285,68 @@ func (r *transactionRepository) Delete(ctx context.Context, id int) error {
 
 // List implements TransactionRepository.List
 func (r *transactionRepository) List(ctx context.Context, opts ListOptions) ([]*Transaction, error) {
-	opts.ValidateSortDirection()
-
-	// Validate order by field for security
-	validOrderFields := map[string]bool{
-		"id": true, "transaction_id": true, "charger_id": true, "connector_id": true,
-		"id_tag": true, "start_time": true, "stop_time": true, "status": true,
-		"created_at": true, "updated_at": true,
-	}
-
-	if !validOrderFields[opts.OrderBy] {
-		opts.OrderBy = "created_at"
-	}
-
-	query := fmt.Sprintf(`
+	query := `
 		SELECT id, transaction_id, charger_id, connector_id, id_tag, 
 			   start_time, stop_time, meter_start, meter_stop, 
-			   energy_delivered, stop_reason, status, created_at, updated_at
-		FROM transactions 
-		ORDER BY %s %s 
-		LIMIT ? OFFSET ?`, opts.OrderBy, opts.SortDir)
+			   energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at
+		FROM transactions`
 
-	rows, err := r.db.QueryContext(ctx, query, opts.Limit, opts.Offset)
+	order := listOrder{fields: transactionOrderFields, fallback: "created_at"}
+	transactions, err := listQuery(ctx, r.db, query, nil, order, opts, scanTransaction)
 	if err != nil {
 		r.logger.Error("Failed to list transactions", "error", err)
 		return nil, fmt.Errorf("failed to list transactions: %w", err)
 	}
-	defer rows.Close()
 
-	var transactions []*Transaction
-	for rows.Next() {
-		var tx Transaction
-		err := rows.Scan(
-			&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
-			&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
-			&tx.EnergyDelivered, &tx.StopReason, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
-		)
-		if err != nil {
-			r.logger.Error("Failed to scan transaction row", "error", err)
-			return nil, fmt.Errorf("failed to scan transaction: %w", err)
-		}
-		transactions = append(transactions, &tx)
+	return transactions, nil
+}
+
+// Search implements TransactionRepository.Search. The time range applies to
+// the transaction start time.
+func (r *transactionRepository) Search(ctx context.Context, filter TransactionFilter, opts ListOptions) ([]*Transaction, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if filter.ChargerID != "" {
+		conditions = append(conditions, "charger_id = ?")
+		args = append(args, filter.ChargerID)
+	}
+	if filter.ConnectorID != nil {
+		conditions = append(conditions, "connector_id = ?")
+		args = append(args, *filter.ConnectorID)
+	}
+	if filter.IDTag != "" {
+		conditions = append(conditions, "id_tag = ?")
+		args = append(args, filter.IDTag)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "start_time >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "start_time <= ?")
+		args = append(args, filter.Until.UTC())
 	}
 
-	if err = rows.Err(); err != nil {
-		r.logger.Error("Row iteration error", "error", err)
-		return nil, fmt.Errorf("row iteration error: %w", err)
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, transaction_id, charger_id, connector_id, id_tag, 
+			   start_time, stop_time, meter_start, meter_stop, 
+			   energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at
+		FROM transactions %s`, where)
+
+	order := listOrder{fields: transactionOrderFields, fallback: "start_time", tiebreak: true}
+	transactions, err := listQuery(ctx, r.db, query, args, order, opts, scanTransaction)
+	if err != nil {
+		r.logger.Error("Failed to search transactions", "error", err)
+		return nil, fmt.Errorf("failed to search transactions: %w", err)
 	}
 
 	return transactions, nil
@@ -264,56 +354,50 @@ func (r *transactionRepository) List(ctx context.Context, opts ListOptions) ([]*
 
 // GetByChargerID implements TransactionRepository.GetByChargerID
 func (r *transactionRepository) GetByChargerID(ctx context.Context, chargerID string, opts ListOptions) ([]*Transaction, error) {
-	opts.ValidateSortDirection()
-
-	// Validate order by field for security
-	validOrderFields := map[string]bool{
-		"id": true, "transaction_id": true, "connector_id": true,
-		"id_tag": true, "start_time": true, "stop_time": true, "status": true,
-		"created_at": true, "updated_at": true,
-	}
-
-	if !validOrderFields[opts.OrderBy] {
-		opts.OrderBy = "created_at"
-	}
-
-	query := fmt.Sprintf(`
+	query := `
 		SELECT id, transaction_id, charger_id, connector_id, id_tag, 
 			   start_time, stop_time, meter_start, meter_stop, 
-			   energy_delivered, stop_reason, status, created_at, updated_at
+			   energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at
 		FROM transactions 
-		WHERE charger_id = ?
-		ORDER BY %s %s 
-		LIMIT ? OFFSET ?`, opts.OrderBy, opts.SortDir)
+		WHERE charger_id = ?`
 
-	rows, err := r.db.QueryContext(ctx, query, chargerID, opts.Limit, opts.Offset)
+	order := listOrder{fields: chargerTransactionOrderFields, fallback: "created_at"}
+	transactions, err := listQuery(ctx, r.db, query, []interface{}{chargerID}, order, opts, scanTransaction)
 	if err != nil {
 		r.logger.Error("Failed to get transactions by charger", "charger_id", chargerID, "error", err)
 		return nil, fmt.Errorf("failed to get transactions by charger: %w", err)
 	}
-	defer rows.Close()
 
-	var transactions []*Transaction
-	for rows.Next() {
-		var tx Transaction
-		err := rows.Scan(
-			&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
-			&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
-			&tx.EnergyDelivered, &tx.StopReason, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
-		)
-		if err != nil {
-			r.logger.Error("Failed to scan transaction row", "error", err)
-			return nil, fmt.Errorf("failed to scan transaction: %w", err)
-		}
-		transactions = append(transactions, &tx)
-	}
+	return transactions, nil
+}
 
-	if err = rows.Err(); err != nil {
-		r.logger.Error("Row iteration error", "error", err)
-		return nil, fmt.Errorf("row iteration error: %w", err)
-	}
+// transactionOrderFields are the columns transaction lists may be ordered by
+var transactionOrderFields = map[string]bool{
+	"id": true, "transaction_id": true, "charger_id": true, "connector_id": true,
+	"id_tag": true, "start_time": true, "stop_time": true, "status": true,
+	"created_at": true, "updated_at": true,
+}
 
-	return transactions, nil
+// chargerTransactionOrderFields are the columns a single charger's
+// transactions may be ordered by
+var chargerTransactionOrderFields = map[string]bool{
+	"id": true, "transaction_id": true, "connector_id": true,
+	"id_tag": true, "start_time": true, "stop_time": true, "status": true,
+	"created_at": true, "updated_at": true,
+}
+
+// scanTransaction scans a transactions row selected with the list columns
+func scanTransaction(row rowScanner) (*Transaction, error) {
+	var tx Transaction
+	err := row.Scan(
+		&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
+		&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
+		&tx.EnergyDelivered, &tx.StopReason, &tx.StopSource, &tx.Status, &tx.ClockSkewSeconds, &tx.CreatedAt, &tx.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
 }
 
 // GetActive implements TransactionRepository.GetActive
@@ -321,7 +405,7 @@ func (r *transactionRepository) GetActive(ctx context.Context) ([]*Transaction,
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, id_tag, 
 			   start_time, stop_time, meter_start, meter_stop, 
-			   energy_delivered, stop_reason, status, created_at, updated_at
+			   energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at
 		FROM transactions 
 		WHERE status = 'Active'
 		ORDER BY start_time DESC`
@@ -339,7 +423,7 @@ func (r *transactionRepository) GetActive(ctx context.Context) ([]*Transaction,
 		err := rows.Scan(
 			&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
 			&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
-			&tx.EnergyDelivered, &tx.StopReason, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
+			&tx.EnergyDelivered, &tx.StopReason, &tx.StopSource, &tx.Status, &tx.ClockSkewSeconds, &tx.CreatedAt, &tx.UpdatedAt,
 		)
 		if err != nil {
 			r.logger.Error("Failed to scan transaction row", "error", err)
@@ -361,7 +445,7 @@ func (r *transactionRepository) GetActiveByConnector(ctx context.Context, charge
 	query := `
 		SELECT id, transaction_id, charger_id, connector_id, id_tag, 
 			   start_time, stop_time, meter_start, meter_stop, 
-			   energy_delivered, stop_reason, status, created_at, updated_at
+			   energy_delivered, stop_reason, stop_source, status, clock_skew_seconds, created_at, updated_at
 		FROM transactions 
 		WHERE charger_id = ? AND connector_id = ? AND status = 'Active'
 		ORDER BY start_time DESC
@@ -371,7 +455,7 @@ func (r *transactionRepository) GetActiveByConnector(ctx context.Context, charge
 	err := r.db.QueryRowContext(ctx, query, chargerID, connectorID).Scan(
 		&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
 		&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
-		&tx.EnergyDelivered, &tx.StopReason, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
+		&tx.EnergyDelivered, &tx.StopReason, &tx.StopSource, &tx.Status, &tx.ClockSkewSeconds, &tx.CreatedAt, &tx.UpdatedAt,
 	)
 
 	if err != nil {
@@ -386,17 +470,70 @@ func (r *transactionRepository) GetActiveByConnector(ctx context.Context, charge
 	return &tx, nil
 }
 
+// GetActiveWithoutChargingConnector implements TransactionRepository.GetActiveWithoutChargingConnector
+func (r *transactionRepository) GetActiveWithoutChargingConnector(ctx context.Context) ([]*Transaction, error) {
+	query := `
+		SELECT t.id, t.transaction_id, t.charger_id, t.connector_id, t.id_tag,
+			   t.start_time, t.stop_time, t.meter_start, t.meter_stop,
+			   t.energy_delivered, t.stop_reason, t.stop_source, t.status, t.clock_skew_seconds, t.created_at, t.updated_at
+		FROM transactions t
+		LEFT JOIN charger_connectors c
+			ON c.charger_id = t.charger_id AND c.connector_id = t.connector_id
+		WHERE t.status = 'Active'
+		  AND (c.status IS NULL OR c.status NOT IN ('Charging', 'SuspendedEV', 'SuspendedEVSE'))
+		ORDER BY t.start_time`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get active transactions without charging connector", "error", err)
+		return nil, fmt.Errorf("failed to get active transactions without charging connector: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		var tx Transaction
+		err := rows.Scan(
+			&tx.ID, &tx.TransactionID, &tx.ChargerID, &tx.ConnectorID, &tx.IDTag,
+			&tx.StartTime, &tx.StopTime, &tx.MeterStart, &tx.MeterStop,
+			&tx.EnergyDelivered, &tx.StopReason, &tx.StopSource, &tx.Status, &tx.ClockSkewSeconds, &tx.CreatedAt, &tx.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, &tx)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return transactions, nil
+}
+
 // Stop implements TransactionRepository.Stop
-func (r *transactionRepository) Stop(ctx context.Context, id int, meterStop int, stopTime time.Time, stopReason string) error {
+func (r *transactionRepository) Stop(ctx context.Context, id int, meterStop int, stopTime time.Time, stopReason, stopSource string) error {
 	// Calculate energy delivered
-	energyQuery := `SELECT meter_start FROM transactions WHERE id = ?`
+	energyQuery := `SELECT meter_start, status FROM transactions WHERE id = ?`
 	var meterStart int
-	err := r.db.QueryRowContext(ctx, energyQuery, id).Scan(&meterStart)
+	var status TransactionStatus
+	err := r.db.QueryRowContext(ctx, energyQuery, id).Scan(&meterStart, &status)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("transaction not found: %d", id)
+		}
 		r.logger.Error("Failed to get meter start for transaction", "id", id, "error", err)
 		return fmt.Errorf("failed to get meter start: %w", err)
 	}
 
+	if status == TransactionStatusCompleted {
+		return fmt.Errorf("%w: transaction %d is already stopped", ErrInvalidTransactionTransition, id)
+	}
+	if err := status.ValidateTransition(TransactionStatusCompleted); err != nil {
+		r.logger.Warn("Rejected transaction stop", "id", id, "status", status)
+		return err
+	}
+
 	energyDelivered := meterStop - meterStart
 	if energyDelivered < 0 {
 		energyDelivered = 0 // Prevent negative energy
@@ -404,11 +541,11 @@ func (r *transactionRepository) Stop(ctx context.Context, id int, meterStop int,
 
 	query := `
 		UPDATE transactions 
-		SET meter_stop = ?, stop_time = ?, stop_reason = ?, 
-			energy_delivered = ?, status = 'Completed', updated_at = CURRENT_TIMESTAMP 
+		SET meter_stop = ?, stop_time = ?, stop_reason = ?, stop_source = ?,
+			energy_delivered = ?, status = ?, updated_at = CURRENT_TIMESTAMP 
 		WHERE id = ?`
 
-	result, err := r.db.ExecContext(ctx, query, meterStop, stopTime, stopReason, energyDelivered, id)
+	result, err := r.db.ExecContext(ctx, query, meterStop, stopTime, stopReason, stopSource, energyDelivered, TransactionStatusCompleted, id)
 	if err != nil {
 		r.logger.Error("Failed to stop transaction", "id", id, "error", err)
 		return fmt.Errorf("failed to stop transaction: %w", err)
@@ -427,10 +564,34 @@ func (r *transactionRepository) Stop(ctx context.Context, id int, meterStop int,
 		"id", id,
 		"meter_stop", meterStop,
 		"energy_delivered", energyDelivered,
-		"stop_reason", stopReason)
+		"stop_reason", stopReason,
+		"stop_source", stopSource)
+	return nil
+}
+
+// FlagClockSkew implements TransactionRepository.FlagClockSkew
+func (r *transactionRepository) FlagClockSkew(ctx context.Context, id int, skew time.Duration) error {
+	query := `UPDATE transactions SET clock_skew_seconds = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := r.db.ExecContext(ctx, query, int64(skew/time.Second), id); err != nil {
+		r.logger.Error("Failed to flag transaction clock skew", "id", id, "error", err)
+		return fmt.Errorf("failed to flag clock skew: %w", err)
+	}
 	return nil
 }
 
+// CountOutOfRangeIDs implements TransactionRepository.CountOutOfRangeIDs
+func (r *transactionRepository) CountOutOfRangeIDs(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM transactions WHERE transaction_id < ? OR transaction_id > ?`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, math.MinInt32, math.MaxInt32).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count out of range transaction ids: %w", err)
+	}
+
+	return count, nil
+}
+
 // Count implements TransactionRepository.Count
 func (r *transactionRepository) Count(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM transactions`
@@ -461,19 +622,11 @@ func (r *transactionRepository) CountByChargerID(ctx context.Context, chargerID
 
 // GenerateTransactionID implements TransactionRepository.GenerateTransactionID
 func (r *transactionRepository) GenerateTransactionID(ctx context.Context) (int, error) {
-	// Strategy: Use a combination of timestamp and random number for uniqueness
-	// This ensures we don't have collisions even in high-frequency scenarios
+	// Strategy: pick a random positive int32 so the id survives chargers that
+	// store it as a signed 32-bit integer, retrying on the rare collision
 
 	for attempts := 0; attempts < 10; attempts++ {
-		// Generate a candidate ID using timestamp + random component
-		now := time.Now().Unix()
-		random := rand.Int31n(1000) // 0-999
-		candidateID := int(now*1000 + int64(random))
-
-		// Ensure it's positive and within reasonable bounds
-		if candidateID <= 0 {
-			candidateID = int(time.Now().Unix() % 2147483647) // Keep within int32 range
-		}
+		candidateID := int(rand.Int31n(math.MaxInt32)) + 1
 
 		// Check if this ID is already used
 		checkQuery := `SELECT COUNT(*) FROM transactions WHERE transaction_id = ?`
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TransactionStatus is the lifecycle state of a transaction
+type TransactionStatus string
+
+// Transaction statuses
+const (
+	// TransactionStatusActive is a transaction the charger has started and not stopped
+	TransactionStatusActive TransactionStatus = "Active"
+	// TransactionStatusCompleted is a transaction stopped with a final meter reading
+	TransactionStatusCompleted TransactionStatus = "Completed"
+	// TransactionStatusFaulted is a transaction interrupted by a charger fault;
+	// it may still be stopped or aborted once the charger reports back
+	TransactionStatusFaulted TransactionStatus = "Faulted"
+	// TransactionStatusAborted is a transaction abandoned without a final meter reading
+	TransactionStatusAborted TransactionStatus = "Aborted"
+)
+
+// ErrInvalidTransactionTransition is returned when a transaction update would
+// move it to a status its current status cannot reach
+var ErrInvalidTransactionTransition = errors.New("invalid transaction status transition")
+
+// transactionTransitions are the statuses each status may move to. Completed
+// and Aborted are final.
+var transactionTransitions = map[TransactionStatus][]TransactionStatus{
+	TransactionStatusActive:  {TransactionStatusCompleted, TransactionStatusFaulted, TransactionStatusAborted},
+	TransactionStatusFaulted: {TransactionStatusCompleted, TransactionStatusAborted},
+}
+
+// IsValid reports whether s is one of the transaction statuses
+func (s TransactionStatus) IsValid() bool {
+	switch s {
+	case TransactionStatusActive, TransactionStatusCompleted, TransactionStatusFaulted, TransactionStatusAborted:
+		return true
+	}
+	return false
+}
+
+// IsFinal reports whether no transition leaves s
+func (s TransactionStatus) IsFinal() bool {
+	return s.IsValid() && len(transactionTransitions[s]) == 0
+}
+
+// CanTransitionTo reports whether a transaction in status s may move to next.
+// Keeping the current status is always allowed.
+func (s TransactionStatus) CanTransitionTo(next TransactionStatus) bool {
+	if !next.IsValid() {
+		return false
+	}
+	if s == next {
+		return true
+	}
+	for _, allowed := range transactionTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns ErrInvalidTransactionTransition when s may not move to next
+func (s TransactionStatus) ValidateTransition(next TransactionStatus) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransactionTransition, s, next)
+	}
+	return nil
+}
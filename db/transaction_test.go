@@ -0,0 +1,261 @@
+package db_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionSearch(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repos := dbtest.NewRepositories(t, clock.NewFake(base))
+
+	for _, id := range []string{"CP-1", "CP-2"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+	}
+
+	seed := []struct {
+		chargerID   string
+		connectorID int
+		idTag       string
+		hour        int
+		stopped     bool
+	}{
+		{"CP-1", 1, "TAG-A", 1, true},
+		{"CP-1", 2, "TAG-B", 2, true},
+		{"CP-1", 1, "TAG-B", 3, false},
+		{"CP-2", 1, "TAG-A", 4, true},
+		{"CP-2", 2, "TAG-A", 5, false},
+	}
+	for _, s := range seed {
+		start := base.Add(time.Duration(s.hour) * time.Hour)
+		tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+			ChargerID:   s.chargerID,
+			ConnectorID: s.connectorID,
+			IDTag:       s.idTag,
+			StartTime:   &start,
+		})
+		require.NoError(t, err)
+		if s.stopped {
+			require.NoError(t, repos.Transactions().Stop(ctx, tx.ID, 1000, start.Add(30*time.Minute), "Local", db.StopSourceCharger))
+		}
+	}
+
+	connector1 := 1
+	since := base.Add(2 * time.Hour)
+	until := base.Add(4 * time.Hour)
+
+	tests := []struct {
+		name   string
+		filter db.TransactionFilter
+		want   []int // start hours of the expected transactions, newest first
+	}{
+		{"no filter", db.TransactionFilter{}, []int{5, 4, 3, 2, 1}},
+		{"charger", db.TransactionFilter{ChargerID: "CP-1"}, []int{3, 2, 1}},
+		{"charger and connector", db.TransactionFilter{ChargerID: "CP-1", ConnectorID: &connector1}, []int{3, 1}},
+		{"connector across chargers", db.TransactionFilter{ConnectorID: &connector1}, []int{4, 3, 1}},
+		{"id tag", db.TransactionFilter{IDTag: "TAG-A"}, []int{5, 4, 1}},
+		{"status", db.TransactionFilter{Status: db.TransactionStatusActive}, []int{5, 3}},
+		{"time range", db.TransactionFilter{Since: &since, Until: &until}, []int{4, 3, 2}},
+		{"charger, connector and time range", db.TransactionFilter{ChargerID: "CP-1", ConnectorID: &connector1, Since: &since, Until: &until}, []int{3}},
+		{"tag and status", db.TransactionFilter{IDTag: "TAG-B", Status: db.TransactionStatusCompleted}, []int{2}},
+		{"no match", db.TransactionFilter{ChargerID: "CP-2", IDTag: "TAG-B"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs, err := repos.Transactions().Search(ctx, tt.filter, db.DefaultListOptions())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, transactionHours(base, txs))
+		})
+	}
+
+	t.Run("pagination and ordering", func(t *testing.T) {
+		opts := db.ListOptions{Limit: 2, Offset: 1, OrderBy: "start_time", SortDir: "ASC"}
+		txs, err := repos.Transactions().Search(ctx, db.TransactionFilter{}, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []int{2, 3}, transactionHours(base, txs))
+	})
+}
+
+func transactionHours(base time.Time, txs []*db.Transaction) []int {
+	var hours []int
+	for _, tx := range txs {
+		hours = append(hours, int(tx.StartTime.Sub(base)/time.Hour))
+	}
+	return hours
+}
+
+func TestTransactionIDsFitInInt32(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewDatabase(t)
+	repos := db.NewRepositoryManager(database, dbtest.Logger(), clock.NewFake(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	for _, id := range []int{math.MaxInt32 + 1, math.MinInt32 - 1, 1712345678901} {
+		_, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+			TransactionID: &id, ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-1",
+		})
+		assert.ErrorIs(t, err, db.ErrTransactionIDOutOfRange, "id %d", id)
+	}
+
+	maxID := math.MaxInt32
+	_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+		TransactionID: &maxID, ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-1",
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 2 + i, IDTag: "TAG-1"})
+		require.NoError(t, err)
+		require.NotNil(t, tx.TransactionID)
+		assert.Positive(t, *tx.TransactionID)
+		assert.LessOrEqual(t, *tx.TransactionID, math.MaxInt32)
+	}
+
+	// The schema rejects ids written around the repository
+	_, err = database.Exec(`INSERT INTO transactions (transaction_id, charger_id, connector_id, id_tag, start_time, meter_start, status)
+		VALUES (?, 'CP-1', 1, 'TAG-1', CURRENT_TIMESTAMP, 0, 'Active')`, int64(math.MaxInt32)+1)
+	assert.ErrorContains(t, err, "outside the int32 range")
+	_, err = database.Exec(`UPDATE transactions SET transaction_id = ? WHERE transaction_id = ?`, int64(math.MaxInt32)+1, maxID)
+	assert.ErrorContains(t, err, "outside the int32 range")
+
+	count, err := repos.Transactions().CountOutOfRangeIDs(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestTransactionStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to db.TransactionStatus
+		allowed  bool
+	}{
+		{db.TransactionStatusActive, db.TransactionStatusCompleted, true},
+		{db.TransactionStatusActive, db.TransactionStatusFaulted, true},
+		{db.TransactionStatusActive, db.TransactionStatusAborted, true},
+		{db.TransactionStatusFaulted, db.TransactionStatusCompleted, true},
+		{db.TransactionStatusFaulted, db.TransactionStatusAborted, true},
+		{db.TransactionStatusActive, db.TransactionStatusActive, true},
+		{db.TransactionStatusFaulted, db.TransactionStatusActive, false},
+		{db.TransactionStatusCompleted, db.TransactionStatusActive, false},
+		{db.TransactionStatusCompleted, db.TransactionStatusFaulted, false},
+		{db.TransactionStatusAborted, db.TransactionStatusCompleted, false},
+		{db.TransactionStatusActive, "Paused", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, tt.from.CanTransitionTo(tt.to), "%s to %s", tt.from, tt.to)
+	}
+
+	assert.True(t, db.TransactionStatusCompleted.IsFinal())
+	assert.True(t, db.TransactionStatusAborted.IsFinal())
+	assert.False(t, db.TransactionStatusFaulted.IsFinal())
+}
+
+func TestTransactionRepositoryEnforcesTransitions(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	repos := dbtest.NewRepositories(t, fake)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+
+	create := func(txID int) *db.Transaction {
+		tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{
+			TransactionID: &txID, ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG", MeterStart: 100,
+		})
+		require.NoError(t, err)
+		require.Equal(t, db.TransactionStatusActive, tx.Status)
+		return tx
+	}
+	status := func(s db.TransactionStatus) *db.TransactionStatus { return &s }
+
+	t.Run("fault then stop", func(t *testing.T) {
+		tx := create(1)
+
+		updated, err := repos.Transactions().Update(ctx, tx.ID, db.UpdateTransactionRequest{Status: status(db.TransactionStatusFaulted)})
+		require.NoError(t, err)
+		assert.Equal(t, db.TransactionStatusFaulted, updated.Status)
+
+		// A faulted transaction cannot resume
+		_, err = repos.Transactions().Update(ctx, tx.ID, db.UpdateTransactionRequest{Status: status(db.TransactionStatusActive)})
+		assert.ErrorIs(t, err, db.ErrInvalidTransactionTransition)
+
+		require.NoError(t, repos.Transactions().Stop(ctx, tx.ID, 500, fake.Now(), "EmergencyStop", db.StopSourceCharger))
+		stopped, err := repos.Transactions().GetByID(ctx, tx.ID)
+		require.NoError(t, err)
+		assert.Equal(t, db.TransactionStatusCompleted, stopped.Status)
+		assert.Equal(t, 400, stopped.EnergyDelivered)
+	})
+
+	t.Run("completed is final", func(t *testing.T) {
+		tx := create(2)
+		require.NoError(t, repos.Transactions().Stop(ctx, tx.ID, 300, fake.Now(), "Local", db.StopSourceCharger))
+
+		err := repos.Transactions().Stop(ctx, tx.ID, 900, fake.Now(), "Local", db.StopSourceCharger)
+		assert.ErrorIs(t, err, db.ErrInvalidTransactionTransition)
+
+		_, err = repos.Transactions().Update(ctx, tx.ID, db.UpdateTransactionRequest{Status: status(db.TransactionStatusAborted)})
+		assert.ErrorIs(t, err, db.ErrInvalidTransactionTransition)
+
+		// The rejected stop left the recorded reading alone
+		stopped, err := repos.Transactions().GetByID(ctx, tx.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 300, *stopped.MeterStop)
+	})
+
+	t.Run("aborted cannot be stopped", func(t *testing.T) {
+		tx := create(3)
+		_, err := repos.Transactions().Update(ctx, tx.ID, db.UpdateTransactionRequest{Status: status(db.TransactionStatusAborted)})
+		require.NoError(t, err)
+
+		err = repos.Transactions().Stop(ctx, tx.ID, 300, fake.Now(), "Local", db.StopSourceCharger)
+		assert.ErrorIs(t, err, db.ErrInvalidTransactionTransition)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		tx := create(4)
+		_, err := repos.Transactions().Update(ctx, tx.ID, db.UpdateTransactionRequest{Status: status("Paused")})
+		assert.ErrorIs(t, err, db.ErrInvalidTransactionTransition)
+	})
+}
+
+func TestOneActiveTransactionPerConnector(t *testing.T) {
+	ctx := context.Background()
+	repos := dbtest.NewRepositories(t, clock.NewFake(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	for _, id := range []string{"CP-1", "CP-2"} {
+		_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: id})
+		require.NoError(t, err)
+	}
+
+	first, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-1"})
+	require.NoError(t, err)
+
+	_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-2"})
+	assert.ErrorIs(t, err, db.ErrConnectorTransactionActive)
+
+	// Other connectors and chargers are unaffected
+	_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 2, IDTag: "TAG-2"})
+	require.NoError(t, err)
+	_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-2", ConnectorID: 1, IDTag: "TAG-2"})
+	require.NoError(t, err)
+
+	// A duplicate OCPP transaction id is not mistaken for a busy connector
+	_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{TransactionID: first.TransactionID, ChargerID: "CP-2", ConnectorID: 3, IDTag: "TAG-2"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, db.ErrConnectorTransactionActive)
+
+	require.NoError(t, repos.Transactions().Stop(ctx, first.ID, 500, time.Now(), "Local", db.StopSourceCharger))
+	_, err = repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-2"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// webhookOutboxRepository implements WebhookOutboxRepository
+type webhookOutboxRepository struct {
+	db     Executor
+	logger Logger
+}
+
+// NewWebhookOutboxRepository creates a new webhook outbox repository
+func NewWebhookOutboxRepository(db Executor, logger Logger) WebhookOutboxRepository {
+	return &webhookOutboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Enqueue implements WebhookOutboxRepository.Enqueue
+func (r *webhookOutboxRepository) Enqueue(ctx context.Context, req CreateWebhookDeliveryRequest) (*WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_outbox (
+			event_type, url, payload, status, attempts, next_attempt_at, created_at, updated_at
+		) VALUES (?, ?, ?, 'Pending', 0, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id, event_type, url, payload, status, attempts, next_attempt_at,
+				  last_error, delivered_at, created_at, updated_at`
+
+	var d WebhookDelivery
+	err := r.db.QueryRowContext(ctx, query,
+		req.EventType, req.URL, req.Payload, req.NextAttemptAt.UTC(),
+	).Scan(
+		&d.ID, &d.EventType, &d.URL, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastError, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error("Failed to enqueue webhook", "event_type", req.EventType, "error", err)
+		return nil, fmt.Errorf("failed to enqueue webhook: %w", err)
+	}
+
+	r.logger.Debug("Enqueued webhook", "id", d.ID, "event_type", d.EventType)
+	return &d, nil
+}
+
+// GetByID implements WebhookOutboxRepository.GetByID
+func (r *webhookOutboxRepository) GetByID(ctx context.Context, id int) (*WebhookDelivery, error) {
+	query := `
+		SELECT id, event_type, url, payload, status, attempts, next_attempt_at,
+			   last_error, delivered_at, created_at, updated_at
+		FROM webhook_outbox WHERE id = ?`
+
+	var d WebhookDelivery
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&d.ID, &d.EventType, &d.URL, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastError, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return &d, nil
+}
+
+// GetDue implements WebhookOutboxRepository.GetDue
+func (r *webhookOutboxRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	query := `
+		SELECT id, event_type, url, payload, status, attempts, next_attempt_at,
+			   last_error, delivered_at, created_at, updated_at
+		FROM webhook_outbox
+		WHERE status = 'Pending' AND next_attempt_at <= ?
+		ORDER BY next_attempt_at ASC, id ASC
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, now.UTC(), limit)
+	if err != nil {
+		r.logger.Error("Failed to get due webhooks", "error", err)
+		return nil, fmt.Errorf("failed to get due webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(
+			&d.ID, &d.EventType, &d.URL, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastError, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// MarkDelivered implements WebhookOutboxRepository.MarkDelivered
+func (r *webhookOutboxRepository) MarkDelivered(ctx context.Context, id int, deliveredAt time.Time) error {
+	query := `
+		UPDATE webhook_outbox
+		SET status = 'Delivered', attempts = attempts + 1, delivered_at = ?, last_error = '',
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	return r.exec(ctx, "mark webhook delivered", id, query, deliveredAt.UTC(), id)
+}
+
+// MarkFailed implements WebhookOutboxRepository.MarkFailed
+func (r *webhookOutboxRepository) MarkFailed(ctx context.Context, id int, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE webhook_outbox
+		SET attempts = attempts + 1, next_attempt_at = ?, last_error = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	return r.exec(ctx, "mark webhook failed", id, query, nextAttemptAt.UTC(), lastError, id)
+}
+
+// MarkDeadLetter implements WebhookOutboxRepository.MarkDeadLetter
+func (r *webhookOutboxRepository) MarkDeadLetter(ctx context.Context, id int, lastError string) error {
+	query := `
+		UPDATE webhook_outbox
+		SET status = 'DeadLetter', attempts = attempts + 1, last_error = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	return r.exec(ctx, "mark webhook dead-lettered", id, query, lastError, id)
+}
+
+// exec runs a single-row update and reports a missing row as not found
+func (r *webhookOutboxRepository) exec(ctx context.Context, op string, id int, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to "+op, "id", id, "error", err)
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook delivery not found: %d", id)
+	}
+
+	return nil
+}
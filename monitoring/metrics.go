@@ -4,46 +4,93 @@ import (
 	"net/http"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Metrics holds all application metrics
 type Metrics struct {
+	registry *prometheus.Registry
+	handler  http.Handler
+
+	// Build metrics
+	buildInfo *prometheus.GaugeVec
+
 	// HTTP request metrics
 	httpRequestsTotal    *prometheus.CounterVec
 	httpRequestDuration  *prometheus.HistogramVec
 	httpRequestsInFlight *prometheus.GaugeVec
+	httpPanicsTotal      *prometheus.CounterVec
 
 	// OCPP connection metrics
 	ocppConnectionsTotal  *prometheus.CounterVec
 	ocppConnectionsActive *prometheus.GaugeVec
 	ocppMessagesTotal     *prometheus.CounterVec
 	ocppMessageDuration   *prometheus.HistogramVec
+	ocppHandshakeTimeouts prometheus.Counter
+	ocppDisconnectsTotal  *prometheus.CounterVec
 
 	// Database metrics
 	databaseConnectionsActive *prometheus.GaugeVec
 	databaseQueryDuration     *prometheus.HistogramVec
 	databaseQueriesTotal      *prometheus.CounterVec
+	databaseTxDuration        *prometheus.HistogramVec
+	databaseTxRollbacks       *prometheus.CounterVec
+	databaseWALSize           prometheus.Gauge
+	databaseCheckpointTime    prometheus.Histogram
+	databaseStorageFaults     *prometheus.CounterVec
 
 	// Business metrics
 	chargePointsTotal  *prometheus.GaugeVec
 	transactionsTotal  *prometheus.CounterVec
 	transactionsActive *prometheus.GaugeVec
+	connectorsByStatus *prometheus.GaugeVec
+
+	// Data quality metrics
+	meterValuesUnassociated prometheus.Gauge
+	meterValuesDropped      *prometheus.CounterVec
+	meterValuesRateLimited  *prometheus.CounterVec
+	ocppClockSkew           *prometheus.GaugeVec
+	connectorOutOfRange     *prometheus.CounterVec
+	heartbeatMissed         *prometheus.CounterVec
+	unknownConnectorStatus  *prometheus.CounterVec
+	ocppCallErrors          *prometheus.CounterVec
 }
 
-// NewMetrics creates and registers new metrics
+// NewMetrics creates new metrics registered on their own registry
 func NewMetrics() *Metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	factory := promauto.With(registry)
+
 	metrics := &Metrics{
+		registry: registry,
+		handler: promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			EnableOpenMetrics: true,
+		}),
+
+		// Build metrics
+		buildInfo: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "levity_build_info",
+				Help: "Build information, always 1",
+			},
+			[]string{"version", "commit"},
+		),
+
 		// HTTP metrics
-		httpRequestsTotal: promauto.NewCounterVec(
+		httpRequestsTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "http_requests_total",
 				Help: "Total number of HTTP requests",
 			},
 			[]string{"method", "endpoint", "status"},
 		),
-		httpRequestDuration: promauto.NewHistogramVec(
+		httpRequestDuration: factory.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:    "http_request_duration_seconds",
 				Help:    "HTTP request duration in seconds",
@@ -51,37 +98,44 @@ func NewMetrics() *Metrics {
 			},
 			[]string{"method", "endpoint"},
 		),
-		httpRequestsInFlight: promauto.NewGaugeVec(
+		httpRequestsInFlight: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "http_requests_in_flight",
 				Help: "Current number of HTTP requests being processed",
 			},
 			[]string{"method", "endpoint"},
 		),
+		httpPanicsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_panics_total",
+				Help: "Total number of HTTP handlers that panicked",
+			},
+			[]string{"method", "endpoint"},
+		),
 
 		// OCPP metrics
-		ocppConnectionsTotal: promauto.NewCounterVec(
+		ocppConnectionsTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "ocpp_connections_total",
 				Help: "Total number of OCPP connections",
 			},
 			[]string{"charge_point_id", "status"},
 		),
-		ocppConnectionsActive: promauto.NewGaugeVec(
+		ocppConnectionsActive: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "ocpp_connections_active",
 				Help: "Current number of active OCPP connections",
 			},
 			[]string{"charge_point_id"},
 		),
-		ocppMessagesTotal: promauto.NewCounterVec(
+		ocppMessagesTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "ocpp_messages_total",
 				Help: "Total number of OCPP messages processed",
 			},
 			[]string{"charge_point_id", "message_type", "direction"},
 		),
-		ocppMessageDuration: promauto.NewHistogramVec(
+		ocppMessageDuration: factory.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:    "ocpp_message_duration_seconds",
 				Help:    "OCPP message processing duration in seconds",
@@ -90,15 +144,29 @@ func NewMetrics() *Metrics {
 			[]string{"charge_point_id", "message_type"},
 		),
 
+		ocppHandshakeTimeouts: factory.NewCounter(
+			prometheus.CounterOpts{
+				Name: "ocpp_handshake_timeouts_total",
+				Help: "Total number of connections closed for not completing the handshake in time",
+			},
+		),
+		ocppDisconnectsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ocpp_disconnects_total",
+				Help: "Total number of OCPP connections closed, by reason",
+			},
+			[]string{"reason"},
+		),
+
 		// Database metrics
-		databaseConnectionsActive: promauto.NewGaugeVec(
+		databaseConnectionsActive: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "database_connections_active",
 				Help: "Current number of active database connections",
 			},
 			[]string{"database"},
 		),
-		databaseQueryDuration: promauto.NewHistogramVec(
+		databaseQueryDuration: factory.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:    "database_query_duration_seconds",
 				Help:    "Database query duration in seconds",
@@ -106,36 +174,135 @@ func NewMetrics() *Metrics {
 			},
 			[]string{"database", "query_type"},
 		),
-		databaseQueriesTotal: promauto.NewCounterVec(
+		databaseQueriesTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "database_queries_total",
 				Help: "Total number of database queries",
 			},
 			[]string{"database", "query_type", "status"},
 		),
+		databaseTxDuration: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "database_transaction_duration_seconds",
+				Help:    "Database transaction duration from begin to commit or rollback in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"operation"},
+		),
+		databaseTxRollbacks: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "database_transaction_rollbacks_total",
+				Help: "Total number of database transactions rolled back",
+			},
+			[]string{"operation"},
+		),
+		databaseWALSize: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "database_wal_size_bytes",
+				Help: "Size of the SQLite write-ahead log after the last checkpoint",
+			},
+		),
+		databaseCheckpointTime: factory.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "database_checkpoint_duration_seconds",
+				Help:    "Duration of timed WAL checkpoints in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+		),
+		databaseStorageFaults: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "database_storage_faults_total",
+				Help: "Total number of queries failed by a full disk, an I/O error or corruption, by kind",
+			},
+			[]string{"kind"},
+		),
 
 		// Business metrics
-		chargePointsTotal: promauto.NewGaugeVec(
+		chargePointsTotal: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "charge_points_total",
 				Help: "Total number of charge points",
 			},
 			[]string{"status"},
 		),
-		transactionsTotal: promauto.NewCounterVec(
+		transactionsTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "transactions_total",
 				Help: "Total number of transactions",
 			},
 			[]string{"charge_point_id", "status"},
 		),
-		transactionsActive: promauto.NewGaugeVec(
+		transactionsActive: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "transactions_active",
 				Help: "Current number of active transactions",
 			},
 			[]string{"charge_point_id"},
 		),
+		connectorsByStatus: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "connectors_by_status",
+				Help: "Current number of connectors in each OCPP status",
+			},
+			[]string{"status"},
+		),
+
+		// Data quality metrics
+		meterValuesUnassociated: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "meter_values_unassociated",
+				Help: "Number of meter values that could not be linked to a transaction",
+			},
+		),
+		meterValuesDropped: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "meter_values_dropped_total",
+				Help: "Total number of sampled values dropped because their measurand is not persisted",
+			},
+			[]string{"measurand"},
+		),
+		meterValuesRateLimited: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "meter_values_rate_limited_total",
+				Help: "Total number of sampled values dropped because a charger exceeded its meter value rate limit",
+			},
+			[]string{"charge_point_id"},
+		),
+		ocppClockSkew: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ocpp_clock_skew_seconds",
+				Help: "Difference between the last charger-supplied timestamp and server time, positive when the charger clock is ahead",
+			},
+			[]string{"charge_point_id"},
+		),
+		connectorOutOfRange: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ocpp_connector_out_of_range_total",
+				Help: "Total number of StatusNotifications ignored because their connector id exceeds ocpp.max_connectors_per_charger",
+			},
+			[]string{"charge_point_id"},
+		),
+		ocppCallErrors: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ocpp_call_errors_total",
+				Help: "Total number of CALLERRORs sent to or received from charge points, by action and error code",
+			},
+			[]string{"action", "error_code"},
+		),
+		unknownConnectorStatus: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ocpp_unknown_connector_status_total",
+				Help: "Total number of StatusNotifications reporting a status outside the OCPP set",
+			},
+			[]string{"charge_point_id"},
+		),
+		heartbeatMissed: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ocpp_heartbeat_missed_total",
+				Help: "Total number of HeartbeatMissed alerts raised for charge points overdue on their heartbeat",
+			},
+			[]string{"charge_point_id"},
+		),
 	}
 
 	return metrics
@@ -156,6 +323,11 @@ func (m *Metrics) SetHTTPRequestsInFlight(method, endpoint string, count float64
 	m.httpRequestsInFlight.WithLabelValues(method, endpoint).Set(count)
 }
 
+// RecordHTTPPanic counts a recovered panic in an HTTP handler
+func (m *Metrics) RecordHTTPPanic(method, endpoint string) {
+	m.httpPanicsTotal.WithLabelValues(method, endpoint).Inc()
+}
+
 // RecordOCPPConnection records an OCPP connection metric
 func (m *Metrics) RecordOCPPConnection(chargePointID, status string) {
 	m.ocppConnectionsTotal.WithLabelValues(chargePointID, status).Inc()
@@ -166,6 +338,11 @@ func (m *Metrics) SetOCPPConnectionsActive(chargePointID string, count float64)
 	m.ocppConnectionsActive.WithLabelValues(chargePointID).Set(count)
 }
 
+// RecordOCPPDisconnect counts why a closed OCPP connection closed
+func (m *Metrics) RecordOCPPDisconnect(chargePointID, reason string) {
+	m.ocppDisconnectsTotal.WithLabelValues(reason).Inc()
+}
+
 // RecordOCPPMessage records an OCPP message metric
 func (m *Metrics) RecordOCPPMessage(chargePointID, messageType, direction string) {
 	m.ocppMessagesTotal.WithLabelValues(chargePointID, messageType, direction).Inc()
@@ -176,6 +353,11 @@ func (m *Metrics) RecordOCPPMessageDuration(chargePointID, messageType string, d
 	m.ocppMessageDuration.WithLabelValues(chargePointID, messageType).Observe(duration)
 }
 
+// RecordHandshakeTimeout counts a connection closed during a stalled handshake
+func (m *Metrics) RecordHandshakeTimeout() {
+	m.ocppHandshakeTimeouts.Inc()
+}
+
 // SetDatabaseConnectionsActive sets the number of active database connections
 func (m *Metrics) SetDatabaseConnectionsActive(database string, count float64) {
 	m.databaseConnectionsActive.WithLabelValues(database).Set(count)
@@ -191,6 +373,27 @@ func (m *Metrics) RecordDatabaseQueryDuration(database, queryType string, durati
 	m.databaseQueryDuration.WithLabelValues(database, queryType).Observe(duration)
 }
 
+// RecordDatabaseTransaction records the duration of a database transaction,
+// counting it as a rollback when it did not commit
+func (m *Metrics) RecordDatabaseTransaction(operation string, duration float64, committed bool) {
+	m.databaseTxDuration.WithLabelValues(operation).Observe(duration)
+	if !committed {
+		m.databaseTxRollbacks.WithLabelValues(operation).Inc()
+	}
+}
+
+// RecordDatabaseCheckpoint records the duration of a WAL checkpoint and the
+// WAL size it left behind
+func (m *Metrics) RecordDatabaseCheckpoint(duration float64, walSize int64) {
+	m.databaseCheckpointTime.Observe(duration)
+	m.databaseWALSize.Set(float64(walSize))
+}
+
+// RecordDatabaseStorageFault counts a query failed by the storage under the database
+func (m *Metrics) RecordDatabaseStorageFault(kind string) {
+	m.databaseStorageFaults.WithLabelValues(kind).Inc()
+}
+
 // SetChargePointsTotal sets the total number of charge points
 func (m *Metrics) SetChargePointsTotal(status string, count float64) {
 	m.chargePointsTotal.WithLabelValues(status).Set(count)
@@ -206,7 +409,82 @@ func (m *Metrics) SetTransactionsActive(chargePointID string, count float64) {
 	m.transactionsActive.WithLabelValues(chargePointID).Set(count)
 }
 
-// Handler returns an HTTP handler for Prometheus metrics
+// SetConnectorsByStatus replaces the connector counts by status, dropping
+// statuses no connector is in
+func (m *Metrics) SetConnectorsByStatus(counts map[string]int) {
+	m.connectorsByStatus.Reset()
+	for status, count := range counts {
+		m.connectorsByStatus.WithLabelValues(status).Set(float64(count))
+	}
+}
+
+// MoveConnectorStatus moves a connector from one status to another. An empty
+// from counts a new connector; equal statuses leave the gauge unchanged.
+func (m *Metrics) MoveConnectorStatus(from, to string) {
+	if from == to {
+		return
+	}
+	if from != "" {
+		m.connectorsByStatus.WithLabelValues(from).Dec()
+	}
+	if to != "" {
+		m.connectorsByStatus.WithLabelValues(to).Inc()
+	}
+}
+
+// SetMeterValuesUnassociated sets the number of meter values not linked to a transaction
+func (m *Metrics) SetMeterValuesUnassociated(count float64) {
+	m.meterValuesUnassociated.Set(count)
+}
+
+// RecordMeterValueDropped counts a sampled value that was not persisted
+func (m *Metrics) RecordMeterValueDropped(measurand string) {
+	m.meterValuesDropped.WithLabelValues(measurand).Inc()
+}
+
+// RecordMeterValuesRateLimited counts sampled values dropped by the rate limit
+func (m *Metrics) RecordMeterValuesRateLimited(chargePointID string, count int) {
+	m.meterValuesRateLimited.WithLabelValues(chargePointID).Add(float64(count))
+}
+
+// SetBuildInfo records the running build in the levity_build_info metric
+func (m *Metrics) SetBuildInfo(version, commit string) {
+	m.buildInfo.Reset()
+	m.buildInfo.WithLabelValues(version, commit).Set(1)
+}
+
+// Registry returns the registry holding the application metrics
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics, negotiating the OpenMetrics format when requested
 func (m *Metrics) Handler(w http.ResponseWriter, r *http.Request) {
-	promhttp.Handler().ServeHTTP(w, r)
+	m.handler.ServeHTTP(w, r)
+}
+
+// SetClockSkew sets the last observed clock skew of a charge point
+func (m *Metrics) SetClockSkew(chargePointID string, seconds float64) {
+	m.ocppClockSkew.WithLabelValues(chargePointID).Set(seconds)
+}
+
+// RecordConnectorOutOfRange counts a StatusNotification for a connector id beyond the configured limit
+func (m *Metrics) RecordConnectorOutOfRange(chargePointID string) {
+	m.connectorOutOfRange.WithLabelValues(chargePointID).Inc()
+}
+
+// RecordCallError counts a CALLERROR answering a CALL for action. Callers
+// bound both labels to the OCPP sets.
+func (m *Metrics) RecordCallError(action, errorCode string) {
+	m.ocppCallErrors.WithLabelValues(action, errorCode).Inc()
+}
+
+// RecordUnknownConnectorStatus counts a StatusNotification with a status outside the OCPP set
+func (m *Metrics) RecordUnknownConnectorStatus(chargePointID string) {
+	m.unknownConnectorStatus.WithLabelValues(chargePointID).Inc()
+}
+
+// RecordHeartbeatMissed counts a HeartbeatMissed alert for a charge point
+func (m *Metrics) RecordHeartbeatMissed(chargePointID string) {
+	m.heartbeatMissed.WithLabelValues(chargePointID).Inc()
 }
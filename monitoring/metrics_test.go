@@ -0,0 +1,49 @@
+package monitoring
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInfo(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.SetBuildInfo("1.2.3", "abc123")
+
+	expected := `
+# HELP levity_build_info Build information, always 1
+# TYPE levity_build_info gauge
+levity_build_info{commit="abc123",version="1.2.3"} 1
+`
+	err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "levity_build_info")
+	require.NoError(t, err)
+
+	// Setting it again replaces the previous build
+	metrics.SetBuildInfo("1.2.4", "def456")
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.buildInfo))
+}
+
+func TestHandlerNegotiatesOpenMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.SetBuildInfo("1.2.3", "abc123")
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w := httptest.NewRecorder()
+	metrics.Handler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/openmetrics-text")
+	assert.Contains(t, w.Body.String(), "levity_build_info")
+	assert.True(t, strings.HasSuffix(w.Body.String(), "# EOF\n"))
+
+	// Plain scrapes still get the text format
+	w = httptest.NewRecorder()
+	metrics.Handler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+}
@@ -6,7 +6,8 @@ import (
 	"github.com/keeth/levity/config"
 )
 
-// LoadBalancingPlugin handles load balancing between multiple charging stations
+// LoadBalancingPlugin handles load balancing between the charging stations of
+// each site, keeping their combined draw within the site's max_current
 type LoadBalancingPlugin struct {
 	config  *config.Config
 	logger  *slog.Logger
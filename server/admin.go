@@ -0,0 +1,99 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// reconcile checks connector, transaction and connection state for
+// inconsistencies, fixing them when ?fix=true
+func (s *Server) reconcile(c *gin.Context) {
+	fix := false
+	if raw := c.Query("fix"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fix parameter"})
+			return
+		}
+		fix = parsed
+	}
+	if fix && !s.confirmed(c, operationReconcileFix) {
+		return
+	}
+
+	report, err := s.coreSystem.CheckConsistency(c.Request.Context(), fix)
+	if err != nil {
+		s.logger.Error("Consistency check failed", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Consistency check failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// listAudit lists audit log entries, newest first, filtered by entity_type,
+// entity_id, actor, operation and an RFC 3339 since/until range
+func (s *Server) listAudit(c *gin.Context) {
+	filter := db.AuditFilter{
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		Actor:      c.Query("actor"),
+		Operation:  c.Query("operation"),
+	}
+
+	var err error
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since parameter"})
+		return
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until parameter"})
+		return
+	}
+	if filter.Limit, err = queryInt(c, "limit"); err != nil || filter.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+	if filter.Offset, err = queryInt(c, "offset"); err != nil || filter.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return
+	}
+
+	entries, err := s.coreSystem.GetRepositories().Audit().List(c.Request.Context(), filter)
+	if err != nil {
+		s.logger.Error("Failed to list audit entries", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit entries"})
+		return
+	}
+	if entries == nil {
+		entries = []*db.AuditEntry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// setMaintenanceRequest is the body of a maintenance mode change
+type setMaintenanceRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// setMaintenance turns maintenance mode on or off
+func (s *Server) setMaintenance(c *gin.Context) {
+	var req setMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Enabled == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := s.coreSystem.SetMaintenanceMode(c.Request.Context(), *req.Enabled); err != nil {
+		s.logger.Error("Failed to set maintenance mode", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set maintenance mode"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": *req.Enabled})
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/db"
+)
+
+// Role is the access level granted to an API key
+type Role int
+
+// API key roles, in increasing order of privilege
+const (
+	RoleNone Role = iota
+	RoleOperator
+	RoleAdmin
+)
+
+// roleContextKey is the gin context key holding the caller's role
+const roleContextKey = "auth_role"
+
+// apiKeyHeader is an alternative to the Authorization bearer token
+const apiKeyHeader = "X-API-Key"
+
+// String returns the role name
+func (r Role) String() string {
+	switch r {
+	case RoleAdmin:
+		return "admin"
+	case RoleOperator:
+		return "operator"
+	default:
+		return "none"
+	}
+}
+
+// roleForKey returns the role granted to an API key
+func roleForKey(cfg config.AuthConfig, key string) Role {
+	if key == "" {
+		return RoleNone
+	}
+	if containsKey(cfg.AdminKeys, key) {
+		return RoleAdmin
+	}
+	if containsKey(cfg.OperatorKeys, key) {
+		return RoleOperator
+	}
+	return RoleNone
+}
+
+// containsKey compares the key against each candidate in constant time
+func containsKey(keys []string, key string) bool {
+	found := false
+	for _, candidate := range keys {
+		if candidate != "" && subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			found = true
+		}
+	}
+	return found
+}
+
+// requestAPIKey extracts the API key from the Authorization or X-API-Key header
+func requestAPIKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return c.GetHeader(apiKeyHeader)
+}
+
+// requireRole rejects requests whose API key does not grant at least the given role
+func requireRole(cfg config.AuthConfig, role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := requestAPIKey(c)
+		granted := roleForKey(cfg, key)
+
+		if granted == RoleNone {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing API key"})
+			return
+		}
+		if granted < role {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+
+		c.Set(roleContextKey, granted)
+		c.Request = c.Request.WithContext(db.WithActor(c.Request.Context(), actorForKey(granted, key)))
+		c.Next()
+	}
+}
+
+// actorForKey identifies the caller in the audit log by role and a key
+// fingerprint, so the key itself is never stored
+func actorForKey(role Role, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return role.String() + ":" + hex.EncodeToString(sum[:4])
+}
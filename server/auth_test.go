@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.AuthConfig{
+		AdminKeys:    []string{"admin-key"},
+		OperatorKeys: []string{"operator-key"},
+	}
+
+	router := gin.New()
+	router.GET("/admin", requireRole(cfg, RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/ops", requireRole(cfg, RoleOperator), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"missing key", "/admin", "", "", http.StatusUnauthorized},
+		{"unknown key", "/admin", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"operator on admin route", "/admin", "Authorization", "Bearer operator-key", http.StatusForbidden},
+		{"admin bearer", "/admin", "Authorization", "Bearer admin-key", http.StatusOK},
+		{"admin api key header", "/admin", apiKeyHeader, "admin-key", http.StatusOK},
+		{"operator on operator route", "/ops", apiKeyHeader, "operator-key", http.StatusOK},
+		{"admin on operator route", "/ops", apiKeyHeader, "admin-key", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/db"
+)
+
+// compileChargerIDPattern compiles the configured charger id pattern. An empty
+// or invalid pattern falls back to the default; config.Load rejects an
+// invalid one up front.
+func compileChargerIDPattern(pattern string) *regexp.Regexp {
+	if pattern != "" {
+		if re, err := regexp.Compile(pattern); err == nil {
+			return re
+		}
+	}
+	return regexp.MustCompile(config.DefaultChargerIDPattern)
+}
+
+// validChargerID reports whether id may be used as a charge point id
+func (s *Server) validChargerID(id string) bool {
+	return s.chargerIDPattern.MatchString(id)
+}
+
+// createChargerRequest is the body of a charge point registration
+type createChargerRequest struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	NumConnectors int    `json:"num_connectors"`
+}
+
+// createChargePoint registers a charge point ahead of its first connection
+func (s *Server) createChargePoint(c *gin.Context) {
+	var req createChargerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if !s.validChargerID(req.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid charge point ID"})
+		return
+	}
+	if req.NumConnectors < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid number of connectors"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	chargers := s.coreSystem.GetRepositories().Chargers()
+	if _, err := chargers.GetByID(ctx, req.ID); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Charge point already exists"})
+		return
+	}
+
+	charger, err := chargers.Create(ctx, db.CreateChargerRequest{
+		ID:            req.ID,
+		Name:          req.Name,
+		NumConnectors: req.NumConnectors,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create charger", slog.String("charger_id", req.ID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create charge point"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newChargerResponse(charger))
+}
+
+// updateChargerRequest is the body of a charge point update; omitted fields
+// are left unchanged
+type updateChargerRequest struct {
+	Name          *string `json:"name"`
+	NumConnectors *int    `json:"num_connectors"`
+}
+
+// updateChargePoint changes the name or connector count of a charge point
+func (s *Server) updateChargePoint(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	var req updateChargerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.NumConnectors != nil && *req.NumConnectors < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid number of connectors"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	chargers := s.coreSystem.GetRepositories().Chargers()
+	if _, err := chargers.GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	charger, err := chargers.Update(ctx, chargerID, db.UpdateChargerRequest{
+		Name:          req.Name,
+		NumConnectors: req.NumConnectors,
+	})
+	if err != nil {
+		s.logger.Error("Failed to update charger", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update charge point"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newChargerResponse(charger))
+}
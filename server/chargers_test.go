@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateChargePoint(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+
+	w := postCommand(srv, "/api/v1/chargepoints", `{"id": "CP-NEW.1", "name": "Lobby", "num_connectors": 2}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"id":"CP-NEW.1"`)
+
+	charger, err := srv.coreSystem.GetRepositories().Chargers().GetByID(context.Background(), "CP-NEW.1")
+	require.NoError(t, err)
+	assert.Equal(t, "Lobby", charger.Name)
+	assert.Equal(t, 2, charger.NumConnectors)
+
+	// The same id cannot be registered twice
+	w = postCommand(srv, "/api/v1/chargepoints", `{"id": "CP-NEW.1"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestGetChargePointDetail(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ctx := context.Background()
+	repos := srv.coreSystem.GetRepositories()
+	at := time.Date(2024, 11, 4, 9, 0, 0, 0, time.UTC)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1", Vendor: "Acme"})
+	require.NoError(t, err)
+	for _, connectorID := range []int{1, 2} {
+		_, err = repos.Connectors().Create(ctx, "CP-1", connectorID)
+		require.NoError(t, err)
+	}
+	for i, value := range []float64{1200, 1500} {
+		_, err = repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			ChargerID: "CP-1", ConnectorID: 1, Timestamp: at.Add(time.Duration(i) * time.Minute),
+			Measurand: "Energy.Active.Import.Register", Value: dbtest.Float64(value), Unit: "Wh",
+		})
+		require.NoError(t, err)
+	}
+	active, err := repos.Errors().Create(ctx, db.CreateChargerErrorRequest{ChargerID: "CP-1", ErrorCode: "GroundFailure", Timestamp: at})
+	require.NoError(t, err)
+	resolved, err := repos.Errors().Create(ctx, db.CreateChargerErrorRequest{ChargerID: "CP-1", ErrorCode: "OverVoltage", Timestamp: at})
+	require.NoError(t, err)
+	require.NoError(t, repos.Errors().Resolve(ctx, resolved.ID, at))
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chargepoints/CP-1", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var detail chargerDetailResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "CP-1", detail.ID)
+	assert.Equal(t, "Acme", detail.Vendor)
+
+	require.Len(t, detail.Connectors, 2)
+	assert.Equal(t, 1, detail.Connectors[0].ConnectorID)
+	require.NotNil(t, detail.Connectors[0].LatestMeterValue)
+	assert.Equal(t, 1500.0, detail.Connectors[0].LatestMeterValue.Value)
+	assert.Nil(t, detail.Connectors[1].LatestMeterValue)
+
+	require.Len(t, detail.ActiveErrors, 1)
+	assert.Equal(t, active.ID, detail.ActiveErrors[0].ID)
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chargepoints/CP-MISSING", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error": "Charge point not found"}`, w.Body.String())
+}
+
+func TestListChargePoints(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	for i := 1; i <= 3; i++ {
+		_, err := srv.coreSystem.GetRepositories().Chargers().Create(context.Background(), db.CreateChargerRequest{ID: fmt.Sprintf("CP-%d", i)})
+		require.NoError(t, err)
+	}
+
+	list := func(query string) ([]string, int, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chargepoints?"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp struct {
+			ChargePoints []chargerResponse `json:"chargepoints"`
+			Total        int               `json:"total"`
+			Limit        int               `json:"limit"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		ids := make([]string, 0, len(resp.ChargePoints))
+		for _, charger := range resp.ChargePoints {
+			ids = append(ids, charger.ID)
+		}
+		return ids, resp.Limit, w
+	}
+
+	ids, limit, w := list("limit=2&offset=1&order_by=id&sort_dir=asc")
+	assert.Equal(t, []string{"CP-2", "CP-3"}, ids)
+	assert.Equal(t, 2, limit)
+	assert.Equal(t, "5", w.Header().Get("X-Total-Count"))
+	assert.Contains(t, w.Body.String(), `"total":5`)
+
+	// Unknown columns fall back to the default order rather than failing
+	ids, _, _ = list("order_by=nope&sort_dir=asc")
+	assert.Len(t, ids, 5)
+
+	_, limit, _ = list("limit=10000")
+	assert.Equal(t, maxListLimit, limit)
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chargepoints?limit=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateChargePointRejectsInvalidID(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+
+	for _, body := range []string{
+		`{"id": ""}`,
+		`{"id": "CP 001"}`,
+		`{"id": "CP/001"}`,
+		`{"id": "../etc"}`,
+		`{"id": "` + strings.Repeat("a", 65) + `"}`,
+	} {
+		w := postCommand(srv, "/api/v1/chargepoints", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestUpdateChargePoint(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+
+	w := patchCommand(srv, "/api/v1/chargepoints/CP-ONLINE", `{"num_connectors": 3}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	charger, err := srv.coreSystem.GetRepositories().Chargers().GetByID(context.Background(), "CP-ONLINE")
+	require.NoError(t, err)
+	assert.Equal(t, 3, charger.NumConnectors)
+
+	w = patchCommand(srv, "/api/v1/chargepoints/CP-ONLINE", `{"num_connectors": -1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = patchCommand(srv, "/api/v1/chargepoints/CP-MISSING", `{"name": "Garage"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func patchCommand(srv *Server, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apiKeyHeader, testOperatorKey)
+	srv.router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,345 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// changeAvailabilityRequest is the body of an availability change. Connector 0
+// addresses the whole charge point.
+type changeAvailabilityRequest struct {
+	ConnectorID int    `json:"connector_id"`
+	Type        string `json:"type"`
+}
+
+// remoteStartRequest is the body of a remote start. Without a connector the
+// charger picks one.
+type remoteStartRequest struct {
+	IDTag       string `json:"id_tag"`
+	ConnectorID *int   `json:"connector_id"`
+}
+
+// resetRequest is the body of a reset
+type resetRequest struct {
+	Type string `json:"type"`
+}
+
+// validateCommand responds with the resolved targets of a command instead of
+// sending it, for ?validate=true requests
+func (s *Server) validateCommand(c *gin.Context, action string, chargerIDs ...string) {
+	targets := s.coreSystem.ValidateCommand(c.Request.Context(), action, chargerIDs)
+	c.JSON(http.StatusOK, gin.H{
+		"validate": true,
+		"action":   action,
+		"targets":  targets,
+	})
+}
+
+// changeAvailability sets a charger or one of its connectors Operative or Inoperative
+func (s *Server) changeAvailability(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	var req changeAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := core.ValidateAvailability(req.ConnectorID, req.Type); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if validate {
+		s.validateCommand(c, ocpp.ActionChangeAvailability, chargerID)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	status, err := s.coreSystem.Availability().ChangeAvailability(ctx, chargerID, req.ConnectorID, req.Type)
+	if err != nil {
+		if errors.Is(err, ocpp.ErrNotConnected) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+			return
+		}
+		s.logger.Error("Failed to change availability", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to change availability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
+
+// remoteStart asks a charger to start a transaction for an ID tag
+func (s *Server) remoteStart(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	var req remoteStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := core.ValidateRemoteStart(req.IDTag, req.ConnectorID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if validate {
+		s.validateCommand(c, ocpp.ActionRemoteStartTransaction, chargerID)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	status, err := s.coreSystem.RemoteControl().RemoteStart(ctx, chargerID, req.IDTag, req.ConnectorID)
+	if err != nil {
+		if errors.Is(err, ocpp.ErrNotConnected) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Charge point did not answer in time"})
+			return
+		}
+		s.logger.Error("Failed to remote start", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to remote start"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
+
+// reset asks a charger to perform a Soft or Hard reset
+func (s *Server) reset(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	var req resetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := core.ValidateReset(req.Type); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if validate {
+		s.validateCommand(c, ocpp.ActionReset, chargerID)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	status, err := s.coreSystem.RemoteControl().Reset(ctx, chargerID, req.Type)
+	if err != nil {
+		if errors.Is(err, ocpp.ErrNotConnected) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Charge point did not answer in time"})
+			return
+		}
+		s.logger.Error("Failed to reset", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to reset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
+
+// listCommandHistory lists the commands sent to a charger, newest first
+func (s *Server) listCommandHistory(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	opts, ok := queryListOptions(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	repos := s.coreSystem.GetRepositories()
+	if _, err := repos.Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	entries, err := repos.Commands().ListByCharger(ctx, chargerID, opts)
+	if err != nil {
+		s.logger.Error("Failed to list command history", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list command history"})
+		return
+	}
+	if entries == nil {
+		entries = []*db.CommandHistoryEntry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"commands": entries,
+		"limit":    opts.Limit,
+		"offset":   opts.Offset,
+	})
+}
+
+// triggerMeterValuesRequest is the optional body of a MeterValues trigger. An
+// omitted connector asks for every connector.
+type triggerMeterValuesRequest struct {
+	ConnectorID *int `json:"connector_id"`
+}
+
+// triggerMeterValues asks a charger to send MeterValues now
+func (s *Server) triggerMeterValues(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	var req triggerMeterValuesRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+	if req.ConnectorID != nil && *req.ConnectorID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connector ID"})
+		return
+	}
+
+	if validate {
+		s.validateCommand(c, ocpp.ActionTriggerMessage, chargerID)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	status, err := s.coreSystem.MeterValues().TriggerMeterValues(ctx, chargerID, req.ConnectorID)
+	if err != nil {
+		if errors.Is(err, ocpp.ErrNotConnected) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+			return
+		}
+		s.logger.Error("Failed to trigger meter values", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to trigger meter values"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
+
+// getConfiguration reads a charger's configuration, the keys given as
+// repeated key parameters or all of them
+func (s *Server) getConfiguration(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	keys := c.QueryArray("key")
+	for _, key := range keys {
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key parameter"})
+			return
+		}
+	}
+
+	if validate {
+		s.validateCommand(c, ocpp.ActionGetConfiguration, chargerID)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	result, err := s.coreSystem.Configuration().Get(ctx, chargerID, keys)
+	if err != nil {
+		if errors.Is(err, ocpp.ErrNotConnected) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Charge point did not answer in time"})
+			return
+		}
+		s.logger.Error("Failed to get configuration", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get configuration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOperatorKey = "operator-key"
+
+// recordingSender counts the OCPP calls the server tries to send
+type recordingSender struct {
+	actions []string
+}
+
+func (s *recordingSender) SendCall(ctx context.Context, chargerID, action string, request, response interface{}) error {
+	s.actions = append(s.actions, action)
+	return json.Unmarshal([]byte(`{"status":"Accepted"}`), response)
+}
+
+// staticRegistry reports a fixed set of chargers as connected
+type staticRegistry map[string]bool
+
+func (r staticRegistry) IsConnected(chargerID string) bool {
+	return r[chargerID]
+}
+
+func newCommandTestServer(t *testing.T) (*Server, *recordingSender) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Auth.OperatorKeys = []string{testOperatorKey}
+	cfg.Database = config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "levity.db"), MaxOpenConns: 5, MaxIdleConns: 5, AutoMigrate: true}
+
+	system, err := core.NewSystem(cfg, dbtest.Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { system.GetDatabase().Close() })
+
+	ctx := context.Background()
+	for _, id := range []string{"CP-ONLINE", "CP-OFFLINE"} {
+		_, err := system.GetRepositories().Chargers().Create(ctx, db.CreateChargerRequest{ID: id, NumConnectors: 1})
+		require.NoError(t, err)
+		_, err = system.GetRepositories().Connectors().Create(ctx, id, 1)
+		require.NoError(t, err)
+	}
+
+	sender := &recordingSender{}
+	system.SetCommandSender(sender)
+	system.SetConnectionRegistry(staticRegistry{"CP-ONLINE": true})
+
+	return NewServer(cfg, system, nil, dbtest.Logger()), sender
+}
+
+type validateResponse struct {
+	Validate bool                 `json:"validate"`
+	Action   string               `json:"action"`
+	Targets  []core.CommandTarget `json:"targets"`
+}
+
+func postCommand(srv *Server, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apiKeyHeader, testOperatorKey)
+	srv.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestValidateCommandSendsNothing(t *testing.T) {
+	srv, sender := newCommandTestServer(t)
+
+	tests := []struct {
+		path   string
+		body   string
+		action string
+		target core.CommandTarget
+	}{
+		{
+			path:   "/api/v1/chargepoints/CP-ONLINE/availability?validate=true",
+			body:   `{"connector_id": 0, "type": "Inoperative"}`,
+			action: ocpp.ActionChangeAvailability,
+			target: core.CommandTarget{ChargerID: "CP-ONLINE", Found: true, Online: true, Supported: true},
+		},
+		{
+			path:   "/api/v1/chargepoints/CP-OFFLINE/availability?validate=true",
+			body:   `{"connector_id": 1, "type": "Operative"}`,
+			action: ocpp.ActionChangeAvailability,
+			target: core.CommandTarget{ChargerID: "CP-OFFLINE", Found: true, Supported: true},
+		},
+		{
+			path:   "/api/v1/chargepoints/CP-MISSING/availability?validate=true",
+			body:   `{"connector_id": 1, "type": "Operative"}`,
+			action: ocpp.ActionChangeAvailability,
+			target: core.CommandTarget{ChargerID: "CP-MISSING"},
+		},
+		{
+			path:   "/api/v1/chargepoints/CP-ONLINE/local-list?validate=true",
+			action: ocpp.ActionSendLocalList,
+			target: core.CommandTarget{ChargerID: "CP-ONLINE", Found: true, Online: true, Supported: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := postCommand(srv, tt.path, tt.body)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var resp validateResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.True(t, resp.Validate)
+			assert.Equal(t, tt.action, resp.Action)
+			assert.Equal(t, []core.CommandTarget{tt.target}, resp.Targets)
+		})
+	}
+
+	assert.Empty(t, sender.actions)
+}
+
+func TestValidateCommandChecksBody(t *testing.T) {
+	srv, sender := newCommandTestServer(t)
+
+	w := postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/availability?validate=true", `{"connector_id": 1, "type": "Off"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/availability?validate=maybe", `{"connector_id": 1, "type": "Operative"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Empty(t, sender.actions)
+}
+
+func TestChangeAvailabilitySendsWithoutValidate(t *testing.T) {
+	srv, sender := newCommandTestServer(t)
+
+	w := postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/availability", `{"connector_id": 0, "type": "Inoperative"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"status": "Accepted"}`, w.Body.String())
+	assert.Equal(t, []string{ocpp.ActionChangeAvailability}, sender.actions)
+}
+
+func TestCommandHistoryEndpoint(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+
+	w := postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/availability?validate=true", `{"connector_id": 1, "type": "Operative"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/availability", `{"connector_id": 1, "type": "Inoperative"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chargepoints/CP-ONLINE/commands/history", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Commands []db.CommandHistoryEntry `json:"commands"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	// Validated commands are never sent, so only the real one is recorded
+	require.Len(t, resp.Commands, 1)
+	entry := resp.Commands[0]
+	assert.Equal(t, ocpp.ActionChangeAvailability, entry.Action)
+	assert.Equal(t, ocpp.AvailabilityStatusAccepted, entry.Status)
+	assert.Equal(t, actorForKey(RoleOperator, testOperatorKey), entry.Operator)
+	assert.JSONEq(t, `{"connectorId": 1, "type": "Inoperative"}`, string(entry.Params))
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chargepoints/CP-MISSING/commands/history", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// configurationSender answers every GetConfiguration with the requested keys
+// set to "1", at most one key per call
+type configurationSender struct{}
+
+func (configurationSender) SendCall(ctx context.Context, chargerID, action string, request, response interface{}) error {
+	resp := response.(*ocpp.GetConfigurationResponse)
+	for _, key := range request.(ocpp.GetConfigurationRequest).Key[:1] {
+		value := "1"
+		resp.ConfigurationKey = append(resp.ConfigurationKey, ocpp.KeyValue{Key: key, Value: &value})
+	}
+	return nil
+}
+
+func TestGetConfigurationEndpoint(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	srv.coreSystem.SetCommandSender(configurationSender{})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(apiKeyHeader, testOperatorKey)
+		srv.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/chargepoints/CP-ONLINE/configuration?key=HeartbeatInterval&key=NumberOfConnectors")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{
+		"keys": [
+			{"key": "HeartbeatInterval", "readonly": false, "value": "1"},
+			{"key": "NumberOfConnectors", "readonly": false, "value": "1"}
+		],
+		"unknown_keys": [],
+		"missing_keys": [],
+		"calls": 2
+	}`, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/chargepoints/CP-MISSING/configuration?key=HeartbeatInterval").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/chargepoints/CP-ONLINE/configuration?key=").Code)
+}
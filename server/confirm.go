@@ -0,0 +1,177 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/core/clock"
+)
+
+// Destructive admin operations that can be configured to need a confirmation
+// token, named as in auth.confirm_operations
+const (
+	operationReconcileFix     = "reconcile_fix"
+	operationResetConnections = "reset_connections"
+)
+
+// confirmTokenHeader carries the confirmation token of a destructive request
+const confirmTokenHeader = "X-Confirm-Token"
+
+// defaultConfirmTokenTTL applies when auth.confirm_token_ttl is not set
+const defaultConfirmTokenTTL = 2 * time.Minute
+
+// confirmation is an issued token for one operation
+type confirmation struct {
+	Operation string    `json:"operation"`
+	Token     string    `json:"token"`
+	Impact    string    `json:"impact"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// confirmationStore issues single-use confirmation tokens and validates them
+// until they expire. Tokens live in memory, so a restart invalidates them.
+type confirmationStore struct {
+	clock clock.Clock
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]confirmation
+}
+
+// newConfirmationStore creates a store whose tokens are valid for ttl
+func newConfirmationStore(clk clock.Clock, ttl time.Duration) *confirmationStore {
+	if ttl <= 0 {
+		ttl = defaultConfirmTokenTTL
+	}
+	return &confirmationStore{
+		clock:  clk,
+		ttl:    ttl,
+		tokens: make(map[string]confirmation),
+	}
+}
+
+// Issue creates a token confirming operation, dropping any that have expired
+func (s *confirmationStore) Issue(operation, impact string) confirmation {
+	now := s.clock.Now()
+	issued := confirmation{
+		Operation: operation,
+		Token:     newRequestID(),
+		Impact:    impact,
+		ExpiresAt: now.Add(s.ttl).UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, c := range s.tokens {
+		if !now.Before(c.ExpiresAt) {
+			delete(s.tokens, token)
+		}
+	}
+	s.tokens[issued.Token] = issued
+	return issued
+}
+
+// Consume reports whether token confirms operation and has not expired. A
+// token is used up by the first attempt to consume it, valid or not.
+func (s *confirmationStore) Consume(operation, token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issued, ok := s.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(s.tokens, token)
+	return issued.Operation == operation && s.clock.Now().Before(issued.ExpiresAt)
+}
+
+// requiresConfirmation reports whether operation is listed in auth.confirm_operations
+func (s *Server) requiresConfirmation(operation string) bool {
+	for _, configured := range s.config.Auth.ConfirmOperations {
+		if configured == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmed checks the confirmation token of a destructive request, writing
+// the error response and returning false when it is missing or invalid
+func (s *Server) confirmed(c *gin.Context, operation string) bool {
+	if !s.requiresConfirmation(operation) {
+		return true
+	}
+
+	token := c.GetHeader(confirmTokenHeader)
+	if token == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"error":       "Confirmation token required",
+			"confirm_url": "/admin/confirm/" + operation,
+		})
+		return false
+	}
+	if !s.confirmations.Consume(operation, token) {
+		s.logger.Warn("Rejected confirmation token",
+			slog.String("operation", operation),
+			slog.String("request_id", requestID(c)))
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired confirmation token"})
+		return false
+	}
+	return true
+}
+
+// issueConfirmation returns a short-lived token for a destructive operation,
+// describing what the operation would change if run now
+func (s *Server) issueConfirmation(c *gin.Context) {
+	operation := c.Param("operation")
+
+	var impact string
+	switch operation {
+	case operationReconcileFix:
+		report, err := s.coreSystem.CheckConsistency(c.Request.Context(), false)
+		if err != nil {
+			s.logger.Error("Consistency check failed", slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Consistency check failed"})
+			return
+		}
+		impact = fmt.Sprintf("Repairs %d connectors charging without a transaction, %d transactions not charging and %d chargers connected without a socket",
+			len(report.ChargingWithoutTransaction), len(report.TransactionsNotCharging), len(report.ConnectedWithoutSocket))
+	case operationResetConnections:
+		impact = fmt.Sprintf("Closes %d charger connections", s.registry.Count())
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown operation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s.confirmations.Issue(operation, impact))
+}
+
+// resetConnections closes every live charger connection, forcing chargers to
+// reconnect
+func (s *Server) resetConnections(c *gin.Context) {
+	if !s.confirmed(c, operationResetConnections) {
+		return
+	}
+
+	closed := 0
+	for _, chargerID := range s.registry.List() {
+		conn, ok := s.registry.Get(chargerID)
+		if !ok {
+			continue
+		}
+		if err := conn.Close(); err != nil {
+			s.logger.Warn("Failed to close charger connection",
+				slog.String("charger_id", chargerID),
+				slog.Any("error", err))
+			continue
+		}
+		closed++
+	}
+
+	s.logger.Warn("Reset charger connections", slog.Int("closed", closed))
+	c.JSON(http.StatusOK, gin.H{"closed": closed})
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminKey = "admin-key"
+
+// closableConn is a registered connection that records being closed
+type closableConn struct {
+	chargerID string
+	closed    bool
+}
+
+func (c *closableConn) ChargerID() string      { return c.chargerID }
+func (c *closableConn) ConnectedAt() time.Time { return time.Time{} }
+func (c *closableConn) Close() error {
+	c.closed = true
+	return nil
+}
+func (c *closableConn) SendCall(ctx context.Context, action string, request, response interface{}) error {
+	return nil
+}
+
+func newConfirmTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Auth.AdminKeys = []string{testAdminKey}
+	cfg.Auth.ConfirmOperations = []string{operationReconcileFix, operationResetConnections}
+	cfg.Database = config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "levity.db"), MaxOpenConns: 5, MaxIdleConns: 5, AutoMigrate: true}
+
+	system, err := core.NewSystem(cfg, dbtest.Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { system.GetDatabase().Close() })
+
+	return NewServer(cfg, system, nil, dbtest.Logger())
+}
+
+func adminRequest(srv *Server, method, path, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(apiKeyHeader, testAdminKey)
+	if token != "" {
+		req.Header.Set(confirmTokenHeader, token)
+	}
+	srv.router.ServeHTTP(w, req)
+	return w
+}
+
+func issueTestConfirmation(t *testing.T, srv *Server, operation string) confirmation {
+	t.Helper()
+
+	w := adminRequest(srv, http.MethodGet, "/admin/confirm/"+operation, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var issued confirmation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.Equal(t, operation, issued.Operation)
+	assert.NotEmpty(t, issued.Token)
+	return issued
+}
+
+func TestResetConnectionsRequiresConfirmation(t *testing.T) {
+	srv := newConfirmTestServer(t)
+	conns := []*closableConn{{chargerID: "CP-1"}, {chargerID: "CP-2"}}
+	for _, conn := range conns {
+		srv.registry.Add(conn)
+	}
+
+	w := adminRequest(srv, http.MethodPost, "/admin/connections/reset", "")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	assert.JSONEq(t, `{"error": "Confirmation token required", "confirm_url": "/admin/confirm/reset_connections"}`, w.Body.String())
+
+	issued := issueTestConfirmation(t, srv, operationResetConnections)
+	assert.Equal(t, "Closes 2 charger connections", issued.Impact)
+
+	w = adminRequest(srv, http.MethodPost, "/admin/connections/reset", issued.Token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"closed": 2}`, w.Body.String())
+	for _, conn := range conns {
+		assert.True(t, conn.closed)
+	}
+
+	// Tokens are single use
+	w = adminRequest(srv, http.MethodPost, "/admin/connections/reset", issued.Token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestConfirmationTokenIsBoundToOperation(t *testing.T) {
+	srv := newConfirmTestServer(t)
+
+	issued := issueTestConfirmation(t, srv, operationResetConnections)
+	w := adminRequest(srv, http.MethodPost, "/admin/reconcile?fix=true", issued.Token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = adminRequest(srv, http.MethodPost, "/admin/reconcile?fix=true", "not-a-token")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// A dry run changes nothing and needs no token
+	w = adminRequest(srv, http.MethodPost, "/admin/reconcile", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	issued = issueTestConfirmation(t, srv, operationReconcileFix)
+	w = adminRequest(srv, http.MethodPost, "/admin/reconcile?fix=true", issued.Token)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = adminRequest(srv, http.MethodGet, "/admin/confirm/drop_everything", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestConfirmationTokenExpires(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	store := newConfirmationStore(fake, time.Minute)
+
+	issued := store.Issue(operationResetConnections, "Closes 1 charger connections")
+	assert.Equal(t, fake.Now().Add(time.Minute), issued.ExpiresAt)
+
+	fake.Advance(time.Minute)
+	assert.False(t, store.Consume(operationResetConnections, issued.Token))
+
+	issued = store.Issue(operationResetConnections, "Closes 1 charger connections")
+	fake.Advance(59 * time.Second)
+	assert.True(t, store.Consume(operationResetConnections, issued.Token))
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/config"
+)
+
+// ConnectionAuthorizer decides whether a charger may open an OCPP connection.
+// It is called during the WebSocket upgrade with the charge point id and the
+// upgrade request headers. An error means no decision could be made.
+type ConnectionAuthorizer interface {
+	Authorize(ctx context.Context, chargerID string, header http.Header) (bool, error)
+}
+
+// AllowAllAuthorizer lets every charger connect
+type AllowAllAuthorizer struct{}
+
+// Authorize implements ConnectionAuthorizer.Authorize
+func (AllowAllAuthorizer) Authorize(ctx context.Context, chargerID string, header http.Header) (bool, error) {
+	return true, nil
+}
+
+// defaultConnectionAuthTimeout applies when ocpp.connection_auth_timeout is not set
+const defaultConnectionAuthTimeout = 5 * time.Second
+
+// ConnectionAuthRequest is the JSON body POSTed to the connection auth URL
+type ConnectionAuthRequest struct {
+	ChargerID string      `json:"charger_id"`
+	Headers   http.Header `json:"headers"`
+}
+
+// WebhookAuthorizer asks an external service whether a charger may connect. A
+// 2xx response allows the connection and 401, 403 or 404 denies it; any other
+// response is an error.
+type WebhookAuthorizer struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookAuthorizer creates an authorizer that POSTs to url
+func NewWebhookAuthorizer(url string, timeout time.Duration) *WebhookAuthorizer {
+	if timeout <= 0 {
+		timeout = defaultConnectionAuthTimeout
+	}
+	return &WebhookAuthorizer{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Authorize implements ConnectionAuthorizer.Authorize
+func (a *WebhookAuthorizer) Authorize(ctx context.Context, chargerID string, header http.Header) (bool, error) {
+	body, err := json.Marshal(ConnectionAuthRequest{ChargerID: chargerID, Headers: header})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal connection auth request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create connection auth request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("connection auth request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return true, nil
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("connection auth returned status %d", resp.StatusCode)
+	}
+}
+
+// newConnectionAuthorizer returns the webhook authorizer when a URL is
+// configured and allows every charger otherwise
+func newConnectionAuthorizer(cfg config.OCPPConfig) ConnectionAuthorizer {
+	if cfg.ConnectionAuthURL == "" {
+		return AllowAllAuthorizer{}
+	}
+	return NewWebhookAuthorizer(cfg.ConnectionAuthURL, cfg.ConnectionAuthTimeout)
+}
+
+// SetConnectionAuthorizer replaces the authorizer consulted before each
+// charger connection
+func (s *Server) SetConnectionAuthorizer(authorizer ConnectionAuthorizer) {
+	s.authorizer = authorizer
+}
+
+// authorizeConnection asks the authorizer whether a charger may connect,
+// responding 403 when it is denied and 503 when no decision could be made
+func (s *Server) authorizeConnection(c *gin.Context, chargerID string) bool {
+	allowed, err := s.authorizer.Authorize(c.Request.Context(), chargerID, c.Request.Header)
+	if err != nil {
+		s.logger.Error("Connection authorization failed",
+			slog.String("charger_id", chargerID),
+			slog.Any("error", err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Connection authorization unavailable"})
+		return false
+	}
+	if !allowed {
+		s.logger.Warn("Rejected WebSocket from unauthorized charger",
+			slog.String("charger_id", chargerID),
+			slog.String("remote_addr", c.Request.RemoteAddr))
+		c.JSON(http.StatusForbidden, gin.H{"error": "Charge point not authorized"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// defaultDisconnectWindow is how far back disconnects are counted by default
+const defaultDisconnectWindow = 24 * time.Hour
+
+// actionDisconnect is the command history action of a forced disconnect
+const actionDisconnect = "Disconnect"
+
+// listConnections lists the connections of a charger, newest first, with the
+// number of disconnects since the since parameter (RFC 3339, default last 24h)
+func (s *Server) listConnections(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	opts, ok := queryListOptions(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+	since, err := queryTime(c, "since")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since parameter"})
+		return
+	}
+
+	start := s.coreSystem.GetClock().Now().Add(-defaultDisconnectWindow)
+	if since != nil {
+		start = *since
+	}
+
+	ctx := c.Request.Context()
+	connections := s.coreSystem.GetRepositories().Connections()
+
+	conns, err := connections.ListByCharger(ctx, chargerID, opts)
+	if err != nil {
+		s.logger.Error("Failed to list connections", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list connections"})
+		return
+	}
+	if conns == nil {
+		conns = []*db.ChargerConnection{}
+	}
+
+	disconnects, err := connections.CountDisconnects(ctx, chargerID, start)
+	if err != nil {
+		s.logger.Error("Failed to count disconnects", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list connections"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connections": conns,
+		"disconnects": disconnects,
+		"since":       start,
+		"limit":       opts.Limit,
+		"offset":      opts.Offset,
+	})
+}
+
+// disconnectChargePoint closes the live connection of a charger with a normal
+// closure and marks it disconnected, recording the action in command history
+func (s *Server) disconnectChargePoint(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	repos := s.coreSystem.GetRepositories()
+	if _, err := repos.Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	conn, ok := s.registry.Get(chargerID)
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+		return
+	}
+
+	clk := s.coreSystem.GetClock()
+	start := clk.Now()
+	closeErr := conn.Close()
+
+	entry := db.CreateCommandHistoryRequest{
+		ChargerID: chargerID,
+		Action:    actionDisconnect,
+		Status:    db.CommandStatusCompleted,
+		Latency:   clk.Now().Sub(start),
+	}
+	if closeErr != nil {
+		entry.Status = db.CommandStatusError
+		entry.Error = closeErr.Error()
+	}
+	if _, err := repos.Commands().Record(ctx, entry); err != nil {
+		s.logger.Error("Failed to record command history",
+			slog.String("charger_id", chargerID),
+			slog.String("action", actionDisconnect),
+			slog.Any("error", err))
+	}
+
+	if closeErr != nil {
+		s.logger.Error("Failed to disconnect charge point", slog.String("charger_id", chargerID), slog.Any("error", closeErr))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to disconnect charge point"})
+		return
+	}
+
+	if err := repos.Chargers().UpdateConnectionStatus(ctx, chargerID, false); err != nil {
+		s.logger.Error("Failed to mark charge point disconnected", slog.String("charger_id", chargerID), slog.Any("error", err))
+	}
+
+	s.logger.Warn("Disconnected charge point", slog.String("charger_id", chargerID))
+	c.JSON(http.StatusOK, gin.H{"status": "Disconnected"})
+}
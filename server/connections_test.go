@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/keeth/levity/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisconnectChargePoint(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ctx := context.Background()
+	repos := srv.coreSystem.GetRepositories()
+	require.NoError(t, repos.Chargers().UpdateConnectionStatus(ctx, "CP-ONLINE", true))
+
+	conn := &closableConn{chargerID: "CP-ONLINE"}
+	srv.registry.Add(conn)
+
+	w := postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/disconnect", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"status": "Disconnected"}`, w.Body.String())
+	assert.True(t, conn.closed)
+
+	charger, err := repos.Chargers().GetByID(ctx, "CP-ONLINE")
+	require.NoError(t, err)
+	assert.False(t, charger.IsConnected)
+
+	history, err := repos.Commands().ListByCharger(ctx, "CP-ONLINE", db.DefaultListOptions())
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, actionDisconnect, history[0].Action)
+	assert.Equal(t, db.CommandStatusCompleted, history[0].Status)
+}
+
+func TestDisconnectChargePointNotConnected(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+
+	w := postCommand(srv, "/api/v1/chargepoints/CP-OFFLINE/disconnect", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = postCommand(srv, "/api/v1/chargepoints/CP-UNKNOWN/disconnect", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	history, err := srv.coreSystem.GetRepositories().Commands().ListByCharger(context.Background(), "CP-OFFLINE", db.DefaultListOptions())
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
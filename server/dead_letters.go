@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/db"
+)
+
+// queryDeadLetterFilter parses the dead letter filter parameters, responding
+// 400 and returning false when one is invalid
+func queryDeadLetterFilter(c *gin.Context) (db.DeadLetterFilter, bool) {
+	filter := db.DeadLetterFilter{
+		ChargerID: c.Query("charger_id"),
+		Action:    c.Query("action"),
+	}
+
+	if raw := c.Query("resolved"); raw != "" {
+		resolved, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolved parameter"})
+			return filter, false
+		}
+		filter.Resolved = &resolved
+	}
+
+	return filter, true
+}
+
+// listDeadLetters lists charger calls whose handler failed, filtered by
+// charger_id, action and resolved
+func (s *Server) listDeadLetters(c *gin.Context) {
+	filter, ok := queryDeadLetterFilter(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := queryListOptions(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	letters, err := s.coreSystem.GetRepositories().DeadLetters().List(c.Request.Context(), filter, opts)
+	if err != nil {
+		s.logger.Error("Failed to list dead letters", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list dead letters"})
+		return
+	}
+	if letters == nil {
+		letters = []*db.DeadLetter{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": letters,
+		"limit":        opts.Limit,
+		"offset":       opts.Offset,
+	})
+}
+
+// replayDeadLetter runs a dead letter through the current handlers, marking it
+// resolved on success. A replay that fails again answers 422 with its error.
+func (s *Server) replayDeadLetter(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dead letter ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	letter, err := s.coreSystem.GetRepositories().DeadLetters().GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrDeadLetterNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+			return
+		}
+		s.logger.Error("Failed to get dead letter", slog.Int("id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get dead letter"})
+		return
+	}
+
+	replay, err := s.coreSystem.DeadLetters().Replay(ctx, letter)
+	if err != nil {
+		if errors.Is(err, core.ErrDeadLetterResolved) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Dead letter is already resolved"})
+			return
+		}
+		s.logger.Error("Failed to replay dead letter", slog.Int("id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay dead letter"})
+		return
+	}
+
+	if !replay.Resolved {
+		c.JSON(http.StatusUnprocessableEntity, replay)
+		return
+	}
+	c.JSON(http.StatusOK, replay)
+}
+
+// replayDeadLetters replays every unresolved dead letter matching charger_id
+// and action, oldest first
+func (s *Server) replayDeadLetters(c *gin.Context) {
+	filter, ok := queryDeadLetterFilter(c)
+	if !ok {
+		return
+	}
+
+	result, err := s.coreSystem.DeadLetters().ReplayAll(c.Request.Context(), filter)
+	if err != nil {
+		s.logger.Error("Bulk dead letter replay failed",
+			slog.Int("replayed", result.Replayed),
+			slog.Int("resolved", result.Resolved),
+			slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay dead letters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeDeadLetter dead-letters a BootNotification from chargerID
+func storeDeadLetter(t *testing.T, srv *Server, chargerID, uniqueID string) *db.DeadLetter {
+	t.Helper()
+
+	letter, err := srv.coreSystem.GetRepositories().DeadLetters().Create(context.Background(), db.CreateDeadLetterRequest{
+		ChargerID: chargerID,
+		Action:    "BootNotification",
+		UniqueID:  uniqueID,
+		Frame:     `[2,"` + uniqueID + `","BootNotification",{"chargePointVendor":"Acme","chargePointModel":"X1"}]`,
+		Error:     "failed to create charger: database is locked",
+	})
+	require.NoError(t, err)
+	return letter
+}
+
+func TestReplayDeadLetterEndpoint(t *testing.T) {
+	srv := newConfirmTestServer(t)
+	letter := storeDeadLetter(t, srv, "CP-NEW", "boot-1")
+	path := "/admin/dead-letters/" + strconv.Itoa(letter.ID) + "/replay"
+
+	w := adminRequest(srv, http.MethodPost, path, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"id": `+strconv.Itoa(letter.ID)+`, "resolved": true}`, w.Body.String())
+
+	charger, err := srv.coreSystem.GetRepositories().Chargers().GetByID(context.Background(), "CP-NEW")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", charger.Vendor)
+
+	w = adminRequest(srv, http.MethodGet, "/admin/dead-letters?resolved=true", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var listed struct {
+		DeadLetters []db.DeadLetter `json:"dead_letters"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed.DeadLetters, 1)
+	assert.NotNil(t, listed.DeadLetters[0].ResolvedAt)
+
+	w = adminRequest(srv, http.MethodPost, path, "")
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	w = adminRequest(srv, http.MethodPost, "/admin/dead-letters/9999/replay", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
+
+func TestReplayDeadLettersByFilter(t *testing.T) {
+	srv := newConfirmTestServer(t)
+	storeDeadLetter(t, srv, "CP-1", "boot-1")
+	storeDeadLetter(t, srv, "CP-1", "boot-2")
+	other := storeDeadLetter(t, srv, "CP-2", "boot-3")
+
+	w := adminRequest(srv, http.MethodPost, "/admin/dead-letters/replay?charger_id=CP-1", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result core.DeadLetterReplayResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Replayed)
+	assert.Equal(t, 2, result.Resolved)
+	assert.Empty(t, result.Failed)
+
+	letter, err := srv.coreSystem.GetRepositories().DeadLetters().GetByID(context.Background(), other.ID)
+	require.NoError(t, err)
+	assert.Nil(t, letter.ResolvedAt)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// listErrors lists charger errors filtered by charger_id, error_code,
+// resolved (true, false or all) and an RFC 3339 since/until range
+func (s *Server) listErrors(c *gin.Context) {
+	filter := db.ErrorFilter{
+		ChargerID: c.Query("charger_id"),
+		ErrorCode: c.Query("error_code"),
+	}
+
+	if raw := c.Query("resolved"); raw != "" && raw != "all" {
+		resolved, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolved parameter"})
+			return
+		}
+		filter.Resolved = &resolved
+	}
+
+	var err error
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since parameter"})
+		return
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until parameter"})
+		return
+	}
+
+	opts, ok := queryListOptions(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	chargerErrors, err := s.coreSystem.GetRepositories().Errors().List(c.Request.Context(), filter, opts)
+	if err != nil {
+		s.logger.Error("Failed to list errors", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list errors"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"errors": newChargerErrorResponses(chargerErrors),
+		"limit":  opts.Limit,
+		"offset": opts.Offset,
+	})
+}
+
+// resolveErrors resolves the active errors matching a charger_id, error_code
+// and list of ids in the body. At least one must be given so a bare request
+// cannot resolve every error in the fleet.
+func (s *Server) resolveErrors(c *gin.Context) {
+	var filter db.ResolveErrorsFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "charger_id, error_code or ids is required"})
+		return
+	}
+
+	resolvedAt := s.coreSystem.GetClock().Now().UTC()
+	resolved, err := s.coreSystem.GetRepositories().Errors().ResolveMatching(c.Request.Context(), filter, resolvedAt)
+	if err != nil {
+		s.logger.Error("Failed to resolve errors", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve errors"})
+		return
+	}
+
+	s.logger.Info("Resolved errors",
+		slog.String("charger_id", filter.ChargerID),
+		slog.String("error_code", filter.ErrorCode),
+		slog.Int("ids", len(filter.IDs)),
+		slog.Int("resolved", resolved))
+	c.JSON(http.StatusOK, gin.H{"resolved": resolved})
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/keeth/levity/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedErrors reports errors on both test chargers and returns their ids
+func seedErrors(t *testing.T, srv *Server) map[string][]int {
+	t.Helper()
+
+	ctx := context.Background()
+	ids := make(map[string][]int)
+	for _, chargerID := range []string{"CP-ONLINE", "CP-OFFLINE"} {
+		for _, code := range []string{"GroundFailure", "OverVoltage"} {
+			cerr, err := srv.coreSystem.GetRepositories().Errors().Create(ctx, db.CreateChargerErrorRequest{
+				ChargerID: chargerID, ErrorCode: code,
+			})
+			require.NoError(t, err)
+			ids[code] = append(ids[code], cerr.ID)
+		}
+	}
+	return ids
+}
+
+func activeErrorCodes(t *testing.T, srv *Server) []string {
+	t.Helper()
+
+	active, err := srv.coreSystem.GetRepositories().Errors().GetActive(context.Background())
+	require.NoError(t, err)
+	codes := make([]string, 0, len(active))
+	for _, cerr := range active {
+		codes = append(codes, cerr.ChargerID+"/"+cerr.ErrorCode)
+	}
+	return codes
+}
+
+func TestResolveErrorsByCode(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	seedErrors(t, srv)
+
+	w := postCommand(srv, "/api/v1/errors/resolve", `{"error_code": "GroundFailure"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"resolved": 2}`, w.Body.String())
+	assert.ElementsMatch(t, []string{"CP-ONLINE/OverVoltage", "CP-OFFLINE/OverVoltage"}, activeErrorCodes(t, srv))
+
+	// Already resolved errors are not counted again
+	w = postCommand(srv, "/api/v1/errors/resolve", `{"error_code": "GroundFailure"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"resolved": 0}`, w.Body.String())
+
+	w = postCommand(srv, "/api/v1/errors/resolve", `{"charger_id": "CP-ONLINE", "error_code": "OverVoltage"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"resolved": 1}`, w.Body.String())
+	assert.Equal(t, []string{"CP-OFFLINE/OverVoltage"}, activeErrorCodes(t, srv))
+}
+
+func TestResolveErrorsByIDs(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ids := seedErrors(t, srv)
+
+	body := `{"ids": [` + strconv.Itoa(ids["OverVoltage"][0]) + `, ` + strconv.Itoa(ids["GroundFailure"][1]) + `, 9999]}`
+	w := postCommand(srv, "/api/v1/errors/resolve", body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"resolved": 2}`, w.Body.String())
+	assert.ElementsMatch(t, []string{"CP-ONLINE/GroundFailure", "CP-OFFLINE/OverVoltage"}, activeErrorCodes(t, srv))
+}
+
+func TestResolveErrorsRequiresFilter(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	seedErrors(t, srv)
+
+	for _, body := range []string{`{}`, `{"ids": []}`, `not json`} {
+		w := postCommand(srv, "/api/v1/errors/resolve", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Len(t, activeErrorCodes(t, srv), 4)
+}
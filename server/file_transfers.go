@@ -0,0 +1,190 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// signedUpdateFirmwareRequest is the body of a signed firmware update. An
+// omitted retrieve time asks the charger to download the firmware now.
+type signedUpdateFirmwareRequest struct {
+	Location           string     `json:"location"`
+	RetrieveDateTime   *time.Time `json:"retrieve_date_time"`
+	InstallDateTime    *time.Time `json:"install_date_time"`
+	SigningCertificate string     `json:"signing_certificate"`
+	Signature          string     `json:"signature"`
+	Retries            *int       `json:"retries"`
+	RetryInterval      *int       `json:"retry_interval"`
+}
+
+// getLogRequest is the body of a log upload request
+type getLogRequest struct {
+	LogType         string     `json:"log_type"`
+	RemoteLocation  string     `json:"remote_location"`
+	OldestTimestamp *time.Time `json:"oldest_timestamp"`
+	LatestTimestamp *time.Time `json:"latest_timestamp"`
+	Retries         *int       `json:"retries"`
+	RetryInterval   *int       `json:"retry_interval"`
+}
+
+// signedUpdateFirmware asks a charger to install signed firmware
+func (s *Server) signedUpdateFirmware(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	var req signedUpdateFirmwareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	firmware := ocpp.Firmware{
+		Location:           req.Location,
+		RetrieveDateTime:   s.coreSystem.GetClock().Now().UTC(),
+		InstallDateTime:    req.InstallDateTime,
+		SigningCertificate: req.SigningCertificate,
+		Signature:          req.Signature,
+	}
+	if req.RetrieveDateTime != nil {
+		firmware.RetrieveDateTime = req.RetrieveDateTime.UTC()
+	}
+	if err := core.ValidateFirmware(firmware); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if validate {
+		s.validateCommand(c, ocpp.ActionSignedUpdateFirmware, chargerID)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	transfer, err := s.coreSystem.FileTransfers().SignedUpdateFirmware(ctx, chargerID, firmware, req.Retries, req.RetryInterval)
+	if err != nil {
+		s.fileTransferError(c, chargerID, "Failed to update firmware", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// getLog asks a charger to upload a diagnostics or security log
+func (s *Server) getLog(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	var req getLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	log := ocpp.LogParameters{
+		RemoteLocation:  req.RemoteLocation,
+		OldestTimestamp: req.OldestTimestamp,
+		LatestTimestamp: req.LatestTimestamp,
+	}
+	if err := core.ValidateLogRequest(req.LogType, log); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if validate {
+		s.validateCommand(c, ocpp.ActionGetLog, chargerID)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	transfer, err := s.coreSystem.FileTransfers().GetLog(ctx, chargerID, req.LogType, log, req.Retries, req.RetryInterval)
+	if err != nil {
+		s.fileTransferError(c, chargerID, "Failed to request log", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// fileTransferError responds to a firmware update or log request that could
+// not be sent
+func (s *Server) fileTransferError(c *gin.Context, chargerID, message string, err error) {
+	if errors.Is(err, ocpp.ErrNotConnected) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+		return
+	}
+	s.logger.Error(message, slog.String("charger_id", chargerID), slog.Any("error", err))
+	c.JSON(http.StatusBadGateway, gin.H{"error": message})
+}
+
+// listFileTransfers lists the firmware updates and log uploads requested from
+// a charger, newest first
+func (s *Server) listFileTransfers(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	opts, ok := queryListOptions(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	repos := s.coreSystem.GetRepositories()
+	if _, err := repos.Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	transfers, err := repos.FileTransfers().ListByCharger(ctx, chargerID, opts)
+	if err != nil {
+		s.logger.Error("Failed to list file transfers", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list file transfers"})
+		return
+	}
+	if transfers == nil {
+		transfers = []*db.FileTransfer{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"file_transfers": transfers,
+		"limit":          opts.Limit,
+		"offset":         opts.Offset,
+	})
+}
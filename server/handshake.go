@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/keeth/levity/monitoring"
+)
+
+// handshakeConnKey is the context key holding the handshakeConn of a request
+type handshakeConnKey struct{}
+
+// handshakeListener wraps accepted connections so that connections closed by
+// the header read timeout before sending a complete request are counted
+type handshakeListener struct {
+	net.Listener
+	metrics *monitoring.Metrics
+}
+
+// Accept implements net.Listener.Accept
+func (l *handshakeListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &handshakeConn{Conn: conn, metrics: l.metrics}, nil
+}
+
+// handshakeConn records a handshake timeout when a read times out before the
+// connection has delivered its first request
+type handshakeConn struct {
+	net.Conn
+	metrics  *monitoring.Metrics
+	served   atomic.Bool
+	recorded atomic.Bool
+}
+
+// Read implements net.Conn.Read
+func (c *handshakeConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+
+	var netErr net.Error
+	if err != nil && errors.As(err, &netErr) && netErr.Timeout() &&
+		!c.served.Load() && c.recorded.CompareAndSwap(false, true) && c.metrics != nil {
+		c.metrics.RecordHandshakeTimeout()
+	}
+	return n, err
+}
+
+// handshakeConnContext stores the connection in the context of its requests
+func handshakeConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if hc, ok := conn.(*handshakeConn); ok {
+		return context.WithValue(ctx, handshakeConnKey{}, hc)
+	}
+	return ctx
+}
+
+// markServed wraps a handler to flag connections that completed a request
+func markServed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hc, ok := r.Context().Value(handshakeConnKey{}).(*handshakeConn); ok {
+			hc.served.Store(true)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
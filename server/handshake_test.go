@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshakeTimeoutClosesStalledConnection(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OCPP.HandshakeTimeout = 50 * time.Millisecond
+	metrics := monitoring.NewMetrics()
+	srv := NewServer(cfg, nil, metrics, dbtest.Logger())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(listener)
+	defer srv.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Start the upgrade request but never finish the headers
+	_, err = conn.Write([]byte("GET /ocpp/CP-1 HTTP/1.1\r\nHost: levity\r\nUpgrade: websocket\r\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err, "the server closes the connection rather than the client timing out")
+
+	expected := `
+		# HELP ocpp_handshake_timeouts_total Total number of connections closed for not completing the handshake in time
+		# TYPE ocpp_handshake_timeouts_total counter
+		ocpp_handshake_timeouts_total 1
+	`
+	assert.Eventually(t, func() bool {
+		return testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_handshake_timeouts_total") == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHandshakeTimeoutIgnoresCompletedRequests(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OCPP.HandshakeTimeout = 50 * time.Millisecond
+	metrics := monitoring.NewMetrics()
+	srv := NewServer(cfg, nil, metrics, dbtest.Logger())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(listener)
+	defer srv.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET /version HTTP/1.1\r\nHost: levity\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	response, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Contains(t, string(response), "200 OK")
+
+	expected := `
+		# HELP ocpp_handshake_timeouts_total Total number of connections closed for not completing the handshake in time
+		# TYPE ocpp_handshake_timeouts_total counter
+		ocpp_handshake_timeouts_total 0
+	`
+	err = testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_handshake_timeouts_total")
+	require.NoError(t, err)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ocppRoutePath is the route chargers connect to
+const ocppRoutePath = "/ocpp/:chargePointId"
+
+// Readiness statuses of a subsystem
+const (
+	subsystemOK       = "ok"
+	subsystemDegraded = "degraded"
+)
+
+// subsystemStatus is one entry of the readiness report
+type subsystemStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ocppStatus reports whether chargers can connect
+type ocppStatus struct {
+	subsystemStatus
+	Listening   bool `json:"listening"`
+	Connections int  `json:"connections"`
+}
+
+// readinessCheck reports whether the database and the OCPP WebSocket endpoint
+// are ready, responding 503 when either is degraded. The database is degraded
+// while a storage fault is outstanding even if it still answers queries.
+func (s *Server) readinessCheck(c *gin.Context) {
+	database := subsystemStatus{Status: subsystemOK}
+	if err := s.coreSystem.GetRepositories().HealthCheck(c.Request.Context()); err != nil {
+		database = subsystemStatus{Status: subsystemDegraded, Error: err.Error()}
+	} else if err := s.coreSystem.LastStorageFault(); err != nil {
+		database = subsystemStatus{Status: subsystemDegraded, Error: err.Error()}
+	}
+	ocpp := s.checkOCPP()
+
+	status, code := "ready", http.StatusOK
+	if database.Status != subsystemOK || ocpp.Status != subsystemOK {
+		status, code = "degraded", http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status":    status,
+		"timestamp": s.coreSystem.GetClock().Now().UTC(),
+		"subsystems": gin.H{
+			"database": database,
+			"ocpp":     ocpp,
+		},
+	})
+}
+
+// checkOCPP verifies that the WebSocket route, upgrader and connection
+// registry are set up. Whether the listener is bound is reported but does not
+// degrade readiness, since the probe itself arrives through the listener.
+func (s *Server) checkOCPP() ocppStatus {
+	status := ocppStatus{
+		subsystemStatus: subsystemStatus{Status: subsystemOK},
+		Listening:       s.listening.Load(),
+	}
+
+	switch {
+	case s.registry == nil:
+		status.subsystemStatus = subsystemStatus{Status: subsystemDegraded, Error: "connection registry not initialized"}
+	case s.upgrader.CheckOrigin == nil:
+		status.subsystemStatus = subsystemStatus{Status: subsystemDegraded, Error: "WebSocket upgrader not initialized"}
+	case !s.hasRoute(http.MethodGet, ocppRoutePath):
+		status.subsystemStatus = subsystemStatus{Status: subsystemDegraded, Error: "OCPP WebSocket route not registered"}
+	default:
+		status.Connections = s.registry.Count()
+	}
+
+	return status
+}
+
+// hasRoute reports whether the router serves method and path
+func (s *Server) hasRoute(method, path string) bool {
+	for _, route := range s.router.Routes() {
+		if route.Method == method && route.Path == path {
+			return true
+		}
+	}
+	return false
+}
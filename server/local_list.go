@@ -0,0 +1,76 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/core/ocpp"
+)
+
+// sendLocalListRequest is the body of a local list update. An omitted list on a
+// Full update sends every stored ID tag, and an omitted version uses the next one.
+type sendLocalListRequest struct {
+	ListVersion            int                      `json:"list_version"`
+	UpdateType             string                   `json:"update_type"`
+	LocalAuthorizationList []ocpp.AuthorizationData `json:"local_authorization_list"`
+}
+
+// sendLocalList pushes a local authorization list to a charger
+func (s *Server) sendLocalList(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	var req sendLocalListRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+	if req.UpdateType == "" {
+		req.UpdateType = ocpp.UpdateTypeFull
+	}
+
+	ctx := c.Request.Context()
+	if validate {
+		s.validateCommand(c, ocpp.ActionSendLocalList, chargerID)
+		return
+	}
+
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	resp, version, err := s.coreSystem.LocalLists().SendLocalList(ctx, chargerID, req.ListVersion, req.UpdateType, req.LocalAuthorizationList)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidLocalList) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ocpp.ErrNotConnected) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+			return
+		}
+		s.logger.Error("Failed to send local list", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send local list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       resp.Status,
+		"list_version": version,
+	})
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// Defaults and bounds for meter value series
+const (
+	defaultSeriesWindow = 24 * time.Hour
+	defaultSeriesBucket = time.Hour
+	maxSeriesBuckets    = 10000
+)
+
+// getMeterValueSeries returns avg/min/max of a measurand on a connector per
+// bucket (a duration such as 5m) between since and until (RFC 3339),
+// defaulting to hourly buckets of the energy register over the last 24 hours
+func (s *Server) getMeterValueSeries(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	connectorID, err := queryInt(c, "connector_id")
+	if err != nil || connectorID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connector_id parameter"})
+		return
+	}
+	until, err := queryTime(c, "until")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until parameter"})
+		return
+	}
+	since, err := queryTime(c, "since")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since parameter"})
+		return
+	}
+
+	bucket := defaultSeriesBucket
+	if raw := c.Query("bucket"); raw != "" {
+		bucket, err = time.ParseDuration(raw)
+		if err != nil || bucket < time.Second {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bucket parameter"})
+			return
+		}
+	}
+
+	measurand := c.Query("measurand")
+	if measurand == "" {
+		measurand = ocpp.DefaultMeasurand
+	}
+
+	end := s.coreSystem.GetClock().Now()
+	if until != nil {
+		end = *until
+	}
+	start := end.Add(-defaultSeriesWindow)
+	if since != nil {
+		start = *since
+	}
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be before until"})
+		return
+	}
+	if end.Sub(start)/bucket > maxSeriesBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many buckets, use a larger bucket or a shorter range"})
+		return
+	}
+
+	series, err := s.coreSystem.GetRepositories().MeterValues().GetSeries(c.Request.Context(), chargerID, connectorID, measurand, start, end, bucket)
+	if err != nil {
+		s.logger.Error("Failed to get meter value series", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get meter value series"})
+		return
+	}
+	if series == nil {
+		series = []db.SeriesPoint{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"charger_id":   chargerID,
+		"connector_id": connectorID,
+		"measurand":    measurand,
+		"since":        start,
+		"until":        end,
+		"bucket":       bucket.String(),
+		"points":       series,
+	})
+}
+
+// listMeasurands lists the distinct measurands recorded for a charger
+func (s *Server) listMeasurands(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	measurands, err := s.coreSystem.GetRepositories().MeterValues().DistinctMeasurands(c.Request.Context(), chargerID)
+	if err != nil {
+		s.logger.Error("Failed to list measurands", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list measurands"})
+		return
+	}
+	if measurands == nil {
+		measurands = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"charger_id": chargerID,
+		"measurands": measurands,
+	})
+}
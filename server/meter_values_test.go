@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMeterValueSeries(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, value := range []float64{1.0, 2.0, 4.0} {
+		_, err := srv.coreSystem.GetRepositories().MeterValues().Create(context.Background(), db.CreateMeterValueRequest{
+			ChargerID:   "CP-ONLINE",
+			ConnectorID: 1,
+			Timestamp:   start.Add(time.Duration(i) * 20 * time.Minute),
+			Measurand:   "Energy.Active.Import.Register",
+			Value:       dbtest.Float64(value),
+			Unit:        db.UnitKWh,
+		})
+		require.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/chargepoints/CP-ONLINE/meter-values/series?connector_id=1&bucket=30m&since=2024-05-01T12:00:00Z&until=2024-05-01T13:30:00Z", nil)
+	srv.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Measurand string           `json:"measurand"`
+		Bucket    string           `json:"bucket"`
+		Points    []db.SeriesPoint `json:"points"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Energy.Active.Import.Register", resp.Measurand)
+	assert.Equal(t, "30m0s", resp.Bucket)
+	require.Len(t, resp.Points, 3)
+	assert.Equal(t, 2, resp.Points[0].Count)
+	assert.Equal(t, 1500.0, *resp.Points[0].Avg)
+	assert.Equal(t, 1, resp.Points[1].Count)
+	assert.Equal(t, 4000.0, *resp.Points[1].Max)
+	assert.Zero(t, resp.Points[2].Count)
+	assert.Nil(t, resp.Points[2].Avg)
+
+	for _, query := range []string{"bucket=1ms", "bucket=soon", "since=2024-05-02T00:00:00Z&until=2024-05-01T00:00:00Z", "bucket=1s&since=2024-01-01T00:00:00Z"} {
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chargepoints/CP-ONLINE/meter-values/series?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestListMeasurands(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	readings := []struct {
+		chargerID string
+		measurand string
+	}{
+		{"CP-ONLINE", "Voltage"},
+		{"CP-ONLINE", "Energy.Active.Import.Register"},
+		{"CP-ONLINE", "Voltage"},
+		{"CP-ONLINE", "Current.Import"},
+		{"CP-OFFLINE", "Power.Active.Import"},
+	}
+	for _, r := range readings {
+		_, err := srv.coreSystem.GetRepositories().MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			ChargerID: r.chargerID, ConnectorID: 1, Timestamp: now, Measurand: r.measurand, Value: dbtest.Float64(1),
+		})
+		require.NoError(t, err)
+	}
+
+	for chargerID, want := range map[string][]string{
+		"CP-ONLINE":  {"Current.Import", "Energy.Active.Import.Register", "Voltage"},
+		"CP-OFFLINE": {"Power.Active.Import"},
+		"CP-NONE":    {},
+	} {
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chargepoints/"+chargerID+"/measurands", nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp struct {
+			Measurands []string `json:"measurands"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, want, resp.Measurands, chargerID)
+	}
+}
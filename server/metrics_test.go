@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsAuthGate(t *testing.T) {
+	tests := []struct {
+		name        string
+		requireAuth bool
+		adminKeys   []string
+		key         string
+		want        int
+	}{
+		{"open by default", false, []string{"admin-key"}, "", http.StatusOK},
+		{"required without keys stays open", true, nil, "", http.StatusOK},
+		{"required and missing key", true, []string{"admin-key"}, "", http.StatusUnauthorized},
+		{"required with admin key", true, []string{"admin-key"}, "admin-key", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Log:        config.LogConfig{Level: "info"},
+				Monitoring: config.MonitoringConfig{RequireAuth: tt.requireAuth},
+				Auth:       config.AuthConfig{AdminKeys: tt.adminKeys},
+			}
+			srv := NewServer(cfg, nil, monitoring.NewMetrics(), dbtest.Logger())
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.key != "" {
+				req.Header.Set(apiKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
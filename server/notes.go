@@ -0,0 +1,52 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// maxNotesLength bounds the size of operator notes on a charger
+const maxNotesLength = 4096
+
+// updateNotesRequest is the body of a charger notes update
+type updateNotesRequest struct {
+	Notes *string `json:"notes"`
+}
+
+// updateChargePointNotes replaces the operator notes on a charger
+func (s *Server) updateChargePointNotes(c *gin.Context) {
+	chargerID := c.Param("id")
+	if chargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Charge point ID is required"})
+		return
+	}
+
+	var req updateNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Notes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if len(*req.Notes) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Notes are too long"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	chargers := s.coreSystem.GetRepositories().Chargers()
+	if _, err := chargers.GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	charger, err := chargers.Update(ctx, chargerID, db.UpdateChargerRequest{Notes: req.Notes})
+	if err != nil {
+		s.logger.Error("Failed to update charger notes", slog.String("charger_id", chargerID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newChargerResponse(charger))
+}
@@ -0,0 +1,147 @@
+// Package ocppconn tracks the live OCPP WebSocket connections of chargers.
+package ocppconn
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/monitoring"
+)
+
+// Conn is a live OCPP connection to a single charger
+type Conn interface {
+	// ChargerID returns the identity the charger connected with
+	ChargerID() string
+
+	// ConnectedAt returns when the connection was established
+	ConnectedAt() time.Time
+
+	// SendCall sends a CALL and decodes the CALLRESULT payload into response
+	SendCall(ctx context.Context, action string, request interface{}, response interface{}) error
+
+	// Close terminates the connection
+	Close() error
+}
+
+// Registry maps charger IDs to their live connection, so handlers can find
+// the socket of a charger to push commands. Implementations must be safe for
+// concurrent use by the WebSocket upgrade/close paths and HTTP handlers; one
+// shared between Central System instances would forward calls for chargers
+// connected elsewhere.
+type Registry interface {
+	// Add registers a connection, returning the connection it replaced if the
+	// charger was already connected. The caller is responsible for closing it.
+	Add(conn Conn) Conn
+
+	// Remove unregisters a connection. It is a no-op if the charger has since
+	// reconnected with a different connection, and reports whether it removed one.
+	Remove(conn Conn) bool
+
+	// Get returns the live connection of a charger
+	Get(chargerID string) (Conn, bool)
+
+	// IsConnected reports whether a charger has a live connection
+	IsConnected(chargerID string) bool
+
+	// List returns the IDs of all connected chargers in sorted order
+	List() []string
+
+	// Count returns the number of connected chargers
+	Count() int
+
+	// SendCall sends a CALL to a connected charger, failing with
+	// ocpp.ErrNotConnected when it is not
+	SendCall(ctx context.Context, chargerID string, action string, request interface{}, response interface{}) error
+}
+
+// MemoryRegistry is a Registry of the connections to this process
+type MemoryRegistry struct {
+	mu      sync.RWMutex
+	conns   map[string]Conn
+	metrics *monitoring.Metrics
+}
+
+// NewMemoryRegistry creates an empty in-memory registry. metrics may be nil;
+// otherwise ocpp_connections_active follows each charger's registration.
+func NewMemoryRegistry(metrics *monitoring.Metrics) *MemoryRegistry {
+	return &MemoryRegistry{
+		conns:   make(map[string]Conn),
+		metrics: metrics,
+	}
+}
+
+// Add implements Registry.Add
+func (r *MemoryRegistry) Add(conn Conn) Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.conns[conn.ChargerID()]
+	r.conns[conn.ChargerID()] = conn
+	if r.metrics != nil {
+		r.metrics.SetOCPPConnectionsActive(conn.ChargerID(), 1)
+	}
+	return previous
+}
+
+// Remove implements Registry.Remove
+func (r *MemoryRegistry) Remove(conn Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, ok := r.conns[conn.ChargerID()]; ok && current == conn {
+		delete(r.conns, conn.ChargerID())
+		if r.metrics != nil {
+			r.metrics.SetOCPPConnectionsActive(conn.ChargerID(), 0)
+		}
+		return true
+	}
+	return false
+}
+
+// Get implements Registry.Get
+func (r *MemoryRegistry) Get(chargerID string) (Conn, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conn, ok := r.conns[chargerID]
+	return conn, ok
+}
+
+// IsConnected implements Registry.IsConnected and core.ConnectionRegistry.IsConnected
+func (r *MemoryRegistry) IsConnected(chargerID string) bool {
+	_, ok := r.Get(chargerID)
+	return ok
+}
+
+// List implements Registry.List
+func (r *MemoryRegistry) List() []string {
+	r.mu.RLock()
+	ids := make([]string, 0, len(r.conns))
+	for id := range r.conns {
+		ids = append(ids, id)
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(ids)
+	return ids
+}
+
+// Count implements Registry.Count
+func (r *MemoryRegistry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.conns)
+}
+
+// SendCall implements Registry.SendCall and ocpp.Sender.SendCall. The
+// registry lock is not held while the call is in flight.
+func (r *MemoryRegistry) SendCall(ctx context.Context, chargerID string, action string, request interface{}, response interface{}) error {
+	conn, ok := r.Get(chargerID)
+	if !ok {
+		return ocpp.ErrNotConnected
+	}
+	return conn.SendCall(ctx, action, request, response)
+}
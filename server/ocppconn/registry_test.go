@@ -0,0 +1,113 @@
+package ocppconn
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConn is a Conn that records calls without any network
+type fakeConn struct {
+	chargerID string
+	mu        sync.Mutex
+	calls     int
+}
+
+func (c *fakeConn) ChargerID() string      { return c.chargerID }
+func (c *fakeConn) ConnectedAt() time.Time { return time.Time{} }
+func (c *fakeConn) Close() error           { return nil }
+
+func (c *fakeConn) SendCall(ctx context.Context, action string, request, response interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	return nil
+}
+
+// assertConnectionsActive checks the ocpp_connections_active gauge of CP-1
+func assertConnectionsActive(t *testing.T, metrics *monitoring.Metrics, value int) {
+	t.Helper()
+
+	expected := fmt.Sprintf(`
+# HELP ocpp_connections_active Current number of active OCPP connections
+# TYPE ocpp_connections_active gauge
+ocpp_connections_active{charge_point_id="CP-1"} %d
+`, value)
+	err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_connections_active")
+	require.NoError(t, err)
+}
+
+func TestRegistryReplaceAndRemove(t *testing.T) {
+	metrics := monitoring.NewMetrics()
+	registry := NewMemoryRegistry(metrics)
+	first := &fakeConn{chargerID: "CP-1"}
+	second := &fakeConn{chargerID: "CP-1"}
+
+	assert.Nil(t, registry.Add(first))
+	assert.Equal(t, first, registry.Add(second))
+	assertConnectionsActive(t, metrics, 1)
+
+	// A stale close from the replaced connection leaves the new one registered
+	assert.False(t, registry.Remove(first))
+	conn, ok := registry.Get("CP-1")
+	require.True(t, ok)
+	assert.Equal(t, second, conn)
+	assertConnectionsActive(t, metrics, 1)
+
+	assert.True(t, registry.Remove(second))
+	assert.False(t, registry.IsConnected("CP-1"))
+	assertConnectionsActive(t, metrics, 0)
+	assert.ErrorIs(t, registry.SendCall(context.Background(), "CP-1", "Reset", nil, nil), ocpp.ErrNotConnected)
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	registry := NewMemoryRegistry(nil)
+	ctx := context.Background()
+
+	const workers = 50
+	const iterations = 200
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				id := fmt.Sprintf("CP-%d", (w+i)%10)
+				conn := &fakeConn{chargerID: id}
+
+				switch i % 5 {
+				case 0:
+					registry.Add(conn)
+				case 1:
+					registry.Remove(conn)
+				case 2:
+					registry.Get(id)
+					registry.IsConnected(id)
+				case 3:
+					registry.List()
+					registry.Count()
+				case 4:
+					_ = registry.SendCall(ctx, id, "Heartbeat", nil, nil)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// Every listed charger is retrievable and the count agrees
+	ids := registry.List()
+	assert.Equal(t, len(ids), registry.Count())
+	for _, id := range ids {
+		assert.True(t, registry.IsConnected(id))
+	}
+}
@@ -0,0 +1,178 @@
+package ocppconn
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/monitoring"
+)
+
+// maxQueuedCalls bounds the CALLs held back under the queue policy; a charger
+// that floods the connection past this gets CALLERRORs instead
+const maxQueuedCalls = 16
+
+// CallHandler processes an inbound CALL and returns the CALLRESULT payload.
+// An *ocpp.HandlerError is answered with its CALLERROR code and any other
+// error with InternalError.
+type CallHandler func(ctx context.Context, call ocpp.Call) (interface{}, error)
+
+// FrameWriter writes a single OCPP-J frame to the connection. It is called
+// from the handler goroutine and the read loop, so it must serialize writes.
+type FrameWriter func(frame []interface{}) error
+
+// Spawner runs fn in a goroutine owned by the caller's lifecycle, reporting
+// whether it started. core.System.Go is the spawner outside tests, so handlers
+// are cancelled and waited for on shutdown.
+type Spawner func(fn func(ctx context.Context)) bool
+
+// Sequencer enforces the OCPP rule that a charger has at most one unanswered
+// CALL on a connection. The read loop hands every inbound CALL to Dispatch,
+// which never blocks on the handler. A CALL that arrives while another is
+// still being handled is either queued and handled in arrival order
+// (config.ConcurrentCallQueue) or answered with a GenericError CALLERROR
+// (config.ConcurrentCallReject). Handlers therefore never run concurrently
+// for one connection.
+type Sequencer struct {
+	chargerID string
+	policy    string
+	handle    CallHandler
+	write     FrameWriter
+	spawn     Spawner
+	metrics   *monitoring.Metrics
+	logger    *slog.Logger
+
+	mu      sync.Mutex
+	busy    bool
+	pending []ocpp.Call
+	wg      sync.WaitGroup
+}
+
+// NewSequencer creates a sequencer for one charger connection
+func NewSequencer(chargerID, policy string, handle CallHandler, write FrameWriter, spawn Spawner, logger *slog.Logger) *Sequencer {
+	return &Sequencer{
+		chargerID: chargerID,
+		policy:    policy,
+		handle:    handle,
+		write:     write,
+		spawn:     spawn,
+		logger:    logger,
+	}
+}
+
+// SetMetrics sets the metrics the CALLERRORs written are counted in
+func (s *Sequencer) SetMetrics(metrics *monitoring.Metrics) {
+	s.metrics = metrics
+}
+
+// Dispatch accepts an inbound CALL, handling it in the background once no
+// earlier CALL is outstanding
+func (s *Sequencer) Dispatch(call ocpp.Call) {
+	s.mu.Lock()
+	if s.busy {
+		if s.policy == config.ConcurrentCallReject || len(s.pending) >= maxQueuedCalls {
+			s.mu.Unlock()
+			s.reject(call)
+			return
+		}
+		s.pending = append(s.pending, call)
+		s.mu.Unlock()
+		return
+	}
+	s.busy = true
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	if !s.spawn(func(ctx context.Context) { s.run(ctx, call) }) {
+		// Shutting down: nothing was queued behind call while it was busy
+		s.mu.Lock()
+		s.busy = false
+		s.mu.Unlock()
+		s.wg.Done()
+		s.writeError(call, ocpp.ErrorCodeInternalError, "Server is shutting down")
+	}
+}
+
+// Wait blocks until every accepted CALL has been answered. Call it after the
+// read loop has stopped dispatching.
+func (s *Sequencer) Wait() {
+	s.wg.Wait()
+}
+
+// run handles call and then any CALLs queued behind it, one at a time
+func (s *Sequencer) run(ctx context.Context, call ocpp.Call) {
+	defer s.wg.Done()
+
+	for {
+		s.answer(ctx, call)
+
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.busy = false
+			s.mu.Unlock()
+			return
+		}
+		call = s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+	}
+}
+
+// answer runs the handler and writes its CALLRESULT or CALLERROR
+func (s *Sequencer) answer(ctx context.Context, call ocpp.Call) {
+	var frame []interface{}
+	payload, err := s.handle(ctx, call)
+	if err != nil {
+		handlerErr := ocpp.AsHandlerError(err)
+		level := slog.LevelWarn
+		if handlerErr.Code == ocpp.ErrorCodeInternalError {
+			level = slog.LevelError
+		}
+		s.logger.Log(ctx, level, "Failed to handle OCPP call",
+			slog.String("charger_id", s.chargerID),
+			slog.String("action", call.Action),
+			slog.String("unique_id", call.UniqueID),
+			slog.Any("error", err))
+		frame = handlerErr.Frame(call.UniqueID)
+		s.recordError(call, handlerErr.Code)
+	} else {
+		frame = ocpp.CallResultFrame(call.UniqueID, payload)
+	}
+
+	s.writeFrame(call, frame)
+}
+
+// reject answers a CALL sent before the previous one was answered
+func (s *Sequencer) reject(call ocpp.Call) {
+	s.logger.Warn("Rejected OCPP call sent while another was unanswered",
+		slog.String("charger_id", s.chargerID),
+		slog.String("action", call.Action),
+		slog.String("unique_id", call.UniqueID))
+
+	s.writeError(call, ocpp.ErrorCodeGenericError, "A previous call is still being processed")
+}
+
+// writeError answers call with a CALLERROR without details
+func (s *Sequencer) writeError(call ocpp.Call, code, description string) {
+	s.recordError(call, code)
+	s.writeFrame(call, ocpp.CallErrorFrame(call.UniqueID, code, description))
+}
+
+// recordError counts a CALLERROR answering call
+func (s *Sequencer) recordError(call ocpp.Call, code string) {
+	if s.metrics != nil {
+		s.metrics.RecordCallError(ocpp.ActionLabel(call.Action), ocpp.ErrorCodeLabel(code))
+	}
+}
+
+// writeFrame writes the response to call, logging a failed write
+func (s *Sequencer) writeFrame(call ocpp.Call, frame []interface{}) {
+	if err := s.write(frame); err != nil {
+		s.logger.Error("Failed to write OCPP response",
+			slog.String("charger_id", s.chargerID),
+			slog.String("unique_id", call.UniqueID),
+			slog.Any("error", err))
+	}
+}
@@ -0,0 +1,240 @@
+package ocppconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// frameRecorder collects written frames in order
+type frameRecorder struct {
+	mu     sync.Mutex
+	frames [][]interface{}
+}
+
+func (r *frameRecorder) write(frame []interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, frame)
+	return nil
+}
+
+func (r *frameRecorder) snapshot() [][]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]interface{}(nil), r.frames...)
+}
+
+// blockingHandler answers calls only once released, tracking concurrency
+type blockingHandler struct {
+	release  chan struct{}
+	active   atomic.Int32
+	overlaps atomic.Int32
+	handled  []string
+	mu       sync.Mutex
+}
+
+func (h *blockingHandler) handle(ctx context.Context, call ocpp.Call) (interface{}, error) {
+	if h.active.Add(1) > 1 {
+		h.overlaps.Add(1)
+	}
+	defer h.active.Add(-1)
+
+	<-h.release
+	h.mu.Lock()
+	h.handled = append(h.handled, call.UniqueID)
+	h.mu.Unlock()
+	return map[string]string{"status": "Accepted"}, nil
+}
+
+// spawn runs handlers on plain goroutines in place of core.System.Go
+func spawn(fn func(ctx context.Context)) bool {
+	go fn(context.Background())
+	return true
+}
+
+func TestSequencerRejectPolicy(t *testing.T) {
+	recorder := &frameRecorder{}
+	handler := &blockingHandler{release: make(chan struct{})}
+	seq := NewSequencer("CP-1", config.ConcurrentCallReject, handler.handle, recorder.write, spawn, dbtest.Logger())
+
+	seq.Dispatch(ocpp.Call{UniqueID: "1", Action: "Heartbeat"})
+	seq.Dispatch(ocpp.Call{UniqueID: "2", Action: "Heartbeat"})
+
+	// The second call is refused straight away while the first is in flight
+	frames := recorder.snapshot()
+	require.Len(t, frames, 1)
+	assert.Equal(t, ocpp.CallErrorFrame("2", ocpp.ErrorCodeGenericError, "A previous call is still being processed"), frames[0])
+
+	close(handler.release)
+	seq.Wait()
+
+	frames = recorder.snapshot()
+	require.Len(t, frames, 2)
+	assert.Equal(t, ocpp.MessageTypeCallResult, frames[1][0])
+	assert.Equal(t, "1", frames[1][1])
+	assert.Equal(t, []string{"1"}, handler.handled)
+
+	// Once answered, the next call is accepted again
+	seq.Dispatch(ocpp.Call{UniqueID: "3", Action: "Heartbeat"})
+	seq.Wait()
+	assert.Equal(t, []string{"1", "3"}, handler.handled)
+}
+
+func TestSequencerQueuePolicy(t *testing.T) {
+	recorder := &frameRecorder{}
+	handler := &blockingHandler{release: make(chan struct{})}
+	seq := NewSequencer("CP-1", config.ConcurrentCallQueue, handler.handle, recorder.write, spawn, dbtest.Logger())
+
+	for _, id := range []string{"1", "2", "3"} {
+		seq.Dispatch(ocpp.Call{UniqueID: id, Action: "MeterValues"})
+	}
+	assert.Empty(t, recorder.snapshot(), "queued calls wait for the first to be answered")
+
+	close(handler.release)
+	seq.Wait()
+
+	frames := recorder.snapshot()
+	require.Len(t, frames, 3)
+	for i, id := range []string{"1", "2", "3"} {
+		assert.Equal(t, ocpp.MessageTypeCallResult, frames[i][0])
+		assert.Equal(t, id, frames[i][1], "calls are answered in arrival order")
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, handler.handled)
+	assert.Zero(t, handler.overlaps.Load(), "handlers never run concurrently")
+}
+
+func TestSequencerQueueOverflowRejects(t *testing.T) {
+	recorder := &frameRecorder{}
+	handler := &blockingHandler{release: make(chan struct{})}
+	seq := NewSequencer("CP-1", config.ConcurrentCallQueue, handler.handle, recorder.write, spawn, dbtest.Logger())
+
+	seq.Dispatch(ocpp.Call{UniqueID: "in-flight"})
+	for i := 0; i < maxQueuedCalls; i++ {
+		seq.Dispatch(ocpp.Call{UniqueID: "queued"})
+	}
+	seq.Dispatch(ocpp.Call{UniqueID: "overflow"})
+
+	frames := recorder.snapshot()
+	require.Len(t, frames, 1)
+	assert.Equal(t, "overflow", frames[0][1])
+	assert.Equal(t, ocpp.ErrorCodeGenericError, frames[0][2])
+
+	close(handler.release)
+	done := make(chan struct{})
+	go func() {
+		seq.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued calls were not drained")
+	}
+	assert.Len(t, recorder.snapshot(), maxQueuedCalls+2)
+}
+
+func TestSequencerAnswersWhenNothingCanStart(t *testing.T) {
+	recorder := &frameRecorder{}
+	handler := &blockingHandler{release: make(chan struct{})}
+	stopped := func(fn func(ctx context.Context)) bool { return false }
+	seq := NewSequencer("CP-1", config.ConcurrentCallQueue, handler.handle, recorder.write, stopped, dbtest.Logger())
+
+	seq.Dispatch(ocpp.Call{UniqueID: "1", Action: "Heartbeat"})
+	seq.Wait()
+
+	frames := recorder.snapshot()
+	require.Len(t, frames, 1)
+	assert.Equal(t, ocpp.CallErrorFrame("1", ocpp.ErrorCodeInternalError, "Server is shutting down"), frames[0])
+	assert.Empty(t, handler.handled)
+}
+
+func TestSequencerAnswersHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		frame []interface{}
+	}{
+		{
+			name:  "handler error",
+			err:   ocpp.NewPropertyConstraintError("connectorId out of range"),
+			frame: []interface{}{ocpp.MessageTypeCallError, "1", ocpp.ErrorCodePropertyConstraint, "connectorId out of range", struct{}{}},
+		},
+		{
+			name: "handler error with details",
+			err:  ocpp.NewHandlerError(ocpp.ErrorCodeSecurityError, "Unknown charger", map[string]interface{}{"chargerId": "CP-1"}),
+			frame: []interface{}{ocpp.MessageTypeCallError, "1", ocpp.ErrorCodeSecurityError, "Unknown charger",
+				map[string]interface{}{"chargerId": "CP-1"}},
+		},
+		{
+			name:  "wrapped handler error",
+			err:   fmt.Errorf("boot failed: %w", ocpp.NewNotSupportedError("No such profile")),
+			frame: []interface{}{ocpp.MessageTypeCallError, "1", ocpp.ErrorCodeNotSupported, "No such profile", struct{}{}},
+		},
+		{
+			name:  "other error",
+			err:   errors.New("database is locked"),
+			frame: []interface{}{ocpp.MessageTypeCallError, "1", ocpp.ErrorCodeInternalError, "Failed to process request", struct{}{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &frameRecorder{}
+			handle := func(ctx context.Context, call ocpp.Call) (interface{}, error) { return nil, tt.err }
+			seq := NewSequencer("CP-1", config.ConcurrentCallQueue, handle, recorder.write, spawn, dbtest.Logger())
+
+			seq.Dispatch(ocpp.Call{UniqueID: "1", Action: "BootNotification"})
+			seq.Wait()
+
+			frames := recorder.snapshot()
+			require.Len(t, frames, 1)
+			assert.Equal(t, tt.frame, frames[0])
+		})
+	}
+}
+
+func TestSequencerCountsCallErrors(t *testing.T) {
+	recorder := &frameRecorder{}
+	handle := func(ctx context.Context, call ocpp.Call) (interface{}, error) {
+		switch call.Action {
+		case ocpp.ActionHeartbeat:
+			return nil, ocpp.NewSecurityError("Charger is not registered")
+		case "FlashLights":
+			return nil, ocpp.NewNotImplementedError("Unknown action")
+		}
+		return nil, errors.New("database is locked")
+	}
+	metrics := monitoring.NewMetrics()
+	seq := NewSequencer("CP-1", config.ConcurrentCallQueue, handle, recorder.write, spawn, dbtest.Logger())
+	seq.SetMetrics(metrics)
+
+	for i, action := range []string{ocpp.ActionHeartbeat, ocpp.ActionHeartbeat, "FlashLights", ocpp.ActionStartTransaction} {
+		seq.Dispatch(ocpp.Call{UniqueID: fmt.Sprint(i), Action: action})
+		seq.Wait()
+	}
+
+	// Unknown actions share one label so chargers cannot grow the series
+	expected := `
+# HELP ocpp_call_errors_total Total number of CALLERRORs sent to or received from charge points, by action and error code
+# TYPE ocpp_call_errors_total counter
+ocpp_call_errors_total{action="Heartbeat",error_code="SecurityError"} 2
+ocpp_call_errors_total{action="Other",error_code="NotImplemented"} 1
+ocpp_call_errors_total{action="StartTransaction",error_code="InternalError"} 1
+`
+	err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_call_errors_total")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,134 @@
+package ocppconn
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/keeth/levity/monitoring"
+)
+
+// Reasons a connection closed, recorded in ocpp_disconnects_total
+const (
+	DisconnectClean     = "clean"
+	DisconnectAbnormal  = "abnormal"
+	DisconnectReadError = "read_error"
+	DisconnectTimeout   = "timeout"
+	DisconnectPanic     = "panic"
+)
+
+// CloseReason classifies the error that ended a connection's read loop. A
+// close frame with a normal or going-away code is clean; a connection dropped
+// without one (1006) or closed with an error code is abnormal.
+func CloseReason(err error) string {
+	if err == nil || websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return DisconnectClean
+	}
+
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return DisconnectAbnormal
+	}
+
+	var netErr net.Error
+	if errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return DisconnectTimeout
+	}
+
+	return DisconnectReadError
+}
+
+// Session is a charger connection from registration to teardown. However the
+// connection ends, its teardown unregisters it, updates the active connection
+// gauge and calls onClose exactly once.
+type Session struct {
+	conn     Conn
+	registry Registry
+	metrics  *monitoring.Metrics
+	onClose  func(reason string)
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	closed bool
+	reason string
+}
+
+// Open registers conn, which counts it as active. A connection it replaces is
+// closed, and its own session records that teardown when its read loop ends.
+// metrics and onClose may be nil.
+func Open(registry Registry, conn Conn, metrics *monitoring.Metrics, onClose func(reason string), logger *slog.Logger) *Session {
+	s := &Session{
+		conn:     conn,
+		registry: registry,
+		metrics:  metrics,
+		onClose:  onClose,
+		logger:   logger,
+	}
+
+	if previous := registry.Add(conn); previous != nil {
+		logger.Info("Charger reconnected, closing previous connection",
+			slog.String("charger_id", conn.ChargerID()))
+		previous.Close()
+	}
+
+	return s
+}
+
+// Serve runs the connection's read loop and tears the session down when it
+// returns. A panic in the read loop is recovered and recorded as such rather
+// than taking down the server. It returns the reason the connection closed.
+func (s *Session) Serve(readLoop func() error) (reason string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("OCPP connection panicked",
+				slog.String("charger_id", s.conn.ChargerID()),
+				slog.Any("panic", r))
+			reason = DisconnectPanic
+		}
+		s.Close(reason)
+		reason = s.Reason()
+	}()
+
+	return CloseReason(readLoop())
+}
+
+// Close tears the session down, recording reason. Only the first call has any
+// effect; it reports whether this call closed the session.
+func (s *Session) Close(reason string) bool {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return false
+	}
+	s.closed = true
+	s.reason = reason
+	s.mu.Unlock()
+
+	s.registry.Remove(s.conn)
+	if err := s.conn.Close(); err != nil {
+		s.logger.Debug("Failed to close OCPP connection",
+			slog.String("charger_id", s.conn.ChargerID()),
+			slog.Any("error", err))
+	}
+	if s.metrics != nil {
+		s.metrics.RecordOCPPDisconnect(s.conn.ChargerID(), reason)
+	}
+	if s.onClose != nil {
+		s.onClose(reason)
+	}
+
+	s.logger.Info("OCPP connection closed",
+		slog.String("charger_id", s.conn.ChargerID()),
+		slog.String("reason", reason))
+	return true
+}
+
+// Reason returns why the session closed, or "" while it is open
+func (s *Session) Reason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason
+}
@@ -0,0 +1,107 @@
+package ocppconn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, DisconnectClean},
+		{"normal closure", &websocket.CloseError{Code: websocket.CloseNormalClosure}, DisconnectClean},
+		{"going away", &websocket.CloseError{Code: websocket.CloseGoingAway}, DisconnectClean},
+		{"abnormal closure", &websocket.CloseError{Code: websocket.CloseAbnormalClosure}, DisconnectAbnormal},
+		{"protocol error", &websocket.CloseError{Code: websocket.CloseProtocolError}, DisconnectAbnormal},
+		{"deadline", fmt.Errorf("read: %w", os.ErrDeadlineExceeded), DisconnectTimeout},
+		{"read error", errors.New("connection reset by peer"), DisconnectReadError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CloseReason(tt.err))
+		})
+	}
+}
+
+func TestSessionTeardownReasons(t *testing.T) {
+	tests := []struct {
+		reason   string
+		readLoop func() error
+	}{
+		{DisconnectClean, func() error { return &websocket.CloseError{Code: websocket.CloseNormalClosure} }},
+		{DisconnectAbnormal, func() error { return &websocket.CloseError{Code: websocket.CloseAbnormalClosure} }},
+		{DisconnectReadError, func() error { return errors.New("unexpected EOF") }},
+		{DisconnectTimeout, func() error { return os.ErrDeadlineExceeded }},
+		{DisconnectPanic, func() error { panic("handler bug") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			metrics := monitoring.NewMetrics()
+			registry := NewMemoryRegistry(metrics)
+			var closed []string
+
+			conn := &fakeConn{chargerID: "CP-1"}
+			session := Open(registry, conn, metrics, func(reason string) { closed = append(closed, reason) }, dbtest.Logger())
+			assert.True(t, registry.IsConnected("CP-1"))
+
+			assert.Equal(t, tt.reason, session.Serve(tt.readLoop))
+
+			// A second teardown, such as a close racing the read loop, is ignored
+			assert.False(t, session.Close(DisconnectReadError))
+			assert.Equal(t, tt.reason, session.Reason())
+
+			assert.False(t, registry.IsConnected("CP-1"))
+			assert.Equal(t, []string{tt.reason}, closed)
+
+			expected := fmt.Sprintf(`
+# HELP ocpp_connections_active Current number of active OCPP connections
+# TYPE ocpp_connections_active gauge
+ocpp_connections_active{charge_point_id="CP-1"} 0
+# HELP ocpp_disconnects_total Total number of OCPP connections closed, by reason
+# TYPE ocpp_disconnects_total counter
+ocpp_disconnects_total{reason=%q} 1
+`, tt.reason)
+			err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected),
+				"ocpp_connections_active", "ocpp_disconnects_total")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSessionReplacedConnection(t *testing.T) {
+	metrics := monitoring.NewMetrics()
+	registry := NewMemoryRegistry(metrics)
+
+	first := Open(registry, &fakeConn{chargerID: "CP-1"}, metrics, nil, dbtest.Logger())
+	second := Open(registry, &fakeConn{chargerID: "CP-1"}, metrics, nil, dbtest.Logger())
+
+	// The replaced connection's read loop ends once it is closed
+	first.Serve(func() error { return &websocket.CloseError{Code: websocket.CloseAbnormalClosure} })
+	assert.True(t, registry.IsConnected("CP-1"))
+
+	second.Serve(func() error { return nil })
+	assert.False(t, registry.IsConnected("CP-1"))
+
+	expected := `
+# HELP ocpp_connections_active Current number of active OCPP connections
+# TYPE ocpp_connections_active gauge
+ocpp_connections_active{charge_point_id="CP-1"} 0
+`
+	err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "ocpp_connections_active")
+	require.NoError(t, err)
+}
@@ -0,0 +1,235 @@
+package ocppconn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/keeth/levity/core/ocpp"
+)
+
+// closeWriteTimeout bounds writing the close frame to an unresponsive charger
+const closeWriteTimeout = time.Second
+
+// frameWriteTimeout bounds writing a frame to a charger that stopped reading
+const frameWriteTimeout = 10 * time.Second
+
+// callResponse is the CALLRESULT payload or CALLERROR answering an outbound CALL
+type callResponse struct {
+	payload json.RawMessage
+	err     error
+}
+
+// WSConn is a charger connection over a WebSocket. Its read loop hands inbound
+// CALLs to a Sequencer and matches CALLRESULTs and CALLERRORs to the outbound
+// CALLs waiting in SendCall by uniqueId.
+type WSConn struct {
+	chargerID   string
+	ws          *websocket.Conn
+	connectedAt time.Time
+	logger      *slog.Logger
+
+	writeTimeout time.Duration
+	writeMu      sync.Mutex
+	nextID       atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[string]chan callResponse
+	closed  bool
+
+	closeOnce sync.Once
+}
+
+// NewWSConn wraps an upgraded WebSocket for a charger
+func NewWSConn(chargerID string, ws *websocket.Conn, connectedAt time.Time, logger *slog.Logger) *WSConn {
+	return &WSConn{
+		chargerID:    chargerID,
+		ws:           ws,
+		connectedAt:  connectedAt,
+		logger:       logger,
+		writeTimeout: frameWriteTimeout,
+		pending:      make(map[string]chan callResponse),
+	}
+}
+
+// ChargerID implements Conn.ChargerID
+func (c *WSConn) ChargerID() string {
+	return c.chargerID
+}
+
+// ConnectedAt implements Conn.ConnectedAt
+func (c *WSConn) ConnectedAt() time.Time {
+	return c.connectedAt
+}
+
+// Close implements Conn.Close, sending a normal closure before closing the
+// socket. It does not wait for writeMu: WriteControl may run alongside a
+// frame write, and closing the socket fails a write blocked on the charger.
+func (c *WSConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(closeWriteTimeout))
+		err = c.ws.Close()
+	})
+	return err
+}
+
+// WriteFrame writes one OCPP-J frame, failing once a charger that stopped
+// reading has blocked it for frameWriteTimeout; it is safe for concurrent use
+func (c *WSConn) WriteFrame(frame []interface{}) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return fmt.Errorf("failed to encode frame: %w", err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.ws.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return err
+	}
+	return c.ws.WriteMessage(websocket.TextMessage, data)
+}
+
+// SendCall implements Conn.SendCall. It waits for the charger's answer until
+// ctx is done; a CALLERROR is returned as an *ocpp.CallError.
+func (c *WSConn) SendCall(ctx context.Context, action string, request interface{}, response interface{}) error {
+	uniqueID := strconv.FormatUint(c.nextID.Add(1), 10)
+	answer := make(chan callResponse, 1)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ocpp.ErrNotConnected
+	}
+	c.pending[uniqueID] = answer
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, uniqueID)
+		c.mu.Unlock()
+	}()
+
+	if err := c.WriteFrame([]interface{}{ocpp.MessageTypeCall, uniqueID, action, request}); err != nil {
+		return fmt.Errorf("failed to send %s: %w", action, err)
+	}
+
+	select {
+	case res := <-answer:
+		if res.err != nil {
+			return res.err
+		}
+		if response == nil {
+			return nil
+		}
+		if err := json.Unmarshal(res.payload, response); err != nil {
+			return fmt.Errorf("invalid %s response: %w", action, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ReadLoop reads frames until the connection fails, dispatching inbound CALLs
+// to seq. Outbound CALLs still waiting when it returns fail with
+// ocpp.ErrNotConnected.
+func (c *WSConn) ReadLoop(seq *Sequencer) error {
+	defer c.abandonPending()
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		c.handleFrame(seq, data)
+	}
+}
+
+// handleFrame dispatches a CALL or resolves the outbound CALL a response answers
+func (c *WSConn) handleFrame(seq *Sequencer, data []byte) {
+	msg, err := ocpp.ParseMessage(data)
+	if err != nil {
+		c.handleMalformedFrame(err, len(data))
+		return
+	}
+
+	switch msg.MessageType {
+	case ocpp.MessageTypeCall:
+		seq.Dispatch(ocpp.Call{UniqueID: msg.UniqueID, Action: msg.Action, Payload: msg.Payload})
+
+	case ocpp.MessageTypeCallResult:
+		c.resolve(msg.UniqueID, callResponse{payload: msg.Payload})
+
+	case ocpp.MessageTypeCallError:
+		c.resolve(msg.UniqueID, callResponse{err: &ocpp.CallError{Code: msg.ErrorCode, Description: msg.ErrorDescription}})
+	}
+}
+
+// handleMalformedFrame answers a malformed CALL with a CALLERROR and fails
+// the outbound CALL a malformed response answers. A frame without a unique id
+// cannot be answered and is dropped.
+func (c *WSConn) handleMalformedFrame(err error, size int) {
+	var frameErr *ocpp.FrameError
+	if !errors.As(err, &frameErr) || frameErr.UniqueID == "" {
+		c.logger.Warn("Ignoring malformed OCPP frame",
+			slog.String("charger_id", c.chargerID),
+			slog.Int("size", size),
+			slog.Any("error", err))
+		return
+	}
+
+	switch frameErr.MessageType {
+	case ocpp.MessageTypeCallResult, ocpp.MessageTypeCallError:
+		c.resolve(frameErr.UniqueID, callResponse{err: err})
+	default:
+		c.writeError(frameErr.UniqueID, frameErr.Code, frameErr.Description)
+	}
+}
+
+// resolve hands a response to the outbound CALL waiting on uniqueID
+func (c *WSConn) resolve(uniqueID string, res callResponse) {
+	c.mu.Lock()
+	answer, ok := c.pending[uniqueID]
+	delete(c.pending, uniqueID)
+	c.mu.Unlock()
+
+	if !ok {
+		c.logger.Warn("Ignoring OCPP response to unknown call",
+			slog.String("charger_id", c.chargerID),
+			slog.String("unique_id", uniqueID))
+		return
+	}
+	answer <- res
+}
+
+// abandonPending fails the outbound CALLs still waiting for an answer
+func (c *WSConn) abandonPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for uniqueID, answer := range c.pending {
+		answer <- callResponse{err: ocpp.ErrNotConnected}
+		delete(c.pending, uniqueID)
+	}
+}
+
+// writeError answers a frame the read loop could not dispatch
+func (c *WSConn) writeError(uniqueID, code, description string) {
+	if err := c.WriteFrame(ocpp.CallErrorFrame(uniqueID, code, description)); err != nil {
+		c.logger.Error("Failed to write OCPP response",
+			slog.String("charger_id", c.chargerID),
+			slog.String("unique_id", uniqueID),
+			slog.Any("error", err))
+	}
+}
@@ -0,0 +1,97 @@
+package ocppconn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStalledWSConn returns a server-side connection whose charger never
+// reads, so writes fill the socket buffers and then block
+func newStalledWSConn(t *testing.T) *WSConn {
+	t.Helper()
+
+	conns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- ws
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return NewWSConn("CP-1", <-conns, time.Now(), dbtest.Logger())
+}
+
+// fillFrame is large enough that a few writes fill the socket buffers
+var fillFrame = []interface{}{2, "1", "DataTransfer", map[string]string{"data": strings.Repeat("x", 1<<20)}}
+
+func TestWriteFrameTimesOutWhenChargerStopsReading(t *testing.T) {
+	conn := newStalledWSConn(t)
+	conn.writeTimeout = 50 * time.Millisecond
+
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		err = conn.WriteFrame(fillFrame)
+	}
+	require.Error(t, err)
+
+	closed := make(chan struct{})
+	go func() {
+		conn.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked after a timed out write")
+	}
+}
+
+func TestCloseDoesNotWaitForBlockedWrite(t *testing.T) {
+	conn := newStalledWSConn(t)
+	conn.writeTimeout = time.Minute
+
+	written := make(chan error, 1)
+	go func() {
+		var err error
+		for err == nil {
+			err = conn.WriteFrame(fillFrame)
+		}
+		written <- err
+	}()
+
+	// Give the writer time to block on the full socket
+	time.Sleep(200 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		conn.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for a blocked write")
+	}
+
+	select {
+	case err := <-written:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the socket did not fail the blocked write")
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDocument is the part of an OpenAPI 3 document the tests check
+type openAPIDocument struct {
+	OpenAPI    string `json:"openapi"`
+	Info       struct{ Title, Version string }
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components map[string]map[string]json.RawMessage  `json:"components"`
+}
+
+type openAPIOperation struct {
+	Summary   string                     `json:"summary"`
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+func fetchOpenAPISpec(t *testing.T, srv *Server) openAPIDocument {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	return doc
+}
+
+func TestOpenAPISpecIsValid(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	doc := fetchOpenAPISpec(t, srv)
+
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."), "openapi version %q", doc.OpenAPI)
+	assert.NotEmpty(t, doc.Info.Title)
+	assert.NotEmpty(t, doc.Info.Version)
+
+	for _, path := range []string{
+		"/api/v1/chargepoints",
+		"/api/v1/chargepoints/{id}",
+		"/api/v1/transactions",
+		"/api/v1/chargepoints/{id}/meter-values/series",
+		"/api/v1/chargepoints/{id}/availability",
+	} {
+		assert.Contains(t, doc.Paths, path)
+	}
+
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			assert.NotEmpty(t, operation.Summary, "%s %s has no summary", method, path)
+			assert.NotEmpty(t, operation.Responses, "%s %s has no responses", method, path)
+		}
+	}
+
+	// Every $ref must point at a defined component
+	refs := regexp.MustCompile(`"\$ref": "#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(string(openAPISpec), -1)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		assert.Contains(t, doc.Components[ref[1]], ref[2], "undefined reference %s", ref[0])
+	}
+}
+
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	doc := fetchOpenAPISpec(t, srv)
+
+	// The OCPP endpoints and the API description itself are not part of the API
+	undocumented := map[string]bool{ocppRoutePath: true, "/openapi.json": true, "/docs": true}
+	param := regexp.MustCompile(`:(\w+)`)
+
+	var routes []string
+	for _, route := range srv.router.Routes() {
+		if undocumented[route.Path] || strings.HasPrefix(route.Path, "/ocpp/") {
+			continue
+		}
+		routes = append(routes, strings.ToLower(route.Method)+" "+param.ReplaceAllString(route.Path, "{$1}"))
+	}
+
+	var documented []string
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			documented = append(documented, method+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(documented)
+	assert.Equal(t, routes, documented, "server/openapi.json is out of sync with the routes")
+}
+
+func TestDocsServesSwaggerUI(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "/openapi.json"`)
+}
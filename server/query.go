@@ -0,0 +1,73 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// maxListLimit caps the page size of list endpoints that clamp limit
+const maxListLimit = 500
+
+// totalCountHeader carries the number of rows a paginated list could return
+const totalCountHeader = "X-Total-Count"
+
+// queryTime parses an optional RFC 3339 query parameter
+func queryTime(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// queryInt parses an optional integer query parameter, defaulting to 0
+func queryInt(c *gin.Context, name string) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(raw)
+}
+
+// queryBool parses an optional boolean query parameter, defaulting to false
+func queryBool(c *gin.Context, name string) (bool, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(raw)
+}
+
+// queryListOptions parses limit, offset, order_by and sort_dir on top of the
+// defaults. Repositories whitelist order_by themselves.
+func queryListOptions(c *gin.Context) (db.ListOptions, bool) {
+	opts := db.DefaultListOptions()
+	opts.OrderBy = c.Query("order_by")
+	if sortDir := c.Query("sort_dir"); sortDir != "" {
+		opts.SortDir = strings.ToUpper(sortDir)
+	}
+
+	limit, err := queryInt(c, "limit")
+	if err != nil || limit < 0 {
+		return opts, false
+	}
+	if limit > 0 {
+		opts.Limit = limit
+	}
+
+	offset, err := queryInt(c, "offset")
+	if err != nil || offset < 0 {
+		return opts, false
+	}
+	opts.Offset = offset
+
+	return opts, true
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// queryStatsMiddleware logs, at debug level, how many database queries a
+// request made and how long they took, to surface N+1 queries in handlers.
+// Requests are not instrumented unless debug logging is enabled.
+func queryStatsMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !logger.Enabled(c.Request.Context(), slog.LevelDebug) {
+			c.Next()
+			return
+		}
+
+		ctx, stats := db.WithQueryStats(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		logger.Debug("HTTP request queries",
+			slog.String("request_id", requestID(c)),
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.Int("queries", stats.Count()),
+			slog.Duration("query_time", stats.Duration()))
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keeth/levity/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queryLogs returns the per-request query log entries written to buf
+func queryLogs(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		if entry["msg"] == "HTTP request queries" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func TestQueryStatsLoggedPerRequest(t *testing.T) {
+	base, _ := newCommandTestServer(t)
+	_, err := base.coreSystem.GetRepositories().Sites().Create(context.Background(), db.CreateSiteRequest{ID: "SITE-1", Name: "Depot"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	srv := NewServer(base.config, base.coreSystem, nil, logger)
+
+	// Getting a site loads it, then its charger ids
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sites/SITE-1", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	entries := queryLogs(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "/api/v1/sites/:id", entries[0]["route"])
+	assert.EqualValues(t, 2, entries[0]["queries"])
+
+	// Without debug logging nothing is logged
+	buf.Reset()
+	quiet := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	srv = NewServer(base.config, base.coreSystem, nil, quiet)
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sites/SITE-1", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, queryLogs(t, &buf))
+}
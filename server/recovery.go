@@ -0,0 +1,49 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/monitoring"
+)
+
+// recoveryMiddleware turns a handler panic into a 500 with the error envelope,
+// counting it in http_panics_total and logging the stack with the request's
+// correlation id
+func recoveryMiddleware(metrics *monitoring.Metrics, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// The client went away; let net/http abort the connection quietly
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			endpoint := c.FullPath()
+			if metrics != nil {
+				metrics.RecordHTTPPanic(c.Request.Method, endpoint)
+			}
+
+			id := requestID(c)
+			logger.Error("Recovered from panic in HTTP handler",
+				slog.String("request_id", id),
+				slog.String("method", c.Request.Method),
+				slog.String("endpoint", endpoint),
+				slog.Any("panic", recovered),
+				slog.String("stack", string(debug.Stack())))
+
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":      "Internal server error",
+				"request_id": id,
+			})
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	metrics := monitoring.NewMetrics()
+	srv := NewServer(&config.Config{}, nil, metrics, dbtest.Logger())
+	srv.router.GET("/boom", func(c *gin.Context) {
+		panic("something broke")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set(requestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "req-123", w.Header().Get(requestIDHeader))
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Internal server error", body["error"])
+	assert.Equal(t, "req-123", body["request_id"])
+
+	expected := `
+		# HELP http_panics_total Total number of HTTP handlers that panicked
+		# TYPE http_panics_total counter
+		http_panics_total{endpoint="/boom",method="GET"} 1
+	`
+	err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "http_panics_total")
+	require.NoError(t, err)
+}
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	srv := NewServer(&config.Config{}, nil, nil, dbtest.Logger())
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Len(t, w.Header().Get(requestIDHeader), 32)
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// remoteSender answers remote starts and stops with a fixed status and
+// records the requests it was asked to send
+type remoteSender struct {
+	status   string
+	err      error
+	block    bool
+	requests []interface{}
+}
+
+func (s *remoteSender) SendCall(ctx context.Context, chargerID, action string, request, response interface{}) error {
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.requests = append(s.requests, request)
+	return json.Unmarshal([]byte(`{"status":"`+s.status+`"}`), response)
+}
+
+func TestRemoteStart(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	sender := &remoteSender{status: ocpp.RemoteStartStopRejected}
+	srv.coreSystem.SetCommandSender(sender)
+
+	w := postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/remote-start", `{"id_tag": "TAG-1", "connector_id": 1}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"status": "Rejected"}`, w.Body.String())
+
+	w = postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/remote-start", `{"id_tag": "TAG-2"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	require.Len(t, sender.requests, 2)
+	first := sender.requests[0].(ocpp.RemoteStartTransactionRequest)
+	assert.Equal(t, "TAG-1", first.IDTag)
+	require.NotNil(t, first.ConnectorID)
+	assert.Equal(t, 1, *first.ConnectorID)
+	assert.Nil(t, sender.requests[1].(ocpp.RemoteStartTransactionRequest).ConnectorID)
+}
+
+func TestRemoteStartFailures(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	srv.coreSystem.GetConfig().OCPP.ConnectionTimeout = 10 * time.Millisecond
+
+	tests := []struct {
+		name   string
+		sender *remoteSender
+		path   string
+		body   string
+		want   int
+	}{
+		{"missing id tag", &remoteSender{}, "/api/v1/chargepoints/CP-ONLINE/remote-start", `{"connector_id": 1}`, http.StatusBadRequest},
+		{"id tag too long", &remoteSender{}, "/api/v1/chargepoints/CP-ONLINE/remote-start", `{"id_tag": "123456789012345678901"}`, http.StatusBadRequest},
+		{"connector zero", &remoteSender{}, "/api/v1/chargepoints/CP-ONLINE/remote-start", `{"id_tag": "TAG", "connector_id": 0}`, http.StatusBadRequest},
+		{"unknown charger", &remoteSender{}, "/api/v1/chargepoints/CP-MISSING/remote-start", `{"id_tag": "TAG"}`, http.StatusNotFound},
+		{"not connected", &remoteSender{err: ocpp.ErrNotConnected}, "/api/v1/chargepoints/CP-OFFLINE/remote-start", `{"id_tag": "TAG"}`, http.StatusConflict},
+		{"no answer", &remoteSender{block: true}, "/api/v1/chargepoints/CP-ONLINE/remote-start", `{"id_tag": "TAG"}`, http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.coreSystem.SetCommandSender(tt.sender)
+			w := postCommand(srv, tt.path, tt.body)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			assert.Empty(t, tt.sender.requests)
+		})
+	}
+}
+
+// seedRemoteStopTransactions creates an active transaction on each test
+// charger and a completed one, returning their row ids by name
+func seedRemoteStopTransactions(t *testing.T, srv *Server) map[string]int {
+	t.Helper()
+
+	ctx := context.Background()
+	txs := srv.coreSystem.GetRepositories().Transactions()
+	ids := make(map[string]int)
+	for i, name := range []string{"CP-ONLINE", "CP-OFFLINE", "completed"} {
+		chargerID, connectorID, txID := name, 1, 100+i
+		if name == "completed" {
+			chargerID, connectorID = "CP-ONLINE", 2
+		}
+		tx, err := txs.Create(ctx, db.CreateTransactionRequest{
+			TransactionID: &txID, ChargerID: chargerID, ConnectorID: connectorID, IDTag: "TAG",
+		})
+		require.NoError(t, err)
+		ids[name] = tx.ID
+	}
+	require.NoError(t, txs.Stop(ctx, ids["completed"], 500, time.Now(), "Local", db.StopSourceCharger))
+	return ids
+}
+
+func TestRemoteStop(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ids := seedRemoteStopTransactions(t, srv)
+	sender := &remoteSender{status: ocpp.RemoteStartStopAccepted}
+	srv.coreSystem.SetCommandSender(sender)
+
+	w := postCommand(srv, fmt.Sprintf("/api/v1/transactions/%d/remote-stop", ids["CP-ONLINE"]), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"status": "Accepted"}`, w.Body.String())
+
+	// The charger is sent its own transaction id, not the row id
+	require.Len(t, sender.requests, 1)
+	assert.Equal(t, ocpp.RemoteStopTransactionRequest{TransactionID: 100}, sender.requests[0])
+}
+
+func TestRemoteStopFailures(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ids := seedRemoteStopTransactions(t, srv)
+	srv.coreSystem.GetConfig().OCPP.ConnectionTimeout = 10 * time.Millisecond
+
+	tests := []struct {
+		name   string
+		sender *remoteSender
+		id     string
+		want   int
+	}{
+		{"invalid id", &remoteSender{}, "abc", http.StatusBadRequest},
+		{"unknown transaction", &remoteSender{}, "9999", http.StatusNotFound},
+		{"completed", &remoteSender{}, strconv.Itoa(ids["completed"]), http.StatusConflict},
+		{"not connected", &remoteSender{err: ocpp.ErrNotConnected}, strconv.Itoa(ids["CP-OFFLINE"]), http.StatusConflict},
+		{"no answer", &remoteSender{block: true}, strconv.Itoa(ids["CP-ONLINE"]), http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.coreSystem.SetCommandSender(tt.sender)
+			w := postCommand(srv, "/api/v1/transactions/"+tt.id+"/remote-stop", "")
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			assert.Empty(t, tt.sender.requests)
+		})
+	}
+}
+
+func TestRemoteStopLookupFailure(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ids := seedRemoteStopTransactions(t, srv)
+	sender := &remoteSender{status: ocpp.RemoteStartStopAccepted}
+	srv.coreSystem.SetCommandSender(sender)
+	require.NoError(t, srv.coreSystem.GetDatabase().Close())
+
+	// A failed lookup is a server error, not a missing transaction
+	w := postCommand(srv, fmt.Sprintf("/api/v1/transactions/%d/remote-stop", ids["CP-ONLINE"]), "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+	assert.Empty(t, sender.requests)
+}
+
+func TestResetValidatesType(t *testing.T) {
+	srv, sender := newCommandTestServer(t)
+
+	for _, body := range []string{`{}`, `{"type": "soft"}`, `{"type": "Reboot"}`, `not json`} {
+		w := postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/reset", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Empty(t, sender.actions)
+
+	w := postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/reset", `{"type": "Soft"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"status": "Accepted"}`, w.Body.String())
+	assert.Equal(t, []string{ocpp.ActionReset}, sender.actions)
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader carries the correlation id of a request and its response
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey is the gin context key holding the correlation id
+const requestIDKey = "request_id"
+
+// maxRequestIDLength bounds client-supplied correlation ids
+const maxRequestIDLength = 128
+
+// requestIDMiddleware assigns every request a correlation id, reusing the
+// caller's X-Request-ID when present, and echoes it in the response
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestID returns the correlation id assigned to a request
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// newRequestID returns a random 128-bit hex id
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"time"
+
+	"github.com/keeth/levity/db"
+)
+
+// The API response types below are the public contract of the REST API. They
+// are mapped from the db models so the schema can change without breaking
+// clients; add fields here deliberately rather than exposing new columns.
+
+// chargerResponse is a charge point as returned by the API
+type chargerResponse struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Vendor          string     `json:"vendor"`
+	Model           string     `json:"model"`
+	SerialNumber    string     `json:"serial_number"`
+	FirmwareVersion string     `json:"firmware_version"`
+	Status          string     `json:"status"`
+	IsConnected     bool       `json:"is_connected"`
+	NumConnectors   int        `json:"num_connectors"`
+	Notes           string     `json:"notes"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at"`
+	LastBootAt      *time.Time `json:"last_boot_at"`
+	LastConnectAt   *time.Time `json:"last_connect_at"`
+	LastTxStartAt   *time.Time `json:"last_tx_start_at"`
+	LastTxStopAt    *time.Time `json:"last_tx_stop_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// newChargerResponse maps a charger to its API representation. SIM
+// identifiers (ICCID, IMSI) are deliberately left out.
+func newChargerResponse(charger *db.Charger) chargerResponse {
+	return chargerResponse{
+		ID:              charger.ID,
+		Name:            charger.Name,
+		Vendor:          charger.Vendor,
+		Model:           charger.Model,
+		SerialNumber:    charger.SerialNumber,
+		FirmwareVersion: charger.FirmwareVersion,
+		Status:          charger.Status,
+		IsConnected:     charger.IsConnected,
+		NumConnectors:   charger.NumConnectors,
+		Notes:           charger.Notes,
+		LastHeartbeatAt: charger.LastHeartbeatAt,
+		LastBootAt:      charger.LastBootAt,
+		LastConnectAt:   charger.LastConnectAt,
+		LastTxStartAt:   charger.LastTxStartAt,
+		LastTxStopAt:    charger.LastTxStopAt,
+		CreatedAt:       charger.CreatedAt,
+		UpdatedAt:       charger.UpdatedAt,
+	}
+}
+
+// chargerDetailResponse is a charge point with its connectors and active
+// errors, as returned by the detail endpoint
+type chargerDetailResponse struct {
+	chargerResponse
+	Connectors   []connectorResponse    `json:"connectors"`
+	ActiveErrors []chargerErrorResponse `json:"active_errors"`
+}
+
+// connectorResponse is a connector as returned by the API, with the latest
+// meter value it reported
+type connectorResponse struct {
+	ConnectorID      int                 `json:"connector_id"`
+	Status           string              `json:"status"`
+	ErrorCode        string              `json:"error_code"`
+	VendorErrorCode  string              `json:"vendor_error_code"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+	LatestMeterValue *meterValueResponse `json:"latest_meter_value"`
+}
+
+// newConnectorResponse maps a connector and its latest meter value, nil when
+// it has reported none, to their API representation
+func newConnectorResponse(connector *db.ChargerConnector, latest *db.MeterValue) connectorResponse {
+	response := connectorResponse{
+		ConnectorID:     connector.ConnectorID,
+		Status:          connector.Status,
+		ErrorCode:       connector.ErrorCode,
+		VendorErrorCode: connector.VendorErrorCode,
+		UpdatedAt:       connector.UpdatedAt,
+	}
+	if latest != nil {
+		value := newMeterValueResponse(latest)
+		response.LatestMeterValue = &value
+	}
+	return response
+}
+
+// meterValueResponse is a sampled meter value as returned by the API
+type meterValueResponse struct {
+	TransactionID *int      `json:"transaction_id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Measurand     string    `json:"measurand"`
+	Value         float64   `json:"value"`
+	Unit          string    `json:"unit"`
+	Context       string    `json:"context"`
+	Location      string    `json:"location"`
+	Phase         string    `json:"phase"`
+}
+
+// newMeterValueResponse maps a meter value to its API representation
+func newMeterValueResponse(mv *db.MeterValue) meterValueResponse {
+	return meterValueResponse{
+		TransactionID: mv.TransactionID,
+		Timestamp:     mv.Timestamp,
+		Measurand:     mv.Measurand,
+		Value:         mv.Value,
+		Unit:          mv.Unit,
+		Context:       mv.Context,
+		Location:      mv.Location,
+		Phase:         mv.Phase,
+	}
+}
+
+// newMeterValueResponses maps a list of meter values, never returning nil
+func newMeterValueResponses(values []*db.MeterValue) []meterValueResponse {
+	responses := make([]meterValueResponse, 0, len(values))
+	for _, mv := range values {
+		responses = append(responses, newMeterValueResponse(mv))
+	}
+	return responses
+}
+
+// transactionResponse is a charging session as returned by the API
+type transactionResponse struct {
+	ID               int        `json:"id"`
+	TransactionID    *int       `json:"transaction_id"`
+	ChargerID        string     `json:"charger_id"`
+	ConnectorID      int        `json:"connector_id"`
+	IDTag            string     `json:"id_tag"`
+	Status           string     `json:"status"`
+	StartTime        time.Time  `json:"start_time"`
+	StopTime         *time.Time `json:"stop_time"`
+	StopReason       string     `json:"stop_reason"`
+	StopSource       string     `json:"stop_source"`
+	MeterStart       int        `json:"meter_start"`
+	MeterStop        *int       `json:"meter_stop"`
+	EnergyDelivered  int        `json:"energy_delivered"`
+	EnergyKWh        float64    `json:"energy_kwh"`
+	DurationSeconds  int64      `json:"duration_seconds"`
+	ClockSkewSeconds *int64     `json:"clock_skew_seconds,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// newTransactionResponse maps a transaction to its API representation. The
+// duration of a session that has not stopped runs until now.
+func newTransactionResponse(tx *db.Transaction, now time.Time) transactionResponse {
+	end := now
+	if tx.StopTime != nil {
+		end = *tx.StopTime
+	}
+
+	duration := end.Sub(tx.StartTime)
+	if duration < 0 {
+		duration = 0
+	}
+
+	return transactionResponse{
+		ID:               tx.ID,
+		TransactionID:    tx.TransactionID,
+		ChargerID:        tx.ChargerID,
+		ConnectorID:      tx.ConnectorID,
+		IDTag:            tx.IDTag,
+		Status:           string(tx.Status),
+		StartTime:        tx.StartTime,
+		StopTime:         tx.StopTime,
+		StopReason:       tx.StopReason,
+		StopSource:       tx.StopSource,
+		MeterStart:       tx.MeterStart,
+		MeterStop:        tx.MeterStop,
+		EnergyDelivered:  tx.EnergyDelivered,
+		EnergyKWh:        float64(tx.EnergyDelivered) / 1000,
+		DurationSeconds:  int64(duration / time.Second),
+		ClockSkewSeconds: tx.ClockSkewSeconds,
+		CreatedAt:        tx.CreatedAt,
+		UpdatedAt:        tx.UpdatedAt,
+	}
+}
+
+// newTransactionResponses maps a list of transactions, never returning nil
+func newTransactionResponses(txs []*db.Transaction, now time.Time) []transactionResponse {
+	responses := make([]transactionResponse, 0, len(txs))
+	for _, tx := range txs {
+		responses = append(responses, newTransactionResponse(tx, now))
+	}
+	return responses
+}
+
+// transactionDetailResponse is a transaction with, when requested, its meter
+// values. MeterValues is a pointer so a requested but empty list is still
+// returned as [].
+type transactionDetailResponse struct {
+	transactionResponse
+	MeterValues *[]meterValueResponse `json:"meter_values,omitempty"`
+}
+
+// chargerErrorResponse is a charger error as returned by the API
+type chargerErrorResponse struct {
+	ID               int        `json:"id"`
+	ChargerID        string     `json:"charger_id"`
+	ConnectorID      *int       `json:"connector_id"`
+	ErrorCode        string     `json:"error_code"`
+	VendorErrorCode  string     `json:"vendor_error_code"`
+	ErrorDescription string     `json:"error_description"`
+	VendorErrorInfo  string     `json:"vendor_error_info"`
+	Timestamp        time.Time  `json:"timestamp"`
+	Resolved         bool       `json:"resolved"`
+	ResolvedAt       *time.Time `json:"resolved_at"`
+}
+
+// newChargerErrorResponse maps a charger error to its API representation
+func newChargerErrorResponse(cerr *db.ChargerError) chargerErrorResponse {
+	return chargerErrorResponse{
+		ID:               cerr.ID,
+		ChargerID:        cerr.ChargerID,
+		ConnectorID:      cerr.ConnectorID,
+		ErrorCode:        cerr.ErrorCode,
+		VendorErrorCode:  cerr.VendorErrorCode,
+		ErrorDescription: cerr.ErrorDescription,
+		VendorErrorInfo:  cerr.VendorErrorInfo,
+		Timestamp:        cerr.Timestamp,
+		Resolved:         cerr.ResolvedAt != nil,
+		ResolvedAt:       cerr.ResolvedAt,
+	}
+}
+
+// newChargerErrorResponses maps a list of charger errors, never returning nil
+func newChargerErrorResponses(errs []*db.ChargerError) []chargerErrorResponse {
+	responses := make([]chargerErrorResponse, 0, len(errs))
+	for _, cerr := range errs {
+		responses = append(responses, newChargerErrorResponse(cerr))
+	}
+	return responses
+}
+
+// siteResponse is a site as returned by the API
+type siteResponse struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	MaxCurrent float64   `json:"max_current"`
+	ChargerIDs []string  `json:"charger_ids,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// newSiteResponse maps a site and, when loaded, its charger ids to their API
+// representation
+func newSiteResponse(site *db.Site, chargerIDs []string) siteResponse {
+	return siteResponse{
+		ID:         site.ID,
+		Name:       site.Name,
+		MaxCurrent: site.MaxCurrent,
+		ChargerIDs: chargerIDs,
+		CreatedAt:  site.CreatedAt,
+		UpdatedAt:  site.UpdatedAt,
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChargerResponse(t *testing.T) {
+	heartbeat := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	charger := &db.Charger{
+		ID:              "CP-1",
+		Vendor:          "Acme",
+		ICCID:           "8901",
+		IMSI:            "3101",
+		IsConnected:     true,
+		NumConnectors:   2,
+		LastHeartbeatAt: &heartbeat,
+	}
+
+	body, err := json.Marshal(newChargerResponse(charger))
+	require.NoError(t, err)
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &fields))
+	assert.Equal(t, "CP-1", fields["id"])
+	assert.Equal(t, true, fields["is_connected"])
+	assert.Equal(t, "2024-07-01T12:00:00Z", fields["last_heartbeat_at"])
+	assert.Nil(t, fields["last_boot_at"], "unset timestamps are null")
+	assert.Contains(t, fields, "last_boot_at")
+	assert.NotContains(t, fields, "iccid")
+	assert.NotContains(t, fields, "imsi")
+}
+
+func TestTransactionResponse(t *testing.T) {
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(90 * time.Minute)
+	ocppID := 42
+
+	t.Run("active", func(t *testing.T) {
+		resp := newTransactionResponse(&db.Transaction{
+			ID:            1,
+			TransactionID: &ocppID,
+			StartTime:     start,
+			Status:        db.TransactionStatusActive,
+		}, now)
+		assert.Equal(t, &ocppID, resp.TransactionID)
+		assert.Nil(t, resp.StopTime)
+		assert.Nil(t, resp.MeterStop)
+		assert.Equal(t, int64(90*60), resp.DurationSeconds, "active sessions run until now")
+		assert.Zero(t, resp.EnergyKWh)
+	})
+
+	t.Run("completed", func(t *testing.T) {
+		stop := start.Add(45 * time.Minute)
+		meterStop := 13500
+		resp := newTransactionResponse(&db.Transaction{
+			ID:              2,
+			StartTime:       start,
+			StopTime:        &stop,
+			MeterStart:      1000,
+			MeterStop:       &meterStop,
+			EnergyDelivered: 12500,
+			Status:          db.TransactionStatusCompleted,
+		}, now)
+		assert.Nil(t, resp.TransactionID)
+		assert.Equal(t, &meterStop, resp.MeterStop)
+		assert.Equal(t, int64(45*60), resp.DurationSeconds)
+		assert.Equal(t, 12.5, resp.EnergyKWh)
+	})
+
+	t.Run("clock skew never yields a negative duration", func(t *testing.T) {
+		resp := newTransactionResponse(&db.Transaction{StartTime: now.Add(time.Minute)}, now)
+		assert.Zero(t, resp.DurationSeconds)
+	})
+
+	assert.NotNil(t, newTransactionResponses(nil, now), "empty lists encode as []")
+}
+
+func TestChargerErrorResponse(t *testing.T) {
+	resolvedAt := time.Date(2024, 7, 1, 13, 0, 0, 0, time.UTC)
+	connector := 1
+
+	open := newChargerErrorResponse(&db.ChargerError{ID: 1, ErrorCode: "OverVoltage"})
+	assert.False(t, open.Resolved)
+	assert.Nil(t, open.ConnectorID)
+	assert.Nil(t, open.ResolvedAt)
+
+	resolved := newChargerErrorResponse(&db.ChargerError{ID: 2, ConnectorID: &connector, ResolvedAt: &resolvedAt})
+	assert.True(t, resolved.Resolved)
+	assert.Equal(t, &connector, resolved.ConnectorID)
+	assert.Equal(t, &resolvedAt, resolved.ResolvedAt)
+
+	assert.NotNil(t, newChargerErrorResponses(nil))
+}
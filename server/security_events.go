@@ -0,0 +1,56 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// listSecurityEvents lists charger security events, newest first
+func (s *Server) listSecurityEvents(c *gin.Context) {
+	filter := db.SecurityEventFilter{
+		ChargerID: c.Query("charger_id"),
+		Type:      c.Query("type"),
+	}
+
+	var err error
+	if raw := c.Query("critical"); raw != "" {
+		critical, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid critical parameter"})
+			return
+		}
+		filter.Critical = &critical
+	}
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since parameter"})
+		return
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until parameter"})
+		return
+	}
+	if filter.Limit, err = queryInt(c, "limit"); err != nil || filter.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+	if filter.Offset, err = queryInt(c, "offset"); err != nil || filter.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return
+	}
+
+	events, err := s.coreSystem.GetRepositories().SecurityEvents().List(c.Request.Context(), filter)
+	if err != nil {
+		s.logger.Error("Failed to list security events", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list security events"})
+		return
+	}
+	if events == nil {
+		events = []*db.SecurityEvent{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"security_events": events})
+}
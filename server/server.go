@@ -2,24 +2,42 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/keeth/levity/config"
 	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/core/clock"
+	"github.com/keeth/levity/db"
 	"github.com/keeth/levity/monitoring"
+	"github.com/keeth/levity/server/ocppconn"
+	"github.com/keeth/levity/version"
 )
 
 // Server represents the HTTP server for the MCPP Central System
 type Server struct {
-	config     *config.Config
-	coreSystem *core.System
-	metrics    *monitoring.Metrics
-	logger     *slog.Logger
-	httpServer *http.Server
-	router     *gin.Engine
+	config           *config.Config
+	coreSystem       *core.System
+	metrics          *monitoring.Metrics
+	registry         ocppconn.Registry
+	upgrader         websocket.Upgrader
+	chargerIDPattern *regexp.Regexp
+	confirmations    *confirmationStore
+	authorizer       ConnectionAuthorizer
+	listening        atomic.Bool
+	recomputing      atomic.Bool
+	logger           *slog.Logger
+	httpServer       *http.Server
+	router           *gin.Engine
 }
 
 // NewServer creates a new server instance
@@ -34,28 +52,45 @@ func NewServer(cfg *config.Config, coreSystem *core.System, metrics *monitoring.
 	router := gin.New()
 
 	// Add middleware
-	router.Use(gin.Recovery())
+	router.Use(requestIDMiddleware())
 	router.Use(loggingMiddleware(logger))
-	router.Use(corsMiddleware())
+	router.Use(queryStatsMiddleware(logger))
+	router.Use(recoveryMiddleware(metrics, logger))
+	router.Use(corsMiddleware(cfg.Server.CORS.AllowedOrigins))
+	router.Use(timeoutMiddleware(cfg.Server.RequestTimeout))
+
+	// The core system is absent in tests that only exercise the HTTP layer
+	clk := clock.Real()
+	if coreSystem != nil {
+		clk = coreSystem.GetClock()
+	}
 
 	server := &Server{
-		config:     cfg,
-		coreSystem: coreSystem,
-		metrics:    metrics,
-		logger:     logger,
-		router:     router,
+		config:           cfg,
+		coreSystem:       coreSystem,
+		metrics:          metrics,
+		registry:         ocppconn.NewMemoryRegistry(metrics),
+		upgrader:         newUpgrader(cfg.Server.CORS.AllowedOrigins, cfg.OCPP.HandshakeTimeout),
+		chargerIDPattern: compileChargerIDPattern(cfg.OCPP.ChargerIDPattern),
+		confirmations:    newConfirmationStore(clk, cfg.Auth.ConfirmTokenTTL),
+		authorizer:       newConnectionAuthorizer(cfg.OCPP),
+		logger:           logger,
+		router:           router,
 	}
 
 	// Setup routes
 	server.setupRoutes()
 
-	// Create HTTP server
+	// Create HTTP server. The header read timeout bounds the WebSocket
+	// handshake of chargers that connect and then stall.
 	server.httpServer = &http.Server{
-		Addr:           cfg.Server.Address,
-		Handler:        router,
-		ReadTimeout:    cfg.Server.ReadTimeout,
-		WriteTimeout:   cfg.Server.WriteTimeout,
-		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+		Addr:              cfg.Server.Address,
+		Handler:           markServed(router),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.OCPP.HandshakeTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		ConnContext:       handshakeConnContext,
 	}
 
 	return server
@@ -65,28 +100,101 @@ func NewServer(cfg *config.Config, coreSystem *core.System, metrics *monitoring.
 func (s *Server) setupRoutes() {
 	// Health check endpoint
 	s.router.GET("/health", s.healthCheck)
+	s.router.GET("/health/ready", s.readinessCheck)
+
+	// Version endpoint
+	s.router.GET("/version", s.versionHandler)
 
-	// Metrics endpoint
-	s.router.GET("/metrics", s.metricsHandler)
+	// API description
+	s.router.GET("/openapi.json", s.openAPIHandler)
+	s.router.GET("/docs", s.docsHandler)
+
+	// Metrics endpoint, admin-only when auth is enabled and required
+	if s.config.Monitoring.RequireAuth && s.config.Auth.Enabled() {
+		s.router.GET("/metrics", requireRole(s.config.Auth, RoleAdmin), s.metricsHandler)
+	} else {
+		s.router.GET("/metrics", s.metricsHandler)
+	}
 
 	// OCPP WebSocket endpoint
-	s.router.GET("/ocpp/:chargePointId", s.ocppWebSocketHandler)
+	s.router.GET(ocppRoutePath, s.ocppWebSocketHandler)
 
 	// API endpoints
 	api := s.router.Group("/api/v1")
 	{
 		api.GET("/chargepoints", s.listChargePoints)
+		api.POST("/chargepoints", requireRole(s.config.Auth, RoleOperator), s.createChargePoint)
 		api.GET("/chargepoints/:id", s.getChargePoint)
+		api.PATCH("/chargepoints/:id", requireRole(s.config.Auth, RoleOperator), s.updateChargePoint)
+		api.PUT("/chargepoints/:id/notes", requireRole(s.config.Auth, RoleOperator), s.updateChargePointNotes)
+		api.POST("/chargepoints/:id/local-list", requireRole(s.config.Auth, RoleOperator), s.sendLocalList)
+		api.POST("/chargepoints/:id/availability", requireRole(s.config.Auth, RoleOperator), s.changeAvailability)
+		api.POST("/chargepoints/:id/remote-start", requireRole(s.config.Auth, RoleOperator), s.remoteStart)
+		api.GET("/chargepoints/:id/configuration", requireRole(s.config.Auth, RoleOperator), s.getConfiguration)
+		api.POST("/chargepoints/:id/reset", requireRole(s.config.Auth, RoleOperator), s.reset)
+		api.POST("/chargepoints/:id/trigger/meter-values", requireRole(s.config.Auth, RoleOperator), s.triggerMeterValues)
+		api.POST("/chargepoints/:id/firmware/signed-update", requireRole(s.config.Auth, RoleOperator), s.signedUpdateFirmware)
+		api.POST("/chargepoints/:id/logs", requireRole(s.config.Auth, RoleOperator), s.getLog)
+		api.GET("/chargepoints/:id/file-transfers", s.listFileTransfers)
+		api.GET("/chargepoints/:id/connections", s.listConnections)
+		api.POST("/chargepoints/:id/disconnect", requireRole(s.config.Auth, RoleOperator), s.disconnectChargePoint)
+		api.GET("/chargepoints/:id/meter-values/series", s.getMeterValueSeries)
+		api.GET("/chargepoints/:id/measurands", s.listMeasurands)
+		api.GET("/chargepoints/:id/commands/history", s.listCommandHistory)
+		api.GET("/sites", s.listSites)
+		api.POST("/sites", requireRole(s.config.Auth, RoleOperator), s.createSite)
+		api.GET("/sites/:id", s.getSite)
+		api.PATCH("/sites/:id", requireRole(s.config.Auth, RoleOperator), s.updateSite)
+		api.DELETE("/sites/:id", requireRole(s.config.Auth, RoleOperator), s.deleteSite)
+		api.GET("/sites/:id/power", s.getSitePower)
+		api.PUT("/sites/:id/chargers/:charger_id", requireRole(s.config.Auth, RoleOperator), s.addSiteCharger)
+		api.DELETE("/sites/:id/chargers/:charger_id", requireRole(s.config.Auth, RoleOperator), s.removeSiteCharger)
 		api.GET("/transactions", s.listTransactions)
 		api.GET("/transactions/:id", s.getTransaction)
+		api.POST("/transactions/:id/remote-stop", requireRole(s.config.Auth, RoleOperator), s.remoteStop)
+		api.GET("/errors", s.listErrors)
+		api.POST("/errors/resolve", requireRole(s.config.Auth, RoleOperator), s.resolveErrors)
+		api.GET("/security-events", s.listSecurityEvents)
+		api.GET("/stats/top-errors", s.getTopErrors)
 		api.GET("/status", s.getSystemStatus)
 	}
+
+	// Admin endpoints
+	admin := s.router.Group("/admin", requireRole(s.config.Auth, RoleAdmin))
+	{
+		admin.GET("/confirm/:operation", s.issueConfirmation)
+		admin.POST("/reconcile", s.reconcile)
+		admin.POST("/connections/reset", s.resetConnections)
+		admin.POST("/transactions/recompute-energy", s.recomputeEnergyBulk)
+		admin.POST("/transactions/:id/recompute-energy", s.recomputeEnergy)
+		admin.GET("/audit", s.listAudit)
+		admin.GET("/dead-letters", s.listDeadLetters)
+		admin.POST("/dead-letters/replay", s.replayDeadLetters)
+		admin.POST("/dead-letters/:id/replay", s.replayDeadLetter)
+		admin.POST("/maintenance", s.setMaintenance)
+	}
+}
+
+// Registry returns the registry of live charger connections
+func (s *Server) Registry() ocppconn.Registry {
+	return s.registry
 }
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	s.logger.Info("Starting HTTP server", slog.String("addr", s.config.Server.Address))
-	return s.httpServer.ListenAndServe()
+	listener, err := net.Listen("tcp", s.config.Server.Address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener until the server is shut down
+func (s *Server) Serve(listener net.Listener) error {
+	s.listening.Store(true)
+	defer s.listening.Store(false)
+	return s.httpServer.Serve(&handshakeListener{Listener: listener, metrics: s.metrics})
 }
 
 // Shutdown gracefully shuts down the server
@@ -98,11 +206,16 @@ func (s *Server) Shutdown(ctx context.Context) error {
 func (s *Server) healthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
-		"timestamp": time.Now().UTC(),
-		"version":   "1.0.0",
+		"timestamp": s.coreSystem.GetClock().Now().UTC(),
+		"version":   version.Version,
 	})
 }
 
+// versionHandler returns the build metadata
+func (s *Server) versionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
+
 // metricsHandler handles metrics requests
 func (s *Server) metricsHandler(c *gin.Context) {
 	if s.metrics != nil {
@@ -120,18 +233,83 @@ func (s *Server) ocppWebSocketHandler(c *gin.Context) {
 		return
 	}
 
-	// Upgrade to WebSocket connection
-	// This will be implemented in the OCPP server package
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "WebSocket upgrade not yet implemented"})
+	if !s.validChargerID(chargePointId) {
+		s.logger.Warn("Rejected WebSocket with invalid charge point ID",
+			slog.String("charger_id", chargePointId),
+			slog.String("remote_addr", c.Request.RemoteAddr))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid charge point ID"})
+		return
+	}
+
+	if !s.upgrader.CheckOrigin(c.Request) {
+		s.logger.Warn("Rejected WebSocket from disallowed origin",
+			slog.String("charger_id", chargePointId),
+			slog.String("origin", c.GetHeader("Origin")))
+		c.JSON(http.StatusForbidden, gin.H{"error": "Origin not allowed"})
+		return
+	}
+
+	if !s.authorizeConnection(c, chargePointId) {
+		return
+	}
+
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		c.Header("Upgrade", "websocket")
+		c.JSON(http.StatusUpgradeRequired, gin.H{"error": "WebSocket upgrade required"})
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		s.logger.Warn("WebSocket upgrade failed",
+			slog.String("charger_id", chargePointId),
+			slog.Any("error", err))
+		return
+	}
+
+	s.serveOCPP(ws, chargePointId, c.ClientIP())
 }
 
 // listChargePoints lists all charge points
 func (s *Server) listChargePoints(c *gin.Context) {
-	// This will be implemented when we have the database layer
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Not yet implemented"})
+	opts, ok := queryListOptions(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+	opts.Limit = min(opts.Limit, maxListLimit)
+
+	ctx := c.Request.Context()
+	chargers, err := s.coreSystem.GetRepositories().Chargers().List(ctx, opts)
+	if err != nil {
+		s.logger.Error("Failed to list charge points", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list charge points"})
+		return
+	}
+
+	total, err := s.coreSystem.GetRepositories().Chargers().Count(ctx)
+	if err != nil {
+		s.logger.Error("Failed to count charge points", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list charge points"})
+		return
+	}
+
+	responses := make([]chargerResponse, 0, len(chargers))
+	for _, charger := range chargers {
+		responses = append(responses, newChargerResponse(charger))
+	}
+	c.Header(totalCountHeader, strconv.Itoa(total))
+	c.JSON(http.StatusOK, gin.H{
+		"chargepoints": responses,
+		"total":        total,
+		"limit":        opts.Limit,
+		"offset":       opts.Offset,
+	})
 }
 
-// getChargePoint gets a specific charge point
+// getChargePoint gets a charge point with its connectors, the latest meter
+// value of each, and its active errors
 func (s *Server) getChargePoint(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -139,38 +317,82 @@ func (s *Server) getChargePoint(c *gin.Context) {
 		return
 	}
 
-	// This will be implemented when we have the database layer
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Not yet implemented"})
-}
+	ctx := c.Request.Context()
+	repos := s.coreSystem.GetRepositories()
+	charger, err := repos.Chargers().GetByID(ctx, id)
+	if errors.Is(err, db.ErrChargerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get charge point", slog.String("charger_id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get charge point"})
+		return
+	}
 
-// listTransactions lists all transactions
-func (s *Server) listTransactions(c *gin.Context) {
-	// This will be implemented when we have the database layer
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Not yet implemented"})
-}
+	connectors, err := repos.Connectors().GetByChargerID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get connectors", slog.String("charger_id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get charge point"})
+		return
+	}
 
-// getTransaction gets a specific transaction
-func (s *Server) getTransaction(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Transaction ID is required"})
+	detail := chargerDetailResponse{
+		chargerResponse: newChargerResponse(charger),
+		Connectors:      make([]connectorResponse, 0, len(connectors)),
+	}
+	for _, connector := range connectors {
+		latest, err := repos.MeterValues().GetLatestByConnector(ctx, id, connector.ConnectorID)
+		if err != nil {
+			s.logger.Error("Failed to get latest meter value", slog.String("charger_id", id), slog.Int("connector_id", connector.ConnectorID), slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get charge point"})
+			return
+		}
+		detail.Connectors = append(detail.Connectors, newConnectorResponse(connector, latest))
+	}
+
+	activeErrors, err := repos.Errors().GetActiveByChargerID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get active errors", slog.String("charger_id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get charge point"})
 		return
 	}
+	detail.ActiveErrors = newChargerErrorResponses(activeErrors)
 
-	// This will be implemented when we have the database layer
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Not yet implemented"})
+	c.JSON(http.StatusOK, detail)
 }
 
+// maintenanceBanner is shown by the status endpoint while maintenance mode is on
+const maintenanceBanner = "Maintenance in progress: new charging sessions are not being accepted"
+
 // getSystemStatus gets the overall system status
 func (s *Server) getSystemStatus(c *gin.Context) {
-	// This will be implemented when we have the monitoring layer
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Not yet implemented"})
+	maintenance, err := s.coreSystem.MaintenanceMode(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to get maintenance mode", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get system status"})
+		return
+	}
+
+	status := gin.H{
+		"status":      "ok",
+		"timestamp":   s.coreSystem.GetClock().Now().UTC(),
+		"maintenance": maintenance,
+	}
+	if maintenance {
+		status["status"] = "maintenance"
+		status["banner"] = maintenanceBanner
+	}
+
+	c.JSON(http.StatusOK, status)
 }
 
 // loggingMiddleware adds logging to all requests
 func loggingMiddleware(logger *slog.Logger) gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		requestID, _ := param.Keys[requestIDKey].(string)
 		logger.Info("HTTP Request",
+			slog.String("request_id", requestID),
 			slog.String("client_ip", param.ClientIP),
 			slog.String("timestamp", param.TimeStamp.Format(time.RFC3339)),
 			slog.String("method", param.Method),
@@ -185,12 +407,22 @@ func loggingMiddleware(logger *slog.Logger) gin.HandlerFunc {
 	})
 }
 
-// corsMiddleware adds CORS headers
-func corsMiddleware() gin.HandlerFunc {
+// corsMiddleware adds CORS headers, echoing the request origin when an allow list is configured
+func corsMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowed := originSet(allowedOrigins)
+
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
+		origin := c.GetHeader("Origin")
+		switch {
+		case len(allowed) == 0 || allowed["*"]:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case allowed[strings.ToLower(origin)]:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Expose-Headers", totalCountHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
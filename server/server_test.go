@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/keeth/levity/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+	defer func(v, c string) { version.Version, version.Commit = v, c }(version.Version, version.Commit)
+	version.Version, version.Commit = "1.2.3", "abc123"
+
+	srv := NewServer(&config.Config{}, nil, nil, dbtest.Logger())
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var info version.Info
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "1.2.3", info.Version)
+	assert.Equal(t, "abc123", info.Commit)
+	assert.NotEmpty(t, info.BuildDate)
+	assert.NotEmpty(t, info.GoVersion)
+}
+
+type readinessResponse struct {
+	Status     string `json:"status"`
+	Subsystems struct {
+		Database subsystemStatus `json:"database"`
+		OCPP     ocppStatus      `json:"ocpp"`
+	} `json:"subsystems"`
+}
+
+func getReadiness(t *testing.T, srv *Server) (int, readinessResponse) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var resp readinessResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestReadinessReportsOCPPSubsystem(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+
+	code, resp := getReadiness(t, srv)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, subsystemOK, resp.Subsystems.Database.Status)
+	assert.Equal(t, subsystemOK, resp.Subsystems.OCPP.Status)
+	assert.False(t, resp.Subsystems.OCPP.Listening)
+	assert.Zero(t, resp.Subsystems.OCPP.Connections)
+}
+
+func TestReadinessDegradedWithoutRegistry(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	srv.registry = nil
+
+	code, resp := getReadiness(t, srv)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "degraded", resp.Status)
+	assert.Equal(t, subsystemOK, resp.Subsystems.Database.Status)
+	assert.Equal(t, subsystemDegraded, resp.Subsystems.OCPP.Status)
+	assert.Equal(t, "connection registry not initialized", resp.Subsystems.OCPP.Error)
+}
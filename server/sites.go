@@ -0,0 +1,240 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// maxSiteIDLength bounds the operator-chosen id of a site
+const maxSiteIDLength = 64
+
+// createSiteRequest is the body of a site creation
+type createSiteRequest struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	MaxCurrent float64 `json:"max_current"`
+}
+
+// updateSiteRequest is the body of a site update; omitted fields are left
+// unchanged
+type updateSiteRequest struct {
+	Name       *string  `json:"name"`
+	MaxCurrent *float64 `json:"max_current"`
+}
+
+// listSites lists sites without their chargers
+func (s *Server) listSites(c *gin.Context) {
+	opts, ok := queryListOptions(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	sites, err := s.coreSystem.GetRepositories().Sites().List(c.Request.Context(), opts)
+	if err != nil {
+		s.logger.Error("Failed to list sites", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sites"})
+		return
+	}
+
+	responses := make([]siteResponse, 0, len(sites))
+	for _, site := range sites {
+		responses = append(responses, newSiteResponse(site, nil))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sites":  responses,
+		"limit":  opts.Limit,
+		"offset": opts.Offset,
+	})
+}
+
+// createSite creates a site with an amp limit shared by its chargers
+func (s *Server) createSite(c *gin.Context) {
+	var req createSiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.ID == "" || len(req.ID) > maxSiteIDLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid site ID"})
+		return
+	}
+	if req.MaxCurrent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max current"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	sites := s.coreSystem.GetRepositories().Sites()
+	existing, err := sites.GetByID(ctx, req.ID)
+	if err != nil {
+		s.logger.Error("Failed to get site", slog.String("site_id", req.ID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create site"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Site already exists"})
+		return
+	}
+
+	site, err := sites.Create(ctx, db.CreateSiteRequest{ID: req.ID, Name: req.Name, MaxCurrent: req.MaxCurrent})
+	if err != nil {
+		s.logger.Error("Failed to create site", slog.String("site_id", req.ID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create site"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newSiteResponse(site, []string{}))
+}
+
+// getSite gets a site with the ids of its chargers
+func (s *Server) getSite(c *gin.Context) {
+	site, ok := s.loadSite(c)
+	if !ok {
+		return
+	}
+
+	chargerIDs, err := s.coreSystem.GetRepositories().Sites().ChargerIDs(c.Request.Context(), site.ID)
+	if err != nil {
+		s.logger.Error("Failed to get site chargers", slog.String("site_id", site.ID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get site"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newSiteResponse(site, chargerIDs))
+}
+
+// updateSite changes the name or amp limit of a site
+func (s *Server) updateSite(c *gin.Context) {
+	var req updateSiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.MaxCurrent != nil && *req.MaxCurrent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max current"})
+		return
+	}
+
+	site, ok := s.loadSite(c)
+	if !ok {
+		return
+	}
+
+	site, err := s.coreSystem.GetRepositories().Sites().Update(c.Request.Context(), site.ID, db.UpdateSiteRequest{
+		Name:       req.Name,
+		MaxCurrent: req.MaxCurrent,
+	})
+	if err != nil {
+		s.logger.Error("Failed to update site", slog.String("site_id", c.Param("id")), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update site"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newSiteResponse(site, nil))
+}
+
+// deleteSite deletes a site; its chargers are left without a site
+func (s *Server) deleteSite(c *gin.Context) {
+	site, ok := s.loadSite(c)
+	if !ok {
+		return
+	}
+
+	if err := s.coreSystem.GetRepositories().Sites().Delete(c.Request.Context(), site.ID); err != nil {
+		s.logger.Error("Failed to delete site", slog.String("site_id", site.ID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete site"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// addSiteCharger adds a charger to a site, moving it out of any other site
+func (s *Server) addSiteCharger(c *gin.Context) {
+	site, ok := s.loadSite(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	chargerID := c.Param("charger_id")
+	if _, err := s.coreSystem.GetRepositories().Chargers().GetByID(ctx, chargerID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point not found"})
+		return
+	}
+
+	if err := s.coreSystem.GetRepositories().Sites().AddCharger(ctx, site.ID, chargerID); err != nil {
+		s.logger.Error("Failed to add charger to site",
+			slog.String("site_id", site.ID),
+			slog.String("charger_id", chargerID),
+			slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add charge point to site"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// removeSiteCharger removes a charger from a site
+func (s *Server) removeSiteCharger(c *gin.Context) {
+	site, ok := s.loadSite(c)
+	if !ok {
+		return
+	}
+
+	chargerID := c.Param("charger_id")
+	removed, err := s.coreSystem.GetRepositories().Sites().RemoveCharger(c.Request.Context(), site.ID, chargerID)
+	if err != nil {
+		s.logger.Error("Failed to remove charger from site",
+			slog.String("site_id", site.ID),
+			slog.String("charger_id", chargerID),
+			slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove charge point from site"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Charge point is not in the site"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// loadSite gets the site named by the id path parameter, answering 404 when
+// there is none
+func (s *Server) loadSite(c *gin.Context) (*db.Site, bool) {
+	siteID := c.Param("id")
+	site, err := s.coreSystem.GetRepositories().Sites().GetByID(c.Request.Context(), siteID)
+	if err != nil {
+		s.logger.Error("Failed to get site", slog.String("site_id", siteID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get site"})
+		return nil, false
+	}
+	if site == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return nil, false
+	}
+	return site, true
+}
+
+// getSitePower returns the aggregate power and current drawn by a site's
+// connected chargers and the headroom left under its limit
+func (s *Server) getSitePower(c *gin.Context) {
+	site, ok := s.loadSite(c)
+	if !ok {
+		return
+	}
+
+	power, err := s.coreSystem.SitePower().Read(c.Request.Context(), site)
+	if err != nil {
+		s.logger.Error("Failed to read site power", slog.String("site_id", site.ID), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read site power"})
+		return
+	}
+
+	c.JSON(http.StatusOK, power)
+}
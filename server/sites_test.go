@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// operatorRequest sends a request with the operator key
+func operatorRequest(srv *Server, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apiKeyHeader, testOperatorKey)
+	srv.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSiteEndpoints(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+
+	w := postCommand(srv, "/api/v1/sites", `{"id": "depot", "name": "Depot", "max_current": 63}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = postCommand(srv, "/api/v1/sites", `{"id": "depot"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = postCommand(srv, "/api/v1/sites", `{"id": "annex", "max_current": -1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = operatorRequest(srv, http.MethodPut, "/api/v1/sites/depot/chargers/CP-ONLINE", "")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	w = operatorRequest(srv, http.MethodPut, "/api/v1/sites/depot/chargers/CP-MISSING", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = operatorRequest(srv, http.MethodPut, "/api/v1/sites/annex/chargers/CP-ONLINE", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = patchCommand(srv, "/api/v1/sites/depot", `{"max_current": 80}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = operatorRequest(srv, http.MethodGet, "/api/v1/sites/depot", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var site siteResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &site))
+	assert.Equal(t, "Depot", site.Name)
+	assert.Equal(t, 80.0, site.MaxCurrent)
+	assert.Equal(t, []string{"CP-ONLINE"}, site.ChargerIDs)
+
+	w = operatorRequest(srv, http.MethodGet, "/api/v1/sites", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Sites []siteResponse `json:"sites"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Sites, 1)
+
+	w = operatorRequest(srv, http.MethodDelete, "/api/v1/sites/depot/chargers/CP-ONLINE", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = operatorRequest(srv, http.MethodDelete, "/api/v1/sites/depot/chargers/CP-ONLINE", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = operatorRequest(srv, http.MethodDelete, "/api/v1/sites/depot", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = operatorRequest(srv, http.MethodGet, "/api/v1/sites/depot", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSitePower(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ctx := context.Background()
+	repos := srv.coreSystem.GetRepositories()
+	srv.coreSystem.SetConnectionRegistry(staticRegistry{"CP-ONLINE": true, "CP-SECOND": true})
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-SECOND", NumConnectors: 2})
+	require.NoError(t, err)
+	for _, connectorID := range []int{1, 2} {
+		_, err = repos.Connectors().Create(ctx, "CP-SECOND", connectorID)
+		require.NoError(t, err)
+	}
+
+	_, err = repos.Sites().Create(ctx, db.CreateSiteRequest{ID: "depot", MaxCurrent: 63})
+	require.NoError(t, err)
+	for _, chargerID := range []string{"CP-ONLINE", "CP-OFFLINE", "CP-SECOND"} {
+		require.NoError(t, repos.Sites().AddCharger(ctx, "depot", chargerID))
+	}
+
+	now := srv.coreSystem.GetClock().Now()
+	sample := func(chargerID string, connectorID int, measurand string, value float64, unit string, age time.Duration) {
+		_, err := repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			ChargerID: chargerID, ConnectorID: connectorID, Timestamp: now.Add(-age),
+			Measurand: measurand, Value: dbtest.Float64(value), Unit: unit,
+		})
+		require.NoError(t, err)
+	}
+	charging := func(chargerID string, connectorID int) {
+		_, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: chargerID, ConnectorID: connectorID, IDTag: "TAG"})
+		require.NoError(t, err)
+	}
+
+	// CP-ONLINE draws 7.4 kW / 32 A; only its latest readings count
+	charging("CP-ONLINE", 1)
+	sample("CP-ONLINE", 1, core.MeasurandPowerActiveImport, 3.7, "kW", 2*time.Minute)
+	sample("CP-ONLINE", 1, core.MeasurandPowerActiveImport, 7.4, "kW", time.Minute)
+	sample("CP-ONLINE", 1, core.MeasurandCurrentImport, 32, "A", time.Minute)
+
+	// CP-SECOND charges on connector 1; connector 2 is idle with a stale reading
+	charging("CP-SECOND", 1)
+	sample("CP-SECOND", 1, core.MeasurandPowerActiveImport, 3680, "W", time.Minute)
+	sample("CP-SECOND", 1, core.MeasurandCurrentImport, 16, "A", time.Minute)
+	sample("CP-SECOND", 2, core.MeasurandPowerActiveImport, 11000, "W", time.Hour)
+	sample("CP-SECOND", 2, core.MeasurandCurrentImport, 16, "A", time.Hour)
+
+	// CP-OFFLINE is not connected, so its readings are ignored
+	charging("CP-OFFLINE", 1)
+	sample("CP-OFFLINE", 1, core.MeasurandCurrentImport, 32, "A", time.Minute)
+
+	w := operatorRequest(srv, http.MethodGet, "/api/v1/sites/depot/power", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var power core.SitePower
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &power))
+	assert.Equal(t, 63.0, power.MaxCurrent)
+	assert.InDelta(t, 11080, power.Power, 0.001)
+	assert.Equal(t, 48.0, power.Current)
+	require.NotNil(t, power.Headroom)
+	assert.Equal(t, 15.0, *power.Headroom)
+	require.Len(t, power.Chargers, 3)
+	assert.Equal(t, core.ChargerPower{ChargerID: "CP-OFFLINE"}, power.Chargers[0])
+	assert.Equal(t, core.ChargerPower{ChargerID: "CP-SECOND", Connected: true, Power: 3680, Current: 16}, power.Chargers[2])
+
+	w = operatorRequest(srv, http.MethodGet, "/api/v1/sites/annex/power", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
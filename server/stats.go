@@ -0,0 +1,64 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/db"
+)
+
+// Defaults for the top error codes window
+const (
+	defaultTopErrorsWindow = 24 * time.Hour
+	defaultTopErrorsLimit  = 10
+)
+
+// getTopErrors returns the most frequent error codes between since and until
+// (RFC 3339), defaulting to the last 24 hours
+func (s *Server) getTopErrors(c *gin.Context) {
+	until, err := queryTime(c, "until")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until parameter"})
+		return
+	}
+	since, err := queryTime(c, "since")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since parameter"})
+		return
+	}
+	limit, err := queryInt(c, "limit")
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	end := s.coreSystem.GetClock().Now()
+	if until != nil {
+		end = *until
+	}
+	start := end.Add(-defaultTopErrorsWindow)
+	if since != nil {
+		start = *since
+	}
+	if limit == 0 {
+		limit = defaultTopErrorsLimit
+	}
+
+	counts, err := s.coreSystem.GetRepositories().Errors().TopErrorCodes(c.Request.Context(), start, end, limit)
+	if err != nil {
+		s.logger.Error("Failed to get top error codes", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top error codes"})
+		return
+	}
+	if counts == nil {
+		counts = []db.ErrorCodeCount{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since":       start,
+		"until":       end,
+		"error_codes": counts,
+	})
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(timeoutMiddleware(20 * time.Millisecond))
+
+	// slow stands in for a handler blocked on a database query that is
+	// cancelled with the request context
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query cancelled"})
+		case <-time.After(time.Second):
+			c.JSON(http.StatusOK, gin.H{"status": "done"})
+		}
+	}
+	router.GET("/slow", slow)
+	router.GET("/fast", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "done"})
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		time.Sleep(40 * time.Millisecond)
+		assert.NoError(t, c.Request.Context().Err(), "streaming routes have no deadline")
+		c.String(http.StatusOK, "streamed")
+	})
+	streamingRoutes["/stream"] = true
+	defer delete(streamingRoutes, "/stream")
+
+	t.Run("slow handler times out", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		assert.JSONEq(t, `{"error": "Request timed out"}`, w.Body.String())
+	})
+
+	t.Run("fast handler is unaffected", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"status": "done"}`, w.Body.String())
+	})
+
+	t.Run("streaming routes are exempt", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "streamed", w.Body.String())
+	})
+}
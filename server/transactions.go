@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+)
+
+// listTransactions searches transactions by charger_id, connector_id, id_tag,
+// status and an RFC 3339 since/until range on the start time
+func (s *Server) listTransactions(c *gin.Context) {
+	filter, ok := queryTransactionFilter(c)
+	if !ok {
+		return
+	}
+
+	opts, ok := queryListOptions(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	transactions, err := s.coreSystem.GetRepositories().Transactions().Search(c.Request.Context(), filter, opts)
+	if err != nil {
+		s.logger.Error("Failed to search transactions", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list transactions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": newTransactionResponses(transactions, s.coreSystem.GetClock().Now()),
+		"limit":        opts.Limit,
+		"offset":       opts.Offset,
+	})
+}
+
+// maxTransactionMeterValues caps the meter values included with a transaction
+const maxTransactionMeterValues = 1000
+
+// getTransaction gets a transaction by its database id, with its first
+// meter values in time order when include=meter_values is passed
+func (s *Server) getTransaction(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	includeMeterValues := false
+	if include := c.Query("include"); include != "" {
+		for _, name := range strings.Split(include, ",") {
+			if name != "meter_values" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid include parameter"})
+				return
+			}
+			includeMeterValues = true
+		}
+	}
+
+	ctx := c.Request.Context()
+	repos := s.coreSystem.GetRepositories()
+	tx, err := repos.Transactions().GetByID(ctx, id)
+	if errors.Is(err, db.ErrTransactionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get transaction", slog.Int("id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get transaction"})
+		return
+	}
+
+	detail := transactionDetailResponse{transactionResponse: newTransactionResponse(tx, s.coreSystem.GetClock().Now())}
+	if includeMeterValues {
+		values, err := repos.MeterValues().GetByTransactionID(ctx, tx.ID, db.ListOptions{Limit: maxTransactionMeterValues})
+		if err != nil {
+			s.logger.Error("Failed to get transaction meter values", slog.Int("id", id), slog.Any("error", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get transaction"})
+			return
+		}
+		meterValues := newMeterValueResponses(values)
+		detail.MeterValues = &meterValues
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// queryTransactionFilter parses the transaction search parameters, responding
+// 400 and returning false when one is invalid
+func queryTransactionFilter(c *gin.Context) (db.TransactionFilter, bool) {
+	filter := db.TransactionFilter{
+		ChargerID: c.Query("charger_id"),
+		IDTag:     c.Query("id_tag"),
+		Status:    db.TransactionStatus(c.Query("status")),
+	}
+
+	if c.Query("connector_id") != "" {
+		connectorID, err := queryInt(c, "connector_id")
+		if err != nil || connectorID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connector_id parameter"})
+			return filter, false
+		}
+		filter.ConnectorID = &connectorID
+	}
+
+	var err error
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since parameter"})
+		return filter, false
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until parameter"})
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// recomputeEnergy recomputes a transaction's energy_delivered from its meter
+// values, or its meter start and stop, and stores it
+func (s *Server) recomputeEnergy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.coreSystem.GetRepositories().Transactions().GetByID(ctx, id)
+	if errors.Is(err, db.ErrTransactionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get transaction", slog.Int("id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recompute energy"})
+		return
+	}
+
+	result, err := s.coreSystem.Energy().Recompute(ctx, tx)
+	if err != nil {
+		s.logger.Error("Failed to recompute energy", slog.Int("id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recompute energy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// remoteStop asks the charger of an active transaction to stop it
+func (s *Server) remoteStop(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid validate parameter"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.coreSystem.GetRepositories().Transactions().GetByID(ctx, id)
+	if errors.Is(err, db.ErrTransactionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get transaction", slog.Int("id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remote stop"})
+		return
+	}
+
+	if validate {
+		s.validateCommand(c, ocpp.ActionRemoteStopTransaction, tx.ChargerID)
+		return
+	}
+
+	status, err := s.coreSystem.RemoteControl().RemoteStop(ctx, tx)
+	if err != nil {
+		switch {
+		case errors.Is(err, core.ErrTransactionNotActive):
+			c.JSON(http.StatusConflict, gin.H{"error": "Transaction is not active"})
+		case errors.Is(err, ocpp.ErrNotConnected):
+			c.JSON(http.StatusConflict, gin.H{"error": "Charge point is not connected"})
+		case errors.Is(err, context.DeadlineExceeded):
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Charge point did not answer in time"})
+		default:
+			s.logger.Error("Failed to remote stop", slog.Int("id", id), slog.Any("error", err))
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to remote stop"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
+
+// recomputeEnergyBulk starts a background recompute of every transaction
+// matching the same filters as the transaction search; the outcome is logged.
+// Only one bulk recompute runs at a time.
+func (s *Server) recomputeEnergyBulk(c *gin.Context) {
+	filter, ok := queryTransactionFilter(c)
+	if !ok {
+		return
+	}
+
+	if !s.recomputing.CompareAndSwap(false, true) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Energy recompute already running"})
+		return
+	}
+
+	started := s.coreSystem.Go(func(ctx context.Context) {
+		defer s.recomputing.Store(false)
+
+		result, err := s.coreSystem.Energy().RecomputeAll(ctx, filter)
+		if err != nil {
+			s.logger.Error("Bulk energy recompute failed",
+				slog.Int("checked", result.Checked),
+				slog.Int("updated", result.Updated),
+				slog.Any("error", err))
+			return
+		}
+		s.logger.Info("Bulk energy recompute completed",
+			slog.Int("checked", result.Checked),
+			slog.Int("updated", result.Updated))
+	})
+	if !started {
+		s.recomputing.Store(false)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "System is not running"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "started"})
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecomputeEnergyBulk(t *testing.T) {
+	srv := newConfirmTestServer(t)
+	path := "/admin/transactions/recompute-energy"
+
+	// Nothing can start before the system is running
+	w := adminRequest(srv, http.MethodPost, path, "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, w.Body.String())
+
+	srv.coreSystem.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.coreSystem.Shutdown(ctx)
+	}()
+
+	// A run in flight rejects another
+	srv.recomputing.Store(true)
+	w = adminRequest(srv, http.MethodPost, path, "")
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	srv.recomputing.Store(false)
+
+	w = adminRequest(srv, http.MethodPost, path, "")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Eventually(t, func() bool { return !srv.recomputing.Load() }, time.Second, 10*time.Millisecond)
+}
+
+func TestGetTransaction(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	ctx := context.Background()
+	repos := srv.coreSystem.GetRepositories()
+	at := time.Date(2024, 11, 4, 9, 0, 0, 0, time.UTC)
+
+	_, err := repos.Chargers().Create(ctx, db.CreateChargerRequest{ID: "CP-1"})
+	require.NoError(t, err)
+	tx, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 1, IDTag: "TAG-1", StartTime: &at})
+	require.NoError(t, err)
+	for i, value := range []float64{1200, 1500} {
+		_, err = repos.MeterValues().Create(ctx, db.CreateMeterValueRequest{
+			TransactionID: &tx.ID, ChargerID: "CP-1", ConnectorID: 1, Timestamp: at.Add(time.Duration(i) * time.Minute),
+			Measurand: "Energy.Active.Import.Register", Value: dbtest.Float64(value), Unit: "Wh",
+		})
+		require.NoError(t, err)
+	}
+
+	get := func(path string) (*httptest.ResponseRecorder, map[string]json.RawMessage) {
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	path := "/api/v1/transactions/" + strconv.Itoa(tx.ID)
+	w, body := get(path)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, strconv.Itoa(tx.ID), string(body["id"]))
+	assert.NotContains(t, body, "meter_values")
+
+	w, _ = get(path + "?include=meter_values")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var detail transactionDetailResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "TAG-1", detail.IDTag)
+	require.NotNil(t, detail.MeterValues)
+	meterValues := *detail.MeterValues
+	require.Len(t, meterValues, 2)
+	assert.Equal(t, 1200.0, meterValues[0].Value)
+	assert.Equal(t, 1500.0, meterValues[1].Value)
+
+	// A transaction without meter values still lists them when asked
+	empty, err := repos.Transactions().Create(ctx, db.CreateTransactionRequest{ChargerID: "CP-1", ConnectorID: 2, IDTag: "TAG-1", StartTime: &at})
+	require.NoError(t, err)
+	w, body = get("/api/v1/transactions/" + strconv.Itoa(empty.ID) + "?include=meter_values")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `[]`, string(body["meter_values"]))
+
+	w, _ = get(path + "?include=connectors")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, body = get("/api/v1/transactions/9999")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `"Transaction not found"`, string(body["error"]))
+
+	w, _ = get("/api/v1/transactions/abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/keeth/levity/core"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/server/ocppconn"
+)
+
+// ocppSubprotocol is the WebSocket subprotocol of OCPP 1.6J
+const ocppSubprotocol = "ocpp1.6"
+
+// newUpgrader creates the WebSocket upgrader used for charger connections
+func newUpgrader(allowedOrigins []string, handshakeTimeout time.Duration) websocket.Upgrader {
+	return websocket.Upgrader{
+		HandshakeTimeout: handshakeTimeout,
+		Subprotocols:     []string{ocppSubprotocol},
+		CheckOrigin:      checkOrigin(allowedOrigins),
+	}
+}
+
+// serveOCPP runs an upgraded charger connection. The read loop runs under
+// System.Go so shutdown closes the socket and waits for it; if the system is
+// already stopping the charger is turned away.
+func (s *Server) serveOCPP(ws *websocket.Conn, chargerID, remoteIP string) {
+	if s.config.OCPP.MaxMessageSize > 0 {
+		ws.SetReadLimit(int64(s.config.OCPP.MaxMessageSize))
+	}
+	conn := ocppconn.NewWSConn(chargerID, ws, s.coreSystem.GetClock().Now(), s.logger)
+
+	started := s.coreSystem.Go(func(ctx context.Context) {
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		defer stop()
+
+		// Connection bookkeeping must complete even while shutting down
+		recordCtx := context.WithoutCancel(ctx)
+		connectionID := s.coreSystem.ChargerConnected(recordCtx, chargerID, core.ConnectionInfo{
+			RemoteIP:    remoteIP,
+			Subprotocol: ws.Subprotocol(),
+		})
+
+		handle := func(ctx context.Context, call ocpp.Call) (interface{}, error) {
+			return s.coreSystem.HandleCall(ctx, chargerID, call)
+		}
+		seq := ocppconn.NewSequencer(chargerID, s.config.OCPP.ConcurrentCallPolicy, handle, conn.WriteFrame, s.coreSystem.Go, s.logger)
+		seq.SetMetrics(s.metrics)
+
+		session := ocppconn.Open(s.registry, conn, s.metrics, func(reason string) {
+			s.coreSystem.ChargerDisconnected(recordCtx, chargerID, connectionID, reason)
+		}, s.logger)
+		session.Serve(func() error { return conn.ReadLoop(seq) })
+	})
+	if !started {
+		s.logger.Info("Turned away charger while shutting down", slog.String("charger_id", chargerID))
+		ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"),
+			time.Now().Add(time.Second))
+		ws.Close()
+	}
+}
+
+// checkOrigin returns an origin check for WebSocket upgrades. Requests without
+// an Origin header come from chargers rather than browsers and are always
+// allowed. Browser origins must appear in the allow list, or match the request
+// host when the list is empty.
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	allowed := originSet(allowedOrigins)
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		if len(allowed) == 0 {
+			u, err := url.Parse(origin)
+			return err == nil && strings.EqualFold(u.Host, r.Host)
+		}
+
+		return allowed["*"] || allowed[strings.ToLower(origin)]
+	}
+}
+
+// originSet normalizes configured origins for case-insensitive lookup
+func originSet(origins []string) map[string]bool {
+	set := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		set[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return set
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/keeth/levity/config"
+	"github.com/keeth/levity/core/ocpp"
+	"github.com/keeth/levity/db"
+	"github.com/keeth/levity/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	check := checkOrigin([]string{"https://dashboard.example.com/"})
+
+	request := func(origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://levity.example.com/ocpp/CP001", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return r
+	}
+
+	assert.True(t, check(request("")), "chargers send no Origin header")
+	assert.True(t, check(request("https://dashboard.example.com")))
+	assert.True(t, check(request("https://DASHBOARD.example.com")))
+	assert.False(t, check(request("https://evil.example.com")))
+	assert.False(t, check(request("https://levity.example.com")), "same host is not implied by an allow list")
+
+	sameOrigin := checkOrigin(nil)
+	assert.True(t, sameOrigin(request("https://levity.example.com")))
+	assert.False(t, sameOrigin(request("https://evil.example.com")))
+
+	assert.True(t, checkOrigin([]string{"*"})(request("https://anywhere.example.com")))
+}
+
+func TestOCPPWebSocketRejectsDisallowedOrigin(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.CORS.AllowedOrigins = []string{"https://dashboard.example.com"}
+	srv := NewServer(cfg, nil, nil, dbtest.Logger())
+
+	r := httptest.NewRequest(http.MethodGet, "/ocpp/CP001", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/ocpp/CP001", nil)
+	r.Header.Set("Origin", "https://dashboard.example.com")
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, r)
+	assert.NotEqual(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "https://dashboard.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestOCPPWebSocketValidatesChargerID(t *testing.T) {
+	srv := NewServer(&config.Config{}, nil, nil, dbtest.Logger())
+
+	tests := []struct {
+		path     string
+		accepted bool
+	}{
+		{"/ocpp/CP001", true},
+		{"/ocpp/site-1:bay_2.A", true},
+		{"/ocpp/" + strings.Repeat("a", 64), true},
+		{"/ocpp/" + strings.Repeat("a", 65), false},
+		{"/ocpp/CP%20001", false},
+		{"/ocpp/CP%27;DROP", false},
+		{"/ocpp/%E2%9A%A1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if tt.accepted {
+				assert.NotEqual(t, http.StatusBadRequest, w.Code)
+			} else {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+
+	// A configured pattern replaces the default
+	cfg := &config.Config{}
+	cfg.OCPP.ChargerIDPattern = `^CP[0-9]{3}$`
+	srv = NewServer(cfg, nil, nil, dbtest.Logger())
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ocpp/CP001", nil))
+	assert.NotEqual(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ocpp/site-1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOCPPWebSocketConnectionAuthorization(t *testing.T) {
+	var received ConnectionAuthRequest
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		switch received.ChargerID {
+		case "CP-PROVISIONED":
+			w.WriteHeader(http.StatusNoContent)
+		case "CP-UNKNOWN":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer authService.Close()
+
+	cfg := &config.Config{}
+	cfg.OCPP.ConnectionAuthURL = authService.URL
+	srv := NewServer(cfg, nil, nil, dbtest.Logger())
+
+	tests := []struct {
+		chargerID string
+		want      int
+	}{
+		{"CP-PROVISIONED", http.StatusUpgradeRequired}, // allowed through to the upgrade
+		{"CP-UNKNOWN", http.StatusForbidden},
+		{"CP-BROKEN", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.chargerID, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ocpp/"+tt.chargerID, nil)
+			r.Header.Set("Authorization", "Basic Q1AtMTpzZWNyZXQ=")
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+			assert.Equal(t, tt.chargerID, received.ChargerID)
+			assert.Equal(t, "Basic Q1AtMTpzZWNyZXQ=", received.Headers.Get("Authorization"))
+		})
+	}
+}
+
+// denyAuthorizer rejects every charger
+type denyAuthorizer struct{}
+
+func (denyAuthorizer) Authorize(ctx context.Context, chargerID string, header http.Header) (bool, error) {
+	return false, nil
+}
+
+func TestOCPPWebSocketCustomAuthorizer(t *testing.T) {
+	srv := NewServer(&config.Config{}, nil, nil, dbtest.Logger())
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ocpp/CP001", nil))
+	assert.NotEqual(t, http.StatusForbidden, w.Code, "every charger is allowed by default")
+
+	srv.SetConnectionAuthorizer(denyAuthorizer{})
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ocpp/CP001", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// dialCharger connects to the server's OCPP endpoint as a charger
+func dialCharger(t *testing.T, url, chargerID string) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: []string{ocppSubprotocol}}
+	ws, resp, err := dialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/ocpp/"+chargerID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ocppSubprotocol, resp.Header.Get("Sec-WebSocket-Protocol"))
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// readFrame reads one OCPP-J frame from a charger connection
+func readFrame(t *testing.T, ws *websocket.Conn) []json.RawMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var frame []json.RawMessage
+	require.NoError(t, ws.ReadJSON(&frame))
+	return frame
+}
+
+func TestOCPPWebSocketSession(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	system := srv.coreSystem
+	system.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		system.Shutdown(ctx)
+	}()
+
+	httpServer := httptest.NewServer(srv.router)
+	defer httpServer.Close()
+	ws := dialCharger(t, httpServer.URL, "CP-WS")
+
+	// Inbound CALLs are routed to the OCPP handlers
+	require.NoError(t, ws.WriteJSON([]interface{}{ocpp.MessageTypeCall, "boot-1", ocpp.ActionBootNotification,
+		map[string]string{"chargePointVendor": "Acme", "chargePointModel": "X1"}}))
+	frame := readFrame(t, ws)
+	require.Len(t, frame, 3)
+	assert.JSONEq(t, `3`, string(frame[0]))
+	assert.JSONEq(t, `"boot-1"`, string(frame[1]))
+	var boot ocpp.BootNotificationResponse
+	require.NoError(t, json.Unmarshal(frame[2], &boot))
+	assert.Equal(t, ocpp.RegistrationAccepted, boot.Status)
+
+	// Unknown actions are answered with NotImplemented
+	require.NoError(t, ws.WriteJSON([]interface{}{ocpp.MessageTypeCall, "x-1", "Unknown", struct{}{}}))
+	frame = readFrame(t, ws)
+	assert.JSONEq(t, `4`, string(frame[0]))
+	assert.JSONEq(t, `"`+ocpp.ErrorCodeNotImplemented+`"`, string(frame[2]))
+
+	// Outbound CALLs are matched to the charger's answer
+	require.Eventually(t, func() bool { return srv.registry.IsConnected("CP-WS") }, time.Second, 10*time.Millisecond)
+	result := make(chan error, 1)
+	var resp ocpp.ChangeConfigurationResponse
+	go func() {
+		result <- srv.registry.SendCall(context.Background(), "CP-WS", ocpp.ActionChangeConfiguration,
+			ocpp.ChangeConfigurationRequest{Key: "HeartbeatInterval", Value: "300"}, &resp)
+	}()
+	frame = readFrame(t, ws)
+	assert.JSONEq(t, `2`, string(frame[0]))
+	assert.JSONEq(t, `"`+ocpp.ActionChangeConfiguration+`"`, string(frame[2]))
+	require.NoError(t, ws.WriteJSON([]interface{}{ocpp.MessageTypeCallResult, frame[1], map[string]string{"status": "Accepted"}}))
+	require.NoError(t, <-result)
+	assert.Equal(t, ocpp.ConfigurationStatusAccepted, resp.Status)
+
+	// Closing the socket unregisters the charger and closes its connection record
+	ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	ws.Close()
+	require.Eventually(t, func() bool { return !srv.registry.IsConnected("CP-WS") }, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		conns, err := system.GetRepositories().Connections().ListByCharger(context.Background(), "CP-WS", db.DefaultListOptions())
+		return err == nil && len(conns) == 1 && conns[0].DisconnectedAt != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHardResetReturnsOnCallResult(t *testing.T) {
+	srv, _ := newCommandTestServer(t)
+	system := srv.coreSystem
+	system.SetCommandSender(srv.Registry())
+	system.SetConnectionRegistry(srv.Registry())
+	system.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		system.Shutdown(ctx)
+	}()
+
+	httpServer := httptest.NewServer(srv.router)
+	defer httpServer.Close()
+	ws := dialCharger(t, httpServer.URL, "CP-ONLINE")
+	require.Eventually(t, func() bool { return srv.registry.IsConnected("CP-ONLINE") }, time.Second, 10*time.Millisecond)
+
+	result := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		result <- postCommand(srv, "/api/v1/chargepoints/CP-ONLINE/reset", `{"type": "Hard"}`)
+	}()
+
+	frame := readFrame(t, ws)
+	assert.JSONEq(t, `"`+ocpp.ActionReset+`"`, string(frame[2]))
+	assert.JSONEq(t, `{"type": "Hard"}`, string(frame[3]))
+
+	// The charger answers and reboots without closing the socket cleanly; the
+	// response must not wait for the disconnect
+	require.NoError(t, ws.WriteJSON([]interface{}{ocpp.MessageTypeCallResult, frame[1], map[string]string{"status": "Accepted"}}))
+	select {
+	case w := <-result:
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"status": "Accepted"}`, w.Body.String())
+	case <-time.After(2 * time.Second):
+		t.Fatal("reset did not return after the CALLRESULT")
+	}
+
+	ws.Close()
+	require.Eventually(t, func() bool { return !srv.registry.IsConnected("CP-ONLINE") }, time.Second, 10*time.Millisecond)
+}
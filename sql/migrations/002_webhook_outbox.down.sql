@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_webhook_outbox_due;
+DROP TABLE IF EXISTS webhook_outbox;
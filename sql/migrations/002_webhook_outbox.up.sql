@@ -0,0 +1,16 @@
+-- Webhook Outbox - Pending and delivered webhook notifications (at-least-once delivery)
+CREATE TABLE webhook_outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_type TEXT NOT NULL,              -- Event name (charger.connected, charger.disconnected)
+    url TEXT NOT NULL,                     -- Destination URL
+    payload TEXT NOT NULL,                 -- JSON request body
+    status TEXT NOT NULL DEFAULT 'Pending', -- Delivery status (Pending, Delivered, DeadLetter)
+    attempts INTEGER NOT NULL DEFAULT 0,   -- Number of delivery attempts made
+    next_attempt_at DATETIME NOT NULL,     -- Earliest time of the next attempt
+    last_error TEXT DEFAULT '',            -- Error from the most recent failed attempt
+    delivered_at DATETIME,                 -- When the webhook was acknowledged
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_outbox_due ON webhook_outbox(status, next_attempt_at);
@@ -0,0 +1,13 @@
+-- Outbox - Domain events written alongside state changes, relayed to the event bus
+CREATE TABLE outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    topic TEXT NOT NULL,                   -- Event topic (e.g. transaction.started)
+    payload TEXT NOT NULL,                 -- JSON event body
+    status TEXT NOT NULL DEFAULT 'Pending', -- Relay status (Pending, Published)
+    attempts INTEGER NOT NULL DEFAULT 0,   -- Number of failed publish attempts
+    last_error TEXT DEFAULT '',            -- Error from the most recent failed publish
+    published_at DATETIME,                 -- When the event was handed to the bus
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_pending ON outbox(status, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_meter_values_unreconciled;
+ALTER TABLE meter_values DROP COLUMN unassociated;
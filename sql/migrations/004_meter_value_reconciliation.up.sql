@@ -0,0 +1,4 @@
+-- Flag meter values the reconciliation job could not link to a transaction
+ALTER TABLE meter_values ADD COLUMN unassociated BOOLEAN NOT NULL DEFAULT FALSE; -- No transaction covers this reading
+
+CREATE INDEX idx_meter_values_unreconciled ON meter_values(charger_id, connector_id, transaction_id, unassociated);
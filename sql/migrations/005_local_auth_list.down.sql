@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS charger_local_lists;
+DROP TABLE IF EXISTS id_tags;
@@ -0,0 +1,17 @@
+-- ID Tags - Authorization identifiers (RFID cards, app tokens)
+CREATE TABLE id_tags (
+    id_tag TEXT PRIMARY KEY,               -- OCPP idToken (max 20 characters)
+    parent_id_tag TEXT DEFAULT '',         -- Group identifier shared by related tags
+    status TEXT NOT NULL DEFAULT 'Accepted', -- Authorization status (Accepted, Blocked, Expired, Invalid)
+    expiry_date DATETIME,                  -- When the tag stops being valid (NULL for no expiry)
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Charger Local Lists - Local authorization list version last synced to each charger
+CREATE TABLE charger_local_lists (
+    charger_id TEXT PRIMARY KEY,           -- Charger holding the list
+    list_version INTEGER NOT NULL,         -- Version accepted by the charger
+    synced_at DATETIME NOT NULL,           -- When the charger accepted the list
+    FOREIGN KEY (charger_id) REFERENCES chargers(id) ON DELETE CASCADE
+);
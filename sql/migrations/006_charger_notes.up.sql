@@ -0,0 +1,2 @@
+-- Operator notes on chargers (free text, e.g. site access or known issues)
+ALTER TABLE chargers ADD COLUMN notes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- Energy readings converted to Wh (or varh) regardless of the unit reported by the charger
+ALTER TABLE meter_values ADD COLUMN value_normalized REAL NOT NULL DEFAULT 0;
+
+UPDATE meter_values
+SET value_normalized = CASE WHEN unit IN ('kWh', 'kvarh') THEN value * 1000 ELSE value END;
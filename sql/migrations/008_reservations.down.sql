@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reservations_connector;
+DROP TABLE IF EXISTS reservations;
@@ -0,0 +1,15 @@
+-- Reservations - Connectors held for an ID tag (ReserveNow)
+CREATE TABLE reservations (
+    id INTEGER PRIMARY KEY,                -- OCPP reservationId
+    charger_id TEXT NOT NULL,              -- Charger holding the reservation
+    connector_id INTEGER NOT NULL,         -- Reserved connector (0 reserves any connector)
+    id_tag TEXT NOT NULL,                  -- ID tag the connector is reserved for
+    parent_id_tag TEXT DEFAULT '',         -- Parent ID tag also allowed to use the reservation
+    expiry_date DATETIME NOT NULL,         -- When the reservation lapses
+    status TEXT NOT NULL DEFAULT 'Active', -- Reservation status (Active, Used, Cancelled, Expired)
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (charger_id) REFERENCES chargers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_reservations_connector ON reservations(charger_id, connector_id, status);
@@ -0,0 +1 @@
+ALTER TABLE chargers DROP COLUMN num_connectors;
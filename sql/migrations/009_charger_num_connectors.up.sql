@@ -0,0 +1,2 @@
+-- Connectors to provision as Unavailable on first boot (0 to wait for the charger to report them)
+ALTER TABLE chargers ADD COLUMN num_connectors INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,13 @@
+-- Audit Log - Who changed which entity, and how
+CREATE TABLE audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor TEXT NOT NULL,                   -- Caller that made the change (API role and key fingerprint, or system)
+    entity_type TEXT NOT NULL,             -- Kind of entity changed (charger, id_tag)
+    entity_id TEXT NOT NULL,               -- ID of the changed entity
+    operation TEXT NOT NULL,               -- Create, Update or Delete
+    changes TEXT NOT NULL DEFAULT '{}',    -- JSON object of changed fields with before and after values
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
@@ -0,0 +1,6 @@
+-- Settings - Small persistent key-value state (feature flags, counters)
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,                  -- Setting name
+    value TEXT NOT NULL,                   -- Setting value, encoded as text
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
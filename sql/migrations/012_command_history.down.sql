@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_command_history_charger;
+DROP TABLE IF EXISTS command_history;
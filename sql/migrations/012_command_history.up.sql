@@ -0,0 +1,15 @@
+-- Command History - Outbound commands sent to chargers and their results
+CREATE TABLE command_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    charger_id TEXT NOT NULL,              -- Charger the command was sent to
+    action TEXT NOT NULL,                  -- OCPP action (ChangeAvailability, SendLocalList, ...)
+    params TEXT NOT NULL DEFAULT '{}',     -- JSON request payload
+    status TEXT NOT NULL,                  -- Result status reported by the charger, or Error
+    error TEXT NOT NULL DEFAULT '',        -- Failure reason when the command could not be completed
+    latency_ms INTEGER NOT NULL DEFAULT 0, -- Time from sending the command to its result
+    operator TEXT NOT NULL,                -- Caller that issued the command (API role and key fingerprint, or system)
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (charger_id) REFERENCES chargers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_command_history_charger ON command_history(charger_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_charger_connections_disconnected_at;
+DROP INDEX IF EXISTS idx_charger_connections_charger;
+DROP TABLE IF EXISTS charger_connections;
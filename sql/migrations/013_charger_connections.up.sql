@@ -0,0 +1,15 @@
+-- Charger Connections - One row per OCPP WebSocket connection, for reconnect analytics.
+-- No foreign key to chargers: a new charger connects before its BootNotification registers it.
+CREATE TABLE charger_connections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    charger_id TEXT NOT NULL,                -- Charger that connected
+    remote_ip TEXT NOT NULL DEFAULT '',      -- Address the connection came from
+    subprotocol TEXT NOT NULL DEFAULT '',    -- Negotiated WebSocket subprotocol (ocpp1.6, ...)
+    connected_at DATETIME NOT NULL,
+    disconnected_at DATETIME,                -- NULL while the connection is open
+    disconnect_reason TEXT NOT NULL DEFAULT '', -- clean, abnormal, read_error, timeout or panic
+    duration_seconds INTEGER                 -- Set on disconnect
+);
+
+CREATE INDEX idx_charger_connections_charger ON charger_connections(charger_id, connected_at);
+CREATE INDEX idx_charger_connections_disconnected_at ON charger_connections(disconnected_at);
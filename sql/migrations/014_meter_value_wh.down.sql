@@ -0,0 +1 @@
+ALTER TABLE meter_values DROP COLUMN value_wh;
@@ -0,0 +1,3 @@
+-- Energy readings as whole Wh (or varh), so totals used for billing do not
+-- accumulate float rounding. Only written when database.integer_energy is on.
+ALTER TABLE meter_values ADD COLUMN value_wh INTEGER;
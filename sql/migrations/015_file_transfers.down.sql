@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_file_transfers_charger;
+DROP TABLE IF EXISTS file_transfers;
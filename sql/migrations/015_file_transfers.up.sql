@@ -0,0 +1,15 @@
+-- File Transfers - Signed firmware updates and log uploads requested from chargers
+CREATE TABLE file_transfers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,  -- Also the OCPP requestId sent to the charger
+    charger_id TEXT NOT NULL,              -- Charger the transfer was requested from
+    kind TEXT NOT NULL,                    -- Firmware or Log
+    log_type TEXT NOT NULL DEFAULT '',     -- DiagnosticsLog or SecurityLog, for log uploads
+    location TEXT NOT NULL,                -- Firmware download URI, or log upload URI
+    status TEXT NOT NULL,                  -- Latest status from the command response or status notifications
+    filename TEXT NOT NULL DEFAULT '',     -- Log file name reported by the charger
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (charger_id) REFERENCES chargers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_file_transfers_charger ON file_transfers(charger_id, kind, created_at);
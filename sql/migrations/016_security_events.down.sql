@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_security_events_charger;
+DROP INDEX IF EXISTS idx_security_events_timestamp;
+DROP TABLE IF EXISTS security_events;
@@ -0,0 +1,14 @@
+-- Security Events - SecurityEventNotification reports from chargers
+CREATE TABLE security_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    charger_id TEXT NOT NULL,              -- Charger that reported the event
+    type TEXT NOT NULL,                    -- Security event type (FirmwareUpdated, TamperDetectionActivated, ...)
+    tech_info TEXT NOT NULL DEFAULT '',    -- Additional technical information from the charger
+    critical BOOLEAN NOT NULL DEFAULT FALSE, -- Whether the type is critical and raised a security alert
+    timestamp DATETIME NOT NULL,           -- When the event occurred, as reported by the charger
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (charger_id) REFERENCES chargers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_security_events_timestamp ON security_events(timestamp);
+CREATE INDEX idx_security_events_charger ON security_events(charger_id, timestamp);
@@ -0,0 +1 @@
+ALTER TABLE transactions DROP COLUMN clock_skew_seconds;
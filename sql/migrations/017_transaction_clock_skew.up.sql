@@ -0,0 +1,3 @@
+-- Skew between the charger and server clocks when a transaction timestamp was
+-- beyond ocpp.max_clock_skew and kept as reported; NULL when within bounds
+ALTER TABLE transactions ADD COLUMN clock_skew_seconds INTEGER;
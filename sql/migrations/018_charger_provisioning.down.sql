@@ -0,0 +1 @@
+DROP TABLE IF EXISTS charger_provisioning;
@@ -0,0 +1,8 @@
+-- Charger Provisioning - the provisioning profile last pushed to each charger
+CREATE TABLE charger_provisioning (
+    charger_id TEXT PRIMARY KEY,
+    profile TEXT NOT NULL,                 -- Name of the provisioning profile
+    version INTEGER NOT NULL,              -- Profile version that was pushed
+    applied_at DATETIME NOT NULL,          -- When the profile was pushed
+    FOREIGN KEY (charger_id) REFERENCES chargers(id) ON DELETE CASCADE
+);
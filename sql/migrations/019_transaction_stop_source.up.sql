@@ -0,0 +1,3 @@
+-- Who ended a transaction: the charger (Charger), the charger answering a
+-- RemoteStopTransaction (Remote) or a server policy (Server); empty while active
+ALTER TABLE transactions ADD COLUMN stop_source TEXT DEFAULT '';
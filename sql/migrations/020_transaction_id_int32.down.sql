@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS transactions_transaction_id_int32_update;
+DROP TRIGGER IF EXISTS transactions_transaction_id_int32_insert;
@@ -0,0 +1,15 @@
+-- Many chargers store transactionId as a signed 32-bit integer, so reject ids
+-- they would truncate. Existing rows are left alone and reported at startup.
+CREATE TRIGGER transactions_transaction_id_int32_insert
+BEFORE INSERT ON transactions
+WHEN NEW.transaction_id < -2147483648 OR NEW.transaction_id > 2147483647
+BEGIN
+    SELECT RAISE(ABORT, 'transaction_id is outside the int32 range');
+END;
+
+CREATE TRIGGER transactions_transaction_id_int32_update
+BEFORE UPDATE OF transaction_id ON transactions
+WHEN NEW.transaction_id < -2147483648 OR NEW.transaction_id > 2147483647
+BEGIN
+    SELECT RAISE(ABORT, 'transaction_id is outside the int32 range');
+END;
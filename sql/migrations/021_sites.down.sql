@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS site_chargers;
+DROP TABLE IF EXISTS sites;
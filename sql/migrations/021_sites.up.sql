@@ -0,0 +1,19 @@
+-- Sites - groups of chargers sharing an electrical supply, balanced against its limit
+CREATE TABLE sites (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    max_current REAL NOT NULL DEFAULT 0,   -- Amps the site's chargers may draw together; 0 is unlimited
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Site Chargers - site membership; a charger belongs to at most one site
+CREATE TABLE site_chargers (
+    charger_id TEXT PRIMARY KEY,
+    site_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE,
+    FOREIGN KEY (charger_id) REFERENCES chargers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_site_chargers_site ON site_chargers(site_id);
@@ -0,0 +1,15 @@
+-- Dead letters - charger CALLs whose handler failed, kept so they can be
+-- replayed once the failure is fixed
+CREATE TABLE dead_letters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    charger_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    unique_id TEXT NOT NULL,
+    frame TEXT NOT NULL,                  -- OCPP-J CALL frame as received
+    last_error TEXT NOT NULL DEFAULT '',  -- Error of the original call or the latest replay
+    replays INTEGER NOT NULL DEFAULT 0,   -- Failed replay attempts
+    resolved_at DATETIME,                 -- Set once a replay succeeds
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_dead_letters_charger ON dead_letters(charger_id);
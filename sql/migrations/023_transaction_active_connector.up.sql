@@ -0,0 +1,11 @@
+-- At most one active transaction per connector, whichever process or charger
+-- message inserts it. Older duplicates left by concurrent StartTransactions
+-- are aborted by the server first so the index can be built.
+UPDATE transactions
+SET status = 'Aborted', stop_source = 'Server', updated_at = CURRENT_TIMESTAMP
+WHERE status = 'Active' AND id NOT IN (
+    SELECT MAX(id) FROM transactions WHERE status = 'Active' GROUP BY charger_id, connector_id
+);
+
+CREATE UNIQUE INDEX idx_transactions_active_connector
+ON transactions(charger_id, connector_id) WHERE status = 'Active';
@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// FS contains the SQL migration files embedded into the binary, so migrations
+// do not depend on the process working directory
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,20 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	defer func(v, c, d string) { Version, Commit, BuildDate = v, c, d }(Version, Commit, BuildDate)
+	Version, Commit, BuildDate = "1.2.3", "abc123", "2024-01-01T00:00:00Z"
+
+	assert.Equal(t, Info{
+		Version:   "1.2.3",
+		Commit:    "abc123",
+		BuildDate: "2024-01-01T00:00:00Z",
+		GoVersion: runtime.Version(),
+	}, Get())
+}